@@ -0,0 +1,5 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the version PlatformMesh objects are stored in and
+// converted through.
+func (*PlatformMesh) Hub() {}
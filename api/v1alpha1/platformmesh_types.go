@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,6 +32,187 @@ type PlatformMeshSpec struct {
 	InfraValues      apiextensionsv1.JSON `json:"infraValues,omitempty"`
 	Wait             *WaitConfig          `json:"wait,omitempty"`
 	ProfileConfigMap *ConfigMapReference  `json:"profileConfigMap,omitempty"`
+	// Profiles are profile ConfigMaps deep-merged in order, later profiles
+	// override earlier ones, e.g. a base, an environment and a cluster-local
+	// profile. When set, profileConfigMap is ignored.
+	// +optional
+	Profiles []ConfigMapReference `json:"profiles,omitempty"`
+	// Channel selects the section under channels in the profile whose infra and
+	// components are merged over the top-level ones, e.g. stable or edge.
+	// +optional
+	Channel string `json:"channel,omitempty"`
+	// +optional
+	Bootstrap *BootstrapConfig `json:"bootstrap,omitempty"`
+	// Components switches components of the profile on or off by service
+	// name, e.g. keycloak, openfga or portal. It overrides the enabled flags of
+	// the profile and of values. The HelmRelease and OCM Resources of a
+	// component switched off here are pruned.
+	// +optional
+	Components map[string]ComponentSwitch `json:"components,omitempty"`
+	// TeardownPolicy controls what happens to the kcp workspaces and objects the
+	// operator created when the PlatformMesh is deleted. Delete removes them,
+	// Orphan leaves them behind.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +kubebuilder:default=Orphan
+	// +optional
+	TeardownPolicy TeardownPolicy `json:"teardownPolicy,omitempty"`
+	// RuntimeClusters are further runtime clusters the runtime and components
+	// runtime templates are rendered for and applied to, next to the runtime
+	// cluster of the operator.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	RuntimeClusters []RuntimeCluster `json:"runtimeClusters,omitempty"`
+	// ReadinessGates are further checks that must pass before the instance
+	// is Ready. The Wait subroutine evaluates them on each reconcile.
+	// +optional
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty"`
+	// Adoption adopts HelmReleases that deployed profile components before
+	// the operator managed them, instead of installing the components twice.
+	// +optional
+	Adoption *AdoptionConfig `json:"adoption,omitempty"`
+	// MaintenanceWindows restrict disruptive operations, such as recreating
+	// kcp objects, upgrading components and restarting the operator, to the
+	// windows. Outside of them these operations are deferred while the rest
+	// of the reconciliation goes on. Without windows they run at any time.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+	// Versions pins the chart and image versions of components by service
+	// name instead of the versions resolved from the OCM component. It takes
+	// precedence over the version block of the service in the profile.
+	// +optional
+	Versions map[string]ComponentVersions `json:"versions,omitempty"`
+}
+
+// TeardownPolicy describes how the operator deals with the kcp objects it
+// created when the PlatformMesh is deleted. An empty policy orphans them.
+type TeardownPolicy string
+
+const (
+	TeardownPolicyDelete TeardownPolicy = "Delete"
+	TeardownPolicyOrphan TeardownPolicy = "Orphan"
+)
+
+// BootstrapConfig selects prerequisites the operator installs from pinned,
+// embedded manifests when they are missing on the cluster.
+type BootstrapConfig struct {
+	// Flux installs the Flux controllers when the Flux CRDs are absent.
+	// +optional
+	Flux bool `json:"flux,omitempty"`
+	// CertManager installs cert-manager through Flux when the cert-manager CRDs are absent.
+	// +optional
+	CertManager bool `json:"certManager,omitempty"`
+}
+
+// ComponentSwitch turns a component of the profile on or off.
+type ComponentSwitch struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ComponentVersions pins the versions of a component. An empty version is
+// resolved from the OCM component.
+type ComponentVersions struct {
+	// Chart is the version of the Helm chart of the component.
+	// +optional
+	Chart string `json:"chart,omitempty"`
+	// Image is the tag of the images of the component.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// RuntimeCluster is a further runtime cluster of an instance.
+type RuntimeCluster struct {
+	// Name identifies the cluster. The templates get it as runtimeCluster.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// KubeconfigSecretRef is the Secret in the namespace of the instance that
+	// holds the kubeconfig of the cluster.
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+	// TemplateSelector selects the templates rendered for the cluster. All
+	// templates are rendered when it is not set.
+	// +optional
+	TemplateSelector *RuntimeTemplateSelector `json:"templateSelector,omitempty"`
+}
+
+// ReadinessGateType is the kind of check of a readiness gate.
+// +kubebuilder:validation:Enum=httpProbe;resourceCondition
+type ReadinessGateType string
+
+const (
+	// ReadinessGateHTTPProbe sends a GET request to the target URL and
+	// expects the status code in expected, 200 by default. A class like 2xx
+	// matches all codes of the class.
+	ReadinessGateHTTPProbe ReadinessGateType = "httpProbe"
+	// ReadinessGateResourceCondition reads the target object from the infra
+	// cluster, given as apiVersion/kind/namespace/name or apiVersion/kind/name
+	// for cluster-scoped objects, and expects the condition in expected, as
+	// type or type=status, Ready=True by default.
+	ReadinessGateResourceCondition ReadinessGateType = "resourceCondition"
+)
+
+// ReadinessGate is a check that must pass before the instance is Ready.
+type ReadinessGate struct {
+	Type ReadinessGateType `json:"type"`
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+	// +optional
+	Expected string `json:"expected,omitempty"`
+}
+
+// AdoptionConfig configures the adoption of existing HelmReleases.
+type AdoptionConfig struct {
+	// Enabled discovers the HelmReleases in Namespaces that deploy a profile
+	// component and were not created by the operator. A HelmRelease deploys
+	// the component named by its core.platform-mesh.io/adopt-as annotation,
+	// or else by its Helm release name. The operator creates no HelmRelease
+	// for a component with a discovered release until it is adopted.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Namespaces are searched for HelmReleases. Defaults to the release
+	// namespace of the components.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Components are the discovered components to adopt, "*" adopts all of
+	// them. The values diff of a discovered component is reported in
+	// status.adoptions before it is listed here.
+	// +optional
+	Components []string `json:"components,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period in which disruptive operations may
+// run.
+type MaintenanceWindow struct {
+	// Cron is a five-field cron expression, evaluated in UTC, at which the
+	// window opens, e.g. "0 2 * * sat".
+	// +kubebuilder:validation:MinLength=1
+	Cron string `json:"cron"`
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// SecretKeyReference references a key of a Secret in the namespace of the
+// instance.
+type SecretKeyReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:default=kubeconfig
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// RuntimeTemplateSelector selects the runtime templates of a runtime cluster.
+type RuntimeTemplateSelector struct {
+	// Components are the services of the components profile rendered from
+	// the components runtime templates. All enabled services are rendered
+	// when empty.
+	// +optional
+	Components []string `json:"components,omitempty"`
+	// Templates are file name patterns, e.g. "resource*.yaml", of the
+	// templates rendered from both runtime template directories. All
+	// templates are rendered when empty.
+	// +optional
+	Templates []string `json:"templates,omitempty"`
 }
 
 type ConfigMapReference struct {
@@ -79,21 +261,168 @@ type ComponentConfig struct {
 
 type ExposureConfig struct {
 	BaseDomain string `json:"baseDomain,omitempty"`
-	Port       int    `json:"port,omitempty"`
-	Protocol   string `json:"protocol,omitempty"`
+	// AdditionalBaseDomains are further domains the platform is served on,
+	// e.g. a legacy domain next to BaseDomain. They share Port and Protocol.
+	// +listType=set
+	// +optional
+	AdditionalBaseDomains []string `json:"additionalBaseDomains,omitempty"`
+	Port                  int      `json:"port,omitempty"`
+	Protocol              string   `json:"protocol,omitempty"`
+	// Mode selects how the portal, the kcp front-proxy and keycloak are
+	// exposed. istio leaves it to the Istio objects of the components,
+	// gatewayapi and ingress render Gateway API routes or Ingresses from the
+	// exposure blocks of the profile. Defaults to istio with the istio
+	// integration of the operator enabled and to gatewayapi without it.
+	// +kubebuilder:validation:Enum=istio;gatewayapi;ingress
+	// +optional
+	Mode ExposureMode `json:"mode,omitempty"`
 }
 
+// ExposureMode describes how the endpoints of a PlatformMesh are exposed.
+type ExposureMode string
+
+const (
+	ExposureModeIstio      ExposureMode = "istio"
+	ExposureModeGatewayAPI ExposureMode = "gatewayapi"
+	ExposureModeIngress    ExposureMode = "ingress"
+)
+
 type Kcp struct {
 	ProviderConnections      []ProviderConnection             `json:"providerConnections,omitempty"`
 	ExtraProviderConnections []ProviderConnection             `json:"extraProviderConnections,omitempty"`
 	ExtraDefaultAPIBindings  []DefaultAPIBindingConfiguration `json:"extraDefaultAPIBindings,omitempty"`
+	// InitializerConnections are kubeconfig secrets pointing at the initializing
+	// virtual workspace of a WorkspaceType, for initializers deployed separately.
+	// +optional
+	InitializerConnections []InitializerConnection `json:"initializerConnections,omitempty"`
 	// +optional
 	ExtraWorkspaces []WorkspaceDeclaration `json:"extraWorkspaces,omitempty"`
+	// Workspaces declares the kcp workspaces and the manifests applied in
+	// them, in apply order. When empty, the numbered directories of the kcp
+	// manifest directory are applied instead.
+	// +optional
+	Workspaces []KcpWorkspaceManifests `json:"workspaces,omitempty"`
+	// DeletionPolicy controls how KCP objects are handled when an immutable field changes.
+	// Retain reports a RequiresRecreate condition, Delete removes the object so it is recreated.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// AdminSecretRefs is an ordered list of secrets holding kcp admin credentials.
+	// The operator uses the first one that can be loaded and falls back to the
+	// configured cluster admin secret when none can. The namespace defaults to the kcp namespace.
+	// +optional
+	AdminSecretRefs []SecretReference `json:"adminSecretRefs,omitempty"`
+	// DeletionProtection keeps the listed workspaces from being deleted.
+	// +optional
+	DeletionProtection *WorkspaceDeletionProtection `json:"deletionProtection,omitempty"`
+	// RawManifests are applied as is into arbitrary workspaces with the
+	// permissions of the operator. They are meant for one-off fixes in
+	// emergencies, not for regular content.
+	// +optional
+	RawManifests []RawManifest `json:"rawManifests,omitempty"`
+	// Initializers are WorkspaceType initializers the operator runs itself,
+	// instead of a separately deployed initializer.
+	// +optional
+	Initializers []WorkspaceInitializer `json:"initializers,omitempty"`
+}
+
+// WorkspaceInitializer is the initializer of a WorkspaceType run by the
+// operator. Each logical cluster of the type that waits for the initializer
+// gets the manifests applied, then the initializer is removed from it.
+type WorkspaceInitializer struct {
+	// WorkspaceTypeName is the name of the WorkspaceType, which must set
+	// spec.initializer.
+	WorkspaceTypeName string `json:"workspaceTypeName"`
+	// Path is the path of the workspace of the WorkspaceType, e.g. root.
+	Path string `json:"path"`
+	// Manifests are applied into each initializing logical cluster.
+	Manifests WorkspaceManifestSource `json:"manifests"`
+}
+
+// RawManifest is a single object applied into a workspace.
+type RawManifest struct {
+	// WorkspacePath is the path of the workspace, e.g. root:orgs.
+	// +kubebuilder:validation:MinLength=1
+	WorkspacePath string `json:"workspacePath"`
+	// Manifest is the object as YAML. Go template variables of the kcp
+	// manifests such as {{ .baseDomain }} are substituted.
+	// +kubebuilder:validation:MinLength=1
+	Manifest string `json:"manifest"`
+}
+
+// WorkspaceDeletionProtection lists workspaces the operator protects from
+// deletion with a finalizer. Deleting a protected workspace takes two steps:
+// listing it in Unlocked and annotating the Workspace with
+// WorkspaceDeletionUnlockAnnotation set to "true".
+type WorkspaceDeletionProtection struct {
+	// Workspaces are the paths of the protected workspaces, e.g. root:orgs.
+	// +optional
+	Workspaces []string `json:"workspaces,omitempty"`
+	// Unlocked are the paths of protected workspaces that may be deleted once
+	// they also carry the unlock annotation.
+	// +optional
+	Unlocked []string `json:"unlocked,omitempty"`
+}
+
+// WorkspaceDeletionUnlockAnnotation set to "true" on a protected Workspace
+// that is listed in WorkspaceDeletionProtection.Unlocked releases its
+// deletion protection.
+const WorkspaceDeletionUnlockAnnotation = "core.platform-mesh.io/deletion-unlocked"
+
+// DeletionPolicy describes how the operator deals with KCP objects that can only be
+// updated by recreating them.
+type DeletionPolicy string
+
+const (
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+)
+
+// KcpWorkspaceManifests declares a kcp workspace and the manifests applied in
+// it. The paths of the declarations form the workspace tree, a workspace is
+// declared after its parent.
+type KcpWorkspaceManifests struct {
+	// Path of the workspace, e.g. root:platform-mesh-system.
+	// +kubebuilder:validation:Pattern=`^root(:[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Path string `json:"path"`
+	// Type of the workspace. When set, the operator creates the workspace in
+	// its parent workspace. Otherwise the manifests of an earlier declaration
+	// have to create it.
+	// +optional
+	Type *WorkspaceTypeReference `json:"type,omitempty"`
+	// Manifests are glob patterns of manifest files relative to the kcp
+	// manifest directory. The files are applied in the order of the patterns.
+	// +optional
+	Manifests []string `json:"manifests,omitempty"`
 }
 
 type WorkspaceDeclaration struct {
 	Path string                 `json:"path"`
 	Type WorkspaceTypeReference `json:"type"`
+	// Manifests are applied in the workspace once it is ready, e.g. its
+	// initial APIBindings, RBAC and default resources.
+	// +optional
+	Manifests *WorkspaceManifestSource `json:"manifests,omitempty"`
+}
+
+// WorkspaceManifestSource references the manifests of an extra workspace in
+// a ConfigMap or a directory, one of which is set. Like the kcp manifests of
+// the operator they are Go templates.
+type WorkspaceManifestSource struct {
+	// ConfigMapRef references a ConfigMap whose keys ending in .yaml are
+	// applied in key order. The ConfigMap must be in the namespace of the
+	// instance.
+	// +optional
+	ConfigMapRef *ConfigMapReference `json:"configMapRef,omitempty"`
+	// Directory is a directory relative to the workspace directory of the
+	// operator whose .yaml files are applied in name order.
+	// +optional
+	Directory string `json:"directory,omitempty"`
+	// TemplateData is added to the template data of the kcp manifests when
+	// rendering the manifests, replacing keys of the same name.
+	// +optional
+	TemplateData *apiextensionsv1.JSON `json:"templateData,omitempty"`
 }
 
 type WorkspaceTypeReference struct {
@@ -112,6 +441,11 @@ type InitializerConnection struct {
 	Path              string `json:"path"`
 	Secret            string `json:"secret,omitempty"`
 	Namespace         string `json:"namespace,omitempty"`
+	// HostOverride replaces the front-proxy host in the server URL of the
+	// initializer kubeconfig, e.g. an internal service hostname in split-horizon
+	// networks. A value without scheme uses https.
+	// +optional
+	HostOverride string `json:"hostOverride,omitempty"`
 }
 
 type WebhookConfiguration struct {
@@ -148,29 +482,668 @@ type ProviderConnection struct {
 	// Scoped mode requires exactly one of endpointSliceName (virtual workspace server from slice) or apiExportName (workspace server for Path).
 	// +optional
 	AdminAuth *bool `json:"adminAuth,omitempty"`
+	// Mode selects the kubeconfig written for the connection: admin derives it
+	// from the kcp admin credentials, scoped issues credentials of its own with
+	// RBAC from the APIExport. It takes precedence over adminAuth.
+	// +optional
+	Mode ProviderConnectionMode `json:"mode,omitempty"`
+	// TokenExpiration is the lifetime of the ServiceAccount tokens and client
+	// certificates of a scoped connection. It overrides
+	// --subroutines-provider-secret-token-expiration.
+	// +optional
+	TokenExpiration *metav1.Duration `json:"tokenExpiration,omitempty"`
+	// SANamespace is the namespace of the ServiceAccount of a scoped connection
+	// in its workspace, default by default.
+	// +optional
+	SANamespace string `json:"saNamespace,omitempty"`
+	// AuthMode selects the credential of a scoped kubeconfig. token (the
+	// default) uses a ServiceAccount token, clientCert a client certificate
+	// issued by cert-manager from the kcp client CA. It is ignored with adminAuth.
+	// +optional
+	AuthMode ProviderAuthMode `json:"authMode,omitempty"`
+	// HostOverride replaces the front-proxy host in the server URL of the
+	// generated kubeconfig, e.g. an internal service hostname in split-horizon
+	// networks. It takes precedence over external. A value without scheme uses
+	// https.
+	// +optional
+	HostOverride string `json:"hostOverride,omitempty"`
+	// EndpointSelector selects the endpoint of the APIExportEndpointSlice
+	// endpointSliceName the kubeconfig is written for. Without it, the first
+	// endpoint is used.
+	// +optional
+	EndpointSelector *EndpointSelector `json:"endpointSelector,omitempty"`
+}
+
+// EndpointSelector selects an endpoint of an APIExportEndpointSlice in
+// multi-shard environments. The endpoints matching all set criteria are
+// candidates, index picks one of them. No matching endpoint is an error.
+type EndpointSelector struct {
+	// Shard selects the endpoints served by the kcp shard of this name, i.e.
+	// with the host of its virtual workspace URL.
+	// +optional
+	Shard string `json:"shard,omitempty"`
+	// URLPattern selects the endpoints whose URL matches this regular
+	// expression.
+	// +optional
+	URLPattern string `json:"urlPattern,omitempty"`
+	// Index picks the candidate at this position, the first by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Index *int `json:"index,omitempty"`
 }
 
+// ProviderConnectionMode is the kind of kubeconfig written for a provider
+// connection.
+// +kubebuilder:validation:Enum=admin;scoped
+type ProviderConnectionMode string
+
+const (
+	ProviderConnectionModeAdmin  ProviderConnectionMode = "admin"
+	ProviderConnectionModeScoped ProviderConnectionMode = "scoped"
+)
+
+// ProviderAuthMode is the credential of a scoped provider kubeconfig.
+// +kubebuilder:validation:Enum=token;clientCert
+type ProviderAuthMode string
+
+const (
+	ProviderAuthModeToken      ProviderAuthMode = "token"
+	ProviderAuthModeClientCert ProviderAuthMode = "clientCert"
+)
+
 // PlatformMeshStatus defines the observed state of PlatformMesh
 type PlatformMeshStatus struct {
 	Conditions         []metav1.Condition `json:"conditions,omitempty"`
 	ObservedGeneration int64              `json:"observedGeneration,omitempty" protobuf:"varint,3,opt,name=observedGeneration"`
 	NextReconcileTime  metav1.Time        `json:"nextReconcileTime,omitempty"`
 	KcpWorkspaces      []KcpWorkspace     `json:"kcpWorkspaces,omitempty"`
+	// +optional
+	ProviderSecrets []ProviderSecretStatus `json:"providerSecrets,omitempty"`
+	// +optional
+	ProviderConnections []ProviderConnectionStatus `json:"providerConnections,omitempty"`
+	// AdminSecret is the kcp admin credential secret currently in use.
+	// +optional
+	AdminSecret *SecretReference `json:"adminSecret,omitempty"`
+	// Connections lists the kcp server URLs written into provider and
+	// initializer connection secrets.
+	// +optional
+	Connections []ConnectionStatus `json:"connections,omitempty"`
+	// Plan references the result of the last planned reconciliation, see
+	// PlanModeAnnotation.
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+	// ComponentRenderErrors lists profile components whose templates failed to
+	// render. Their manifests are not applied, all other components are.
+	// +optional
+	ComponentRenderErrors []ComponentRenderError `json:"componentRenderErrors,omitempty"`
+	// PinnedComponents lists components rendered from a values snapshot after
+	// a rollback requested with ValuesRollbackAnnotation.
+	// +optional
+	PinnedComponents []PinnedComponent `json:"pinnedComponents,omitempty"`
+	// ValuesRollback is the last handled value of ValuesRollbackAnnotation.
+	// +optional
+	ValuesRollback string `json:"valuesRollback,omitempty"`
+	// ProfileHash is the checksum of the profile the components were last
+	// rendered from.
+	// +optional
+	ProfileHash string `json:"profileHash,omitempty"`
+	// ProtectedWorkspaces lists the paths of the workspaces the operator
+	// protects from deletion, see WorkspaceDeletionProtection.
+	// +optional
+	ProtectedWorkspaces []string `json:"protectedWorkspaces,omitempty"`
+	// FailedAPIBindings lists the applied APIBindings that did not become
+	// ready within their timeout during the last kcp setup.
+	// +optional
+	FailedAPIBindings []FailedAPIBinding `json:"failedAPIBindings,omitempty"`
+	// Shards reports the setup of each kcp shard when the per-shard setup is
+	// enabled.
+	// +optional
+	Shards []ShardStatus `json:"shards,omitempty"`
+	// Components reports the health of the deployed components as last
+	// probed by the health aggregator.
+	// +optional
+	Components []ComponentHealth `json:"components,omitempty"`
+	// WorkspaceTypeBindings reports how spec.kcp.extraDefaultAPIBindings were
+	// merged into each WorkspaceType during the last kcp setup.
+	// +optional
+	WorkspaceTypeBindings []WorkspaceTypeBindings `json:"workspaceTypeBindings,omitempty"`
+	// TenantNamespaces lists the namespaces provisioned on the runtime cluster
+	// for the tenants of the services with tenantNamespaces in the profile.
+	// +optional
+	TenantNamespaces []string `json:"tenantNamespaces,omitempty"`
+	// OpenFGA reports the OpenFGA store and authorization model bootstrapped
+	// for the instance.
+	// +optional
+	OpenFGA *OpenFGAStatus `json:"openFGA,omitempty"`
+	// ManagedSecrets summarizes the secrets the operator generated for the
+	// instance by purpose.
+	// +optional
+	ManagedSecrets *ManagedSecretsSummary `json:"managedSecrets,omitempty"`
+	// RuntimeClusters reports the templates applied to each of
+	// spec.runtimeClusters.
+	// +optional
+	RuntimeClusters []RuntimeClusterStatus `json:"runtimeClusters,omitempty"`
+	// ReadinessGates reports the result of each of spec.readinessGates.
+	// +optional
+	ReadinessGates []ReadinessGateStatus `json:"readinessGates,omitempty"`
+	// Adoptions reports the HelmReleases discovered or adopted for profile
+	// components with spec.adoption.
+	// +optional
+	Adoptions []ComponentAdoption `json:"adoptions,omitempty"`
+	// DeferredOperations are the disruptive operations waiting for the next
+	// of spec.maintenanceWindows.
+	// +optional
+	DeferredOperations []DeferredOperation `json:"deferredOperations,omitempty"`
+	// NextMaintenanceWindow is when the next maintenance window opens, set
+	// while operations are deferred.
+	// +optional
+	NextMaintenanceWindow *metav1.Time `json:"nextMaintenanceWindow,omitempty"`
+	// Recovery reports the last recovery requested with RecoverAnnotation.
+	// +optional
+	Recovery *RecoveryStatus `json:"recovery,omitempty"`
+	// LastError reports the last error a subroutine returned, until the
+	// subroutine completes without error.
+	// +optional
+	LastError *LastErrorStatus `json:"lastError,omitempty"`
+}
+
+// MaintenanceOperation is a kind of disruptive operation.
+// +kubebuilder:validation:Enum=Recreate;Upgrade;Restart
+type MaintenanceOperation string
+
+const (
+	// MaintenanceRecreate deletes a kcp object to recreate it with changed
+	// immutable fields.
+	MaintenanceRecreate MaintenanceOperation = "Recreate"
+	// MaintenanceUpgrade changes the chart or values of a deployed component.
+	MaintenanceUpgrade MaintenanceOperation = "Upgrade"
+	// MaintenanceRestart restarts the operator Deployment.
+	MaintenanceRestart MaintenanceOperation = "Restart"
+)
+
+// DeferredOperation is a disruptive operation deferred to a maintenance
+// window.
+type DeferredOperation struct {
+	Operation MaintenanceOperation `json:"operation"`
+	// Target is the object the operation is about.
+	Target string `json:"target"`
+	// Message describes the deferred change.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// AdoptionPhase is how far the HelmRelease of a component is adopted.
+// +kubebuilder:validation:Enum=Discovered;Adopted
+type AdoptionPhase string
+
+const (
+	// AdoptionDiscovered HelmReleases are left alone until their component
+	// is listed in spec.adoption.components.
+	AdoptionDiscovered AdoptionPhase = "Discovered"
+	// AdoptionAdopted HelmReleases are managed by the operator.
+	AdoptionAdopted AdoptionPhase = "Adopted"
+)
+
+// ComponentAdoption is a HelmRelease that deployed a profile component before
+// the operator managed it.
+type ComponentAdoption struct {
+	Component string        `json:"component"`
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Phase     AdoptionPhase `json:"phase"`
+	// ChangedValues are the paths of the values the profile sets to another
+	// value than the HelmRelease, as compared before its adoption.
+	// +optional
+	ChangedValues []string `json:"changedValues,omitempty"`
+	// ExtraValues are the paths of the values only the HelmRelease sets. The
+	// operator does not manage them, so they are kept.
+	// +optional
+	ExtraValues []string `json:"extraValues,omitempty"`
+	// AdoptedAt is when the operator adopted the HelmRelease.
+	// +optional
+	AdoptedAt *metav1.Time `json:"adoptedAt,omitempty"`
+}
+
+// ReadinessGateStatus is the last result of a readiness gate.
+type ReadinessGateStatus struct {
+	Type   ReadinessGateType `json:"type"`
+	Target string            `json:"target"`
+	Passed bool              `json:"passed"`
+	// Message is why the gate did not pass.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when the gate last started or stopped passing.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// RuntimeClusterPhase is the result of applying the templates to a runtime
+// cluster.
+// +kubebuilder:validation:Enum=Applied;Failed;Unreachable;Removing
+type RuntimeClusterPhase string
+
+const (
+	RuntimeClusterApplied     RuntimeClusterPhase = "Applied"
+	RuntimeClusterFailed      RuntimeClusterPhase = "Failed"
+	RuntimeClusterUnreachable RuntimeClusterPhase = "Unreachable"
+	// RuntimeClusterRemoving is set while the objects applied to a cluster
+	// that was removed from spec.runtimeClusters, or of an instance being
+	// deleted, are deleted.
+	RuntimeClusterRemoving RuntimeClusterPhase = "Removing"
+)
+
+// RuntimeClusterStatus reports the last apply of the templates to a runtime
+// cluster of spec.runtimeClusters.
+type RuntimeClusterStatus struct {
+	Name  string              `json:"name"`
+	Phase RuntimeClusterPhase `json:"phase"`
+	// Applied is the number of objects applied in the last reconcile.
+	// +optional
+	Applied int `json:"applied,omitempty"`
+	// Message is the error of the last apply.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastApplied is when changed templates were last applied to the
+	// cluster.
+	// +optional
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+	// Digest identifies the objects last applied to the cluster.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// KubeconfigSecretRef is the kubeconfig the objects were applied with.
+	// It is kept to delete them once the cluster is removed from
+	// spec.runtimeClusters.
+	// +optional
+	KubeconfigSecretRef *SecretKeyReference `json:"kubeconfigSecretRef,omitempty"`
+}
+
+// ValuesRollbackAnnotation requests a rollback of the values of the listed
+// components, comma-separated or "*" for all, to their previous successfully
+// rendered snapshot. The components stay pinned to the snapshot until the
+// spec changes. Change the value to request another rollback.
+const ValuesRollbackAnnotation = "core.platform-mesh.io/rollback-values"
+
+// PinnedComponent reports a component rendered from a values snapshot.
+type PinnedComponent struct {
+	// Component is the service name of the component in the profile.
+	Component string `json:"component"`
+	// SnapshotChecksum identifies the values snapshot the component is
+	// rendered from.
+	SnapshotChecksum string `json:"snapshotChecksum"`
+	// SnapshotGeneration is the generation the snapshot was rendered for.
+	SnapshotGeneration int64 `json:"snapshotGeneration"`
+	// PinnedGeneration is the generation the component was pinned at. The pin
+	// is released once the generation changes.
+	PinnedGeneration int64 `json:"pinnedGeneration"`
+	// PinnedAt is when the rollback was applied.
+	PinnedAt metav1.Time `json:"pinnedAt"`
+}
+
+// RecoverAnnotation set to "true" makes the operator rebuild all derived
+// secrets and the kcp wiring of the instance from scratch: the generated
+// provider, scoped, initializer and webhook secrets are deleted to be
+// recreated, cached lookups are dropped and every subroutine runs again. The
+// operator removes the annotation when the recovery starts and reports its
+// progress in status.recovery and the Recovery condition.
+const RecoverAnnotation = "platform-mesh.io/recover"
+
+// RecoveryPhase is the phase of a recovery.
+// +kubebuilder:validation:Enum=InProgress;Completed
+type RecoveryPhase string
+
+const (
+	RecoveryPhaseInProgress RecoveryPhase = "InProgress"
+	RecoveryPhaseCompleted  RecoveryPhase = "Completed"
+)
+
+// RecoveryStatus reports a recovery requested with RecoverAnnotation.
+type RecoveryStatus struct {
+	// Phase is InProgress until a reconciliation passed all subroutines.
+	Phase RecoveryPhase `json:"phase"`
+	// StartedAt is when the recovery was requested.
+	StartedAt metav1.Time `json:"startedAt"`
+	// CompletedAt is when all subroutines completed after the recovery
+	// started.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// DeletedSecrets lists the generated secrets deleted to be recreated, as
+	// namespace/name.
+	// +optional
+	DeletedSecrets []string `json:"deletedSecrets,omitempty"`
+}
+
+// ErrorCategory classifies the errors returned by the subroutines.
+// +kubebuilder:validation:Enum=ConfigError;DependencyNotReady;KCPUnavailable;TemplateError;ApplyConflict;Unknown
+type ErrorCategory string
+
+const (
+	// ErrorCategoryConfig is an invalid spec, profile or operator
+	// configuration. It is not resolved by retrying.
+	ErrorCategoryConfig ErrorCategory = "ConfigError"
+	// ErrorCategoryDependencyNotReady is a cluster or a component the
+	// subroutine depends on that is not reachable or ready yet.
+	ErrorCategoryDependencyNotReady ErrorCategory = "DependencyNotReady"
+	// ErrorCategoryKCPUnavailable is a kcp workspace that did not answer.
+	ErrorCategoryKCPUnavailable ErrorCategory = "KCPUnavailable"
+	// ErrorCategoryTemplate is a template that failed to parse or render. It
+	// is not resolved by retrying.
+	ErrorCategoryTemplate ErrorCategory = "TemplateError"
+	// ErrorCategoryApplyConflict is a write that conflicted with another
+	// writer or owner of the object.
+	ErrorCategoryApplyConflict ErrorCategory = "ApplyConflict"
+	// ErrorCategoryUnknown is an error of no other category.
+	ErrorCategoryUnknown ErrorCategory = "Unknown"
+)
+
+// LastErrorStatus reports the last error a subroutine returned.
+type LastErrorStatus struct {
+	// Category classifies the error.
+	Category ErrorCategory `json:"category"`
+	// Message is the message of the error.
+	Message string `json:"message"`
+	// Subroutine is the name of the subroutine that returned the error.
+	Subroutine string `json:"subroutine"`
+	// Retryable is false for errors that persist until the spec, the profile
+	// or the operator configuration changes.
+	Retryable bool `json:"retryable"`
+	// Since is when the subroutine first returned the error.
+	Since metav1.Time `json:"since"`
+	// ObservedGeneration is the generation of the instance the error was
+	// returned for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// PlanModeAnnotation set to "true" makes the operator plan the reconciliation
+// of the instance instead of executing it. The planned actions are stored in
+// the ConfigMap referenced from status.plan. Set to "diff", the plan also
+// lists the fields each apply would change.
+const PlanModeAnnotation = "core.platform-mesh.io/plan-mode"
+
+// Values of PlanModeAnnotation.
+const (
+	PlanModeActions = "true"
+	PlanModeDiff    = "diff"
+)
+
+// PlanStatus reports the last planned reconciliation.
+type PlanStatus struct {
+	// ConfigMapName is the ConfigMap in the namespace of the instance that
+	// holds the plan.
+	ConfigMapName string `json:"configMapName"`
+	// ObservedGeneration is the generation the plan was made for.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// GeneratedAt is when the plan was made.
+	GeneratedAt metav1.Time `json:"generatedAt"`
+	// Actions is the number of planned actions.
+	Actions int `json:"actions"`
+	// Complete is false when planning stopped before the end of the pipeline.
+	Complete bool `json:"complete"`
+}
+
+// ComponentRenderError reports a component of the components profile whose
+// templates failed to render.
+type ComponentRenderError struct {
+	// Component is the service name of the component in the profile.
+	Component string `json:"component"`
+	// Templates is the template set that failed, components-runtime or
+	// components-infra.
+	Templates string `json:"templates"`
+	// Message is the render error.
+	Message string `json:"message"`
+}
+
+// ConnectionType distinguishes provider from initializer connections.
+// +kubebuilder:validation:Enum=Provider;Initializer
+type ConnectionType string
+
+const (
+	ConnectionTypeProvider    ConnectionType = "Provider"
+	ConnectionTypeInitializer ConnectionType = "Initializer"
+)
+
+// ConnectionStatus reports the server URL of a connection secret.
+type ConnectionStatus struct {
+	// Name is the name of the secret holding the connection kubeconfig.
+	Name string         `json:"name"`
+	Type ConnectionType `json:"type"`
+	// ResolvedURL is the server URL written into the kubeconfig. It keeps the
+	// last resolved value while the connection is unhealthy.
+	// +optional
+	ResolvedURL string `json:"resolvedURL,omitempty"`
+	// LastResolved is when ResolvedURL last changed.
+	// +optional
+	LastResolved *metav1.Time `json:"lastResolved,omitempty"`
+	// Healthy is true when the URL was resolved and the secret written in the
+	// last reconciliation.
+	Healthy bool `json:"healthy"`
+	// Ready is true when a discovery request through the written kubeconfig
+	// succeeded in the last reconciliation.
+	Ready bool `json:"ready"`
+}
+
+// ProviderConnectionStatus reports the state of a scoped provider connection.
+type ProviderConnectionStatus struct {
+	Secret string `json:"secret"`
+	Path   string `json:"path"`
+	// RBACUpToDate is true when the scoped ClusterRole of the connection matches
+	// the rules derived from the current APIExport.
+	RBACUpToDate bool `json:"rbacUpToDate"`
+	// TokenExpiresAt is when the ServiceAccount token in the connection
+	// kubeconfig expires. The token is re-issued before that.
+	// +optional
+	TokenExpiresAt *metav1.Time `json:"tokenExpiresAt,omitempty"`
+	// ClientCertExpiresAt is when the client certificate in the connection
+	// kubeconfig expires. cert-manager renews it before that.
+	// +optional
+	ClientCertExpiresAt *metav1.Time `json:"clientCertExpiresAt,omitempty"`
+	// ProposedRules are the rules of the scoped ClusterRole that wait for
+	// approval through RBACApprovalAnnotation. They are only set while the
+	// operator requires approval and the rules were not approved yet.
+	// +optional
+	ProposedRules []rbacv1.PolicyRule `json:"proposedRules,omitempty"`
+	// ProposedRulesHash identifies ProposedRules in RBACApprovalAnnotation.
+	// +optional
+	ProposedRulesHash string `json:"proposedRulesHash,omitempty"`
+	// SANamespace is the namespace of the ServiceAccount of the connection, so
+	// it is found once the connection is removed from the spec.
+	// +optional
+	SANamespace string `json:"saNamespace,omitempty"`
+}
+
+// RBACApprovalAnnotation lists the comma-separated proposedRulesHash values
+// of the provider connection rules that may be granted, when the operator
+// requires approval of scoped RBAC.
+const RBACApprovalAnnotation = "core.platform-mesh.io/approved-rbac"
+
+// ProviderSecretOwnership describes how a provider connection secret is tied to
+// its PlatformMesh instance.
+// +kubebuilder:validation:Enum=Owned;Adopted;Labeled;Conflict
+type ProviderSecretOwnership string
+
+const (
+	// ProviderSecretOwned secrets carry a controller reference to the instance.
+	ProviderSecretOwned ProviderSecretOwnership = "Owned"
+	// ProviderSecretAdopted secrets existed without an owner and were adopted.
+	ProviderSecretAdopted ProviderSecretOwnership = "Adopted"
+	// ProviderSecretLabeled secrets live in another namespace and are tracked by instance labels.
+	ProviderSecretLabeled ProviderSecretOwnership = "Labeled"
+	// ProviderSecretConflict secrets are controlled by another owner and left untouched.
+	ProviderSecretConflict ProviderSecretOwnership = "Conflict"
+)
+
+// ProviderSecretStatus reports the ownership of a provider connection secret.
+type ProviderSecretStatus struct {
+	Name      string                  `json:"name"`
+	Namespace string                  `json:"namespace"`
+	Ownership ProviderSecretOwnership `json:"ownership"`
+}
+
+// FailedAPIBinding reports an APIBinding that did not become ready.
+type FailedAPIBinding struct {
+	// Workspace is the path of the workspace the binding was applied to.
+	Workspace string `json:"workspace"`
+	Name      string `json:"name"`
+	// Message lists the conditions of the binding that are not True.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WorkspaceTypeBindings reports the merge of the extra default APIBindings of
+// a WorkspaceType with the ones declared in its manifest.
+type WorkspaceTypeBindings struct {
+	// WorkspaceTypePath is the workspace path and name of the WorkspaceType,
+	// <path>:<name>.
+	WorkspaceTypePath string `json:"workspaceTypePath"`
+	// Added are the extra bindings added to the WorkspaceType.
+	// +optional
+	Added []APIExportReference `json:"added,omitempty"`
+	// Duplicates are extra bindings the WorkspaceType already had, from its
+	// manifest or an earlier entry.
+	// +optional
+	Duplicates []APIExportReference `json:"duplicates,omitempty"`
+	// Conflicts are extra bindings of an export the WorkspaceType already binds
+	// from another path. They are not added.
+	// +optional
+	Conflicts []APIExportReference `json:"conflicts,omitempty"`
+}
+
+// APIExportReference references an APIExport by the path of its workspace
+// and its name.
+type APIExportReference struct {
+	Path   string `json:"path"`
+	Export string `json:"export"`
+}
+
+// OpenFGAStatus reports the OpenFGA store of an instance and the
+// authorization model last written to it.
+type OpenFGAStatus struct {
+	StoreID   string `json:"storeID"`
+	StoreName string `json:"storeName"`
+	// AuthorizationModelID is the ID of the model last written by the operator.
+	// +optional
+	AuthorizationModelID string `json:"authorizationModelID,omitempty"`
+	// ModelHash is the hash of the model last written, the model is only
+	// written again when it changes.
+	// +optional
+	ModelHash string `json:"modelHash,omitempty"`
+}
+
+// ManagedSecretsSummary summarizes the secrets the operator generated for an
+// instance, which carry the platform-mesh.io/purpose label.
+type ManagedSecretsSummary struct {
+	// Total is the number of generated secrets.
+	Total int32 `json:"total"`
+	// +optional
+	Purposes []ManagedSecretPurpose `json:"purposes,omitempty"`
+}
+
+// ManagedSecretPurpose summarizes the generated secrets of one purpose.
+type ManagedSecretPurpose struct {
+	Purpose string `json:"purpose"`
+	Count   int32  `json:"count"`
+	// OldestSecret is the namespace/name of the least recently rotated secret.
+	OldestSecret string `json:"oldestSecret"`
+	// OldestRotation is when OldestSecret was last rotated, unset if that is
+	// not known.
+	// +optional
+	OldestRotation *metav1.Time `json:"oldestRotation,omitempty"`
+}
+
+// ShardStatus reports the kcp setup of one shard.
+type ShardStatus struct {
+	Name string `json:"name"`
+	// BaseURL is the URL the operator reaches the shard at.
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+	// APIExportIdentityHashes are the identity hashes of the kcp APIExports
+	// read from the shard, by template key.
+	// +optional
+	APIExportIdentityHashes map[string]string `json:"apiExportIdentityHashes,omitempty"`
+	// Ready is true when the hashes were read and the shard manifests applied.
+	Ready bool `json:"ready"`
+	// Message describes why the setup of the shard failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentHealth is the probed health of one component deployed by the
+// operator.
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	// Message describes why the component is unhealthy.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when Healthy last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
 }
 
 type KcpWorkspace struct {
 	Name  string `json:"name"`
 	Phase string `json:"phase"`
+	// Content counts the managed APIBindings, WorkspaceTypes and webhook
+	// configurations expected in the workspace and how many of them exist.
+	// +optional
+	Content []WorkspaceContentCount `json:"content,omitempty"`
+	// MissingContent is true when managed objects expected in the workspace are absent.
+	// +optional
+	MissingContent bool `json:"missingContent,omitempty"`
+	// Webhooks lists the endpoints of the managed webhook configurations in
+	// the workspace and whether the operator could reach them.
+	// +optional
+	Webhooks []WebhookEndpointStatus `json:"webhooks,omitempty"`
+	// APIBindings lists the APIBindings in the workspace with their phase.
+	// +optional
+	APIBindings []WorkspaceAPIBinding `json:"apiBindings,omitempty"`
+}
+
+// WorkspaceAPIBinding is an APIBinding found in a kcp workspace.
+type WorkspaceAPIBinding struct {
+	Name string `json:"name"`
+	// Phase of the APIBinding, Bound once the export is bound.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// Message is why the APIBinding is not ready.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WorkspaceContentCount compares expected and present managed objects of one kind.
+type WorkspaceContentCount struct {
+	Kind     string `json:"kind"`
+	Expected int    `json:"expected"`
+	Present  int    `json:"present"`
+}
+
+// WebhookEndpointStatus is the latest result of probing the endpoint of one
+// webhook of a webhook configuration in a kcp workspace.
+type WebhookEndpointStatus struct {
+	// Configuration is the webhook configuration as <kind>/<name>.
+	Configuration string `json:"configuration"`
+	Webhook       string `json:"webhook"`
+	URL           string `json:"url"`
+	// Reachable is true when a TLS connection verified against the caBundle
+	// of the webhook succeeded.
+	Reachable bool `json:"reachable"`
+	// Message is why the endpoint is not reachable.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when the probes last found Reachable or Message
+	// changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpsetupSubroutine')].status",name="KCP",type=string,description="KCP status (shows reason if Unknown)",priority=0
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpsetupSubroutine')].reason",name="KCP_REASON",type=string,description="KCP reason if status is Unknown",priority=1
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProvidersecretSubroutine')].status",name="SECRET",type=string,description="Provider Secret status (shows reason if Unknown)",priority=0
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProvidersecretSubroutine')].reason",name="SECRET_REASON",type=string,description="Provider Secret reason if status is Unknown",priority=1
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentSubroutine')].status",name="DEPLOYMENT",type=string,description="Deployment status (shows reason if Unknown)",priority=0
-// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentSubroutine')].reason",name="DEPLOYMENT_REASON",type=string,description="Deployment reason if status is Unknown",priority=1
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpSetupReady')].status",name="KCP",type=string,description="KCP setup status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpSetupReady')].reason",name="KCP_REASON",type=string,description="KCP setup step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProviderSecretsReady')].status",name="SECRET",type=string,description="Provider Secret status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProviderSecretsReady')].reason",name="SECRET_REASON",type=string,description="Provider Secret step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentReady')].status",name="DEPLOYMENT",type=string,description="Deployment status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentReady')].reason",name="DEPLOYMENT_REASON",type=string,description="Deployment step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WebhooksReady')].status",name="WEBHOOKS",type=string,description="Authorization webhook status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WebhooksReady')].reason",name="WEBHOOKS_REASON",type=string,description="Authorization webhook step that is not ready",priority=1
 // +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WaitSubroutine')].status",name="WAIT",type=string,description="Wait status (shows reason if Unknown)",priority=0
 // +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WaitSubroutine')].reason",name="WAIT_REASON",type=string,description="Wait reason if status is Unknown",priority=1
 // +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name="Ready",type=string,description="Shows if resource is ready",priority=0
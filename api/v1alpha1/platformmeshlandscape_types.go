@@ -0,0 +1,147 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlatformMeshLandscapeSpec defines which PlatformMesh instances a landscape
+// aggregates.
+type PlatformMeshLandscapeSpec struct {
+	// Selector selects PlatformMesh instances in all namespaces by label. An
+	// empty selector selects every instance.
+	// +optional
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+	// ExpiryThreshold is how far ahead provider connection credentials are
+	// reported as expiring. Defaults to 168h.
+	// +optional
+	ExpiryThreshold *metav1.Duration `json:"expiryThreshold,omitempty"`
+}
+
+// PlatformMeshLandscapeStatus aggregates the status of the selected
+// PlatformMesh instances.
+type PlatformMeshLandscapeStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	// Instances is the number of selected instances.
+	Instances int `json:"instances"`
+	// ReadyInstances is the number of selected instances whose Ready condition
+	// is True.
+	ReadyInstances int `json:"readyInstances"`
+	// NotReady lists the selected instances that are not Ready.
+	// +optional
+	NotReady []LandscapeInstance `json:"notReady,omitempty"`
+	// Channels counts the selected instances per profile channel, which
+	// determines the component versions they run.
+	// +optional
+	Channels []LandscapeChannel `json:"channels,omitempty"`
+	// DriftedComponents lists the parts of the selected instances that do not
+	// match what the operator would apply.
+	// +optional
+	DriftedComponents []LandscapeDriftedComponent `json:"driftedComponents,omitempty"`
+	// ExpiringCredentials lists provider connection credentials of the
+	// selected instances that expire within spec.expiryThreshold.
+	// +optional
+	ExpiringCredentials []LandscapeExpiringCredential `json:"expiringCredentials,omitempty"`
+	// LastAggregated is when the status was last aggregated.
+	// +optional
+	LastAggregated *metav1.Time `json:"lastAggregated,omitempty"`
+}
+
+type LandscapeInstance struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Reason and Message are taken from the Ready condition of the instance.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type LandscapeChannel struct {
+	// Channel is spec.channel of the instances, empty for the top-level profile.
+	Channel   string `json:"channel"`
+	Instances int    `json:"instances"`
+}
+
+type LandscapeDriftedComponent struct {
+	// Instance is the namespace/name of the PlatformMesh.
+	Instance string `json:"instance"`
+	// Component is the profile component, kcp workspace or provider connection
+	// secret that drifted.
+	Component string `json:"component"`
+	// Reason is RenderFailed, MissingContent or RBACOutdated.
+	Reason string `json:"reason"`
+}
+
+const (
+	LandscapeDriftRenderFailed   = "RenderFailed"
+	LandscapeDriftMissingContent = "MissingContent"
+	LandscapeDriftRBACOutdated   = "RBACOutdated"
+)
+
+type LandscapeExpiringCredential struct {
+	// Instance is the namespace/name of the PlatformMesh.
+	Instance string `json:"instance"`
+	// Secret is the provider connection secret holding the credential.
+	Secret string `json:"secret"`
+	// +kubebuilder:validation:Enum=Token;ClientCert
+	Kind      string      `json:"kind"`
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+const (
+	LandscapeCredentialToken      = "Token"
+	LandscapeCredentialClientCert = "ClientCert"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:JSONPath=".status.instances",name="INSTANCES",type=integer,description="Selected PlatformMesh instances",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.readyInstances",name="READY_INSTANCES",type=integer,description="Selected PlatformMesh instances that are Ready",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.lastAggregated",name="AGGREGATED",type=date,description="When the status was last aggregated",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name="Ready",type=string,description="Shows if resource is ready",priority=0
+
+// PlatformMeshLandscape aggregates the status of PlatformMesh instances across
+// namespaces for fleet dashboards
+type PlatformMeshLandscape struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PlatformMeshLandscapeSpec   `json:"spec,omitempty"`
+	Status PlatformMeshLandscapeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PlatformMeshLandscapeList contains a list of PlatformMeshLandscape
+type PlatformMeshLandscapeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlatformMeshLandscape `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PlatformMeshLandscape{}, &PlatformMeshLandscapeList{})
+}
+
+func (i *PlatformMeshLandscape) GetConditions() []metav1.Condition { return i.Status.Conditions }
+func (i *PlatformMeshLandscape) SetConditions(conditions []metav1.Condition) {
+	i.Status.Conditions = conditions
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RenderSnapshotSpec is the output of one render of the templates of a
+// PlatformMesh instance.
+type RenderSnapshotSpec struct {
+	// Instance is the name of the PlatformMesh in the namespace of the
+	// snapshot.
+	Instance string `json:"instance"`
+	// Generation is metadata.generation of the instance that was rendered.
+	Generation int64 `json:"generation"`
+	// Digest hashes the objects, equal renders have equal digests.
+	Digest string `json:"digest"`
+	// Objects are the rendered objects.
+	// +optional
+	Objects []RenderedObject `json:"objects,omitempty"`
+}
+
+// RenderedObject is an object rendered from the templates of an instance.
+type RenderedObject struct {
+	// TemplateType is the template directory the object was rendered from:
+	// infra, runtime, components-infra or components-runtime, followed by
+	// :<name> when rendered for a cluster of spec.runtimeClusters.
+	TemplateType string `json:"templateType"`
+	// Cluster is the cluster the object is applied to, infra, runtime or the
+	// name of a cluster of spec.runtimeClusters.
+	Cluster string `json:"cluster"`
+	// +optional
+	Component  string `json:"component,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Hash is the sha256 of the rendered object.
+	Hash string `json:"hash"`
+}
+
+// RenderSnapshotStatus is the result of applying the rendered objects.
+type RenderSnapshotStatus struct {
+	// Applied is the number of objects that were applied.
+	Applied int `json:"applied"`
+	// Failed lists the objects that could not be applied.
+	// +optional
+	Failed []RenderedObjectFailure `json:"failed,omitempty"`
+	// LastApplied is when the objects were last applied.
+	// +optional
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+}
+
+// RenderedObjectFailure is a rendered object whose apply failed.
+type RenderedObjectFailure struct {
+	TemplateType string `json:"templateType"`
+	Kind         string `json:"kind"`
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".spec.instance",name="INSTANCE",type=string,description="Rendered PlatformMesh instance",priority=0
+// +kubebuilder:printcolumn:JSONPath=".spec.generation",name="GENERATION",type=integer,description="Rendered generation of the instance",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.applied",name="APPLIED",type=integer,description="Objects that were applied",priority=0
+// +kubebuilder:printcolumn:JSONPath=".spec.digest",name="DIGEST",type=string,description="Hash of the rendered objects",priority=1
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="AGE",type=date,priority=0
+
+// RenderSnapshot records the objects the Deployment subroutine rendered for a
+// PlatformMesh instance and the result of applying them
+type RenderSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RenderSnapshotSpec   `json:"spec,omitempty"`
+	Status RenderSnapshotStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RenderSnapshotList contains a list of RenderSnapshot
+type RenderSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RenderSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RenderSnapshot{}, &RenderSnapshotList{})
+}
@@ -21,10 +21,96 @@ limitations under the License.
 package v1alpha1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIExportReference) DeepCopyInto(out *APIExportReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIExportReference.
+func (in *APIExportReference) DeepCopy() *APIExportReference {
+	if in == nil {
+		return nil
+	}
+	out := new(APIExportReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionConfig) DeepCopyInto(out *AdoptionConfig) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionConfig.
+func (in *AdoptionConfig) DeepCopy() *AdoptionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapConfig) DeepCopyInto(out *BootstrapConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapConfig.
+func (in *BootstrapConfig) DeepCopy() *BootstrapConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentAdoption) DeepCopyInto(out *ComponentAdoption) {
+	*out = *in
+	if in.ChangedValues != nil {
+		in, out := &in.ChangedValues, &out.ChangedValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraValues != nil {
+		in, out := &in.ExtraValues, &out.ExtraValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdoptedAt != nil {
+		in, out := &in.AdoptedAt, &out.AdoptedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentAdoption.
+func (in *ComponentAdoption) DeepCopy() *ComponentAdoption {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentAdoption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentConfig) DeepCopyInto(out *ComponentConfig) {
 	*out = *in
@@ -40,6 +126,67 @@ func (in *ComponentConfig) DeepCopy() *ComponentConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentHealth) DeepCopyInto(out *ComponentHealth) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentHealth.
+func (in *ComponentHealth) DeepCopy() *ComponentHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentRenderError) DeepCopyInto(out *ComponentRenderError) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRenderError.
+func (in *ComponentRenderError) DeepCopy() *ComponentRenderError {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentRenderError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentSwitch) DeepCopyInto(out *ComponentSwitch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentSwitch.
+func (in *ComponentSwitch) DeepCopy() *ComponentSwitch {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentSwitch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentVersions) DeepCopyInto(out *ComponentVersions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentVersions.
+func (in *ComponentVersions) DeepCopy() *ComponentVersions {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentVersions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
 	*out = *in
@@ -55,6 +202,25 @@ func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionStatus) DeepCopyInto(out *ConnectionStatus) {
+	*out = *in
+	if in.LastResolved != nil {
+		in, out := &in.LastResolved, &out.LastResolved
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionStatus.
+func (in *ConnectionStatus) DeepCopy() *ConnectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DefaultAPIBindingConfiguration) DeepCopyInto(out *DefaultAPIBindingConfiguration) {
 	*out = *in
@@ -70,9 +236,49 @@ func (in *DefaultAPIBindingConfiguration) DeepCopy() *DefaultAPIBindingConfigura
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeferredOperation) DeepCopyInto(out *DeferredOperation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeferredOperation.
+func (in *DeferredOperation) DeepCopy() *DeferredOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(DeferredOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointSelector) DeepCopyInto(out *EndpointSelector) {
+	*out = *in
+	if in.Index != nil {
+		in, out := &in.Index, &out.Index
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointSelector.
+func (in *EndpointSelector) DeepCopy() *EndpointSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExposureConfig) DeepCopyInto(out *ExposureConfig) {
 	*out = *in
+	if in.AdditionalBaseDomains != nil {
+		in, out := &in.AdditionalBaseDomains, &out.AdditionalBaseDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExposureConfig.
@@ -85,6 +291,21 @@ func (in *ExposureConfig) DeepCopy() *ExposureConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailedAPIBinding) DeepCopyInto(out *FailedAPIBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailedAPIBinding.
+func (in *FailedAPIBinding) DeepCopy() *FailedAPIBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(FailedAPIBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureToggle) DeepCopyInto(out *FeatureToggle) {
 	*out = *in
@@ -159,11 +380,47 @@ func (in *Kcp) DeepCopyInto(out *Kcp) {
 		*out = make([]DefaultAPIBindingConfiguration, len(*in))
 		copy(*out, *in)
 	}
+	if in.InitializerConnections != nil {
+		in, out := &in.InitializerConnections, &out.InitializerConnections
+		*out = make([]InitializerConnection, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraWorkspaces != nil {
 		in, out := &in.ExtraWorkspaces, &out.ExtraWorkspaces
 		*out = make([]WorkspaceDeclaration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = make([]KcpWorkspaceManifests, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdminSecretRefs != nil {
+		in, out := &in.AdminSecretRefs, &out.AdminSecretRefs
+		*out = make([]SecretReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeletionProtection != nil {
+		in, out := &in.DeletionProtection, &out.DeletionProtection
+		*out = new(WorkspaceDeletionProtection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RawManifests != nil {
+		in, out := &in.RawManifests, &out.RawManifests
+		*out = make([]RawManifest, len(*in))
 		copy(*out, *in)
 	}
+	if in.Initializers != nil {
+		in, out := &in.Initializers, &out.Initializers
+		*out = make([]WorkspaceInitializer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Kcp.
@@ -179,6 +436,23 @@ func (in *Kcp) DeepCopy() *Kcp {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KcpWorkspace) DeepCopyInto(out *KcpWorkspace) {
 	*out = *in
+	if in.Content != nil {
+		in, out := &in.Content, &out.Content
+		*out = make([]WorkspaceContentCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]WebhookEndpointStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.APIBindings != nil {
+		in, out := &in.APIBindings, &out.APIBindings
+		*out = make([]WorkspaceAPIBinding, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KcpWorkspace.
@@ -192,218 +466,985 @@ func (in *KcpWorkspace) DeepCopy() *KcpWorkspace {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OCMConfig) DeepCopyInto(out *OCMConfig) {
+func (in *KcpWorkspaceManifests) DeepCopyInto(out *KcpWorkspaceManifests) {
 	*out = *in
-	if in.Repo != nil {
-		in, out := &in.Repo, &out.Repo
-		*out = new(RepoConfig)
+	if in.Type != nil {
+		in, out := &in.Type, &out.Type
+		*out = new(WorkspaceTypeReference)
 		**out = **in
 	}
-	if in.Component != nil {
-		in, out := &in.Component, &out.Component
-		*out = new(ComponentConfig)
-		**out = **in
-	}
-	if in.ReferencePath != nil {
-		in, out := &in.ReferencePath, &out.ReferencePath
-		*out = make([]ReferencePathElement, len(*in))
+	if in.Manifests != nil {
+		in, out := &in.Manifests, &out.Manifests
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCMConfig.
-func (in *OCMConfig) DeepCopy() *OCMConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KcpWorkspaceManifests.
+func (in *KcpWorkspaceManifests) DeepCopy() *KcpWorkspaceManifests {
 	if in == nil {
 		return nil
 	}
-	out := new(OCMConfig)
+	out := new(KcpWorkspaceManifests)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlatformMesh) DeepCopyInto(out *PlatformMesh) {
+func (in *LandscapeChannel) DeepCopyInto(out *LandscapeChannel) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMesh.
-func (in *PlatformMesh) DeepCopy() *PlatformMesh {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LandscapeChannel.
+func (in *LandscapeChannel) DeepCopy() *LandscapeChannel {
 	if in == nil {
 		return nil
 	}
-	out := new(PlatformMesh)
+	out := new(LandscapeChannel)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PlatformMesh) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LandscapeDriftedComponent) DeepCopyInto(out *LandscapeDriftedComponent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LandscapeDriftedComponent.
+func (in *LandscapeDriftedComponent) DeepCopy() *LandscapeDriftedComponent {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(LandscapeDriftedComponent)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlatformMeshList) DeepCopyInto(out *PlatformMeshList) {
+func (in *LandscapeExpiringCredential) DeepCopyInto(out *LandscapeExpiringCredential) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]PlatformMesh, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LandscapeExpiringCredential.
+func (in *LandscapeExpiringCredential) DeepCopy() *LandscapeExpiringCredential {
+	if in == nil {
+		return nil
 	}
+	out := new(LandscapeExpiringCredential)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshList.
-func (in *PlatformMeshList) DeepCopy() *PlatformMeshList {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LandscapeInstance) DeepCopyInto(out *LandscapeInstance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LandscapeInstance.
+func (in *LandscapeInstance) DeepCopy() *LandscapeInstance {
 	if in == nil {
 		return nil
 	}
-	out := new(PlatformMeshList)
+	out := new(LandscapeInstance)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PlatformMeshList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LastErrorStatus) DeepCopyInto(out *LastErrorStatus) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastErrorStatus.
+func (in *LastErrorStatus) DeepCopy() *LastErrorStatus {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(LastErrorStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlatformMeshSpec) DeepCopyInto(out *PlatformMeshSpec) {
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
 	*out = *in
-	if in.Exposure != nil {
-		in, out := &in.Exposure, &out.Exposure
-		*out = new(ExposureConfig)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
 	}
-	in.Kcp.DeepCopyInto(&out.Kcp)
-	in.Values.DeepCopyInto(&out.Values)
-	if in.OCM != nil {
-		in, out := &in.OCM, &out.OCM
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretPurpose) DeepCopyInto(out *ManagedSecretPurpose) {
+	*out = *in
+	if in.OldestRotation != nil {
+		in, out := &in.OldestRotation, &out.OldestRotation
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretPurpose.
+func (in *ManagedSecretPurpose) DeepCopy() *ManagedSecretPurpose {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretPurpose)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretsSummary) DeepCopyInto(out *ManagedSecretsSummary) {
+	*out = *in
+	if in.Purposes != nil {
+		in, out := &in.Purposes, &out.Purposes
+		*out = make([]ManagedSecretPurpose, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretsSummary.
+func (in *ManagedSecretsSummary) DeepCopy() *ManagedSecretsSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretsSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCMConfig) DeepCopyInto(out *OCMConfig) {
+	*out = *in
+	if in.Repo != nil {
+		in, out := &in.Repo, &out.Repo
+		*out = new(RepoConfig)
+		**out = **in
+	}
+	if in.Component != nil {
+		in, out := &in.Component, &out.Component
+		*out = new(ComponentConfig)
+		**out = **in
+	}
+	if in.ReferencePath != nil {
+		in, out := &in.ReferencePath, &out.ReferencePath
+		*out = make([]ReferencePathElement, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCMConfig.
+func (in *OCMConfig) DeepCopy() *OCMConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OCMConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenFGAStatus) DeepCopyInto(out *OpenFGAStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenFGAStatus.
+func (in *OpenFGAStatus) DeepCopy() *OpenFGAStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenFGAStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedComponent) DeepCopyInto(out *PinnedComponent) {
+	*out = *in
+	in.PinnedAt.DeepCopyInto(&out.PinnedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedComponent.
+func (in *PinnedComponent) DeepCopy() *PinnedComponent {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedComponent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+	*out = *in
+	in.GeneratedAt.DeepCopyInto(&out.GeneratedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
+func (in *PlanStatus) DeepCopy() *PlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMesh) DeepCopyInto(out *PlatformMesh) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMesh.
+func (in *PlatformMesh) DeepCopy() *PlatformMesh {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMesh)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlatformMesh) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshLandscape) DeepCopyInto(out *PlatformMeshLandscape) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshLandscape.
+func (in *PlatformMeshLandscape) DeepCopy() *PlatformMeshLandscape {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshLandscape)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlatformMeshLandscape) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshLandscapeList) DeepCopyInto(out *PlatformMeshLandscapeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlatformMeshLandscape, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshLandscapeList.
+func (in *PlatformMeshLandscapeList) DeepCopy() *PlatformMeshLandscapeList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshLandscapeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlatformMeshLandscapeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshLandscapeSpec) DeepCopyInto(out *PlatformMeshLandscapeSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.ExpiryThreshold != nil {
+		in, out := &in.ExpiryThreshold, &out.ExpiryThreshold
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshLandscapeSpec.
+func (in *PlatformMeshLandscapeSpec) DeepCopy() *PlatformMeshLandscapeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshLandscapeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshLandscapeStatus) DeepCopyInto(out *PlatformMeshLandscapeStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NotReady != nil {
+		in, out := &in.NotReady, &out.NotReady
+		*out = make([]LandscapeInstance, len(*in))
+		copy(*out, *in)
+	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = make([]LandscapeChannel, len(*in))
+		copy(*out, *in)
+	}
+	if in.DriftedComponents != nil {
+		in, out := &in.DriftedComponents, &out.DriftedComponents
+		*out = make([]LandscapeDriftedComponent, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpiringCredentials != nil {
+		in, out := &in.ExpiringCredentials, &out.ExpiringCredentials
+		*out = make([]LandscapeExpiringCredential, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastAggregated != nil {
+		in, out := &in.LastAggregated, &out.LastAggregated
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshLandscapeStatus.
+func (in *PlatformMeshLandscapeStatus) DeepCopy() *PlatformMeshLandscapeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshLandscapeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshList) DeepCopyInto(out *PlatformMeshList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlatformMesh, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshList.
+func (in *PlatformMeshList) DeepCopy() *PlatformMeshList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlatformMeshList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshSpec) DeepCopyInto(out *PlatformMeshSpec) {
+	*out = *in
+	if in.Exposure != nil {
+		in, out := &in.Exposure, &out.Exposure
+		*out = new(ExposureConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Kcp.DeepCopyInto(&out.Kcp)
+	in.Values.DeepCopyInto(&out.Values)
+	if in.OCM != nil {
+		in, out := &in.OCM, &out.OCM
 		*out = new(OCMConfig)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.FeatureToggles != nil {
-		in, out := &in.FeatureToggles, &out.FeatureToggles
-		*out = make([]FeatureToggle, len(*in))
+	if in.FeatureToggles != nil {
+		in, out := &in.FeatureToggles, &out.FeatureToggles
+		*out = make([]FeatureToggle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.InfraValues.DeepCopyInto(&out.InfraValues)
+	if in.Wait != nil {
+		in, out := &in.Wait, &out.Wait
+		*out = new(WaitConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProfileConfigMap != nil {
+		in, out := &in.ProfileConfigMap, &out.ProfileConfigMap
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]ConfigMapReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(BootstrapConfig)
+		**out = **in
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string]ComponentSwitch, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RuntimeClusters != nil {
+		in, out := &in.RuntimeClusters, &out.RuntimeClusters
+		*out = make([]RuntimeCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.Adoption != nil {
+		in, out := &in.Adoption, &out.Adoption
+		*out = new(AdoptionConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make(map[string]ComponentVersions, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshSpec.
+func (in *PlatformMeshSpec) DeepCopy() *PlatformMeshSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformMeshStatus) DeepCopyInto(out *PlatformMeshStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.NextReconcileTime.DeepCopyInto(&out.NextReconcileTime)
+	if in.KcpWorkspaces != nil {
+		in, out := &in.KcpWorkspaces, &out.KcpWorkspaces
+		*out = make([]KcpWorkspace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProviderSecrets != nil {
+		in, out := &in.ProviderSecrets, &out.ProviderSecrets
+		*out = make([]ProviderSecretStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProviderConnections != nil {
+		in, out := &in.ProviderConnections, &out.ProviderConnections
+		*out = make([]ProviderConnectionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdminSecret != nil {
+		in, out := &in.AdminSecret, &out.AdminSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Connections != nil {
+		in, out := &in.Connections, &out.Connections
+		*out = make([]ConnectionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(PlanStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ComponentRenderErrors != nil {
+		in, out := &in.ComponentRenderErrors, &out.ComponentRenderErrors
+		*out = make([]ComponentRenderError, len(*in))
+		copy(*out, *in)
+	}
+	if in.PinnedComponents != nil {
+		in, out := &in.PinnedComponents, &out.PinnedComponents
+		*out = make([]PinnedComponent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProtectedWorkspaces != nil {
+		in, out := &in.ProtectedWorkspaces, &out.ProtectedWorkspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedAPIBindings != nil {
+		in, out := &in.FailedAPIBindings, &out.FailedAPIBindings
+		*out = make([]FailedAPIBinding, len(*in))
+		copy(*out, *in)
+	}
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = make([]ShardStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentHealth, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkspaceTypeBindings != nil {
+		in, out := &in.WorkspaceTypeBindings, &out.WorkspaceTypeBindings
+		*out = make([]WorkspaceTypeBindings, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TenantNamespaces != nil {
+		in, out := &in.TenantNamespaces, &out.TenantNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OpenFGA != nil {
+		in, out := &in.OpenFGA, &out.OpenFGA
+		*out = new(OpenFGAStatus)
+		**out = **in
+	}
+	if in.ManagedSecrets != nil {
+		in, out := &in.ManagedSecrets, &out.ManagedSecrets
+		*out = new(ManagedSecretsSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RuntimeClusters != nil {
+		in, out := &in.RuntimeClusters, &out.RuntimeClusters
+		*out = make([]RuntimeClusterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Adoptions != nil {
+		in, out := &in.Adoptions, &out.Adoptions
+		*out = make([]ComponentAdoption, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeferredOperations != nil {
+		in, out := &in.DeferredOperations, &out.DeferredOperations
+		*out = make([]DeferredOperation, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextMaintenanceWindow != nil {
+		in, out := &in.NextMaintenanceWindow, &out.NextMaintenanceWindow
+		*out = (*in).DeepCopy()
+	}
+	if in.Recovery != nil {
+		in, out := &in.Recovery, &out.Recovery
+		*out = new(RecoveryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(LastErrorStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshStatus.
+func (in *PlatformMeshStatus) DeepCopy() *PlatformMeshStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformMeshStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderConnection) DeepCopyInto(out *ProviderConnection) {
+	*out = *in
+	if in.EndpointSliceName != nil {
+		in, out := &in.EndpointSliceName, &out.EndpointSliceName
+		*out = new(string)
+		**out = **in
+	}
+	if in.APIExportName != nil {
+		in, out := &in.APIExportName, &out.APIExportName
+		*out = new(string)
+		**out = **in
+	}
+	if in.RawPath != nil {
+		in, out := &in.RawPath, &out.RawPath
+		*out = new(string)
+		**out = **in
+	}
+	if in.Namespace != nil {
+		in, out := &in.Namespace, &out.Namespace
+		*out = new(string)
+		**out = **in
+	}
+	if in.AdminAuth != nil {
+		in, out := &in.AdminAuth, &out.AdminAuth
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TokenExpiration != nil {
+		in, out := &in.TokenExpiration, &out.TokenExpiration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.EndpointSelector != nil {
+		in, out := &in.EndpointSelector, &out.EndpointSelector
+		*out = new(EndpointSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConnection.
+func (in *ProviderConnection) DeepCopy() *ProviderConnection {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderConnection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderConnectionStatus) DeepCopyInto(out *ProviderConnectionStatus) {
+	*out = *in
+	if in.TokenExpiresAt != nil {
+		in, out := &in.TokenExpiresAt, &out.TokenExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ClientCertExpiresAt != nil {
+		in, out := &in.ClientCertExpiresAt, &out.ClientCertExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ProposedRules != nil {
+		in, out := &in.ProposedRules, &out.ProposedRules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConnectionStatus.
+func (in *ProviderConnectionStatus) DeepCopy() *ProviderConnectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderConnectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSecretStatus) DeepCopyInto(out *ProviderSecretStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSecretStatus.
+func (in *ProviderSecretStatus) DeepCopy() *ProviderSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RawManifest) DeepCopyInto(out *RawManifest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RawManifest.
+func (in *RawManifest) DeepCopy() *RawManifest {
+	if in == nil {
+		return nil
+	}
+	out := new(RawManifest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGateStatus) DeepCopyInto(out *ReadinessGateStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGateStatus.
+func (in *ReadinessGateStatus) DeepCopy() *ReadinessGateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryStatus) DeepCopyInto(out *RecoveryStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DeletedSecrets != nil {
+		in, out := &in.DeletedSecrets, &out.DeletedSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryStatus.
+func (in *RecoveryStatus) DeepCopy() *RecoveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReferencePathElement) DeepCopyInto(out *ReferencePathElement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReferencePathElement.
+func (in *ReferencePathElement) DeepCopy() *ReferencePathElement {
+	if in == nil {
+		return nil
+	}
+	out := new(ReferencePathElement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RenderSnapshot) DeepCopyInto(out *RenderSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderSnapshot.
+func (in *RenderSnapshot) DeepCopy() *RenderSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(RenderSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RenderSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RenderSnapshotList) DeepCopyInto(out *RenderSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RenderSnapshot, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	in.InfraValues.DeepCopyInto(&out.InfraValues)
-	if in.Wait != nil {
-		in, out := &in.Wait, &out.Wait
-		*out = new(WaitConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ProfileConfigMap != nil {
-		in, out := &in.ProfileConfigMap, &out.ProfileConfigMap
-		*out = new(ConfigMapReference)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshSpec.
-func (in *PlatformMeshSpec) DeepCopy() *PlatformMeshSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderSnapshotList.
+func (in *RenderSnapshotList) DeepCopy() *RenderSnapshotList {
 	if in == nil {
 		return nil
 	}
-	out := new(PlatformMeshSpec)
+	out := new(RenderSnapshotList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RenderSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlatformMeshStatus) DeepCopyInto(out *PlatformMeshStatus) {
+func (in *RenderSnapshotSpec) DeepCopyInto(out *RenderSnapshotSpec) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	in.NextReconcileTime.DeepCopyInto(&out.NextReconcileTime)
-	if in.KcpWorkspaces != nil {
-		in, out := &in.KcpWorkspaces, &out.KcpWorkspaces
-		*out = make([]KcpWorkspace, len(*in))
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]RenderedObject, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformMeshStatus.
-func (in *PlatformMeshStatus) DeepCopy() *PlatformMeshStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderSnapshotSpec.
+func (in *RenderSnapshotSpec) DeepCopy() *RenderSnapshotSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PlatformMeshStatus)
+	out := new(RenderSnapshotSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProviderConnection) DeepCopyInto(out *ProviderConnection) {
+func (in *RenderSnapshotStatus) DeepCopyInto(out *RenderSnapshotStatus) {
 	*out = *in
-	if in.EndpointSliceName != nil {
-		in, out := &in.EndpointSliceName, &out.EndpointSliceName
-		*out = new(string)
-		**out = **in
-	}
-	if in.APIExportName != nil {
-		in, out := &in.APIExportName, &out.APIExportName
-		*out = new(string)
-		**out = **in
-	}
-	if in.RawPath != nil {
-		in, out := &in.RawPath, &out.RawPath
-		*out = new(string)
-		**out = **in
+	if in.Failed != nil {
+		in, out := &in.Failed, &out.Failed
+		*out = make([]RenderedObjectFailure, len(*in))
+		copy(*out, *in)
 	}
-	if in.Namespace != nil {
-		in, out := &in.Namespace, &out.Namespace
-		*out = new(string)
-		**out = **in
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
 	}
-	if in.AdminAuth != nil {
-		in, out := &in.AdminAuth, &out.AdminAuth
-		*out = new(bool)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderSnapshotStatus.
+func (in *RenderSnapshotStatus) DeepCopy() *RenderSnapshotStatus {
+	if in == nil {
+		return nil
 	}
+	out := new(RenderSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConnection.
-func (in *ProviderConnection) DeepCopy() *ProviderConnection {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RenderedObject) DeepCopyInto(out *RenderedObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderedObject.
+func (in *RenderedObject) DeepCopy() *RenderedObject {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderConnection)
+	out := new(RenderedObject)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReferencePathElement) DeepCopyInto(out *ReferencePathElement) {
+func (in *RenderedObjectFailure) DeepCopyInto(out *RenderedObjectFailure) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReferencePathElement.
-func (in *ReferencePathElement) DeepCopy() *ReferencePathElement {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderedObjectFailure.
+func (in *RenderedObjectFailure) DeepCopy() *RenderedObjectFailure {
 	if in == nil {
 		return nil
 	}
-	out := new(ReferencePathElement)
+	out := new(RenderedObjectFailure)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -440,6 +1481,91 @@ func (in *ResourceType) DeepCopy() *ResourceType {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeCluster) DeepCopyInto(out *RuntimeCluster) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+	if in.TemplateSelector != nil {
+		in, out := &in.TemplateSelector, &out.TemplateSelector
+		*out = new(RuntimeTemplateSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeCluster.
+func (in *RuntimeCluster) DeepCopy() *RuntimeCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeClusterStatus) DeepCopyInto(out *RuntimeClusterStatus) {
+	*out = *in
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
+	if in.KubeconfigSecretRef != nil {
+		in, out := &in.KubeconfigSecretRef, &out.KubeconfigSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeClusterStatus.
+func (in *RuntimeClusterStatus) DeepCopy() *RuntimeClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeTemplateSelector) DeepCopyInto(out *RuntimeTemplateSelector) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeTemplateSelector.
+func (in *RuntimeTemplateSelector) DeepCopy() *RuntimeTemplateSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeTemplateSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -455,6 +1581,28 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShardStatus) DeepCopyInto(out *ShardStatus) {
+	*out = *in
+	if in.APIExportIdentityHashes != nil {
+		in, out := &in.APIExportIdentityHashes, &out.APIExportIdentityHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShardStatus.
+func (in *ShardStatus) DeepCopy() *ShardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ShardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WaitConfig) DeepCopyInto(out *WaitConfig) {
 	*out = *in
@@ -494,10 +1642,61 @@ func (in *WebhookConfiguration) DeepCopy() *WebhookConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookEndpointStatus) DeepCopyInto(out *WebhookEndpointStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookEndpointStatus.
+func (in *WebhookEndpointStatus) DeepCopy() *WebhookEndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookEndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceAPIBinding) DeepCopyInto(out *WorkspaceAPIBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceAPIBinding.
+func (in *WorkspaceAPIBinding) DeepCopy() *WorkspaceAPIBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceAPIBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceContentCount) DeepCopyInto(out *WorkspaceContentCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceContentCount.
+func (in *WorkspaceContentCount) DeepCopy() *WorkspaceContentCount {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceContentCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkspaceDeclaration) DeepCopyInto(out *WorkspaceDeclaration) {
 	*out = *in
 	out.Type = in.Type
+	if in.Manifests != nil {
+		in, out := &in.Manifests, &out.Manifests
+		*out = new(WorkspaceManifestSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceDeclaration.
@@ -510,6 +1709,102 @@ func (in *WorkspaceDeclaration) DeepCopy() *WorkspaceDeclaration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceDeletionProtection) DeepCopyInto(out *WorkspaceDeletionProtection) {
+	*out = *in
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Unlocked != nil {
+		in, out := &in.Unlocked, &out.Unlocked
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceDeletionProtection.
+func (in *WorkspaceDeletionProtection) DeepCopy() *WorkspaceDeletionProtection {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceDeletionProtection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceInitializer) DeepCopyInto(out *WorkspaceInitializer) {
+	*out = *in
+	in.Manifests.DeepCopyInto(&out.Manifests)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceInitializer.
+func (in *WorkspaceInitializer) DeepCopy() *WorkspaceInitializer {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceInitializer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceManifestSource) DeepCopyInto(out *WorkspaceManifestSource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+	if in.TemplateData != nil {
+		in, out := &in.TemplateData, &out.TemplateData
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceManifestSource.
+func (in *WorkspaceManifestSource) DeepCopy() *WorkspaceManifestSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceManifestSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceTypeBindings) DeepCopyInto(out *WorkspaceTypeBindings) {
+	*out = *in
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]APIExportReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Duplicates != nil {
+		in, out := &in.Duplicates, &out.Duplicates
+		*out = make([]APIExportReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]APIExportReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceTypeBindings.
+func (in *WorkspaceTypeBindings) DeepCopy() *WorkspaceTypeBindings {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceTypeBindings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkspaceTypeReference) DeepCopyInto(out *WorkspaceTypeReference) {
 	*out = *in
@@ -0,0 +1,274 @@
+package v1alpha2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+var _ conversion.Convertible = &PlatformMesh{}
+
+// ConvertTo converts src to the v1alpha1 hub. The component overrides are
+// written into the values of their services and replace the values there.
+func (src *PlatformMesh) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.PlatformMesh)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", dstRaw)
+	}
+	values, err := setComponentOverrides(src.Spec.Values, src.Spec.Components)
+	if err != nil {
+		return err
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha1.PlatformMeshSpec{
+		Exposure:           src.Spec.Exposure,
+		Kcp:                src.Spec.Kcp,
+		Values:             values,
+		OCM:                src.Spec.OCM,
+		FeatureToggles:     src.Spec.FeatureToggles,
+		InfraValues:        src.Spec.InfraValues,
+		Wait:               src.Spec.Wait,
+		ProfileConfigMap:   src.Spec.ProfileConfigMap,
+		Profiles:           src.Spec.Profiles,
+		Channel:            src.Spec.Channel,
+		Bootstrap:          src.Spec.Bootstrap,
+		Components:         componentSwitches(src.Spec.Components),
+		TeardownPolicy:     src.Spec.TeardownPolicy,
+		RuntimeClusters:    src.Spec.RuntimeClusters,
+		ReadinessGates:     src.Spec.ReadinessGates,
+		Adoption:           src.Spec.Adoption,
+		MaintenanceWindows: src.Spec.MaintenanceWindows,
+		Versions:           src.Spec.Versions,
+	}
+	dst.Status = src.Status
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub to dst. Service values that fit a
+// field of ComponentOverrides are moved there, all other values are kept in
+// Values.
+func (dst *PlatformMesh) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.PlatformMesh)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", srcRaw)
+	}
+	values, components, err := extractComponentOverrides(src.Spec.Values)
+	if err != nil {
+		return err
+	}
+	components = setComponentSwitches(components, src.Spec.Components)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = PlatformMeshSpec{
+		Exposure:           src.Spec.Exposure,
+		Kcp:                src.Spec.Kcp,
+		Components:         components,
+		Values:             values,
+		OCM:                src.Spec.OCM,
+		FeatureToggles:     src.Spec.FeatureToggles,
+		InfraValues:        src.Spec.InfraValues,
+		Wait:               src.Spec.Wait,
+		ProfileConfigMap:   src.Spec.ProfileConfigMap,
+		Profiles:           src.Spec.Profiles,
+		Channel:            src.Spec.Channel,
+		Bootstrap:          src.Spec.Bootstrap,
+		TeardownPolicy:     src.Spec.TeardownPolicy,
+		RuntimeClusters:    src.Spec.RuntimeClusters,
+		ReadinessGates:     src.Spec.ReadinessGates,
+		Adoption:           src.Spec.Adoption,
+		MaintenanceWindows: src.Spec.MaintenanceWindows,
+		Versions:           src.Spec.Versions,
+	}
+	dst.Status = src.Status
+	return nil
+}
+
+// setComponentOverrides returns values with the overrides of components set
+// in the Helm values of their services.
+func setComponentOverrides(values apiextensionsv1.JSON, components map[string]ComponentOverrides) (apiextensionsv1.JSON, error) {
+	if !slices.ContainsFunc(slices.Collect(maps.Values(components)), hasValueOverrides) {
+		return *values.DeepCopy(), nil
+	}
+	root := map[string]interface{}{}
+	if len(values.Raw) > 0 {
+		if err := json.Unmarshal(values.Raw, &root); err != nil {
+			return apiextensionsv1.JSON{}, fmt.Errorf("spec.values is not an object: %w", err)
+		}
+		if root == nil {
+			root = map[string]interface{}{}
+		}
+	}
+
+	services := servicesOf(root)
+	for name, c := range components {
+		if !hasValueOverrides(c) {
+			continue
+		}
+		helmValues := childMap(childMap(services, name), "values")
+		if c.ImageTag != "" {
+			childMap(helmValues, "image")["tag"] = c.ImageTag
+		}
+		if c.Replicas != nil {
+			helmValues["replicaCount"] = *c.Replicas
+		}
+		if c.Resources != nil {
+			helmValues["resources"] = c.Resources
+		}
+	}
+
+	raw, err := json.Marshal(root)
+	if err != nil {
+		return apiextensionsv1.JSON{}, err
+	}
+	return apiextensionsv1.JSON{Raw: raw}, nil
+}
+
+// hasValueOverrides reports whether c overrides any Helm values.
+func hasValueOverrides(c ComponentOverrides) bool {
+	return c.ImageTag != "" || c.Replicas != nil || c.Resources != nil
+}
+
+// componentSwitches returns the enabled flags of components as the
+// spec.components of v1alpha1.
+func componentSwitches(components map[string]ComponentOverrides) map[string]v1alpha1.ComponentSwitch {
+	var switches map[string]v1alpha1.ComponentSwitch
+	for name, c := range components {
+		if c.Enabled == nil {
+			continue
+		}
+		if switches == nil {
+			switches = map[string]v1alpha1.ComponentSwitch{}
+		}
+		switches[name] = v1alpha1.ComponentSwitch{Enabled: *c.Enabled}
+	}
+	return switches
+}
+
+// setComponentSwitches sets the enabled flags of the spec.components of
+// v1alpha1 in components.
+func setComponentSwitches(components map[string]ComponentOverrides, switches map[string]v1alpha1.ComponentSwitch) map[string]ComponentOverrides {
+	for name, s := range switches {
+		if components == nil {
+			components = map[string]ComponentOverrides{}
+		}
+		c := components[name]
+		enabled := s.Enabled
+		c.Enabled = &enabled
+		components[name] = c
+	}
+	return components
+}
+
+// extractComponentOverrides moves the service values of values that fit a
+// field of ComponentOverrides into the returned overrides. Values that are not
+// an object are returned unchanged.
+func extractComponentOverrides(values apiextensionsv1.JSON) (apiextensionsv1.JSON, map[string]ComponentOverrides, error) {
+	var root map[string]interface{}
+	if len(values.Raw) == 0 || json.Unmarshal(values.Raw, &root) != nil || root == nil {
+		return *values.DeepCopy(), nil, nil
+	}
+
+	components := map[string]ComponentOverrides{}
+	services := servicesOf(root)
+	for name, v := range services {
+		component, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		helmValues, ok := component["values"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var c ComponentOverrides
+		if image, ok := helmValues["image"].(map[string]interface{}); ok {
+			if tag, ok := image["tag"].(string); ok && tag != "" {
+				c.ImageTag = tag
+				delete(image, "tag")
+				if len(image) == 0 {
+					delete(helmValues, "image")
+				}
+			}
+		}
+		if n, ok := helmValues["replicaCount"].(float64); ok && n >= 0 && n <= math.MaxInt32 && n == math.Trunc(n) {
+			replicas := int32(n)
+			c.Replicas = &replicas
+			delete(helmValues, "replicaCount")
+		}
+		if resources, ok := decodeResources(helmValues["resources"]); ok {
+			c.Resources = resources
+			delete(helmValues, "resources")
+		}
+		if !hasValueOverrides(c) {
+			continue
+		}
+
+		components[name] = c
+		if len(helmValues) == 0 {
+			delete(component, "values")
+		}
+		if len(component) == 0 {
+			delete(services, name)
+		}
+	}
+	if len(components) == 0 {
+		return *values.DeepCopy(), nil, nil
+	}
+	if len(root) == 0 {
+		return apiextensionsv1.JSON{}, components, nil
+	}
+
+	raw, err := json.Marshal(root)
+	if err != nil {
+		return apiextensionsv1.JSON{}, nil, err
+	}
+	return apiextensionsv1.JSON{Raw: raw}, components, nil
+}
+
+// servicesOf returns the services of the values root. Like the deployment,
+// it accepts them under a services key or at the top level.
+func servicesOf(root map[string]interface{}) map[string]interface{} {
+	if services, ok := root["services"].(map[string]interface{}); ok {
+		return services
+	}
+	return root
+}
+
+// childMap returns the object at key of m, replacing any other value.
+func childMap(m map[string]interface{}, key string) map[string]interface{} {
+	child, ok := m[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		m[key] = child
+	}
+	return child
+}
+
+// decodeResources decodes v into resource requirements if it holds nothing
+// else.
+func decodeResources(v interface{}) (*corev1.ResourceRequirements, bool) {
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, false
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	resources := &corev1.ResourceRequirements{}
+	if err := dec.Decode(resources); err != nil {
+		return nil, false
+	}
+	return resources, true
+}
@@ -0,0 +1,144 @@
+package v1alpha2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestConvertFrom(t *testing.T) {
+	values := `{
+		"iam": {"enabled": true, "values": {"image": {"repository": "ghcr.io/platform-mesh/iam", "tag": "v1.2.3"}, "replicaCount": 2, "resources": {"limits": {"cpu": "500m"}}}},
+		"portal": {"values": {"replicaCount": 1}},
+		"keycloak": {"values": {"replicaCount": "2", "image": {"tag": 1.5}, "resources": {"limits": {"cpu": "500m"}, "unknown": true}}}
+	}`
+	hub := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"},
+		Spec: v1alpha1.PlatformMeshSpec{
+			Exposure: &v1alpha1.ExposureConfig{BaseDomain: "example.com"},
+			Values:   apiextensionsv1.JSON{Raw: []byte(values)},
+			Channel:  "stable",
+		},
+		Status: v1alpha1.PlatformMeshStatus{ObservedGeneration: 3},
+	}
+
+	spoke := &PlatformMesh{}
+	require.NoError(t, spoke.ConvertFrom(hub))
+	assert.Equal(t, hub.ObjectMeta, spoke.ObjectMeta)
+	assert.Equal(t, hub.Spec.Exposure, spoke.Spec.Exposure)
+	assert.Equal(t, "stable", spoke.Spec.Channel)
+	assert.Equal(t, int64(3), spoke.Status.ObservedGeneration)
+	assert.Equal(t, map[string]ComponentOverrides{
+		"iam": {
+			ImageTag:  "v1.2.3",
+			Replicas:  ptr.To[int32](2),
+			Resources: &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+		},
+		"portal": {Replicas: ptr.To[int32](1)},
+	}, spoke.Spec.Components)
+	// Values that do not fit the typed fields stay in values.
+	assert.JSONEq(t, `{
+		"iam": {"enabled": true, "values": {"image": {"repository": "ghcr.io/platform-mesh/iam"}}},
+		"keycloak": {"values": {"replicaCount": "2", "image": {"tag": 1.5}, "resources": {"limits": {"cpu": "500m"}, "unknown": true}}}
+	}`, string(spoke.Spec.Values.Raw))
+
+	roundTripped := &v1alpha1.PlatformMesh{}
+	require.NoError(t, spoke.ConvertTo(roundTripped))
+	assert.JSONEq(t, values, string(roundTripped.Spec.Values.Raw))
+	roundTripped.Spec.Values = hub.Spec.Values
+	assert.Equal(t, hub, roundTripped)
+}
+
+func TestConvertFrom_NoOverrides(t *testing.T) {
+	for _, values := range []string{``, `null`, `[]`, `{"iam":{"enabled":true}}`} {
+		hub := &v1alpha1.PlatformMesh{Spec: v1alpha1.PlatformMeshSpec{Values: apiextensionsv1.JSON{Raw: []byte(values)}}}
+		spoke := &PlatformMesh{}
+		require.NoError(t, spoke.ConvertFrom(hub))
+		assert.Empty(t, spoke.Spec.Components, values)
+		assert.Equal(t, values, string(spoke.Spec.Values.Raw))
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	spoke := &PlatformMesh{Spec: PlatformMeshSpec{
+		Components: map[string]ComponentOverrides{
+			"iam":    {ImageTag: "v2.0.0", Replicas: ptr.To[int32](3)},
+			"portal": {Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}}},
+		},
+		// Services under a services key and overrides that conflict with the
+		// typed fields.
+		Values: apiextensionsv1.JSON{Raw: []byte(`{"services":{"iam":{"enabled":true,"values":{"image":{"tag":"v1.0.0"},"replicaCount":1}}}}`)},
+	}}
+
+	hub := &v1alpha1.PlatformMesh{}
+	require.NoError(t, spoke.ConvertTo(hub))
+	assert.JSONEq(t, `{"services":{
+		"iam": {"enabled": true, "values": {"image": {"tag": "v2.0.0"}, "replicaCount": 3}},
+		"portal": {"values": {"resources": {"requests": {"memory": "128Mi"}}}}
+	}}`, string(hub.Spec.Values.Raw))
+
+	spoke.Spec.Values = apiextensionsv1.JSON{Raw: []byte(`"not an object"`)}
+	assert.Error(t, spoke.ConvertTo(hub))
+}
+
+func TestConvertComponentSwitches(t *testing.T) {
+	spoke := &PlatformMesh{Spec: PlatformMeshSpec{
+		Components: map[string]ComponentOverrides{
+			"iam":    {Enabled: ptr.To(true), ImageTag: "v2.0.0"},
+			"portal": {Enabled: ptr.To(false)},
+		},
+	}}
+
+	hub := &v1alpha1.PlatformMesh{}
+	require.NoError(t, spoke.ConvertTo(hub))
+	assert.Equal(t, map[string]v1alpha1.ComponentSwitch{"iam": {Enabled: true}, "portal": {Enabled: false}}, hub.Spec.Components)
+	// Switches alone do not add values.
+	assert.JSONEq(t, `{"iam": {"values": {"image": {"tag": "v2.0.0"}}}}`, string(hub.Spec.Values.Raw))
+
+	roundTripped := &PlatformMesh{}
+	require.NoError(t, roundTripped.ConvertFrom(hub))
+	assert.Equal(t, spoke.Spec.Components, roundTripped.Spec.Components)
+
+	spoke.Spec.Components = map[string]ComponentOverrides{"portal": {Enabled: ptr.To(false)}}
+	hub = &v1alpha1.PlatformMesh{}
+	require.NoError(t, spoke.ConvertTo(hub))
+	assert.Empty(t, hub.Spec.Values.Raw)
+}
+
+func TestConvertRuntimeClusters(t *testing.T) {
+	clusters := []v1alpha1.RuntimeCluster{{
+		Name:                "eu-1",
+		KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "eu-1-kubeconfig", Key: "kubeconfig"},
+		TemplateSelector:    &v1alpha1.RuntimeTemplateSelector{Components: []string{"iam"}},
+	}}
+	gates := []v1alpha1.ReadinessGate{{Type: v1alpha1.ReadinessGateHTTPProbe, Target: "https://portal.example.com/healthz"}}
+	adoption := &v1alpha1.AdoptionConfig{Enabled: true, Components: []string{"portal"}}
+	windows := []v1alpha1.MaintenanceWindow{{Cron: "0 2 * * sat", Duration: metav1.Duration{Duration: 4 * time.Hour}}}
+	versions := map[string]v1alpha1.ComponentVersions{"iam": {Chart: "0.4.2", Image: "v0.4.2"}}
+	spoke := &PlatformMesh{Spec: PlatformMeshSpec{RuntimeClusters: clusters, ReadinessGates: gates, Adoption: adoption, MaintenanceWindows: windows, Versions: versions}}
+
+	hub := &v1alpha1.PlatformMesh{}
+	require.NoError(t, spoke.ConvertTo(hub))
+	assert.Equal(t, clusters, hub.Spec.RuntimeClusters)
+	assert.Equal(t, gates, hub.Spec.ReadinessGates)
+	assert.Equal(t, adoption, hub.Spec.Adoption)
+	assert.Equal(t, windows, hub.Spec.MaintenanceWindows)
+	assert.Equal(t, versions, hub.Spec.Versions)
+
+	roundTripped := &PlatformMesh{}
+	require.NoError(t, roundTripped.ConvertFrom(hub))
+	assert.Equal(t, clusters, roundTripped.Spec.RuntimeClusters)
+	assert.Equal(t, gates, roundTripped.Spec.ReadinessGates)
+	assert.Equal(t, adoption, roundTripped.Spec.Adoption)
+	assert.Equal(t, windows, roundTripped.Spec.MaintenanceWindows)
+	assert.Equal(t, versions, roundTripped.Spec.Versions)
+}
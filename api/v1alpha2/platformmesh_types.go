@@ -0,0 +1,148 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// PlatformMeshSpec defines the desired state of PlatformMesh. It is the spec
+// of v1alpha1 with the common overrides of component values as typed fields.
+type PlatformMeshSpec struct {
+	Exposure *v1alpha1.ExposureConfig `json:"exposure,omitempty"`
+	Kcp      v1alpha1.Kcp             `json:"kcp,omitempty"`
+	// Components overrides common Helm values of the profile components, by
+	// service name, and switches them on or off. They take precedence over
+	// the same values set in Values.
+	// +optional
+	Components map[string]ComponentOverrides `json:"components,omitempty"`
+	// Values holds all other overrides of the component values, as in
+	// v1alpha1.
+	// +optional
+	Values           apiextensionsv1.JSON         `json:"values,omitempty"`
+	OCM              *v1alpha1.OCMConfig          `json:"ocm,omitempty"`
+	FeatureToggles   []v1alpha1.FeatureToggle     `json:"featureToggles,omitempty"`
+	InfraValues      apiextensionsv1.JSON         `json:"infraValues,omitempty"`
+	Wait             *v1alpha1.WaitConfig         `json:"wait,omitempty"`
+	ProfileConfigMap *v1alpha1.ConfigMapReference `json:"profileConfigMap,omitempty"`
+	// Profiles are profile ConfigMaps deep-merged in order, later profiles
+	// override earlier ones, e.g. a base, an environment and a cluster-local
+	// profile. When set, profileConfigMap is ignored.
+	// +optional
+	Profiles []v1alpha1.ConfigMapReference `json:"profiles,omitempty"`
+	// Channel selects the section under channels in the profile whose infra and
+	// components are merged over the top-level ones, e.g. stable or edge.
+	// +optional
+	Channel string `json:"channel,omitempty"`
+	// +optional
+	Bootstrap *v1alpha1.BootstrapConfig `json:"bootstrap,omitempty"`
+	// TeardownPolicy controls what happens to the kcp workspaces and objects the
+	// operator created when the PlatformMesh is deleted. Delete removes them,
+	// Orphan leaves them behind.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +kubebuilder:default=Orphan
+	// +optional
+	TeardownPolicy v1alpha1.TeardownPolicy `json:"teardownPolicy,omitempty"`
+	// RuntimeClusters are further runtime clusters the runtime and components
+	// runtime templates are rendered for and applied to, next to the runtime
+	// cluster of the operator.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	RuntimeClusters []v1alpha1.RuntimeCluster `json:"runtimeClusters,omitempty"`
+	// ReadinessGates are further checks that must pass before the instance
+	// is Ready. The Wait subroutine evaluates them on each reconcile.
+	// +optional
+	ReadinessGates []v1alpha1.ReadinessGate `json:"readinessGates,omitempty"`
+	// Adoption adopts HelmReleases that deployed profile components before
+	// the operator managed them, instead of installing the components twice.
+	// +optional
+	Adoption *v1alpha1.AdoptionConfig `json:"adoption,omitempty"`
+	// MaintenanceWindows restrict disruptive operations, such as recreating
+	// kcp objects, upgrading components and restarting the operator, to the
+	// windows. Outside of them these operations are deferred while the rest
+	// of the reconciliation goes on. Without windows they run at any time.
+	// +optional
+	MaintenanceWindows []v1alpha1.MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+	// Versions pins the chart and image versions of components by service
+	// name instead of the versions resolved from the OCM component. It takes
+	// precedence over the version block of the service in the profile.
+	// +optional
+	Versions map[string]v1alpha1.ComponentVersions `json:"versions,omitempty"`
+}
+
+// ComponentOverrides are the common Helm values of a component, set in the
+// values of the service in v1alpha1.
+type ComponentOverrides struct {
+	// Enabled switches the component on or off, spec.components in v1alpha1.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// ImageTag is the tag of the component image, values.image.tag.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	ImageTag string `json:"imageTag,omitempty"`
+	// Replicas is the number of replicas of the component, values.replicaCount.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources are the compute resources of the component, values.resources.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpSetupReady')].status",name="KCP",type=string,description="KCP setup status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='KcpSetupReady')].reason",name="KCP_REASON",type=string,description="KCP setup step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProviderSecretsReady')].status",name="SECRET",type=string,description="Provider Secret status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='ProviderSecretsReady')].reason",name="SECRET_REASON",type=string,description="Provider Secret step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentReady')].status",name="DEPLOYMENT",type=string,description="Deployment status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='DeploymentReady')].reason",name="DEPLOYMENT_REASON",type=string,description="Deployment step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WebhooksReady')].status",name="WEBHOOKS",type=string,description="Authorization webhook status",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WebhooksReady')].reason",name="WEBHOOKS_REASON",type=string,description="Authorization webhook step that is not ready",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WaitSubroutine')].status",name="WAIT",type=string,description="Wait status (shows reason if Unknown)",priority=0
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='WaitSubroutine')].reason",name="WAIT_REASON",type=string,description="Wait reason if status is Unknown",priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].status",name="Ready",type=string,description="Shows if resource is ready",priority=0
+
+// PlatformMesh is the Schema for the platform-mesh API
+type PlatformMesh struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PlatformMeshSpec            `json:"spec,omitempty"`
+	Status v1alpha1.PlatformMeshStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PlatformMeshList contains a list of PlatformMesh
+type PlatformMeshList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlatformMesh `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PlatformMesh{}, &PlatformMeshList{})
+}
+
+func (i *PlatformMesh) GetConditions() []metav1.Condition           { return i.Status.Conditions }
+func (i *PlatformMesh) SetConditions(conditions []metav1.Condition) { i.Status.Conditions = conditions }
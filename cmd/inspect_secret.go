@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/secretinspect"
+)
+
+const defaultInspectProbeTimeout = 10 * time.Second
+
+var (
+	inspectKey        string
+	inspectKubeconfig string
+	inspectProbe      bool
+)
+
+var inspectSecretCmd = &cobra.Command{
+	Use:   "inspect-secret <namespace>/<name>",
+	Short: "decode the kubeconfig of a provider or initializer secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, ok := strings.Cut(args[0], "/")
+		if !ok || namespace == "" || name == "" {
+			return fmt.Errorf("expected <namespace>/<name>, got %q", args[0])
+		}
+
+		restCfg, err := ctrl.GetConfig()
+		if inspectKubeconfig != "" {
+			restCfg, err = clientcmd.BuildConfigFromFlags("", inspectKubeconfig)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to load kubeconfig: %w", err)
+		}
+		cl, err := client.New(restCfg, client.Options{Scheme: scheme})
+		if err != nil {
+			return err
+		}
+		secret := &corev1.Secret{}
+		if err := cl.Get(cmd.Context(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+			return err
+		}
+		data, ok := secret.Data[inspectKey]
+		if !ok {
+			return fmt.Errorf("secret %s has no key %q", args[0], inspectKey)
+		}
+
+		report, err := secretinspect.Inspect(data)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if err := secretinspect.Write(out, report, time.Now()); err != nil {
+			return err
+		}
+		if !inspectProbe {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), defaultInspectProbeTimeout)
+		defer cancel()
+		version, err := secretinspect.Probe(ctx, data)
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "%-20s failed: %v\n", "Probe:", err)
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%-20s ok, server version %s\n", "Probe:", version)
+		return err
+	},
+}
@@ -22,43 +22,66 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	mcapiexportprovider "github.com/kcp-dev/multicluster-provider/apiexport"
 	pmcontext "github.com/platform-mesh/golang-commons/context"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 	mcmultiprovider "sigs.k8s.io/multicluster-runtime/providers/multi"
 
 	"github.com/platform-mesh/golang-commons/traces"
 
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/internal/controller"
 	"github.com/platform-mesh/platform-mesh-operator/internal/controller/providers"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventing"
+	"github.com/platform-mesh/platform-mesh-operator/internal/kubeconfig"
+	"github.com/platform-mesh/platform-mesh-operator/internal/loglevel"
+	"github.com/platform-mesh/platform-mesh-operator/internal/rbac"
+	"github.com/platform-mesh/platform-mesh-operator/internal/runbook"
+	webhookv1alpha1 "github.com/platform-mesh/platform-mesh-operator/internal/webhook/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/version"
 )
 
+// inClusterNamespaceFile holds the namespace of the operator pod.
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 var operatorCmd = &cobra.Command{
 	Use:   "operator",
 	Short: "operator to setup platform-mesh",
 	Run:   RunController,
 }
 
-const defaultWaitForKcpAdminKubeconfigPeriod = time.Second * 15
+const (
+	defaultWaitForKcpAdminKubeconfigPeriod = time.Second * 15
+	defaultLogLevelSyncPeriod              = time.Second * 10
+	defaultEventingSendTimeout             = time.Second * 10
+)
 
 func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 	var err error
 
 	ctrl.SetLogger(log.ComponentLogger("controller-runtime").Logr())
 
-	log.Info().Msg("Starting PlatformMesh Operator")
+	log.Info().Str("version", version.Version).Msg("Starting PlatformMesh Operator")
 	defer log.Info().Msg("Shutting down PlatformMesh Operator")
 
 	ctx, _, shutdown := pmcontext.StartContext(log, operatorCfg, defaultCfg.ShutdownTimeout)
@@ -95,51 +118,121 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 
 	log.Info().Msg("Starting manager")
 
-	restCfg := ctrl.GetConfigOrDie()
+	if operatorCfg.Dev.IsEnabled() {
+		setupLog.Info("Running out-of-cluster in dev mode, in-cluster only behaviour is disabled")
+		operatorCfg.ApplyDevDefaults()
+	}
+
+	restCfg := devRestConfigOrDie(operatorCfg.Dev.KubeconfigRuntime)
 	runtimeClient, err := client.New(restCfg, client.Options{Scheme: subroutines.GetClientScheme()})
 	if err != nil {
 		setupLog.Error(err, "unable to create PlatformMesh client")
 		os.Exit(1)
 	}
+	if err := operatorCfg.Controllers.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("invalid controller configuration")
+	}
+	switch operatorCfg.Subroutines.Deployment.IstioScope {
+	case config.IstioScopeLocal, config.IstioScopeAlways:
+	default:
+		log.Fatal().Str("scope", operatorCfg.Subroutines.Deployment.IstioScope).Msg("invalid --subroutines-deployment-istio-scope, must be local or always")
+	}
+	var runtimeReloader, infraReloader *kubeconfig.Reloader
 	if operatorCfg.RemoteRuntime.IsEnabled() {
 		setupLog.Info("Remote PlatformMesh reconciliation enabled, kubeconfig: " + operatorCfg.RemoteRuntime.Kubeconfig)
-		var err error
-		runtimeClient, restCfg, err = subroutines.GetClientAndRestConfig(operatorCfg.RemoteRuntime.Kubeconfig)
-		if err != nil {
-			setupLog.Error(err, "unable to create PlatformMesh client")
-			os.Exit(1)
+		if !operatorCfg.IstioGateEnabled() && operatorCfg.Subroutines.Deployment.EnableIstio {
+			setupLog.Info("Istio gate skipped for the remote runtime, set --subroutines-deployment-istio-scope=always to run it")
 		}
+		runtimeClient, restCfg, runtimeReloader = remoteClientOrDie(operatorCfg.RemoteRuntime)
 	}
 	setupLog.Info(fmt.Sprintf("PlatformMesh Host: %s", restCfg.Host))
+	checkRBAC(ctx, restCfg)
+	if operatorCfg.Subroutines.VersionSkew.Enabled {
+		if err := subroutines.CheckBundleVersionSkew(ctx, runtimeClient, filepath.Join(operatorCfg.WorkspaceDir, "gotemplates"), version.Version); err != nil {
+			log.Fatal().Err(err).Msg("bundle version check failed")
+		}
+	}
 	restCfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
 		return otelhttp.NewTransport(rt)
 	})
 
 	var leaderCfg *rest.Config
-	if defaultCfg.LeaderElectionEnabled {
+	var leaderLock resourcelock.Interface
+	leaderElection := operatorCfg.LeaderElection
+	if defaultCfg.LeaderElectionEnabled && operatorCfg.Dev.IsEnabled() {
+		// There is no in-cluster config out-of-cluster, elect against the runtime cluster
+		leaderCfg = rest.CopyConfig(restCfg)
+		if leaderElection.Namespace == "" {
+			leaderElection.Namespace = operatorCfg.KCP.Namespace
+		}
+	} else if defaultCfg.LeaderElectionEnabled {
 		leaderCfg, err = rest.InClusterConfig()
 		if err != nil {
 			log.Fatal().Err(err).Msg("unable to get in-cluster config")
 		}
 	}
+	if defaultCfg.LeaderElectionEnabled {
+		// Both halves lead in their own pods when they are split.
+		if operatorCfg.Controllers.Split() && operatorCfg.Controllers.Kcp {
+			leaderElection.ID += "-kcp"
+		} else if operatorCfg.Controllers.Split() {
+			leaderElection.ID += "-deployment"
+		}
+		if err := leaderElection.Validate(); err != nil {
+			log.Fatal().Err(err).Msg("invalid leader election configuration")
+		}
+		if leaderLock, err = leaderElectionLock(leaderElection, leaderCfg); err != nil {
+			log.Fatal().Err(err).Msg("unable to create leader election lock")
+		}
+		log.Info().Str("lease", leaderElection.Namespace+"/"+leaderElection.ID).Str("identity", leaderElection.Identity).Msg("Leader election enabled")
+	}
 
 	// NOTE: We are using MC multi provider. When adding new controllers, remember to
 	// set your WithEngageWithLocalCluster and/or WithEngageWithProviderClusters ForOption
 	// in For() [mcbuilder.TypedBuilder] appropriately, so that your reconciler receives
 	// only events for the cluster(s) it is supposed to.
+	var healthReport *subroutines.HealthReport
+	var metricsHandlers map[string]http.Handler
+	if operatorCfg.Controllers.Deployment && operatorCfg.Health.Interval > 0 {
+		healthReport = subroutines.NewHealthReport()
+		metricsHandlers = map[string]http.Handler{"/healthz/components": healthReport}
+	}
+	var webhookServer webhook.Server
+	if operatorCfg.Webhook.Enabled {
+		webhookServer = webhook.NewServer(webhook.Options{
+			Port:    operatorCfg.Webhook.Port,
+			CertDir: operatorCfg.Webhook.CertDir,
+			TLSOpts: tlsOpts,
+		})
+	}
 	mgr, err := mcmanager.New(restCfg, mcmultiprovider.New(mcmultiprovider.Options{}), mcmanager.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress:   defaultCfg.Metrics.BindAddress,
 			SecureServing: defaultCfg.Metrics.Secure,
 			TLSOpts:       tlsOpts,
+			ExtraHandlers: metricsHandlers,
 		},
-		BaseContext:                   func() context.Context { return ctx },
-		HealthProbeBindAddress:        defaultCfg.HealthProbeBindAddress,
-		LeaderElection:                defaultCfg.LeaderElectionEnabled,
-		LeaderElectionID:              "81924e50.platform-mesh.org",
-		LeaderElectionConfig:          leaderCfg,
-		LeaderElectionReleaseOnCancel: true,
+		WebhookServer:                       webhookServer,
+		BaseContext:                         func() context.Context { return ctx },
+		HealthProbeBindAddress:              defaultCfg.HealthProbeBindAddress,
+		LeaderElection:                      defaultCfg.LeaderElectionEnabled,
+		LeaderElectionID:                    leaderElection.ID,
+		LeaderElectionNamespace:             leaderElection.Namespace,
+		LeaderElectionConfig:                leaderCfg,
+		LeaderElectionResourceLockInterface: leaderLock,
+		LeaderElectionReleaseOnCancel:       true,
+		LeaseDuration:                       &leaderElection.LeaseDuration,
+		RenewDeadline:                       &leaderElection.RenewDeadline,
+		RetryPeriod:                         &leaderElection.RetryPeriod,
+		// Only profile ConfigMaps are cached, for the profile watch of the
+		// PlatformMesh controller. All other ConfigMaps are read uncached.
+		Cache: cache.Options{ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Label: subroutines.ProfileCacheSelector()},
+		}},
+		Client: client.Options{Cache: &client.CacheOptions{
+			DisableFor: []client.Object{&corev1.ConfigMap{}},
+		}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -148,7 +241,7 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 
 	log.Info().Msg("Manager successfully created")
 
-	restCfgInfra := ctrl.GetConfigOrDie()
+	restCfgInfra := devRestConfigOrDie(operatorCfg.Dev.KubeconfigInfra)
 	restCfgInfra.Wrap(func(rt http.RoundTripper) http.RoundTripper {
 		return otelhttp.NewTransport(rt)
 	})
@@ -158,10 +251,14 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 		os.Exit(1)
 	}
 	if operatorCfg.RemoteInfra.IsEnabled() {
-		var infraErr error
-		clientInfra, _, infraErr = subroutines.GetClientAndRestConfig(operatorCfg.RemoteInfra.Kubeconfig)
-		if infraErr != nil {
-			setupLog.Error(infraErr, "unable to create Infra client")
+		clientInfra, _, infraReloader = remoteClientOrDie(operatorCfg.RemoteInfra)
+	}
+	for _, reloader := range []*kubeconfig.Reloader{runtimeReloader, infraReloader} {
+		if reloader == nil {
+			continue
+		}
+		if err := mgr.GetLocalManager().Add(reloader); err != nil {
+			setupLog.Error(err, "unable to set up kubeconfig reloader")
 			os.Exit(1)
 		}
 	}
@@ -177,24 +274,42 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 		os.Exit(1)
 	}
 
-	resourceReconciler, err := controller.NewResourceReconciler(mgr, &operatorCfg, clientInfra, imageVersionStore)
-	if err != nil {
-		setupLog.Error(err, "unable to create Resource reconciler")
-		os.Exit(1)
-	}
-	if err := resourceReconciler.SetupWithManager(mgr, defaultCfg); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Resource")
-		os.Exit(1)
+	if operatorCfg.Controllers.Deployment {
+		landscapeReconciler, err := controller.NewPlatformMeshLandscapeReconciler(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create PlatformMeshLandscape reconciler")
+			os.Exit(1)
+		}
+		if err := landscapeReconciler.SetupWithManager(mgr, defaultCfg); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PlatformMeshLandscape")
+			os.Exit(1)
+		}
+
+		resourceReconciler, err := controller.NewResourceReconciler(mgr, &operatorCfg, clientInfra, imageVersionStore)
+		if err != nil {
+			setupLog.Error(err, "unable to create Resource reconciler")
+			os.Exit(1)
+		}
+		if err := resourceReconciler.SetupWithManager(mgr, defaultCfg); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Resource")
+			os.Exit(1)
+		}
 	}
 
-	managedProvidersReconciler, err := providers.NewManagedProviderReconciler(mgr, &operatorCfg, defaultCfg)
-	if err != nil {
-		setupLog.Error(err, "unable to create ManagedProvider reconciler")
-		os.Exit(1)
+	if operatorCfg.Controllers.Kcp {
+		managedProvidersReconciler, err := providers.NewManagedProviderReconciler(mgr, &operatorCfg, defaultCfg)
+		if err != nil {
+			setupLog.Error(err, "unable to create ManagedProvider reconciler")
+			os.Exit(1)
+		}
+		if err := managedProvidersReconciler.SetupWithManager(mgr, defaultCfg); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ManagedProvider")
+			os.Exit(1)
+		}
 	}
-	if err := managedProvidersReconciler.SetupWithManager(mgr, defaultCfg); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ManagedProvider")
-		os.Exit(1)
+
+	if webhookServer != nil {
+		webhookv1alpha1.SetupPlatformMeshWebhookWithServer(webhookServer)
 	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -206,7 +321,96 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 		os.Exit(1)
 	}
 
-	go startProvidersOperator(ctx, runtimeClient, mgr)
+	if operatorCfg.LogLevel.SignalsEnabled {
+		loglevel.Default().HandleSignals(ctx, log)
+	}
+	if operatorCfg.LogLevel.ConfigMapName != "" {
+		watcher := loglevel.NewConfigMapWatcher(mgr.GetLocalManager().GetClient(), types.NamespacedName{
+			Name:      operatorCfg.LogLevel.ConfigMapName,
+			Namespace: operatorCfg.LogLevel.ConfigMapNamespace,
+		}, defaultLogLevelSyncPeriod, loglevel.Default(), log)
+		if err := mgr.GetLocalManager().Add(watcher); err != nil {
+			setupLog.Error(err, "unable to set up log level watcher")
+			os.Exit(1)
+		}
+	}
+
+	if operatorCfg.Runbook.ConfigMapName != "" {
+		watcher := runbook.NewConfigMapWatcher(mgr.GetLocalManager().GetClient(), types.NamespacedName{
+			Name:      operatorCfg.Runbook.ConfigMapName,
+			Namespace: operatorCfg.Runbook.ConfigMapNamespace,
+		}, defaultLogLevelSyncPeriod, runbook.Default(), log)
+		if err := mgr.GetLocalManager().Add(watcher); err != nil {
+			setupLog.Error(err, "unable to set up runbook watcher")
+			os.Exit(1)
+		}
+	}
+
+	kcpUrl := operatorCfg.KCP.Url
+	if kcpUrl == "" {
+		kcpUrl = fmt.Sprintf("https://%s-front-proxy.%s:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.Namespace, operatorCfg.KCP.FrontProxyPort)
+	}
+	if operatorCfg.Controllers.Kcp && operatorCfg.Subroutines.ProviderSecret.Enabled && operatorCfg.Subroutines.ProviderSecret.EndpointSliceResyncInterval > 0 {
+		watcher := subroutines.NewEndpointSliceWatcher(mgr.GetLocalManager().GetClient(), pmReconciler.ReconcileTrigger(), operatorCfg.KCP, kcpUrl,
+			operatorCfg.Subroutines.ProviderSecret.EndpointSliceResyncInterval, log)
+		if err := mgr.GetLocalManager().Add(watcher); err != nil {
+			setupLog.Error(err, "unable to set up APIExportEndpointSlice watcher")
+			os.Exit(1)
+		}
+	}
+
+	if operatorCfg.Controllers.Kcp && operatorCfg.Initializer.Interval > 0 {
+		initializer := subroutines.NewInitializer(mgr.GetLocalManager().GetClient(), operatorCfg.KCP, kcpUrl,
+			operatorCfg.WorkspaceDir, operatorCfg.Initializer.Interval, log)
+		if err := mgr.GetLocalManager().Add(initializer); err != nil {
+			setupLog.Error(err, "unable to set up initializer")
+			os.Exit(1)
+		}
+	}
+
+	if operatorCfg.Controllers.Deployment && operatorCfg.Subroutines.Deployment.Enabled && operatorCfg.Subroutines.Deployment.DriftInterval > 0 {
+		localClient := mgr.GetLocalManager().GetClient()
+		deployment := subroutines.NewDeploymentSubroutine(localClient, clientInfra, defaultCfg, &operatorCfg)
+		deployment.SetImageVersionStore(imageVersionStore)
+		detector := subroutines.NewDriftDetector(localClient, pmReconciler.ReconcileTrigger(), deployment, operatorCfg.Subroutines.Deployment.DriftInterval,
+			operatorCfg.Subroutines.Deployment.DriftAutoCorrect, log)
+		if err := mgr.GetLocalManager().Add(detector); err != nil {
+			setupLog.Error(err, "unable to set up drift detector")
+			os.Exit(1)
+		}
+	}
+
+	if healthReport != nil {
+		aggregator := subroutines.NewHealthAggregator(mgr.GetLocalManager().GetClient(), clientInfra, operatorCfg.KCP,
+			operatorCfg.Health.Interval, healthReport, log)
+		if err := mgr.GetLocalManager().Add(aggregator); err != nil {
+			setupLog.Error(err, "unable to set up health aggregator")
+			os.Exit(1)
+		}
+		if operatorCfg.Health.ReadinessGate {
+			if err := mgr.AddReadyzCheck("components", healthReport.Checker); err != nil {
+				setupLog.Error(err, "unable to set up components ready check")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if operatorCfg.Eventing.SinkURL != "" {
+		emitter := eventing.NewEmitter(eventing.NewHTTPSink(operatorCfg.Eventing.SinkURL, defaultEventingSendTimeout), eventing.Options{
+			Source:     operatorCfg.Eventing.Source,
+			Types:      operatorCfg.Eventing.Types,
+			MaxRetries: operatorCfg.Eventing.MaxRetries,
+		}, log)
+		if err := mgr.GetLocalManager().Add(emitter); err != nil {
+			setupLog.Error(err, "unable to set up event emitter")
+			os.Exit(1)
+		}
+		eventing.SetDefault(emitter)
+	}
+
+	if operatorCfg.Controllers.Kcp {
+		go startProvidersOperator(ctx, runtimeClient, mgr)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -214,6 +418,96 @@ func RunController(_ *cobra.Command, _ []string) { // coverage-ignore
 	}
 }
 
+// checkRBAC verifies that the operator has the permissions its enabled
+// subroutines need on the runtime cluster and logs each missing one.
+func checkRBAC(ctx context.Context, restCfg *rest.Config) { // coverage-ignore
+	mode := operatorCfg.RBAC.SelfCheck
+	switch mode {
+	case config.RBACSelfCheckDisabled:
+		return
+	case config.RBACSelfCheckWarn, config.RBACSelfCheckFail:
+	default:
+		log.Fatal().Str("mode", mode).Msg("invalid --rbac-self-check, must be warn, fail or disabled")
+	}
+
+	cl, err := client.New(restCfg, client.Options{})
+	if err != nil {
+		log.Error().Err(err).Msg("unable to create client for RBAC self-check")
+		return
+	}
+	missing, err := rbac.Missing(ctx, cl, &operatorCfg)
+	if err != nil {
+		log.Error().Err(err).Msg("RBAC self-check failed")
+		return
+	}
+	for _, p := range missing {
+		log.Warn().Str("verb", p.Verb).Str("group", p.Group).Str("resource", p.Resource).
+			Strs("requiredBy", p.RequiredBy).Msg("Missing RBAC permission")
+	}
+	if len(missing) == 0 {
+		log.Info().Msg("RBAC self-check passed")
+		return
+	}
+	if mode == config.RBACSelfCheckFail {
+		log.Fatal().Int("missing", len(missing)).Msg("operator lacks permissions needed by the enabled subroutines, see `platform-mesh-operator rbac`")
+	}
+}
+
+// leaderElectionLock returns the Lease lock holding cfg.Identity, or nil to let
+// the manager create its lock with a generated identity. A lock of its own
+// needs the namespace, which the manager would otherwise find in-cluster.
+func leaderElectionLock(cfg config.LeaderElectionConfig, leaderCfg *rest.Config) (resourcelock.Interface, error) { // coverage-ignore
+	if cfg.Identity == "" {
+		return nil, nil
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		data, err := os.ReadFile(inClusterNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("--leader-election-namespace is required out of cluster: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	return resourcelock.NewFromKubeconfig(resourcelock.LeasesResourceLock, namespace, cfg.ID,
+		resourcelock.ResourceLockConfig{Identity: cfg.Identity}, leaderCfg, cfg.RenewDeadline)
+}
+
+// devRestConfigOrDie loads the kubeconfig given for dev mode, or the default
+// config when it is empty.
+func devRestConfigOrDie(kubeconfig string) *rest.Config { // coverage-ignore
+	if kubeconfig == "" {
+		return ctrl.GetConfigOrDie()
+	}
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		log.Fatal().Err(err).Str("kubeconfig", kubeconfig).Msg("unable to load kubeconfig")
+	}
+	return cfg
+}
+
+// remoteClientOrDie connects to the remote cluster of cfg. With a reload
+// interval the credentials follow the kubeconfig file through the returned
+// Reloader, which has to be added to the manager.
+func remoteClientOrDie(cfg config.RemoteClusterConfig) (client.Client, *rest.Config, *kubeconfig.Reloader) { // coverage-ignore
+	if cfg.ReloadInterval <= 0 {
+		cl, restCfg, err := subroutines.GetClientAndRestConfig(cfg.Kubeconfig)
+		if err != nil {
+			log.Fatal().Err(err).Str("kubeconfig", cfg.Kubeconfig).Msg("unable to create remote cluster client")
+		}
+		return cl, restCfg, nil
+	}
+	reloader, err := kubeconfig.NewReloader(cfg.Kubeconfig, cfg.ReloadInterval, log)
+	if err != nil {
+		log.Fatal().Err(err).Str("kubeconfig", cfg.Kubeconfig).Msg("unable to load remote cluster kubeconfig")
+	}
+	restCfg := reloader.RestConfig()
+	cl, err := client.New(restCfg, client.Options{Scheme: subroutines.GetClientScheme()})
+	if err != nil {
+		log.Fatal().Err(err).Str("kubeconfig", cfg.Kubeconfig).Msg("unable to create remote cluster client")
+	}
+	return cl, restCfg, reloader
+}
+
 func startProvidersOperator(ctx context.Context, runtimeCl client.Client, mgr mcmanager.Manager) {
 	multiProvider := mgr.GetProvider().(*mcmultiprovider.Provider)
 
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/rbac"
+)
+
+var rbacRoleName string
+
+var rbacCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "print the minimal ClusterRole for the given operator flags",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		out, err := yaml.Marshal(rbac.ClusterRole(rbacRoleName, &operatorCfg))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "---\n%s", out)
+		return err
+	},
+}
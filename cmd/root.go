@@ -4,6 +4,7 @@ import (
 	"github.com/go-logr/logr"
 	pmconfig "github.com/platform-mesh/golang-commons/config"
 	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -15,7 +16,9 @@ import (
 	kcpapisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
 	providers1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	corev1alpha2 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha2"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/loglevel"
 )
 
 var (
@@ -35,6 +38,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(corev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(corev1alpha2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 
 	utilruntime.Must(corev1.AddToScheme(scheme))
@@ -43,11 +47,19 @@ func init() {
 	utilruntime.Must(kcpapisv1alpha1.AddToScheme(scheme))
 
 	rootCmd.AddCommand(operatorCmd)
+	rootCmd.AddCommand(rbacCmd)
+	rootCmd.AddCommand(inspectSecretCmd)
 
 	defaultCfg = pmconfig.NewDefaultConfig()
 	operatorCfg = config.NewOperatorConfig()
 	defaultCfg.AddFlags(rootCmd.PersistentFlags())
 	operatorCfg.AddFlags(operatorCmd.Flags())
+	// rbac takes the operator flags to derive the permissions they need.
+	operatorCfg.AddFlags(rbacCmd.Flags())
+	rbacCmd.Flags().StringVar(&rbacRoleName, "role-name", "manager-role", "Name of the generated ClusterRole")
+	inspectSecretCmd.Flags().StringVar(&inspectKey, "key", "kubeconfig", "Key of the secret holding the kubeconfig")
+	inspectSecretCmd.Flags().StringVar(&inspectKubeconfig, "kubeconfig-runtime", "", "Kubeconfig of the runtime cluster holding the secret (defaults to the in-cluster config or KUBECONFIG)")
+	inspectSecretCmd.Flags().BoolVar(&inspectProbe, "probe", false, "Request the server version with the decoded kubeconfig")
 
 	cobra.OnInitialize(initLog)
 }
@@ -62,6 +74,14 @@ func initLog() { // coverage-ignore
 	if err != nil {
 		panic(err)
 	}
+	// The effective level is controlled through zerolog's global level so it
+	// can be changed at runtime, see internal/loglevel.
+	level, err := zerolog.ParseLevel(logcfg.Level)
+	if err != nil {
+		panic(err)
+	}
+	log = log.Level(logger.Level(zerolog.TraceLevel))
+	loglevel.Default().SetGlobal(level)
 	ctrl.SetLogger(log.Logr())
 	setupLog = ctrl.Log.WithName("setup") // coverage-ignore
 }
@@ -1,6 +1,11 @@
 package config
 
-import "github.com/spf13/pflag"
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
 
 type KCPConfig struct {
 	Url                    string
@@ -9,6 +14,9 @@ type KCPConfig struct {
 	FrontProxyName         string
 	FrontProxyPort         string
 	ClusterAdminSecretName string
+	// TLSServerName overrides the server name verified against the kcp serving
+	// certificate, e.g. when Url points to a port-forward on localhost.
+	TLSServerName string
 }
 
 type IDPConfig struct {
@@ -18,40 +26,422 @@ type IDPConfig struct {
 }
 
 type DeploymentSubroutineConfig struct {
-	Enabled                          bool
-	AuthorizationWebhookSecretName   string
-	AuthorizationWebhookSecretCAName string
-	EnableIstio                      bool
+	Enabled                        bool
+	AuthorizationWebhookSecretName string
+	// AuthorizationWebhookSecretNamespace is where the kubeconfig secret kcp
+	// mounts for the authorization webhook lives. Empty means the KCP
+	// namespace.
+	AuthorizationWebhookSecretNamespace string
+	AuthorizationWebhookSecretCAName    string
+	// AuthorizationWebhookSecretCANamespace is where the CA secret of the
+	// webhook certificate lives. Empty means the namespace of the
+	// PlatformMesh.
+	AuthorizationWebhookSecretCANamespace string
+	EnableIstio                           bool
+	// IstioMaxRestarts caps how often the operator restarts its Deployment to
+	// get an istio-proxy injected before it reports IstioInjectionFailed
+	// instead.
+	IstioMaxRestarts int
+	// IstioScope selects where the istio gate runs: local only runs it when
+	// the runtime cluster is the one the operator runs in, always also runs
+	// it with a remote runtime.
+	IstioScope string
+	// OperatorNamespace is the namespace of the operator Deployment that is
+	// restarted to get an istio-proxy injected. Empty means the namespace in
+	// the POD_NAMESPACE environment variable.
+	OperatorNamespace string
+	// OperatorDeploymentName is the operator Deployment that is restarted to
+	// get an istio-proxy injected. Empty means the Deployment owning the pod
+	// in the POD_NAME environment variable.
+	OperatorDeploymentName string
+	// ValuesSnapshots is how many successfully rendered values snapshots are
+	// kept per component for rollbacks. Zero disables snapshots.
+	ValuesSnapshots int
+	// DriftInterval is how often the rendered infra manifests are compared
+	// with the infra cluster. Zero disables drift detection.
+	DriftInterval time.Duration
+	// DriftAutoCorrect requests a reconcile when drift is found, so the
+	// manifests are applied again.
+	DriftAutoCorrect bool
+	// LookupNamespaces are the namespaces the lookup template functions may
+	// read from in addition to the PlatformMesh namespace.
+	LookupNamespaces []string
+	// PruneDisabledComponents deletes the HelmReleases of services that are
+	// disabled in the profile, after their preDelete hooks ran.
+	PruneDisabledComponents bool
+	// PruneOrphanedObjects deletes the objects in the inventory of an
+	// instance that its templates no longer render.
+	PruneOrphanedObjects bool
+	// RenderSnapshots is how many RenderSnapshots are kept per instance.
+	// Zero disables them.
+	RenderSnapshots int
+	// ExportRenderedManifests writes the manifests rendered by each
+	// reconcile to the ConfigMap <instance>-rendered-manifests.
+	ExportRenderedManifests bool
+	// UpgradeMaxUnready is how many component HelmReleases of earlier
+	// upgrade waves may be not Ready while components of later waves are
+	// upgraded. Negative disables the upgrade ordering.
+	UpgradeMaxUnready int
+	// RollbackAfter is how long a component HelmRelease may report
+	// Ready=False after a change before its last Ready spec is applied
+	// again. Zero disables the rollbacks.
+	RollbackAfter time.Duration
+	Validation    RenderValidationConfig
+	Requeue       RequeuePolicy
+}
+
+// RenderValidationConfig selects the policies rendered manifests are checked
+// against before they are applied. Each engine is disabled when its policy
+// directory is empty and skipped when its binary cannot be found.
+type RenderValidationConfig struct {
+	KyvernoPolicyDir string
+	KyvernoBinary    string
+	OPAPolicyDir     string
+	OPABinary        string
+	OPAQuery         string
 }
 
 type KcpSetupSubroutineConfig struct {
 	Enabled                       bool
 	DomainCertificateCASecretName string
 	DomainCertificateCASecretKey  string
+	// APIBindingTimeout is how long an applied APIBinding may stay not ready
+	// after its creation before it is reported as failed. Zero disables the
+	// checks.
+	APIBindingTimeout time.Duration
+	// Shards enables the per-shard setup of all shards of the kcp instance.
+	Shards bool
+	// WebhookProbeTimeout is how long the operator tries to reach each
+	// endpoint of the managed webhook configurations. Zero disables the
+	// probes.
+	WebhookProbeTimeout time.Duration
+	// WebhookProbeInterval is how often the endpoints are probed again,
+	// outside of the reconciliation. Zero disables the probes.
+	WebhookProbeInterval time.Duration
+	// WorkspaceTimeout only sets the warning threshold: how long a kcp
+	// workspace may stay not ready after its creation before a warning is
+	// recorded. The setup never waits for a workspace, it skips its subtree
+	// and requeues. Zero warns after 15s.
+	WorkspaceTimeout time.Duration
+	// RawManifests gates spec.kcp.rawManifests.
+	RawManifests RawManifestsConfig
+	Requeue      RequeuePolicy
+}
+
+// RawManifestsConfig restricts the raw manifests of spec.kcp.rawManifests,
+// which are applied with the permissions of the operator. Empty lists allow
+// nothing.
+type RawManifestsConfig struct {
+	Enabled bool
+	// WorkspacePaths are the workspaces raw manifests may be applied to. A
+	// path ending in :* allows the workspaces below it.
+	WorkspacePaths []string
+	// Kinds are the kinds raw manifests may hold, as Kind for the core group
+	// and Kind.group otherwise.
+	Kinds []string
 }
 
 type ProviderSecretSubroutineConfig struct {
 	Enabled bool
+	// TokenExpiration is the requested lifetime of the ServiceAccount tokens in
+	// scoped provider kubeconfigs.
+	TokenExpiration time.Duration
+	// TokenRenewBefore is how long before its expiry a scoped token is
+	// re-issued.
+	TokenRenewBefore time.Duration
+	// ClientCertIssuerName is the cert-manager Issuer in the KCP namespace that
+	// signs client certificates of scoped connections. It defaults to the
+	// client CA issuer of the root shard, <root shard>-client-ca.
+	ClientCertIssuerName string
+	// EndpointSliceResyncInterval is how often the set of watched
+	// APIExportEndpointSlices is resynced and ended watches are restarted.
+	// Zero disables watching endpoint slices.
+	EndpointSliceResyncInterval time.Duration
+	// MaxConcurrentConnections bounds how many provider connections, and then
+	// how many initializer connections, are handled in parallel during a
+	// reconcile.
+	MaxConcurrentConnections int
+	// RequireRBACApproval holds back the scoped RBAC of provider connections
+	// until its rules are approved on the PlatformMesh.
+	RequireRBACApproval bool
+	// MergedKubeconfig also writes the kubeconfigs of all provider secrets of
+	// an instance as contexts of one kubeconfig into the secret
+	// <instance>-merged-kubeconfig.
+	MergedKubeconfig bool
+	Requeue          RequeuePolicy
 }
 
 type FeatureTogglesSubroutineConfig struct {
 	Enabled bool
+	Requeue RequeuePolicy
 }
 
 type WaitSubroutineConfig struct {
 	Enabled bool
+	Requeue RequeuePolicy
+}
+
+// OpenFGASubroutineConfig configures the bootstrap of the OpenFGA store and
+// authorization model.
+type OpenFGASubroutineConfig struct {
+	Enabled bool
+	// URL is the HTTP endpoint of OpenFGA. It defaults to the openfga service
+	// in the namespace of the PlatformMesh.
+	URL       string
+	StoreName string
+	// ModelConfigMapName is a ConfigMap in the namespace of the PlatformMesh
+	// whose model.json key is written as the authorization model.
+	ModelConfigMapName string
+	// WriteBaseModel writes the embedded authorization model when no
+	// ConfigMap is configured. Without either, no model is written.
+	WriteBaseModel bool
+	Requeue        RequeuePolicy
+}
+
+// IdentitySubroutineConfig configures the provisioning of the Keycloak realm
+// and OIDC clients.
+type IdentitySubroutineConfig struct {
+	Enabled bool
+	// URL is the HTTP endpoint of Keycloak including its relative path. It
+	// defaults to the keycloak service in the namespace of the PlatformMesh.
+	URL   string
+	Realm string
+	// AdminUser, AdminSecretName and AdminSecretKey are the credentials of the
+	// admin user of the master realm. The Secret is read from the namespace
+	// of the PlatformMesh.
+	AdminUser       string
+	AdminSecretName string
+	AdminSecretKey  string
+	Requeue         RequeuePolicy
+}
+
+type VersionSkewSubroutineConfig struct {
+	Enabled bool
+}
+
+type BootstrapSubroutineConfig struct {
+	Enabled bool
+	// MinInterval is the minimum time between two install attempts of the same component.
+	MinInterval time.Duration
+	Requeue     RequeuePolicy
+}
+
+type PrerequisitesSubroutineConfig struct {
+	Enabled bool
+	// Block stops the reconcile while prerequisites are missing instead of
+	// only reporting them.
+	Block   bool
+	Requeue RequeuePolicy
+}
+
+// RequeuePolicy is the backoff of a subroutine waiting for something: the
+// first requeue is after Initial and each consecutive one of the same
+// instance doubles the delay up to Max. Jitter adds up to that fraction of
+// the delay, so instances waiting on the same thing spread out.
+type RequeuePolicy struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64
+}
+
+func DefaultRequeuePolicy() RequeuePolicy {
+	return RequeuePolicy{Initial: 5 * time.Second, Max: 5 * time.Minute, Jitter: 0.1}
+}
+
+func (p *RequeuePolicy) addFlags(fs *pflag.FlagSet, subroutine string) {
+	prefix := "subroutines-" + subroutine + "-requeue-"
+	fs.DurationVar(&p.Initial, prefix+"initial", p.Initial, "First requeue delay of the "+subroutine+" subroutine while it waits")
+	fs.DurationVar(&p.Max, prefix+"max", p.Max, "Maximum requeue delay of the "+subroutine+" subroutine")
+	fs.Float64Var(&p.Jitter, prefix+"jitter", p.Jitter, "Fraction of the requeue delay of the "+subroutine+" subroutine added at random")
+}
+
+// ResourceSubroutineConfig controls how the Resource subroutine writes the
+// versions it resolves into Flux and ArgoCD objects.
+type ResourceSubroutineConfig struct {
+	// ApplyStrategy is server-side to apply only the resolved fields with
+	// server-side apply, or update to modify and update the whole object.
+	ApplyStrategy string
+	// ForceConflicts lists the kinds whose conflicting fields are taken over
+	// from other field managers. Applies to other kinds fail on conflicts.
+	ForceConflicts []string
+	Requeue        RequeuePolicy
+}
+
+const (
+	ApplyStrategyServerSide = "server-side"
+	ApplyStrategyUpdate     = "update"
+)
+
+const (
+	IstioScopeLocal  = "local"
+	IstioScopeAlways = "always"
+)
+
+// IstioGateEnabled reports whether the Deployment subroutine waits for istiod
+// and an istio-proxy in the operator pod. With a remote runtime that has its
+// own mesh the gate is skipped unless the scope is always.
+func (c *OperatorConfig) IstioGateEnabled() bool {
+	d := c.Subroutines.Deployment
+	if !d.EnableIstio {
+		return false
+	}
+	return d.IstioScope == IstioScopeAlways || !c.RemoteRuntime.IsEnabled()
 }
 
 type RemoteClusterConfig struct {
 	Kubeconfig      string
 	InfraSecretName string
 	InfraSecretKey  string
+	// ReloadInterval is how often the kubeconfig file is checked for rotated
+	// credentials. Zero loads it once at startup.
+	ReloadInterval time.Duration
 }
 
 func (r *RemoteClusterConfig) IsEnabled() bool {
 	return r.Kubeconfig != ""
 }
 
+// DevConfig runs the operator out-of-cluster against the clusters of the given
+// kubeconfigs. Unlike RemoteRuntime it does not change what is deployed.
+type DevConfig struct {
+	KubeconfigInfra   string
+	KubeconfigRuntime string
+}
+
+func (d *DevConfig) IsEnabled() bool {
+	return d.KubeconfigInfra != "" || d.KubeconfigRuntime != ""
+}
+
+// ApplyDevDefaults fills in settings for running out-of-cluster. A KCP URL set in
+// dev mode is usually a port-forward, so the serving certificate is verified
+// against the in-cluster front-proxy service name instead.
+func (c *OperatorConfig) ApplyDevDefaults() {
+	if !c.Dev.IsEnabled() || c.KCP.Url == "" || c.KCP.TLSServerName != "" {
+		return
+	}
+	c.KCP.TLSServerName = c.KCP.FrontProxyName + "-front-proxy." + c.KCP.Namespace
+}
+
+type LogLevelConfig struct {
+	ConfigMapName      string
+	ConfigMapNamespace string
+	SignalsEnabled     bool
+}
+
+// RunbookConfig locates the ConfigMap mapping condition and event reasons to
+// runbook references.
+type RunbookConfig struct {
+	ConfigMapName      string
+	ConfigMapNamespace string
+}
+
+type EventingConfig struct {
+	// SinkURL is the HTTP endpoint receiving CloudEvents; eventing is disabled when empty.
+	SinkURL    string
+	Source     string
+	Types      []string
+	MaxRetries int
+}
+
+// EventsConfig bounds the Kubernetes events the operator records on the
+// PlatformMesh.
+type EventsConfig struct {
+	// QueueSize bounds the events waiting to be recorded.
+	QueueSize int
+	// DedupeWindow is how long repeats of an event are counted instead of
+	// recorded. Zero records every repeat.
+	DedupeWindow time.Duration
+	// RateLimit is the number of events recorded per reason and
+	// RateLimitInterval. Zero disables the limit.
+	RateLimit         int
+	RateLimitInterval time.Duration
+}
+
+// HealthConfig controls the health aggregator probing the components the
+// operator deploys.
+type HealthConfig struct {
+	// Interval is how often the components are probed. Zero disables the
+	// health aggregator.
+	Interval time.Duration
+	// ReadinessGate fails the readiness probe of the operator while
+	// components of any instance are unhealthy.
+	ReadinessGate bool
+}
+
+// InitializerConfig controls the initializer loop running the WorkspaceType
+// initializers of spec.kcp.initializers.
+type InitializerConfig struct {
+	// Interval is how often logical clusters waiting for an initializer are
+	// looked for. Zero, the default, disables the initializer loop.
+	Interval time.Duration
+}
+
+const (
+	RBACSelfCheckWarn     = "warn"
+	RBACSelfCheckFail     = "fail"
+	RBACSelfCheckDisabled = "disabled"
+)
+
+type RBACConfig struct {
+	// SelfCheck controls the startup check of the permissions needed by the
+	// enabled subroutines: warn logs missing ones, fail also exits.
+	SelfCheck string
+}
+
+// LeaderElectionConfig configures the Lease replicas of the operator elect
+// their leader with. Only the leader reconciles.
+type LeaderElectionConfig struct {
+	// ID is the name of the Lease.
+	ID string
+	// Namespace of the Lease, the namespace of the operator pod when empty.
+	Namespace string
+	// Identity of this replica in the Lease, the hostname with a random
+	// suffix when empty.
+	Identity string
+	// LeaseDuration is how long followers wait before taking over a Lease
+	// that was not renewed.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader tries to renew the Lease before it
+	// gives up leadership.
+	RenewDeadline time.Duration
+	// RetryPeriod is how often renewals and acquisitions are tried.
+	RetryPeriod time.Duration
+}
+
+// ControllersConfig selects the halves of the PlatformMesh reconciliation the
+// operator runs. With only one of them enabled, the halves run in separate
+// pods and coordinate through the status of the PlatformMesh.
+type ControllersConfig struct {
+	// Kcp runs the KcpSetup, ProviderSecret and FeatureToggles subroutines
+	// and the loops working on kcp.
+	Kcp bool
+	// Deployment runs the subroutines rendering and deploying the components
+	// and the loops working on the infra cluster.
+	Deployment bool
+}
+
+// Split reports whether only one half runs in this operator.
+func (c ControllersConfig) Split() bool {
+	return c.Kcp != c.Deployment
+}
+
+// Validate reports a configuration that runs neither half.
+func (c ControllersConfig) Validate() error {
+	if !c.Kcp && !c.Deployment {
+		return fmt.Errorf("at least one of --enable-kcp-controller and --enable-deployment-controller must be set")
+	}
+	return nil
+}
+
+// WebhookConfig configures the admission webhook server of the operator.
+type WebhookConfig struct {
+	// Enabled serves the defaulting webhook of PlatformMesh.
+	Enabled bool
+	Port    int
+	// CertDir holds tls.crt and tls.key of the webhook server.
+	CertDir string
+}
+
 type ManagedProviderSubroutineConfig struct {
 	Enabled bool
 }
@@ -66,6 +456,8 @@ type ManagedProviderSubroutinesConfig struct {
 	WaitProvider     ManagedProviderSubroutineConfig
 	KubeconfigCopy   ManagedProviderSubroutineConfig
 	Deploy           ManagedProviderSubroutineConfig
+	// Requeue is shared by the subroutines of a ManagedProvider.
+	Requeue RequeuePolicy
 }
 
 type SubroutinesConfig struct {
@@ -74,19 +466,36 @@ type SubroutinesConfig struct {
 	ProviderSecret  ProviderSecretSubroutineConfig
 	FeatureToggles  FeatureTogglesSubroutineConfig
 	Wait            WaitSubroutineConfig
+	VersionSkew     VersionSkewSubroutineConfig
+	Bootstrap       BootstrapSubroutineConfig
+	Prerequisites   PrerequisitesSubroutineConfig
+	OpenFGA         OpenFGASubroutineConfig
+	Identity        IdentitySubroutineConfig
+	Resource        ResourceSubroutineConfig
 	ManagedProvider ManagedProviderSubroutinesConfig
 	Provider        ProviderSubroutinesConfig
 }
 
 // OperatorConfig struct to hold the app config
 type OperatorConfig struct {
-	WorkspaceDir  string
-	KCP           KCPConfig
-	IDP           IDPConfig
-	Subroutines   SubroutinesConfig
-	RemoteRuntime RemoteClusterConfig
-	RemoteInfra   RemoteClusterConfig
-	Providers     ProvidersConfig
+	WorkspaceDir   string
+	KCP            KCPConfig
+	IDP            IDPConfig
+	Subroutines    SubroutinesConfig
+	RemoteRuntime  RemoteClusterConfig
+	RemoteInfra    RemoteClusterConfig
+	Dev            DevConfig
+	Providers      ProvidersConfig
+	LogLevel       LogLevelConfig
+	Runbook        RunbookConfig
+	Eventing       EventingConfig
+	Events         EventsConfig
+	Health         HealthConfig
+	Initializer    InitializerConfig
+	RBAC           RBACConfig
+	Webhook        WebhookConfig
+	LeaderElection LeaderElectionConfig
+	Controllers    ControllersConfig
 }
 
 func NewOperatorConfig() OperatorConfig {
@@ -100,26 +509,119 @@ func NewOperatorConfig() OperatorConfig {
 			ClusterAdminSecretName: "kcp-cluster-admin-client-cert",
 		},
 		Providers: NewProvidersConfig(),
+		RemoteRuntime: RemoteClusterConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		RemoteInfra: RemoteClusterConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		LogLevel: LogLevelConfig{
+			ConfigMapNamespace: "platform-mesh-system",
+			SignalsEnabled:     true,
+		},
+		Runbook: RunbookConfig{
+			ConfigMapNamespace: "platform-mesh-system",
+		},
+		Eventing: EventingConfig{
+			Source:     "platform-mesh-operator",
+			MaxRetries: 5,
+		},
+		Events: EventsConfig{
+			QueueSize:         1000,
+			DedupeWindow:      10 * time.Minute,
+			RateLimit:         30,
+			RateLimitInterval: time.Minute,
+		},
+		Health: HealthConfig{
+			Interval: time.Minute,
+		},
+		RBAC: RBACConfig{
+			SelfCheck: RBACSelfCheckWarn,
+		},
+		Webhook: WebhookConfig{
+			Port: 9443,
+		},
+		LeaderElection: LeaderElectionConfig{
+			ID:            "81924e50.platform-mesh.org",
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		},
+		Controllers: ControllersConfig{
+			Kcp:        true,
+			Deployment: true,
+		},
 		Subroutines: SubroutinesConfig{
 			Deployment: DeploymentSubroutineConfig{
 				Enabled:                          true,
 				AuthorizationWebhookSecretName:   "kcp-webhook-secret",
 				AuthorizationWebhookSecretCAName: "rebac-authz-webhook-cert",
 				EnableIstio:                      true,
+				IstioMaxRestarts:                 3,
+				IstioScope:                       IstioScopeLocal,
+				ValuesSnapshots:                  3,
+				RenderSnapshots:                  5,
+				DriftInterval:                    10 * time.Minute,
+				Requeue:                          DefaultRequeuePolicy(),
+				Validation: RenderValidationConfig{
+					KyvernoBinary: "kyverno",
+					OPABinary:     "opa",
+					OPAQuery:      "data.platformmesh.deny",
+				},
 			},
 			KcpSetup: KcpSetupSubroutineConfig{
 				Enabled:                       true,
 				DomainCertificateCASecretName: "domain-certificate",
 				DomainCertificateCASecretKey:  "ca.crt",
+				APIBindingTimeout:             30 * time.Second,
+				WebhookProbeTimeout:           5 * time.Second,
+				WebhookProbeInterval:          5 * time.Minute,
+				WorkspaceTimeout:              2 * time.Minute,
+				Requeue:                       DefaultRequeuePolicy(),
 			},
 			ProviderSecret: ProviderSecretSubroutineConfig{
-				Enabled: true,
+				Enabled:                     true,
+				TokenExpiration:             7 * 24 * time.Hour,
+				TokenRenewBefore:            24 * time.Hour,
+				EndpointSliceResyncInterval: time.Minute,
+				MaxConcurrentConnections:    4,
+				Requeue:                     DefaultRequeuePolicy(),
 			},
 			FeatureToggles: FeatureTogglesSubroutineConfig{
 				Enabled: false,
+				Requeue: DefaultRequeuePolicy(),
 			},
 			Wait: WaitSubroutineConfig{
 				Enabled: true,
+				Requeue: DefaultRequeuePolicy(),
+			},
+			VersionSkew: VersionSkewSubroutineConfig{
+				Enabled: true,
+			},
+			Bootstrap: BootstrapSubroutineConfig{
+				Enabled:     true,
+				MinInterval: 10 * time.Minute,
+				Requeue:     DefaultRequeuePolicy(),
+			},
+			Prerequisites: PrerequisitesSubroutineConfig{
+				Enabled: true,
+				Requeue: DefaultRequeuePolicy(),
+			},
+			OpenFGA: OpenFGASubroutineConfig{
+				StoreName: "platform-mesh",
+				Requeue:   DefaultRequeuePolicy(),
+			},
+			Identity: IdentitySubroutineConfig{
+				Realm:           "platform-mesh",
+				AdminUser:       "keycloak-admin",
+				AdminSecretName: "keycloak-admin",
+				AdminSecretKey:  "secret",
+				Requeue:         DefaultRequeuePolicy(),
+			},
+			Resource: ResourceSubroutineConfig{
+				ApplyStrategy:  ApplyStrategyServerSide,
+				ForceConflicts: []string{"Application", "GitRepository", "HelmRelease", "HelmRepository", "OCIRepository"},
+				Requeue:        DefaultRequeuePolicy(),
 			},
 			ManagedProvider: ManagedProviderSubroutinesConfig{
 				WaitPlatformMesh: ManagedProviderSubroutineConfig{Enabled: true},
@@ -127,10 +629,12 @@ func NewOperatorConfig() OperatorConfig {
 				WaitProvider:     ManagedProviderSubroutineConfig{Enabled: true},
 				KubeconfigCopy:   ManagedProviderSubroutineConfig{Enabled: true},
 				Deploy:           ManagedProviderSubroutineConfig{Enabled: true},
+				Requeue:          DefaultRequeuePolicy(),
 			},
 			Provider: ProviderSubroutinesConfig{
 				Workspace:  ProviderSubroutineConfig{Enabled: true},
 				Kubeconfig: ProviderSubroutineConfig{Enabled: true},
+				Requeue:    DefaultRequeuePolicy(),
 			},
 		},
 	}
@@ -140,6 +644,7 @@ func (c *OperatorConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.WorkspaceDir, "workspace-dir", c.WorkspaceDir, "Set workspace directory")
 
 	fs.StringVar(&c.KCP.Url, "kcp-url", c.KCP.Url, "Set KCP URL")
+	fs.StringVar(&c.KCP.TLSServerName, "kcp-tls-server-name", c.KCP.TLSServerName, "Server name to verify the KCP certificate against (defaults to the front-proxy service in dev mode)")
 	fs.StringVar(&c.KCP.Namespace, "kcp-namespace", c.KCP.Namespace, "Set KCP namespace")
 	fs.StringVar(&c.KCP.RootShardName, "kcp-root-shard-name", c.KCP.RootShardName, "Set KCP root shard name")
 	fs.StringVar(&c.KCP.FrontProxyName, "kcp-front-proxy-name", c.KCP.FrontProxyName, "Set KCP front-proxy name")
@@ -152,23 +657,89 @@ func (c *OperatorConfig) AddFlags(fs *pflag.FlagSet) {
 
 	fs.BoolVar(&c.Subroutines.Deployment.Enabled, "subroutines-deployment-enabled", c.Subroutines.Deployment.Enabled, "Enable deployment subroutine")
 	fs.StringVar(&c.Subroutines.Deployment.AuthorizationWebhookSecretName, "authorization-webhook-secret-name", c.Subroutines.Deployment.AuthorizationWebhookSecretName, "Authorization webhook secret name")
+	fs.StringVar(&c.Subroutines.Deployment.AuthorizationWebhookSecretNamespace, "authorization-webhook-secret-namespace", c.Subroutines.Deployment.AuthorizationWebhookSecretNamespace, "Authorization webhook secret namespace (defaults to the KCP namespace)")
 	fs.StringVar(&c.Subroutines.Deployment.AuthorizationWebhookSecretCAName, "authorization-webhook-secret-ca-name", c.Subroutines.Deployment.AuthorizationWebhookSecretCAName, "Authorization webhook CA secret name")
+	fs.StringVar(&c.Subroutines.Deployment.AuthorizationWebhookSecretCANamespace, "authorization-webhook-secret-ca-namespace", c.Subroutines.Deployment.AuthorizationWebhookSecretCANamespace, "Authorization webhook CA secret namespace (defaults to the PlatformMesh namespace)")
 	fs.BoolVar(&c.Subroutines.Deployment.EnableIstio, "subroutines-deployment-enable-istio", c.Subroutines.Deployment.EnableIstio, "Enable Istio integration in deployment subroutine")
+	fs.IntVar(&c.Subroutines.Deployment.IstioMaxRestarts, "subroutines-deployment-istio-max-restarts", c.Subroutines.Deployment.IstioMaxRestarts, "Maximum number of operator Deployment restarts to get an istio-proxy injected")
+	fs.StringVar(&c.Subroutines.Deployment.IstioScope, "subroutines-deployment-istio-scope", c.Subroutines.Deployment.IstioScope, "Where the istio gate runs: local skips it with a remote runtime, always runs it regardless")
+	fs.StringVar(&c.Subroutines.Deployment.OperatorNamespace, "subroutines-deployment-operator-namespace", c.Subroutines.Deployment.OperatorNamespace, "Namespace of the operator Deployment restarted for istio-proxy injection (defaults to $POD_NAMESPACE)")
+	fs.StringVar(&c.Subroutines.Deployment.OperatorDeploymentName, "subroutines-deployment-operator-deployment-name", c.Subroutines.Deployment.OperatorDeploymentName, "Operator Deployment restarted for istio-proxy injection (defaults to the owner of the pod $POD_NAME)")
+	fs.IntVar(&c.Subroutines.Deployment.ValuesSnapshots, "subroutines-deployment-values-snapshots", c.Subroutines.Deployment.ValuesSnapshots, "Successfully rendered values snapshots kept per component for rollbacks (0 disables them)")
+	fs.DurationVar(&c.Subroutines.Deployment.DriftInterval, "subroutines-deployment-drift-interval", c.Subroutines.Deployment.DriftInterval, "How often rendered infra manifests are compared with the infra cluster (0 disables drift detection)")
+	fs.BoolVar(&c.Subroutines.Deployment.DriftAutoCorrect, "subroutines-deployment-drift-auto-correct", c.Subroutines.Deployment.DriftAutoCorrect, "Request a reconcile that applies the manifests again when drift is found")
+	fs.StringSliceVar(&c.Subroutines.Deployment.LookupNamespaces, "subroutines-deployment-lookup-namespaces", c.Subroutines.Deployment.LookupNamespaces, "Namespaces the lookup template functions may read from in addition to the PlatformMesh namespace (comma-separated)")
+	fs.BoolVar(&c.Subroutines.Deployment.PruneDisabledComponents, "subroutines-deployment-prune-disabled-components", c.Subroutines.Deployment.PruneDisabledComponents, "Delete the HelmReleases of components disabled in the profile after running their preDelete hooks")
+	fs.BoolVar(&c.Subroutines.Deployment.PruneOrphanedObjects, "subroutines-deployment-prune-orphaned-objects", c.Subroutines.Deployment.PruneOrphanedObjects, "Delete the applied objects the templates of an instance no longer render")
+	fs.IntVar(&c.Subroutines.Deployment.RenderSnapshots, "subroutines-deployment-render-snapshots", c.Subroutines.Deployment.RenderSnapshots, "RenderSnapshots kept per instance (0 disables them)")
+	fs.BoolVar(&c.Subroutines.Deployment.ExportRenderedManifests, "subroutines-deployment-export-rendered-manifests", c.Subroutines.Deployment.ExportRenderedManifests, "Write the manifests rendered by each reconcile to the ConfigMap <instance>-rendered-manifests for debugging")
+	fs.IntVar(&c.Subroutines.Deployment.UpgradeMaxUnready, "subroutines-deployment-upgrade-max-unready", c.Subroutines.Deployment.UpgradeMaxUnready, "Component HelmReleases of earlier upgrade waves that may be not Ready while later components are upgraded (negative disables upgrade ordering)")
+	fs.DurationVar(&c.Subroutines.Deployment.RollbackAfter, "subroutines-deployment-rollback-after", c.Subroutines.Deployment.RollbackAfter, "How long a changed component HelmRelease may report Ready=False before its last Ready spec is applied again (0 disables rollbacks)")
+	fs.StringVar(&c.Subroutines.Deployment.Validation.KyvernoPolicyDir, "render-validation-kyverno-policy-dir", c.Subroutines.Deployment.Validation.KyvernoPolicyDir, "Directory of kyverno policies checked against rendered manifests before apply (disabled when empty)")
+	fs.StringVar(&c.Subroutines.Deployment.Validation.KyvernoBinary, "render-validation-kyverno-binary", c.Subroutines.Deployment.Validation.KyvernoBinary, "Path of the kyverno CLI (kyverno validation is skipped when it cannot be found)")
+	fs.StringVar(&c.Subroutines.Deployment.Validation.OPAPolicyDir, "render-validation-opa-policy-dir", c.Subroutines.Deployment.Validation.OPAPolicyDir, "Directory of rego policies checked against rendered manifests before apply (disabled when empty)")
+	fs.StringVar(&c.Subroutines.Deployment.Validation.OPABinary, "render-validation-opa-binary", c.Subroutines.Deployment.Validation.OPABinary, "Path of the opa CLI (opa validation is skipped when it cannot be found)")
+	fs.StringVar(&c.Subroutines.Deployment.Validation.OPAQuery, "render-validation-opa-query", c.Subroutines.Deployment.Validation.OPAQuery, "Rego query returning the deny messages for a rendered object")
+	c.Subroutines.Deployment.Requeue.addFlags(fs, "deployment")
 
 	fs.BoolVar(&c.Subroutines.KcpSetup.Enabled, "subroutines-kcp-setup-enabled", c.Subroutines.KcpSetup.Enabled, "Enable KCP setup subroutine")
 	fs.StringVar(&c.Subroutines.KcpSetup.DomainCertificateCASecretName, "domain-certificate-ca-secret-name", c.Subroutines.KcpSetup.DomainCertificateCASecretName, "Domain certificate secret name")
 	fs.StringVar(&c.Subroutines.KcpSetup.DomainCertificateCASecretKey, "domain-certificate-ca-secret-key", c.Subroutines.KcpSetup.DomainCertificateCASecretKey, "Domain certificate secret key")
+	fs.DurationVar(&c.Subroutines.KcpSetup.APIBindingTimeout, "subroutines-kcp-setup-api-binding-timeout", c.Subroutines.KcpSetup.APIBindingTimeout, "How long an applied APIBinding may stay not ready after its creation before it is reported as failed (0 disables the checks)")
+	fs.BoolVar(&c.Subroutines.KcpSetup.Shards, "subroutines-kcp-setup-shards", c.Subroutines.KcpSetup.Shards, "Collect APIExport identity hashes and apply the shard manifests on every kcp shard")
+	fs.DurationVar(&c.Subroutines.KcpSetup.WebhookProbeTimeout, "subroutines-kcp-setup-webhook-probe-timeout", c.Subroutines.KcpSetup.WebhookProbeTimeout, "How long each endpoint of the managed kcp webhook configurations is probed (0 disables the probes)")
+	fs.DurationVar(&c.Subroutines.KcpSetup.WebhookProbeInterval, "subroutines-kcp-setup-webhook-probe-interval", c.Subroutines.KcpSetup.WebhookProbeInterval, "How often the endpoints of the managed kcp webhook configurations are probed again (0 disables the probes)")
+	fs.DurationVar(&c.Subroutines.KcpSetup.WorkspaceTimeout, "subroutines-kcp-setup-workspace-timeout", c.Subroutines.KcpSetup.WorkspaceTimeout, "Warning threshold only: how long a kcp workspace may stay not ready after its creation before a WorkspaceNotReady warning is recorded. The setup never waits for workspaces (0 warns after 15s)")
+	fs.BoolVar(&c.Subroutines.KcpSetup.RawManifests.Enabled, "subroutines-kcp-setup-raw-manifests-enabled", c.Subroutines.KcpSetup.RawManifests.Enabled, "Apply spec.kcp.rawManifests of PlatformMesh instances")
+	fs.StringSliceVar(&c.Subroutines.KcpSetup.RawManifests.WorkspacePaths, "subroutines-kcp-setup-raw-manifests-workspace-paths", c.Subroutines.KcpSetup.RawManifests.WorkspacePaths, "Workspaces raw manifests may be applied to, a trailing :* allows the workspaces below (comma-separated)")
+	fs.StringSliceVar(&c.Subroutines.KcpSetup.RawManifests.Kinds, "subroutines-kcp-setup-raw-manifests-kinds", c.Subroutines.KcpSetup.RawManifests.Kinds, "Kinds raw manifests may hold, as Kind for the core group and Kind.group otherwise (comma-separated)")
+	c.Subroutines.KcpSetup.Requeue.addFlags(fs, "kcp-setup")
 
 	fs.BoolVar(&c.Subroutines.ProviderSecret.Enabled, "subroutines-provider-secret-enabled", c.Subroutines.ProviderSecret.Enabled, "Enable provider secret subroutine")
+	fs.DurationVar(&c.Subroutines.ProviderSecret.TokenExpiration, "subroutines-provider-secret-token-expiration", c.Subroutines.ProviderSecret.TokenExpiration, "Requested lifetime of ServiceAccount tokens in scoped provider kubeconfigs")
+	fs.DurationVar(&c.Subroutines.ProviderSecret.TokenRenewBefore, "subroutines-provider-secret-token-renew-before", c.Subroutines.ProviderSecret.TokenRenewBefore, "Time before expiry at which a scoped provider token is re-issued")
+	fs.StringVar(&c.Subroutines.ProviderSecret.ClientCertIssuerName, "subroutines-provider-secret-client-cert-issuer", c.Subroutines.ProviderSecret.ClientCertIssuerName, "cert-manager Issuer in the KCP namespace signing client certificates of scoped provider connections (defaults to <root shard>-client-ca)")
+	fs.DurationVar(&c.Subroutines.ProviderSecret.EndpointSliceResyncInterval, "subroutines-provider-secret-endpoint-slice-resync-interval", c.Subroutines.ProviderSecret.EndpointSliceResyncInterval, "Interval at which watches of APIExportEndpointSlices used by provider connections are resynced (0 disables the watches)")
+	fs.IntVar(&c.Subroutines.ProviderSecret.MaxConcurrentConnections, "subroutines-provider-secret-max-concurrent-connections", c.Subroutines.ProviderSecret.MaxConcurrentConnections, "Provider or initializer connections handled in parallel during a reconcile")
+	fs.BoolVar(&c.Subroutines.ProviderSecret.RequireRBACApproval, "subroutines-provider-secret-require-rbac-approval", c.Subroutines.ProviderSecret.RequireRBACApproval, "Propose the scoped RBAC rules of provider connections in the status and only grant them once approved")
+	fs.BoolVar(&c.Subroutines.ProviderSecret.MergedKubeconfig, "subroutines-provider-secret-merged-kubeconfig", c.Subroutines.ProviderSecret.MergedKubeconfig, "Also write all provider kubeconfigs of an instance as contexts of one kubeconfig into the secret <instance>-merged-kubeconfig")
+	c.Subroutines.ProviderSecret.Requeue.addFlags(fs, "provider-secret")
 	fs.BoolVar(&c.Subroutines.FeatureToggles.Enabled, "subroutines-feature-toggles-enabled", c.Subroutines.FeatureToggles.Enabled, "Enable feature toggles subroutine")
+	c.Subroutines.FeatureToggles.Requeue.addFlags(fs, "feature-toggles")
 	fs.BoolVar(&c.Subroutines.Wait.Enabled, "subroutines-wait-enabled", c.Subroutines.Wait.Enabled, "Enable wait subroutine")
+	c.Subroutines.Wait.Requeue.addFlags(fs, "wait")
+	fs.BoolVar(&c.Subroutines.VersionSkew.Enabled, "subroutines-version-skew-enabled", c.Subroutines.VersionSkew.Enabled, "Enable version skew guard subroutine")
+	fs.BoolVar(&c.Subroutines.Bootstrap.Enabled, "subroutines-bootstrap-enabled", c.Subroutines.Bootstrap.Enabled, "Enable bootstrap subroutine for spec.bootstrap")
+	fs.DurationVar(&c.Subroutines.Bootstrap.MinInterval, "subroutines-bootstrap-min-interval", c.Subroutines.Bootstrap.MinInterval, "Minimum interval between install attempts of a bootstrap component")
+	c.Subroutines.Bootstrap.Requeue.addFlags(fs, "bootstrap")
+	fs.BoolVar(&c.Subroutines.Prerequisites.Enabled, "subroutines-prerequisites-enabled", c.Subroutines.Prerequisites.Enabled, "Enable the prerequisites subroutine that reports missing inputs of enabled features")
+	fs.BoolVar(&c.Subroutines.Prerequisites.Block, "subroutines-prerequisites-block", c.Subroutines.Prerequisites.Block, "Stop reconciling while prerequisites are missing")
+	c.Subroutines.Prerequisites.Requeue.addFlags(fs, "prerequisites")
+	fs.BoolVar(&c.Subroutines.OpenFGA.Enabled, "subroutines-openfga-enabled", c.Subroutines.OpenFGA.Enabled, "Enable the subroutine that creates the OpenFGA store and writes the configured authorization model")
+	fs.StringVar(&c.Subroutines.OpenFGA.URL, "subroutines-openfga-url", c.Subroutines.OpenFGA.URL, "HTTP endpoint of OpenFGA (defaults to http://openfga.<namespace>:8080)")
+	fs.StringVar(&c.Subroutines.OpenFGA.StoreName, "subroutines-openfga-store-name", c.Subroutines.OpenFGA.StoreName, "Name of the OpenFGA store")
+	fs.StringVar(&c.Subroutines.OpenFGA.ModelConfigMapName, "subroutines-openfga-model-configmap", c.Subroutines.OpenFGA.ModelConfigMapName, "ConfigMap whose model.json key is written as the authorization model")
+	fs.BoolVar(&c.Subroutines.OpenFGA.WriteBaseModel, "subroutines-openfga-write-base-model", c.Subroutines.OpenFGA.WriteBaseModel, "Write the authorization model embedded in the operator when no model ConfigMap is configured")
+	c.Subroutines.OpenFGA.Requeue.addFlags(fs, "openfga")
+	fs.BoolVar(&c.Subroutines.Identity.Enabled, "subroutines-identity-enabled", c.Subroutines.Identity.Enabled, "Enable the subroutine that provisions the Keycloak realm and OIDC clients once keycloak is ready")
+	fs.StringVar(&c.Subroutines.Identity.URL, "subroutines-identity-keycloak-url", c.Subroutines.Identity.URL, "HTTP endpoint of Keycloak (defaults to http://keycloak.<namespace>/keycloak)")
+	fs.StringVar(&c.Subroutines.Identity.Realm, "subroutines-identity-realm", c.Subroutines.Identity.Realm, "Keycloak realm holding the OIDC clients")
+	fs.StringVar(&c.Subroutines.Identity.AdminUser, "subroutines-identity-admin-user", c.Subroutines.Identity.AdminUser, "Admin user of the Keycloak master realm")
+	fs.StringVar(&c.Subroutines.Identity.AdminSecretName, "subroutines-identity-admin-secret-name", c.Subroutines.Identity.AdminSecretName, "Secret holding the password of the Keycloak admin user")
+	fs.StringVar(&c.Subroutines.Identity.AdminSecretKey, "subroutines-identity-admin-secret-key", c.Subroutines.Identity.AdminSecretKey, "Key of the Keycloak admin password in the admin Secret")
+	c.Subroutines.Identity.Requeue.addFlags(fs, "identity")
+	fs.StringVar(&c.Subroutines.Resource.ApplyStrategy, "subroutines-resource-apply-strategy", c.Subroutines.Resource.ApplyStrategy, "How resolved versions are written: server-side or update (previous get-modify-update)")
+	fs.StringSliceVar(&c.Subroutines.Resource.ForceConflicts, "subroutines-resource-force-conflicts", c.Subroutines.Resource.ForceConflicts, "Kinds applied with forced ownership of conflicting fields (comma-separated)")
+	c.Subroutines.Resource.Requeue.addFlags(fs, "resource")
 	fs.BoolVar(&c.Subroutines.ManagedProvider.WaitPlatformMesh.Enabled, "subroutines-managed-provider-wait-platform-mesh-enabled", c.Subroutines.ManagedProvider.WaitPlatformMesh.Enabled, "Enable ManagedProvider wait-platform-mesh subroutine")
 	fs.BoolVar(&c.Subroutines.ManagedProvider.ProviderResource.Enabled, "subroutines-managed-provider-resource-enabled", c.Subroutines.ManagedProvider.ProviderResource.Enabled, "Enable ManagedProvider provider-resource subroutine")
 	fs.BoolVar(&c.Subroutines.ManagedProvider.WaitProvider.Enabled, "subroutines-managed-provider-wait-enabled", c.Subroutines.ManagedProvider.WaitProvider.Enabled, "Enable ManagedProvider wait-provider subroutine")
 	fs.BoolVar(&c.Subroutines.ManagedProvider.KubeconfigCopy.Enabled, "subroutines-managed-provider-kubeconfig-enabled", c.Subroutines.ManagedProvider.KubeconfigCopy.Enabled, "Enable ManagedProvider kubeconfig-copy subroutine")
 	fs.BoolVar(&c.Subroutines.ManagedProvider.Deploy.Enabled, "subroutines-managed-provider-deploy-enabled", c.Subroutines.ManagedProvider.Deploy.Enabled, "Enable ManagedProvider deploy subroutine")
+	c.Subroutines.ManagedProvider.Requeue.addFlags(fs, "managed-provider")
 	fs.BoolVar(&c.Subroutines.Provider.Workspace.Enabled, "subroutines-providers-workspace-enabled", c.Subroutines.Provider.Workspace.Enabled, "Enable Provider workspace subroutine")
 	fs.BoolVar(&c.Subroutines.Provider.Kubeconfig.Enabled, "subroutines-providers-kubeconfig-enabled", c.Subroutines.Provider.Kubeconfig.Enabled, "Enable Provider scoped-kubeconfig subroutine")
+	c.Subroutines.Provider.Requeue.addFlags(fs, "providers")
 
 	fs.StringVar(&c.Providers.ProvidersAPIExportEndpointSliceName, "providers-apiexport-endpointslice-name", c.Providers.ProvidersAPIExportEndpointSliceName, "Set name of the Providers APIExport endpoint slice to use")
 	fs.StringVar(&c.Providers.ProvidersAPIExportEndpointSliceWorkspace, "providers-apiexport-endpointslice-workspace", c.Providers.ProvidersAPIExportEndpointSliceWorkspace, "Set workspace of the Providers APIExport endpoint slice to use")
@@ -176,13 +747,71 @@ func (c *OperatorConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.RemoteRuntime.Kubeconfig, "remote-runtime-kubeconfig", c.RemoteRuntime.Kubeconfig, "Kubeconfig for remote runtime cluster")
 	fs.StringVar(&c.RemoteRuntime.InfraSecretName, "remote-runtime-infra-secret-name", c.RemoteRuntime.InfraSecretName, "Secret name for remote runtime infra kubeconfig")
 	fs.StringVar(&c.RemoteRuntime.InfraSecretKey, "remote-runtime-infra-secret-key", c.RemoteRuntime.InfraSecretKey, "Secret key for remote runtime infra kubeconfig")
+	fs.DurationVar(&c.RemoteRuntime.ReloadInterval, "remote-runtime-kubeconfig-reload-interval", c.RemoteRuntime.ReloadInterval, "How often the remote runtime kubeconfig is checked for rotated credentials (0 loads it once)")
 
 	fs.StringVar(&c.RemoteInfra.Kubeconfig, "remote-infra-kubeconfig", c.RemoteInfra.Kubeconfig, "Kubeconfig for remote infra cluster")
+	fs.DurationVar(&c.RemoteInfra.ReloadInterval, "remote-infra-kubeconfig-reload-interval", c.RemoteInfra.ReloadInterval, "How often the remote infra kubeconfig is checked for rotated credentials (0 loads it once)")
+
+	fs.StringVar(&c.Dev.KubeconfigInfra, "kubeconfig-infra", c.Dev.KubeconfigInfra, "Run out-of-cluster for development with this kubeconfig for the infra cluster")
+	fs.StringVar(&c.Dev.KubeconfigRuntime, "kubeconfig-runtime", c.Dev.KubeconfigRuntime, "Run out-of-cluster for development with this kubeconfig for the runtime cluster")
+
+	fs.StringVar(&c.LogLevel.ConfigMapName, "log-level-configmap-name", c.LogLevel.ConfigMapName, "ConfigMap to read the runtime log level from (disabled when empty)")
+	fs.StringVar(&c.LogLevel.ConfigMapNamespace, "log-level-configmap-namespace", c.LogLevel.ConfigMapNamespace, "Namespace of the log level ConfigMap")
+	fs.BoolVar(&c.LogLevel.SignalsEnabled, "log-level-signals-enabled", c.LogLevel.SignalsEnabled, "Raise/lower the log level on SIGUSR1/SIGUSR2")
+
+	fs.StringVar(&c.Runbook.ConfigMapName, "runbook-configmap-name", c.Runbook.ConfigMapName, "ConfigMap mapping condition and event reasons to runbook references (disabled when empty)")
+	fs.StringVar(&c.Runbook.ConfigMapNamespace, "runbook-configmap-namespace", c.Runbook.ConfigMapNamespace, "Namespace of the runbook ConfigMap")
+
+	fs.StringVar(&c.Eventing.SinkURL, "eventing-sink-url", c.Eventing.SinkURL, "HTTP endpoint receiving CloudEvents for lifecycle milestones (disabled when empty)")
+	fs.StringVar(&c.Eventing.Source, "eventing-source", c.Eventing.Source, "CloudEvents source attribute of emitted events")
+	fs.StringSliceVar(&c.Eventing.Types, "eventing-types", c.Eventing.Types, "Event types to emit, entries ending in * match by prefix (comma-separated, all when empty)")
+	fs.IntVar(&c.Eventing.MaxRetries, "eventing-max-retries", c.Eventing.MaxRetries, "Redeliveries of an event after a failed send")
+
+	fs.IntVar(&c.Events.QueueSize, "events-queue-size", c.Events.QueueSize, "Kubernetes events waiting to be recorded before further events are dropped")
+	fs.DurationVar(&c.Events.DedupeWindow, "events-dedupe-window", c.Events.DedupeWindow, "How long repeats of a Kubernetes event are counted instead of recorded (0 records every repeat)")
+	fs.IntVar(&c.Events.RateLimit, "events-rate-limit", c.Events.RateLimit, "Kubernetes events recorded per reason and rate limit interval (0 disables the limit)")
+	fs.DurationVar(&c.Events.RateLimitInterval, "events-rate-limit-interval", c.Events.RateLimitInterval, "Interval of the Kubernetes event rate limit")
+
+	fs.DurationVar(&c.Health.Interval, "health-interval", c.Health.Interval, "How often the deployed components are probed for the ComponentsReady condition (0 disables the health aggregator)")
+	fs.BoolVar(&c.Health.ReadinessGate, "health-readiness-gate", c.Health.ReadinessGate, "Fail the operator readiness probe while deployed components are unhealthy")
+
+	fs.DurationVar(&c.Initializer.Interval, "initializer-interval", c.Initializer.Interval, "How often logical clusters waiting for an initializer of spec.kcp.initializers are looked for, e.g. 30s (0 disables the initializer loop)")
+
+	fs.StringVar(&c.RBAC.SelfCheck, "rbac-self-check", c.RBAC.SelfCheck, "Check the permissions needed by the enabled subroutines at startup: warn, fail or disabled")
+
+	fs.BoolVar(&c.Webhook.Enabled, "webhook-enabled", c.Webhook.Enabled, "Serve the defaulting webhook of PlatformMesh")
+	fs.IntVar(&c.Webhook.Port, "webhook-port", c.Webhook.Port, "Port of the webhook server")
+	fs.StringVar(&c.Webhook.CertDir, "webhook-cert-dir", c.Webhook.CertDir, "Directory with tls.crt and tls.key of the webhook server (defaults to the controller-runtime default)")
+
+	fs.StringVar(&c.LeaderElection.ID, "leader-election-id", c.LeaderElection.ID, "Name of the Lease used for leader election")
+	fs.StringVar(&c.LeaderElection.Namespace, "leader-election-namespace", c.LeaderElection.Namespace, "Namespace of the leader election Lease (defaults to the namespace of the operator pod)")
+	fs.StringVar(&c.LeaderElection.Identity, "leader-election-identity", c.LeaderElection.Identity, "Identity of this replica in the leader election Lease (defaults to the hostname with a random suffix)")
+	fs.DurationVar(&c.LeaderElection.LeaseDuration, "leader-election-lease-duration", c.LeaderElection.LeaseDuration, "How long followers wait before taking over a Lease that was not renewed")
+	fs.DurationVar(&c.LeaderElection.RenewDeadline, "leader-election-renew-deadline", c.LeaderElection.RenewDeadline, "How long the leader tries to renew the Lease before giving up leadership")
+	fs.DurationVar(&c.LeaderElection.RetryPeriod, "leader-election-retry-period", c.LeaderElection.RetryPeriod, "How often Lease renewals and acquisitions are tried")
+
+	fs.BoolVar(&c.Controllers.Kcp, "enable-kcp-controller", c.Controllers.Kcp, "Run the kcp setup half of the PlatformMesh reconciliation")
+	fs.BoolVar(&c.Controllers.Deployment, "enable-deployment-controller", c.Controllers.Deployment, "Run the deployment half of the PlatformMesh reconciliation")
+}
+
+// Validate reports leader election durations that cannot work together: the
+// leader must give up before followers take over, and retry within its
+// deadline.
+func (c LeaderElectionConfig) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("--leader-election-id must not be empty")
+	}
+	if c.RetryPeriod <= 0 || c.RenewDeadline <= c.RetryPeriod || c.LeaseDuration <= c.RenewDeadline {
+		return fmt.Errorf("leader election needs lease duration (%s) > renew deadline (%s) > retry period (%s) > 0",
+			c.LeaseDuration, c.RenewDeadline, c.RetryPeriod)
+	}
+	return nil
 }
 
 type ProviderSubroutinesConfig struct {
 	Workspace  ProviderSubroutineConfig
 	Kubeconfig ProviderSubroutineConfig
+	Requeue    RequeuePolicy
 }
 
 type ProvidersConfig struct {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
@@ -21,19 +22,55 @@ func TestNewOperatorConfig(t *testing.T) {
 	assert.Equal(t, "kcp-webhook-secret", cfg.Subroutines.Deployment.AuthorizationWebhookSecretName)
 	assert.Equal(t, "rebac-authz-webhook-cert", cfg.Subroutines.Deployment.AuthorizationWebhookSecretCAName)
 	assert.True(t, cfg.Subroutines.Deployment.EnableIstio)
+	assert.Equal(t, 3, cfg.Subroutines.Deployment.IstioMaxRestarts)
+	assert.Equal(t, IstioScopeLocal, cfg.Subroutines.Deployment.IstioScope)
+	assert.Equal(t, 3, cfg.Subroutines.Deployment.ValuesSnapshots)
+	assert.Equal(t, 10*time.Minute, cfg.Subroutines.Deployment.DriftInterval)
+	assert.False(t, cfg.Subroutines.Deployment.DriftAutoCorrect)
+	assert.Empty(t, cfg.Subroutines.Deployment.LookupNamespaces)
+	assert.False(t, cfg.Subroutines.Deployment.PruneDisabledComponents)
+	assert.False(t, cfg.Subroutines.Deployment.PruneOrphanedObjects)
+	assert.Equal(t, 5, cfg.Subroutines.Deployment.RenderSnapshots)
+	assert.Zero(t, cfg.Subroutines.Deployment.UpgradeMaxUnready)
+	assert.False(t, cfg.Subroutines.Deployment.ExportRenderedManifests)
+	assert.Zero(t, cfg.Subroutines.Deployment.RollbackAfter)
 
 	assert.True(t, cfg.Subroutines.KcpSetup.Enabled)
 	assert.Equal(t, "domain-certificate", cfg.Subroutines.KcpSetup.DomainCertificateCASecretName)
 	assert.Equal(t, "ca.crt", cfg.Subroutines.KcpSetup.DomainCertificateCASecretKey)
+	assert.Equal(t, 30*time.Second, cfg.Subroutines.KcpSetup.APIBindingTimeout)
+	assert.Equal(t, 5*time.Second, cfg.Subroutines.KcpSetup.WebhookProbeTimeout)
+	assert.Equal(t, 5*time.Minute, cfg.Subroutines.KcpSetup.WebhookProbeInterval)
+	assert.Equal(t, 2*time.Minute, cfg.Subroutines.KcpSetup.WorkspaceTimeout)
+	assert.False(t, cfg.Subroutines.KcpSetup.Shards)
 
 	assert.True(t, cfg.Subroutines.ProviderSecret.Enabled)
+	assert.Equal(t, 7*24*time.Hour, cfg.Subroutines.ProviderSecret.TokenExpiration)
+	assert.Equal(t, 24*time.Hour, cfg.Subroutines.ProviderSecret.TokenRenewBefore)
+	assert.Equal(t, time.Minute, cfg.Subroutines.ProviderSecret.EndpointSliceResyncInterval)
+	assert.Equal(t, 4, cfg.Subroutines.ProviderSecret.MaxConcurrentConnections)
+	assert.False(t, cfg.Subroutines.ProviderSecret.RequireRBACApproval)
 	assert.False(t, cfg.Subroutines.FeatureToggles.Enabled)
 	assert.True(t, cfg.Subroutines.Wait.Enabled)
+	assert.True(t, cfg.Subroutines.VersionSkew.Enabled)
+	assert.True(t, cfg.Subroutines.Bootstrap.Enabled)
+	assert.Equal(t, 10*time.Minute, cfg.Subroutines.Bootstrap.MinInterval)
+	assert.True(t, cfg.Subroutines.Prerequisites.Enabled)
+	assert.False(t, cfg.Subroutines.Prerequisites.Block)
+	assert.Equal(t, ApplyStrategyServerSide, cfg.Subroutines.Resource.ApplyStrategy)
+	assert.Equal(t, []string{"Application", "GitRepository", "HelmRelease", "HelmRepository", "OCIRepository"}, cfg.Subroutines.Resource.ForceConflicts)
 
 	assert.Equal(t, "providers.platform-mesh.io", cfg.Providers.ProvidersAPIExportEndpointSliceName)
 	assert.Equal(t, "root:platform-mesh-system", cfg.Providers.ProvidersAPIExportEndpointSliceWorkspace)
 	assert.True(t, cfg.Subroutines.Provider.Workspace.Enabled)
 	assert.True(t, cfg.Subroutines.Provider.Kubeconfig.Enabled)
+
+	assert.Equal(t, 30*time.Second, cfg.RemoteRuntime.ReloadInterval)
+	assert.Equal(t, 30*time.Second, cfg.RemoteInfra.ReloadInterval)
+
+	assert.Empty(t, cfg.LogLevel.ConfigMapName)
+	assert.Equal(t, "platform-mesh-system", cfg.LogLevel.ConfigMapNamespace)
+	assert.True(t, cfg.LogLevel.SignalsEnabled)
 }
 
 func TestOperatorConfigAddFlags(t *testing.T) {
@@ -55,13 +92,48 @@ func TestOperatorConfigAddFlags(t *testing.T) {
 		"--subroutines-deployment-enabled=false",
 		"--authorization-webhook-secret-name=authz-secret",
 		"--authorization-webhook-secret-ca-name=authz-ca",
+		"--authorization-webhook-secret-namespace=kcp-system",
+		"--authorization-webhook-secret-ca-namespace=cert-system",
 		"--subroutines-deployment-enable-istio=false",
+		"--subroutines-deployment-istio-max-restarts=5",
+		"--subroutines-deployment-istio-scope=always",
+		"--subroutines-deployment-operator-namespace=operators",
+		"--subroutines-deployment-operator-deployment-name=pm-operator",
+		"--subroutines-deployment-values-snapshots=0",
+		"--subroutines-deployment-drift-interval=0",
+		"--subroutines-deployment-drift-auto-correct=true",
+		"--subroutines-deployment-lookup-namespaces=istio-system,gateway",
+		"--subroutines-deployment-prune-disabled-components",
+		"--subroutines-deployment-prune-orphaned-objects",
+		"--subroutines-deployment-render-snapshots=0",
+		"--subroutines-deployment-export-rendered-manifests",
+		"--subroutines-deployment-upgrade-max-unready=-1",
+		"--subroutines-deployment-rollback-after=20m",
 		"--subroutines-kcp-setup-enabled=false",
 		"--domain-certificate-ca-secret-name=domain-ca",
 		"--domain-certificate-ca-secret-key=ca.crt",
+		"--subroutines-kcp-setup-api-binding-timeout=0",
+		"--subroutines-kcp-setup-shards",
+		"--subroutines-kcp-setup-webhook-probe-timeout=0",
+		"--subroutines-kcp-setup-webhook-probe-interval=1m",
+		"--subroutines-kcp-setup-workspace-timeout=0",
 		"--subroutines-provider-secret-enabled=false",
+		"--subroutines-provider-secret-token-expiration=24h",
+		"--subroutines-provider-secret-token-renew-before=6h",
+		"--subroutines-provider-secret-client-cert-issuer=provider-issuer",
+		"--subroutines-provider-secret-endpoint-slice-resync-interval=0s",
+		"--subroutines-provider-secret-max-concurrent-connections=8",
+		"--subroutines-provider-secret-require-rbac-approval",
+		"--subroutines-provider-secret-merged-kubeconfig",
 		"--subroutines-feature-toggles-enabled=true",
 		"--subroutines-wait-enabled=false",
+		"--subroutines-version-skew-enabled=false",
+		"--subroutines-bootstrap-enabled=false",
+		"--subroutines-bootstrap-min-interval=1m",
+		"--subroutines-prerequisites-enabled=false",
+		"--subroutines-prerequisites-block=true",
+		"--subroutines-resource-apply-strategy=update",
+		"--subroutines-resource-force-conflicts=OCIRepository",
 	})
 
 	assert.NoError(t, err)
@@ -79,15 +151,50 @@ func TestOperatorConfigAddFlags(t *testing.T) {
 	assert.False(t, cfg.Subroutines.Deployment.Enabled)
 	assert.Equal(t, "authz-secret", cfg.Subroutines.Deployment.AuthorizationWebhookSecretName)
 	assert.Equal(t, "authz-ca", cfg.Subroutines.Deployment.AuthorizationWebhookSecretCAName)
+	assert.Equal(t, "kcp-system", cfg.Subroutines.Deployment.AuthorizationWebhookSecretNamespace)
+	assert.Equal(t, "cert-system", cfg.Subroutines.Deployment.AuthorizationWebhookSecretCANamespace)
 	assert.False(t, cfg.Subroutines.Deployment.EnableIstio)
+	assert.Equal(t, 5, cfg.Subroutines.Deployment.IstioMaxRestarts)
+	assert.Equal(t, IstioScopeAlways, cfg.Subroutines.Deployment.IstioScope)
+	assert.Equal(t, "operators", cfg.Subroutines.Deployment.OperatorNamespace)
+	assert.Equal(t, "pm-operator", cfg.Subroutines.Deployment.OperatorDeploymentName)
+	assert.Zero(t, cfg.Subroutines.Deployment.ValuesSnapshots)
+	assert.Zero(t, cfg.Subroutines.Deployment.DriftInterval)
+	assert.True(t, cfg.Subroutines.Deployment.DriftAutoCorrect)
+	assert.Equal(t, []string{"istio-system", "gateway"}, cfg.Subroutines.Deployment.LookupNamespaces)
+	assert.True(t, cfg.Subroutines.Deployment.PruneDisabledComponents)
+	assert.True(t, cfg.Subroutines.Deployment.PruneOrphanedObjects)
+	assert.Zero(t, cfg.Subroutines.Deployment.RenderSnapshots)
+	assert.Equal(t, -1, cfg.Subroutines.Deployment.UpgradeMaxUnready)
+	assert.True(t, cfg.Subroutines.Deployment.ExportRenderedManifests)
+	assert.Equal(t, 20*time.Minute, cfg.Subroutines.Deployment.RollbackAfter)
 
 	assert.False(t, cfg.Subroutines.KcpSetup.Enabled)
 	assert.Equal(t, "domain-ca", cfg.Subroutines.KcpSetup.DomainCertificateCASecretName)
 	assert.Equal(t, "ca.crt", cfg.Subroutines.KcpSetup.DomainCertificateCASecretKey)
+	assert.Zero(t, cfg.Subroutines.KcpSetup.APIBindingTimeout)
+	assert.Zero(t, cfg.Subroutines.KcpSetup.WebhookProbeTimeout)
+	assert.Equal(t, time.Minute, cfg.Subroutines.KcpSetup.WebhookProbeInterval)
+	assert.Zero(t, cfg.Subroutines.KcpSetup.WorkspaceTimeout)
+	assert.True(t, cfg.Subroutines.KcpSetup.Shards)
 
 	assert.False(t, cfg.Subroutines.ProviderSecret.Enabled)
+	assert.Equal(t, 24*time.Hour, cfg.Subroutines.ProviderSecret.TokenExpiration)
+	assert.Equal(t, 6*time.Hour, cfg.Subroutines.ProviderSecret.TokenRenewBefore)
+	assert.Equal(t, "provider-issuer", cfg.Subroutines.ProviderSecret.ClientCertIssuerName)
+	assert.Zero(t, cfg.Subroutines.ProviderSecret.EndpointSliceResyncInterval)
+	assert.Equal(t, 8, cfg.Subroutines.ProviderSecret.MaxConcurrentConnections)
+	assert.True(t, cfg.Subroutines.ProviderSecret.RequireRBACApproval)
+	assert.True(t, cfg.Subroutines.ProviderSecret.MergedKubeconfig)
 	assert.True(t, cfg.Subroutines.FeatureToggles.Enabled)
 	assert.False(t, cfg.Subroutines.Wait.Enabled)
+	assert.False(t, cfg.Subroutines.VersionSkew.Enabled)
+	assert.False(t, cfg.Subroutines.Bootstrap.Enabled)
+	assert.Equal(t, time.Minute, cfg.Subroutines.Bootstrap.MinInterval)
+	assert.False(t, cfg.Subroutines.Prerequisites.Enabled)
+	assert.True(t, cfg.Subroutines.Prerequisites.Block)
+	assert.Equal(t, ApplyStrategyUpdate, cfg.Subroutines.Resource.ApplyStrategy)
+	assert.Equal(t, []string{"OCIRepository"}, cfg.Subroutines.Resource.ForceConflicts)
 }
 
 func TestOperatorConfigAddFlagsProviders(t *testing.T) {
@@ -104,3 +211,414 @@ func TestOperatorConfigAddFlagsProviders(t *testing.T) {
 	assert.Equal(t, "custom.providers.io", cfg.Providers.ProvidersAPIExportEndpointSliceName)
 	assert.Equal(t, "root:custom-ws", cfg.Providers.ProvidersAPIExportEndpointSliceWorkspace)
 }
+
+func TestOperatorConfigAddFlagsRemote(t *testing.T) {
+	cfg := NewOperatorConfig()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--remote-runtime-kubeconfig=/runtime.kubeconfig",
+		"--remote-runtime-kubeconfig-reload-interval=1m",
+		"--remote-infra-kubeconfig=/infra.kubeconfig",
+		"--remote-infra-kubeconfig-reload-interval=0",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.RemoteRuntime.IsEnabled())
+	assert.Equal(t, time.Minute, cfg.RemoteRuntime.ReloadInterval)
+	assert.True(t, cfg.RemoteInfra.IsEnabled())
+	assert.Zero(t, cfg.RemoteInfra.ReloadInterval)
+}
+
+func TestOperatorConfigAddFlagsLogLevel(t *testing.T) {
+	cfg := NewOperatorConfig()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--log-level-configmap-name=operator-log-level",
+		"--log-level-configmap-namespace=custom-ns",
+		"--log-level-signals-enabled=false",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "operator-log-level", cfg.LogLevel.ConfigMapName)
+	assert.Equal(t, "custom-ns", cfg.LogLevel.ConfigMapNamespace)
+	assert.False(t, cfg.LogLevel.SignalsEnabled)
+}
+
+func TestOperatorConfigAddFlagsRunbook(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Empty(t, cfg.Runbook.ConfigMapName)
+	assert.Equal(t, "platform-mesh-system", cfg.Runbook.ConfigMapNamespace)
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--runbook-configmap-name=operator-runbooks",
+		"--runbook-configmap-namespace=custom-ns",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "operator-runbooks", cfg.Runbook.ConfigMapName)
+	assert.Equal(t, "custom-ns", cfg.Runbook.ConfigMapNamespace)
+}
+
+func TestOperatorConfigAddFlagsEventing(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Empty(t, cfg.Eventing.SinkURL)
+	assert.Equal(t, "platform-mesh-operator", cfg.Eventing.Source)
+	assert.Equal(t, 5, cfg.Eventing.MaxRetries)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--eventing-sink-url=http://broker.example",
+		"--eventing-source=/clusters/dev",
+		"--eventing-types=io.platform-mesh.operator.workspace.created,io.platform-mesh.operator.secret.*",
+		"--eventing-max-retries=2",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://broker.example", cfg.Eventing.SinkURL)
+	assert.Equal(t, "/clusters/dev", cfg.Eventing.Source)
+	assert.Equal(t, []string{"io.platform-mesh.operator.workspace.created", "io.platform-mesh.operator.secret.*"}, cfg.Eventing.Types)
+	assert.Equal(t, 2, cfg.Eventing.MaxRetries)
+}
+
+func TestOperatorConfigAddFlagsEvents(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, 1000, cfg.Events.QueueSize)
+	assert.Equal(t, 10*time.Minute, cfg.Events.DedupeWindow)
+	assert.Equal(t, 30, cfg.Events.RateLimit)
+	assert.Equal(t, time.Minute, cfg.Events.RateLimitInterval)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--events-queue-size=50",
+		"--events-dedupe-window=0",
+		"--events-rate-limit=5",
+		"--events-rate-limit-interval=30s",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 50, cfg.Events.QueueSize)
+	assert.Zero(t, cfg.Events.DedupeWindow)
+	assert.Equal(t, 5, cfg.Events.RateLimit)
+	assert.Equal(t, 30*time.Second, cfg.Events.RateLimitInterval)
+}
+
+func TestOperatorConfigAddFlagsHealth(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, time.Minute, cfg.Health.Interval)
+	assert.False(t, cfg.Health.ReadinessGate)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--health-interval=0",
+		"--health-readiness-gate=true",
+	})
+
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.Health.Interval)
+	assert.True(t, cfg.Health.ReadinessGate)
+}
+
+func TestOperatorConfigAddFlagsInitializer(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Zero(t, cfg.Initializer.Interval)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{"--initializer-interval=30s"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.Initializer.Interval)
+}
+
+func TestOperatorConfigAddFlagsRawManifests(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, RawManifestsConfig{}, cfg.Subroutines.KcpSetup.RawManifests)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--subroutines-kcp-setup-raw-manifests-enabled=true",
+		"--subroutines-kcp-setup-raw-manifests-workspace-paths=root,root:orgs:*",
+		"--subroutines-kcp-setup-raw-manifests-kinds=ConfigMap,APIBinding.apis.kcp.io",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, RawManifestsConfig{
+		Enabled:        true,
+		WorkspacePaths: []string{"root", "root:orgs:*"},
+		Kinds:          []string{"ConfigMap", "APIBinding.apis.kcp.io"},
+	}, cfg.Subroutines.KcpSetup.RawManifests)
+}
+
+func TestOperatorConfigAddFlagsRequeue(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, DefaultRequeuePolicy(), cfg.Subroutines.Wait.Requeue)
+	assert.Equal(t, DefaultRequeuePolicy(), cfg.Subroutines.KcpSetup.Requeue)
+	assert.Equal(t, DefaultRequeuePolicy(), cfg.Subroutines.ManagedProvider.Requeue)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--subroutines-wait-requeue-initial=10s",
+		"--subroutines-wait-requeue-max=2m",
+		"--subroutines-wait-requeue-jitter=0",
+		"--subroutines-kcp-setup-requeue-max=1m",
+		"--subroutines-managed-provider-requeue-initial=10s",
+		"--subroutines-providers-requeue-max=1m",
+		"--subroutines-resource-requeue-initial=1s",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, RequeuePolicy{Initial: 10 * time.Second, Max: 2 * time.Minute}, cfg.Subroutines.Wait.Requeue)
+	assert.Equal(t, time.Minute, cfg.Subroutines.KcpSetup.Requeue.Max)
+	assert.Equal(t, DefaultRequeuePolicy(), cfg.Subroutines.Deployment.Requeue)
+	assert.Equal(t, 10*time.Second, cfg.Subroutines.ManagedProvider.Requeue.Initial)
+	assert.Equal(t, time.Minute, cfg.Subroutines.Provider.Requeue.Max)
+	assert.Equal(t, time.Second, cfg.Subroutines.Resource.Requeue.Initial)
+}
+
+func TestOperatorConfigAddFlagsOpenFGA(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.False(t, cfg.Subroutines.OpenFGA.Enabled)
+	assert.Empty(t, cfg.Subroutines.OpenFGA.URL)
+	assert.Equal(t, "platform-mesh", cfg.Subroutines.OpenFGA.StoreName)
+	assert.False(t, cfg.Subroutines.OpenFGA.WriteBaseModel)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--subroutines-openfga-enabled=true",
+		"--subroutines-openfga-url=http://openfga.fga:8080",
+		"--subroutines-openfga-store-name=core",
+		"--subroutines-openfga-model-configmap=fga-model",
+		"--subroutines-openfga-write-base-model=true",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Subroutines.OpenFGA.Enabled)
+	assert.Equal(t, "http://openfga.fga:8080", cfg.Subroutines.OpenFGA.URL)
+	assert.Equal(t, "core", cfg.Subroutines.OpenFGA.StoreName)
+	assert.Equal(t, "fga-model", cfg.Subroutines.OpenFGA.ModelConfigMapName)
+	assert.True(t, cfg.Subroutines.OpenFGA.WriteBaseModel)
+}
+
+func TestOperatorConfigAddFlagsIdentity(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.False(t, cfg.Subroutines.Identity.Enabled)
+	assert.Equal(t, "platform-mesh", cfg.Subroutines.Identity.Realm)
+	assert.Equal(t, "keycloak-admin", cfg.Subroutines.Identity.AdminSecretName)
+	assert.Equal(t, "secret", cfg.Subroutines.Identity.AdminSecretKey)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--subroutines-identity-enabled=true",
+		"--subroutines-identity-keycloak-url=http://keycloak.iam/auth",
+		"--subroutines-identity-realm=core",
+		"--subroutines-identity-admin-user=admin",
+		"--subroutines-identity-admin-secret-name=kc-admin",
+		"--subroutines-identity-admin-secret-key=password",
+		"--subroutines-identity-requeue-max=1m",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Subroutines.Identity.Enabled)
+	assert.Equal(t, "http://keycloak.iam/auth", cfg.Subroutines.Identity.URL)
+	assert.Equal(t, "core", cfg.Subroutines.Identity.Realm)
+	assert.Equal(t, "admin", cfg.Subroutines.Identity.AdminUser)
+	assert.Equal(t, "kc-admin", cfg.Subroutines.Identity.AdminSecretName)
+	assert.Equal(t, "password", cfg.Subroutines.Identity.AdminSecretKey)
+	assert.Equal(t, time.Minute, cfg.Subroutines.Identity.Requeue.Max)
+}
+
+func TestOperatorConfigAddFlagsRBAC(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, RBACSelfCheckWarn, cfg.RBAC.SelfCheck)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{"--rbac-self-check=fail"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, RBACSelfCheckFail, cfg.RBAC.SelfCheck)
+}
+
+func TestOperatorConfigAddFlagsWebhook(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.False(t, cfg.Webhook.Enabled)
+	assert.Equal(t, 9443, cfg.Webhook.Port)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{"--webhook-enabled", "--webhook-port=10443", "--webhook-cert-dir=/certs"})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Webhook.Enabled)
+	assert.Equal(t, 10443, cfg.Webhook.Port)
+	assert.Equal(t, "/certs", cfg.Webhook.CertDir)
+}
+
+func TestOperatorConfigAddFlagsRenderValidation(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Empty(t, cfg.Subroutines.Deployment.Validation.KyvernoPolicyDir)
+	assert.Empty(t, cfg.Subroutines.Deployment.Validation.OPAPolicyDir)
+	assert.Equal(t, "data.platformmesh.deny", cfg.Subroutines.Deployment.Validation.OPAQuery)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--render-validation-kyverno-policy-dir=/operator/policies/kyverno",
+		"--render-validation-kyverno-binary=/usr/local/bin/kyverno",
+		"--render-validation-opa-policy-dir=/operator/policies/opa",
+		"--render-validation-opa-query=data.custom.violations",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/operator/policies/kyverno", cfg.Subroutines.Deployment.Validation.KyvernoPolicyDir)
+	assert.Equal(t, "/usr/local/bin/kyverno", cfg.Subroutines.Deployment.Validation.KyvernoBinary)
+	assert.Equal(t, "/operator/policies/opa", cfg.Subroutines.Deployment.Validation.OPAPolicyDir)
+	assert.Equal(t, "opa", cfg.Subroutines.Deployment.Validation.OPABinary)
+	assert.Equal(t, "data.custom.violations", cfg.Subroutines.Deployment.Validation.OPAQuery)
+}
+
+func TestOperatorConfigAddFlagsDev(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.False(t, cfg.Dev.IsEnabled())
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--kubeconfig-infra=/home/dev/.kube/infra",
+		"--kubeconfig-runtime=/home/dev/.kube/runtime",
+		"--kcp-url=https://localhost:8443",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Dev.IsEnabled())
+	assert.Equal(t, "/home/dev/.kube/infra", cfg.Dev.KubeconfigInfra)
+	assert.Equal(t, "/home/dev/.kube/runtime", cfg.Dev.KubeconfigRuntime)
+	assert.False(t, cfg.RemoteRuntime.IsEnabled())
+
+	cfg.ApplyDevDefaults()
+	assert.Equal(t, "frontproxy-front-proxy.platform-mesh-system", cfg.KCP.TLSServerName)
+}
+
+func TestApplyDevDefaultsKeepsExplicitServerName(t *testing.T) {
+	cfg := NewOperatorConfig()
+	cfg.KCP.Url = "https://localhost:8443"
+	cfg.KCP.TLSServerName = "kcp.example.com"
+	cfg.Dev.KubeconfigRuntime = "/home/dev/.kube/config"
+
+	cfg.ApplyDevDefaults()
+	assert.Equal(t, "kcp.example.com", cfg.KCP.TLSServerName)
+
+	cfg = NewOperatorConfig()
+	cfg.KCP.Url = "https://localhost:8443"
+	cfg.ApplyDevDefaults()
+	assert.Empty(t, cfg.KCP.TLSServerName)
+}
+
+func TestOperatorConfigAddFlagsLeaderElection(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.Equal(t, "81924e50.platform-mesh.org", cfg.LeaderElection.ID)
+	assert.NoError(t, cfg.LeaderElection.Validate())
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{
+		"--leader-election-id=pm-operator",
+		"--leader-election-namespace=pm-system",
+		"--leader-election-identity=pm-operator-0",
+		"--leader-election-lease-duration=30s",
+		"--leader-election-renew-deadline=20s",
+		"--leader-election-retry-period=5s",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, LeaderElectionConfig{
+		ID:            "pm-operator",
+		Namespace:     "pm-system",
+		Identity:      "pm-operator-0",
+		LeaseDuration: 30 * time.Second,
+		RenewDeadline: 20 * time.Second,
+		RetryPeriod:   5 * time.Second,
+	}, cfg.LeaderElection)
+	assert.NoError(t, cfg.LeaderElection.Validate())
+}
+
+func TestLeaderElectionConfigValidate(t *testing.T) {
+	valid := NewOperatorConfig().LeaderElection
+	tests := []struct {
+		name   string
+		modify func(*LeaderElectionConfig)
+	}{
+		{name: "empty id", modify: func(c *LeaderElectionConfig) { c.ID = "" }},
+		{name: "renew deadline not below lease duration", modify: func(c *LeaderElectionConfig) { c.RenewDeadline = c.LeaseDuration }},
+		{name: "retry period not below renew deadline", modify: func(c *LeaderElectionConfig) { c.RetryPeriod = c.RenewDeadline }},
+		{name: "zero retry period", modify: func(c *LeaderElectionConfig) { c.RetryPeriod = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestOperatorConfigAddFlagsControllers(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.True(t, cfg.Controllers.Kcp)
+	assert.True(t, cfg.Controllers.Deployment)
+	assert.False(t, cfg.Controllers.Split())
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	err := fs.Parse([]string{"--enable-deployment-controller=false"})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Controllers.Kcp)
+	assert.False(t, cfg.Controllers.Deployment)
+	assert.True(t, cfg.Controllers.Split())
+	assert.NoError(t, cfg.Controllers.Validate())
+
+	cfg.Controllers.Kcp = false
+	assert.Error(t, cfg.Controllers.Validate())
+}
+
+func TestIstioGateEnabled(t *testing.T) {
+	cfg := NewOperatorConfig()
+	assert.True(t, cfg.IstioGateEnabled())
+
+	cfg.RemoteRuntime.Kubeconfig = "/runtime.kubeconfig"
+	assert.False(t, cfg.IstioGateEnabled())
+
+	cfg.Subroutines.Deployment.IstioScope = IstioScopeAlways
+	assert.True(t, cfg.IstioGateEnabled())
+
+	cfg.Subroutines.Deployment.EnableIstio = false
+	assert.False(t, cfg.IstioGateEnabled())
+}
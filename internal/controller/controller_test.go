@@ -20,11 +20,14 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	pmconfig "github.com/platform-mesh/golang-commons/config"
+	pmsubroutines "github.com/platform-mesh/subroutines"
 	"github.com/stretchr/testify/suite"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -40,19 +43,23 @@ import (
 	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
 // fakeCtrlManager implements sigs.k8s.io/controller-runtime/pkg/manager.Manager for unit tests.
-// Only GetClient and GetScheme are functional; all other methods panic if called.
+// Only GetClient, GetScheme and GetEventRecorder are functional; all other methods panic if called.
 type fakeCtrlManager struct {
 	client client.Client
 	scheme *runtime.Scheme
@@ -66,12 +73,14 @@ func (f *fakeCtrlManager) GetFieldIndexer() client.FieldIndexer { panic("not imp
 func (f *fakeCtrlManager) GetEventRecorderFor(_ string) record.EventRecorder {
 	panic("not implemented")
 }
-func (f *fakeCtrlManager) GetEventRecorder(_ string) events.EventRecorder { panic("not implemented") }
-func (f *fakeCtrlManager) GetRESTMapper() meta.RESTMapper                 { panic("not implemented") }
-func (f *fakeCtrlManager) GetAPIReader() client.Reader                    { panic("not implemented") }
-func (f *fakeCtrlManager) GetHTTPClient() *http.Client                    { panic("not implemented") }
-func (f *fakeCtrlManager) Add(_ ctrlmanager.Runnable) error               { return nil }
-func (f *fakeCtrlManager) Elected() <-chan struct{}                       { panic("not implemented") }
+func (f *fakeCtrlManager) GetEventRecorder(_ string) events.EventRecorder {
+	return events.NewFakeRecorder(100)
+}
+func (f *fakeCtrlManager) GetRESTMapper() meta.RESTMapper   { panic("not implemented") }
+func (f *fakeCtrlManager) GetAPIReader() client.Reader      { panic("not implemented") }
+func (f *fakeCtrlManager) GetHTTPClient() *http.Client      { panic("not implemented") }
+func (f *fakeCtrlManager) Add(_ ctrlmanager.Runnable) error { return nil }
+func (f *fakeCtrlManager) Elected() <-chan struct{}         { panic("not implemented") }
 func (f *fakeCtrlManager) AddMetricsServerExtraHandler(_ string, _ http.Handler) error {
 	panic("not implemented")
 }
@@ -295,6 +304,27 @@ func (s *MapConfigMapTestSuite) Test_multipleMatches_returnsAllMatchingRequests(
 	s.Len(reqs, 2)
 }
 
+func (s *MapConfigMapTestSuite) Test_debugPredicate_filtersInstances() {
+	// Both instances use the shared profile, only the selected one is enqueued.
+	spec := corev1alpha1.PlatformMeshSpec{ProfileConfigMap: &corev1alpha1.ConfigMapReference{Name: "shared-profile"}}
+	debug := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}, Spec: spec}
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}, Spec: spec}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(s.scheme).
+		WithObjects(debug, other).
+		Build()
+	r := s.newReconcilerWithClient(fakeClient)
+	r.debugPredicate = predicate.NewPredicateFuncs(func(obj client.Object) bool { return obj.GetName() == "debug" })
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-profile", Namespace: "default"},
+	}
+	reqs := r.mapConfigMapToPlatformMesh(context.Background(), cm)
+
+	s.Require().Len(reqs, 1)
+	s.Equal("debug", reqs[0].Name)
+}
+
 func (s *MapConfigMapTestSuite) Test_defaultNameWrongNamespace_doesNotMatch() {
 	pm := &corev1alpha1.PlatformMesh{
 		ObjectMeta: metav1.ObjectMeta{Name: "my-pm", Namespace: "ns-a"},
@@ -314,6 +344,38 @@ func (s *MapConfigMapTestSuite) Test_defaultNameWrongNamespace_doesNotMatch() {
 	s.Empty(reqs)
 }
 
+func (s *MapConfigMapTestSuite) Test_configMapMatchesProfileLayer_returnsRequest() {
+	pm := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-pm", Namespace: "ns-a"},
+		Spec: corev1alpha1.PlatformMeshSpec{
+			// spec.profiles replaces profileConfigMap.
+			ProfileConfigMap: &corev1alpha1.ConfigMapReference{Name: "ignored-profile"},
+			Profiles: []corev1alpha1.ConfigMapReference{
+				{Name: "base-profile", Namespace: "platform-mesh-system"},
+				{Name: "local-profile"},
+			},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(s.scheme).
+		WithObjects(pm).
+		Build()
+	r := s.newReconcilerWithClient(fakeClient)
+
+	for _, cm := range []*corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "base-profile", Namespace: "platform-mesh-system"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "local-profile", Namespace: "ns-a"}},
+	} {
+		reqs := r.mapConfigMapToPlatformMesh(context.Background(), cm)
+		s.Require().Len(reqs, 1, cm.Name)
+		s.Equal(types.NamespacedName{Name: "my-pm", Namespace: "ns-a"}, reqs[0].NamespacedName)
+	}
+
+	s.Empty(r.mapConfigMapToPlatformMesh(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ignored-profile", Namespace: "ns-a"},
+	}))
+}
+
 // ---- NewResourceReconciler nil clientInfra guard ----
 
 type NewResourceReconcilerTestSuite struct {
@@ -376,6 +438,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_allSubroutinesDisabled_returns
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			Deployment:     config.DeploymentSubroutineConfig{Enabled: false},
 			KcpSetup:       config.KcpSetupSubroutineConfig{Enabled: false},
@@ -397,6 +460,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_deploymentSubroutineEnabled_re
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			Deployment: config.DeploymentSubroutineConfig{Enabled: true},
 		},
@@ -413,6 +477,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_kcpSetupSubroutineEnabled_retu
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			KcpSetup: config.KcpSetupSubroutineConfig{Enabled: true},
 		},
@@ -429,6 +494,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_waitSubroutineEnabled_returnsV
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			Wait: config.WaitSubroutineConfig{Enabled: true},
 		},
@@ -445,6 +511,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_providerSecretSubroutineEnable
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			ProviderSecret: config.ProviderSecretSubroutineConfig{Enabled: true},
 		},
@@ -461,6 +528,7 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_featureTogglesSubroutineEnable
 	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
 	mgr := newFakeManager(fakeClient, s.scheme)
 	cfg := &config.OperatorConfig{
+		Controllers: config.ControllersConfig{Kcp: true, Deployment: true},
 		Subroutines: config.SubroutinesConfig{
 			FeatureToggles: config.FeatureTogglesSubroutineConfig{Enabled: true},
 		},
@@ -472,3 +540,163 @@ func (s *NewPlatformMeshReconcilerTestSuite) Test_featureTogglesSubroutineEnable
 	s.NotNil(r)
 	s.NotNil(r.lifecycle)
 }
+
+func (s *NewPlatformMeshReconcilerTestSuite) Test_splitControllers_selectSubroutines() {
+	fakeClient := fake.NewClientBuilder().WithScheme(s.scheme).Build()
+	newSubs := func(controllers config.ControllersConfig) []pmsubroutines.Subroutine {
+		cfg := &config.OperatorConfig{
+			Controllers: controllers,
+			Subroutines: config.SubroutinesConfig{
+				Deployment: config.DeploymentSubroutineConfig{Enabled: true},
+				KcpSetup:   config.KcpSetupSubroutineConfig{Enabled: true},
+				Wait:       config.WaitSubroutineConfig{Enabled: true},
+			},
+		}
+		return newPlatformMeshSubroutines(fakeClient, fakeClient, &subroutines.Helper{}, cfg, &pmconfig.CommonServiceConfig{},
+			"/tmp", "https://kcp.example.com", subroutines.NewImageVersionStore(), nil)
+	}
+	names := func(controllers config.ControllersConfig) []string {
+		var names []string
+		for _, sub := range newSubs(controllers) {
+			names = append(names, sub.GetName())
+		}
+		return names
+	}
+
+	s.Equal([]string{
+		subroutines.DeploymentSubroutineName, subroutines.KcpsetupSubroutineName, subroutines.WaitSubroutineName, subroutines.RecoverySubroutineName,
+	}, names(config.ControllersConfig{Kcp: true, Deployment: true}))
+	s.Equal([]string{
+		subroutines.DeploymentSubroutineName,
+	}, names(config.ControllersConfig{Deployment: true}))
+	s.Equal([]string{
+		subroutines.DeploymentGateSubroutineName, subroutines.DeploymentSubroutineName, subroutines.KcpsetupSubroutineName,
+		subroutines.WaitSubroutineName, subroutines.RecoverySubroutineName,
+	}, names(config.ControllersConfig{Kcp: true}))
+
+	// Split, only the kcp controller holds the finalizer of the Deployment
+	// subroutine, and only the deployment controller processes it.
+	deploymentHalf := newSubs(config.ControllersConfig{Deployment: true})[0]
+	_, finalizer := deploymentHalf.(pmsubroutines.Finalizer)
+	s.False(finalizer)
+	_, processor := deploymentHalf.(pmsubroutines.Processor)
+	s.True(processor)
+	kcpHalf := newSubs(config.ControllersConfig{Kcp: true})[1]
+	_, processor = kcpHalf.(pmsubroutines.Processor)
+	s.False(processor)
+	s.Equal([]string{subroutines.DeploymentSubroutineFinalizer}, kcpHalf.(pmsubroutines.Finalizer).Finalizers(&corev1alpha1.PlatformMesh{}))
+
+	s.True(ownsConditions(config.ControllersConfig{Kcp: true, Deployment: true}))
+	s.True(ownsConditions(config.ControllersConfig{Kcp: true}))
+	s.False(ownsConditions(config.ControllersConfig{Deployment: true}))
+
+	s.Equal(pmReconcilerName, platformMeshReconcilerName(config.ControllersConfig{Kcp: true, Deployment: true}))
+	s.Equal(pmKcpReconcilerName, platformMeshReconcilerName(config.ControllersConfig{Kcp: true}))
+	s.Equal(pmDeploymentReconcilerName, platformMeshReconcilerName(config.ControllersConfig{Deployment: true}))
+}
+
+// planSubroutine creates a ConfigMap and reports progress in the status of the
+// instance it processes, like the real subroutines do.
+type planSubroutine struct {
+	client client.Client
+}
+
+func (p *planSubroutine) GetName() string { return "PlanSubroutine" }
+
+func (p *planSubroutine) Process(ctx context.Context, obj client.Object) (pmsubroutines.Result, error) {
+	inst := obj.(*corev1alpha1.PlatformMesh)
+	meta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{Type: "Planned", Status: metav1.ConditionTrue, Reason: "Planned"})
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "component", Namespace: inst.Namespace}}
+	return pmsubroutines.OK(), p.client.Create(ctx, cm)
+}
+
+type PlanTestSuite struct {
+	suite.Suite
+	scheme *runtime.Scheme
+}
+
+func TestPlanTestSuite(t *testing.T) {
+	suite.Run(t, new(PlanTestSuite))
+}
+
+func (s *PlanTestSuite) SetupSuite() {
+	s.scheme = runtime.NewScheme()
+	s.Require().NoError(clientgoscheme.AddToScheme(s.scheme))
+	s.Require().NoError(corev1alpha1.AddToScheme(s.scheme))
+}
+
+func (s *PlanTestSuite) TestReconcilePlan() {
+	ctx := context.Background()
+	pm := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{
+		Name: "my-pm", Namespace: "default", Generation: 5,
+		Annotations: map[string]string{corev1alpha1.PlanModeAnnotation: "true"},
+	}}
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).WithObjects(pm).WithStatusSubresource(pm).Build()
+	r := &PlatformMeshReconciler{
+		client: cl,
+		planSubroutines: func(rec *plan.Recorder) []pmsubroutines.Subroutine {
+			return []pmsubroutines.Subroutine{&planSubroutine{client: rec.Client(plan.ClusterRuntime, cl)}}
+		},
+	}
+
+	_, err := r.Reconcile(ctx, mcreconcile.Request{Request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "my-pm", Namespace: "default"}}})
+	s.Require().NoError(err)
+
+	// The planned ConfigMap is only part of the plan.
+	s.True(kerrors.IsNotFound(cl.Get(ctx, types.NamespacedName{Name: "component", Namespace: "default"}, &corev1.ConfigMap{})))
+
+	planCM := &corev1.ConfigMap{}
+	s.Require().NoError(cl.Get(ctx, types.NamespacedName{Name: "my-pm-plan", Namespace: "default"}, planCM))
+	result := &plan.Plan{}
+	s.Require().NoError(yaml.Unmarshal([]byte(planCM.Data[PlanConfigMapKey]), result))
+	s.Equal(int64(5), result.Generation)
+	s.Equal([]plan.Action{{Verb: plan.VerbCreate, Cluster: plan.ClusterRuntime, APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "component"}}, result.Actions)
+	s.Empty(result.Incomplete)
+
+	updated := &corev1alpha1.PlatformMesh{}
+	s.Require().NoError(cl.Get(ctx, types.NamespacedName{Name: "my-pm", Namespace: "default"}, updated))
+	s.Require().NotNil(updated.Status.Plan)
+	s.Equal("my-pm-plan", updated.Status.Plan.ConfigMapName)
+	s.Equal(int64(5), updated.Status.Plan.ObservedGeneration)
+	s.Equal(1, updated.Status.Plan.Actions)
+	s.True(updated.Status.Plan.Complete)
+	s.Empty(updated.Status.Conditions)
+
+	// Planning the same generation again leaves the instance alone.
+	generatedAt := metav1.NewTime(updated.Status.Plan.GeneratedAt.Add(-time.Hour))
+	updated.Status.Plan.GeneratedAt = generatedAt
+	s.Require().NoError(cl.Status().Update(ctx, updated))
+	_, err = r.Reconcile(ctx, mcreconcile.Request{Request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "my-pm", Namespace: "default"}}})
+	s.Require().NoError(err)
+	s.Require().NoError(cl.Get(ctx, types.NamespacedName{Name: "my-pm", Namespace: "default"}, updated))
+	s.True(generatedAt.Equal(&updated.Status.Plan.GeneratedAt))
+}
+
+func (s *PlanTestSuite) TestPlanNotRequested() {
+	pm := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "my-pm", Namespace: "default"}}
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).WithObjects(pm).Build()
+	r := &PlatformMeshReconciler{client: cl, planSubroutines: func(*plan.Recorder) []pmsubroutines.Subroutine { return nil }}
+
+	inst, err := r.planRequested(context.Background(), mcreconcile.Request{Request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "my-pm", Namespace: "default"}}})
+	s.Require().NoError(err)
+	s.Nil(inst)
+
+	inst, err = r.planRequested(context.Background(), mcreconcile.Request{Request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "gone", Namespace: "default"}}})
+	s.Require().NoError(err)
+	s.Nil(inst)
+}
+
+func (s *PlanTestSuite) TestPlanModes() {
+	for mode, requested := range map[string]bool{corev1alpha1.PlanModeActions: true, corev1alpha1.PlanModeDiff: true, "false": false} {
+		pm := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{
+			Name: "my-pm", Namespace: "default",
+			Annotations: map[string]string{corev1alpha1.PlanModeAnnotation: mode},
+		}}
+		cl := fake.NewClientBuilder().WithScheme(s.scheme).WithObjects(pm).Build()
+		r := &PlatformMeshReconciler{client: cl, planSubroutines: func(*plan.Recorder) []pmsubroutines.Subroutine { return nil }}
+
+		inst, err := r.planRequested(context.Background(), mcreconcile.Request{Request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "my-pm", Namespace: "default"}}})
+		s.Require().NoError(err)
+		s.Equal(requested, inst != nil, mode)
+	}
+}
@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
@@ -29,45 +30,111 @@ import (
 	"github.com/platform-mesh/subroutines/lifecycle"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	mchandler "sigs.k8s.io/multicluster-runtime/pkg/handler"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/decorate"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventrecorder"
+	"github.com/platform-mesh/platform-mesh-operator/internal/loglevel"
 	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/internal/runbook"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
 	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
 var (
 	pmReconcilerName = "PlatformMeshReconciler"
+	// The names of the reconcilers when the kcp and deployment halves run in
+	// separate pods, see config.ControllersConfig.
+	pmKcpReconcilerName        = "PlatformMeshKcpReconciler"
+	pmDeploymentReconcilerName = "PlatformMeshDeploymentReconciler"
 )
 
+// platformMeshReconcilerName returns the name of the reconciler running the
+// halves enabled in controllers.
+func platformMeshReconcilerName(controllers config.ControllersConfig) string {
+	switch {
+	case !controllers.Split():
+		return pmReconcilerName
+	case controllers.Kcp:
+		return pmKcpReconcilerName
+	default:
+		return pmDeploymentReconcilerName
+	}
+}
+
 // PlatformMeshReconciler reconciles a PlatformMesh object
 type PlatformMeshReconciler struct {
+	name        string
 	lifecycle   *lifecycle.Lifecycle
 	rateLimiter workqueue.TypedRateLimiter[mcreconcile.Request]
 	client      client.Client
+	// recorder records the events of the subroutines on the reconciled
+	// PlatformMesh. Plans record none.
+	recorder events.EventRecorder
+	// planSubroutines returns the subroutines with their writes recorded by
+	// rec, see reconcilePlan.
+	planSubroutines func(rec *plan.Recorder) []subroutines.Subroutine
+	// recovery starts recoveries, see pmsubs.StartRecovery. Only the
+	// controller running the kcp half does.
+	recovery bool
+	// trigger enqueues the reconciles requested by runnables outside the
+	// controller, see ReconcileTrigger.
+	trigger *pmsubs.ReconcileTrigger
+	// debugPredicate selects the PlatformMesh instances this operator
+	// reconciles by their debug label, also for mapped ConfigMap events.
+	debugPredicate predicate.Predicate
+}
+
+// ReconcileTrigger returns the trigger through which the EndpointSliceWatcher
+// and the DriftDetector request reconciles of this controller.
+func (r *PlatformMeshReconciler) ReconcileTrigger() *pmsubs.ReconcileTrigger {
+	return r.trigger
 }
 
 // +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshes/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.platform-mesh.io,resources=rendersnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.platform-mesh.io,resources=rendersnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 func (r *PlatformMeshReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
-	result, err := r.lifecycle.Reconcile(ctx, req)
+	var result ctrl.Result
+	inst, err := r.planRequested(ctx, req)
+	if err == nil && inst != nil {
+		result, err = r.reconcilePlan(ctx, inst)
+	} else if err == nil {
+		ctx = pmsubs.WithEventRecorder(ctx, r.recorder)
+		if r.recovery {
+			_, err = pmsubs.StartRecovery(ctx, r.client, req.NamespacedName)
+		}
+		if err == nil {
+			result, err = r.lifecycle.Reconcile(ctx, req)
+		}
+	}
 	labelResult := "success"
 	if err != nil {
 		labelResult = "error"
 	}
-	metrics.ReconcileTotal.WithLabelValues(pmReconcilerName, labelResult).Inc()
+	metrics.ReconcileTotal.WithLabelValues(r.name, labelResult).Inc()
 	return result, err
 }
 
@@ -78,12 +145,19 @@ func (r *PlatformMeshReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *pm
 		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
 		RateLimiter:             r.rateLimiter,
 	}
-	predicates := append([]predicate.Predicate{filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)}, eventPredicates...)
+	r.debugPredicate = filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)
+	predicates := append([]predicate.Predicate{r.debugPredicate}, eventPredicates...)
 	return mcbuilder.ControllerManagedBy(mgr).
-		Named(pmReconcilerName).
-		For(&corev1alpha1.PlatformMesh{}, mcbuilder.WithEngageWithLocalCluster(true), mcbuilder.WithEngageWithProviderClusters(false)).
+		Named(r.name).
+		For(&corev1alpha1.PlatformMesh{}, mcbuilder.WithEngageWithLocalCluster(true), mcbuilder.WithEngageWithProviderClusters(false),
+			mcbuilder.WithPredicates(predicate.And(predicates...))).
+		// Profile changes re-render the components right away instead of on
+		// the next reconcile of the PlatformMesh. The cache only holds
+		// ConfigMaps labeled as profiles, see pmsubs.ProfileCacheSelector.
+		Watches(&corev1.ConfigMap{}, mchandler.EnqueueRequestsFromMapFunc(r.mapConfigMapToPlatformMesh),
+			mcbuilder.WithEngageWithLocalCluster(true), mcbuilder.WithEngageWithProviderClusters(false)).
+		WatchesRawSource(r.trigger.Source()).
 		WithOptions(opts).
-		WithEventFilter(predicate.And(predicates...)).
 		Complete(r)
 }
 
@@ -102,19 +176,10 @@ func (r *PlatformMeshReconciler) mapConfigMapToPlatformMesh(ctx context.Context,
 	}
 
 	for _, pm := range platformMeshList.Items {
-		configMapName := ""
-		configMapNamespace := pm.Namespace
-
-		if pm.Spec.ProfileConfigMap != nil {
-			configMapName = pm.Spec.ProfileConfigMap.Name
-			if pm.Spec.ProfileConfigMap.Namespace != "" {
-				configMapNamespace = pm.Spec.ProfileConfigMap.Namespace
-			}
-		} else {
-			configMapName = pm.Name + "-profile"
+		if r.debugPredicate != nil && !r.debugPredicate.Create(event.CreateEvent{Object: &pm}) {
+			continue
 		}
-
-		if configMap.Name == configMapName && configMap.Namespace == configMapNamespace {
+		if slices.Contains(pmsubs.ProfileReferences(&pm), client.ObjectKeyFromObject(configMap)) {
 			requests = append(requests, reconcile.Request{
 				NamespacedName: types.NamespacedName{
 					Name:      pm.Name,
@@ -133,26 +198,17 @@ func NewPlatformMeshReconciler(mgr mcmanager.Manager, cfg *config.OperatorConfig
 		kcpUrl = cfg.KCP.Url
 	}
 
+	name := platformMeshReconcilerName(cfg.Controllers)
 	localCl := mgr.GetLocalManager().GetClient()
-
-	var subs []subroutines.Subroutine
-	if cfg.Subroutines.Deployment.Enabled {
-		deploymentSub := pmsubs.NewDeploymentSubroutine(localCl, clientInfra, commonCfg, cfg)
-		deploymentSub.SetImageVersionStore(imageVersionStore)
-		subs = append(subs, deploymentSub)
-	}
-	if cfg.Subroutines.KcpSetup.Enabled {
-		subs = append(subs, pmsubs.NewKcpsetupSubroutine(localCl, &pmsubs.Helper{}, cfg, dir+"/manifests/kcp", kcpUrl))
-	}
-	if cfg.Subroutines.ProviderSecret.Enabled {
-		subs = append(subs, pmsubs.NewProviderSecretSubroutine(localCl, &pmsubs.Helper{}, pmsubs.DefaultHelmGetter{}, kcpUrl))
-	}
-	if cfg.Subroutines.FeatureToggles.Enabled {
-		subs = append(subs, pmsubs.NewFeatureToggleSubroutine(localCl, &pmsubs.Helper{}, cfg, kcpUrl))
-	}
-	if cfg.Subroutines.Wait.Enabled {
-		subs = append(subs, pmsubs.NewWaitSubroutine(clientInfra, localCl, cfg, &pmsubs.Helper{}, kcpUrl))
+	trigger := pmsubs.NewReconcileTrigger()
+	var webhookProber *pmsubs.WebhookProber
+	if kcpSetup := cfg.Subroutines.KcpSetup; cfg.Controllers.Kcp && kcpSetup.Enabled && kcpSetup.WebhookProbeTimeout > 0 && kcpSetup.WebhookProbeInterval > 0 {
+		webhookProber = pmsubs.NewWebhookProber(trigger, kcpSetup.WebhookProbeInterval, kcpSetup.WebhookProbeTimeout)
+		if err := mgr.GetLocalManager().Add(webhookProber); err != nil {
+			return nil, fmt.Errorf("adding webhook prober: %w", err)
+		}
 	}
+	subs := newPlatformMeshSubroutines(localCl, clientInfra, &pmsubs.Helper{}, cfg, commonCfg, dir, kcpUrl, imageVersionStore, webhookProber)
 
 	rl, err := ratelimiter.NewStaticThenExponentialRateLimiter[mcreconcile.Request](ratelimiter.NewConfig(
 		ratelimiter.WithRequeueDelay(30*time.Second),
@@ -164,13 +220,131 @@ func NewPlatformMeshReconciler(mgr mcmanager.Manager, cfg *config.OperatorConfig
 		return nil, fmt.Errorf("creating rate limiter: %w", err)
 	}
 
-	lc := lifecycle.New(mgr, pmReconcilerName, func() client.Object {
+	lc := lifecycle.New(mgr, name, func() client.Object {
 		return &corev1alpha1.PlatformMesh{}
-	}, subs...).WithConditions(conditions.NewManager())
+	}, decorate.Wrap(subs, runbook.Default(), pmsubs.LastErrorHook{}, loglevel.Default())...)
+	// Split, only the kcp controller manages the conditions, so Ready is set
+	// by one controller. It passes the DeploymentGateSubroutine only once the
+	// deployment half reports DeploymentReady, so its Ready covers both halves.
+	if ownsConditions(cfg.Controllers) {
+		lc = lc.WithConditions(conditions.NewManager())
+	}
+
+	// Events pass a bounded queue that aggregates repeats and rate limits
+	// them per reason, so a flapping dependency cannot flood the API server.
+	recorder := eventrecorder.New(mgr.GetLocalManager().GetEventRecorder(name), eventrecorder.Options{
+		QueueSize:         cfg.Events.QueueSize,
+		DedupeWindow:      cfg.Events.DedupeWindow,
+		RateLimit:         cfg.Events.RateLimit,
+		RateLimitInterval: cfg.Events.RateLimitInterval,
+	})
+	if err := mgr.GetLocalManager().Add(recorder); err != nil {
+		return nil, fmt.Errorf("adding event recorder: %w", err)
+	}
 
 	return &PlatformMeshReconciler{
+		name:        name,
 		lifecycle:   lc,
 		rateLimiter: rl,
 		client:      localCl,
+		recorder:    recorder,
+		planSubroutines: func(rec *plan.Recorder) []subroutines.Subroutine {
+			planCfg := *cfg
+			// Waiting only reads, and a plan made while components roll out
+			// would never get past it.
+			planCfg.Subroutines.Wait.Enabled = false
+			return loglevel.Wrap(loglevel.Default(), newPlatformMeshSubroutines(
+				rec.Client(plan.ClusterRuntime, localCl), rec.Client(plan.ClusterInfra, clientInfra),
+				rec.KcpHelper(&pmsubs.Helper{}), &planCfg, commonCfg, dir, kcpUrl, imageVersionStore, nil)...)
+		},
+		recovery: cfg.Controllers.Kcp,
+		trigger:  trigger,
 	}, nil
 }
+
+// newPlatformMeshSubroutines returns the enabled subroutines of the enabled
+// halves, see config.ControllersConfig, in the order they run. A nil
+// webhookProber skips the webhook reachability checks.
+func newPlatformMeshSubroutines(localCl, clientInfra client.Client, kcpHelper pmsubs.KcpHelper, cfg *config.OperatorConfig, commonCfg *pmconfig.CommonServiceConfig, dir, kcpUrl string, imageVersionStore *pmsubs.ImageVersionStore, webhookProber *pmsubs.WebhookProber) []subroutines.Subroutine {
+	var subs []subroutines.Subroutine
+	if cfg.Controllers.Deployment {
+		subs = append(subs, newDeploymentSubroutines(localCl, clientInfra, cfg, commonCfg, imageVersionStore)...)
+	}
+	if cfg.Controllers.Kcp {
+		if cfg.Controllers.Split() {
+			subs = append(subs, pmsubs.NewDeploymentGateSubroutine())
+			// The kcp controller owns all finalizers, so it also deletes
+			// the objects of the runtime clusters.
+			if cfg.Subroutines.Deployment.Enabled {
+				subs = append(subs, pmsubs.FinalizerOnly(pmsubs.NewDeploymentSubroutine(localCl, clientInfra, commonCfg, cfg)))
+			}
+		}
+		subs = append(subs, newKcpSubroutines(localCl, kcpHelper, cfg, dir, kcpUrl, webhookProber)...)
+		// Wait runs after the kcp half also when split, so it waits for
+		// both halves and the Ready of the kcp controller covers it.
+		if cfg.Subroutines.Wait.Enabled {
+			subs = append(subs, pmsubs.NewWaitSubroutine(clientInfra, localCl, cfg, kcpHelper, kcpUrl))
+		}
+		// Last, so a recovery only completes once all others passed.
+		subs = append(subs, pmsubs.NewRecoverySubroutine())
+	}
+	return subs
+}
+
+// ownsConditions reports whether the PlatformMesh controller of controllers
+// manages the conditions of its subroutines and the aggregated Ready. Like the
+// finalizers, they are owned by the kcp controller when split.
+func ownsConditions(controllers config.ControllersConfig) bool {
+	return controllers.Kcp
+}
+
+// newDeploymentSubroutines returns the enabled subroutines of the deployment
+// half.
+func newDeploymentSubroutines(localCl, clientInfra client.Client, cfg *config.OperatorConfig, commonCfg *pmconfig.CommonServiceConfig, imageVersionStore *pmsubs.ImageVersionStore) []subroutines.Subroutine {
+	var subs []subroutines.Subroutine
+	if cfg.Subroutines.VersionSkew.Enabled {
+		subs = append(subs, pmsubs.NewVersionSkewSubroutine(localCl, cfg))
+	}
+	if cfg.Subroutines.Bootstrap.Enabled {
+		subs = append(subs, pmsubs.NewBootstrapSubroutine(clientInfra, cfg))
+	}
+	var deploymentSub *pmsubs.DeploymentSubroutine
+	if cfg.Subroutines.Deployment.Enabled {
+		deploymentSub = pmsubs.NewDeploymentSubroutine(localCl, clientInfra, commonCfg, cfg)
+		deploymentSub.SetImageVersionStore(imageVersionStore)
+	}
+	if cfg.Subroutines.Prerequisites.Enabled {
+		subs = append(subs, pmsubs.NewPrerequisitesSubroutine(localCl, deploymentSub, cfg))
+	}
+	if deploymentSub != nil && cfg.Controllers.Split() {
+		subs = append(subs, pmsubs.WithoutFinalizers(deploymentSub))
+	} else if deploymentSub != nil {
+		subs = append(subs, deploymentSub)
+	}
+	if cfg.Subroutines.OpenFGA.Enabled {
+		subs = append(subs, pmsubs.NewOpenFGASubroutine(localCl, cfg))
+	}
+	if cfg.Subroutines.Identity.Enabled {
+		subs = append(subs, pmsubs.NewIdentitySubroutine(localCl, clientInfra, cfg))
+	}
+	return subs
+}
+
+// newKcpSubroutines returns the enabled subroutines of the kcp half.
+func newKcpSubroutines(localCl client.Client, kcpHelper pmsubs.KcpHelper, cfg *config.OperatorConfig, dir, kcpUrl string, webhookProber *pmsubs.WebhookProber) []subroutines.Subroutine {
+	var subs []subroutines.Subroutine
+	if cfg.Subroutines.KcpSetup.Enabled {
+		kcpSetupSub := pmsubs.NewKcpsetupSubroutine(localCl, kcpHelper, cfg, dir+"/manifests/kcp", kcpUrl)
+		kcpSetupSub.SetWebhookProber(webhookProber)
+		subs = append(subs, kcpSetupSub)
+	}
+	if cfg.Subroutines.ProviderSecret.Enabled {
+		providerSecretSub := pmsubs.NewProviderSecretSubroutine(localCl, kcpHelper, pmsubs.DefaultHelmGetter{}, kcpUrl)
+		providerSecretSub.SetRequeuePolicy(cfg.Subroutines.ProviderSecret.Requeue)
+		subs = append(subs, providerSecretSub)
+	}
+	if cfg.Subroutines.FeatureToggles.Enabled {
+		subs = append(subs, pmsubs.NewFeatureToggleSubroutine(localCl, kcpHelper, cfg, kcpUrl))
+	}
+	return subs
+}
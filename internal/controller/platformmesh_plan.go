@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+// PlanConfigMapKey is the key of the plan in the plan ConfigMap.
+const PlanConfigMapKey = "plan.yaml"
+
+// planRequested returns the instance of req when it asks for a plan instead of
+// a reconciliation, and nil otherwise.
+func (r *PlatformMeshReconciler) planRequested(ctx context.Context, req mcreconcile.Request) (*corev1alpha1.PlatformMesh, error) {
+	if r.planSubroutines == nil {
+		return nil, nil
+	}
+	inst := &corev1alpha1.PlatformMesh{}
+	if err := r.client.Get(ctx, req.NamespacedName, inst); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	mode := inst.Annotations[corev1alpha1.PlanModeAnnotation]
+	if (mode != corev1alpha1.PlanModeActions && mode != corev1alpha1.PlanModeDiff) || !inst.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+// reconcilePlan runs the subroutines of inst with recording clients and stores
+// the resulting plan in the ConfigMap <name>-plan. Nothing else is written;
+// the instance keeps its status apart from status.plan until the plan mode
+// annotation is removed. In diff mode the applies are compared with the live
+// objects.
+func (r *PlatformMeshReconciler) reconcilePlan(ctx context.Context, inst *corev1alpha1.PlatformMesh) (ctrl.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	rec := plan.NewRecorder()
+	if inst.Annotations[corev1alpha1.PlanModeAnnotation] == corev1alpha1.PlanModeDiff {
+		rec = plan.NewDiffRecorder()
+	}
+	planCtx := subroutines.WithClient(plan.WithPlanning(ctx), rec.Client(plan.ClusterRuntime, r.client))
+	// The subroutines update the status of the object they process, which must
+	// not end up in the stored instance.
+	planned := inst.DeepCopy()
+	incomplete := ""
+	for _, sub := range r.planSubroutines(rec) {
+		processor, ok := sub.(subroutines.Processor)
+		if !ok {
+			continue
+		}
+		res, err := processor.Process(planCtx, planned)
+		if err != nil {
+			incomplete = fmt.Sprintf("%s failed: %s", sub.GetName(), err)
+			break
+		}
+		if res.IsStop() || res.IsStopWithRequeue() || res.IsSkipAll() {
+			incomplete = fmt.Sprintf("%s stopped: %s", sub.GetName(), res.Message())
+			break
+		}
+	}
+
+	result := rec.Plan(inst.Generation, incomplete)
+	data, err := yaml.Marshal(result)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("marshaling plan: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: inst.Name + "-plan", Namespace: inst.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, cm, func() error {
+		cm.Data = map[string]string{PlanConfigMapKey: string(data)}
+		return controllerutil.SetControllerReference(inst, cm, r.client.Scheme())
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("writing plan ConfigMap: %w", err)
+	}
+
+	status := &corev1alpha1.PlanStatus{
+		ConfigMapName:      cm.Name,
+		ObservedGeneration: inst.Generation,
+		GeneratedAt:        metav1.Now(),
+		Actions:            len(result.Actions),
+		Complete:           incomplete == "",
+	}
+	// An unchanged plan keeps the time it was first made, so replanning the
+	// same generation does not write the instance again.
+	if prev := inst.Status.Plan; prev != nil && op == controllerutil.OperationResultNone {
+		status.GeneratedAt = prev.GeneratedAt
+		if *prev == *status {
+			log.Debug().Str("configMap", cm.Name).Msg("Plan is unchanged")
+			return ctrl.Result{}, nil
+		}
+	}
+	orig := inst.DeepCopy()
+	inst.Status.Plan = status
+	if err := r.client.Status().Patch(ctx, inst, client.MergeFrom(orig)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patching plan status: %w", err)
+	}
+
+	log.Info().Str("configMap", cm.Name).Int("actions", len(result.Actions)).Str("incomplete", incomplete).Msg("Planned reconciliation")
+	return ctrl.Result{}, nil
+}
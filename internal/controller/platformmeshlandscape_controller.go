@@ -0,0 +1,95 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	pmconfig "github.com/platform-mesh/golang-commons/config"
+	"github.com/platform-mesh/golang-commons/controller/filter"
+	"github.com/platform-mesh/golang-commons/controller/lifecycle/ratelimiter"
+	"github.com/platform-mesh/subroutines/conditions"
+	"github.com/platform-mesh/subroutines/lifecycle"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
+)
+
+const landscapeReconcilerName = "PlatformMeshLandscapeReconciler"
+
+// PlatformMeshLandscapeReconciler aggregates the status of PlatformMesh
+// instances into PlatformMeshLandscape objects.
+type PlatformMeshLandscapeReconciler struct {
+	lifecycle   *lifecycle.Lifecycle
+	rateLimiter workqueue.TypedRateLimiter[mcreconcile.Request]
+}
+
+// +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshlandscapes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshlandscapes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.platform-mesh.io,resources=platformmeshlandscapes/finalizers,verbs=update
+
+func (r *PlatformMeshLandscapeReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
+	result, err := r.lifecycle.Reconcile(ctx, req)
+	labelResult := "success"
+	if err != nil {
+		labelResult = "error"
+	}
+	metrics.ReconcileTotal.WithLabelValues(landscapeReconcilerName, labelResult).Inc()
+	return result, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PlatformMeshLandscapeReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *pmconfig.CommonServiceConfig,
+	eventPredicates ...predicate.Predicate) error {
+	opts := controller.TypedOptions[mcreconcile.Request]{
+		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+		RateLimiter:             r.rateLimiter,
+	}
+	predicates := append([]predicate.Predicate{filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)}, eventPredicates...)
+	return mcbuilder.ControllerManagedBy(mgr).
+		Named(landscapeReconcilerName).
+		For(&corev1alpha1.PlatformMeshLandscape{}, mcbuilder.WithEngageWithLocalCluster(true), mcbuilder.WithEngageWithProviderClusters(false)).
+		WithOptions(opts).
+		WithEventFilter(predicate.And(predicates...)).
+		Complete(r)
+}
+
+func NewPlatformMeshLandscapeReconciler(mgr mcmanager.Manager) (*PlatformMeshLandscapeReconciler, error) {
+	rl, err := ratelimiter.NewStaticThenExponentialRateLimiter[mcreconcile.Request](ratelimiter.NewConfig())
+	if err != nil {
+		return nil, fmt.Errorf("error creating rate limiter: %w", err)
+	}
+
+	lc := lifecycle.New(mgr, landscapeReconcilerName, func() client.Object {
+		return &corev1alpha1.PlatformMeshLandscape{}
+	}, pmsubs.NewLandscapeSubroutine(mgr.GetLocalManager().GetClient())).WithConditions(conditions.NewManager())
+
+	return &PlatformMeshLandscapeReconciler{
+		lifecycle:   lc,
+		rateLimiter: rl,
+	}, nil
+}
@@ -84,7 +84,7 @@ func NewManagedProviderReconciler(mgr mcmanager.Manager, operatorCfg *config.Ope
 
 	var subs []subroutines.Subroutine
 	if operatorCfg.Subroutines.ManagedProvider.WaitPlatformMesh.Enabled {
-		subs = append(subs, pmsubs.NewWaitPlatformMeshSubroutine(localCl))
+		subs = append(subs, pmsubs.NewWaitPlatformMeshSubroutine(localCl, operatorCfg.Subroutines.ManagedProvider.Requeue))
 	}
 	if operatorCfg.Subroutines.ManagedProvider.ProviderResource.Enabled {
 		sub, err := pmsubs.NewProviderResourceSubroutine(localCl, kcpHelper, operatorCfg, kcpUrl)
@@ -100,7 +100,7 @@ func NewManagedProviderReconciler(mgr mcmanager.Manager, operatorCfg *config.Ope
 		subs = append(subs, pmsubs.NewKubeconfigCopySubroutine(localCl, kcpHelper, operatorCfg, kcpUrl))
 	}
 	if operatorCfg.Subroutines.ManagedProvider.Deploy.Enabled {
-		sub, err := pmsubs.NewDeploySubroutine(localCl)
+		sub, err := pmsubs.NewDeploySubroutine(localCl, operatorCfg.Subroutines.ManagedProvider.Requeue)
 		if err != nil {
 			return nil, fmt.Errorf("error creating DeploySubroutine: %v", err)
 		}
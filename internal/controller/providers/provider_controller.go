@@ -87,7 +87,7 @@ func NewProviderReconciler(mgr mcmanager.Manager, operatorCfg *config.OperatorCo
 	var subs []subroutines.Subroutine
 
 	if operatorCfg.Subroutines.Provider.Workspace.Enabled {
-		sub, err := pmsubs.NewProviderWorkspaceSubroutine(localClient, kcpHelper, operatorCfg.KCP, kcpUrl)
+		sub, err := pmsubs.NewProviderWorkspaceSubroutine(localClient, kcpHelper, operatorCfg.KCP, kcpUrl, operatorCfg.Subroutines.Provider.Requeue)
 		if err != nil {
 			return nil, fmt.Errorf("error creating ProviderWorkspaceSubroutine: %v", err)
 		}
@@ -100,6 +100,7 @@ func NewProviderReconciler(mgr mcmanager.Manager, operatorCfg *config.OperatorCo
 			kcpHelper,
 			operatorCfg.KCP,
 			kcpUrl,
+			operatorCfg.Subroutines.Provider.Requeue,
 			func(ctx context.Context) (client.Client, error) {
 				cluster, err := mgr.ClusterFromContext(ctx)
 				if err != nil {
@@ -27,6 +27,7 @@ import (
 	"github.com/platform-mesh/subroutines/lifecycle"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,6 +38,8 @@ import (
 	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
 
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/decorate"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventrecorder"
 	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
 	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/resource"
@@ -56,10 +59,13 @@ var gvk = schema.GroupVersionKind{
 type ResourceReconciler struct {
 	lifecycle   *lifecycle.Lifecycle
 	rateLimiter workqueue.TypedRateLimiter[mcreconcile.Request]
+	// recorder records the classified errors of the subroutine on the
+	// Resource, whose status the operator does not write.
+	recorder events.EventRecorder
 }
 
 func (r *ResourceReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
-	result, err := r.lifecycle.Reconcile(ctx, req)
+	result, err := r.lifecycle.Reconcile(pmsubs.WithEventRecorder(ctx, r.recorder), req)
 	labelResult := "success"
 	if err != nil {
 		labelResult = "error"
@@ -116,10 +122,21 @@ func NewResourceReconciler(mgr mcmanager.Manager, cfg *config.OperatorConfig, cl
 		u := &unstructured.Unstructured{}
 		u.SetGroupVersionKind(gvk)
 		return u
-	}, subs...).WithReadOnly()
+	}, decorate.Wrap(subs, pmsubs.LastErrorHook{})...).WithReadOnly()
+
+	recorder := eventrecorder.New(mgr.GetLocalManager().GetEventRecorder(resourceReconcilerName), eventrecorder.Options{
+		QueueSize:         cfg.Events.QueueSize,
+		DedupeWindow:      cfg.Events.DedupeWindow,
+		RateLimit:         cfg.Events.RateLimit,
+		RateLimitInterval: cfg.Events.RateLimitInterval,
+	})
+	if err := mgr.GetLocalManager().Add(recorder); err != nil {
+		return nil, fmt.Errorf("adding event recorder: %w", err)
+	}
 
 	return &ResourceReconciler{
 		lifecycle:   lc,
 		rateLimiter: rl,
+		recorder:    recorder,
 	}, nil
 }
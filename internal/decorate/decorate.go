@@ -0,0 +1,134 @@
+// Package decorate runs hooks around the Process and Finalize calls of
+// subroutines. All hooks of a subroutine share one wrapper that implements
+// exactly the capabilities of the wrapped subroutine, so checks for
+// subroutines.Processor or subroutines.Finalizer see through it, and Unwrap
+// returns the concrete subroutine for any other check.
+package decorate
+
+import (
+	"context"
+
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Hook runs around the calls of a subroutine. Before runs in the order the
+// hooks are given and may replace the context of the call, After runs in
+// reverse order with the outcome of the call.
+type Hook interface {
+	Before(ctx context.Context, sub subroutines.Subroutine, obj client.Object) context.Context
+	After(ctx context.Context, sub subroutines.Subroutine, obj client.Object, res subroutines.Result, err error)
+}
+
+// Wrap returns the given subroutines with hooks run around their calls.
+// Subroutines wrapped before are unwrapped first, so a subroutine is never
+// wrapped twice and the hooks of both calls add up.
+func Wrap(subs []subroutines.Subroutine, hooks ...Hook) []subroutines.Subroutine {
+	wrapped := make([]subroutines.Subroutine, 0, len(subs))
+	for _, sub := range subs {
+		d := &decorated{Subroutine: sub}
+		if inner, ok := sub.(wrapper); ok {
+			base := inner.base()
+			d = &decorated{Subroutine: base.Subroutine, hooks: append(append([]Hook(nil), hooks...), base.hooks...)}
+		} else {
+			d.hooks = hooks
+		}
+		wrapped = append(wrapped, d.capabilities())
+	}
+	return wrapped
+}
+
+// Unwrap returns the subroutine wrapped by Wrap, and sub itself when it is not
+// wrapped.
+func Unwrap(sub subroutines.Subroutine) subroutines.Subroutine {
+	if w, ok := sub.(wrapper); ok {
+		return w.base().Subroutine
+	}
+	return sub
+}
+
+type wrapper interface {
+	base() *decorated
+}
+
+type decorated struct {
+	subroutines.Subroutine
+	hooks []Hook
+}
+
+func (d *decorated) base() *decorated {
+	return d
+}
+
+// capabilities returns d as the wrapper implementing the capabilities of the
+// wrapped subroutine.
+func (d *decorated) capabilities() subroutines.Subroutine {
+	_, processor := d.Subroutine.(subroutines.Processor)
+	_, finalizer := d.Subroutine.(subroutines.Finalizer)
+	switch {
+	case processor && finalizer:
+		return &processingFinalizer{d}
+	case processor:
+		return &processing{d}
+	case finalizer:
+		return &finalizing{d}
+	}
+	return d
+}
+
+func (d *decorated) run(ctx context.Context, obj client.Object, call func(ctx context.Context) (subroutines.Result, error)) (subroutines.Result, error) {
+	for _, h := range d.hooks {
+		ctx = h.Before(ctx, d.Subroutine, obj)
+	}
+	res, err := call(ctx)
+	for i := len(d.hooks) - 1; i >= 0; i-- {
+		d.hooks[i].After(ctx, d.Subroutine, obj, res, err)
+	}
+	return res, err
+}
+
+func (d *decorated) process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return d.run(ctx, obj, func(ctx context.Context) (subroutines.Result, error) {
+		return d.Subroutine.(subroutines.Processor).Process(ctx, obj)
+	})
+}
+
+func (d *decorated) finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return d.run(ctx, obj, func(ctx context.Context) (subroutines.Result, error) {
+		return d.Subroutine.(subroutines.Finalizer).Finalize(ctx, obj)
+	})
+}
+
+func (d *decorated) finalizers(obj client.Object) []string {
+	return d.Subroutine.(subroutines.Finalizer).Finalizers(obj)
+}
+
+type processing struct{ *decorated }
+
+func (p *processing) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return p.process(ctx, obj)
+}
+
+type finalizing struct{ *decorated }
+
+func (f *finalizing) Finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return f.finalize(ctx, obj)
+}
+
+func (f *finalizing) Finalizers(obj client.Object) []string {
+	return f.finalizers(obj)
+}
+
+type processingFinalizer struct{ *decorated }
+
+func (p *processingFinalizer) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return p.process(ctx, obj)
+}
+
+func (p *processingFinalizer) Finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return p.finalize(ctx, obj)
+}
+
+func (p *processingFinalizer) Finalizers(obj client.Object) []string {
+	return p.finalizers(obj)
+}
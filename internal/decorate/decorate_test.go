@@ -0,0 +1,86 @@
+package decorate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ctxKey struct{}
+
+// recordingHook records the calls it runs around in calls.
+type recordingHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordingHook) Before(ctx context.Context, sub subroutines.Subroutine, _ client.Object) context.Context {
+	*h.calls = append(*h.calls, h.name+" before "+sub.GetName())
+	return context.WithValue(ctx, ctxKey{}, h.name)
+}
+
+func (h recordingHook) After(_ context.Context, sub subroutines.Subroutine, _ client.Object, _ subroutines.Result, err error) {
+	*h.calls = append(*h.calls, h.name+" after "+sub.GetName()+": "+err.Error())
+}
+
+type processingSubroutine struct {
+	seen string
+}
+
+func (s *processingSubroutine) GetName() string { return "Processing" }
+
+func (s *processingSubroutine) Process(ctx context.Context, _ client.Object) (subroutines.Result, error) {
+	s.seen, _ = ctx.Value(ctxKey{}).(string)
+	return subroutines.OK(), errors.New("failed")
+}
+
+type finalizingSubroutine struct{}
+
+func (finalizingSubroutine) GetName() string { return "Finalizing" }
+
+func (finalizingSubroutine) Finalize(context.Context, client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), errors.New("not finalized")
+}
+
+func (finalizingSubroutine) Finalizers(client.Object) []string {
+	return []string{"platform-mesh.core.platform-mesh.io/finalizer"}
+}
+
+func TestWrap(t *testing.T) {
+	var calls []string
+	inner := &processingSubroutine{}
+	subs := Wrap([]subroutines.Subroutine{inner, finalizingSubroutine{}}, recordingHook{name: "outer", calls: &calls})
+	subs = Wrap(subs, recordingHook{name: "first", calls: &calls})
+	require.Len(t, subs, 2)
+
+	// The wrappers have the capabilities of the wrapped subroutines only.
+	processor, ok := subs[0].(subroutines.Processor)
+	require.True(t, ok)
+	_, ok = subs[0].(subroutines.Finalizer)
+	assert.False(t, ok)
+	_, ok = subs[1].(subroutines.Processor)
+	assert.False(t, ok)
+	finalizer, ok := subs[1].(subroutines.Finalizer)
+	require.True(t, ok)
+	assert.Same(t, inner, Unwrap(subs[0]))
+	assert.Equal(t, "Processing", subs[0].GetName())
+
+	// Wrapping again adds the hooks to the same wrapper, the later ones first.
+	_, err := processor.Process(context.Background(), &corev1.ConfigMap{})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, "outer", inner.seen)
+	assert.Equal(t, []string{
+		"first before Processing", "outer before Processing",
+		"outer after Processing: failed", "first after Processing: failed",
+	}, calls)
+
+	assert.Equal(t, []string{"platform-mesh.core.platform-mesh.io/finalizer"}, finalizer.Finalizers(&corev1.ConfigMap{}))
+	_, err = finalizer.Finalize(context.Background(), &corev1.ConfigMap{})
+	assert.EqualError(t, err, "not finalized")
+}
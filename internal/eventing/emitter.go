@@ -0,0 +1,189 @@
+package eventing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Options configures an Emitter.
+type Options struct {
+	// Source is the CloudEvents source attribute of all events.
+	Source string
+	// Types restricts the emitted event types. Entries ending in "*" match by
+	// prefix. An empty list emits all types.
+	Types []string
+	// MaxRetries is the number of redeliveries after a failed send.
+	MaxRetries int
+	// InitialBackoff is the delay before the first redelivery; it doubles on
+	// every further attempt.
+	InitialBackoff time.Duration
+	// QueueSize bounds the number of pending events. Events are dropped when
+	// the queue is full so that reconciliation never blocks on the sink.
+	QueueSize int
+}
+
+// Emitter queues events and delivers them to a Sink in the background. It
+// implements manager.Runnable. A nil *Emitter discards all events.
+type Emitter struct {
+	sink  Sink
+	opts  Options
+	queue chan Event
+	log   *logger.Logger
+
+	mu      sync.Mutex
+	emitted map[string]string
+}
+
+func NewEmitter(sink Sink, opts Options, log *logger.Logger) *Emitter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = time.Second
+	}
+	return &Emitter{
+		sink:    sink,
+		opts:    opts,
+		queue:   make(chan Event, opts.QueueSize),
+		log:     log.ChildLogger("component", "eventing"),
+		emitted: map[string]string{},
+	}
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultEmitter *Emitter
+)
+
+// Default returns the process-wide Emitter, nil if eventing is disabled.
+func Default() *Emitter {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultEmitter
+}
+
+// SetDefault sets the process-wide Emitter.
+func SetDefault(e *Emitter) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultEmitter = e
+}
+
+// Emit queues ev unless it is filtered out.
+func (e *Emitter) Emit(ev Event) {
+	if e == nil || !e.allowed(ev.Type) {
+		return
+	}
+	if ev.ID == "" {
+		ev.ID = uuid.NewString()
+	}
+	if ev.Source == "" {
+		ev.Source = e.opts.Source
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.SpecVersion == "" {
+		ev.SpecVersion = SpecVersion
+	}
+	if ev.DataContentType == "" && ev.Data != nil {
+		ev.DataContentType = "application/json"
+	}
+
+	select {
+	case e.queue <- ev:
+	default:
+		e.log.Warn().Str("type", ev.Type).Str("subject", ev.Subject).Msg("Event queue full, dropping event")
+	}
+}
+
+// EmitOnTransition emits ev only if the last event emitted for key had a
+// different type, so milestones observed on every reconciliation are reported
+// once per process. Forget resets key.
+func (e *Emitter) EmitOnTransition(key string, ev Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	if e.emitted[key] == ev.Type {
+		e.mu.Unlock()
+		return
+	}
+	e.emitted[key] = ev.Type
+	e.mu.Unlock()
+	e.Emit(ev)
+}
+
+// Forget clears the transition state of key.
+func (e *Emitter) Forget(key string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.emitted, key)
+}
+
+func (e *Emitter) allowed(eventType string) bool {
+	if len(e.opts.Types) == 0 {
+		return true
+	}
+	for _, t := range e.opts.Types {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			if strings.HasPrefix(eventType, prefix) {
+				return true
+			}
+		} else if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Start delivers queued events until ctx is cancelled.
+func (e *Emitter) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-e.queue:
+			if err := e.send(ctx, ev); err != nil {
+				e.log.Error().Err(err).Str("id", ev.ID).Str("type", ev.Type).Str("subject", ev.Subject).Msg("Failed to deliver event")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes only the leader deliver events, the replica that
+// also runs the reconcilers producing them.
+func (e *Emitter) NeedLeaderElection() bool {
+	return true
+}
+
+func (e *Emitter) send(ctx context.Context, ev Event) error {
+	var lastErr error
+	backoff := wait.Backoff{Duration: e.opts.InitialBackoff, Factor: 2, Jitter: 0.1, Steps: e.opts.MaxRetries + 1}
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		lastErr = e.sink.Send(ctx, ev)
+		if lastErr == nil {
+			return true, nil
+		}
+		var permanent *PermanentError
+		if errors.As(lastErr, &permanent) {
+			return false, lastErr
+		}
+		e.log.Debug().Err(lastErr).Str("id", ev.ID).Msg("Event delivery failed, retrying")
+		return false, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
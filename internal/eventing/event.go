@@ -0,0 +1,75 @@
+// Package eventing emits CloudEvents v1.0 for lifecycle milestones reached by
+// the operator, such as a workspace becoming available or a component turning
+// ready.
+package eventing
+
+import "time"
+
+// SpecVersion is the CloudEvents specification version of emitted events.
+const SpecVersion = "1.0"
+
+const (
+	// TypeWorkspaceCreated is emitted once a workspace managed by the operator
+	// exists and is Ready. Data: WorkspaceCreated.
+	TypeWorkspaceCreated = "io.platform-mesh.operator.workspace.created"
+	// TypeComponentReady is emitted when a resource the operator waits for
+	// reaches its ready condition. Data: ComponentReady.
+	TypeComponentReady = "io.platform-mesh.operator.component.ready"
+	// TypeSecretRotated is emitted when the content of an existing provider
+	// secret changed. Data: SecretRotated.
+	TypeSecretRotated = "io.platform-mesh.operator.secret.rotated"
+)
+
+// Event is a CloudEvent in structured JSON mode.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	Data            any       `json:"data,omitempty"`
+}
+
+// WorkspaceCreated is the data of TypeWorkspaceCreated events.
+type WorkspaceCreated struct {
+	Workspace string `json:"workspace"`
+}
+
+// ComponentReady is the data of TypeComponentReady events.
+type ComponentReady struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// SecretRotated is the data of TypeSecretRotated events.
+type SecretRotated struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func NewWorkspaceCreated(workspace string) Event {
+	return Event{Type: TypeWorkspaceCreated, Subject: workspace, Data: WorkspaceCreated{Workspace: workspace}}
+}
+
+func NewComponentReady(apiVersion, kind, namespace, name string) Event {
+	return Event{
+		Type:    TypeComponentReady,
+		Subject: subject(namespace, name),
+		Data:    ComponentReady{APIVersion: apiVersion, Kind: kind, Namespace: namespace, Name: name},
+	}
+}
+
+func NewSecretRotated(namespace, name string) Event {
+	return Event{Type: TypeSecretRotated, Subject: subject(namespace, name), Data: SecretRotated{Namespace: namespace, Name: name}}
+}
+
+func subject(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
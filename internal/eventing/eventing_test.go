@@ -0,0 +1,164 @@
+package eventing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+	errs   []error
+}
+
+func (s *recordingSink) Send(_ context.Context, ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+type EventingTestSuite struct {
+	suite.Suite
+	log *logger.Logger
+}
+
+func TestEventingTestSuite(t *testing.T) {
+	suite.Run(t, new(EventingTestSuite))
+}
+
+func (s *EventingTestSuite) SetupTest() {
+	logCfg := logger.DefaultConfig()
+	logCfg.NoJSON = true
+	logCfg.Name = "EventingTestSuite"
+	s.log, _ = logger.New(logCfg)
+}
+
+func (s *EventingTestSuite) TestEmit_SetsAttributes() {
+	e := NewEmitter(&recordingSink{}, Options{Source: "platform-mesh-operator"}, s.log)
+
+	e.Emit(NewSecretRotated("platform-mesh-system", "kubeconfig"))
+
+	ev := <-e.queue
+	s.Equal(SpecVersion, ev.SpecVersion)
+	s.NotEmpty(ev.ID)
+	s.Equal("platform-mesh-operator", ev.Source)
+	s.Equal(TypeSecretRotated, ev.Type)
+	s.Equal("platform-mesh-system/kubeconfig", ev.Subject)
+	s.Equal("application/json", ev.DataContentType)
+	s.False(ev.Time.IsZero())
+}
+
+func (s *EventingTestSuite) TestEmit_Filters() {
+	e := NewEmitter(&recordingSink{}, Options{Types: []string{TypeWorkspaceCreated, "io.platform-mesh.operator.secret.*"}}, s.log)
+
+	e.Emit(NewComponentReady("v2", "HelmRelease", "default", "kcp"))
+	e.Emit(NewWorkspaceCreated("root:orgs"))
+	e.Emit(NewSecretRotated("default", "kubeconfig"))
+
+	s.Len(e.queue, 2)
+}
+
+func (s *EventingTestSuite) TestEmit_DropsWhenQueueFull() {
+	e := NewEmitter(&recordingSink{}, Options{QueueSize: 1}, s.log)
+
+	e.Emit(NewWorkspaceCreated("root:a"))
+	e.Emit(NewWorkspaceCreated("root:b"))
+
+	s.Len(e.queue, 1)
+}
+
+func (s *EventingTestSuite) TestEmitOnTransition() {
+	e := NewEmitter(&recordingSink{}, Options{}, s.log)
+
+	e.EmitOnTransition("workspace/root:orgs", NewWorkspaceCreated("root:orgs"))
+	e.EmitOnTransition("workspace/root:orgs", NewWorkspaceCreated("root:orgs"))
+	s.Len(e.queue, 1)
+
+	e.Forget("workspace/root:orgs")
+	e.EmitOnTransition("workspace/root:orgs", NewWorkspaceCreated("root:orgs"))
+	s.Len(e.queue, 2)
+}
+
+func (s *EventingTestSuite) TestNilEmitter() {
+	var e *Emitter
+	s.NotPanics(func() {
+		e.Emit(NewWorkspaceCreated("root:orgs"))
+		e.EmitOnTransition("k", NewWorkspaceCreated("root:orgs"))
+		e.Forget("k")
+	})
+}
+
+func (s *EventingTestSuite) TestSend_Retries() {
+	sink := &recordingSink{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	e := NewEmitter(sink, Options{MaxRetries: 3, InitialBackoff: time.Millisecond}, s.log)
+
+	s.NoError(e.send(context.Background(), NewWorkspaceCreated("root:orgs")))
+	s.Equal(3, sink.count())
+}
+
+func (s *EventingTestSuite) TestSend_GivesUp() {
+	sink := &recordingSink{errs: []error{errors.New("a"), errors.New("b"), errors.New("c")}}
+	e := NewEmitter(sink, Options{MaxRetries: 1, InitialBackoff: time.Millisecond}, s.log)
+
+	s.Error(e.send(context.Background(), NewWorkspaceCreated("root:orgs")))
+	s.Equal(2, sink.count())
+}
+
+func (s *EventingTestSuite) TestSend_PermanentError() {
+	sink := &recordingSink{errs: []error{&PermanentError{Err: errors.New("bad request")}}}
+	e := NewEmitter(sink, Options{MaxRetries: 3, InitialBackoff: time.Millisecond}, s.log)
+
+	s.Error(e.send(context.Background(), NewWorkspaceCreated("root:orgs")))
+	s.Equal(1, sink.count())
+}
+
+func (s *EventingTestSuite) TestHTTPSink() {
+	var received Event
+	var contentType string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		s.NoError(json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, time.Second)
+	ev := NewWorkspaceCreated("root:orgs")
+	ev.ID = "1"
+
+	s.Require().NoError(sink.Send(context.Background(), ev))
+	s.Equal(structuredContentType, contentType)
+	s.Equal("1", received.ID)
+	s.Equal(TypeWorkspaceCreated, received.Type)
+
+	status = http.StatusBadRequest
+	var permanent *PermanentError
+	s.ErrorAs(sink.Send(context.Background(), ev), &permanent)
+
+	status = http.StatusServiceUnavailable
+	err := sink.Send(context.Background(), ev)
+	s.Error(err)
+	s.False(errors.As(err, &permanent))
+}
@@ -0,0 +1,70 @@
+package eventing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const structuredContentType = "application/cloudevents+json; charset=utf-8"
+
+// Sink delivers events to a receiver.
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+// PermanentError marks a delivery failure that is not retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPSink posts events in structured content mode to an HTTP endpoint. Kafka
+// is reached through an HTTP ingress for CloudEvents such as a Knative KafkaSink.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("marshal event %s: %w", ev.ID, err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("build request: %w", err)}
+	}
+	req.Header.Set("Content-Type", structuredContentType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("sink responded with %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+// Package eventrecorder aggregates and rate limits the Kubernetes events of
+// the operator, so that a flapping dependency does not flood the API server
+// with near-identical events.
+package eventrecorder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+// Causes of suppressed events, see metrics.EventsSuppressedTotal.
+const (
+	SuppressedDuplicate   = "duplicate"
+	SuppressedRateLimited = "rateLimited"
+	SuppressedQueueFull   = "queueFull"
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// QueueSize bounds the number of events waiting to be recorded. Events
+	// are dropped when the queue is full so that reconciliation never blocks.
+	QueueSize int
+	// DedupeWindow is how long repeats of an event are counted instead of
+	// recorded. The count is recorded with the event once the window ends.
+	// Zero records every repeat.
+	DedupeWindow time.Duration
+	// RateLimit is the number of events recorded per reason and
+	// RateLimitInterval. Zero disables the limit.
+	RateLimit         int
+	RateLimitInterval time.Duration
+}
+
+type event struct {
+	regarding runtime.Object
+	related   runtime.Object
+	eventtype string
+	reason    string
+	action    string
+	note      string
+}
+
+// series counts the repeats of a recorded event within the dedupe window.
+type series struct {
+	event     event
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// window counts the events of a reason recorded since start.
+type window struct {
+	start time.Time
+	count int
+}
+
+// Recorder is an events.EventRecorder that queues events, aggregates their
+// repeats and rate limits them per reason before passing them to the
+// wrapped recorder in the background. It implements manager.Runnable.
+type Recorder struct {
+	inner events.EventRecorder
+	opts  Options
+	queue chan event
+	now   func() time.Time
+
+	mu      sync.Mutex
+	series  map[string]*series
+	windows map[string]*window
+}
+
+var _ events.EventRecorder = &Recorder{}
+
+func New(inner events.EventRecorder, opts Options) *Recorder {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.RateLimitInterval <= 0 {
+		opts.RateLimitInterval = time.Minute
+	}
+	return &Recorder{
+		inner:   inner,
+		opts:    opts,
+		queue:   make(chan event, opts.QueueSize),
+		now:     time.Now,
+		series:  map[string]*series{},
+		windows: map[string]*window{},
+	}
+}
+
+// Eventf queues the event unless it repeats an event recorded within the
+// dedupe window or its reason exceeds the rate limit. It never blocks.
+func (r *Recorder) Eventf(regarding runtime.Object, related runtime.Object, eventtype, reason, action, note string, args ...interface{}) {
+	ev := event{regarding: regarding, related: related, eventtype: eventtype, reason: reason, action: action, note: fmt.Sprintf(note, args...)}
+	if cause, ok := r.admit(&ev); !ok {
+		metrics.EventsSuppressedTotal.WithLabelValues(reason, cause).Inc()
+		return
+	}
+	r.enqueue(ev)
+}
+
+func (r *Recorder) enqueue(ev event) {
+	select {
+	case r.queue <- ev:
+	default:
+		metrics.EventsSuppressedTotal.WithLabelValues(ev.reason, SuppressedQueueFull).Inc()
+	}
+}
+
+// admit reports whether ev is recorded, or why not. An event that repeats
+// after its dedupe window carries the count of the repeats in between.
+func (r *Recorder) admit(ev *event) (string, bool) {
+	now := r.now()
+	key := eventKey(*ev)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, seen := r.series[key]
+	if seen && now.Sub(s.firstSeen) < r.opts.DedupeWindow {
+		s.count++
+		s.lastSeen = now
+		return SuppressedDuplicate, false
+	}
+	if r.opts.RateLimit > 0 {
+		w, ok := r.windows[ev.reason]
+		if !ok || now.Sub(w.start) >= r.opts.RateLimitInterval {
+			w = &window{start: now}
+			r.windows[ev.reason] = w
+		}
+		if w.count >= r.opts.RateLimit {
+			return SuppressedRateLimited, false
+		}
+		w.count++
+	}
+	if seen && s.count > 0 {
+		ev.note = s.summary(ev.note)
+	}
+	if r.opts.DedupeWindow > 0 {
+		r.series[key] = &series{event: *ev, firstSeen: now, lastSeen: now}
+	}
+	return "", true
+}
+
+// summary returns note with the count and time range of the repeats of s.
+func (s *series) summary(note string) string {
+	return fmt.Sprintf("%s (repeated %d times between %s and %s)", note, s.count,
+		s.firstSeen.UTC().Format(time.RFC3339), s.lastSeen.UTC().Format(time.RFC3339))
+}
+
+// flush drops the series whose dedupe window ended and queues the event of
+// those with repeats once more, carrying their count.
+func (r *Recorder) flush() {
+	now := r.now()
+	r.mu.Lock()
+	var repeated []event
+	for key, s := range r.series {
+		if now.Sub(s.firstSeen) < r.opts.DedupeWindow {
+			continue
+		}
+		delete(r.series, key)
+		if s.count > 0 {
+			ev := s.event
+			ev.note = s.summary(ev.note)
+			repeated = append(repeated, ev)
+		}
+	}
+	r.mu.Unlock()
+	for _, ev := range repeated {
+		r.enqueue(ev)
+	}
+}
+
+// eventKey identifies the repeats of ev.
+func eventKey(ev event) string {
+	regarding := ""
+	if obj, err := meta.Accessor(ev.regarding); err == nil {
+		regarding = string(obj.GetUID())
+		if regarding == "" {
+			regarding = obj.GetNamespace() + "/" + obj.GetName()
+		}
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", regarding, ev.eventtype, ev.reason, ev.action, ev.note)
+}
+
+// Start records queued events with the wrapped recorder until ctx is
+// cancelled.
+func (r *Recorder) Start(ctx context.Context) error {
+	var tick <-chan time.Time
+	if r.opts.DedupeWindow > 0 {
+		ticker := time.NewTicker(r.opts.DedupeWindow)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+			r.flush()
+		case ev := <-r.queue:
+			r.inner.Eventf(ev.regarding, ev.related, ev.eventtype, ev.reason, ev.action, "%s", ev.note)
+		}
+	}
+}
+
+// NeedLeaderElection makes only the leader record events, the replica that
+// also runs the reconcilers producing them.
+func (r *Recorder) NeedLeaderElection() bool {
+	return true
+}
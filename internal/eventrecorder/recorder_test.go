@@ -0,0 +1,137 @@
+package eventrecorder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+func newTestRecorder(opts Options) (*Recorder, *events.FakeRecorder, *time.Time) {
+	inner := events.NewFakeRecorder(100)
+	r := New(inner, opts)
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, inner, &now
+}
+
+// drain records the queued events with the wrapped recorder.
+func drain(r *Recorder) {
+	for {
+		select {
+		case ev := <-r.queue:
+			r.inner.Eventf(ev.regarding, ev.related, ev.eventtype, ev.reason, ev.action, "%s", ev.note)
+		default:
+			return
+		}
+	}
+}
+
+func received(inner *events.FakeRecorder) []string {
+	var got []string
+	for {
+		select {
+		case ev := <-inner.Events:
+			got = append(got, ev)
+		default:
+			return got
+		}
+	}
+}
+
+func TestRecorderDedupe(t *testing.T) {
+	r, inner, now := newTestRecorder(Options{DedupeWindow: 10 * time.Minute})
+	inst := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns", UID: "uid"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "pm-ns", UID: "other"}}
+
+	before := testutil.ToFloat64(metrics.EventsSuppressedTotal.WithLabelValues("Waiting", SuppressedDuplicate))
+	for range 3 {
+		r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "istio")
+		*now = now.Add(time.Minute)
+	}
+	r.Eventf(other, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "istio")
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "cert-manager")
+	drain(r)
+	assert.Equal(t, []string{
+		"Normal Waiting Waiting for istio",
+		"Normal Waiting Waiting for istio",
+		"Normal Waiting Waiting for cert-manager",
+	}, received(inner))
+	assert.Equal(t, before+2, testutil.ToFloat64(metrics.EventsSuppressedTotal.WithLabelValues("Waiting", SuppressedDuplicate)))
+
+	// A repeat after the window carries the count of the suppressed repeats.
+	*now = now.Add(10 * time.Minute)
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "istio")
+	drain(r)
+	assert.Equal(t, []string{
+		"Normal Waiting Waiting for istio (repeated 2 times between 2026-10-16T08:00:00Z and 2026-10-16T08:02:00Z)",
+	}, received(inner))
+
+	// Repeats that stop are recorded once the window ends.
+	*now = now.Add(time.Minute)
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "istio")
+	r.flush()
+	drain(r)
+	assert.Empty(t, received(inner))
+	*now = now.Add(10 * time.Minute)
+	r.flush()
+	drain(r)
+	assert.Equal(t, []string{
+		"Normal Waiting Waiting for istio (repeated 1 times between 2026-10-16T08:13:00Z and 2026-10-16T08:14:00Z)",
+	}, received(inner))
+	assert.Empty(t, r.series)
+}
+
+func TestRecorderRateLimit(t *testing.T) {
+	r, inner, now := newTestRecorder(Options{RateLimit: 2, RateLimitInterval: time.Minute})
+	inst := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns"}}
+
+	for i := range 3 {
+		r.Eventf(inst, nil, corev1.EventTypeWarning, "ApplyFailed", "Apply", "Failed %d", i)
+	}
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting")
+	drain(r)
+	assert.Equal(t, []string{"Warning ApplyFailed Failed 0", "Warning ApplyFailed Failed 1", "Normal Waiting Waiting"}, received(inner))
+
+	*now = now.Add(time.Minute)
+	r.Eventf(inst, nil, corev1.EventTypeWarning, "ApplyFailed", "Apply", "Failed %d", 3)
+	drain(r)
+	assert.Equal(t, []string{"Warning ApplyFailed Failed 3"}, received(inner))
+}
+
+func TestRecorderQueueFull(t *testing.T) {
+	r, _, _ := newTestRecorder(Options{QueueSize: 1})
+	inst := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns"}}
+
+	before := testutil.ToFloat64(metrics.EventsSuppressedTotal.WithLabelValues("Waiting", SuppressedQueueFull))
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "first")
+	r.Eventf(inst, nil, corev1.EventTypeNormal, "Waiting", "Wait", "second")
+	assert.Len(t, r.queue, 1)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.EventsSuppressedTotal.WithLabelValues("Waiting", SuppressedQueueFull)))
+}
+
+func TestRecorderStart(t *testing.T) {
+	inner := events.NewFakeRecorder(10)
+	r := New(inner, Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Start(ctx) }()
+
+	r.Eventf(&corev1.ConfigMap{}, nil, corev1.EventTypeNormal, "Waiting", "Wait", "Waiting for %s", "istio")
+	select {
+	case ev := <-inner.Events:
+		assert.Equal(t, "Normal Waiting Waiting for istio", ev)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not recorded")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
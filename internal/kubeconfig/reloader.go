@@ -0,0 +1,132 @@
+package kubeconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Reloader serves requests with the credentials of a kubeconfig file and
+// reloads them when the file changes, e.g. because the secret it is mounted
+// from was rotated. Clients built from RestConfig keep their caches and
+// watches across reloads. It implements manager.Runnable and runs on every
+// replica.
+type Reloader struct {
+	path     string
+	interval time.Duration
+	log      *logger.Logger
+
+	mu        sync.RWMutex
+	transport http.RoundTripper
+	config    *rest.Config
+	sum       [sha256.Size]byte
+}
+
+// NewReloader loads the kubeconfig at path, which is checked for changes every
+// interval once the Reloader is started.
+func NewReloader(path string, interval time.Duration, log *logger.Logger) (*Reloader, error) {
+	r := &Reloader{
+		path:     path,
+		interval: interval,
+		log:      log.ChildLogger("component", "kubeconfig"),
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RestConfig returns a rest.Config for the server of the kubeconfig that sends
+// its requests with the current credentials.
+func (r *Reloader) RestConfig() *rest.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return &rest.Config{
+		Host:      r.config.Host,
+		APIPath:   r.config.APIPath,
+		UserAgent: r.config.UserAgent,
+		QPS:       r.config.QPS,
+		Burst:     r.config.Burst,
+		Timeout:   r.config.Timeout,
+		Transport: r,
+	}
+}
+
+// RoundTrip sends req with the transport of the current kubeconfig.
+func (r *Reloader) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.RLock()
+	transport := r.transport
+	r.mu.RUnlock()
+	return transport.RoundTrip(req)
+}
+
+func (r *Reloader) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, r.Sync, r.interval)
+	return nil
+}
+
+func (r *Reloader) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync reloads the kubeconfig if the file changed since the last call. A
+// kubeconfig that cannot be loaded keeps the previous credentials in use.
+func (r *Reloader) Sync(_ context.Context) {
+	changed, err := r.reload()
+	if err != nil {
+		r.log.Error().Err(err).Str("kubeconfig", r.path).Msg("Failed to reload kubeconfig, keeping the previous credentials")
+		return
+	}
+	if changed {
+		r.log.Info().Str("kubeconfig", r.path).Msg("Reloaded kubeconfig")
+	}
+}
+
+func (r *Reloader) reload() (bool, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	r.mu.RLock()
+	unchanged := r.config != nil && sum == r.sum
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	raw, err := clientcmd.LoadFromFile(r.path)
+	if err != nil {
+		return false, err
+	}
+	config, err := clientcmd.NewDefaultClientConfig(*raw, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return false, err
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.config != nil && r.config.Host != config.Host {
+		// Clients built from RestConfig are bound to the server they started with.
+		return false, fmt.Errorf("server changed from %s to %s, restart the operator to switch servers", r.config.Host, config.Host)
+	}
+	previous := r.transport
+	r.transport, r.config, r.sum = transport, config, sum
+	if idle, ok := previous.(interface{ CloseIdleConnections() }); ok {
+		idle.CloseIdleConnections()
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package kubeconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func writeKubeconfig(t *testing.T, path, server, token string) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["runtime"] = &clientcmdapi.Cluster{Server: server}
+	cfg.AuthInfos["runtime"] = &clientcmdapi.AuthInfo{Token: token}
+	cfg.Contexts["runtime"] = &clientcmdapi.Context{Cluster: "runtime", AuthInfo: "runtime"}
+	cfg.CurrentContext = "runtime"
+	require.NoError(t, clientcmd.WriteToFile(*cfg, path))
+}
+
+func TestReloader(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	writeKubeconfig(t, path, server.URL, "first")
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	r, err := NewReloader(path, time.Minute, log)
+	require.NoError(t, err)
+
+	httpClient, err := rest.HTTPClientFor(r.RestConfig())
+	require.NoError(t, err)
+	get := func() string {
+		resp, err := httpClient.Get(server.URL + "/api")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return authorization
+	}
+	assert.Equal(t, "Bearer first", get())
+
+	writeKubeconfig(t, path, server.URL, "second")
+	r.Sync(context.Background())
+	assert.Equal(t, "Bearer second", get())
+
+	// Switching servers and invalid kubeconfigs keep the current credentials.
+	writeKubeconfig(t, path, "https://other.example", "third")
+	r.Sync(context.Background())
+	assert.Equal(t, "Bearer second", get())
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	r.Sync(context.Background())
+	assert.Equal(t, "Bearer second", get())
+}
+
+func TestNewReloaderFailsWithoutKubeconfig(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	_, err = NewReloader(filepath.Join(t.TempDir(), "missing"), time.Minute, log)
+	assert.Error(t, err)
+}
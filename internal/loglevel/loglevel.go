@@ -0,0 +1,249 @@
+package loglevel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/decorate"
+)
+
+const (
+	// LevelKey holds the global log level in the log-level ConfigMap.
+	LevelKey = "level"
+	// SubroutineKeyPrefix prefixes per-subroutine overrides in the log-level
+	// ConfigMap, e.g. "subroutine.DeploymentSubroutine: debug".
+	SubroutineKeyPrefix = "subroutine."
+)
+
+// Controller holds the runtime log level of the operator. The global level is
+// applied through zerolog.SetGlobalLevel; per-subroutine overrides are applied
+// to the logger handed to each subroutine by Wrap.
+//
+// Because zerolog's global level is a floor for every logger, it is set to the
+// most verbose of the global and all subroutine levels. Loggers outside of
+// wrapped subroutines therefore follow that floor while an override is active.
+type Controller struct {
+	mu          sync.RWMutex
+	global      zerolog.Level
+	subroutines map[string]zerolog.Level
+}
+
+var defaultController = NewController(zerolog.InfoLevel)
+
+// Default returns the process-wide Controller.
+func Default() *Controller {
+	return defaultController
+}
+
+// NewController creates a Controller with the given global level.
+func NewController(global zerolog.Level) *Controller {
+	return &Controller{
+		global:      global,
+		subroutines: map[string]zerolog.Level{},
+	}
+}
+
+// Global returns the configured global level.
+func (c *Controller) Global() zerolog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.global
+}
+
+// SetGlobal sets the global level and applies it.
+func (c *Controller) SetGlobal(level zerolog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.global = level
+	c.applyLocked()
+}
+
+// SubroutineLevel returns the effective level for the named subroutine.
+func (c *Controller) SubroutineLevel(name string) zerolog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if lvl, ok := c.subroutines[name]; ok {
+		return lvl
+	}
+	return c.global
+}
+
+// Increase makes the global level one step more verbose (down to trace).
+func (c *Controller) Increase() zerolog.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.global > zerolog.TraceLevel {
+		c.global--
+	}
+	c.applyLocked()
+	return c.global
+}
+
+// Decrease makes the global level one step less verbose (up to panic).
+func (c *Controller) Decrease() zerolog.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.global < zerolog.PanicLevel {
+		c.global++
+	}
+	c.applyLocked()
+	return c.global
+}
+
+// Apply replaces the current configuration with the contents of a log-level
+// ConfigMap. A missing LevelKey keeps the current global level.
+func (c *Controller) Apply(data map[string]string) error {
+	global := c.Global()
+	if v, ok := data[LevelKey]; ok {
+		lvl, err := zerolog.ParseLevel(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", LevelKey, v, err)
+		}
+		global = lvl
+	}
+
+	overrides := map[string]zerolog.Level{}
+	for k, v := range data {
+		name, ok := strings.CutPrefix(k, SubroutineKeyPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		lvl, err := zerolog.ParseLevel(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid level %q for subroutine %s: %w", v, name, err)
+		}
+		overrides[name] = lvl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.global = global
+	c.subroutines = overrides
+	c.applyLocked()
+	return nil
+}
+
+func (c *Controller) applyLocked() {
+	floor := c.global
+	for _, lvl := range c.subroutines {
+		if lvl < floor {
+			floor = lvl
+		}
+	}
+	zerolog.SetGlobalLevel(floor)
+}
+
+// Logger returns a copy of log with the effective level of the named subroutine.
+func (c *Controller) Logger(log *logger.Logger, subroutine string) *logger.Logger {
+	return log.Level(logger.Level(c.SubroutineLevel(subroutine)))
+}
+
+// HandleSignals raises the global level on SIGUSR1 and lowers it on SIGUSR2
+// until ctx is cancelled.
+func (c *Controller) HandleSignals(ctx context.Context, log *logger.Logger) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				var lvl zerolog.Level
+				if sig == syscall.SIGUSR1 {
+					lvl = c.Increase()
+				} else {
+					lvl = c.Decrease()
+				}
+				log.WithLevel(zerolog.NoLevel).Str("signal", sig.String()).Str("level", lvl.String()).Msg("Changed log level")
+			}
+		}
+	}()
+}
+
+// Wrap returns the given subroutines with their context logger adjusted to the
+// level configured for them in c.
+func Wrap(c *Controller, subs ...subroutines.Subroutine) []subroutines.Subroutine {
+	return decorate.Wrap(subs, c)
+}
+
+// Before hands sub a context logger at the level configured for it, see
+// decorate.Hook.
+func (c *Controller) Before(ctx context.Context, sub subroutines.Subroutine, _ client.Object) context.Context {
+	log := c.Logger(logger.LoadLoggerFromContext(ctx), sub.GetName())
+	return context.WithValue(ctx, keys.LoggerCtxKey, log)
+}
+
+// After does nothing, see decorate.Hook.
+func (c *Controller) After(context.Context, subroutines.Subroutine, client.Object, subroutines.Result, error) {
+}
+
+// ConfigMapWatcher periodically reads a ConfigMap and applies it to a Controller.
+// It implements manager.Runnable and runs on every replica.
+type ConfigMapWatcher struct {
+	client   client.Client
+	key      types.NamespacedName
+	interval time.Duration
+	ctrl     *Controller
+	log      *logger.Logger
+
+	lastResourceVersion string
+}
+
+func NewConfigMapWatcher(cl client.Client, key types.NamespacedName, interval time.Duration, ctrl *Controller, log *logger.Logger) *ConfigMapWatcher {
+	return &ConfigMapWatcher{
+		client:   cl,
+		key:      key,
+		interval: interval,
+		ctrl:     ctrl,
+		log:      log.ChildLogger("component", "loglevel"),
+	}
+}
+
+func (w *ConfigMapWatcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, w.Sync, w.interval)
+	return nil
+}
+
+func (w *ConfigMapWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync applies the ConfigMap if it changed since the last call. Signal-driven
+// changes stay in effect until the ConfigMap is modified again.
+func (w *ConfigMapWatcher) Sync(ctx context.Context) {
+	cm := &corev1.ConfigMap{}
+	if err := w.client.Get(ctx, w.key, cm); err != nil {
+		if !kerrors.IsNotFound(err) {
+			w.log.Error().Err(err).Str("configmap", w.key.String()).Msg("Failed to get log-level ConfigMap")
+		}
+		return
+	}
+	if cm.ResourceVersion == w.lastResourceVersion {
+		return
+	}
+	if err := w.ctrl.Apply(cm.Data); err != nil {
+		w.log.Error().Err(err).Str("configmap", w.key.String()).Msg("Invalid log-level ConfigMap")
+		w.lastResourceVersion = cm.ResourceVersion
+		return
+	}
+	w.lastResourceVersion = cm.ResourceVersion
+	w.log.WithLevel(zerolog.NoLevel).Str("configmap", w.key.String()).Str("level", w.ctrl.Global().String()).Msg("Applied log level from ConfigMap")
+}
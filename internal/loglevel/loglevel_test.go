@@ -0,0 +1,172 @@
+package loglevel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type LogLevelTestSuite struct {
+	suite.Suite
+	previous zerolog.Level
+	log      *logger.Logger
+}
+
+func TestLogLevelTestSuite(t *testing.T) {
+	suite.Run(t, new(LogLevelTestSuite))
+}
+
+func (s *LogLevelTestSuite) SetupTest() {
+	s.previous = zerolog.GlobalLevel()
+	logCfg := logger.DefaultConfig()
+	logCfg.NoJSON = true
+	logCfg.Name = "LogLevelTestSuite"
+	s.log, _ = logger.New(logCfg)
+}
+
+func (s *LogLevelTestSuite) TearDownTest() {
+	zerolog.SetGlobalLevel(s.previous)
+}
+
+func (s *LogLevelTestSuite) TestIncreaseDecrease() {
+	c := NewController(zerolog.InfoLevel)
+
+	s.Equal(zerolog.DebugLevel, c.Increase())
+	s.Equal(zerolog.DebugLevel, zerolog.GlobalLevel())
+	s.Equal(zerolog.TraceLevel, c.Increase())
+	s.Equal(zerolog.TraceLevel, c.Increase())
+
+	c.SetGlobal(zerolog.FatalLevel)
+	s.Equal(zerolog.PanicLevel, c.Decrease())
+	s.Equal(zerolog.PanicLevel, c.Decrease())
+}
+
+func (s *LogLevelTestSuite) TestApply() {
+	c := NewController(zerolog.InfoLevel)
+
+	err := c.Apply(map[string]string{
+		LevelKey: "warn",
+		SubroutineKeyPrefix + "DeploymentSubroutine": "debug",
+		"unrelated": "value",
+	})
+	s.Require().NoError(err)
+
+	s.Equal(zerolog.WarnLevel, c.Global())
+	s.Equal(zerolog.DebugLevel, c.SubroutineLevel("DeploymentSubroutine"))
+	s.Equal(zerolog.WarnLevel, c.SubroutineLevel("WaitSubroutine"))
+	s.Equal(zerolog.DebugLevel, zerolog.GlobalLevel())
+
+	s.Require().NoError(c.Apply(map[string]string{}))
+	s.Equal(zerolog.WarnLevel, c.SubroutineLevel("DeploymentSubroutine"))
+	s.Equal(zerolog.WarnLevel, zerolog.GlobalLevel())
+}
+
+func (s *LogLevelTestSuite) TestApply_InvalidLevel() {
+	c := NewController(zerolog.InfoLevel)
+
+	s.Error(c.Apply(map[string]string{LevelKey: "loud"}))
+	s.Error(c.Apply(map[string]string{SubroutineKeyPrefix + "WaitSubroutine": "loud"}))
+	s.Equal(zerolog.InfoLevel, c.Global())
+}
+
+func (s *LogLevelTestSuite) TestLogger() {
+	c := NewController(zerolog.InfoLevel)
+	s.Require().NoError(c.Apply(map[string]string{SubroutineKeyPrefix + "WaitSubroutine": "debug"}))
+
+	s.Equal(zerolog.DebugLevel, c.Logger(s.log, "WaitSubroutine").GetLevel())
+	s.Equal(zerolog.InfoLevel, c.Logger(s.log, "DeploymentSubroutine").GetLevel())
+}
+
+type recordingSubroutine struct {
+	level zerolog.Level
+}
+
+func (r *recordingSubroutine) GetName() string { return "WaitSubroutine" }
+
+func (r *recordingSubroutine) Process(ctx context.Context, _ client.Object) (subroutines.Result, error) {
+	r.level = logger.LoadLoggerFromContext(ctx).GetLevel()
+	return subroutines.OK(), nil
+}
+
+func (r *recordingSubroutine) Finalize(context.Context, client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *recordingSubroutine) Finalizers(client.Object) []string {
+	return []string{"platform-mesh.core.platform-mesh.io/finalizer"}
+}
+
+type nameOnlySubroutine struct{}
+
+func (nameOnlySubroutine) GetName() string { return "NameOnly" }
+
+func (s *LogLevelTestSuite) TestWrap() {
+	c := NewController(zerolog.InfoLevel)
+	s.Require().NoError(c.Apply(map[string]string{SubroutineKeyPrefix + "WaitSubroutine": "debug"}))
+	inner := &recordingSubroutine{}
+
+	wrapped := Wrap(c, inner, nameOnlySubroutine{})
+	s.Require().Len(wrapped, 2)
+
+	processor, ok := wrapped[0].(subroutines.Processor)
+	s.Require().True(ok)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	_, err := processor.Process(ctx, &corev1.ConfigMap{})
+	s.Require().NoError(err)
+	s.Equal(zerolog.DebugLevel, inner.level)
+
+	finalizer, ok := wrapped[0].(subroutines.Finalizer)
+	s.Require().True(ok)
+	s.Equal([]string{"platform-mesh.core.platform-mesh.io/finalizer"}, finalizer.Finalizers(&corev1.ConfigMap{}))
+
+	// The wrapper has the capabilities of the wrapped subroutine only.
+	_, ok = wrapped[1].(subroutines.Processor)
+	s.False(ok)
+	_, ok = wrapped[1].(subroutines.Finalizer)
+	s.False(ok)
+}
+
+func (s *LogLevelTestSuite) TestConfigMapWatcher_Sync() {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "log-level", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{LevelKey: "debug"},
+	}
+	cl := fake.NewClientBuilder().WithObjects(cm).Build()
+	c := NewController(zerolog.InfoLevel)
+	w := NewConfigMapWatcher(cl, types.NamespacedName{Name: "log-level", Namespace: "platform-mesh-system"}, time.Second, c, s.log)
+
+	w.Sync(ctx)
+	s.Equal(zerolog.DebugLevel, c.Global())
+
+	// Signal-driven changes survive until the ConfigMap changes again.
+	c.Decrease()
+	w.Sync(ctx)
+	s.Equal(zerolog.InfoLevel, c.Global())
+
+	cm.Data[LevelKey] = "error"
+	s.Require().NoError(cl.Update(ctx, cm))
+	w.Sync(ctx)
+	s.Equal(zerolog.ErrorLevel, c.Global())
+}
+
+func (s *LogLevelTestSuite) TestConfigMapWatcher_NotFound() {
+	cl := fake.NewClientBuilder().Build()
+	c := NewController(zerolog.WarnLevel)
+	w := NewConfigMapWatcher(cl, types.NamespacedName{Name: "missing", Namespace: "default"}, time.Second, c, s.log)
+
+	w.Sync(context.Background())
+	s.Equal(zerolog.WarnLevel, c.Global())
+	s.False(w.NeedLeaderElection())
+}
@@ -33,6 +33,45 @@ var (
 		},
 		[]string{"subroutine"},
 	)
+
+	// SubroutineErrorsTotal counts subroutine errors per subroutine and
+	// error category.
+	SubroutineErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "platform_mesh_operator_subroutine_errors_total",
+			Help: "Total number of subroutine errors by subroutine and category.",
+		},
+		[]string{"subroutine", "category"},
+	)
+
+	// DriftedObjects reports the rendered objects per instance, template type
+	// and kind whose cluster state differs from the rendered manifest.
+	DriftedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "platform_mesh_operator_drifted_objects",
+			Help: "Number of rendered objects that drifted from their manifest by instance, templates and kind.",
+		},
+		[]string{"namespace", "name", "templates", "kind"},
+	)
+
+	// DriftChecksTotal counts drift checks per result (clean/drifted/error).
+	DriftChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "platform_mesh_operator_drift_checks_total",
+			Help: "Total number of drift checks of rendered manifests by result.",
+		},
+		[]string{"result"},
+	)
+
+	// EventsSuppressedTotal counts the Kubernetes events that were not
+	// recorded per reason and cause (duplicate/rateLimited/queueFull).
+	EventsSuppressedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "platform_mesh_operator_events_suppressed_total",
+			Help: "Total number of Kubernetes events that were not recorded by reason and cause.",
+		},
+		[]string{"reason", "cause"},
+	)
 )
 
 func init() {
@@ -40,5 +79,9 @@ func init() {
 		ReconcileTotal,
 		SubroutineTotal,
 		SubroutineDuration,
+		SubroutineErrorsTotal,
+		DriftedObjects,
+		DriftChecksTotal,
+		EventsSuppressedTotal,
 	)
 }
@@ -48,3 +48,14 @@ func (s *MetricsTestSuite) TestSubroutineDuration() {
 	metrics.SubroutineDuration.WithLabelValues("deployment").Observe(0.1)
 	s.Assert().Greater(testutil.CollectAndCount(metrics.SubroutineDuration), before)
 }
+
+// TestDriftMetrics verifies that the drift gauge is set per label combination
+// and the check counter increments per result.
+func (s *MetricsTestSuite) TestDriftMetrics() {
+	metrics.DriftedObjects.WithLabelValues("platform-mesh-system", "platform-mesh", "infra", "HelmRelease").Set(2)
+	s.Require().Equal(float64(2), testutil.ToFloat64(metrics.DriftedObjects.WithLabelValues("platform-mesh-system", "platform-mesh", "infra", "HelmRelease")))
+
+	before := testutil.ToFloat64(metrics.DriftChecksTotal.WithLabelValues("drifted"))
+	metrics.DriftChecksTotal.WithLabelValues("drifted").Inc()
+	s.Require().Equal(before+1, testutil.ToFloat64(metrics.DriftChecksTotal.WithLabelValues("drifted")))
+}
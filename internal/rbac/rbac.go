@@ -0,0 +1,296 @@
+// Package rbac derives the permissions the operator needs on its runtime
+// cluster from the enabled subroutines, renders them as a ClusterRole and
+// checks them against the API server at startup.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+var (
+	readVerbs = []string{"get", "list", "watch"}
+	allVerbs  = []string{"create", "delete", "get", "list", "patch", "update", "watch"}
+)
+
+func rule(group string, verbs []string, resources ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{APIGroups: []string{group}, Resources: resources, Verbs: verbs}
+}
+
+// permissionSet is the RBAC one part of the operator needs while enabled.
+type permissionSet struct {
+	name    string
+	enabled func(cfg *config.OperatorConfig) bool
+	rules   []rbacv1.PolicyRule
+}
+
+func always(*config.OperatorConfig) bool { return true }
+
+// permissionSets must cover the rules generated from the kubebuilder markers
+// into config/rbac/role.yaml, TestPermissions_CoverGeneratedRole checks it.
+var permissionSets = []permissionSet{
+	{
+		name:    "PlatformMeshReconciler",
+		enabled: always,
+		rules: []rbacv1.PolicyRule{
+			rule("core.platform-mesh.io", allVerbs, "platformmeshes", "platformmeshlandscapes"),
+			rule("core.platform-mesh.io", []string{"get", "patch", "update"}, "platformmeshes/status", "platformmeshlandscapes/status"),
+			rule("core.platform-mesh.io", []string{"update"}, "platformmeshes/finalizers", "platformmeshlandscapes/finalizers"),
+			rule("", allVerbs, "configmaps"),
+			rule("", []string{"create", "patch"}, "events"),
+			rule("events.k8s.io", []string{"create", "patch"}, "events"),
+			rule("apiextensions.k8s.io", readVerbs, "customresourcedefinitions"),
+			// The operator restarts its own Deployment to get an istio
+			// sidecar injected.
+			rule("apps", []string{"get", "list", "patch", "watch"}, "deployments"),
+		},
+	},
+	{
+		name:    "BootstrapSubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.Bootstrap.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("apiextensions.k8s.io", allVerbs, "customresourcedefinitions"),
+			rule("", allVerbs, "namespaces", "serviceaccounts", "services", "resourcequotas"),
+			rule("apps", allVerbs, "deployments"),
+			rule("networking.k8s.io", allVerbs, "networkpolicies"),
+			rule("rbac.authorization.k8s.io", append([]string{"bind", "escalate"}, allVerbs...), "clusterroles", "clusterrolebindings"),
+			rule("helm.toolkit.fluxcd.io", allVerbs, "helmreleases"),
+			rule("source.toolkit.fluxcd.io", allVerbs, "helmrepositories"),
+		},
+	},
+	{
+		name:    "DeploymentSubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.Deployment.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", allVerbs, "secrets"),
+			rule("", []string{"delete", "get", "list"}, "pods"),
+			rule("core.platform-mesh.io", allVerbs, "rendersnapshots"),
+			rule("core.platform-mesh.io", []string{"get", "patch", "update"}, "rendersnapshots/status"),
+			rule("", readVerbs, "services"),
+			rule("delivery.ocm.software", allVerbs, "resources", "components", "repositories"),
+			rule("helm.toolkit.fluxcd.io", allVerbs, "helmreleases"),
+			rule("source.toolkit.fluxcd.io", allVerbs, "ocirepositories", "helmrepositories", "gitrepositories"),
+			rule("kustomize.toolkit.fluxcd.io", allVerbs, "kustomizations"),
+			rule("argoproj.io", allVerbs, "applications", "appprojects"),
+			rule("cert-manager.io", allVerbs, "certificates", "issuers"),
+			rule("policy", allVerbs, "poddisruptionbudgets"),
+			rule("autoscaling", allVerbs, "horizontalpodautoscalers"),
+			rule("batch", allVerbs, "jobs"),
+			rule("operator.kcp.io", readVerbs, "rootshards", "frontproxies"),
+		},
+	},
+	{
+		name:    "OpenFGASubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.OpenFGA.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", readVerbs, "configmaps"),
+		},
+	},
+	{
+		name:    "IdentitySubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.Identity.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", allVerbs, "secrets"),
+			rule("helm.toolkit.fluxcd.io", readVerbs, "helmreleases"),
+			rule("argoproj.io", readVerbs, "applications"),
+		},
+	},
+	{
+		name:    "KcpsetupSubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.KcpSetup.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", readVerbs, "secrets"),
+			rule("", allVerbs, "namespaces", "resourcequotas", "limitranges"),
+			rule("operator.kcp.io", readVerbs, "rootshards", "frontproxies"),
+		},
+	},
+	{
+		name:    "ProvidersecretSubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.ProviderSecret.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", allVerbs, "secrets"),
+			rule("cert-manager.io", allVerbs, "certificates"),
+			rule("operator.kcp.io", readVerbs, "rootshards", "frontproxies"),
+			rule("helm.toolkit.fluxcd.io", readVerbs, "helmreleases"),
+		},
+	},
+	{
+		name:    "WaitSubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.Wait.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("helm.toolkit.fluxcd.io", readVerbs, "helmreleases"),
+		},
+	},
+	{
+		name: "ManagedProviderReconciler",
+		enabled: func(cfg *config.OperatorConfig) bool {
+			mp := cfg.Subroutines.ManagedProvider
+			return mp.WaitPlatformMesh.Enabled || mp.ProviderResource.Enabled || mp.WaitProvider.Enabled || mp.KubeconfigCopy.Enabled || mp.Deploy.Enabled
+		},
+		rules: []rbacv1.PolicyRule{
+			rule("providers.platform-mesh.io", allVerbs, "managedproviders"),
+			rule("providers.platform-mesh.io", []string{"get", "patch", "update"}, "managedproviders/status"),
+			rule("providers.platform-mesh.io", []string{"update"}, "managedproviders/finalizers"),
+		},
+	},
+	{
+		name:    "KubeconfigCopySubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.ManagedProvider.KubeconfigCopy.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("", allVerbs, "secrets"),
+		},
+	},
+	{
+		name:    "DeploySubroutine",
+		enabled: func(cfg *config.OperatorConfig) bool { return cfg.Subroutines.ManagedProvider.Deploy.Enabled },
+		rules: []rbacv1.PolicyRule{
+			rule("helm.toolkit.fluxcd.io", allVerbs, "helmreleases"),
+			rule("source.toolkit.fluxcd.io", allVerbs, "ocirepositories", "helmrepositories"),
+			rule("delivery.ocm.software", allVerbs, "resources", "components", "repositories"),
+		},
+	},
+}
+
+// Permission is a single verb on a resource.
+type Permission struct {
+	Group    string
+	Resource string
+	Verb     string
+	// RequiredBy lists the parts of the operator that need the permission.
+	RequiredBy []string
+}
+
+// String renders p in kubectl notation, e.g. "patch platformmeshes.core.platform-mesh.io/status".
+func (p Permission) String() string {
+	resource, subresource, _ := strings.Cut(p.Resource, "/")
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if subresource != "" {
+		resource += "/" + subresource
+	}
+	return fmt.Sprintf("%s %s (required by %s)", p.Verb, resource, strings.Join(p.RequiredBy, ", "))
+}
+
+// Permissions returns the permissions needed with cfg, sorted by group,
+// resource and verb.
+func Permissions(cfg *config.OperatorConfig) []Permission {
+	byKey := map[[3]string]*Permission{}
+	for _, set := range permissionSets {
+		if !set.enabled(cfg) {
+			continue
+		}
+		for _, r := range set.rules {
+			for _, group := range r.APIGroups {
+				for _, resource := range r.Resources {
+					for _, verb := range r.Verbs {
+						key := [3]string{group, resource, verb}
+						p, ok := byKey[key]
+						if !ok {
+							p = &Permission{Group: group, Resource: resource, Verb: verb}
+							byKey[key] = p
+						}
+						if !contains(p.RequiredBy, set.name) {
+							p.RequiredBy = append(p.RequiredBy, set.name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	perms := make([]Permission, 0, len(byKey))
+	for _, p := range byKey {
+		perms = append(perms, *p)
+	}
+	sort.Slice(perms, func(i, j int) bool {
+		a, b := perms[i], perms[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Verb < b.Verb
+	})
+	return perms
+}
+
+// ClusterRole returns the minimal ClusterRole for cfg. Resources of a group
+// that need the same verbs share a rule, like controller-gen renders them.
+func ClusterRole(name string, cfg *config.OperatorConfig) *rbacv1.ClusterRole {
+	verbs := map[[2]string][]string{}
+	for _, p := range Permissions(cfg) {
+		key := [2]string{p.Group, p.Resource}
+		verbs[key] = append(verbs[key], p.Verb)
+	}
+
+	rulesByKey := map[string]*rbacv1.PolicyRule{}
+	var keys []string
+	for key, v := range verbs {
+		ruleKey := key[0] + "|" + strings.Join(v, ",")
+		r, ok := rulesByKey[ruleKey]
+		if !ok {
+			r = &rbacv1.PolicyRule{APIGroups: []string{key[0]}, Verbs: v}
+			rulesByKey[ruleKey] = r
+			keys = append(keys, ruleKey)
+		}
+		r.Resources = append(r.Resources, key[1])
+	}
+	sort.Strings(keys)
+
+	role := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	for _, key := range keys {
+		r := rulesByKey[key]
+		sort.Strings(r.Resources)
+		role.Rules = append(role.Rules, *r)
+	}
+	return role
+}
+
+// Missing returns the permissions needed with cfg that the identity of c does
+// not have cluster-wide, checked with SelfSubjectAccessReviews.
+func Missing(ctx context.Context, c client.Client, cfg *config.OperatorConfig) ([]Permission, error) {
+	var missing []Permission
+	for _, p := range Permissions(cfg) {
+		resource, subresource, _ := strings.Cut(p.Resource, "/")
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Group:       p.Group,
+					Resource:    resource,
+					Subresource: subresource,
+					Verb:        p.Verb,
+				},
+			},
+		}
+		if err := c.Create(ctx, review); err != nil {
+			return nil, fmt.Errorf("checking permission %s: %w", p, err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func allDisabled() *config.OperatorConfig {
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines = config.SubroutinesConfig{}
+	return &cfg
+}
+
+func hasPermission(perms []Permission, group, resource, verb string) bool {
+	for _, p := range perms {
+		if p.Group == group && p.Resource == resource && p.Verb == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPermissions_FollowEnabledSubroutines(t *testing.T) {
+	cfg := allDisabled()
+	perms := Permissions(cfg)
+	assert.True(t, hasPermission(perms, "core.platform-mesh.io", "platformmeshes", "watch"))
+	assert.False(t, hasPermission(perms, "", "secrets", "get"))
+	assert.False(t, hasPermission(perms, "rbac.authorization.k8s.io", "clusterroles", "create"))
+
+	cfg.Subroutines.Bootstrap.Enabled = true
+	perms = Permissions(cfg)
+	assert.True(t, hasPermission(perms, "rbac.authorization.k8s.io", "clusterroles", "escalate"))
+	assert.False(t, hasPermission(perms, "", "secrets", "get"))
+}
+
+// TestPermissions_CoverGeneratedRole fails when a kubebuilder marker grants
+// a permission the permission sets miss, so the startup check and the
+// minimal ClusterRole do not fall behind config/rbac/role.yaml.
+func TestPermissions_CoverGeneratedRole(t *testing.T) {
+	data, err := os.ReadFile("../../config/rbac/role.yaml")
+	require.NoError(t, err)
+	generated := &rbacv1.ClusterRole{}
+	require.NoError(t, yaml.Unmarshal(data, generated))
+	require.NotEmpty(t, generated.Rules)
+
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.ManagedProvider.Deploy.Enabled = true
+	cfg.Subroutines.ManagedProvider.KubeconfigCopy.Enabled = true
+	perms := Permissions(&cfg)
+	for _, r := range generated.Rules {
+		for _, group := range r.APIGroups {
+			for _, resource := range r.Resources {
+				for _, verb := range r.Verbs {
+					assert.True(t, hasPermission(perms, group, resource, verb), "%s %s.%s not in any permission set", verb, resource, group)
+				}
+			}
+		}
+	}
+}
+
+func TestPermissions_RequiredByMerged(t *testing.T) {
+	cfg := allDisabled()
+	cfg.Subroutines.Deployment.Enabled = true
+	cfg.Subroutines.ProviderSecret.Enabled = true
+
+	for _, p := range Permissions(cfg) {
+		if p.Group == "" && p.Resource == "secrets" && p.Verb == "create" {
+			assert.Equal(t, []string{"DeploymentSubroutine", "ProvidersecretSubroutine"}, p.RequiredBy)
+			assert.Equal(t, "create secrets (required by DeploymentSubroutine, ProvidersecretSubroutine)", p.String())
+			return
+		}
+	}
+	t.Fatal("create secrets not required")
+}
+
+func TestClusterRole_GroupsResourcesWithSameVerbs(t *testing.T) {
+	role := ClusterRole("manager-role", allDisabled())
+
+	assert.Equal(t, "ClusterRole", role.Kind)
+	assert.Equal(t, "manager-role", role.Name)
+	assert.Contains(t, role.Rules, rbacv1.PolicyRule{
+		APIGroups: []string{"core.platform-mesh.io"},
+		Resources: []string{"platformmeshes/finalizers", "platformmeshlandscapes/finalizers"},
+		Verbs:     []string{"update"},
+	})
+	assert.Contains(t, role.Rules, rbacv1.PolicyRule{
+		APIGroups: []string{"core.platform-mesh.io"},
+		Resources: []string{"platformmeshes", "platformmeshlandscapes"},
+		Verbs:     []string{"create", "delete", "get", "list", "patch", "update", "watch"},
+	})
+	assert.Equal(t, role, ClusterRole("manager-role", allDisabled()))
+}
+
+func TestMissing(t *testing.T) {
+	cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SelfSubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = !(attrs.Resource == "platformmeshes" && attrs.Subresource == "status" && attrs.Verb == "patch")
+			return nil
+		},
+	}).Build()
+
+	missing, err := Missing(context.Background(), cl, allDisabled())
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "patch platformmeshes.core.platform-mesh.io/status (required by PlatformMeshReconciler)", missing[0].String())
+}
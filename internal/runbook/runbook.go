@@ -0,0 +1,183 @@
+// Package runbook holds the runbook references of condition and event reasons,
+// so the conditions and events the operator reports carry a pointer to their
+// remediation.
+package runbook
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/decorate"
+)
+
+// Registry maps condition and event reasons to runbook references, a URL or
+// a short remediation snippet.
+type Registry struct {
+	mu       sync.RWMutex
+	runbooks map[string]string
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the process-wide Registry.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{runbooks: map[string]string{}}
+}
+
+// Apply replaces the runbooks with the contents of a runbook ConfigMap, whose
+// keys are reasons. Empty references are ignored.
+func (r *Registry) Apply(data map[string]string) {
+	runbooks := map[string]string{}
+	for reason, ref := range data {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			runbooks[reason] = ref
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runbooks = runbooks
+}
+
+// Lookup returns the runbook reference of reason, or "" if there is none.
+func (r *Registry) Lookup(reason string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.runbooks[reason]
+}
+
+// Runbooks returns a copy of all runbook references by reason.
+func (r *Registry) Runbooks() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.runbooks)
+}
+
+// Annotate appends the runbook reference of reason to message. Messages of
+// reasons without a runbook are returned unchanged.
+func (r *Registry) Annotate(reason, message string) string {
+	ref := r.Lookup(reason)
+	if ref == "" {
+		return message
+	}
+	if message == "" {
+		return "runbook: " + ref
+	}
+	return message + " (runbook: " + ref + ")"
+}
+
+// AnnotateConditions appends the runbook reference of their reason to the
+// messages of all conditions that are not True. Messages that already carry
+// the reference are left alone.
+func (r *Registry) AnnotateConditions(conditions []metav1.Condition) {
+	for i := range conditions {
+		cond := &conditions[i]
+		if cond.Status == metav1.ConditionTrue {
+			continue
+		}
+		ref := r.Lookup(cond.Reason)
+		if ref == "" || strings.Contains(cond.Message, "runbook: "+ref) {
+			continue
+		}
+		cond.Message = r.Annotate(cond.Reason, cond.Message)
+	}
+}
+
+// conditionsObject is an object reporting conditions, like PlatformMesh.
+type conditionsObject interface {
+	GetConditions() []metav1.Condition
+	SetConditions([]metav1.Condition)
+}
+
+// Wrap returns the given subroutines with the conditions they leave on the
+// object annotated with their runbook references from r.
+func Wrap(r *Registry, subs ...subroutines.Subroutine) []subroutines.Subroutine {
+	return decorate.Wrap(subs, r)
+}
+
+// Before does nothing, see decorate.Hook.
+func (r *Registry) Before(ctx context.Context, _ subroutines.Subroutine, _ client.Object) context.Context {
+	return ctx
+}
+
+// After annotates the conditions the subroutine left on obj with their
+// runbook references, see decorate.Hook.
+func (r *Registry) After(_ context.Context, _ subroutines.Subroutine, obj client.Object, _ subroutines.Result, _ error) {
+	if o, ok := obj.(conditionsObject); ok {
+		conditions := o.GetConditions()
+		r.AnnotateConditions(conditions)
+		o.SetConditions(conditions)
+	}
+}
+
+// ConfigMapWatcher periodically reads a ConfigMap and applies it to a Registry.
+// It implements manager.Runnable and runs on every replica.
+type ConfigMapWatcher struct {
+	client   client.Client
+	key      types.NamespacedName
+	interval time.Duration
+	registry *Registry
+	log      *logger.Logger
+
+	lastResourceVersion string
+}
+
+func NewConfigMapWatcher(cl client.Client, key types.NamespacedName, interval time.Duration, registry *Registry, log *logger.Logger) *ConfigMapWatcher {
+	return &ConfigMapWatcher{
+		client:   cl,
+		key:      key,
+		interval: interval,
+		registry: registry,
+		log:      log.ChildLogger("component", "runbook"),
+	}
+}
+
+func (w *ConfigMapWatcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, w.Sync, w.interval)
+	return nil
+}
+
+func (w *ConfigMapWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync applies the ConfigMap if it changed since the last call. A deleted
+// ConfigMap removes all runbooks.
+func (w *ConfigMapWatcher) Sync(ctx context.Context) {
+	cm := &corev1.ConfigMap{}
+	if err := w.client.Get(ctx, w.key, cm); err != nil {
+		if !kerrors.IsNotFound(err) {
+			w.log.Error().Err(err).Str("configmap", w.key.String()).Msg("Failed to get runbook ConfigMap")
+			return
+		}
+		if w.lastResourceVersion != "" {
+			w.registry.Apply(nil)
+			w.lastResourceVersion = ""
+			w.log.Info().Str("configmap", w.key.String()).Msg("Runbook ConfigMap was deleted, removed all runbooks")
+		}
+		return
+	}
+	if cm.ResourceVersion == w.lastResourceVersion {
+		return
+	}
+	w.registry.Apply(cm.Data)
+	w.lastResourceVersion = cm.ResourceVersion
+	w.log.Info().Str("configmap", w.key.String()).Int("runbooks", len(w.registry.Runbooks())).Msg("Applied runbooks from ConfigMap")
+}
@@ -0,0 +1,101 @@
+package runbook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	assert.Equal(t, "RootShard is not ready", r.Annotate("WaitingForRootShard", "RootShard is not ready"))
+
+	r.Apply(map[string]string{
+		"WaitingForRootShard": " https://runbooks.example.com/root-shard \n",
+		"ProfileMissing":      "Create the profile ConfigMap <instance>-profile",
+		"Empty":               " ",
+	})
+	assert.Equal(t, map[string]string{
+		"WaitingForRootShard": "https://runbooks.example.com/root-shard",
+		"ProfileMissing":      "Create the profile ConfigMap <instance>-profile",
+	}, r.Runbooks())
+	assert.Equal(t, "RootShard is not ready (runbook: https://runbooks.example.com/root-shard)", r.Annotate("WaitingForRootShard", "RootShard is not ready"))
+	assert.Equal(t, "runbook: Create the profile ConfigMap <instance>-profile", r.Annotate("ProfileMissing", ""))
+
+	r.Apply(nil)
+	assert.Empty(t, r.Lookup("WaitingForRootShard"))
+}
+
+func TestAnnotateConditions(t *testing.T) {
+	r := NewRegistry()
+	r.Apply(map[string]string{"WaitingForRootShard": "https://runbooks.example.com/root-shard", "Ready": "https://runbooks.example.com/ready"})
+	conditions := []metav1.Condition{
+		{Type: "KcpSetupReady", Status: metav1.ConditionFalse, Reason: "WaitingForRootShard", Message: "RootShard is not ready"},
+		{Type: "DeploymentReady", Status: metav1.ConditionTrue, Reason: "Ready", Message: "all steps completed"},
+	}
+
+	r.AnnotateConditions(conditions)
+	r.AnnotateConditions(conditions)
+	assert.Equal(t, "RootShard is not ready (runbook: https://runbooks.example.com/root-shard)", conditions[0].Message)
+	assert.Equal(t, "all steps completed", conditions[1].Message)
+}
+
+type conditionSubroutine struct{}
+
+func (conditionSubroutine) GetName() string { return "conditionSubroutine" }
+
+func (conditionSubroutine) Process(_ context.Context, obj client.Object) (subroutines.Result, error) {
+	inst := obj.(*corev1alpha1.PlatformMesh)
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type: "KcpSetupReady", Status: metav1.ConditionFalse, Reason: "WaitingForRootShard", Message: "RootShard is not ready",
+	})
+	return subroutines.OK(), errors.New("not ready")
+}
+
+func TestWrap(t *testing.T) {
+	r := NewRegistry()
+	r.Apply(map[string]string{"WaitingForRootShard": "https://runbooks.example.com/root-shard"})
+	subs := Wrap(r, conditionSubroutine{})
+	require.Len(t, subs, 1)
+	assert.Equal(t, "conditionSubroutine", subs[0].GetName())
+
+	inst := &corev1alpha1.PlatformMesh{}
+	_, err := subs[0].(subroutines.Processor).Process(context.Background(), inst)
+	assert.EqualError(t, err, "not ready")
+	assert.Equal(t, "RootShard is not ready (runbook: https://runbooks.example.com/root-shard)", inst.Status.Conditions[0].Message)
+	_, ok := subs[0].(subroutines.Finalizer)
+	assert.False(t, ok)
+}
+
+func TestConfigMapWatcher(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "runbooks", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"WaitingForRootShard": "https://runbooks.example.com/root-shard"},
+	}
+	cl := fake.NewClientBuilder().WithObjects(cm).Build()
+	r := NewRegistry()
+	w := NewConfigMapWatcher(cl, types.NamespacedName{Name: "runbooks", Namespace: "platform-mesh-system"}, time.Second, r, log)
+
+	w.Sync(context.Background())
+	assert.Equal(t, "https://runbooks.example.com/root-shard", r.Lookup("WaitingForRootShard"))
+
+	require.NoError(t, cl.Delete(context.Background(), cm))
+	w.Sync(context.Background())
+	assert.Empty(t, r.Runbooks())
+}
@@ -0,0 +1,205 @@
+// Package secretinspect decodes the kubeconfigs of provider and initializer
+// secrets for debugging.
+package secretinspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Report describes the current context of a kubeconfig.
+type Report struct {
+	Context       string
+	Cluster       string
+	User          string
+	Server        string
+	TLSServerName string
+	// CAFingerprint is the SHA-256 fingerprint of the first CA certificate,
+	// empty if the kubeconfig embeds none.
+	CAFingerprint string
+	CAExpiry      *time.Time
+	// Auth is token, client-certificate, exec or none.
+	Auth string
+	// TokenExpiry is the exp claim of a JWT bearer token. It is nil for
+	// tokens without one.
+	TokenExpiry *time.Time
+	// ClientCertExpiry is the NotAfter of the client certificate.
+	ClientCertExpiry  *time.Time
+	ClientCertSubject string
+}
+
+// Inspect decodes the kubeconfig in data and reports its current context.
+// Embedded certificates and tokens are decoded, but nothing is verified.
+func Inspect(data []byte) (*Report, error) {
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	kctx, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q not found", kubeconfig.CurrentContext)
+	}
+	report := &Report{Context: kubeconfig.CurrentContext, Cluster: kctx.Cluster, User: kctx.AuthInfo}
+
+	cluster, ok := kubeconfig.Clusters[kctx.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q of context %q not found", kctx.Cluster, report.Context)
+	}
+	report.Server = cluster.Server
+	report.TLSServerName = cluster.TLSServerName
+	if len(cluster.CertificateAuthorityData) > 0 {
+		cert, err := firstCertificate(cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode CA certificate: %w", err)
+		}
+		sum := sha256.Sum256(cert.Raw)
+		report.CAFingerprint = fingerprint(sum[:])
+		report.CAExpiry = &cert.NotAfter
+	}
+
+	if user, ok := kubeconfig.AuthInfos[kctx.AuthInfo]; ok {
+		if err := inspectAuth(report, user); err != nil {
+			return nil, err
+		}
+	} else {
+		report.Auth = "none"
+	}
+	return report, nil
+}
+
+func inspectAuth(report *Report, user *clientcmdapi.AuthInfo) error {
+	switch {
+	case user.Token != "":
+		report.Auth = "token"
+		report.TokenExpiry = tokenExpiry(user.Token)
+	case len(user.ClientCertificateData) > 0:
+		report.Auth = "client-certificate"
+		cert, err := firstCertificate(user.ClientCertificateData)
+		if err != nil {
+			return fmt.Errorf("failed to decode client certificate: %w", err)
+		}
+		report.ClientCertExpiry = &cert.NotAfter
+		report.ClientCertSubject = cert.Subject.String()
+	case user.Exec != nil:
+		report.Auth = "exec"
+	default:
+		report.Auth = "none"
+	}
+	return nil
+}
+
+func firstCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func fingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// tokenExpiry returns the exp claim of a JWT without verifying it.
+func tokenExpiry(token string) *time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return nil
+	}
+	exp := time.Unix(claims.Exp, 0).UTC()
+	return &exp
+}
+
+// Probe connects with the kubeconfig in data and returns the server version
+// reported by discovery.
+func Probe(ctx context.Context, data []byte) (string, error) {
+	restCfg, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to build rest config: %w", err)
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	body, err := dc.RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("discovery failed: %w", err)
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("failed to decode server version: %w", err)
+	}
+	return info.GitVersion, nil
+}
+
+// Write prints report in a compact form. Expiries are shown relative to now.
+func Write(w io.Writer, report *Report, now time.Time) error {
+	lines := [][2]string{
+		{"Context", report.Context},
+		{"Cluster", report.Cluster},
+		{"Server", report.Server},
+	}
+	if report.TLSServerName != "" {
+		lines = append(lines, [2]string{"TLS server name", report.TLSServerName})
+	}
+	if report.CAFingerprint != "" {
+		lines = append(lines,
+			[2]string{"CA fingerprint", "SHA256 " + report.CAFingerprint},
+			[2]string{"CA expiry", expiry(report.CAExpiry, now)})
+	} else {
+		lines = append(lines, [2]string{"CA", "none embedded"})
+	}
+	lines = append(lines, [2]string{"User", report.User}, [2]string{"Auth", report.Auth})
+	switch report.Auth {
+	case "token":
+		lines = append(lines, [2]string{"Token expiry", expiry(report.TokenExpiry, now)})
+	case "client-certificate":
+		lines = append(lines,
+			[2]string{"Client cert subject", report.ClientCertSubject},
+			[2]string{"Client cert expiry", expiry(report.ClientCertExpiry, now)})
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintf(w, "%-20s %s\n", l[0]+":", l[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expiry(t *time.Time, now time.Time) string {
+	if t == nil {
+		return "unknown"
+	}
+	d := t.Sub(now).Round(time.Second)
+	if d < 0 {
+		return fmt.Sprintf("%s (expired %s ago)", t.UTC().Format(time.RFC3339), -d)
+	}
+	return fmt.Sprintf("%s (in %s)", t.UTC().Format(time.RFC3339), d)
+}
@@ -0,0 +1,125 @@
+package secretinspect
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func selfSignedPEM(t *testing.T, cn string, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func kubeconfig(t *testing.T, server string, caData []byte, user *clientcmdapi.AuthInfo) []byte {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["kcp"] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: caData}
+	cfg.AuthInfos["provider"] = user
+	cfg.Contexts["default"] = &clientcmdapi.Context{Cluster: "kcp", AuthInfo: "provider"}
+	cfg.CurrentContext = "default"
+	data, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+	return data
+}
+
+func jwt(exp int64) string {
+	enc := base64.RawURLEncoding
+	payload := enc.EncodeToString([]byte(`{"sub":"system:serviceaccount:default:provider","exp":` + big.NewInt(exp).String() + `}`))
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + payload + ".sig"
+}
+
+func TestInspect_Token(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ca := selfSignedPEM(t, "kcp-ca", now.Add(365*24*time.Hour))
+	data := kubeconfig(t, "https://kcp.example:8443/clusters/root:orgs", ca,
+		&clientcmdapi.AuthInfo{Token: jwt(now.Add(2 * time.Hour).Unix())})
+
+	report, err := Inspect(data)
+	require.NoError(t, err)
+	assert.Equal(t, "default", report.Context)
+	assert.Equal(t, "https://kcp.example:8443/clusters/root:orgs", report.Server)
+	assert.Equal(t, "token", report.Auth)
+	require.NotNil(t, report.TokenExpiry)
+	assert.Equal(t, now.Add(2*time.Hour), *report.TokenExpiry)
+	assert.Len(t, report.CAFingerprint, 32*3-1)
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, report, now))
+	assert.Contains(t, out.String(), "Server:              https://kcp.example:8443/clusters/root:orgs\n")
+	assert.Contains(t, out.String(), "Token expiry:        2026-01-01T02:00:00Z (in 2h0m0s)\n")
+	assert.Contains(t, out.String(), "CA fingerprint:      SHA256 "+report.CAFingerprint+"\n")
+}
+
+func TestInspect_ClientCertificate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := kubeconfig(t, "https://kcp.example:8443", nil,
+		&clientcmdapi.AuthInfo{ClientCertificateData: selfSignedPEM(t, "provider", now.Add(-time.Hour)), ClientKeyData: []byte("key")})
+
+	report, err := Inspect(data)
+	require.NoError(t, err)
+	assert.Equal(t, "client-certificate", report.Auth)
+	assert.Equal(t, "CN=provider", report.ClientCertSubject)
+	assert.Empty(t, report.CAFingerprint)
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, report, now))
+	assert.Contains(t, out.String(), "CA:                  none embedded\n")
+	assert.Contains(t, out.String(), "(expired 1h0m0s ago)")
+}
+
+func TestInspect_Invalid(t *testing.T) {
+	_, err := Inspect([]byte("not: [a kubeconfig"))
+	assert.Error(t, err)
+
+	cfg := clientcmdapi.NewConfig()
+	cfg.CurrentContext = "missing"
+	data, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+	_, err = Inspect(data)
+	assert.EqualError(t, err, `current context "missing" not found`)
+}
+
+func TestTokenExpiry_NotJWT(t *testing.T) {
+	assert.Nil(t, tokenExpiry("opaque-token"))
+	assert.Nil(t, tokenExpiry(jwt(0)))
+}
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.31.0+kcp-v0.28.0"}`))
+	}))
+	defer srv.Close()
+
+	version, err := Probe(context.Background(), kubeconfig(t, srv.URL, nil, &clientcmdapi.AuthInfo{Token: "t"}))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.31.0+kcp-v0.28.0", version)
+}
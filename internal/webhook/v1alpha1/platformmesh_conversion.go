@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	corev1alpha2 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha2"
+)
+
+// PlatformMeshConversionPath is the path the conversion webhook of
+// PlatformMesh is served on.
+const PlatformMeshConversionPath = "/convert"
+
+// PlatformMeshConverter answers the ConversionReviews of the API server for
+// PlatformMesh objects. Objects are converted through the v1alpha1 hub.
+type PlatformMeshConverter struct{}
+
+var _ http.Handler = &PlatformMeshConverter{}
+
+// ServeHTTP converts the objects of the ConversionReview in the request body.
+func (c *PlatformMeshConverter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextensionsv1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+	review.Response = convertRequest(review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode ConversionReview: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// convertRequest converts all objects of req or none of them.
+func convertRequest(req *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	res := &apiextensionsv1.ConversionResponse{UID: req.UID, Result: metav1.Status{Status: metav1.StatusSuccess}}
+	for _, obj := range req.Objects {
+		converted, err := ConvertPlatformMesh(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			return &apiextensionsv1.ConversionResponse{
+				UID:    req.UID,
+				Result: metav1.Status{Status: metav1.StatusFailure, Message: err.Error()},
+			}
+		}
+		res.ConvertedObjects = append(res.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	return res
+}
+
+// ConvertPlatformMesh converts the PlatformMesh in raw to desiredAPIVersion.
+func ConvertPlatformMesh(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("failed to decode object: %w", err)
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	hub := &corev1alpha1.PlatformMesh{}
+	switch typeMeta.APIVersion {
+	case corev1alpha1.GroupVersion.String():
+		if err := json.Unmarshal(raw, hub); err != nil {
+			return nil, fmt.Errorf("failed to decode %s PlatformMesh: %w", typeMeta.APIVersion, err)
+		}
+	case corev1alpha2.GroupVersion.String():
+		spoke := &corev1alpha2.PlatformMesh{}
+		if err := json.Unmarshal(raw, spoke); err != nil {
+			return nil, fmt.Errorf("failed to decode %s PlatformMesh: %w", typeMeta.APIVersion, err)
+		}
+		if err := spoke.ConvertTo(hub); err != nil {
+			return nil, fmt.Errorf("failed to convert PlatformMesh %s/%s: %w", spoke.Namespace, spoke.Name, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported API version %q", typeMeta.APIVersion)
+	}
+
+	var out runtime.Object
+	switch desiredAPIVersion {
+	case corev1alpha1.GroupVersion.String():
+		out = hub
+	case corev1alpha2.GroupVersion.String():
+		spoke := &corev1alpha2.PlatformMesh{}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			return nil, fmt.Errorf("failed to convert PlatformMesh %s/%s: %w", hub.Namespace, hub.Name, err)
+		}
+		out = spoke
+	default:
+		return nil, fmt.Errorf("unsupported API version %q", desiredAPIVersion)
+	}
+	out.GetObjectKind().SetGroupVersionKind(schema.FromAPIVersionAndKind(desiredAPIVersion, typeMeta.Kind))
+	return json.Marshal(out)
+}
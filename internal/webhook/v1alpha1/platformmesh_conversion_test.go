@@ -0,0 +1,66 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	corev1alpha2 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha2"
+)
+
+func TestPlatformMeshConverter(t *testing.T) {
+	convert := func(desiredAPIVersion string, objects ...string) *apiextensionsv1.ConversionResponse {
+		req := &apiextensionsv1.ConversionReview{Request: &apiextensionsv1.ConversionRequest{UID: "uid", DesiredAPIVersion: desiredAPIVersion}}
+		for _, obj := range objects {
+			req.Request.Objects = append(req.Request.Objects, runtime.RawExtension{Raw: []byte(obj)})
+		}
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		(&PlatformMeshConverter{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PlatformMeshConversionPath, bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		res := &apiextensionsv1.ConversionReview{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+		require.NotNil(t, res.Response)
+		assert.Equal(t, "uid", string(res.Response.UID))
+		return res.Response
+	}
+	v1alpha1Obj := `{"apiVersion":"core.platform-mesh.io/v1alpha1","kind":"PlatformMesh","metadata":{"name":"pm"},"spec":{"values":{"iam":{"values":{"image":{"tag":"v1.2.3"}}}}}}`
+
+	res := convert("core.platform-mesh.io/v1alpha2", v1alpha1Obj)
+	require.Equal(t, metav1.StatusSuccess, res.Result.Status, res.Result.Message)
+	require.Len(t, res.ConvertedObjects, 1)
+	spoke := &corev1alpha2.PlatformMesh{}
+	require.NoError(t, json.Unmarshal(res.ConvertedObjects[0].Raw, spoke))
+	assert.Equal(t, "core.platform-mesh.io/v1alpha2", spoke.APIVersion)
+	assert.Equal(t, "PlatformMesh", spoke.Kind)
+	assert.Equal(t, "pm", spoke.Name)
+	assert.Equal(t, "v1.2.3", spoke.Spec.Components["iam"].ImageTag)
+
+	res = convert("core.platform-mesh.io/v1alpha1", string(res.ConvertedObjects[0].Raw))
+	require.Equal(t, metav1.StatusSuccess, res.Result.Status, res.Result.Message)
+	require.Len(t, res.ConvertedObjects, 1)
+	hub := &corev1alpha1.PlatformMesh{}
+	require.NoError(t, json.Unmarshal(res.ConvertedObjects[0].Raw, hub))
+	assert.Equal(t, "core.platform-mesh.io/v1alpha1", hub.APIVersion)
+	assert.JSONEq(t, `{"iam":{"values":{"image":{"tag":"v1.2.3"}}}}`, string(hub.Spec.Values.Raw))
+
+	// A single object that cannot be converted fails the whole request.
+	res = convert("core.platform-mesh.io/v1alpha2", v1alpha1Obj, `{"apiVersion":"core.platform-mesh.io/v1beta1","kind":"PlatformMesh"}`)
+	assert.Equal(t, metav1.StatusFailure, res.Result.Status)
+	assert.Contains(t, res.Result.Message, "v1beta1")
+	assert.Empty(t, res.ConvertedObjects)
+
+	rec := httptest.NewRecorder()
+	(&PlatformMeshConverter{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PlatformMeshConversionPath, bytes.NewReader([]byte(`{}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
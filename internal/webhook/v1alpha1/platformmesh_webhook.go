@@ -0,0 +1,122 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 holds the admission and conversion webhooks of the
+// core.platform-mesh.io v1alpha1 resources.
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
+)
+
+// PlatformMeshDefaultingPath is the path the defaulting webhook of
+// PlatformMesh is served on.
+const PlatformMeshDefaultingPath = "/mutate-core-platform-mesh-io-v1alpha1-platformmesh"
+
+// SetupPlatformMeshWebhookWithServer registers the defaulting and the
+// conversion webhook of PlatformMesh with srv.
+func SetupPlatformMeshWebhookWithServer(srv webhook.Server) {
+	srv.Register(PlatformMeshDefaultingPath, &webhook.Admission{Handler: &PlatformMeshDefaulter{}})
+	srv.Register(PlatformMeshConversionPath, &PlatformMeshConverter{})
+}
+
+// +kubebuilder:webhook:path=/mutate-core-platform-mesh-io-v1alpha1-platformmesh,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.platform-mesh.io,resources=platformmeshes,verbs=create;update,versions=v1alpha1,name=mplatformmesh-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PlatformMeshDefaulter stores the exposure defaults the subroutines apply to
+// a PlatformMesh in the object itself and normalizes its workspace paths.
+type PlatformMeshDefaulter struct{}
+
+var _ admission.Handler = &PlatformMeshDefaulter{}
+
+// Handle returns the patch that defaults the PlatformMesh of req.
+func (d *PlatformMeshDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	inst := &corev1alpha1.PlatformMesh{}
+	if err := json.Unmarshal(req.Object.Raw, inst); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	Default(inst)
+	defaulted, err := json.Marshal(inst)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// Default fills in the exposure inst gets when it is unset and normalizes its
+// workspace paths. The default provider connections are not stored, the
+// subroutines add them at reconcile time, so they follow the operator version.
+func Default(inst *corev1alpha1.PlatformMesh) {
+	if inst.Spec.Exposure == nil {
+		inst.Spec.Exposure = &corev1alpha1.ExposureConfig{}
+	}
+	if inst.Spec.Exposure.BaseDomain == "" {
+		inst.Spec.Exposure.BaseDomain = subroutines.DefaultExposureBaseDomain
+	}
+	if inst.Spec.Exposure.Port == 0 {
+		inst.Spec.Exposure.Port = subroutines.DefaultExposurePort
+	}
+	if inst.Spec.Exposure.Protocol == "" {
+		inst.Spec.Exposure.Protocol = subroutines.DefaultExposureProtocol
+	}
+
+	kcp := &inst.Spec.Kcp
+	for i := range kcp.ProviderConnections {
+		kcp.ProviderConnections[i].Path = NormalizeWorkspacePath(kcp.ProviderConnections[i].Path)
+	}
+	for i := range kcp.ExtraProviderConnections {
+		kcp.ExtraProviderConnections[i].Path = NormalizeWorkspacePath(kcp.ExtraProviderConnections[i].Path)
+	}
+	for i := range kcp.ExtraDefaultAPIBindings {
+		kcp.ExtraDefaultAPIBindings[i].Path = NormalizeWorkspacePath(kcp.ExtraDefaultAPIBindings[i].Path)
+		kcp.ExtraDefaultAPIBindings[i].WorkspaceTypePath = NormalizeWorkspacePath(kcp.ExtraDefaultAPIBindings[i].WorkspaceTypePath)
+	}
+	for i := range kcp.ExtraWorkspaces {
+		kcp.ExtraWorkspaces[i].Path = NormalizeWorkspacePath(kcp.ExtraWorkspaces[i].Path)
+		kcp.ExtraWorkspaces[i].Type.Path = NormalizeWorkspacePath(kcp.ExtraWorkspaces[i].Type.Path)
+	}
+	for i := range kcp.RawManifests {
+		kcp.RawManifests[i].WorkspacePath = NormalizeWorkspacePath(kcp.RawManifests[i].WorkspacePath)
+	}
+	if dp := kcp.DeletionProtection; dp != nil {
+		for i := range dp.Workspaces {
+			dp.Workspaces[i] = NormalizeWorkspacePath(dp.Workspaces[i])
+		}
+		for i := range dp.Unlocked {
+			dp.Unlocked[i] = NormalizeWorkspacePath(dp.Unlocked[i])
+		}
+	}
+}
+
+// NormalizeWorkspacePath returns path without surrounding whitespace and
+// empty segments, e.g. "root:orgs" for " root::orgs: ".
+func NormalizeWorkspacePath(path string) string {
+	var segments []string
+	for _, segment := range strings.Split(path, ":") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, ":")
+}
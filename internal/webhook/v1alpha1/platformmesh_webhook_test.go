@@ -0,0 +1,79 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestDefault(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{}
+	Default(inst)
+	assert.Equal(t, &corev1alpha1.ExposureConfig{BaseDomain: "portal.localhost", Port: 8443, Protocol: "https"}, inst.Spec.Exposure)
+	// The default provider connections are added at reconcile time.
+	assert.Empty(t, inst.Spec.Kcp.ProviderConnections)
+
+	// Set values are kept.
+	inst = &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "example.com"},
+		Kcp: corev1alpha1.Kcp{
+			ProviderConnections: []corev1alpha1.ProviderConnection{{Path: "root:orgs:", Secret: "custom"}},
+			ExtraWorkspaces:     []corev1alpha1.WorkspaceDeclaration{{Path: " root::team", Type: corev1alpha1.WorkspaceTypeReference{Name: "team", Path: "root:"}}},
+			DeletionProtection:  &corev1alpha1.WorkspaceDeletionProtection{Workspaces: []string{"root:orgs:"}},
+		},
+	}}
+	Default(inst)
+	assert.Equal(t, &corev1alpha1.ExposureConfig{BaseDomain: "example.com", Port: 8443, Protocol: "https"}, inst.Spec.Exposure)
+	assert.Equal(t, []corev1alpha1.ProviderConnection{{Path: "root:orgs", Secret: "custom"}}, inst.Spec.Kcp.ProviderConnections)
+	assert.Equal(t, "root:team", inst.Spec.Kcp.ExtraWorkspaces[0].Path)
+	assert.Equal(t, "root", inst.Spec.Kcp.ExtraWorkspaces[0].Type.Path)
+	assert.Equal(t, []string{"root:orgs"}, inst.Spec.Kcp.DeletionProtection.Workspaces)
+}
+
+func TestNormalizeWorkspacePath(t *testing.T) {
+	assert.Equal(t, "root:orgs", NormalizeWorkspacePath(" root::orgs: "))
+	assert.Equal(t, "root", NormalizeWorkspacePath("root"))
+	assert.Empty(t, NormalizeWorkspacePath(""))
+}
+
+func TestPlatformMeshDefaulter_Handle(t *testing.T) {
+	raw := []byte(`{"apiVersion":"core.platform-mesh.io/v1alpha1","kind":"PlatformMesh","metadata":{"name":"pm","namespace":"platform-mesh-system"},"spec":{"exposure":{"baseDomain":"example.com","port":443,"protocol":"https"},"kcp":{"providerConnections":[{"path":"root","secret":"custom"}]}}}`)
+	res := (&PlatformMeshDefaulter{}).Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	require.True(t, res.Allowed)
+	// Set exposure and provider connections are not changed.
+	for _, patch := range res.Patches {
+		assert.NotContains(t, patch.Path, "/spec/exposure", "unexpected patch %v", patch)
+		assert.NotContains(t, patch.Path, "/spec/kcp", "unexpected patch %v", patch)
+	}
+
+	res = (&PlatformMeshDefaulter{}).Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"core.platform-mesh.io/v1alpha1","kind":"PlatformMesh","metadata":{"name":"pm"},"spec":{}}`)},
+	}})
+	require.True(t, res.Allowed)
+	var paths []string
+	for _, patch := range res.Patches {
+		paths = append(paths, patch.Path)
+		// No provider connections are stored.
+		assert.NotContains(t, patch.Path, "providerConnections")
+		if patch.Path == "/spec/kcp" {
+			assert.NotContains(t, patch.Value, "providerConnections")
+		}
+	}
+	assert.Contains(t, paths, "/spec/exposure")
+
+	res = (&PlatformMeshDefaulter{}).Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Object: runtime.RawExtension{Raw: []byte(`not json`)},
+	}})
+	assert.False(t, res.Allowed)
+}
@@ -0,0 +1,88 @@
+// Package bootstrap embeds pinned manifests of cluster prerequisites that the
+// operator installs on clusters where they are missing.
+package bootstrap
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed manifests
+var manifests embed.FS
+
+// Component is a prerequisite that can be bootstrapped from embedded manifests.
+type Component struct {
+	// Name identifies the component in logs and status messages.
+	Name string
+	// Version is the pinned version of the embedded manifests.
+	Version string
+	// CRDs are the CustomResourceDefinitions whose presence marks the component
+	// as installed.
+	CRDs []string
+
+	file string
+}
+
+var (
+	// Flux installs the Flux controllers.
+	Flux = Component{
+		Name:    "flux",
+		Version: "v2.6.4",
+		CRDs:    []string{"helmreleases.helm.toolkit.fluxcd.io", "helmrepositories.source.toolkit.fluxcd.io"},
+		file:    "manifests/flux2-v2.6.4/flux2-install.yaml",
+	}
+	// CertManager installs cert-manager as a Flux HelmRelease and therefore
+	// requires Flux.
+	CertManager = Component{
+		Name:    "cert-manager",
+		Version: "v1.18.2",
+		CRDs:    []string{"issuers.cert-manager.io", "certificates.cert-manager.io"},
+		file:    "manifests/cert-manager-v1.18.2/cert-manager.yaml",
+	}
+)
+
+// Objects returns the embedded objects of the component. Namespaces and
+// CustomResourceDefinitions come first so that the rest can be applied in order.
+func (c Component) Objects() ([]unstructured.Unstructured, error) {
+	data, err := manifests.ReadFile(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifests of %s: %w", c.Name, err)
+	}
+
+	content := strings.TrimPrefix(string(data), "---\n")
+	var objs []unstructured.Unstructured
+	for i, doc := range strings.Split(content, "\n---\n") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, fmt.Errorf("parsing document %d of %s: %w", i, c.Name, err)
+		}
+		if obj == nil {
+			continue
+		}
+		objs = append(objs, unstructured.Unstructured{Object: obj})
+	}
+
+	sort.SliceStable(objs, func(i, j int) bool {
+		return applyOrder(objs[i].GetKind()) < applyOrder(objs[j].GetKind())
+	})
+	return objs, nil
+}
+
+func applyOrder(kind string) int {
+	switch kind {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition":
+		return 1
+	default:
+		return 2
+	}
+}
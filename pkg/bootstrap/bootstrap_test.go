@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjects(t *testing.T) {
+	for _, c := range []Component{Flux, CertManager} {
+		t.Run(c.Name, func(t *testing.T) {
+			objs, err := c.Objects()
+			require.NoError(t, err)
+			require.NotEmpty(t, objs)
+
+			assert.Equal(t, "Namespace", objs[0].GetKind())
+			seenOther := false
+			for _, obj := range objs {
+				assert.NotEmpty(t, obj.GetKind())
+				assert.NotEmpty(t, obj.GetName())
+				switch obj.GetKind() {
+				case "Namespace", "CustomResourceDefinition":
+					assert.False(t, seenOther, "%s %s is applied after other objects", obj.GetKind(), obj.GetName())
+				default:
+					seenOther = true
+				}
+			}
+		})
+	}
+}
+
+func TestObjects_FluxProvidesCRDs(t *testing.T) {
+	objs, err := Flux.Objects()
+	require.NoError(t, err)
+
+	crds := map[string]bool{}
+	for _, obj := range objs {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds[obj.GetName()] = true
+		}
+	}
+	for _, name := range Flux.CRDs {
+		assert.True(t, crds[name], "embedded manifests do not contain CRD %s", name)
+	}
+}
+
+func TestObjects_UnknownFile(t *testing.T) {
+	_, err := Component{Name: "missing", file: "manifests/missing.yaml"}.Objects()
+	assert.Error(t, err)
+}
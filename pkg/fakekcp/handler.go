@@ -0,0 +1,552 @@
+package fakekcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// request is a parsed resource request.
+type request struct {
+	cluster     *logicalCluster
+	resource    resource
+	version     string
+	namespace   string
+	name        string
+	subresource string
+}
+
+func (req request) key() objectKey {
+	return objectKey{group: req.resource.group, resource: req.resource.name, namespace: req.namespace, name: req.name}
+}
+
+func (req request) groupResource() schema.GroupResource {
+	return schema.GroupResource{Group: req.resource.group, Resource: req.resource.name}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	clusterRef := RootCluster
+	if len(segments) >= 2 && segments[0] == "clusters" {
+		clusterRef, segments = segments[1], segments[2:]
+	}
+	cluster, ok := s.cluster(clusterRef)
+	if !ok || !s.clusterReady(cluster) {
+		writeError(w, apierrors.NewForbidden(schema.GroupResource{}, clusterRef, fmt.Errorf("access to workspace %s is not permitted", clusterRef)))
+		return
+	}
+
+	if r.Method == http.MethodGet && s.serveDiscovery(w, segments) {
+		return
+	}
+	req, err := s.parseRequest(cluster, segments)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("watch") == "true":
+		writeError(w, apierrors.NewMethodNotSupported(req.groupResource(), "watch"))
+	case r.Method == http.MethodGet && req.name == "":
+		s.list(w, r, req)
+	case r.Method == http.MethodGet:
+		s.get(w, req)
+	case r.Method == http.MethodPost && req.subresource == "token":
+		s.createToken(w, r, req)
+	case r.Method == http.MethodPost && req.name == "":
+		s.create(w, r, req)
+	case r.Method == http.MethodPut && req.name != "":
+		s.update(w, r, req)
+	case r.Method == http.MethodPatch && req.name != "":
+		s.patch(w, r, req)
+	case r.Method == http.MethodDelete && req.name != "":
+		s.delete(w, req)
+	default:
+		writeError(w, apierrors.NewMethodNotSupported(req.groupResource(), r.Method))
+	}
+}
+
+// clusterReady reports whether the workspace of c is Ready; kcp denies access
+// to workspaces that are still initializing.
+func (s *Server) clusterReady(c *logicalCluster) bool {
+	if c.path == RootCluster {
+		return true
+	}
+	parent, name := splitPath(c.path)
+	p, ok := s.clusters[parent]
+	if !ok {
+		return false
+	}
+	ws, ok := p.objects[objectKey{group: "tenancy.kcp.io", resource: "workspaces", name: name}]
+	if !ok {
+		return false
+	}
+	phase, _, _ := unstructured.NestedString(ws, "status", "phase")
+	return phase == "Ready"
+}
+
+func (s *Server) serveDiscovery(w http.ResponseWriter, segments []string) bool {
+	switch {
+	case len(segments) == 1 && segments[0] == "api":
+		writeJSON(w, http.StatusOK, s.apiVersions())
+	case len(segments) == 1 && segments[0] == "apis":
+		writeJSON(w, http.StatusOK, s.apiGroupList())
+	case len(segments) == 2 && segments[0] == "api":
+		list, ok := s.apiResourceList("", segments[1])
+		if !ok {
+			writeError(w, apierrors.NewNotFound(schema.GroupResource{}, segments[1]))
+			return true
+		}
+		writeJSON(w, http.StatusOK, list)
+	case len(segments) == 3 && segments[0] == "apis":
+		list, ok := s.apiResourceList(segments[1], segments[2])
+		if !ok {
+			writeError(w, apierrors.NewNotFound(schema.GroupResource{}, segments[1]+"/"+segments[2]))
+			return true
+		}
+		writeJSON(w, http.StatusOK, list)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) parseRequest(cluster *logicalCluster, segments []string) (request, error) {
+	req := request{cluster: cluster}
+	var group string
+	switch {
+	case len(segments) >= 3 && segments[0] == "api":
+		req.version, segments = segments[1], segments[2:]
+	case len(segments) >= 4 && segments[0] == "apis":
+		group, req.version, segments = segments[1], segments[2], segments[3:]
+	default:
+		return req, apierrors.NewNotFound(schema.GroupResource{}, strings.Join(segments, "/"))
+	}
+
+	// namespaces/<ns>/<resource>/... addresses namespaced resources, unless the
+	// third segment is a subresource of the namespace itself.
+	if len(segments) >= 3 && group == "" && segments[0] == "namespaces" && segments[2] != "status" {
+		req.namespace, segments = segments[1], segments[2:]
+	}
+	res, ok := s.lookupResource(group, req.version, segments[0])
+	if !ok {
+		return req, apierrors.NewNotFound(schema.GroupResource{Group: group, Resource: segments[0]}, "")
+	}
+	req.resource = res
+	if len(segments) > 1 {
+		req.name = segments[1]
+	}
+	if len(segments) > 2 {
+		req.subresource = segments[2]
+		if !res.hasSubresource(req.subresource) {
+			return req, apierrors.NewNotFound(req.groupResource(), req.name+"/"+req.subresource)
+		}
+	}
+	if len(segments) > 3 || (res.namespaced && req.namespace == "" && req.name != "") {
+		return req, apierrors.NewBadRequest("unsupported request path")
+	}
+	return req, nil
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, req request) {
+	selector := labels.Everything()
+	if raw := r.URL.Query().Get("labelSelector"); raw != "" {
+		var err error
+		if selector, err = labels.Parse(raw); err != nil {
+			writeError(w, apierrors.NewBadRequest(err.Error()))
+			return
+		}
+	}
+
+	var items []interface{}
+	var keys []objectKey
+	for key := range req.cluster.objects {
+		if key.group == req.resource.group && key.resource == req.resource.name && (req.namespace == "" || key.namespace == req.namespace) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		return keys[i].name < keys[j].name
+	})
+	for _, key := range keys {
+		obj := s.response(req, req.cluster.objects[key])
+		if selector.Matches(labels.Set((&unstructured.Unstructured{Object: obj}).GetLabels())) {
+			items = append(items, obj)
+		}
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"apiVersion": req.resource.apiVersion(req.version),
+		"kind":       req.resource.kind + "List",
+		"metadata":   map[string]interface{}{"resourceVersion": fmt.Sprintf("%d", s.resourceVersion)},
+		"items":      items,
+	})
+}
+
+func (s *Server) get(w http.ResponseWriter, req request) {
+	obj, ok := req.cluster.objects[req.key()]
+	if !ok {
+		writeError(w, apierrors.NewNotFound(req.groupResource(), req.name))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(req, obj))
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request, req request) {
+	obj, err := decodeBody(r)
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if u.GetName() == "" && u.GetGenerateName() != "" {
+		u.SetName(u.GetGenerateName() + rand5())
+	}
+	if u.GetName() == "" {
+		writeError(w, apierrors.NewBadRequest("metadata.name is required"))
+		return
+	}
+	if req.resource.namespaced {
+		u.SetNamespace(req.namespace)
+	}
+	req.name = u.GetName()
+	if _, exists := req.cluster.objects[req.key()]; exists {
+		writeError(w, apierrors.NewAlreadyExists(req.groupResource(), req.name))
+		return
+	}
+	if req.resource.status {
+		delete(obj, "status")
+	}
+	u.SetUID(uuid.NewUUID())
+	u.SetCreationTimestamp(metav1.Now())
+	u.SetGeneration(1)
+	u.SetDeletionTimestamp(nil)
+	if err := s.admitCreate(req, u); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.store(req.cluster, req.key(), obj)
+	s.afterCreate(req, u)
+	writeJSON(w, http.StatusCreated, s.response(req, obj))
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, req request) {
+	obj, err := decodeBody(r)
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	s.write(w, req, obj, true)
+}
+
+func (s *Server) patch(w http.ResponseWriter, r *http.Request, req request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	patchType := types.PatchType(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+
+	existing, exists := req.cluster.objects[req.key()]
+	if !exists {
+		if patchType != types.ApplyPatchType {
+			writeError(w, apierrors.NewNotFound(req.groupResource(), req.name))
+			return
+		}
+		obj, err := yamlToObject(body)
+		if err != nil {
+			writeError(w, apierrors.NewBadRequest(err.Error()))
+			return
+		}
+		r.Body = io.NopCloser(strings.NewReader(mustJSON(obj)))
+		r.Header.Set("Content-Type", "application/json")
+		req.name = ""
+		s.create(w, r, req)
+		return
+	}
+
+	original, err := json.Marshal(existing)
+	if err != nil {
+		writeError(w, apierrors.NewInternalError(err))
+		return
+	}
+	var patched []byte
+	switch patchType {
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, body)
+	case types.ApplyPatchType:
+		var applied []byte
+		if applied, err = yaml.YAMLToJSON(body); err == nil {
+			patched, err = jsonpatch.MergePatch(original, applied)
+		}
+	case types.JSONPatchType:
+		var p jsonpatch.Patch
+		if p, err = jsonpatch.DecodePatch(body); err == nil {
+			patched, err = p.Apply(original)
+		}
+	case types.StrategicMergePatchType:
+		gvk := schema.GroupVersionKind{Group: req.resource.group, Version: req.version, Kind: req.resource.kind}
+		typed, newErr := clientgoscheme.Scheme.New(gvk)
+		if newErr != nil {
+			writeError(w, unsupportedMediaType(patchType))
+			return
+		}
+		patched, err = strategicpatch.StrategicMergePatch(original, body, typed)
+	default:
+		writeError(w, unsupportedMediaType(patchType))
+		return
+	}
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(patched, &obj); err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	// Patches apply to the current state, so resourceVersion preconditions
+	// only hold if the patch sets one explicitly.
+	if !strings.Contains(string(body), `"resourceVersion"`) {
+		(&unstructured.Unstructured{Object: obj}).SetResourceVersion("")
+	}
+	s.write(w, req, obj, false)
+}
+
+// write stores obj as the new state of an existing object, honoring the
+// status subresource, resourceVersion preconditions and finalizers.
+func (s *Server) write(w http.ResponseWriter, req request, obj map[string]interface{}, replace bool) {
+	existing, ok := req.cluster.objects[req.key()]
+	if !ok {
+		writeError(w, apierrors.NewNotFound(req.groupResource(), req.name))
+		return
+	}
+	old := &unstructured.Unstructured{Object: existing}
+	u := &unstructured.Unstructured{Object: obj}
+	if rv := u.GetResourceVersion(); rv != "" && rv != old.GetResourceVersion() {
+		writeError(w, apierrors.NewConflict(req.groupResource(), req.name,
+			fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again")))
+		return
+	}
+
+	next := runtime.DeepCopyJSON(existing)
+	switch {
+	case req.subresource == "status":
+		if status, found := obj["status"]; found {
+			next["status"] = status
+		} else {
+			delete(next, "status")
+		}
+	case req.resource.status:
+		status, found := existing["status"]
+		next = obj
+		delete(next, "status")
+		if found {
+			next["status"] = runtime.DeepCopyJSONValue(status)
+		}
+	default:
+		next = obj
+	}
+
+	n := &unstructured.Unstructured{Object: next}
+	// Server managed metadata cannot be changed by clients.
+	n.SetName(old.GetName())
+	n.SetNamespace(old.GetNamespace())
+	n.SetUID(old.GetUID())
+	n.SetCreationTimestamp(old.GetCreationTimestamp())
+	n.SetDeletionTimestamp(old.GetDeletionTimestamp())
+	n.SetGeneration(old.GetGeneration())
+	if !reflect.DeepEqual(existing["spec"], next["spec"]) {
+		n.SetGeneration(old.GetGeneration() + 1)
+	}
+	if replace && n.GetAPIVersion() == "" {
+		n.SetAPIVersion(old.GetAPIVersion())
+		n.SetKind(old.GetKind())
+	}
+
+	if err := s.admitUpdate(req, old, n); err != nil {
+		writeError(w, err)
+		return
+	}
+	if n.GetDeletionTimestamp() != nil && len(n.GetFinalizers()) == 0 {
+		delete(req.cluster.objects, req.key())
+		s.afterDelete(req, n)
+		writeJSON(w, http.StatusOK, s.response(req, next))
+		return
+	}
+	s.bumpResourceVersion(next)
+	req.cluster.objects[req.key()] = next
+	writeJSON(w, http.StatusOK, s.response(req, next))
+}
+
+func (s *Server) delete(w http.ResponseWriter, req request) {
+	obj, ok := req.cluster.objects[req.key()]
+	if !ok {
+		writeError(w, apierrors.NewNotFound(req.groupResource(), req.name))
+		return
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if len(u.GetFinalizers()) > 0 {
+		if u.GetDeletionTimestamp() == nil {
+			now := metav1.Now()
+			u.SetDeletionTimestamp(&now)
+			s.bumpResourceVersion(obj)
+		}
+		writeJSON(w, http.StatusOK, s.response(req, obj))
+		return
+	}
+	delete(req.cluster.objects, req.key())
+	s.afterDelete(req, u)
+	writeJSON(w, http.StatusOK, &metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusSuccess,
+		Details:  &metav1.StatusDetails{Name: req.name, Group: req.resource.group, Kind: req.resource.name, UID: u.GetUID()},
+	})
+}
+
+// createToken serves the serviceaccounts/token subresource with an opaque
+// token naming the logical cluster and ServiceAccount.
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request, req request) {
+	if _, ok := req.cluster.objects[req.key()]; !ok {
+		writeError(w, apierrors.NewNotFound(req.groupResource(), req.name))
+		return
+	}
+	tr, err := decodeBody(r)
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	expiration := int64(3600)
+	spec, _ := tr["spec"].(map[string]interface{})
+	switch v := spec["expirationSeconds"].(type) {
+	case int64:
+		expiration = v
+	case float64:
+		expiration = int64(v)
+	}
+	tr["apiVersion"] = "authentication.k8s.io/v1"
+	tr["kind"] = "TokenRequest"
+	tr["status"] = map[string]interface{}{
+		"token":               fmt.Sprintf("fakekcp:%s:%s:%s", req.cluster.path, req.namespace, req.name),
+		"expirationTimestamp": time.Now().Add(time.Duration(expiration) * time.Second).UTC().Format(time.RFC3339),
+	}
+	writeJSON(w, http.StatusCreated, tr)
+}
+
+// response returns a copy of obj as served in the requested version.
+func (s *Server) response(req request, obj map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(obj)
+	out["apiVersion"] = req.resource.apiVersion(req.version)
+	out["kind"] = req.resource.kind
+	return out
+}
+
+func initMetadata(u *unstructured.Unstructured) {
+	if u.GetUID() == "" {
+		u.SetUID(uuid.NewUUID())
+	}
+	if ts := u.GetCreationTimestamp(); ts.IsZero() {
+		u.SetCreationTimestamp(metav1.Now())
+	}
+	if u.GetGeneration() == 0 {
+		u.SetGeneration(1)
+	}
+}
+
+// decodeBody decodes JSON, YAML or, for built-in types, protobuf request
+// bodies into an unstructured object.
+func decodeBody(r *http.Request) (map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), runtime.ContentTypeProtobuf) {
+		typed, gvk, err := clientgoscheme.Codecs.UniversalDeserializer().Decode(body, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+		if err != nil {
+			return nil, err
+		}
+		obj["apiVersion"], obj["kind"] = gvk.GroupVersion().String(), gvk.Kind
+		return runtime.DeepCopyJSON(obj), nil
+	}
+	return yamlToObject(body)
+}
+
+func yamlToObject(body []byte) (map[string]interface{}, error) {
+	data, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func rand5() string {
+	return strings.ReplaceAll(string(uuid.NewUUID()), "-", "")[:5]
+}
+
+func unsupportedMediaType(patchType types.PatchType) error {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusUnsupportedMediaType,
+		Reason:  metav1.StatusReasonUnsupportedMediaType,
+		Message: fmt.Sprintf("patch type %s is not supported", patchType),
+	}}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	s := status.Status()
+	s.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	writeJSON(w, int(s.Code), &s)
+}
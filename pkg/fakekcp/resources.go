@@ -0,0 +1,155 @@
+package fakekcp
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resource describes an API resource served in every logical cluster. Objects
+// are stored per group and resource, so all versions share one storage.
+type resource struct {
+	group      string
+	versions   []string
+	name       string
+	kind       string
+	namespaced bool
+	// status marks resources with a status subresource; their status is
+	// ignored on create and on updates of the main resource.
+	status       bool
+	subresources []string
+}
+
+var builtinResources = []resource{
+	{group: "", versions: []string{"v1"}, name: "namespaces", kind: "Namespace", status: true},
+	{group: "", versions: []string{"v1"}, name: "secrets", kind: "Secret", namespaced: true},
+	{group: "", versions: []string{"v1"}, name: "configmaps", kind: "ConfigMap", namespaced: true},
+	{group: "", versions: []string{"v1"}, name: "serviceaccounts", kind: "ServiceAccount", namespaced: true, subresources: []string{"token"}},
+	{group: "rbac.authorization.k8s.io", versions: []string{"v1"}, name: "clusterroles", kind: "ClusterRole"},
+	{group: "rbac.authorization.k8s.io", versions: []string{"v1"}, name: "clusterrolebindings", kind: "ClusterRoleBinding"},
+	{group: "rbac.authorization.k8s.io", versions: []string{"v1"}, name: "roles", kind: "Role", namespaced: true},
+	{group: "rbac.authorization.k8s.io", versions: []string{"v1"}, name: "rolebindings", kind: "RoleBinding", namespaced: true},
+	{group: "tenancy.kcp.io", versions: []string{"v1alpha1"}, name: "workspaces", kind: "Workspace", status: true},
+	{group: "tenancy.kcp.io", versions: []string{"v1alpha1"}, name: "workspacetypes", kind: "WorkspaceType", status: true},
+	{group: "apis.kcp.io", versions: []string{"v1alpha2", "v1alpha1"}, name: "apiexports", kind: "APIExport", status: true},
+	{group: "apis.kcp.io", versions: []string{"v1alpha2", "v1alpha1"}, name: "apibindings", kind: "APIBinding", status: true},
+	{group: "apis.kcp.io", versions: []string{"v1alpha1"}, name: "apiexportendpointslices", kind: "APIExportEndpointSlice", status: true},
+	{group: "core.kcp.io", versions: []string{"v1alpha1"}, name: "logicalclusters", kind: "LogicalCluster", status: true},
+	{group: "core.kcp.io", versions: []string{"v1alpha1"}, name: "shards", kind: "Shard", status: true},
+}
+
+func (r resource) hasVersion(version string) bool {
+	for _, v := range r.versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (r resource) hasSubresource(sub string) bool {
+	if sub == "status" {
+		return r.status
+	}
+	for _, s := range r.subresources {
+		if s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+func (r resource) apiVersion(version string) string {
+	if r.group == "" {
+		return version
+	}
+	return r.group + "/" + version
+}
+
+func (s *Server) lookupResource(group, version, name string) (resource, bool) {
+	for _, r := range s.resources {
+		if r.group == group && r.name == name && r.hasVersion(version) {
+			return r, true
+		}
+	}
+	return resource{}, false
+}
+
+func (s *Server) apiVersions() *metav1.APIVersions {
+	return &metav1.APIVersions{
+		TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+		Versions: []string{"v1"},
+	}
+}
+
+func (s *Server) apiGroupList() *metav1.APIGroupList {
+	groups := map[string]*metav1.APIGroup{}
+	var order []string
+	for _, r := range s.resources {
+		if r.group == "" {
+			continue
+		}
+		g, ok := groups[r.group]
+		if !ok {
+			g = &metav1.APIGroup{Name: r.group}
+			groups[r.group] = g
+			order = append(order, r.group)
+		}
+		for _, v := range r.versions {
+			gv := metav1.GroupVersionForDiscovery{GroupVersion: r.apiVersion(v), Version: v}
+			if !containsGroupVersion(g.Versions, gv) {
+				g.Versions = append(g.Versions, gv)
+			}
+		}
+	}
+	sort.Strings(order)
+
+	list := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}}
+	for _, name := range order {
+		g := groups[name]
+		g.PreferredVersion = g.Versions[0]
+		list.Groups = append(list.Groups, *g)
+	}
+	return list
+}
+
+func containsGroupVersion(versions []metav1.GroupVersionForDiscovery, gv metav1.GroupVersionForDiscovery) bool {
+	for _, v := range versions {
+		if v == gv {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) apiResourceList(group, version string) (*metav1.APIResourceList, bool) {
+	list := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: resource{group: group}.apiVersion(version),
+	}
+	verbs := metav1.Verbs{"create", "delete", "get", "list", "patch", "update"}
+	for _, r := range s.resources {
+		if r.group != group || !r.hasVersion(version) {
+			continue
+		}
+		list.APIResources = append(list.APIResources, metav1.APIResource{
+			Name:       r.name,
+			Namespaced: r.namespaced,
+			Kind:       r.kind,
+			Verbs:      verbs,
+		})
+		if r.status {
+			list.APIResources = append(list.APIResources, metav1.APIResource{
+				Name: r.name + "/status", Namespaced: r.namespaced, Kind: r.kind, Verbs: metav1.Verbs{"get", "patch", "update"},
+			})
+		}
+		for _, sub := range r.subresources {
+			apiResource := metav1.APIResource{Name: r.name + "/" + sub, Namespaced: r.namespaced, Verbs: metav1.Verbs{"create"}}
+			if sub == "token" {
+				apiResource.Group, apiResource.Version, apiResource.Kind = "authentication.k8s.io", "v1", "TokenRequest"
+			}
+			list.APIResources = append(list.APIResources, apiResource)
+		}
+	}
+	return list, len(list.APIResources) > 0
+}
@@ -0,0 +1,149 @@
+package fakekcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	workspaceKind  = schema.GroupKind{Group: "tenancy.kcp.io", Kind: "Workspace"}
+	apiBindingKind = schema.GroupKind{Group: "apis.kcp.io", Kind: "APIBinding"}
+)
+
+// admitCreate rejects objects kcp would reject and fills in fields kcp sets on
+// creation.
+func (s *Server) admitCreate(req request, obj *unstructured.Unstructured) error {
+	switch {
+	case req.resource.group == "tenancy.kcp.io" && req.resource.name == "workspaces":
+		typeName, _, _ := unstructured.NestedString(obj.Object, "spec", "type", "name")
+		typePath, _, _ := unstructured.NestedString(obj.Object, "spec", "type", "path")
+		if typeName == "" {
+			typeName, typePath = "universal", RootCluster
+			_ = unstructured.SetNestedField(obj.Object, typeName, "spec", "type", "name")
+			_ = unstructured.SetNestedField(obj.Object, typePath, "spec", "type", "path")
+		}
+		if typePath == "" {
+			typePath = req.cluster.path
+		}
+		if !s.exists(typePath, objectKey{group: "tenancy.kcp.io", resource: "workspacetypes", name: typeName}) {
+			return apierrors.NewInvalid(workspaceKind, obj.GetName(), field.ErrorList{
+				field.NotFound(field.NewPath("spec", "type"), fmt.Sprintf("%s:%s", typePath, typeName)),
+			})
+		}
+		path := req.cluster.path + ":" + obj.GetName()
+		_ = unstructured.SetNestedField(obj.Object, clusterName(path), "spec", "cluster")
+		_ = unstructured.SetNestedField(obj.Object, s.URL()+"/clusters/"+path, "spec", "URL")
+		_ = unstructured.SetNestedField(obj.Object, s.workspacePhase, "status", "phase")
+
+	case req.resource.group == "apis.kcp.io" && req.resource.name == "apiexports":
+		sum := sha256.Sum256([]byte(req.cluster.name + ":" + obj.GetName()))
+		_ = unstructured.SetNestedField(obj.Object, hex.EncodeToString(sum[:]), "status", "identityHash")
+
+	case req.resource.group == "apis.kcp.io" && req.resource.name == "apibindings":
+		exportName, _, _ := unstructured.NestedString(obj.Object, "spec", "reference", "export", "name")
+		if exportName == "" {
+			return apierrors.NewInvalid(apiBindingKind, obj.GetName(), field.ErrorList{
+				field.Required(field.NewPath("spec", "reference", "export", "name"), ""),
+			})
+		}
+		s.bind(req, obj)
+	}
+	return nil
+}
+
+// afterCreate creates the objects kcp derives from a new object.
+func (s *Server) afterCreate(req request, obj *unstructured.Unstructured) {
+	switch {
+	case req.resource.group == "tenancy.kcp.io" && req.resource.name == "workspaces":
+		path := req.cluster.path + ":" + obj.GetName()
+		s.addCluster(path, clusterName(path))
+
+	case req.resource.group == "apis.kcp.io" && req.resource.name == "apiexports":
+		key := objectKey{group: "apis.kcp.io", resource: "apiexportendpointslices", name: obj.GetName()}
+		if _, exists := req.cluster.objects[key]; exists {
+			return
+		}
+		s.store(req.cluster, key, map[string]interface{}{
+			"apiVersion": "apis.kcp.io/v1alpha1",
+			"kind":       "APIExportEndpointSlice",
+			"metadata":   map[string]interface{}{"name": obj.GetName()},
+			"spec": map[string]interface{}{
+				"export": map[string]interface{}{"path": req.cluster.path, "name": obj.GetName()},
+			},
+			"status": map[string]interface{}{
+				"endpoints": []interface{}{
+					map[string]interface{}{"url": fmt.Sprintf("%s/services/apiexport/%s/%s", s.URL(), req.cluster.name, obj.GetName())},
+				},
+			},
+		})
+	}
+}
+
+// admitUpdate enforces immutable fields.
+func (s *Server) admitUpdate(req request, old, obj *unstructured.Unstructured) error {
+	if req.resource.group == "tenancy.kcp.io" && req.resource.name == "workspaces" && req.subresource == "" {
+		oldType, _, _ := unstructured.NestedMap(old.Object, "spec", "type")
+		newType, found, _ := unstructured.NestedMap(obj.Object, "spec", "type")
+		if found && !reflect.DeepEqual(oldType, newType) {
+			return apierrors.NewInvalid(workspaceKind, obj.GetName(), field.ErrorList{
+				field.Invalid(field.NewPath("spec", "type"), newType, "field is immutable"),
+			})
+		}
+		if !found {
+			_ = unstructured.SetNestedMap(obj.Object, oldType, "spec", "type")
+		}
+		for _, f := range []string{"cluster", "URL"} {
+			if v, ok, _ := unstructured.NestedString(old.Object, "spec", f); ok {
+				_ = unstructured.SetNestedField(obj.Object, v, "spec", f)
+			}
+		}
+	}
+	if req.resource.group == "apis.kcp.io" && req.resource.name == "apibindings" && req.subresource == "" {
+		oldRef, _, _ := unstructured.NestedMap(old.Object, "spec", "reference")
+		newRef, _, _ := unstructured.NestedMap(obj.Object, "spec", "reference")
+		if !reflect.DeepEqual(oldRef, newRef) {
+			return apierrors.NewInvalid(apiBindingKind, obj.GetName(), field.ErrorList{
+				field.Invalid(field.NewPath("spec", "reference"), newRef, "field is immutable"),
+			})
+		}
+	}
+	return nil
+}
+
+// afterDelete removes the logical cluster of a deleted workspace.
+func (s *Server) afterDelete(req request, obj *unstructured.Unstructured) {
+	if req.resource.group == "tenancy.kcp.io" && req.resource.name == "workspaces" {
+		s.removeCluster(req.cluster.path + ":" + obj.GetName())
+	}
+}
+
+// bind sets the APIBinding phase to Bound if the referenced APIExport exists.
+func (s *Server) bind(req request, obj *unstructured.Unstructured) {
+	exportName, _, _ := unstructured.NestedString(obj.Object, "spec", "reference", "export", "name")
+	exportPath, _, _ := unstructured.NestedString(obj.Object, "spec", "reference", "export", "path")
+	if exportPath == "" {
+		exportPath = req.cluster.path
+	}
+	phase := "Binding"
+	if c, ok := s.cluster(exportPath); ok && s.exists(c.path, objectKey{group: "apis.kcp.io", resource: "apiexports", name: exportName}) {
+		phase = "Bound"
+		_ = unstructured.SetNestedField(obj.Object, c.name, "status", "apiExportClusterName")
+	}
+	_ = unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+}
+
+func (s *Server) exists(path string, key objectKey) bool {
+	c, ok := s.cluster(path)
+	if !ok {
+		return false
+	}
+	_, ok = c.objects[key]
+	return ok
+}
@@ -0,0 +1,260 @@
+// Package fakekcp provides an in-memory kcp API server for unit tests.
+//
+// The server speaks enough of the Kubernetes REST protocol for controller-runtime
+// clients: discovery, get, list with label selectors, create, update, patch and
+// delete, including status subresources and ServiceAccount token requests. It
+// serves every logical cluster under /clusters/<path> and mimics the kcp
+// behavior the operator depends on:
+//
+//   - creating a Workspace creates its logical cluster and, once Ready, makes it
+//     reachable by path and by the cluster name in spec.cluster
+//   - the referenced WorkspaceType must exist and spec.type is immutable
+//   - creating an APIExport creates an APIExportEndpointSlice of the same name
+//   - APIBindings become Bound when the referenced APIExport exists
+//
+// Watches, field selectors and server-side apply field ownership are not
+// supported; apply patches are merged like JSON merge patches.
+package fakekcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// RootCluster is the logical cluster every server starts with.
+const RootCluster = "root"
+
+// DefaultWorkspaceTypes are created in the root cluster, as in a fresh kcp.
+var DefaultWorkspaceTypes = []string{"root", "universal", "organization", "team"}
+
+type objectKey struct {
+	group     string
+	resource  string
+	namespace string
+	name      string
+}
+
+type logicalCluster struct {
+	path    string
+	name    string
+	objects map[objectKey]map[string]interface{}
+}
+
+// Server is an in-memory kcp API server backed by httptest.
+type Server struct {
+	httpServer *httptest.Server
+	resources  []resource
+
+	mu              sync.Mutex
+	clusters        map[string]*logicalCluster // by path
+	clusterNames    map[string]string          // logical cluster name -> path
+	resourceVersion int64
+	workspacePhase  string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithWorkspacePhase sets the phase new workspaces start in. The default is
+// Ready; use SetWorkspacePhase to move a workspace on.
+func WithWorkspacePhase(phase string) Option {
+	return func(s *Server) {
+		s.workspacePhase = phase
+	}
+}
+
+// New starts a server with an empty root cluster holding the default
+// WorkspaceTypes. Call Close when done.
+func New(opts ...Option) *Server {
+	s := &Server{
+		resources:      builtinResources,
+		clusters:       map[string]*logicalCluster{},
+		clusterNames:   map[string]string{},
+		workspacePhase: "Ready",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.addCluster(RootCluster, RootCluster)
+	for _, name := range DefaultWorkspaceTypes {
+		wt := map[string]interface{}{
+			"apiVersion": "tenancy.kcp.io/v1alpha1",
+			"kind":       "WorkspaceType",
+			"metadata":   map[string]interface{}{"name": name},
+		}
+		s.store(s.clusters[RootCluster], objectKey{group: "tenancy.kcp.io", resource: "workspacetypes", name: name}, wt)
+	}
+	s.httpServer = httptest.NewServer(s)
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// URL is the base URL of the server, without a cluster path.
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// RestConfig returns a config for the root cluster. KcpHelper implementations
+// replace the path with /clusters/<workspace>.
+func (s *Server) RestConfig() *rest.Config {
+	return &rest.Config{Host: s.httpServer.URL + "/clusters/" + RootCluster}
+}
+
+// AddObjects stores objs in the logical cluster at path, bypassing admission
+// semantics. It is meant to seed fixtures.
+func (s *Server) AddObjects(path string, objs ...runtime.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cluster, ok := s.clusters[path]
+	if !ok {
+		return fmt.Errorf("logical cluster %s does not exist", path)
+	}
+	for _, obj := range objs {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		o := &unstructured.Unstructured{Object: u}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if gvk.Empty() {
+			return fmt.Errorf("object %s has no apiVersion and kind", o.GetName())
+		}
+		res, ok := s.resourceForKind(gvk)
+		if !ok {
+			return fmt.Errorf("kind %s is not served", gvk)
+		}
+		initMetadata(o)
+		s.store(cluster, objectKey{group: res.group, resource: res.name, namespace: o.GetNamespace(), name: o.GetName()}, o.Object)
+	}
+	return nil
+}
+
+// Get returns a copy of the object stored in the logical cluster at path.
+func (s *Server) Get(path string, gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cluster, ok := s.clusters[path]
+	if !ok {
+		return nil, false
+	}
+	obj, ok := cluster.objects[objectKey{group: gvr.Group, resource: gvr.Resource, namespace: namespace, name: name}]
+	if !ok {
+		return nil, false
+	}
+	return &unstructured.Unstructured{Object: runtime.DeepCopyJSON(obj)}, true
+}
+
+// HasCluster reports whether a logical cluster exists at path.
+func (s *Server) HasCluster(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.clusters[path]
+	return ok
+}
+
+// SetWorkspacePhase sets status.phase of the workspace at path, e.g. to
+// simulate a workspace that is still initializing.
+func (s *Server) SetWorkspacePhase(path, phase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parent, name := splitPath(path)
+	cluster, ok := s.clusters[parent]
+	if !ok {
+		return fmt.Errorf("logical cluster %s does not exist", parent)
+	}
+	ws, ok := cluster.objects[objectKey{group: "tenancy.kcp.io", resource: "workspaces", name: name}]
+	if !ok {
+		return fmt.Errorf("workspace %s does not exist", path)
+	}
+	if err := unstructured.SetNestedField(ws, phase, "status", "phase"); err != nil {
+		return err
+	}
+	s.bumpResourceVersion(ws)
+	return nil
+}
+
+func (s *Server) resourceForKind(gvk schema.GroupVersionKind) (resource, bool) {
+	for _, r := range s.resources {
+		if r.group == gvk.Group && r.kind == gvk.Kind && r.hasVersion(gvk.Version) {
+			return r, true
+		}
+	}
+	return resource{}, false
+}
+
+// cluster resolves a logical cluster by path or by logical cluster name.
+func (s *Server) cluster(pathOrName string) (*logicalCluster, bool) {
+	if c, ok := s.clusters[pathOrName]; ok {
+		return c, true
+	}
+	if path, ok := s.clusterNames[pathOrName]; ok {
+		return s.clusters[path], true
+	}
+	return nil, false
+}
+
+func (s *Server) addCluster(path, name string) *logicalCluster {
+	c := &logicalCluster{path: path, name: name, objects: map[objectKey]map[string]interface{}{}}
+	s.clusters[path] = c
+	s.clusterNames[name] = path
+	s.store(c, objectKey{group: "core.kcp.io", resource: "logicalclusters", name: "cluster"}, map[string]interface{}{
+		"apiVersion": "core.kcp.io/v1alpha1",
+		"kind":       "LogicalCluster",
+		"metadata": map[string]interface{}{
+			"name":        "cluster",
+			"annotations": map[string]interface{}{"kcp.io/path": path},
+		},
+		"status": map[string]interface{}{"phase": "Ready"},
+	})
+	return c
+}
+
+// removeCluster deletes the logical cluster at path and all its descendants.
+func (s *Server) removeCluster(path string) {
+	for p, c := range s.clusters {
+		if p == path || strings.HasPrefix(p, path+":") {
+			delete(s.clusterNames, c.name)
+			delete(s.clusters, p)
+		}
+	}
+}
+
+func (s *Server) store(c *logicalCluster, key objectKey, obj map[string]interface{}) {
+	u := &unstructured.Unstructured{Object: obj}
+	initMetadata(u)
+	s.bumpResourceVersion(obj)
+	c.objects[key] = obj
+}
+
+func (s *Server) bumpResourceVersion(obj map[string]interface{}) {
+	s.resourceVersion++
+	(&unstructured.Unstructured{Object: obj}).SetResourceVersion(fmt.Sprintf("%d", s.resourceVersion))
+}
+
+// clusterName derives a stable logical cluster name from its path, shaped
+// like the names kcp generates.
+func clusterName(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func splitPath(path string) (parent, name string) {
+	i := strings.LastIndex(path, ":")
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
@@ -0,0 +1,203 @@
+package fakekcp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	kcpapiv1alpha1 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	kcpapiv1alpha2 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha2"
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/stretchr/testify/suite"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+	server *Server
+	scheme *runtime.Scheme
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+func (s *ServerTestSuite) SetupTest() {
+	s.server = New()
+	s.scheme = runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(s.scheme))
+	utilruntime.Must(rbacv1.AddToScheme(s.scheme))
+	utilruntime.Must(authv1.AddToScheme(s.scheme))
+	utilruntime.Must(kcptenancyv1alpha.AddToScheme(s.scheme))
+	utilruntime.Must(kcpapiv1alpha1.AddToScheme(s.scheme))
+	utilruntime.Must(kcpapiv1alpha2.AddToScheme(s.scheme))
+}
+
+func (s *ServerTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ServerTestSuite) client(path string) client.Client {
+	cl, err := client.New(&rest.Config{Host: s.server.URL() + "/clusters/" + path}, client.Options{Scheme: s.scheme})
+	s.Require().NoError(err)
+	return cl
+}
+
+func (s *ServerTestSuite) workspace(name, typeName, typePath string) *kcptenancyv1alpha.Workspace {
+	return &kcptenancyv1alpha.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: kcptenancyv1alpha.WorkspaceSpec{
+			Type: &kcptenancyv1alpha.WorkspaceTypeReference{Name: kcptenancyv1alpha.WorkspaceTypeName(typeName), Path: typePath},
+		},
+	}
+}
+
+func (s *ServerTestSuite) TestWorkspaceLifecycle() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	s.Require().NoError(root.Create(ctx, s.workspace("orgs", "organization", "root")))
+
+	ws := &kcptenancyv1alpha.Workspace{}
+	s.Require().NoError(root.Get(ctx, client.ObjectKey{Name: "orgs"}, ws))
+	s.EqualValues("Ready", ws.Status.Phase)
+	s.NotEmpty(ws.Spec.Cluster)
+	s.True(s.server.HasCluster("root:orgs"))
+
+	// The workspace is reachable by path and by logical cluster name.
+	s.Require().NoError(s.client("root:orgs").Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}}))
+	s.Require().NoError(s.client(ws.Spec.Cluster).Get(ctx, client.ObjectKey{Name: "cm", Namespace: "default"}, &corev1.ConfigMap{}))
+
+	s.Require().NoError(root.Delete(ctx, ws))
+	s.False(s.server.HasCluster("root:orgs"))
+}
+
+func (s *ServerTestSuite) TestWorkspaceTypeValidation() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	err := root.Create(ctx, s.workspace("orgs", "missing", "root"))
+	s.True(kerrors.IsInvalid(err))
+
+	s.Require().NoError(root.Create(ctx, s.workspace("orgs", "organization", "root")))
+	ws := &kcptenancyv1alpha.Workspace{}
+	s.Require().NoError(root.Get(ctx, client.ObjectKey{Name: "orgs"}, ws))
+	ws.Spec.Type.Name = "team"
+	s.True(kerrors.IsInvalid(root.Update(ctx, ws)))
+}
+
+func (s *ServerTestSuite) TestWorkspaceNotReadyIsForbidden() {
+	s.server.Close()
+	s.server = New(WithWorkspacePhase("Initializing"))
+	ctx := context.Background()
+
+	s.Require().NoError(s.client(RootCluster).Create(ctx, s.workspace("orgs", "organization", "root")))
+	resp, err := http.Get(s.server.URL() + "/clusters/root:orgs/api/v1/namespaces/default/configmaps")
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	s.Require().NoError(s.server.SetWorkspacePhase("root:orgs", "Ready"))
+	s.NoError(s.client("root:orgs").List(ctx, &corev1.ConfigMapList{}))
+}
+
+func (s *ServerTestSuite) TestAPIExportAndBinding() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	s.Require().NoError(root.Create(ctx, &kcpapiv1alpha2.APIExport{ObjectMeta: metav1.ObjectMeta{Name: "core.platform-mesh.io"}}))
+
+	slice := &kcpapiv1alpha1.APIExportEndpointSlice{}
+	s.Require().NoError(root.Get(ctx, client.ObjectKey{Name: "core.platform-mesh.io"}, slice))
+	s.Equal("core.platform-mesh.io", slice.Spec.APIExport.Name)
+	s.Require().Len(slice.Status.APIExportEndpoints, 1)
+
+	binding := &kcpapiv1alpha2.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "core"},
+		Spec: kcpapiv1alpha2.APIBindingSpec{
+			Reference: kcpapiv1alpha2.BindingReference{
+				Export: &kcpapiv1alpha2.ExportBindingReference{Name: "core.platform-mesh.io", Path: "root"},
+			},
+		},
+	}
+	s.Require().NoError(root.Create(ctx, binding))
+	s.Require().NoError(root.Get(ctx, client.ObjectKey{Name: "core"}, binding))
+	s.EqualValues("Bound", binding.Status.Phase)
+}
+
+func (s *ServerTestSuite) TestCRUDSemantics() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	cr := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "role", Labels: map[string]string{"app": "a"}}}
+	s.Require().NoError(root.Create(ctx, cr))
+	s.True(kerrors.IsAlreadyExists(root.Create(ctx, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "role"}})))
+
+	stale := cr.DeepCopy()
+	cr.Rules = []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}}
+	s.Require().NoError(root.Update(ctx, cr))
+	stale.Rules = nil
+	s.True(kerrors.IsConflict(root.Update(ctx, stale)))
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	cr.Labels["app"] = "b"
+	s.Require().NoError(root.Patch(ctx, cr, patch))
+
+	list := &rbacv1.ClusterRoleList{}
+	s.Require().NoError(root.List(ctx, list, client.MatchingLabels{"app": "b"}))
+	s.Len(list.Items, 1)
+	s.Require().NoError(root.List(ctx, list, client.MatchingLabels{"app": "a"}))
+	s.Empty(list.Items)
+
+	obj, found := s.server.Get(RootCluster, schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, "", "role")
+	s.Require().True(found)
+	s.Equal("b", obj.GetLabels()["app"])
+}
+
+func (s *ServerTestSuite) TestFinalizersDelayDeletion() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", Finalizers: []string{"test"}}}
+	s.Require().NoError(root.Create(ctx, cm))
+	s.Require().NoError(root.Delete(ctx, cm))
+
+	s.Require().NoError(root.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	s.NotNil(cm.DeletionTimestamp)
+
+	cm.Finalizers = nil
+	s.Require().NoError(root.Update(ctx, cm))
+	s.True(kerrors.IsNotFound(root.Get(ctx, client.ObjectKeyFromObject(cm), cm)))
+}
+
+func (s *ServerTestSuite) TestServiceAccountToken() {
+	ctx := context.Background()
+	root := s.client(RootCluster)
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"}}
+	s.Require().NoError(root.Create(ctx, sa))
+
+	tr := &authv1.TokenRequest{}
+	s.Require().NoError(root.SubResource("token").Create(ctx, sa, tr))
+	s.Equal("fakekcp:root:default:provider", tr.Status.Token)
+}
+
+func (s *ServerTestSuite) TestAddObjects() {
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "s", Namespace: "default"},
+	}
+	s.Require().NoError(s.server.AddObjects(RootCluster, secret))
+	s.NoError(s.client(RootCluster).Get(context.Background(), client.ObjectKeyFromObject(secret), &corev1.Secret{}))
+
+	s.Error(s.server.AddObjects("root:missing", secret))
+}
@@ -0,0 +1,166 @@
+// Package keycloak is a minimal client of the Keycloak admin REST API for
+// provisioning realms and OIDC clients.
+package keycloak
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned for realms and clients that do not exist.
+var ErrNotFound = errors.New("not found")
+
+// ClientRepresentation is the part of a Keycloak client the operator manages.
+type ClientRepresentation struct {
+	// ID is the internal ID Keycloak assigns, ClientID the one used in OIDC.
+	ID                  string            `json:"id,omitempty"`
+	ClientID            string            `json:"clientId"`
+	Enabled             bool              `json:"enabled"`
+	Protocol            string            `json:"protocol,omitempty"`
+	PublicClient        bool              `json:"publicClient"`
+	StandardFlowEnabled bool              `json:"standardFlowEnabled"`
+	RedirectURIs        []string          `json:"redirectUris,omitempty"`
+	WebOrigins          []string          `json:"webOrigins,omitempty"`
+	Attributes          map[string]string `json:"attributes,omitempty"`
+}
+
+// Client talks to the admin REST API of a Keycloak server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// NewClient returns a client for the Keycloak server at baseURL, including
+// its relative path if any. httpClient defaults to http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// Login obtains an access token for the admin user of the master realm. It
+// must be called before any other method.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"admin-cli"},
+		"username":   {username},
+		"password":   {password},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/realms/master/protocol/openid-connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.send(req, &resp); err != nil {
+		return fmt.Errorf("logging in as %s: %w", username, err)
+	}
+	c.token = resp.AccessToken
+	return nil
+}
+
+// GetRealm returns nil if realm exists and ErrNotFound if it does not.
+func (c *Client) GetRealm(ctx context.Context, realm string) error {
+	return c.do(ctx, http.MethodGet, "/admin/realms/"+url.PathEscape(realm), nil, nil)
+}
+
+// CreateRealm creates an enabled realm.
+func (c *Client) CreateRealm(ctx context.Context, realm string) error {
+	return c.do(ctx, http.MethodPost, "/admin/realms", map[string]any{"realm": realm, "enabled": true}, nil)
+}
+
+// FindClient returns the client of realm with the given OIDC client ID or
+// ErrNotFound.
+func (c *Client) FindClient(ctx context.Context, realm, clientID string) (*ClientRepresentation, error) {
+	var clients []ClientRepresentation
+	path := "/admin/realms/" + url.PathEscape(realm) + "/clients?" + url.Values{"clientId": {clientID}}.Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, &clients); err != nil {
+		return nil, err
+	}
+	for _, cl := range clients {
+		if cl.ClientID == clientID {
+			return &cl, nil
+		}
+	}
+	return nil, fmt.Errorf("client %s in realm %s: %w", clientID, realm, ErrNotFound)
+}
+
+// CreateClient creates client in realm.
+func (c *Client) CreateClient(ctx context.Context, realm string, client ClientRepresentation) error {
+	return c.do(ctx, http.MethodPost, "/admin/realms/"+url.PathEscape(realm)+"/clients", client, nil)
+}
+
+// UpdateClient updates the client of realm with the internal ID client.ID.
+func (c *Client) UpdateClient(ctx context.Context, realm string, client ClientRepresentation) error {
+	return c.do(ctx, http.MethodPut, "/admin/realms/"+url.PathEscape(realm)+"/clients/"+url.PathEscape(client.ID), client, nil)
+}
+
+// GetClientSecret returns the secret of the confidential client with the
+// internal ID id.
+func (c *Client) GetClientSecret(ctx context.Context, realm, id string) (string, error) {
+	var resp struct {
+		Value string `json:"value"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/realms/"+url.PathEscape(realm)+"/clients/"+url.PathEscape(id)+"/client-secret", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Value, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if err := c.send(req, out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (c *Client) send(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
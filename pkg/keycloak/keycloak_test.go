@@ -0,0 +1,91 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var created, updated ClientRepresentation
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /keycloak/realms/master/protocol/openid-connect/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("password") != "secret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token"}`))
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /keycloak/admin/realms/{realm}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("realm") != "platform-mesh" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"realm":"platform-mesh"}`))
+	}))
+	mux.HandleFunc("POST /keycloak/admin/realms", authorized(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	mux.HandleFunc("GET /keycloak/admin/realms/{realm}/clients", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("clientId") != "portal" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":"1","clientId":"portal","enabled":true}]`))
+	}))
+	mux.HandleFunc("POST /keycloak/admin/realms/{realm}/clients", authorized(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	mux.HandleFunc("PUT /keycloak/admin/realms/{realm}/clients/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("GET /keycloak/admin/realms/{realm}/clients/{id}/client-secret", authorized(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"type":"secret","value":"s3cr3t"}`))
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL+"/keycloak/", nil)
+
+	assert.ErrorContains(t, c.Login(ctx, "admin", "wrong"), "401")
+	require.NoError(t, c.Login(ctx, "admin", "secret"))
+
+	require.NoError(t, c.GetRealm(ctx, "platform-mesh"))
+	assert.ErrorIs(t, c.GetRealm(ctx, "missing"), ErrNotFound)
+	require.NoError(t, c.CreateRealm(ctx, "missing"))
+
+	cl, err := c.FindClient(ctx, "platform-mesh", "portal")
+	require.NoError(t, err)
+	assert.Equal(t, "1", cl.ID)
+	_, err = c.FindClient(ctx, "platform-mesh", "kcp")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, c.CreateClient(ctx, "platform-mesh", ClientRepresentation{ClientID: "kcp", RedirectURIs: []string{"http://localhost:8000"}}))
+	assert.Equal(t, []string{"http://localhost:8000"}, created.RedirectURIs)
+
+	cl.RedirectURIs = []string{"https://portal.localhost:8443/*"}
+	require.NoError(t, c.UpdateClient(ctx, "platform-mesh", *cl))
+	assert.Equal(t, cl.RedirectURIs, updated.RedirectURIs)
+
+	secret, err := c.GetClientSecret(ctx, "platform-mesh", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", secret)
+}
@@ -0,0 +1,62 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is a maintenance window that opens at each activation of its
+// schedule and stays open for its duration.
+type Window struct {
+	Schedule *Schedule
+	Duration time.Duration
+}
+
+// NewWindow parses cron into a window open for duration.
+func NewWindow(cron string, duration time.Duration) (Window, error) {
+	if duration <= 0 {
+		return Window{}, fmt.Errorf("duration of maintenance window %q must be positive", cron)
+	}
+	schedule, err := ParseSchedule(cron)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{Schedule: schedule, Duration: duration}, nil
+}
+
+// Open reports whether w is open at t, i.e. it opened at most its duration
+// before t.
+func (w Window) Open(t time.Time) bool {
+	opened := w.Schedule.Next(t.Add(-w.Duration))
+	return !opened.IsZero() && !opened.After(t)
+}
+
+// Next returns when w opens next after t, or the zero time if it never does.
+func (w Window) Next(t time.Time) time.Time {
+	return w.Schedule.Next(t)
+}
+
+// Windows are the maintenance windows of an instance.
+type Windows []Window
+
+// Open reports whether any of ws is open at t.
+func (ws Windows) Open(t time.Time) bool {
+	for _, w := range ws {
+		if w.Open(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns when the first of ws opens after t, or the zero time if none
+// ever does.
+func (ws Windows) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range ws {
+		if n := w.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
@@ -0,0 +1,72 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(day, hour, minute int) time.Time {
+	// October 2026 starts on a Thursday.
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseSchedule(t *testing.T) {
+	for _, expr := range []string{"0 2 * * *", "*/15 1-3 * * sat,sun", "30 4 1,15 jan-jun 1-5", "@weekly", "0 0 * * 7", "5/20 * * * ?"} {
+		_, err := ParseSchedule(expr)
+		assert.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "0 2 * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "0 0 * * funday"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 2 * * *", date(16, 1, 30), date(16, 2, 0)},
+		{"0 2 * * *", date(16, 2, 0), date(17, 2, 0)},
+		{"*/15 * * * *", date(16, 2, 7), date(16, 2, 15)},
+		// Saturday and Sunday.
+		{"0 1 * * sat,sun", date(16, 8, 0), date(17, 1, 0)},
+		{"0 1 * * 7", date(16, 8, 0), date(18, 1, 0)},
+		// With both day fields restricted either matches.
+		{"0 0 20 * mon", date(16, 8, 0), date(19, 0, 0)},
+		{"0 0 1 jan *", date(16, 8, 0), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", date(16, 8, 0), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", date(16, 8, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, s.Next(tt.from), tt.expr)
+	}
+}
+
+func TestWindows(t *testing.T) {
+	_, err := NewWindow("0 2 * * *", 0)
+	assert.Error(t, err)
+
+	nightly, err := NewWindow("0 2 * * *", 2*time.Hour)
+	require.NoError(t, err)
+	assert.False(t, nightly.Open(date(16, 1, 59)))
+	assert.True(t, nightly.Open(date(16, 2, 0)))
+	assert.True(t, nightly.Open(date(16, 3, 59)))
+	assert.False(t, nightly.Open(date(16, 4, 0)))
+
+	weekend, err := NewWindow("0 0 * * sat", 48*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, weekend.Open(date(18, 12, 0)))
+
+	ws := Windows{nightly, weekend}
+	assert.True(t, ws.Open(date(18, 12, 0)))
+	assert.False(t, ws.Open(date(16, 12, 0)))
+	assert.Equal(t, date(17, 0, 0), ws.Next(date(16, 12, 0)))
+	assert.True(t, Windows{}.Next(date(16, 12, 0)).IsZero())
+}
@@ -0,0 +1,185 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/copystructure"
+	"github.com/platform-mesh/golang-commons/logger"
+)
+
+// ListMode selects how a list from the overwrite map is combined with the base list.
+type ListMode string
+
+const (
+	// ListReplace drops the base list in favour of the overwrite list. This is the default.
+	ListReplace ListMode = "replace"
+	// ListAppend appends the overwrite items to the base items.
+	ListAppend ListMode = "append"
+	// ListMergeByKey deep merges overwrite items into the base items sharing the
+	// same value for Key and appends the remaining overwrite items.
+	ListMergeByKey ListMode = "merge-by-key"
+)
+
+// ListStrategy describes how the list at a path is merged.
+type ListStrategy struct {
+	Mode ListMode
+	Key  string
+}
+
+// ParseListStrategy parses "replace", "append" or "merge-by-key:<field>".
+func ParseListStrategy(s string) (ListStrategy, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == string(ListReplace):
+		return ListStrategy{Mode: ListReplace}, nil
+	case s == string(ListAppend):
+		return ListStrategy{Mode: ListAppend}, nil
+	case strings.HasPrefix(s, string(ListMergeByKey)+":"):
+		key := strings.TrimPrefix(s, string(ListMergeByKey)+":")
+		if key == "" {
+			return ListStrategy{}, fmt.Errorf("list strategy %q is missing the merge key", s)
+		}
+		return ListStrategy{Mode: ListMergeByKey, Key: key}, nil
+	}
+	return ListStrategy{}, fmt.Errorf("unknown list strategy %q, expected one of replace, append, merge-by-key:<field>", s)
+}
+
+// ListStrategies maps dotted paths to the strategy for the list at that path.
+// A "*" segment matches any key, e.g. "services.*.values.env".
+type ListStrategies map[string]ListStrategy
+
+// ParseListStrategies parses the listMergeStrategies block of a profile.
+func ParseListStrategies(raw map[string]interface{}) (ListStrategies, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	strategies := make(ListStrategies, len(raw))
+	for path, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("list strategy for %s must be a string", path)
+		}
+		strategy, err := ParseListStrategy(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list strategy for %s: %w", path, err)
+		}
+		strategies[path] = strategy
+	}
+	return strategies, nil
+}
+
+// Under returns the strategies below prefix, re-rooted so they apply to a merge
+// of the map found at prefix.
+func (l ListStrategies) Under(prefix string) ListStrategies {
+	if len(l) == 0 {
+		return nil
+	}
+	prefixSegs := strings.Split(prefix, ".")
+	out := ListStrategies{}
+	for path, strategy := range l {
+		segs := strings.Split(path, ".")
+		if len(segs) <= len(prefixSegs) || !segmentsMatch(segs[:len(prefixSegs)], prefixSegs) {
+			continue
+		}
+		out[strings.Join(segs[len(prefixSegs):], ".")] = strategy
+	}
+	return out
+}
+
+func (l ListStrategies) lookup(path []string) (ListStrategy, bool) {
+	for pattern, strategy := range l {
+		if segmentsMatch(strings.Split(pattern, "."), path) {
+			return strategy, true
+		}
+	}
+	return ListStrategy{}, false
+}
+
+func segmentsMatch(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeLists resolves the list values of dst against src in place for the
+// lists that have a strategy configured. dst has the higher precedence.
+func mergeLists(dst, src map[string]interface{}, path []string, strategies ListStrategies, log *logger.Logger) error {
+	if len(strategies) == 0 {
+		return nil
+	}
+	for key, val := range dst {
+		list, isList := val.([]interface{})
+		if !isList {
+			continue
+		}
+		strategy, ok := strategies.lookup(appendPath(path, key))
+		if !ok {
+			continue
+		}
+		baseList, _ := src[key].([]interface{})
+		merged, err := mergeList(baseList, list, strategy, appendPath(path, key), strategies, log)
+		if err != nil {
+			return err
+		}
+		dst[key] = merged
+	}
+	return nil
+}
+
+func mergeList(base, overwrite []interface{}, strategy ListStrategy, path []string, strategies ListStrategies, log *logger.Logger) ([]interface{}, error) {
+	switch strategy.Mode {
+	case ListAppend:
+		out := make([]interface{}, 0, len(base)+len(overwrite))
+		return append(append(out, base...), overwrite...), nil
+	case ListMergeByKey:
+		baseCopy, err := copystructure.Copy(base)
+		if err != nil {
+			return nil, err
+		}
+		out, _ := baseCopy.([]interface{})
+		index := map[interface{}]int{}
+		for i, item := range out {
+			if k, ok := listItemKey(item, strategy.Key); ok {
+				index[k] = i
+			}
+		}
+		for _, item := range overwrite {
+			k, ok := listItemKey(item, strategy.Key)
+			if !ok {
+				return nil, fmt.Errorf("list %s: item has no scalar %q field to merge by", strings.Join(path, "."), strategy.Key)
+			}
+			i, found := index[k]
+			if !found {
+				index[k] = len(out)
+				out = append(out, item)
+				continue
+			}
+			dstItem := item.(map[string]interface{})
+			if err := mergeObject(dstItem, out[i].(map[string]interface{}), path, strategies, log); err != nil {
+				return nil, err
+			}
+			out[i] = dstItem
+		}
+		return out, nil
+	}
+	return overwrite, nil
+}
+
+func listItemKey(item interface{}, field string) (interface{}, bool) {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	switch k := obj[field].(type) {
+	case string, bool, float64, int, int64:
+		return k, true
+	}
+	return nil, false
+}
@@ -6,7 +6,15 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 )
 
+// MergeMaps deep merges overwriteMap into base, with overwriteMap taking precedence.
+// Lists are replaced, see MergeMapsWithStrategies for other list strategies.
 func MergeMaps(base, overwriteMap map[string]interface{}, log *logger.Logger) (map[string]interface{}, error) {
+	return MergeMapsWithStrategies(base, overwriteMap, nil, log)
+}
+
+// MergeMapsWithStrategies is MergeMaps with list strategies by path. Paths are
+// relative to the root of base, lists without a strategy are replaced.
+func MergeMapsWithStrategies(base, overwriteMap map[string]interface{}, strategies ListStrategies, log *logger.Logger) (map[string]interface{}, error) {
 	if overwriteMap == nil {
 		return base, nil
 	}
@@ -19,6 +27,9 @@ func MergeMaps(base, overwriteMap map[string]interface{}, log *logger.Logger) (m
 		return nil, errors.New("failed to merge maps")
 	}
 
+	if err := mergeLists(result, base, nil, strategies, log); err != nil {
+		return nil, err
+	}
 	for key, val := range base {
 		if value, ok := result[key]; ok {
 			if dest, ok := value.(map[string]interface{}); ok {
@@ -29,8 +40,8 @@ func MergeMaps(base, overwriteMap map[string]interface{}, log *logger.Logger) (m
 					if val != nil {
 						log.Warn().Msgf("warning: skipped value for %s: Not a object.", key)
 					}
-				} else {
-					mergeObject(dest, src, log)
+				} else if err := mergeObject(dest, src, []string{key}, strategies, log); err != nil {
+					return nil, err
 				}
 			}
 		} else {
@@ -41,12 +52,12 @@ func MergeMaps(base, overwriteMap map[string]interface{}, log *logger.Logger) (m
 	return result, nil
 }
 
-func mergeObject(dst, src map[string]interface{}, log *logger.Logger) map[string]interface{} {
-	if src == nil {
-		return dst
+func mergeObject(dst, src map[string]interface{}, path []string, strategies ListStrategies, log *logger.Logger) error {
+	if src == nil || dst == nil {
+		return nil
 	}
-	if dst == nil {
-		return src
+	if err := mergeLists(dst, src, path, strategies, log); err != nil {
+		return err
 	}
 	// Because dst has higher precedence than src, dst values override src values.
 	for key, val := range src {
@@ -56,7 +67,9 @@ func mergeObject(dst, src map[string]interface{}, log *logger.Logger) map[string
 		} else if isObject(val) {
 			if isObject(dv) {
 				// Both are objects, recursively merge (dst has higher precedence)
-				mergeObject(dv.(map[string]interface{}), val.(map[string]interface{}), log)
+				if err := mergeObject(dv.(map[string]interface{}), val.(map[string]interface{}), appendPath(path, key), strategies, log); err != nil {
+					return err
+				}
 			} else {
 				// src is object but dst is not, keep dst (dst has higher precedence)
 				if val != nil {
@@ -70,10 +83,16 @@ func mergeObject(dst, src map[string]interface{}, log *logger.Logger) map[string
 			}
 		}
 	}
-	return dst
+	return nil
 }
 
 func isObject(v interface{}) bool {
 	_, ok := v.(map[string]interface{})
 	return ok
 }
+
+func appendPath(path []string, key string) []string {
+	out := make([]string, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, key)
+}
@@ -0,0 +1,129 @@
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mitchellh/copystructure"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FuzzMergeMaps merges two arbitrary JSON objects. MergeMaps must never panic
+// and the result must satisfy the invariants checked by checkMergeInvariants.
+func FuzzMergeMaps(f *testing.F) {
+	seeds := [][2]string{
+		{`{}`, `{}`},
+		{`null`, `{"a":1}`},
+		{`{"a":1}`, `null`},
+		{`{"kcp":{"enabled":true,"url":"https://kcp.example.com","domains":["a","b"]},"logLevel":"info"}`, `{"kcp":{"enabled":false,"domains":["c"]}}`},
+		// Type clashes between object and scalar in both directions.
+		{`{"a":{"b":1}}`, `{"a":"x"}`},
+		{`{"a":"x"}`, `{"a":{"b":1}}`},
+		{`{"a":{"b":{"c":null}}}`, `{"a":{"b":null}}`},
+		{`{"env":[{"name":"A"}]}`, `{"env":[{"name":"B"}]}`},
+	}
+	for _, s := range seeds {
+		f.Add([]byte(s[0]), []byte(s[1]))
+	}
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, baseRaw, overwriteRaw []byte) {
+		var base, overwrite map[string]interface{}
+		if json.Unmarshal(baseRaw, &base) != nil || json.Unmarshal(overwriteRaw, &overwrite) != nil {
+			return
+		}
+		checkMergeInvariants(t, base, overwrite, log)
+	})
+}
+
+// TestMergeMapsInvariants checks the merge invariants on hand picked edge cases
+// so they run on every test run and not only while fuzzing.
+func TestMergeMapsInvariants(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+
+	cases := []struct{ base, overwrite map[string]interface{} }{
+		{nil, nil},
+		{nil, map[string]interface{}{"a": 1.0}},
+		{map[string]interface{}{"a": 1.0}, nil},
+		{map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}, map[string]interface{}{"a": "x"}},
+		{map[string]interface{}{"a": "x"}, map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}},
+		{map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}, map[string]interface{}{"a": nil}},
+		{
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1.0, "d": []interface{}{1.0}}, "e": true}},
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 2.0, "d": []interface{}{}}}},
+		},
+	}
+	for _, tc := range cases {
+		checkMergeInvariants(t, tc.base, tc.overwrite, log)
+	}
+}
+
+// checkMergeInvariants merges overwrite into base and checks that
+//   - neither input is modified,
+//   - every value of overwrite that is not an object ends up in the result,
+//   - every value of base that overwrite does not replace ends up in the result,
+//   - merging overwrite into the result again changes nothing.
+func checkMergeInvariants(t *testing.T, base, overwrite map[string]interface{}, log *logger.Logger) {
+	t.Helper()
+	baseBefore, overwriteBefore := deepCopy(t, base), deepCopy(t, overwrite)
+
+	merged, err := MergeMaps(base, overwrite, log)
+	require.NoError(t, err)
+	assert.Equal(t, baseBefore, base, "base was modified")
+	assert.Equal(t, overwriteBefore, overwrite, "overwrite was modified")
+
+	checkPrecedence(t, merged, base, overwrite, "")
+
+	again, err := MergeMaps(deepCopy(t, merged), deepCopy(t, overwrite), log)
+	require.NoError(t, err)
+	assert.Equal(t, normalize(t, merged), normalize(t, again), "merge is not idempotent")
+}
+
+// checkPrecedence walks base and overwrite below path alongside merged.
+func checkPrecedence(t *testing.T, merged, base, overwrite map[string]interface{}, path string) {
+	t.Helper()
+	for key, ov := range overwrite {
+		mv, ok := merged[key]
+		require.True(t, ok, "%s%s of overwrite is missing", path, key)
+		ovObj, isObj := ov.(map[string]interface{})
+		if !isObj {
+			assert.Equal(t, ov, mv, "%s%s of overwrite does not take precedence", path, key)
+			continue
+		}
+		mvObj, isObj := mv.(map[string]interface{})
+		require.True(t, isObj, "%s%s is no longer an object", path, key)
+		baseObj, _ := base[key].(map[string]interface{})
+		checkPrecedence(t, mvObj, baseObj, ovObj, path+key+".")
+	}
+	for key, bv := range base {
+		if _, replaced := overwrite[key]; replaced {
+			continue
+		}
+		assert.Equal(t, bv, merged[key], "%s%s of base is lost", path, key)
+	}
+}
+
+func deepCopy(t *testing.T, m map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	if m == nil {
+		return nil
+	}
+	c, err := copystructure.Copy(m)
+	require.NoError(t, err)
+	return c.(map[string]interface{})
+}
+
+// normalize returns v in its JSON form, so results built from Go values and
+// from JSON compare equal.
+func normalize(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out interface{}
+	require.NoError(t, json.Unmarshal(raw, &out))
+	return out
+}
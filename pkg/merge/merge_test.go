@@ -34,3 +34,77 @@ func TestObjectMerge(t *testing.T) {
 	assert.Equal(t, "example.com", res["kcp"].(map[string]interface{})["domains"].([]string)[0])
 	assert.Equal(t, "example2.org", res["kcp"].(map[string]interface{})["domains"].([]string)[1])
 }
+
+func TestMergeMapsWithStrategies(t *testing.T) {
+	base := map[string]interface{}{
+		"services": map[string]interface{}{
+			"iam": map[string]interface{}{
+				"values": map[string]interface{}{
+					"env":  []interface{}{map[string]interface{}{"name": "A", "value": "1"}},
+					"args": []interface{}{"--a"},
+					"tags": []interface{}{"a"},
+				},
+			},
+		},
+	}
+	overwrite := map[string]interface{}{
+		"services": map[string]interface{}{
+			"iam": map[string]interface{}{
+				"values": map[string]interface{}{
+					"env":  []interface{}{map[string]interface{}{"name": "A", "value": "2"}},
+					"args": []interface{}{"--b"},
+					"tags": []interface{}{"b"},
+				},
+			},
+		},
+	}
+	strategies, err := ParseListStrategies(map[string]interface{}{
+		"services.*.values.env":  "merge-by-key:name",
+		"services.*.values.args": "append",
+	})
+	assert.NoError(t, err)
+	log, _ := logger.New(logger.DefaultConfig())
+
+	res, err := MergeMapsWithStrategies(base, overwrite, strategies, log)
+
+	assert.NoError(t, err)
+	values := res["services"].(map[string]interface{})["iam"].(map[string]interface{})["values"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "A", "value": "2"}}, values["env"])
+	assert.Equal(t, []interface{}{"--a", "--b"}, values["args"])
+	// lists without a strategy are replaced
+	assert.Equal(t, []interface{}{"b"}, values["tags"])
+
+	services, err := MergeMapsWithStrategies(base["services"].(map[string]interface{}), map[string]interface{}{
+		"iam": map[string]interface{}{"values": map[string]interface{}{
+			"env": []interface{}{map[string]interface{}{"name": "B", "value": "3"}},
+		}},
+	}, strategies.Under("services"), log)
+	assert.NoError(t, err)
+	assert.Len(t, services["iam"].(map[string]interface{})["values"].(map[string]interface{})["env"], 2)
+}
+
+func TestParseListStrategy(t *testing.T) {
+	s, err := ParseListStrategy("merge-by-key:name")
+	assert.NoError(t, err)
+	assert.Equal(t, ListStrategy{Mode: ListMergeByKey, Key: "name"}, s)
+
+	_, err = ParseListStrategy("merge-by-key:")
+	assert.Error(t, err)
+	_, err = ParseListStrategy("prepend")
+	assert.EqualError(t, err, `unknown list strategy "prepend", expected one of replace, append, merge-by-key:<field>`)
+	_, err = ParseListStrategies(map[string]interface{}{"env": 1})
+	assert.EqualError(t, err, "list strategy for env must be a string")
+}
+
+func TestMergeMapsMergeByKeyRequiresKey(t *testing.T) {
+	log, _ := logger.New(logger.DefaultConfig())
+	strategies, err := ParseListStrategies(map[string]interface{}{"env": "merge-by-key:name"})
+	assert.NoError(t, err)
+	_, err = MergeMapsWithStrategies(
+		map[string]interface{}{"env": []interface{}{map[string]interface{}{"name": "A"}}},
+		map[string]interface{}{"env": []interface{}{map[string]interface{}{"value": "x"}}},
+		strategies,
+		log,
+	)
+	assert.EqualError(t, err, `list env: item has no scalar "name" field to merge by`)
+}
@@ -0,0 +1,161 @@
+// Package openfga is a minimal client of the OpenFGA HTTP API for creating
+// stores and writing authorization models, and embeds the base authorization
+// model of the core workspaces.
+package openfga
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaseModel is the authorization model embedded in the operator, in the JSON
+// form of the OpenFGA API.
+//
+//go:embed model.json
+var BaseModel []byte
+
+// ErrNotFound is returned for stores that do not exist.
+var ErrNotFound = errors.New("not found")
+
+// Store is an OpenFGA store.
+type Store struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Client talks to the HTTP API of an OpenFGA server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the OpenFGA server at baseURL. httpClient
+// defaults to http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// GetStore returns the store with the given ID or ErrNotFound.
+func (c *Client) GetStore(ctx context.Context, id string) (*Store, error) {
+	store := &Store{}
+	if err := c.do(ctx, http.MethodGet, "/stores/"+url.PathEscape(id), nil, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// FindStore returns the first store named name or ErrNotFound.
+func (c *Client) FindStore(ctx context.Context, name string) (*Store, error) {
+	token := ""
+	for {
+		query := url.Values{"page_size": {"100"}}
+		if token != "" {
+			query.Set("continuation_token", token)
+		}
+		var page struct {
+			Stores            []Store `json:"stores"`
+			ContinuationToken string  `json:"continuation_token"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/stores?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, s := range page.Stores {
+			if s.Name == name {
+				return &s, nil
+			}
+		}
+		if page.ContinuationToken == "" {
+			return nil, ErrNotFound
+		}
+		token = page.ContinuationToken
+	}
+}
+
+// CreateStore creates a store named name.
+func (c *Client) CreateStore(ctx context.Context, name string) (*Store, error) {
+	store := &Store{}
+	if err := c.do(ctx, http.MethodPost, "/stores", map[string]string{"name": name}, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// WriteAuthorizationModel writes model to the store and returns the ID of the
+// new model. Models are immutable, every write creates a new latest model.
+func (c *Client) WriteAuthorizationModel(ctx context.Context, storeID string, model []byte) (string, error) {
+	var resp struct {
+		AuthorizationModelID string `json:"authorization_model_id"`
+	}
+	path := "/stores/" + url.PathEscape(storeID) + "/authorization-models"
+	if err := c.do(ctx, http.MethodPost, path, json.RawMessage(model), &resp); err != nil {
+		return "", err
+	}
+	return resp.AuthorizationModelID, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, path, ErrNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// ModelHash returns a hash of model that ignores formatting, so that a model
+// is only written again when its content changed.
+func ModelHash(model []byte) (string, error) {
+	var v any
+	if err := json.Unmarshal(model, &v); err != nil {
+		return "", fmt.Errorf("invalid authorization model: %w", err)
+	}
+	// Marshalling sorts map keys, so the hash is independent of the key order.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,93 @@
+package openfga
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var written []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stores", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("continuation_token") == "" {
+			_, _ = w.Write([]byte(`{"stores":[{"id":"01A","name":"other"}],"continuation_token":"next"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"stores":[{"id":"01B","name":"platform-mesh"}],"continuation_token":""}`))
+	})
+	mux.HandleFunc("GET /stores/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "01B" {
+			http.Error(w, `{"code":"store_id_not_found"}`, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"01B","name":"platform-mesh"}`))
+	})
+	mux.HandleFunc("POST /stores", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"01C","name":"` + req["name"] + `"}`))
+	})
+	mux.HandleFunc("POST /stores/{id}/authorization-models", func(w http.ResponseWriter, r *http.Request) {
+		written, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"authorization_model_id":"01M"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL+"/", nil)
+
+	store, err := c.FindStore(ctx, "platform-mesh")
+	require.NoError(t, err)
+	assert.Equal(t, &Store{ID: "01B", Name: "platform-mesh"}, store)
+
+	_, err = c.FindStore(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = c.GetStore(ctx, "01X")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	store, err = c.CreateStore(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, &Store{ID: "01C", Name: "new"}, store)
+
+	id, err := c.WriteAuthorizationModel(ctx, "01B", BaseModel)
+	require.NoError(t, err)
+	assert.Equal(t, "01M", id)
+	assert.JSONEq(t, string(BaseModel), string(written))
+}
+
+func TestClient_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"code":"validation_error","message":"invalid model"}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, nil).WriteAuthorizationModel(context.Background(), "01B", []byte(`{}`))
+	assert.ErrorContains(t, err, "400 Bad Request")
+	assert.ErrorContains(t, err, "invalid model")
+}
+
+func TestModelHash(t *testing.T) {
+	a, err := ModelHash([]byte(`{"schema_version":"1.1","type_definitions":[{"type":"user"}]}`))
+	require.NoError(t, err)
+	b, err := ModelHash([]byte("{\n  \"type_definitions\": [{\"type\": \"user\"}],\n  \"schema_version\": \"1.1\"\n}"))
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	c, err := ModelHash(BaseModel)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c)
+
+	_, err = ModelHash([]byte("not json"))
+	assert.Error(t, err)
+}
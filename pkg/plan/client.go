@@ -0,0 +1,258 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client wraps c so that its writes are recorded as actions on cluster
+// instead of being sent. Reads still go to c.
+func (r *Recorder) Client(cluster string, c client.Client) client.Client {
+	return &recordingClient{Client: c, cluster: cluster, recorder: r}
+}
+
+// KcpHelper creates clients for KCP workspaces.
+type KcpHelper interface {
+	NewKcpClient(config *rest.Config, workspacePath string) (client.Client, error)
+}
+
+// KcpHelper wraps h so that the clients it creates record their writes on the
+// cluster of their workspace, see KcpCluster.
+func (r *Recorder) KcpHelper(h KcpHelper) KcpHelper {
+	return &recordingKcpHelper{helper: h, recorder: r}
+}
+
+type recordingKcpHelper struct {
+	helper   KcpHelper
+	recorder *Recorder
+}
+
+func (h *recordingKcpHelper) NewKcpClient(config *rest.Config, workspacePath string) (client.Client, error) {
+	c, err := h.helper.NewKcpClient(config, workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	return h.recorder.Client(KcpCluster(workspacePath), c), nil
+}
+
+type recordingClient struct {
+	client.Client
+	cluster  string
+	recorder *Recorder
+}
+
+func (c *recordingClient) gvk(obj runtime.Object) schema.GroupVersionKind {
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		return gvk
+	}
+	gvk, err := c.Client.GroupVersionKindFor(obj)
+	if err != nil {
+		return schema.GroupVersionKind{}
+	}
+	return gvk
+}
+
+// exists looks up the live object of gvk. Kinds the cluster does not serve yet
+// count as missing.
+func (c *recordingClient) exists(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (bool, error) {
+	live, err := c.live(ctx, gvk, namespace, name)
+	return live != nil, err
+}
+
+// live returns the live object of gvk, or nil when it does not exist.
+func (c *recordingClient) live(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err := c.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, live)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+func (c *recordingClient) record(verb Verb, gvk schema.GroupVersionKind, namespace, name, subresource string) {
+	c.recordChanges(verb, gvk, namespace, name, subresource, nil)
+}
+
+func (c *recordingClient) recordChanges(verb Verb, gvk schema.GroupVersionKind, namespace, name, subresource string, changes []string) {
+	c.recorder.record(Action{
+		Verb:        verb,
+		Cluster:     c.cluster,
+		APIVersion:  gvk.GroupVersion().String(),
+		Kind:        gvk.Kind,
+		Namespace:   namespace,
+		Name:        name,
+		Subresource: subresource,
+		Changes:     changes,
+	})
+}
+
+// recordApply records an apply of desired as create or update depending on
+// whether the object exists. A diff recorder skips applies without changes.
+func (c *recordingClient) recordApply(ctx context.Context, desired *unstructured.Unstructured) error {
+	gvk := desired.GroupVersionKind()
+	live, err := c.live(ctx, gvk, desired.GetNamespace(), desired.GetName())
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		c.record(VerbCreate, gvk, desired.GetNamespace(), desired.GetName(), "")
+		return nil
+	}
+	if !c.recorder.diff {
+		c.record(VerbUpdate, gvk, desired.GetNamespace(), desired.GetName(), "")
+		return nil
+	}
+	changes := Changes(live.Object, desired.Object, gvk.Group == "" && gvk.Kind == "Secret")
+	if len(changes) == 0 {
+		return nil
+	}
+	c.recordChanges(VerbUpdate, gvk, desired.GetNamespace(), desired.GetName(), "", changes)
+	return nil
+}
+
+// toUnstructured returns obj as unstructured object of gvk.
+func toUnstructured(obj client.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
+func (c *recordingClient) Create(ctx context.Context, obj client.Object, _ ...client.CreateOption) error {
+	gvk := c.gvk(obj)
+	found, err := c.exists(ctx, gvk, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return err
+	}
+	if found {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, obj.GetName())
+	}
+	c.record(VerbCreate, gvk, obj.GetNamespace(), obj.GetName(), "")
+	return nil
+}
+
+func (c *recordingClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.record(VerbUpdate, c.gvk(obj), obj.GetNamespace(), obj.GetName(), "")
+	return nil
+}
+
+func (c *recordingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, _ ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		desired, err := toUnstructured(obj, c.gvk(obj))
+		if err != nil {
+			return err
+		}
+		return c.recordApply(ctx, desired)
+	}
+	c.record(VerbUpdate, c.gvk(obj), obj.GetNamespace(), obj.GetName(), "")
+	return nil
+}
+
+func (c *recordingClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, _ ...client.ApplyOption) error {
+	u, err := applyConfigurationToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	return c.recordApply(ctx, u)
+}
+
+func (c *recordingClient) Delete(ctx context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	gvk := c.gvk(obj)
+	found, err := c.exists(ctx, gvk, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, obj.GetName())
+	}
+	c.record(VerbDelete, gvk, obj.GetNamespace(), obj.GetName(), "")
+	return nil
+}
+
+func (c *recordingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	gvk := c.gvk(obj)
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	listOpts := &client.DeleteAllOfOptions{}
+	listOpts.ApplyOptions(opts)
+	if err := c.Client.List(ctx, list, &listOpts.ListOptions); err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		c.record(VerbDelete, gvk, item.GetNamespace(), item.GetName(), "")
+	}
+	return nil
+}
+
+func (c *recordingClient) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *recordingClient) SubResource(subResource string) client.SubResourceClient {
+	return &recordingSubResourceClient{SubResourceClient: c.Client.SubResource(subResource), client: c, subResource: subResource}
+}
+
+type recordingSubResourceClient struct {
+	client.SubResourceClient
+	client      *recordingClient
+	subResource string
+}
+
+func (s *recordingSubResourceClient) record(obj client.Object, verb Verb) {
+	s.client.record(verb, s.client.gvk(obj), obj.GetNamespace(), obj.GetName(), s.subResource)
+}
+
+func (s *recordingSubResourceClient) Create(_ context.Context, obj client.Object, _ client.Object, _ ...client.SubResourceCreateOption) error {
+	s.record(obj, VerbCreate)
+	return nil
+}
+
+func (s *recordingSubResourceClient) Update(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	s.record(obj, VerbUpdate)
+	return nil
+}
+
+func (s *recordingSubResourceClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	s.record(obj, VerbUpdate)
+	return nil
+}
+
+func (s *recordingSubResourceClient) Apply(_ context.Context, obj runtime.ApplyConfiguration, _ ...client.SubResourceApplyOption) error {
+	u, err := applyConfigurationToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	s.record(u, VerbUpdate)
+	return nil
+}
+
+func applyConfigurationToUnstructured(obj runtime.ApplyConfiguration) (*unstructured.Unstructured, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
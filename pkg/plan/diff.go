@@ -0,0 +1,90 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the values of changed Secret data in a diff.
+const redacted = "<redacted>"
+
+// unset stands for a field that is missing on one side of a diff.
+const unset = "<unset>"
+
+// Changes returns the fields of desired that differ from live as
+// "path: live -> desired" lines sorted by path. Only fields set in desired are
+// compared, as a server-side apply leaves the other fields alone. Status and
+// server-managed metadata are ignored. With redact, values below data and
+// stringData are replaced, so that Secret content does not end up in a plan.
+func Changes(live, desired map[string]interface{}, redact bool) []string {
+	var changes []string
+	for key, value := range desired {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			meta, _ := value.(map[string]interface{})
+			liveMeta, _ := live["metadata"].(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if v, ok := meta[field]; ok {
+					changes = diffValue(changes, "metadata"+pathSegment(field), liveMeta[field], v, false)
+				}
+			}
+			continue
+		}
+		changes = diffValue(changes, pathSegment(key), live[key], value, redact && (key == "data" || key == "stringData"))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func diffValue(changes []string, path string, live, desired interface{}, redact bool) []string {
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	liveMap, liveIsMap := live.(map[string]interface{})
+	if desiredIsMap && (liveIsMap || live == nil) {
+		for key, value := range desiredMap {
+			var liveValue interface{}
+			if liveMap != nil {
+				liveValue = liveMap[key]
+			}
+			changes = diffValue(changes, path+pathSegment(key), liveValue, value, redact)
+		}
+		return changes
+	}
+
+	liveJSON, desiredJSON := formatValue(live), formatValue(desired)
+	if liveJSON == desiredJSON {
+		return changes
+	}
+	if redact {
+		if live != nil {
+			liveJSON = redacted
+		}
+		desiredJSON = redacted
+	}
+	return append(changes, fmt.Sprintf("%s: %s -> %s", strings.TrimPrefix(path, "."), liveJSON, desiredJSON))
+}
+
+// formatValue renders v as JSON, which also makes numbers decoded as int64
+// and float64 compare equal.
+func formatValue(v interface{}) string {
+	if v == nil {
+		return unset
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(raw)
+}
+
+// pathSegment returns key as path segment. Keys with dots or slashes, such as
+// annotation names, are quoted.
+func pathSegment(key string) string {
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("[%q]", key)
+	}
+	return "." + key
+}
@@ -0,0 +1,145 @@
+// Package plan records the writes of a reconciliation instead of executing
+// them, so that the actions of a change can be reviewed before they happen.
+//
+// Clients wrapped by a Recorder forward reads to the cluster and turn every
+// write into an Action. The actions keep the order in which the pipeline
+// issued them; Plan summarizes them into the KCP workspaces that are touched
+// and the secrets whose content would change.
+package plan
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Verb is the kind of change an action makes to an object.
+type Verb string
+
+const (
+	VerbCreate Verb = "create"
+	VerbUpdate Verb = "update"
+	VerbDelete Verb = "delete"
+)
+
+// Clusters of the actions outside of KCP.
+const (
+	ClusterRuntime = "runtime"
+	ClusterInfra   = "infra"
+)
+
+// kcpClusterPrefix marks the cluster of actions in a KCP workspace.
+const kcpClusterPrefix = "kcp:"
+
+// KcpCluster returns the cluster name of the KCP workspace at path.
+func KcpCluster(path string) string {
+	return kcpClusterPrefix + path
+}
+
+// Action is a single write the reconciliation would make.
+type Action struct {
+	Verb        Verb   `json:"verb"`
+	Cluster     string `json:"cluster"`
+	APIVersion  string `json:"apiVersion"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	Subresource string `json:"subresource,omitempty"`
+	// Changes lists the fields an apply to an existing object would change,
+	// see Changes. It is only set by a Recorder made with NewDiffRecorder.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// sameObject reports whether a and b are the same verb on the same object.
+func (a Action) sameObject(b Action) bool {
+	return a.Verb == b.Verb && a.Cluster == b.Cluster && a.APIVersion == b.APIVersion && a.Kind == b.Kind &&
+		a.Namespace == b.Namespace && a.Name == b.Name && a.Subresource == b.Subresource
+}
+
+// Plan is the ordered result of a planned reconciliation.
+type Plan struct {
+	// Generation is the generation of the PlatformMesh the plan was made for.
+	Generation int64    `json:"generation"`
+	Actions    []Action `json:"actions"`
+	// Workspaces lists the KCP workspaces with actions, in order of first use.
+	Workspaces []string `json:"workspaces,omitempty"`
+	// SecretRotations lists the existing secrets, as cluster/namespace/name,
+	// whose content would change.
+	SecretRotations []string `json:"secretRotations,omitempty"`
+	// Incomplete explains why planning stopped before the end of the pipeline.
+	// Actions after that point are not part of the plan.
+	Incomplete string `json:"incomplete,omitempty"`
+}
+
+// Recorder collects the actions of the clients it wraps.
+type Recorder struct {
+	mu      sync.Mutex
+	actions []Action
+	diff    bool
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewDiffRecorder returns a Recorder that also compares applied objects with
+// the live ones. Applies to existing objects list the fields they would
+// change, and applies that would change nothing are not recorded.
+func NewDiffRecorder() *Recorder {
+	return &Recorder{diff: true}
+}
+
+func (r *Recorder) record(a Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The same object is often applied several times in one pass. The last
+	// apply wins, so its changes are kept.
+	for i, existing := range r.actions {
+		if existing.sameObject(a) {
+			r.actions[i].Changes = a.Changes
+			return
+		}
+	}
+	r.actions = append(r.actions, a)
+}
+
+// Actions returns the recorded actions in order.
+func (r *Recorder) Actions() []Action {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Action(nil), r.actions...)
+}
+
+// Plan returns the recorded actions as plan for generation. incomplete is
+// empty when the whole pipeline ran.
+func (r *Recorder) Plan(generation int64, incomplete string) *Plan {
+	p := &Plan{Generation: generation, Actions: r.Actions(), Incomplete: incomplete}
+	if p.Actions == nil {
+		p.Actions = []Action{}
+	}
+	seen := map[string]bool{}
+	for _, a := range p.Actions {
+		if ws, ok := strings.CutPrefix(a.Cluster, kcpClusterPrefix); ok && !seen[ws] {
+			seen[ws] = true
+			p.Workspaces = append(p.Workspaces, ws)
+		}
+		if a.Verb == VerbUpdate && a.Kind == "Secret" && a.Subresource == "" {
+			p.SecretRotations = append(p.SecretRotations, a.Cluster+"/"+a.Namespace+"/"+a.Name)
+		}
+	}
+	return p
+}
+
+type planningKey struct{}
+
+// WithPlanning marks ctx as a planned reconciliation. Code with side effects
+// outside of the recorded clients checks IsPlanning and skips them.
+func WithPlanning(ctx context.Context) context.Context {
+	return context.WithValue(ctx, planningKey{}, true)
+}
+
+// IsPlanning reports whether ctx belongs to a planned reconciliation.
+func IsPlanning(ctx context.Context) bool {
+	planning, _ := ctx.Value(planningKey{}).(bool)
+	return planning
+}
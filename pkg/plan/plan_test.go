@@ -0,0 +1,124 @@
+package plan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func secret(name string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func TestRecordingClient(t *testing.T) {
+	ctx := context.Background()
+	live := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(secret("existing")).Build()
+	rec := NewRecorder()
+	cl := rec.Client(ClusterRuntime, live)
+
+	require.NoError(t, cl.Create(ctx, secret("new")))
+	assert.True(t, apierrors.IsAlreadyExists(cl.Create(ctx, secret("existing"))))
+	require.NoError(t, cl.Update(ctx, secret("existing")))
+	require.NoError(t, cl.Apply(ctx, corev1ac.ConfigMap("applied", "default")))
+	require.NoError(t, cl.Patch(ctx, secret("existing"), client.Apply))
+	require.NoError(t, cl.Status().Update(ctx, secret("existing")))
+	require.NoError(t, cl.Delete(ctx, secret("existing")))
+	assert.True(t, apierrors.IsNotFound(cl.Delete(ctx, secret("missing"))))
+
+	assert.Equal(t, []Action{
+		{Verb: VerbCreate, Cluster: ClusterRuntime, APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "new"},
+		{Verb: VerbUpdate, Cluster: ClusterRuntime, APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "existing"},
+		{Verb: VerbCreate, Cluster: ClusterRuntime, APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "applied"},
+		{Verb: VerbUpdate, Cluster: ClusterRuntime, APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "existing", Subresource: "status"},
+		{Verb: VerbDelete, Cluster: ClusterRuntime, APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "existing"},
+	}, rec.Actions())
+
+	// Nothing reached the cluster.
+	require.NoError(t, live.Get(ctx, types.NamespacedName{Name: "existing", Namespace: "default"}, &corev1.Secret{}))
+	assert.True(t, apierrors.IsNotFound(live.Get(ctx, types.NamespacedName{Name: "new", Namespace: "default"}, &corev1.Secret{})))
+}
+
+func TestPlanSummary(t *testing.T) {
+	ctx := context.Background()
+	live := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(secret("existing")).Build()
+	rec := NewRecorder()
+	require.NoError(t, rec.Client(KcpCluster("root:orgs"), live).Create(ctx, secret("new")))
+	require.NoError(t, rec.Client(KcpCluster("root"), live).Update(ctx, secret("existing")))
+	require.NoError(t, rec.Client(KcpCluster("root:orgs"), live).Update(ctx, secret("existing")))
+
+	p := rec.Plan(4, "")
+	assert.Equal(t, int64(4), p.Generation)
+	assert.Len(t, p.Actions, 3)
+	assert.Equal(t, []string{"root:orgs", "root"}, p.Workspaces)
+	assert.Equal(t, []string{"kcp:root/default/existing", "kcp:root:orgs/default/existing"}, p.SecretRotations)
+	assert.Empty(t, p.Incomplete)
+
+	assert.Equal(t, []Action{}, NewRecorder().Plan(1, "stopped").Actions)
+}
+
+func TestPlanning(t *testing.T) {
+	assert.False(t, IsPlanning(context.Background()))
+	assert.True(t, IsPlanning(WithPlanning(context.Background())))
+}
+
+func TestDiffRecorder(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default", Labels: map[string]string{"app": "portal"}},
+		Data:       map[string]string{"replicas": "1", "mode": "a"},
+	}
+	credentials := secret("credentials")
+	credentials.Data = map[string][]byte{"token": []byte("old")}
+	live := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(existing, credentials).Build()
+	rec := NewDiffRecorder()
+	cl := rec.Client(ClusterInfra, live)
+
+	require.NoError(t, cl.Apply(ctx, corev1ac.ConfigMap("config", "default").WithData(map[string]string{"replicas": "2", "mode": "a"})))
+	require.NoError(t, cl.Apply(ctx, corev1ac.ConfigMap("config", "default").WithLabels(map[string]string{"app": "portal"})))
+	require.NoError(t, cl.Apply(ctx, corev1ac.ConfigMap("new", "default").WithData(map[string]string{"a": "b"})))
+	require.NoError(t, cl.Apply(ctx, corev1ac.Secret("credentials", "default").WithData(map[string][]byte{"token": []byte("new")})))
+
+	assert.Equal(t, []Action{
+		{Verb: VerbUpdate, Cluster: ClusterInfra, APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "config", Changes: []string{`data.replicas: "1" -> "2"`}},
+		{Verb: VerbCreate, Cluster: ClusterInfra, APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "new"},
+		{Verb: VerbUpdate, Cluster: ClusterInfra, APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "credentials", Changes: []string{"data.token: <redacted> -> <redacted>"}},
+	}, rec.Actions())
+}
+
+func TestChanges(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "portal", "resourceVersion": "7", "annotations": map[string]interface{}{"a.io/b": "x"}},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"values":   map[string]interface{}{"image": map[string]interface{}{"tag": "1.0"}, "other": true},
+			"list":     []interface{}{"a", "b"},
+		},
+	}
+	desired := map[string]interface{}{
+		"apiVersion": "v1",
+		"metadata":   map[string]interface{}{"name": "portal", "annotations": map[string]interface{}{"a.io/b": "y"}},
+		"spec": map[string]interface{}{
+			"replicas": float64(2),
+			"values":   map[string]interface{}{"image": map[string]interface{}{"tag": "1.1"}, "added": map[string]interface{}{"x": 1}},
+			"list":     []interface{}{"a"},
+		},
+	}
+
+	assert.Equal(t, []string{
+		`metadata.annotations["a.io/b"]: "x" -> "y"`,
+		`spec.list: ["a","b"] -> ["a"]`,
+		`spec.values.added.x: <unset> -> 1`,
+		`spec.values.image.tag: "1.0" -> "1.1"`,
+	}, Changes(live, desired, false))
+	assert.Empty(t, Changes(live, live, false))
+}
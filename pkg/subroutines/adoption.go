@@ -0,0 +1,274 @@
+package subroutines
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// AdoptedComponentLabel holds the component an adopted HelmRelease
+	// deploys.
+	AdoptedComponentLabel = "core.platform-mesh.io/adopted-component"
+	// AdoptAsAnnotation names the component an existing HelmRelease deploys
+	// when its Helm release name differs from the component.
+	AdoptAsAnnotation = "core.platform-mesh.io/adopt-as"
+
+	// The Flux kustomize-controller neither applies nor prunes objects with
+	// these annotations, so the Kustomization that created an adopted
+	// HelmRelease does not revert the values of the operator or delete it.
+	kustomizeReconcileAnnotation = "kustomize.toolkit.fluxcd.io/reconcile"
+	kustomizePruneAnnotation     = "kustomize.toolkit.fluxcd.io/prune"
+)
+
+// releaseAdoption maps profile components to the HelmReleases that deployed
+// them before the operator managed them, see spec.adoption.
+type releaseAdoption struct {
+	// discover is set while spec.adoption is enabled. Releases adopted
+	// earlier are managed either way.
+	discover bool
+	approved []string
+	// adopted are the HelmReleases adopted for the instance, candidates the
+	// HelmReleases not created by the operator, by component.
+	adopted    map[string]*unstructured.Unstructured
+	candidates map[string]*unstructured.Unstructured
+	// previous are the adoptions in the status of the instance, records those
+	// found while rendering.
+	previous map[string]corev1alpha1.ComponentAdoption
+	records  map[string]corev1alpha1.ComponentAdoption
+	now      func() time.Time
+}
+
+// discoverReleaseAdoption lists the HelmReleases in the adoption namespaces
+// of inst, the release namespace unless spec.adoption names others. It
+// returns nil when inst neither configures nor reports an adoption.
+func (r *DeploymentSubroutine) discoverReleaseAdoption(ctx context.Context, inst *corev1alpha1.PlatformMesh, releaseNamespace string, log *logger.Logger) (*releaseAdoption, error) {
+	cfg := inst.Spec.Adoption
+	if cfg == nil && len(inst.Status.Adoptions) == 0 {
+		return nil, nil
+	}
+	a := &releaseAdoption{
+		adopted:    map[string]*unstructured.Unstructured{},
+		candidates: map[string]*unstructured.Unstructured{},
+		previous:   map[string]corev1alpha1.ComponentAdoption{},
+		records:    map[string]corev1alpha1.ComponentAdoption{},
+		now:        time.Now,
+	}
+	namespaces := map[string]bool{}
+	if cfg != nil {
+		a.discover = cfg.Enabled
+		a.approved = cfg.Components
+		for _, ns := range cfg.Namespaces {
+			namespaces[ns] = true
+		}
+	}
+	if len(namespaces) == 0 {
+		namespaces[releaseNamespace] = true
+	}
+	for _, prev := range inst.Status.Adoptions {
+		a.previous[prev.Component] = prev
+		namespaces[prev.Namespace] = true
+	}
+
+	for _, ns := range slices.Sorted(maps.Keys(namespaces)) {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(helmReleaseGVK.GroupVersion().WithKind("HelmReleaseList"))
+		err := r.clientInfra.List(ctx, list, client.InNamespace(ns))
+		if apimeta.IsNoMatchError(err) {
+			// Without Flux there is nothing to adopt.
+			return a, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list HelmReleases in namespace %s", ns)
+		}
+		for i := range list.Items {
+			a.add(&list.Items[i], inst, log)
+		}
+	}
+	return a, nil
+}
+
+// add classifies release as adopted for inst or, unless the operator created
+// it, as candidate for the component it deploys. Of several candidates for a
+// component the first one is kept.
+func (a *releaseAdoption) add(release *unstructured.Unstructured, inst *corev1alpha1.PlatformMesh, log *logger.Logger) {
+	labels := release.GetLabels()
+	if component := labels[AdoptedComponentLabel]; component != "" {
+		if labeledInstance(release) == instanceKey(inst) {
+			a.adopted[component] = release
+		}
+		return
+	}
+	if !a.discover || labels[operatorCreatedLabel] == "true" {
+		return
+	}
+	component := releaseComponent(release)
+	if first, ok := a.candidates[component]; ok {
+		log.Warn().Str("component", component).Str("helmRelease", release.GetNamespace()+"/"+release.GetName()).
+			Str("candidate", first.GetNamespace()+"/"+first.GetName()).Msg("Several HelmReleases deploy the component, ignoring all but the first")
+		return
+	}
+	a.candidates[component] = release
+}
+
+// releaseComponent returns the component release deploys: the adopt-as
+// annotation, else its Helm release name.
+func releaseComponent(release *unstructured.Unstructured) string {
+	if component := release.GetAnnotations()[AdoptAsAnnotation]; component != "" {
+		return component
+	}
+	if name, _, _ := unstructured.NestedString(release.Object, "spec", "releaseName"); name != "" {
+		return name
+	}
+	return release.GetName()
+}
+
+// postProcess wraps next. The components infra templates render the
+// HelmRelease of a component under the name of the component. It is
+// rewritten to the release adopted for the component, and dropped while the
+// component has a discovered release that is not listed for adoption yet, so
+// the release is not installed twice.
+func (a *releaseAdoption) postProcess(next func(ctx context.Context, obj *unstructured.Unstructured) error) func(ctx context.Context, obj *unstructured.Unstructured) error {
+	return func(ctx context.Context, obj *unstructured.Unstructured) error {
+		if next != nil {
+			if err := next(ctx, obj); err != nil {
+				return err
+			}
+		}
+		if obj.GroupVersionKind() != helmReleaseGVK {
+			return nil
+		}
+		component := obj.GetName()
+		if release, ok := a.adopted[component]; ok {
+			adoptRelease(obj, release, component)
+			return nil
+		}
+		release, ok := a.candidates[component]
+		if !ok {
+			return nil
+		}
+
+		rec := corev1alpha1.ComponentAdoption{
+			Component: component,
+			Namespace: release.GetNamespace(),
+			Name:      release.GetName(),
+			Phase:     corev1alpha1.AdoptionDiscovered,
+		}
+		rec.ChangedValues, rec.ExtraValues = valuesDiff(obj, release)
+		if !a.isApproved(component) {
+			a.records[component] = rec
+			return errSkipObject
+		}
+		adoptedAt := metav1.NewTime(a.now())
+		rec.Phase, rec.AdoptedAt = corev1alpha1.AdoptionAdopted, &adoptedAt
+		a.records[component] = rec
+		adoptRelease(obj, release, component)
+		return nil
+	}
+}
+
+func (a *releaseAdoption) isApproved(component string) bool {
+	return slices.Contains(a.approved, component) || slices.Contains(a.approved, "*")
+}
+
+// adoptRelease rewrites the rendered HelmRelease obj to release. The Helm
+// release name and namespaces of release are kept, as Flux would install the
+// release anew under another name.
+func adoptRelease(obj, release *unstructured.Unstructured, component string) {
+	obj.SetName(release.GetName())
+	obj.SetNamespace(release.GetNamespace())
+	for _, field := range []string{"releaseName", "targetNamespace", "storageNamespace"} {
+		if value, found, _ := unstructured.NestedString(release.Object, "spec", field); found {
+			_ = unstructured.SetNestedField(obj.Object, value, "spec", field)
+		} else {
+			unstructured.RemoveNestedField(obj.Object, "spec", field)
+		}
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[AdoptedComponentLabel] = component
+	obj.SetLabels(labels)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[kustomizeReconcileAnnotation] = "disabled"
+	annotations[kustomizePruneAnnotation] = "disabled"
+	obj.SetAnnotations(annotations)
+}
+
+// valuesDiff returns the paths of the values rendered sets to another value
+// than release, and of the values only release sets.
+func valuesDiff(rendered, release *unstructured.Unstructured) ([]string, []string) {
+	desired, _, _ := unstructured.NestedFieldNoCopy(rendered.Object, "spec", "values")
+	live, _, _ := unstructured.NestedFieldNoCopy(release.Object, "spec", "values")
+	changed := appendDrift(nil, "values", desired, live)
+	var extra []string
+	for _, path := range appendDrift(nil, "values", live, desired) {
+		if !slices.Contains(changed, path) {
+			extra = append(extra, path)
+		}
+	}
+	return changed, extra
+}
+
+// report replaces status.adoptions of inst with the adoptions found while
+// rendering and the releases adopted earlier, and records an event for each
+// release that was discovered or adopted since the last report.
+func (a *releaseAdoption) report(ctx context.Context, inst *corev1alpha1.PlatformMesh) {
+	for component, release := range a.adopted {
+		if _, ok := a.records[component]; ok {
+			continue
+		}
+		rec := a.previous[component]
+		if rec.Phase != corev1alpha1.AdoptionAdopted {
+			rec = corev1alpha1.ComponentAdoption{Component: component, Phase: corev1alpha1.AdoptionAdopted}
+		}
+		rec.Namespace, rec.Name = release.GetNamespace(), release.GetName()
+		a.records[component] = rec
+	}
+
+	records := slices.SortedFunc(maps.Values(a.records), func(x, y corev1alpha1.ComponentAdoption) int {
+		return strings.Compare(x.Component, y.Component)
+	})
+	for _, rec := range records {
+		if prev, ok := a.previous[rec.Component]; ok && prev.Phase == rec.Phase {
+			continue
+		}
+		switch rec.Phase {
+		case corev1alpha1.AdoptionDiscovered:
+			recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonReleaseDiscovered, "Adopt",
+				"Discovered HelmRelease %s/%s of component %s, list the component in spec.adoption.components to adopt it", rec.Namespace, rec.Name, rec.Component)
+		case corev1alpha1.AdoptionAdopted:
+			recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonComponentAdopted, "Adopt",
+				"Adopted HelmRelease %s/%s of component %s", rec.Namespace, rec.Name, rec.Component)
+		}
+	}
+	inst.Status.Adoptions = records
+}
+
+// adoptedRelease returns the key of the HelmRelease adopted for component as
+// reported in the status of inst, or false if it has none.
+func adoptedRelease(inst *corev1alpha1.PlatformMesh, component string) (client.ObjectKey, bool) {
+	for _, rec := range inst.Status.Adoptions {
+		if rec.Component == component && rec.Phase == corev1alpha1.AdoptionAdopted {
+			return client.ObjectKey{Namespace: rec.Namespace, Name: rec.Name}, true
+		}
+	}
+	return client.ObjectKey{}, false
+}
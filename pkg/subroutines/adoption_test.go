@@ -0,0 +1,165 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func newAdoptionHelmRelease(t *testing.T, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	release := &unstructured.Unstructured{}
+	release.SetGroupVersionKind(helmReleaseGVK)
+	release.SetNamespace("flux-system")
+	release.SetName(name)
+	require.NoError(t, unstructured.SetNestedMap(release.Object, spec, "spec"))
+	return release
+}
+
+func TestReleaseComponent(t *testing.T) {
+	release := newAdoptionHelmRelease(t, "portal-release", map[string]interface{}{})
+	assert.Equal(t, "portal-release", releaseComponent(release))
+	require.NoError(t, unstructured.SetNestedField(release.Object, "portal", "spec", "releaseName"))
+	assert.Equal(t, "portal", releaseComponent(release))
+	release.SetAnnotations(map[string]string{AdoptAsAnnotation: "iam"})
+	assert.Equal(t, "iam", releaseComponent(release))
+}
+
+func TestValuesDiff(t *testing.T) {
+	rendered := newAdoptionHelmRelease(t, "portal", map[string]interface{}{
+		"values": map[string]interface{}{"replicas": int64(2), "image": map[string]interface{}{"tag": "1.1.0"}, "debug": true},
+	})
+	release := newAdoptionHelmRelease(t, "portal", map[string]interface{}{
+		"values": map[string]interface{}{"replicas": int64(2), "image": map[string]interface{}{"tag": "1.0.0"}, "nodeSelector": map[string]interface{}{"pool": "infra"}},
+	})
+	changed, extra := valuesDiff(rendered, release)
+	assert.Equal(t, []string{"values.debug", "values.image.tag"}, changed)
+	assert.Equal(t, []string{"values.nodeSelector"}, extra)
+}
+
+func TestReleaseAdoption(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+
+	existing := newAdoptionHelmRelease(t, "portal-release", map[string]interface{}{
+		"releaseName":     "portal",
+		"targetNamespace": "portal",
+		"values":          map[string]interface{}{"replicas": int64(1), "nodeSelector": map[string]interface{}{"pool": "infra"}},
+	})
+	created := newAdoptionHelmRelease(t, "iam", map[string]interface{}{})
+	created.SetLabels(map[string]string{operatorCreatedLabel: "true"})
+	cl := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(existing, created).Build()
+	sub := &DeploymentSubroutine{clientInfra: cl}
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"},
+		Spec: corev1alpha1.PlatformMeshSpec{Adoption: &corev1alpha1.AdoptionConfig{
+			Enabled:    true,
+			Namespaces: []string{"flux-system"},
+		}},
+	}
+	render := func(name string) *unstructured.Unstructured {
+		return newAdoptionHelmRelease(t, name, map[string]interface{}{
+			"releaseName":     name,
+			"targetNamespace": "platform-mesh-system",
+			"values":          map[string]interface{}{"replicas": int64(2)},
+		})
+	}
+
+	// A discovered release keeps the operator from rendering its component.
+	adoption, err := sub.discoverReleaseAdoption(ctx, inst, "platform-mesh-system", log)
+	require.NoError(t, err)
+	postProcess := adoption.postProcess(nil)
+	assert.ErrorIs(t, postProcess(ctx, render("portal")), errSkipObject)
+	assert.NoError(t, postProcess(ctx, render("iam")))
+	adoption.report(ctx, inst)
+	assert.Equal(t, []corev1alpha1.ComponentAdoption{{
+		Component:     "portal",
+		Namespace:     "flux-system",
+		Name:          "portal-release",
+		Phase:         corev1alpha1.AdoptionDiscovered,
+		ChangedValues: []string{"values.replicas"},
+		ExtraValues:   []string{"values.nodeSelector"},
+	}}, inst.Status.Adoptions)
+	assert.Equal(t, "Normal ReleaseDiscovered Discovered HelmRelease flux-system/portal-release of component portal, list the component in spec.adoption.components to adopt it", <-rec.Events)
+
+	// Listing the component adopts the release under its own name.
+	inst.Spec.Adoption.Components = []string{"portal"}
+	adoption, err = sub.discoverReleaseAdoption(ctx, inst, "platform-mesh-system", log)
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	adoption.now = func() time.Time { return now }
+	obj := render("portal")
+	require.NoError(t, adoption.postProcess(nil)(ctx, obj))
+	assert.Equal(t, client.ObjectKey{Namespace: "flux-system", Name: "portal-release"}, client.ObjectKeyFromObject(obj))
+	targetNamespace, _, _ := unstructured.NestedString(obj.Object, "spec", "targetNamespace")
+	assert.Equal(t, "portal", targetNamespace)
+	assert.Equal(t, "portal", obj.GetLabels()[AdoptedComponentLabel])
+	assert.Equal(t, "disabled", obj.GetAnnotations()[kustomizeReconcileAnnotation])
+	adoption.report(ctx, inst)
+	require.Len(t, inst.Status.Adoptions, 1)
+	assert.Equal(t, corev1alpha1.AdoptionAdopted, inst.Status.Adoptions[0].Phase)
+	assert.Equal(t, metav1.NewTime(now), *inst.Status.Adoptions[0].AdoptedAt)
+	assert.Equal(t, []string{"values.replicas"}, inst.Status.Adoptions[0].ChangedValues)
+	assert.Equal(t, "Normal ComponentAdopted Adopted HelmRelease flux-system/portal-release of component portal", <-rec.Events)
+
+	// Once applied, the release stays adopted without spec.adoption.
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(helmReleaseGVK)
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(obj), live))
+	live.SetLabels(obj.GetLabels())
+	setInstanceLabels(live, inst)
+	require.NoError(t, cl.Update(ctx, live))
+	inst.Spec.Adoption = nil
+	adoption, err = sub.discoverReleaseAdoption(ctx, inst, "platform-mesh-system", log)
+	require.NoError(t, err)
+	obj = render("portal")
+	require.NoError(t, adoption.postProcess(nil)(ctx, obj))
+	assert.Equal(t, "portal-release", obj.GetName())
+	previous := inst.Status.Adoptions
+	adoption.report(ctx, inst)
+	assert.Equal(t, previous, inst.Status.Adoptions)
+	assert.Empty(t, rec.Events)
+
+	key, ok := adoptedRelease(inst, "portal")
+	assert.True(t, ok)
+	assert.Equal(t, client.ObjectKey{Namespace: "flux-system", Name: "portal-release"}, key)
+	_, ok = adoptedRelease(inst, "iam")
+	assert.False(t, ok)
+}
+
+func TestDiscoverReleaseAdoption_NotConfigured(t *testing.T) {
+	sub := &DeploymentSubroutine{}
+	adoption, err := sub.discoverReleaseAdoption(context.Background(), &corev1alpha1.PlatformMesh{}, "platform-mesh-system", nil)
+	require.NoError(t, err)
+	assert.Nil(t, adoption)
+}
+
+func TestPruneComponent_Adopted(t *testing.T) {
+	ctx, rec, cl, sub, inst := newPruneTest(t)
+	release := newPruneHelmRelease("portal-release", map[string]string{operatorCreatedLabel: "true", AdoptedComponentLabel: "portal"})
+	require.NoError(t, cl.Create(ctx, release))
+	inst.Status.Adoptions = []corev1alpha1.ComponentAdoption{{
+		Component: "portal", Namespace: "platform-mesh-system", Name: "portal-release", Phase: corev1alpha1.AdoptionAdopted,
+	}}
+
+	done, err := sub.pruneComponent(ctx, inst, "portal", "platform-mesh-system", nil, logger.LoadLoggerFromContext(ctx))
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(release), newPruneHelmRelease("portal-release", nil))))
+	assert.Equal(t, "Normal ComponentPruned Pruned HelmRelease platform-mesh-system/portal-release of disabled component portal", <-rec.Events)
+}
@@ -0,0 +1,199 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+)
+
+const (
+	// APIBindingsReadyConditionType is False when applied APIBindings did not
+	// become ready within their timeout, and Unknown while they are still
+	// binding. It is only set while the checks are enabled.
+	APIBindingsReadyConditionType = "APIBindingsReady"
+
+	apiBindingsReasonReady    = "Ready"
+	apiBindingsReasonNotReady = "NotReady"
+	apiBindingsReasonBinding  = "Binding"
+	apiBindingPhaseBound      = "Bound"
+)
+
+// APIBindingsNotReadyError is returned when applied APIBindings are not ready:
+// Failed did not become ready within their timeout, Binding may still become
+// ready.
+type APIBindingsNotReadyError struct {
+	Failed  []corev1alpha1.FailedAPIBinding
+	Binding []corev1alpha1.FailedAPIBinding
+}
+
+func (e *APIBindingsNotReadyError) Error() string {
+	names := make([]string, 0, len(e.Failed)+len(e.Binding))
+	for _, b := range append(slices.Clone(e.Failed), e.Binding...) {
+		names = append(names, fmt.Sprintf("%s in workspace %s", b.Name, b.Workspace))
+	}
+	return fmt.Sprintf("%d APIBinding(s) not ready: %s", len(names), strings.Join(names, ", "))
+}
+
+// apiBindingWait collects the APIBindings applied per workspace, so that
+// ApplyDirStructure checks them once before it descends into child
+// workspaces. A nil *apiBindingWait checks nothing.
+type apiBindingWait struct {
+	timeout time.Duration
+	now     time.Time
+	pending map[string][]string
+	failed  []corev1alpha1.FailedAPIBinding
+	binding []corev1alpha1.FailedAPIBinding
+}
+
+type apiBindingWaitKey struct{}
+
+// withAPIBindingWait returns ctx carrying the checks of the APIBindings applied
+// at now, which fail bindings not ready timeout after their creation. A zero
+// timeout disables the checks.
+func withAPIBindingWait(ctx context.Context, timeout time.Duration, now time.Time) (context.Context, *apiBindingWait) {
+	if timeout <= 0 {
+		return ctx, nil
+	}
+	w := &apiBindingWait{timeout: timeout, now: now, pending: map[string][]string{}}
+	return context.WithValue(ctx, apiBindingWaitKey{}, w), w
+}
+
+func apiBindingWaitFrom(ctx context.Context) *apiBindingWait {
+	w, _ := ctx.Value(apiBindingWaitKey{}).(*apiBindingWait)
+	return w
+}
+
+// add registers the APIBinding name applied to the workspace at wsPath.
+func (w *apiBindingWait) add(wsPath, name string) {
+	if w == nil || slices.Contains(w.pending[wsPath], name) {
+		return
+	}
+	w.pending[wsPath] = append(w.pending[wsPath], name)
+}
+
+// check gets each APIBinding applied to the workspace at wsPath once, without
+// waiting for it. Bindings that are not bound with all conditions True are
+// recorded as binding, or as failed with their conditions once they exist for
+// longer than the timeout, so the reconcile requeues instead of blocking.
+func (w *apiBindingWait) check(ctx context.Context, k8sClient client.Client, wsPath string, inst *corev1alpha1.PlatformMesh, log *logger.Logger) {
+	if w == nil || plan.IsPlanning(ctx) {
+		return
+	}
+	names := w.pending[wsPath]
+	delete(w.pending, wsPath)
+
+	for _, name := range names {
+		binding := &unstructured.Unstructured{}
+		binding.SetGroupVersionKind(kcpapiv1alpha.SchemeGroupVersion.WithKind("APIBinding"))
+		var ready bool
+		var message string
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, binding); err != nil {
+			message = err.Error()
+		} else {
+			ready, message = apiBindingReady(binding)
+		}
+		if ready {
+			continue
+		}
+		notReady := corev1alpha1.FailedAPIBinding{Workspace: wsPath, Name: name, Message: message}
+		created := binding.GetCreationTimestamp()
+		if created.IsZero() || w.now.Sub(created.Time) < w.timeout {
+			w.binding = append(w.binding, notReady)
+			continue
+		}
+		log.Warn().Str("workspace", wsPath).Str("apiBinding", name).Str("message", message).Msg("APIBinding did not become ready")
+		recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonAPIBindingNotReady, "WaitForAPIBinding",
+			"APIBinding %s in workspace %s did not become ready: %s", name, wsPath, message)
+		w.failed = append(w.failed, notReady)
+	}
+}
+
+// apiBindingReady reports whether binding is bound with all conditions True.
+// Otherwise it describes what is missing.
+func apiBindingReady(binding *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(binding.Object, "status", "phase")
+	conditions, _, _ := unstructured.NestedSlice(binding.Object, "status", "conditions")
+
+	var notTrue []string
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["status"] == string(metav1.ConditionTrue) {
+			continue
+		}
+		msg := fmt.Sprintf("%v=%v", cond["type"], cond["status"])
+		if m, _ := cond["message"].(string); m != "" {
+			msg += ": " + m
+		}
+		notTrue = append(notTrue, msg)
+	}
+	if len(notTrue) > 0 {
+		return false, strings.Join(notTrue, "; ")
+	}
+	if phase != apiBindingPhaseBound {
+		return false, fmt.Sprintf("phase is %q", phase)
+	}
+	return true, ""
+}
+
+// report stores the failed APIBindings in the status of inst and sets
+// APIBindingsReadyConditionType. It returns an *APIBindingsNotReadyError if
+// any binding failed or is still binding.
+func (w *apiBindingWait) report(inst *corev1alpha1.PlatformMesh) error {
+	if w == nil {
+		inst.Status.FailedAPIBindings = nil
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, APIBindingsReadyConditionType)
+		return nil
+	}
+	inst.Status.FailedAPIBindings = w.failed
+	if len(w.failed) == 0 && len(w.binding) == 0 {
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               APIBindingsReadyConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             apiBindingsReasonReady,
+			Message:            "All applied APIBindings are ready",
+			ObservedGeneration: inst.Generation,
+		})
+		return nil
+	}
+	notReady := &APIBindingsNotReadyError{Failed: w.failed, Binding: w.binding}
+	status, reason := metav1.ConditionFalse, apiBindingsReasonNotReady
+	if len(w.failed) == 0 {
+		status, reason = metav1.ConditionUnknown, apiBindingsReasonBinding
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               APIBindingsReadyConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            notReady.Error(),
+		ObservedGeneration: inst.Generation,
+	})
+	return notReady
+}
+
+// apiBindingsNotReady requeues the kcp setup for an APIBindingsNotReadyError
+// instead of failing the reconciliation, since the bindings may still become
+// ready.
+func apiBindingsNotReady(err error, requeue time.Duration) (subroutines.Result, bool) {
+	var notReady *APIBindingsNotReadyError
+	if !stderrors.As(err, &notReady) {
+		return subroutines.Result{}, false
+	}
+	return subroutines.StopWithRequeue(requeue, notReady.Error()), true
+}
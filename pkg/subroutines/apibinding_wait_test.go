@@ -0,0 +1,106 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func apiBinding(name, phase string, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(kcpapiv1alpha.SchemeGroupVersion.WithKind("APIBinding"))
+	obj.SetName(name)
+	conds := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		conds = append(conds, c)
+	}
+	obj.Object["status"] = map[string]interface{}{"phase": phase, "conditions": conds}
+	return obj
+}
+
+func TestAPIBindingReady(t *testing.T) {
+	ready, msg := apiBindingReady(apiBinding("b", "Bound", map[string]interface{}{"type": "Ready", "status": "True"}))
+	assert.True(t, ready)
+	assert.Empty(t, msg)
+
+	ready, msg = apiBindingReady(apiBinding("b", "Bound",
+		map[string]interface{}{"type": "Ready", "status": "False"},
+		map[string]interface{}{"type": "BindingUpToDate", "status": "False", "message": "conflicting schema for accounts"}))
+	assert.False(t, ready)
+	assert.Equal(t, "Ready=False; BindingUpToDate=False: conflicting schema for accounts", msg)
+
+	ready, msg = apiBindingReady(apiBinding("b", "Binding"))
+	assert.False(t, ready)
+	assert.Equal(t, `phase is "Binding"`, msg)
+}
+
+func TestAPIBindingWait(t *testing.T) {
+	now := time.Now()
+	created := func(obj *unstructured.Unstructured, at time.Time) *unstructured.Unstructured {
+		obj.SetCreationTimestamp(metav1.NewTime(at))
+		return obj
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, kcpapiv1alpha.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(
+		created(apiBinding("ready", "Bound", map[string]interface{}{"type": "BindingUpToDate", "status": "True"}), now.Add(-time.Hour)),
+		created(apiBinding("conflicting", "Bound", map[string]interface{}{"type": "BindingUpToDate", "status": "False", "message": "schema conflict"}), now.Add(-time.Hour)),
+		created(apiBinding("binding", "Binding"), now),
+	).Build()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	// Bindings are checked once: new ones are still binding, old ones failed.
+	ctx, w := withAPIBindingWait(context.Background(), time.Minute, now)
+	require.NotNil(t, w)
+	apiBindingWaitFrom(ctx).add("root:orgs", "ready")
+	apiBindingWaitFrom(ctx).add("root:orgs", "conflicting")
+	apiBindingWaitFrom(ctx).add("root:orgs", "conflicting")
+	apiBindingWaitFrom(ctx).add("root:orgs", "binding")
+	w.check(ctx, cl, "root:orgs", inst, log)
+
+	err = w.report(inst)
+	var notReady *APIBindingsNotReadyError
+	require.ErrorAs(t, err, &notReady)
+	assert.Equal(t, "2 APIBinding(s) not ready: conflicting in workspace root:orgs, binding in workspace root:orgs", err.Error())
+	assert.Equal(t, []corev1alpha1.FailedAPIBinding{
+		{Workspace: "root:orgs", Name: "conflicting", Message: "BindingUpToDate=False: schema conflict"},
+	}, inst.Status.FailedAPIBindings)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, APIBindingsReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+
+	res, ok := apiBindingsNotReady(err, DefaultRequeueInterval)
+	require.True(t, ok)
+	assert.True(t, res.IsStopWithRequeue())
+
+	// Bindings within their timeout only requeue.
+	ctx, w = withAPIBindingWait(context.Background(), time.Minute, now)
+	apiBindingWaitFrom(ctx).add("root:orgs", "binding")
+	w.check(ctx, cl, "root:orgs", inst, log)
+	require.ErrorAs(t, w.report(inst), &notReady)
+	assert.Empty(t, inst.Status.FailedAPIBindings)
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, APIBindingsReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionUnknown, cond.Status)
+	assert.Equal(t, apiBindingsReasonBinding, cond.Reason)
+
+	// Disabled checks clear the status.
+	_, w = withAPIBindingWait(context.Background(), 0, now)
+	require.NoError(t, w.report(inst))
+	assert.Empty(t, inst.Status.FailedAPIBindings)
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, APIBindingsReadyConditionType))
+}
@@ -0,0 +1,155 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	gcerrors "github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/bootstrap"
+)
+
+const (
+	BootstrapSubroutineName = "BootstrapSubroutine"
+	fieldManagerBootstrap   = "platform-mesh-bootstrap"
+)
+
+// BootstrapSubroutine installs the prerequisites selected in spec.bootstrap from
+// the manifests embedded in the operator when their CRDs are missing. Install
+// attempts of a component are at most once per minInterval, so a failing
+// install does not hammer the API server on every reconciliation.
+type BootstrapSubroutine struct {
+	// clientInfra installs the components and checks their CRDs, so the check
+	// sees what the install applied.
+	clientInfra client.Client
+	minInterval time.Duration
+
+	mu          sync.Mutex
+	lastAttempt map[string]time.Time
+	now         func() time.Time
+	requeue     *RequeueBackoff
+}
+
+func NewBootstrapSubroutine(clientInfra client.Client, cfg *config.OperatorConfig) *BootstrapSubroutine {
+	return &BootstrapSubroutine{
+		clientInfra: clientInfra,
+		minInterval: cfg.Subroutines.Bootstrap.MinInterval,
+		lastAttempt: map[string]time.Time{},
+		now:         time.Now,
+		requeue:     NewRequeueBackoff(BootstrapSubroutineName, cfg.Subroutines.Bootstrap.Requeue),
+	}
+}
+
+func (r *BootstrapSubroutine) GetName() string {
+	return BootstrapSubroutineName
+}
+
+func (r *BootstrapSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *BootstrapSubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *BootstrapSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		labelResult := "success"
+		if err != nil {
+			labelResult = "error"
+		}
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), labelResult).Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	if inst.Spec.Bootstrap == nil {
+		return subroutines.OK(), nil
+	}
+
+	// Flux goes first, the cert-manager manifests are Flux objects.
+	var components []bootstrap.Component
+	if inst.Spec.Bootstrap.Flux {
+		components = append(components, bootstrap.Flux)
+	}
+	if inst.Spec.Bootstrap.CertManager {
+		components = append(components, bootstrap.CertManager)
+	}
+
+	for _, component := range components {
+		installed, err := r.isInstalled(ctx, component)
+		if err != nil {
+			log.Error().Err(err).Str("component", component.Name).Msg("Failed to check bootstrap component")
+			return subroutines.OK(), err
+		}
+		if installed {
+			continue
+		}
+
+		if !r.allowAttempt(component.Name) {
+			log.Debug().Str("component", component.Name).Msg("Bootstrap install attempted recently, waiting")
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), fmt.Sprintf("Waiting for %s to be installed", component.Name)), nil
+		}
+
+		log.Info().Str("component", component.Name).Str("version", component.Version).Msg("Installing bootstrap component")
+		if err := r.install(ctx, component); err != nil {
+			log.Error().Err(err).Str("component", component.Name).Msg("Failed to install bootstrap component")
+			return subroutines.OK(), err
+		}
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), fmt.Sprintf("Installed %s %s, waiting for its CRDs", component.Name, component.Version)), nil
+	}
+
+	return subroutines.OK(), nil
+}
+
+// isInstalled reports whether all CRDs of the component are established on the
+// infra cluster it is installed into.
+func (r *BootstrapSubroutine) isInstalled(ctx context.Context, component bootstrap.Component) (bool, error) {
+	for _, crd := range component.CRDs {
+		established, err := isCRDEstablished(ctx, r.clientInfra, crd)
+		if err != nil || !established {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// allowAttempt records an install attempt of the component unless the previous
+// one happened less than minInterval ago.
+func (r *BootstrapSubroutine) allowAttempt(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if last, ok := r.lastAttempt[name]; ok && now.Sub(last) < r.minInterval {
+		return false
+	}
+	r.lastAttempt[name] = now
+	return true
+}
+
+func (r *BootstrapSubroutine) install(ctx context.Context, component bootstrap.Component) error {
+	objs, err := component.Objects()
+	if err != nil {
+		return err
+	}
+	for i := range objs {
+		obj := &objs[i]
+		err := r.clientInfra.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj),
+			client.FieldOwner(fieldManagerBootstrap), client.ForceOwnership)
+		if err != nil {
+			return gcerrors.Wrap(err, "Failed to apply %s %s/%s of %s", obj.GetKind(), obj.GetNamespace(), obj.GetName(), component.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/bootstrap"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+)
+
+type BootstrapTestSuite struct {
+	suite.Suite
+	infraMock *mocks.Client
+	testObj   *BootstrapSubroutine
+	ctx       context.Context
+	now       time.Time
+}
+
+func TestBootstrapTestSuite(t *testing.T) {
+	suite.Run(t, new(BootstrapTestSuite))
+}
+
+func (s *BootstrapTestSuite) SetupTest() {
+	s.infraMock = new(mocks.Client)
+
+	cfg := config.NewOperatorConfig()
+	s.testObj = NewBootstrapSubroutine(s.infraMock, &cfg)
+	s.now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.testObj.now = func() time.Time { return s.now }
+
+	logCfg := logger.DefaultConfig()
+	logCfg.Level = "debug"
+	logCfg.NoJSON = true
+	logCfg.Name = "BootstrapTestSuite"
+	log, _ := logger.New(logCfg)
+	s.ctx = context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+}
+
+func (s *BootstrapTestSuite) mockCRDs(established bool, names ...string) {
+	for _, name := range names {
+		s.infraMock.EXPECT().
+			Get(mock.Anything, types.NamespacedName{Name: name}, mock.AnythingOfType("*unstructured.Unstructured")).
+			RunAndReturn(func(ctx context.Context, nn types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+				if !established {
+					return nil
+				}
+				crd := obj.(*unstructured.Unstructured)
+				return unstructured.SetNestedSlice(crd.Object, []interface{}{
+					map[string]interface{}{"type": "Established", "status": "True"},
+				}, "status", "conditions")
+			})
+	}
+}
+
+func (s *BootstrapTestSuite) TestProcess_NoBootstrap() {
+	res, err := s.testObj.Process(s.ctx, &corev1alpha1.PlatformMesh{})
+
+	s.NoError(err)
+	s.True(res.IsContinue())
+	s.infraMock.AssertNotCalled(s.T(), "Get", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *BootstrapTestSuite) TestProcess_AlreadyInstalled() {
+	s.mockCRDs(true, bootstrap.Flux.CRDs...)
+	s.mockCRDs(true, bootstrap.CertManager.CRDs...)
+
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Bootstrap: &corev1alpha1.BootstrapConfig{Flux: true, CertManager: true},
+	}}
+	res, err := s.testObj.Process(s.ctx, inst)
+
+	s.NoError(err)
+	s.True(res.IsContinue())
+	s.infraMock.AssertNotCalled(s.T(), "Apply", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *BootstrapTestSuite) TestProcess_InstallsFluxRateLimited() {
+	s.mockCRDs(false, bootstrap.Flux.CRDs...)
+	objs, err := bootstrap.Flux.Objects()
+	s.Require().NoError(err)
+	s.infraMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(len(objs))
+
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Bootstrap: &corev1alpha1.BootstrapConfig{Flux: true, CertManager: true},
+	}}
+	res, err := s.testObj.Process(s.ctx, inst)
+	s.NoError(err)
+	s.True(res.IsStopWithRequeue())
+
+	// a second reconciliation within the interval does not reinstall
+	s.now = s.now.Add(time.Minute)
+	res, err = s.testObj.Process(s.ctx, inst)
+	s.NoError(err)
+	s.True(res.IsStopWithRequeue())
+	s.infraMock.AssertNumberOfCalls(s.T(), "Apply", len(objs))
+}
+
+func (s *BootstrapTestSuite) TestProcess_InstallsCertManagerAfterFlux() {
+	s.mockCRDs(true, bootstrap.Flux.CRDs...)
+	s.mockCRDs(false, bootstrap.CertManager.CRDs...)
+	objs, err := bootstrap.CertManager.Objects()
+	s.Require().NoError(err)
+	s.infraMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(len(objs))
+
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Bootstrap: &corev1alpha1.BootstrapConfig{Flux: true, CertManager: true},
+	}}
+	res, err := s.testObj.Process(s.ctx, inst)
+
+	s.NoError(err)
+	s.True(res.IsStopWithRequeue())
+}
+
+func (s *BootstrapTestSuite) TestProcess_ApplyError() {
+	s.mockCRDs(false, bootstrap.Flux.CRDs...)
+	s.infraMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Bootstrap: &corev1alpha1.BootstrapConfig{Flux: true},
+	}}
+	_, err := s.testObj.Process(s.ctx, inst)
+	s.Error(err)
+
+	// the next attempt is only made after the minimum interval
+	s.now = s.now.Add(11 * time.Minute)
+	s.infraMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+	_, err = s.testObj.Process(s.ctx, inst)
+	s.Error(err)
+}
+
+func (s *BootstrapTestSuite) TestProcess_CRDCheckError() {
+	s.infraMock.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("boom"))
+
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Bootstrap: &corev1alpha1.BootstrapConfig{CertManager: true},
+	}}
+	_, err := s.testObj.Process(s.ctx, inst)
+
+	s.Error(err)
+}
@@ -0,0 +1,177 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/decorate"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+// lastErrorMessageMaxLength is the length above which the message in
+// status.lastError is truncated.
+const lastErrorMessageMaxLength = 1024
+
+// ClassifiedError is an error of a subroutine with its category. Errors that
+// are not created as ClassifiedError are classified by ClassifyError.
+type ClassifiedError struct {
+	Category corev1alpha1.ErrorCategory
+	// Retryable is false for errors that persist until the spec, the profile
+	// or the operator configuration changes.
+	Retryable bool
+	Err       error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// newClassifiedError returns err with category, and nil for a nil err.
+// Configuration and template errors are not retryable.
+func newClassifiedError(category corev1alpha1.ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Category: category, Retryable: retryableCategory(category), Err: err}
+}
+
+func retryableCategory(category corev1alpha1.ErrorCategory) bool {
+	return category != corev1alpha1.ErrorCategoryConfig && category != corev1alpha1.ErrorCategoryTemplate
+}
+
+// ClassifyError returns err with its category. The category of an
+// ClassifiedError wrapped in err is kept, other errors are classified by their
+// cause: unreachable clusters, conflicting writes, missing objects and
+// failing templates.
+func ClassifyError(err error) *ClassifiedError {
+	var classified *ClassifiedError
+	if stderrors.As(err, &classified) {
+		return &ClassifiedError{Category: classified.Category, Retryable: classified.Retryable, Err: err}
+	}
+	category := errorCategory(err)
+	return &ClassifiedError{Category: category, Retryable: retryableCategory(category), Err: err}
+}
+
+func errorCategory(err error) corev1alpha1.ErrorCategory {
+	var unavailable *clusterUnavailableError
+	var claimed *SharedObjectClaimedError
+	var owned *SecretOwnedByOtherError
+	var execErr template.ExecError
+	switch {
+	case stderrors.As(err, &unavailable):
+		if strings.HasPrefix(unavailable.cluster, plan.KcpCluster("")) {
+			return corev1alpha1.ErrorCategoryKCPUnavailable
+		}
+		return corev1alpha1.ErrorCategoryDependencyNotReady
+	case isClusterUnavailable(err):
+		// Only kcp clients address a workspace below /clusters/.
+		var urlErr *url.Error
+		if stderrors.As(err, &urlErr) && strings.Contains(urlErr.URL, "/clusters/") {
+			return corev1alpha1.ErrorCategoryKCPUnavailable
+		}
+		return corev1alpha1.ErrorCategoryDependencyNotReady
+	case stderrors.As(err, &claimed), stderrors.As(err, &owned), kerrors.IsConflict(err), kerrors.IsAlreadyExists(err):
+		return corev1alpha1.ErrorCategoryApplyConflict
+	case kerrors.IsNotFound(err), apimeta.IsNoMatchError(err):
+		return corev1alpha1.ErrorCategoryDependencyNotReady
+	case stderrors.As(err, &execErr):
+		return corev1alpha1.ErrorCategoryTemplate
+	case kerrors.IsInvalid(err), kerrors.IsBadRequest(err):
+		return corev1alpha1.ErrorCategoryConfig
+	}
+	return corev1alpha1.ErrorCategoryUnknown
+}
+
+// setLastError records err returned by subroutine in status.lastError of
+// inst. Since is kept while the subroutine returns the same error.
+func setLastError(inst *corev1alpha1.PlatformMesh, subroutine string, err error, now time.Time) {
+	classified := ClassifyError(err)
+	last := &corev1alpha1.LastErrorStatus{
+		Category:           classified.Category,
+		Message:            truncateMessage(err.Error()),
+		Subroutine:         subroutine,
+		Retryable:          classified.Retryable,
+		Since:              metav1.NewTime(now),
+		ObservedGeneration: inst.Generation,
+	}
+	if prev := inst.Status.LastError; prev != nil && prev.Subroutine == subroutine && prev.Category == last.Category && prev.Message == last.Message {
+		last.Since = prev.Since
+	}
+	inst.Status.LastError = last
+}
+
+// truncateMessage returns message cut to lastErrorMessageMaxLength.
+func truncateMessage(message string) string {
+	if len(message) > lastErrorMessageMaxLength {
+		return message[:lastErrorMessageMaxLength] + "..."
+	}
+	return message
+}
+
+// clearLastError removes status.lastError of inst if it was returned by
+// subroutine, leaving the errors of other subroutines intact.
+func clearLastError(inst *corev1alpha1.PlatformMesh, subroutine string) {
+	if inst.Status.LastError != nil && inst.Status.LastError.Subroutine == subroutine {
+		inst.Status.LastError = nil
+	}
+}
+
+// WithLastError returns the given subroutines reporting the errors they
+// return on a PlatformMesh in status.lastError, classified by ClassifyError.
+// The error is removed once its subroutine completes without error.
+func WithLastError(subs ...subroutines.Subroutine) []subroutines.Subroutine {
+	return decorate.Wrap(subs, LastErrorHook{})
+}
+
+// LastErrorHook records the errors of the subroutines it runs around in
+// status.lastError, see WithLastError.
+type LastErrorHook struct{}
+
+// Before does nothing, see decorate.Hook.
+func (LastErrorHook) Before(ctx context.Context, _ subroutines.Subroutine, _ client.Object) context.Context {
+	return ctx
+}
+
+// After counts err of sub by category and records it in status.lastError of
+// obj, see decorate.Hook. A result that waits records its message as a
+// DependencyNotReady error, so a stopped reconcile does not clear the reason it
+// stopped; a completed one removes the error sub recorded before. Other
+// objects than a PlatformMesh, such as the OCM Resources the operator only
+// reads, get their errors as warning events instead.
+func (LastErrorHook) After(ctx context.Context, sub subroutines.Subroutine, obj client.Object, res subroutines.Result, err error) {
+	if err != nil {
+		classified := ClassifyError(err)
+		metrics.SubroutineErrorsTotal.WithLabelValues(sub.GetName(), string(classified.Category)).Inc()
+		if _, ok := obj.(*corev1alpha1.PlatformMesh); !ok {
+			recordEvent(ctx, obj, corev1.EventTypeWarning, EventReasonSubroutineFailed, "Reconcile",
+				"%s failed with %s: %s", sub.GetName(), classified.Category, truncateMessage(err.Error()))
+		}
+	}
+	inst, ok := obj.(*corev1alpha1.PlatformMesh)
+	switch {
+	case !ok:
+	case err != nil:
+		setLastError(inst, sub.GetName(), err, time.Now())
+	case (res.IsStopWithRequeue() || res.IsPending()) && res.Message() != "":
+		setLastError(inst, sub.GetName(), newClassifiedError(corev1alpha1.ErrorCategoryDependencyNotReady, stderrors.New(res.Message())), time.Now())
+	default:
+		clearLastError(inst, sub.GetName())
+	}
+}
@@ -0,0 +1,135 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+func TestClassifyError(t *testing.T) {
+	gr := schema.GroupResource{Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"}
+	refused := &url.Error{Op: "Get", URL: "https://kcp.example.com/clusters/root:orgs/api", Err: syscall.ECONNREFUSED}
+	execErr := template.Must(template.New("t").Option("missingkey=error").Parse("{{ .missing }}")).Execute(io.Discard, map[string]interface{}{})
+	for name, tc := range map[string]struct {
+		err       error
+		category  corev1alpha1.ErrorCategory
+		retryable bool
+	}{
+		"tagged":            {errors.Wrap(newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("bad")), "Failed"), corev1alpha1.ErrorCategoryConfig, false},
+		"infra unavailable": {&clusterUnavailableError{cluster: plan.ClusterInfra, err: syscall.ECONNREFUSED}, corev1alpha1.ErrorCategoryDependencyNotReady, true},
+		"kcp unavailable":   {errors.Wrap(refused, "Failed to get workspace"), corev1alpha1.ErrorCategoryKCPUnavailable, true},
+		"kcp cluster":       {&clusterUnavailableError{cluster: plan.KcpCluster("root"), err: syscall.ECONNREFUSED}, corev1alpha1.ErrorCategoryKCPUnavailable, true},
+		"conflict":          {kerrors.NewConflict(gr, "portal", stderrors.New("modified")), corev1alpha1.ErrorCategoryApplyConflict, true},
+		"claimed":           {&SharedObjectClaimedError{Kind: "APIExport", Name: "core"}, corev1alpha1.ErrorCategoryApplyConflict, true},
+		"not found":         {kerrors.NewNotFound(gr, "portal"), corev1alpha1.ErrorCategoryDependencyNotReady, true},
+		"template":          {execErr, corev1alpha1.ErrorCategoryTemplate, false},
+		"invalid":           {kerrors.NewBadRequest("spec.values is invalid"), corev1alpha1.ErrorCategoryConfig, false},
+		"unknown":           {stderrors.New("boom"), corev1alpha1.ErrorCategoryUnknown, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			classified := ClassifyError(tc.err)
+			assert.Equal(t, tc.category, classified.Category)
+			assert.Equal(t, tc.retryable, classified.Retryable)
+			assert.Equal(t, tc.err.Error(), classified.Error())
+		})
+	}
+	assert.NoError(t, newClassifiedError(corev1alpha1.ErrorCategoryConfig, nil))
+}
+
+type failingSubroutine struct {
+	name string
+	res  subroutines.Result
+	err  error
+}
+
+func (s *failingSubroutine) GetName() string { return s.name }
+
+func (s *failingSubroutine) Process(context.Context, client.Object) (subroutines.Result, error) {
+	return s.res, s.err
+}
+
+func TestWithLastError(t *testing.T) {
+	sub := &failingSubroutine{name: "FailingSubroutine", res: subroutines.OK(), err: newClassifiedError(corev1alpha1.ErrorCategoryTemplate, errors.New("Failed to execute template"))}
+	subs := WithLastError(sub)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "FailingSubroutine", subs[0].GetName())
+	p := subs[0].(subroutines.Processor)
+	inst := &corev1alpha1.PlatformMesh{}
+	inst.Generation = 3
+
+	_, err := p.Process(context.Background(), inst)
+	require.Error(t, err)
+	last := inst.Status.LastError
+	require.NotNil(t, last)
+	assert.Equal(t, corev1alpha1.ErrorCategoryTemplate, last.Category)
+	assert.Equal(t, "FailingSubroutine", last.Subroutine)
+	assert.Equal(t, "Failed to execute template", last.Message)
+	assert.False(t, last.Retryable)
+	assert.Equal(t, int64(3), last.ObservedGeneration)
+
+	// The same error keeps its first occurrence.
+	since := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	inst.Status.LastError.Since = since
+	_, _ = p.Process(context.Background(), inst)
+	assert.Equal(t, since, inst.Status.LastError.Since)
+
+	// Another subroutine completing leaves the error alone, its own success
+	// removes it.
+	_, err = WithLastError(&failingSubroutine{name: "OtherSubroutine", res: subroutines.OK()})[0].(subroutines.Processor).Process(context.Background(), inst)
+	require.NoError(t, err)
+	assert.NotNil(t, inst.Status.LastError)
+	// A result that waits records its message instead of clearing the error.
+	sub.err = nil
+	sub.res = subroutines.StopWithRequeue(time.Minute, "waiting for the deployment controller")
+	_, err = p.Process(context.Background(), inst)
+	require.NoError(t, err)
+	last = inst.Status.LastError
+	require.NotNil(t, last)
+	assert.Equal(t, corev1alpha1.ErrorCategoryDependencyNotReady, last.Category)
+	assert.Equal(t, "waiting for the deployment controller", last.Message)
+	assert.True(t, last.Retryable)
+
+	sub.res = subroutines.OK()
+	_, err = p.Process(context.Background(), inst)
+	require.NoError(t, err)
+	assert.Nil(t, inst.Status.LastError)
+	_, ok := subs[0].(subroutines.Finalizer)
+	assert.False(t, ok)
+}
+
+func TestWithLastErrorOnOtherObjects(t *testing.T) {
+	rec := events.NewFakeRecorder(1)
+	ctx := WithEventRecorder(context.Background(), rec)
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("delivery.ocm.software/v1alpha1")
+	resource.SetKind("Resource")
+	resource.SetName("iam")
+
+	// Objects without a status the operator writes get their classified
+	// errors as events.
+	p := WithLastError(&failingSubroutine{name: "ResourceSubroutine", err: kerrors.NewNotFound(schema.GroupResource{Resource: "helmreleases"}, "iam")})[0].(subroutines.Processor)
+	_, err := p.Process(ctx, resource)
+	require.Error(t, err)
+	event := <-rec.Events
+	assert.Contains(t, event, EventReasonSubroutineFailed)
+	assert.Contains(t, event, "ResourceSubroutine failed with DependencyNotReady")
+}
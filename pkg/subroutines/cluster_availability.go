@@ -0,0 +1,161 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+const (
+	RuntimeClusterAvailableConditionType = "RuntimeClusterAvailable"
+	InfraClusterAvailableConditionType   = "InfraClusterAvailable"
+)
+
+// clusterUnavailableError is the error of a request a cluster did not answer.
+type clusterUnavailableError struct {
+	cluster string
+	err     error
+}
+
+func (e *clusterUnavailableError) Error() string {
+	return fmt.Sprintf("%s cluster unavailable: %v", e.cluster, e.err)
+}
+
+func (e *clusterUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// isClusterUnavailable reports whether err means the API server could not be
+// reached or did not answer in time, as opposed to rejecting the request.
+func isClusterUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if kerrors.IsServiceUnavailable(err) || kerrors.IsTimeout(err) || kerrors.IsServerTimeout(err) {
+		return true
+	}
+	if utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+	var netErr net.Error
+	return stderrors.As(err, &netErr) && netErr.Timeout()
+}
+
+// clusterClient marks the errors of requests its cluster did not answer, so
+// callers can tell which cluster failed, see clusterAvailability.
+type clusterClient struct {
+	client.Client
+	cluster string
+}
+
+// withClusterErrors returns c marking errors of an unavailable cluster with
+// cluster.
+func withClusterErrors(cluster string, c client.Client) client.Client {
+	if c == nil {
+		return nil
+	}
+	return &clusterClient{Client: c, cluster: cluster}
+}
+
+func (c *clusterClient) mark(err error) error {
+	if isClusterUnavailable(err) {
+		return &clusterUnavailableError{cluster: c.cluster, err: err}
+	}
+	return err
+}
+
+func (c *clusterClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.mark(c.Client.Get(ctx, key, obj, opts...))
+}
+
+func (c *clusterClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.mark(c.Client.List(ctx, list, opts...))
+}
+
+func (c *clusterClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.mark(c.Client.Create(ctx, obj, opts...))
+}
+
+func (c *clusterClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.mark(c.Client.Update(ctx, obj, opts...))
+}
+
+func (c *clusterClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.mark(c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func (c *clusterClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	return c.mark(c.Client.Apply(ctx, obj, opts...))
+}
+
+func (c *clusterClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.mark(c.Client.Delete(ctx, obj, opts...))
+}
+
+// clusterAvailability collects the clusters a reconcile could not reach, by
+// the first error of each.
+type clusterAvailability map[string]error
+
+// check records the cluster err failed to reach and reports whether err is
+// such a failure.
+func (a clusterAvailability) check(err error) bool {
+	var unavailable *clusterUnavailableError
+	if !stderrors.As(err, &unavailable) {
+		return false
+	}
+	if _, ok := a[unavailable.cluster]; !ok {
+		a[unavailable.cluster] = unavailable.err
+	}
+	return true
+}
+
+func (a clusterAvailability) available(cluster string) bool {
+	_, unavailable := a[cluster]
+	return !unavailable
+}
+
+// message describes the unavailable clusters.
+func (a clusterAvailability) message() string {
+	var parts []string
+	for cluster, err := range a {
+		parts = append(parts, fmt.Sprintf("%s cluster unavailable: %v", cluster, err))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// setConditions sets the availability condition of the runtime and the infra
+// cluster on inst.
+func (a clusterAvailability) setConditions(inst *corev1alpha1.PlatformMesh) {
+	for cluster, condType := range map[string]string{
+		plan.ClusterRuntime: RuntimeClusterAvailableConditionType,
+		plan.ClusterInfra:   InfraClusterAvailableConditionType,
+	} {
+		cond := metav1.Condition{
+			Type:               condType,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Available",
+			Message:            fmt.Sprintf("The %s cluster answered all requests", cluster),
+			ObservedGeneration: inst.Generation,
+		}
+		if err, ok := a[cluster]; ok {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "Unavailable"
+			cond.Message = err.Error()
+		}
+		apimeta.SetStatusCondition(&inst.Status.Conditions, cond)
+	}
+}
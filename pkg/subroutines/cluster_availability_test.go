@@ -0,0 +1,42 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsClusterUnavailable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	secrets := schema.GroupResource{Resource: "secrets"}
+
+	assert.True(t, isClusterUnavailable(refused))
+	assert.True(t, isClusterUnavailable(errors.Wrap(refused, "Failed to get secret")))
+	assert.True(t, isClusterUnavailable(kerrors.NewServiceUnavailable("etcd is down")))
+	assert.True(t, isClusterUnavailable(kerrors.NewTimeoutError("slow", 1)))
+	assert.True(t, isClusterUnavailable(fmt.Errorf("get: %w", context.DeadlineExceeded)))
+
+	assert.False(t, isClusterUnavailable(nil))
+	assert.False(t, isClusterUnavailable(kerrors.NewNotFound(secrets, "missing")))
+	assert.False(t, isClusterUnavailable(kerrors.NewForbidden(secrets, "denied", fmt.Errorf("no"))))
+}
+
+func TestClusterAvailability(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	infra := &clusterClient{cluster: "infra"}
+
+	clusters := clusterAvailability{}
+	assert.False(t, clusters.check(fmt.Errorf("invalid manifest")))
+	assert.True(t, clusters.check(errors.Wrap(infra.mark(refused), "Failed to apply manifest")))
+	assert.False(t, clusters.available("infra"))
+	assert.True(t, clusters.available("runtime"))
+	assert.Contains(t, clusters.message(), "infra cluster unavailable: dial tcp: connect: connection refused")
+}
@@ -0,0 +1,254 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// PreDeleteFailurePolicyAbort keeps the HelmRelease of a component whose
+	// pre-delete hook failed or timed out.
+	PreDeleteFailurePolicyAbort = "Abort"
+	// PreDeleteFailurePolicyContinue prunes the HelmRelease of a component
+	// even if its pre-delete hook failed or timed out.
+	PreDeleteFailurePolicyContinue = "Continue"
+
+	// PreDeleteHookLabel holds the component a pre-delete hook Job runs for.
+	PreDeleteHookLabel = "core.platform-mesh.io/pre-delete-hook"
+
+	preDeleteKey            = "preDelete"
+	preDeleteJobSuffix      = "-pre-delete"
+	defaultPreDeleteTimeout = 10 * time.Minute
+)
+
+var helmReleaseGVK = schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"}
+
+// preDeleteHook is the preDelete entry of a service in the components
+// profile, a Job run before the HelmRelease of the disabled service is pruned.
+type preDeleteHook struct {
+	// Timeout is how long the Job may run, defaults to ten minutes.
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy is Abort or Continue, defaults to Abort.
+	FailurePolicy string          `json:"failurePolicy,omitempty"`
+	Job           batchv1.JobSpec `json:"job"`
+}
+
+// parsePreDeleteHook returns the preDelete entry of the service name with
+// config, or nil if it has none.
+func parsePreDeleteHook(name string, config map[string]interface{}) (*preDeleteHook, error) {
+	entry, ok := config[preDeleteKey]
+	if !ok || entry == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal preDelete of service %s", name)
+	}
+	hook := &preDeleteHook{}
+	if err := json.Unmarshal(raw, hook); err != nil {
+		return nil, errors.Wrap(err, "Invalid preDelete of service %s", name)
+	}
+	if hook.Timeout.Duration <= 0 {
+		hook.Timeout.Duration = defaultPreDeleteTimeout
+	}
+	switch hook.FailurePolicy {
+	case "":
+		hook.FailurePolicy = PreDeleteFailurePolicyAbort
+	case PreDeleteFailurePolicyAbort, PreDeleteFailurePolicyContinue:
+	default:
+		return nil, fmt.Errorf("invalid preDelete failurePolicy %q of service %s, must be %s or %s",
+			hook.FailurePolicy, name, PreDeleteFailurePolicyAbort, PreDeleteFailurePolicyContinue)
+	}
+	if len(hook.Job.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("preDelete of service %s has no job containers", name)
+	}
+	return hook, nil
+}
+
+// pruneDisabledComponents deletes the HelmReleases the operator created for
+// services that are disabled in the components profile, and then the objects
+// of their runtime templates. Without PruneDisabledComponents only services
+// switched off in spec.components are pruned. A service with a preDelete hook
+// has its hook Job run to completion on the runtime cluster first. The
+// returned message names the services still waiting for their hooks and is
+// empty once nothing is left to prune.
+func (r *DeploymentSubroutine) pruneDisabledComponents(ctx context.Context, inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON) (string, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	tmplVars, err := r.buildComponentsTemplateVars(ctx, inst, templateVars)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to build components template vars")
+	}
+	releaseNamespace, _ := tmplVars["releaseNamespace"].(string)
+	services := templateServices(tmplVars)
+
+	var waiting []string
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		config, _ := services[name].(map[string]interface{})
+		if enabled, ok := config["enabled"].(bool); !ok || enabled {
+			continue
+		}
+		if !r.cfgOperator.Subroutines.Deployment.PruneDisabledComponents && !componentSwitchedOff(inst, name) {
+			continue
+		}
+		done, err := r.pruneComponent(ctx, inst, name, releaseNamespace, config, log)
+		if err != nil {
+			return "", err
+		}
+		if !done {
+			waiting = append(waiting, name)
+			continue
+		}
+		if err := r.pruneComponentResources(ctx, inst, tmplVars, name, log); err != nil {
+			return "", err
+		}
+	}
+	if len(waiting) > 0 {
+		return fmt.Sprintf("Waiting for the pre-delete hooks of %s", strings.Join(waiting, ", ")), nil
+	}
+	return "", nil
+}
+
+// pruneComponent deletes the HelmRelease of the disabled service name once
+// its preDelete hook, if any, is done. It reports whether the service is
+// pruned.
+func (r *DeploymentSubroutine) pruneComponent(ctx context.Context, inst *v1alpha1.PlatformMesh, name, releaseNamespace string, config map[string]interface{}, log *logger.Logger) (bool, error) {
+	key := types.NamespacedName{Name: name, Namespace: releaseNamespace}
+	if adopted, ok := adoptedRelease(inst, name); ok {
+		key = adopted
+	}
+	release := &unstructured.Unstructured{}
+	release.SetGroupVersionKind(helmReleaseGVK)
+	err := r.clientInfra.Get(ctx, key, release)
+	if kerrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get HelmRelease %s", key)
+	}
+	// Only HelmReleases the operator created for this instance are pruned.
+	if release.GetLabels()[operatorCreatedLabel] != "true" {
+		return true, nil
+	}
+	if owner := labeledInstance(release); owner != "" && owner != instanceKey(inst) {
+		return true, nil
+	}
+	if !release.GetDeletionTimestamp().IsZero() {
+		return true, nil
+	}
+
+	hook, err := parsePreDeleteHook(name, config)
+	if err != nil {
+		return false, err
+	}
+	var job *batchv1.Job
+	if hook != nil {
+		namespace, _ := config["targetNamespace"].(string)
+		if namespace == "" {
+			namespace = releaseNamespace
+		}
+		var done bool
+		job, done, err = r.runPreDeleteHook(ctx, inst, name, namespace, hook, log)
+		if err != nil || !done {
+			return false, err
+		}
+	}
+
+	if err := client.IgnoreNotFound(r.clientInfra.Delete(ctx, release)); err != nil {
+		return false, errors.Wrap(err, "Failed to delete HelmRelease %s", key)
+	}
+	log.Info().Str("component", name).Msg("Pruned HelmRelease of disabled component")
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonComponentPruned, "Prune",
+		"Pruned HelmRelease %s of disabled component %s", key, name)
+
+	if job != nil {
+		if err := client.IgnoreNotFound(r.clientRuntime.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))); err != nil {
+			return false, errors.Wrap(err, "Failed to delete pre-delete hook Job %s/%s", job.Namespace, job.Name)
+		}
+	}
+	return true, nil
+}
+
+// runPreDeleteHook creates the pre-delete hook Job of the service name in
+// namespace unless it exists and reports whether it is done. A failed or
+// timed out Job is done with the Continue failure policy and an error with
+// Abort, until the Job is deleted to run it again.
+func (r *DeploymentSubroutine) runPreDeleteHook(ctx context.Context, inst *v1alpha1.PlatformMesh, name, namespace string, hook *preDeleteHook, log *logger.Logger) (*batchv1.Job, bool, error) {
+	job := &batchv1.Job{}
+	key := types.NamespacedName{Name: name + preDeleteJobSuffix, Namespace: namespace}
+	err := r.clientRuntime.Get(ctx, key, job)
+	if kerrors.IsNotFound(err) {
+		job = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{operatorCreatedLabel: "true", PreDeleteHookLabel: name},
+			},
+			Spec: *hook.Job.DeepCopy(),
+		}
+		setInstanceLabels(job, inst)
+		if job.Spec.ActiveDeadlineSeconds == nil {
+			deadline := int64(hook.Timeout.Seconds())
+			job.Spec.ActiveDeadlineSeconds = &deadline
+		}
+		if err := r.clientRuntime.Create(ctx, job); err != nil {
+			return nil, false, errors.Wrap(err, "Failed to create pre-delete hook Job %s", key)
+		}
+		log.Info().Str("component", name).Str("job", key.String()).Msg("Started pre-delete hook")
+		recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonPreDeleteHookStarted, "PreDelete",
+			"Started pre-delete hook Job %s of disabled component %s", key, name)
+		return job, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "Failed to get pre-delete hook Job %s", key)
+	}
+
+	var outcome string
+	switch {
+	case jobHasCondition(job, batchv1.JobComplete):
+		return job, true, nil
+	case jobHasCondition(job, batchv1.JobFailed):
+		outcome = "failed"
+	case !job.CreationTimestamp.IsZero() && time.Since(job.CreationTimestamp.Time) > hook.Timeout.Duration:
+		outcome = "timed out"
+	default:
+		return job, false, nil
+	}
+
+	if hook.FailurePolicy == PreDeleteFailurePolicyContinue {
+		log.Warn().Str("component", name).Str("job", key.String()).Msgf("Pre-delete hook %s, pruning anyway", outcome)
+		recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonPreDeleteHookFailed, "PreDelete",
+			"Pre-delete hook Job %s of component %s %s, pruning it anyway", key, name, outcome)
+		return job, true, nil
+	}
+	return job, false, fmt.Errorf("pre-delete hook Job %s of component %s %s, delete the Job to run it again or set the failurePolicy to %s",
+		key, name, outcome, PreDeleteFailurePolicyContinue)
+}
+
+func jobHasCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == conditionType && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const pruneProfileYAML = `
+infra: {}
+components:
+  services:
+    iam:
+      enabled: true
+    portal:
+      enabled: false
+    openfga:
+      enabled: false
+      targetNamespace: openfga
+      preDelete:
+        timeout: 5m
+        job:
+          template:
+            spec:
+              restartPolicy: Never
+              containers:
+              - name: backup
+                image: backup:1.0.0
+`
+
+func newPruneHelmRelease(name string, labels map[string]string) *unstructured.Unstructured {
+	release := &unstructured.Unstructured{}
+	release.SetGroupVersionKind(helmReleaseGVK)
+	release.SetName(name)
+	release.SetNamespace("platform-mesh-system")
+	release.SetLabels(labels)
+	return release
+}
+
+func newPruneTest(t *testing.T) (context.Context, *events.FakeRecorder, client.Client, *DeploymentSubroutine, *v1alpha1.PlatformMesh) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"}}
+	profile := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-profile", Namespace: inst.Namespace},
+		Data:       map[string]string{profileConfigMapKey: pruneProfileYAML},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(inst, profile).Build()
+
+	created := map[string]string{operatorCreatedLabel: "true"}
+	for _, release := range []*unstructured.Unstructured{
+		newPruneHelmRelease("iam", created),
+		newPruneHelmRelease("portal", created),
+		newPruneHelmRelease("openfga", created),
+	} {
+		require.NoError(t, cl.Create(ctx, release))
+	}
+
+	cfg := &config.OperatorConfig{}
+	cfg.Subroutines.Deployment.PruneDisabledComponents = true
+	componentsDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(componentsDir, "runtime"), 0o755))
+	sub := &DeploymentSubroutine{clientRuntime: cl, clientInfra: cl, cfgOperator: cfg, gotemplatesComponentsDir: componentsDir}
+	return ctx, rec, cl, sub, inst
+}
+
+func TestPruneDisabledComponents(t *testing.T) {
+	ctx, rec, cl, sub, inst := newPruneTest(t)
+
+	msg, err := sub.pruneDisabledComponents(ctx, inst, apiextensionsv1.JSON{})
+	require.NoError(t, err)
+	assert.Equal(t, "Waiting for the pre-delete hooks of openfga", msg)
+	assert.Equal(t, "Normal PreDeleteHookStarted Started pre-delete hook Job openfga/openfga-pre-delete of disabled component openfga", <-rec.Events)
+	assert.Equal(t, "Normal ComponentPruned Pruned HelmRelease platform-mesh-system/portal of disabled component portal", <-rec.Events)
+
+	// Enabled components and components waiting for their hook are kept.
+	assert.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "iam"}, newPruneHelmRelease("iam", nil)))
+	assert.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "openfga"}, newPruneHelmRelease("openfga", nil)))
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "portal"}, newPruneHelmRelease("portal", nil))))
+
+	job := &batchv1.Job{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "openfga", Name: "openfga-pre-delete"}, job))
+	assert.Equal(t, "openfga", job.Labels[PreDeleteHookLabel])
+	assert.Equal(t, "platform-mesh", job.Labels[InstanceNameLabel])
+	require.NotNil(t, job.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, int64(300), *job.Spec.ActiveDeadlineSeconds)
+
+	// A running hook keeps the HelmRelease.
+	msg, err = sub.pruneDisabledComponents(ctx, inst, apiextensionsv1.JSON{})
+	require.NoError(t, err)
+	assert.Equal(t, "Waiting for the pre-delete hooks of openfga", msg)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	require.NoError(t, cl.Update(ctx, job))
+	msg, err = sub.pruneDisabledComponents(ctx, inst, apiextensionsv1.JSON{})
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+	assert.Equal(t, "Normal ComponentPruned Pruned HelmRelease platform-mesh-system/openfga of disabled component openfga", <-rec.Events)
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "openfga"}, newPruneHelmRelease("openfga", nil))))
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Namespace: "openfga", Name: "openfga-pre-delete"}, &batchv1.Job{})))
+}
+
+func TestRunPreDeleteHook_FailurePolicy(t *testing.T) {
+	ctx, rec, cl, sub, inst := newPruneTest(t)
+	log := logger.LoadLoggerFromContext(ctx)
+	hook, err := parsePreDeleteHook("openfga", map[string]interface{}{"preDelete": map[string]interface{}{
+		"job": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "backup", "image": "backup:1.0.0"}},
+		}}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, PreDeleteFailurePolicyAbort, hook.FailurePolicy)
+	assert.Equal(t, defaultPreDeleteTimeout, hook.Timeout.Duration)
+
+	_, done, err := sub.runPreDeleteHook(ctx, inst, "openfga", "openfga", hook, log)
+	require.NoError(t, err)
+	assert.False(t, done)
+	<-rec.Events
+
+	job := &batchv1.Job{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "openfga", Name: "openfga-pre-delete"}, job))
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	require.NoError(t, cl.Update(ctx, job))
+
+	_, done, err = sub.runPreDeleteHook(ctx, inst, "openfga", "openfga", hook, log)
+	assert.ErrorContains(t, err, "pre-delete hook Job openfga/openfga-pre-delete of component openfga failed")
+	assert.False(t, done)
+
+	hook.FailurePolicy = PreDeleteFailurePolicyContinue
+	_, done, err = sub.runPreDeleteHook(ctx, inst, "openfga", "openfga", hook, log)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, "Warning PreDeleteHookFailed Pre-delete hook Job openfga/openfga-pre-delete of component openfga failed, pruning it anyway", <-rec.Events)
+
+	// A Job running past the timeout counts as failed.
+	job.Status.Conditions = nil
+	job.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	require.NoError(t, cl.Update(ctx, job))
+	hook.FailurePolicy = PreDeleteFailurePolicyAbort
+	_, _, err = sub.runPreDeleteHook(ctx, inst, "openfga", "openfga", hook, log)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestParsePreDeleteHook(t *testing.T) {
+	hook, err := parsePreDeleteHook("portal", map[string]interface{}{"enabled": false})
+	require.NoError(t, err)
+	assert.Nil(t, hook)
+
+	_, err = parsePreDeleteHook("openfga", map[string]interface{}{"preDelete": map[string]interface{}{"failurePolicy": "Retry"}})
+	assert.ErrorContains(t, err, `invalid preDelete failurePolicy "Retry" of service openfga`)
+
+	_, err = parsePreDeleteHook("openfga", map[string]interface{}{"preDelete": map[string]interface{}{"timeout": "1m"}})
+	assert.ErrorContains(t, err, "preDelete of service openfga has no job containers")
+}
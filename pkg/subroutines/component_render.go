@@ -0,0 +1,156 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// ComponentsRenderedConditionType is False while templates of at least one
+// profile component fail to render. The failing components are listed in
+// status.componentRenderErrors.
+const ComponentsRenderedConditionType = "ComponentsRendered"
+
+// renderAndApplyComponentTemplates renders the templates in dir once per
+// component, so a component whose templates fail to render, e.g. because it
+// lacks an optional value, does not keep the other components from being
+// applied. Render errors are recorded in the status of inst. Applied objects
+// are recorded in inv, which keeps the previous objects of components that
+// failed to render and releases those of disabled components. It returns the
+// number of applied manifests.
+func (r *DeploymentSubroutine) renderAndApplyComponentTemplates(
+	ctx context.Context,
+	inst *corev1alpha1.PlatformMesh,
+	dir string,
+	tmplVars map[string]interface{},
+	k8sClient client.Client,
+	claims *SharedObjectClaims,
+	inv *inventory,
+	log *logger.Logger,
+	templateType string,
+	skipFile func(fileName string) bool,
+	postProcessObj func(ctx context.Context, obj *unstructured.Unstructured) error,
+) (int, error) {
+	manifests, renderErrs := r.renderComponentTemplatesDir(ctx, dir, tmplVars, k8sClient, log, templateType, skipFile, postProcessObj)
+	recordComponentRenderErrors(inst, templateType, renderErrs)
+	for _, e := range renderErrs {
+		inv.keep(e.Component)
+	}
+	for name, config := range templateServices(tmplVars) {
+		c, _ := config.(map[string]interface{})
+		if enabled, ok := c["enabled"].(bool); ok && !enabled {
+			inv.release(name)
+		}
+	}
+
+	applied, err := r.validateAndApply(ctx, manifests, templateType, inv.recording(applyClaimed(k8sClient, claims)))
+	if err != nil {
+		log.Error().Err(err).Str("type", templateType).Msg("Failed to render and apply templates")
+		return applied, err
+	}
+	return applied, nil
+}
+
+// renderComponentTemplatesDir renders dir for each service of the components
+// profile with only that service in values.services, passing the service to
+// postProcessObj with withRenderedComponent. The manifests of services that
+// fail to render are dropped and reported instead.
+func (r *DeploymentSubroutine) renderComponentTemplatesDir(
+	ctx context.Context,
+	dir string,
+	tmplVars map[string]interface{},
+	lookup client.Client,
+	log *logger.Logger,
+	templateType string,
+	skipFile func(fileName string) bool,
+	postProcessObj func(ctx context.Context, obj *unstructured.Unstructured) error,
+) ([]renderedManifest, []corev1alpha1.ComponentRenderError) {
+	field, _, _ := unstructured.NestedFieldNoCopy(tmplVars, "values", "services")
+	services, _ := field.(map[string]interface{})
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// With an upgrade order the components are rendered wave by wave, so the
+	// upgrades of earlier waves are seen before the later ones are checked.
+	upgradeOrderFrom(ctx).sort(names)
+
+	var manifests []renderedManifest
+	var renderErrs []corev1alpha1.ComponentRenderError
+	for _, name := range names {
+		rendered, err := r.renderTemplatesDir(withRenderedComponent(ctx, name), dir, componentTemplateVars(tmplVars, name), lookup, log, skipFile, postProcessObj)
+		if err != nil {
+			log.Warn().Err(err).Str("component", name).Str("type", templateType).Msg("Skipping component whose templates failed to render")
+			renderErrs = append(renderErrs, corev1alpha1.ComponentRenderError{Component: name, Templates: templateType, Message: err.Error()})
+			continue
+		}
+		for _, m := range rendered {
+			m.component = name
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests, renderErrs
+}
+
+// componentTemplateVars returns tmplVars with values.services reduced to the
+// service name. Other values are shared, not copied.
+func componentTemplateVars(tmplVars map[string]interface{}, name string) map[string]interface{} {
+	values := maps.Clone(tmplVars["values"].(map[string]interface{}))
+	services := values["services"].(map[string]interface{})
+	values["services"] = map[string]interface{}{name: services[name]}
+	out := maps.Clone(tmplVars)
+	out["values"] = values
+	return out
+}
+
+// recordComponentRenderErrors replaces the render errors of templateType in
+// the status of inst with renderErrs and updates the ComponentsRendered
+// condition from all recorded errors.
+func recordComponentRenderErrors(inst *corev1alpha1.PlatformMesh, templateType string, renderErrs []corev1alpha1.ComponentRenderError) {
+	kept := append([]corev1alpha1.ComponentRenderError(nil), renderErrs...)
+	for _, e := range inst.Status.ComponentRenderErrors {
+		if e.Templates != templateType {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].Component != kept[j].Component {
+			return kept[i].Component < kept[j].Component
+		}
+		return kept[i].Templates < kept[j].Templates
+	})
+	inst.Status.ComponentRenderErrors = kept
+
+	if len(kept) == 0 {
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               ComponentsRenderedConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Rendered",
+			Message:            "all components rendered",
+			ObservedGeneration: inst.Generation,
+		})
+		return
+	}
+	var failed []string
+	for _, e := range kept {
+		failed = append(failed, fmt.Sprintf("%s (%s)", e.Component, e.Templates))
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               ComponentsRenderedConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             "RenderFailed",
+		Message:            "components failed to render: " + strings.Join(failed, ", "),
+		ObservedGeneration: inst.Generation,
+	})
+}
@@ -0,0 +1,90 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const componentTemplate = `{{- range $service, $config := .values.services }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ $service }}
+  namespace: {{ $.releaseNamespace }}
+data:
+  component: {{ $config.ocm.component.name }}
+---
+{{- end }}
+`
+
+func TestRenderComponentTemplatesDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "configmaps.yaml"), []byte(componentTemplate), 0o600))
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+
+	tmplVars := map[string]interface{}{
+		"releaseNamespace": "platform-mesh-system",
+		"values": map[string]interface{}{
+			"services": map[string]interface{}{
+				"portal":  map[string]interface{}{"ocm": map[string]interface{}{"component": map[string]interface{}{"name": "portal"}}},
+				"missing": map[string]interface{}{},
+				"iam":     map[string]interface{}{"ocm": map[string]interface{}{"component": map[string]interface{}{"name": "iam"}}},
+			},
+		},
+	}
+
+	manifests, renderErrs := (&DeploymentSubroutine{}).renderComponentTemplatesDir(context.Background(), dir, tmplVars, nil, log, "components-runtime", nil, nil)
+
+	var names []string
+	for _, m := range manifests {
+		names = append(names, m.obj.GetName())
+		assert.Equal(t, "platform-mesh-system", m.obj.GetNamespace())
+	}
+	assert.Equal(t, []string{"iam", "portal"}, names)
+	require.Len(t, renderErrs, 1)
+	assert.Equal(t, "missing", renderErrs[0].Component)
+	assert.Equal(t, "components-runtime", renderErrs[0].Templates)
+	assert.Contains(t, renderErrs[0].Message, "configmaps.yaml")
+
+	// The template vars of the caller are left untouched.
+	services := tmplVars["values"].(map[string]interface{})["services"].(map[string]interface{})
+	assert.Len(t, services, 3)
+}
+
+func TestRecordComponentRenderErrors(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+
+	recordComponentRenderErrors(inst, "components-runtime", []corev1alpha1.ComponentRenderError{
+		{Component: "portal", Templates: "components-runtime", Message: "boom"},
+	})
+	recordComponentRenderErrors(inst, "components-infra", []corev1alpha1.ComponentRenderError{
+		{Component: "iam", Templates: "components-infra", Message: "boom"},
+	})
+	assert.Equal(t, []string{"iam", "portal"}, []string{inst.Status.ComponentRenderErrors[0].Component, inst.Status.ComponentRenderErrors[1].Component})
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, ComponentsRenderedConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "components failed to render: iam (components-infra), portal (components-runtime)", cond.Message)
+
+	// A successful pass only clears the errors of its own template set.
+	recordComponentRenderErrors(inst, "components-runtime", nil)
+	require.Len(t, inst.Status.ComponentRenderErrors, 1)
+	assert.Equal(t, "iam", inst.Status.ComponentRenderErrors[0].Component)
+
+	recordComponentRenderErrors(inst, "components-infra", nil)
+	assert.Empty(t, inst.Status.ComponentRenderErrors)
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, ComponentsRenderedConditionType)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, int64(2), cond.ObservedGeneration)
+}
@@ -0,0 +1,88 @@
+package subroutines
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// applyComponentSwitches sets the enabled flag of the services with an entry
+// in spec.components. Switches for services the profile does not know are
+// ignored.
+func applyComponentSwitches(services map[string]interface{}, inst *v1alpha1.PlatformMesh, log *logger.Logger) {
+	for _, name := range slices.Sorted(maps.Keys(inst.Spec.Components)) {
+		config, ok := services[name].(map[string]interface{})
+		if !ok {
+			log.Warn().Str("component", name).Msg("Ignoring spec.components entry of a component that is not in the profile")
+			continue
+		}
+		config["enabled"] = inst.Spec.Components[name].Enabled
+	}
+}
+
+// componentSwitchedOff reports whether spec.components switches the service
+// name off.
+func componentSwitchedOff(inst *v1alpha1.PlatformMesh, name string) bool {
+	s, ok := inst.Spec.Components[name]
+	return ok && !s.Enabled
+}
+
+// hasSwitchedOffComponents reports whether spec.components switches any
+// service off.
+func hasSwitchedOffComponents(inst *v1alpha1.PlatformMesh) bool {
+	for name := range inst.Spec.Components {
+		if componentSwitchedOff(inst, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneComponentResources deletes the objects the components runtime
+// templates render for the disabled service name, such as its OCM Resources,
+// from the runtime cluster. The templates are rendered as if the service was
+// enabled, and only objects labeled for inst are deleted.
+func (r *DeploymentSubroutine) pruneComponentResources(ctx context.Context, inst *v1alpha1.PlatformMesh, tmplVars map[string]interface{}, name string, log *logger.Logger) error {
+	config, _ := templateServices(tmplVars)[name].(map[string]interface{})
+	config = maps.Clone(config)
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	config["enabled"] = true
+	vars := componentTemplateVars(tmplVars, name)
+	vars["values"].(map[string]interface{})["services"] = map[string]interface{}{name: config}
+
+	manifests, err := r.renderTemplatesDir(ctx, r.gotemplatesComponentsDir+"/runtime", vars, r.clientRuntime, log, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render the runtime templates of disabled component %s", name)
+	}
+	for _, m := range manifests {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(m.obj.GroupVersionKind())
+		err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: m.obj.GetName(), Namespace: m.obj.GetNamespace()}, live)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to get %s %s of disabled component %s", m.obj.GetKind(), m.obj.GetName(), name)
+		}
+		if labeledInstance(live) != instanceKey(inst) || !live.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.clientRuntime.Delete(ctx, live)); err != nil {
+			return errors.Wrap(err, "Failed to delete %s %s of disabled component %s", m.obj.GetKind(), m.obj.GetName(), name)
+		}
+		log.Info().Str("component", name).Str("kind", live.GetKind()).Str("name", live.GetName()).Msg("Pruned object of disabled component")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package subroutines
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const switchedResourcesTemplate = `
+{{- range $service, $config := .values.services }}
+{{- if $config.enabled }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ $service }}-resources
+  namespace: {{ $.releaseNamespace }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ $service }}-shared
+  namespace: {{ $.releaseNamespace }}
+{{- end }}
+{{- end }}
+`
+
+func TestApplyComponentSwitches(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	inst := &v1alpha1.PlatformMesh{Spec: v1alpha1.PlatformMeshSpec{Components: map[string]v1alpha1.ComponentSwitch{
+		"portal":  {Enabled: true},
+		"iam":     {Enabled: false},
+		"unknown": {Enabled: true},
+	}}}
+	services := map[string]interface{}{
+		"portal":  map[string]interface{}{"enabled": false},
+		"iam":     map[string]interface{}{"enabled": true, "targetNamespace": "iam"},
+		"openfga": map[string]interface{}{"enabled": true},
+	}
+
+	applyComponentSwitches(services, inst, log)
+	assert.Equal(t, map[string]interface{}{
+		"portal":  map[string]interface{}{"enabled": true},
+		"iam":     map[string]interface{}{"enabled": false, "targetNamespace": "iam"},
+		"openfga": map[string]interface{}{"enabled": true},
+	}, services)
+	assert.True(t, componentSwitchedOff(inst, "iam"))
+	assert.False(t, componentSwitchedOff(inst, "portal"))
+	assert.False(t, componentSwitchedOff(inst, "openfga"))
+	assert.True(t, hasSwitchedOffComponents(inst))
+	assert.False(t, hasSwitchedOffComponents(&v1alpha1.PlatformMesh{}))
+}
+
+func TestPruneSwitchedOffComponents(t *testing.T) {
+	ctx, rec, cl, sub, inst := newPruneTest(t)
+	sub.cfgOperator.Subroutines.Deployment.PruneDisabledComponents = false
+	inst.Spec.Components = map[string]v1alpha1.ComponentSwitch{"iam": {Enabled: false}}
+	require.NoError(t, os.WriteFile(filepath.Join(sub.gotemplatesComponentsDir, "runtime", "resources.yaml"), []byte(switchedResourcesTemplate), 0o644))
+
+	labeled := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "iam-resources", Namespace: inst.Namespace}}
+	setInstanceLabels(labeled, inst)
+	require.NoError(t, cl.Create(ctx, labeled))
+	require.NoError(t, cl.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "iam-shared", Namespace: inst.Namespace}}))
+
+	msg, err := sub.pruneDisabledComponents(ctx, inst, apiextensionsv1.JSON{})
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+	assert.Equal(t, "Normal ComponentPruned Pruned HelmRelease platform-mesh-system/iam of disabled component iam", <-rec.Events)
+	assert.Empty(t, rec.Events)
+
+	// Only the switched off component is pruned without PruneDisabledComponents.
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "iam"}, newPruneHelmRelease("iam", nil))))
+	assert.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "platform-mesh-system", Name: "portal"}, newPruneHelmRelease("portal", nil)))
+
+	// Runtime objects are only deleted when labeled for the instance.
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(labeled), &corev1.ConfigMap{})))
+	assert.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "iam-shared"}, &corev1.ConfigMap{}))
+}
@@ -0,0 +1,79 @@
+package subroutines
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/platform-mesh/golang-commons/logger"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// PinnedVersionAnnotation pins on an OCM Resource the version the
+// ResourceSubroutine deploys, instead of the version the Resource resolved.
+const PinnedVersionAnnotation = "core.platform-mesh.io/pinned-version"
+
+// applyVersionPins pins the chart and image versions of the services from
+// the version block of the profile, overridden field by field by
+// spec.versions. The chart version is set as PinnedVersionAnnotation of the
+// chart Resource, the image version on every image Resource of the service.
+// Pins for services the profile does not know are ignored.
+func applyVersionPins(services map[string]interface{}, inst *v1alpha1.PlatformMesh, log *logger.Logger) {
+	for _, name := range slices.Sorted(maps.Keys(inst.Spec.Versions)) {
+		if _, ok := services[name].(map[string]interface{}); !ok {
+			log.Warn().Str("component", name).Msg("Ignoring spec.versions entry of a component that is not in the profile")
+		}
+	}
+	for name, svc := range services {
+		config, ok := svc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		chart, image := profileVersions(config)
+		if pin, ok := inst.Spec.Versions[name]; ok {
+			if pin.Chart != "" {
+				chart = pin.Chart
+			}
+			if pin.Image != "" {
+				image = pin.Image
+			}
+		}
+		if chart != "" {
+			config["chartResources"] = pinResourceVersion(config["chartResources"], chart)
+		}
+		if image == "" {
+			continue
+		}
+		resources, _ := config["imageResources"].([]interface{})
+		for i := range resources {
+			resources[i] = pinResourceVersion(resources[i], image)
+		}
+	}
+}
+
+// profileVersions returns the chart and image version of the version block
+// of a service in the profile.
+func profileVersions(config map[string]interface{}) (chart, image string) {
+	version, _ := config["version"].(map[string]interface{})
+	chart, _ = version["chart"].(string)
+	image, _ = version["image"].(string)
+	return chart, image
+}
+
+// pinResourceVersion returns the Resource config res with the
+// PinnedVersionAnnotation set to version.
+func pinResourceVersion(res interface{}, version string) interface{} {
+	config, _ := res.(map[string]interface{})
+	config = maps.Clone(config)
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	annotations, _ := config["annotations"].(map[string]interface{})
+	annotations = maps.Clone(annotations)
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	annotations[PinnedVersionAnnotation] = version
+	config["annotations"] = annotations
+	return config
+}
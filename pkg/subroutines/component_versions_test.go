@@ -0,0 +1,44 @@
+package subroutines
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestApplyVersionPins(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	inst := &v1alpha1.PlatformMesh{Spec: v1alpha1.PlatformMeshSpec{Versions: map[string]v1alpha1.ComponentVersions{
+		"iam":     {Chart: "0.4.2"},
+		"unknown": {Chart: "1.0.0"},
+	}}}
+	services := map[string]interface{}{
+		"iam": map[string]interface{}{
+			"version": map[string]interface{}{"chart": "0.4.0", "image": "v0.4.0"},
+			"chartResources": map[string]interface{}{
+				"annotations": map[string]interface{}{"for": "iam"},
+			},
+			"imageResources": []interface{}{
+				map[string]interface{}{"name": "iam-image"},
+				map[string]interface{}{"name": "iam-ui-image"},
+			},
+		},
+		"portal": map[string]interface{}{"enabled": true},
+	}
+
+	applyVersionPins(services, inst, log)
+	iam := services["iam"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"annotations": map[string]interface{}{"for": "iam", PinnedVersionAnnotation: "0.4.2"},
+	}, iam["chartResources"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "iam-image", "annotations": map[string]interface{}{PinnedVersionAnnotation: "v0.4.0"}},
+		map[string]interface{}{"name": "iam-ui-image", "annotations": map[string]interface{}{PinnedVersionAnnotation: "v0.4.0"}},
+	}, iam["imageResources"])
+	assert.Equal(t, map[string]interface{}{"enabled": true}, services["portal"])
+}
@@ -0,0 +1,141 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	ProviderConnectionsReadyConditionType = "ProviderConnectionsReady"
+	// connectionProbeTimeout bounds all connection probes of one reconciliation.
+	connectionProbeTimeout = 10 * time.Second
+)
+
+// ConnectionProber checks that the server of a connection kubeconfig is serving.
+type ConnectionProber interface {
+	Probe(ctx context.Context, kubeconfig []byte) error
+}
+
+// DiscoveryProber probes a connection with a discovery request. Virtual
+// workspaces answer 404 for a while after their endpoint is published, so an
+// empty discovery result counts as not serving.
+type DiscoveryProber struct{}
+
+func (DiscoveryProber) Probe(ctx context.Context, kubeconfig []byte) error {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("load connection kubeconfig: %w", err)
+	}
+	cfg.Timeout = connectionProbeTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if cfg.Timeout = time.Until(deadline); cfg.Timeout <= 0 {
+			return fmt.Errorf("probe deadline of %s exceeded", cfg.Host)
+		}
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("create discovery client: %w", err)
+	}
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		return fmt.Errorf("discovery against %s: %w", cfg.Host, err)
+	}
+	for _, g := range groups.Groups {
+		if len(g.Versions) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("discovery against %s returned no API groups", cfg.Host)
+}
+
+// recordConnectionStatus adds or updates the connection in the instance status.
+// LastResolved only moves when the resolved URL changes, and an empty URL keeps
+// the last known one so that failing connections still show where they pointed.
+// A connection is only ready if it is healthy.
+func recordConnectionStatus(instance *corev1alpha1.PlatformMesh, name string, connType corev1alpha1.ConnectionType, resolvedURL string, healthy, ready bool) {
+	if instance == nil {
+		return
+	}
+	now := metav1.Now()
+	for i := range instance.Status.Connections {
+		c := &instance.Status.Connections[i]
+		if c.Name != name || c.Type != connType {
+			continue
+		}
+		if resolvedURL != "" && resolvedURL != c.ResolvedURL {
+			c.ResolvedURL = resolvedURL
+			c.LastResolved = &now
+		}
+		c.Healthy = healthy
+		c.Ready = healthy && ready
+		return
+	}
+	status := corev1alpha1.ConnectionStatus{Name: name, Type: connType, ResolvedURL: resolvedURL, Healthy: healthy, Ready: healthy && ready}
+	if resolvedURL != "" {
+		status.LastResolved = &now
+	}
+	instance.Status.Connections = append(instance.Status.Connections, status)
+}
+
+// pruneConnectionStatus drops connections of connType whose secret is not in names.
+func pruneConnectionStatus(instance *corev1alpha1.PlatformMesh, connType corev1alpha1.ConnectionType, names map[string]bool) {
+	kept := instance.Status.Connections[:0]
+	for _, c := range instance.Status.Connections {
+		if c.Type == connType && !names[c.Name] {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	instance.Status.Connections = kept
+}
+
+type connectionProbesKey struct{}
+
+// connectionProbes is how the connections of one reconciliation are probed.
+type connectionProbes struct {
+	skip     bool
+	deadline time.Time
+}
+
+// withConnectionProbes returns ctx for handling the connections of one
+// reconciliation. Their probes share one deadline, so connections that do not
+// serve cannot hold up the reconciliation longer than connectionProbeTimeout.
+// With skip, connections are not probed at all: out of cluster the in-cluster
+// endpoints written into the kubeconfigs cannot be reached.
+func withConnectionProbes(ctx context.Context, skip bool) context.Context {
+	return context.WithValue(ctx, connectionProbesKey{}, connectionProbes{skip: skip, deadline: time.Now().Add(connectionProbeTimeout)})
+}
+
+// probeConnection reports whether the kubeconfig written for a connection
+// reaches a serving endpoint.
+func probeConnection(ctx context.Context, prober ConnectionProber, name string, kubeconfig []byte) bool {
+	if probes, ok := ctx.Value(connectionProbesKey{}).(connectionProbes); ok {
+		if probes.skip {
+			return true
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, probes.deadline)
+		defer cancel()
+	}
+	if err := prober.Probe(ctx, kubeconfig); err != nil {
+		logger.LoadLoggerFromContext(ctx).Info().Err(err).Str("secret", name).Msg("Connection is not serving yet")
+		return false
+	}
+	return true
+}
+
+func connectionNotServing(name string, requeue time.Duration) subroutines.Result {
+	return subroutines.Pending(requeue, fmt.Sprintf("connection %s is not serving yet", name))
+}
@@ -0,0 +1,138 @@
+package subroutines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestRecordConnectionStatus(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{}
+
+	recordConnectionStatus(instance, "portal-kubeconfig", corev1alpha1.ConnectionTypeProvider, "https://frontproxy:8443/clusters/root:a", true, true)
+	require.Len(t, instance.Status.Connections, 1)
+	first := instance.Status.Connections[0]
+	require.NotNil(t, first.LastResolved)
+	assert.True(t, first.Healthy)
+
+	// Same name as an initializer is tracked separately.
+	recordConnectionStatus(instance, "portal-kubeconfig", corev1alpha1.ConnectionTypeInitializer, "", false, false)
+	require.Len(t, instance.Status.Connections, 2)
+	assert.Nil(t, instance.Status.Connections[1].LastResolved)
+
+	// Failing resolution keeps the last known URL and timestamp.
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	instance.Status.Connections[0].LastResolved = &past
+	recordConnectionStatus(instance, "portal-kubeconfig", corev1alpha1.ConnectionTypeProvider, "", false, false)
+	assert.Equal(t, "https://frontproxy:8443/clusters/root:a", instance.Status.Connections[0].ResolvedURL)
+	assert.Equal(t, &past, instance.Status.Connections[0].LastResolved)
+	assert.False(t, instance.Status.Connections[0].Healthy)
+
+	// An unchanged URL does not move LastResolved, a new one does.
+	recordConnectionStatus(instance, "portal-kubeconfig", corev1alpha1.ConnectionTypeProvider, "https://frontproxy:8443/clusters/root:a", true, true)
+	assert.Equal(t, &past, instance.Status.Connections[0].LastResolved)
+	recordConnectionStatus(instance, "portal-kubeconfig", corev1alpha1.ConnectionTypeProvider, "https://frontproxy:8443/clusters/root:b", true, true)
+	assert.Equal(t, "https://frontproxy:8443/clusters/root:b", instance.Status.Connections[0].ResolvedURL)
+	assert.True(t, instance.Status.Connections[0].LastResolved.After(past.Time))
+}
+
+func TestPruneConnectionStatus(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{}
+	recordConnectionStatus(instance, "kept", corev1alpha1.ConnectionTypeProvider, "https://a", true, true)
+	recordConnectionStatus(instance, "removed", corev1alpha1.ConnectionTypeProvider, "https://b", true, true)
+	recordConnectionStatus(instance, "removed", corev1alpha1.ConnectionTypeInitializer, "https://c", true, true)
+
+	pruneConnectionStatus(instance, corev1alpha1.ConnectionTypeProvider, map[string]bool{"kept": true})
+
+	require.Len(t, instance.Status.Connections, 2)
+	assert.Equal(t, "kept", instance.Status.Connections[0].Name)
+	assert.Equal(t, corev1alpha1.ConnectionTypeInitializer, instance.Status.Connections[1].Type)
+
+	pruneConnectionStatus(instance, corev1alpha1.ConnectionTypeProvider, nil)
+	pruneConnectionStatus(instance, corev1alpha1.ConnectionTypeInitializer, nil)
+	assert.Nil(t, instance.Status.Connections)
+}
+
+func TestRecordConnectionStatusReady(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{}
+
+	recordConnectionStatus(instance, "a", corev1alpha1.ConnectionTypeProvider, "https://a", true, true)
+	assert.True(t, instance.Status.Connections[0].Ready)
+
+	// A connection that failed to be written is never ready.
+	recordConnectionStatus(instance, "a", corev1alpha1.ConnectionTypeProvider, "https://a", false, true)
+	assert.False(t, instance.Status.Connections[0].Ready)
+}
+
+func TestDiscoveryProber(t *testing.T) {
+	state := "notFound"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state == "notFound" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api" && state == "serving":
+			_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		case r.URL.Path == "/api":
+			_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":[]}`))
+		case r.URL.Path == "/apis":
+			_, _ = w.Write([]byte(`{"kind":"APIGroupList","groups":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	kubeconfig, err := clientcmd.Write(*buildScopedKubeconfig("test", srv.URL, "token", nil))
+	require.NoError(t, err)
+
+	err = DiscoveryProber{}.Probe(context.Background(), kubeconfig)
+	assert.ErrorContains(t, err, "could not find the requested resource")
+
+	state = "empty"
+	err = DiscoveryProber{}.Probe(context.Background(), kubeconfig)
+	assert.ErrorContains(t, err, "returned no API groups")
+
+	state = "serving"
+	assert.NoError(t, DiscoveryProber{}.Probe(context.Background(), kubeconfig))
+
+	assert.Error(t, DiscoveryProber{}.Probe(context.Background(), []byte("not a kubeconfig")))
+}
+
+func TestProbeConnectionDeadline(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	base := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+
+	// Out of cluster connections are not probed.
+	prober := &fakeProber{}
+	assert.True(t, probeConnection(withConnectionProbes(base, true), prober, "a", []byte("kubeconfig")))
+	assert.Empty(t, prober.probed)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	kubeconfig, err := clientcmd.Write(*buildScopedKubeconfig("test", srv.URL, "token", nil))
+	require.NoError(t, err)
+
+	// Once the shared deadline passed, probes fail without a request.
+	ctx := context.WithValue(base, connectionProbesKey{}, connectionProbes{deadline: time.Now().Add(-time.Second)})
+	assert.False(t, probeConnection(ctx, DiscoveryProber{}, "a", kubeconfig))
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.ErrorContains(t, DiscoveryProber{}.Probe(ctx, kubeconfig), "probe deadline")
+}
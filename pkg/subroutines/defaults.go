@@ -11,6 +11,13 @@ import (
 
 const DefaultRequeueInterval = 5 * time.Second
 
+// Exposure of instances that leave spec.exposure or parts of it unset.
+const (
+	DefaultExposureBaseDomain = "portal.localhost"
+	DefaultExposurePort       = 8443
+	DefaultExposureProtocol   = "https"
+)
+
 var AccountOperatorWebhookSecretName = "account-operator-webhook-server-cert"
 var AccountOperatorWebhookSecretNamespace = "platform-mesh-system"
 
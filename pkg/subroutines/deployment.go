@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"maps"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -32,9 +32,16 @@ import (
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/merge"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/validate"
 )
 
-const DeploymentSubroutineName = "DeploymentSubroutine"
+const (
+	DeploymentSubroutineName = "DeploymentSubroutine"
+	// DeploymentSubroutineFinalizer holds the deletion of an instance until
+	// the objects applied to its spec.runtimeClusters are deleted.
+	DeploymentSubroutineFinalizer = "platform-mesh.core.platform-mesh.io/runtime-clusters"
+)
 
 const (
 	deploymentTechFluxCD = "fluxcd"
@@ -50,6 +57,11 @@ type DeploymentSubroutine struct {
 	gotemplatesComponentsDir string
 	cfgOperator              *config.OperatorConfig
 	imageVersionStore        *ImageVersionStore
+	validators               []validate.Validator
+	requeue                  *RequeueBackoff
+	// newClusterClient creates the clients of spec.runtimeClusters,
+	// newRuntimeClusterClient if nil.
+	newClusterClient func(kubeconfig []byte) (client.Client, error)
 }
 
 const (
@@ -67,17 +79,41 @@ func NewDeploymentSubroutine(clientRuntime client.Client, clientInfra client.Cli
 
 	sub := &DeploymentSubroutine{
 		cfg:                      cfg,
-		clientInfra:              clientInfra,
-		clientRuntime:            clientRuntime,
+		clientInfra:              withClusterErrors(plan.ClusterInfra, clientInfra),
+		clientRuntime:            withClusterErrors(plan.ClusterRuntime, clientRuntime),
 		workspaceDirectory:       workspaceDir,
 		gotemplatesInfraDir:      gotemplatesInfraDir,
 		gotemplatesComponentsDir: gotemplatesComponentsDir,
 		cfgOperator:              operatorCfg,
+		validators:               renderValidators(operatorCfg.Subroutines.Deployment.Validation),
+		requeue:                  NewRequeueBackoff(DeploymentSubroutineName, operatorCfg.Subroutines.Deployment.Requeue),
 	}
 
 	return sub
 }
 
+// renderValidators returns the pre-apply validators enabled by cfg. An engine
+// whose CLI cannot be found is skipped with a warning, since the operator
+// image does not ship the policy engines.
+func renderValidators(cfg config.RenderValidationConfig) []validate.Validator {
+	var validators []validate.Validator
+	if cfg.KyvernoPolicyDir != "" {
+		if validate.BinaryAvailable(cfg.KyvernoBinary) {
+			validators = append(validators, validate.NewKyverno(cfg.KyvernoBinary, cfg.KyvernoPolicyDir))
+		} else {
+			log.Warn().Str("binary", cfg.KyvernoBinary).Msg("kyverno CLI not found, skipping kyverno render validation")
+		}
+	}
+	if cfg.OPAPolicyDir != "" {
+		if validate.BinaryAvailable(cfg.OPABinary) {
+			validators = append(validators, validate.NewOPA(cfg.OPABinary, cfg.OPAPolicyDir, cfg.OPAQuery))
+		} else {
+			log.Warn().Str("binary", cfg.OPABinary).Msg("opa CLI not found, skipping opa render validation")
+		}
+	}
+	return validators
+}
+
 // SetImageVersionStore sets the shared ImageVersionStore used to merge
 // Resource-managed image versions into ArgoCD Application helm values.
 func (r *DeploymentSubroutine) SetImageVersionStore(store *ImageVersionStore) {
@@ -85,7 +121,14 @@ func (r *DeploymentSubroutine) SetImageVersionStore(store *ImageVersionStore) {
 }
 
 // getProfileConfigMap returns the profile ConfigMap for the given instance.
+// With spec.profiles, it is the effective profile ConfigMap merged from them.
+// Without spec.profileConfigMap, the default profile ConfigMap is created from
+// the embedded default profile if needed.
 func (r *DeploymentSubroutine) getProfileConfigMap(ctx context.Context, inst *v1alpha1.PlatformMesh) (*corev1.ConfigMap, error) {
+	if len(inst.Spec.Profiles) > 0 {
+		return r.effectiveProfileConfigMap(ctx, inst)
+	}
+
 	var configMapName, configMapNamespace string
 	if inst.Spec.ProfileConfigMap != nil {
 		configMapName = inst.Spec.ProfileConfigMap.Name
@@ -108,15 +151,21 @@ func (r *DeploymentSubroutine) getProfileConfigMap(ctx context.Context, inst *v1
 
 	// Try to get existing ConfigMap
 	err := r.clientRuntime.Get(ctx, client.ObjectKeyFromObject(configMap), configMap)
-	if err == nil {
-		// ConfigMap exists, verify it has the required key
-		if _, ok := configMap.Data[profileConfigMapKey]; !ok {
-			return nil, fmt.Errorf("configMap %s/%s exists but does not contain key %s", configMapNamespace, configMapName, profileConfigMapKey)
-		}
-		return configMap, nil
+	if kerrors.IsNotFound(err) && inst.Spec.ProfileConfigMap == nil {
+		return ensureDefaultProfileConfigMap(ctx, r.clientRuntime, inst, nil)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	// ConfigMap exists, verify it has the required key
+	if _, ok := configMap.Data[profileConfigMapKey]; !ok {
+		return nil, fmt.Errorf("configMap %s/%s exists but does not contain key %s", configMapNamespace, configMapName, profileConfigMapKey)
+	}
+	if inst.Spec.ProfileConfigMap == nil {
+		return ensureDefaultProfileConfigMap(ctx, r.clientRuntime, inst, configMap)
+	}
+	return configMap, nil
 }
 
 // loadProfileSections returns infra and components profile sections as separate YAML strings
@@ -132,11 +181,51 @@ func (r *DeploymentSubroutine) loadProfileSections(ctx context.Context, inst *v1
 	if !ok {
 		return "", "", fmt.Errorf("configMap %s/%s does not contain key %s", configMap.Namespace, configMap.Name, profileConfigMapKey)
 	}
+	recordProfileHash(ctx, inst, configMap, profileYAML)
+
+	infraProfile, componentsProfile, err = profileSections(profileYAML, inst, log)
+	if err != nil {
+		return "", "", err
+	}
+	log.Debug().Str("configmap", configMap.Name).Str("namespace", configMap.Namespace).Str("channel", inst.Spec.Channel).Msg("Loaded profile from ConfigMap")
+	return infraProfile, componentsProfile, nil
+}
 
-	// Parse unified profile
-	var unifiedProfile map[string]interface{}
-	if err := yaml.Unmarshal([]byte(profileYAML), &unifiedProfile); err != nil {
-		return "", "", errors.Wrap(err, "failed to parse profile YAML from ConfigMap")
+// readProfile returns the profile of inst like getProfileConfigMap but without
+// writing: layered profiles are merged in memory and a missing default profile
+// is the embedded one.
+func (r *DeploymentSubroutine) readProfile(ctx context.Context, inst *v1alpha1.PlatformMesh) (string, error) {
+	if len(inst.Spec.Profiles) > 0 {
+		return mergeProfileLayers(ctx, r.clientRuntime, inst)
+	}
+	key := client.ObjectKey{Name: inst.Name + defaultProfileConfigMapSuffix, Namespace: inst.Namespace}
+	if inst.Spec.ProfileConfigMap != nil {
+		key = client.ObjectKey{Name: inst.Spec.ProfileConfigMap.Name, Namespace: inst.Spec.ProfileConfigMap.Namespace}
+		if key.Namespace == "" {
+			key.Namespace = inst.Namespace
+		}
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.clientRuntime.Get(ctx, key, configMap); err != nil {
+		if kerrors.IsNotFound(err) && inst.Spec.ProfileConfigMap == nil {
+			return defaultProfile, nil
+		}
+		return "", err
+	}
+	profileYAML, ok := configMap.Data[profileConfigMapKey]
+	if !ok {
+		return "", fmt.Errorf("configMap %s does not contain key %s", key, profileConfigMapKey)
+	}
+	return profileYAML, nil
+}
+
+// profileSections returns the infra and components sections of profileYAML
+// with the channel of inst resolved.
+func profileSections(profileYAML string, inst *v1alpha1.PlatformMesh, log *logger.Logger) (infraProfile string, componentsProfile string, err error) {
+	// Parse unified profile, resolving the instance's channel
+	unifiedProfile, err := resolveProfile(profileYAML, inst, log)
+	if err != nil {
+		return "", "", err
 	}
 
 	// Extract infra section
@@ -152,21 +241,44 @@ func (r *DeploymentSubroutine) loadProfileSections(ctx context.Context, inst *v1
 	if err != nil {
 		return "", "", errors.Wrap(err, "Failed to marshal components profile")
 	}
-
-	log.Debug().Str("configmap", configMap.Name).Str("namespace", configMap.Namespace).Msg("Loaded profile from ConfigMap")
 	return string(infraYAML), string(componentsYAML), nil
 }
 
+// recordProfileHash sets status.profileHash of inst to the checksum of
+// profileYAML and records an event when the profile changed since the
+// components were last rendered.
+func recordProfileHash(ctx context.Context, inst *v1alpha1.PlatformMesh, configMap *corev1.ConfigMap, profileYAML string) {
+	hash := profileChecksum(profileYAML)
+	if inst.Status.ProfileHash == hash {
+		return
+	}
+	if inst.Status.ProfileHash != "" {
+		logger.LoadLoggerFromContext(ctx).Info().Str("configmap", configMap.Name).Str("namespace", configMap.Namespace).
+			Str("previousHash", inst.Status.ProfileHash).Str("hash", hash).Msg("Profile changed, re-rendering components")
+		recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonProfileChanged, "Render", "Profile ConfigMap %s/%s changed, re-rendering components", configMap.Namespace, configMap.Name)
+	}
+	inst.Status.ProfileHash = hash
+}
+
 func (r *DeploymentSubroutine) GetName() string {
 	return DeploymentSubroutineName
 }
 
-func (r *DeploymentSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+// Finalize deletes the objects applied to the clusters of
+// spec.runtimeClusters, which are not garbage collected with the instance.
+func (r *DeploymentSubroutine) Finalize(ctx context.Context, runtimeObj client.Object) (subroutines.Result, error) {
+	inst, ok := runtimeObj.(*v1alpha1.PlatformMesh)
+	if !ok {
+		return subroutines.OK(), nil
+	}
+	if remaining := r.finalizeRuntimeClusters(ctx, inst); remaining > 0 {
+		return subroutines.Pending(r.requeue.Next(inst), fmt.Sprintf("waiting for the objects of %d runtime clusters to be deleted", remaining)), nil
+	}
 	return subroutines.OK(), nil
 }
 
 func (r *DeploymentSubroutine) Finalizers(instance client.Object) []string { // coverage-ignore
-	return []string{}
+	return []string{DeploymentSubroutineFinalizer}
 }
 
 func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
@@ -182,6 +294,34 @@ func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Ob
 	inst := runtimeObj.(*v1alpha1.PlatformMesh)
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
+	ctx = withLookupCache(ctx)
+	if !plan.IsPlanning(ctx) {
+		ctx = withRenderRecord(ctx)
+		renderRecordFrom(ctx).exportManifests = r.cfgOperator.Subroutines.Deployment.ExportRenderedManifests
+		defer func() {
+			if err := r.writeRenderSnapshot(ctx, inst, renderRecordFrom(ctx), log); err != nil {
+				log.Error().Err(err).Msg("Failed to write render snapshot")
+			}
+			if err := r.writeRenderedManifests(ctx, inst, renderRecordFrom(ctx), log); err != nil {
+				log.Error().Err(err).Msg("Failed to write rendered manifests")
+			}
+		}()
+	}
+	status := trackSteps(inst, DeploymentReadyConditionType, "RenderingInfraTemplates")
+	defer func() { status.done(res, err) }()
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	ctx, maintenanceGate := withMaintenanceGate(ctx, inst, time.Now())
+	defer maintenanceGate.report(inst, v1alpha1.MaintenanceUpgrade, v1alpha1.MaintenanceRestart)
+	ctx, upgrades := withUpgradeOrder(ctx, inst, r.clientInfra, r.cfgOperator.Subroutines.Deployment.UpgradeMaxUnready)
+	// Work on a cluster that does not answer is retried while the work on the
+	// other cluster goes on, see clusterAvailability.
+	clusters := clusterAvailability{}
+	defer func() {
+		if clusters.check(err) {
+			res, err = subroutines.StopWithRequeue(r.requeue.Next(inst), clusters.message()), nil
+		}
+		clusters.setConditions(inst)
+	}()
 
 	// Create DeploymentComponents Version
 	templateVars, err := TemplateVars(ctx, inst, r.clientRuntime)
@@ -189,32 +329,79 @@ func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Ob
 		return subroutines.OK(), err
 	}
 
+	// Without istio, or with another exposure mode, nothing is applied until
+	// every component can be exposed that way.
+	mode := exposureMode(inst, r.cfgOperator.Subroutines.Deployment.EnableIstio)
+	if !r.cfgOperator.Subroutines.Deployment.EnableIstio || mode != v1alpha1.ExposureModeIstio {
+		status.enter("ValidatingExposure")
+		problems, err := r.checkExposure(ctx, inst, mode)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+		if len(problems) > 0 {
+			msg := fmt.Sprintf("exposure mode %s not achievable: %s", mode, strings.Join(problems, "; "))
+			recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonExposureInvalid, "Validate", "%s", msg)
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), msg), nil
+		}
+		status.enter("RenderingInfraTemplates")
+	}
+
 	// Render and apply infra templates directly from gotemplates/infra/infra using profile
 	oErr := r.renderAndApplyInfraTemplates(ctx, inst, templateVars)
-	if oErr != nil {
+	if res, ok := sharedObjectConflict(inst, oErr, sharedObjectConflictReasonDeployment, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if clusters.check(oErr) {
+		log.Warn().Err(oErr).Msg("Cluster unavailable, continuing without infra templates")
+	} else if oErr != nil {
 		log.Error().Err(oErr).Msg("Failed to render and apply infra templates")
 		return subroutines.OK(), oErr
+	} else {
+		log.Debug().Msg("Successfully rendered and applied infra templates")
 	}
-	log.Debug().Msg("Successfully rendered and applied infra templates")
 
+	status.enter("RenderingRuntimeTemplates")
 	oErr = r.renderAndApplyRuntimeTemplates(ctx, inst, templateVars)
-	if oErr != nil {
+	if res, ok := sharedObjectConflict(inst, oErr, sharedObjectConflictReasonDeployment, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if clusters.check(oErr) {
+		log.Warn().Err(oErr).Msg("Cluster unavailable, continuing without runtime templates")
+	} else if oErr != nil {
 		log.Error().Err(oErr).Msg("Failed to render and apply runtime templates")
 		return subroutines.OK(), oErr
+	} else {
+		log.Debug().Msg("Successfully rendered and applied runtime templates")
 	}
-	log.Debug().Msg("Successfully rendered and applied runtime templates")
 
 	// Render and apply components runtime templates (OCM Resources) early so that
 	// ResourceSubroutine can create OCIRepositories on the infra cluster. Those
 	// OCIRepositories are required by the infra HelmReleases (cert-manager, etcd-druid,
 	// etc.) which are applied by renderAndApplyInfraTemplates above. Without the
 	// OCIRepositories the cert-manager HelmRelease will never become Ready.
+	status.enter("RenderingComponentsRuntimeTemplates")
 	oErr = r.renderAndApplyComponentsRuntimeTemplates(ctx, inst, templateVars)
-	if oErr != nil {
+	if res, ok := sharedObjectConflict(inst, oErr, sharedObjectConflictReasonDeployment, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if clusters.check(oErr) {
+		log.Warn().Err(oErr).Msg("Cluster unavailable, continuing without components runtime templates")
+	} else if oErr != nil {
 		log.Error().Err(oErr).Msg("Failed to render and apply components runtime templates")
 		return subroutines.OK(), oErr
+	} else {
+		log.Debug().Msg("Successfully rendered and applied components runtime templates")
+	}
+
+	// The further runtime clusters report their failures in the status and
+	// never fail the reconcile. A planned reconcile does not reach them, as
+	// their clients are not recorded.
+	if len(inst.Spec.RuntimeClusters) > 0 || len(inst.Status.RuntimeClusters) > 0 {
+		status.enter("RenderingRuntimeClusterTemplates")
+		if !plan.IsPlanning(ctx) && clusters.available(plan.ClusterRuntime) {
+			r.applyRuntimeClusters(ctx, inst, templateVars)
+		}
 	}
-	log.Debug().Msg("Successfully rendered and applied components runtime templates")
 
 	// Get deploymentTechnology from template vars or config (needed for checking resource readiness)
 	tmplVars, err := r.templateVarsFromProfileInfra(ctx, inst, templateVars, r.cfgOperator)
@@ -229,13 +416,30 @@ func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Ob
 	deploymentTech = strings.ToLower(deploymentTech)
 
 	// Render and apply components infra templates (HelmReleases for services)
-	oErr = r.renderAndApplyComponentsInfraTemplates(ctx, inst, templateVars)
-	if oErr != nil {
-		log.Error().Err(oErr).Msg("Failed to render and apply components infra templates")
-		return subroutines.OK(), oErr
+	status.enter("RenderingComponentsInfraTemplates")
+	if clusters.available(plan.ClusterInfra) {
+		oErr = r.renderAndApplyComponentsInfraTemplates(ctx, inst, templateVars)
+		if res, ok := sharedObjectConflict(inst, oErr, sharedObjectConflictReasonDeployment, r.requeue.Next(inst), log); ok {
+			return res, nil
+		}
+		if clusters.check(oErr) {
+			log.Warn().Err(oErr).Msg("Cluster unavailable, continuing without components infra templates")
+		} else if oErr != nil {
+			log.Error().Err(oErr).Msg("Failed to render and apply components infra templates")
+			return subroutines.OK(), oErr
+		} else {
+			log.Debug().Msg("Successfully rendered and applied components infra templates")
+		}
+	}
+	if len(clusters) == 0 {
+		clearSharedObjectConflict(inst, sharedObjectConflictReasonDeployment)
+	}
+	// Everything after this waits for or writes to the runtime cluster.
+	if !clusters.available(plan.ClusterRuntime) {
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), clusters.message()), nil
 	}
-	log.Debug().Msg("Successfully rendered and applied components infra templates")
 
+	status.enter("WaitingForCertManager")
 	for _, crd := range []string{"issuers.cert-manager.io", "certificates.cert-manager.io"} {
 		established, err := isCRDEstablished(ctx, r.clientRuntime, crd)
 		if err != nil {
@@ -243,18 +447,24 @@ func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Ob
 			return subroutines.OK(), err
 		}
 		if !established {
-			return subroutines.StopWithRequeue(DefaultRequeueInterval, fmt.Sprintf("cert-manager CRD %s is not established", crd)), nil
+			msg := fmt.Sprintf("cert-manager CRD %s is not established", crd)
+			recordWaiting(ctx, inst, msg)
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), msg), nil
 		}
 	}
 
-	_, oErr = r.manageAuthorizationWebhookSecrets(ctx, inst)
+	status.enter("ManagingWebhooks")
+	webhooks := trackSteps(inst, WebhooksReadyConditionType, "ApplyingIssuer")
+	webhookRes, oErr := r.manageAuthorizationWebhookSecrets(ctx, inst, webhooks)
+	webhooks.done(webhookRes, oErr)
 	if oErr != nil {
 		log.Info().Msg("Failed to manage authorization webhook secrets")
 		return subroutines.OK(), oErr
 	}
 
 	// Check if istio-proxy is injected
-	if r.cfgOperator.Subroutines.Deployment.EnableIstio {
+	if r.cfgOperator.IstioGateEnabled() && clusters.available(plan.ClusterInfra) {
+		status.enter("WaitingForIstio")
 
 		// Wait for istiod release to be ready before continuing
 		rel, err := getDeploymentResource(ctx, r.clientInfra, "istio-istiod", inst.Namespace, deploymentTech)
@@ -268,55 +478,102 @@ func (r *DeploymentSubroutine) Process(ctx context.Context, runtimeObj client.Ob
 			healthStatus, healthFound, _ := unstructured.NestedString(rel.Object, "status", "health", "status")
 
 			if !found || syncStatus != "Synced" {
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, "istio-istiod Application is not synced"), nil
+				recordWaiting(ctx, inst, "istio-istiod Application is not synced")
+				return subroutines.StopWithRequeue(r.requeue.Next(inst), "istio-istiod Application is not synced"), nil
 			}
 			if !healthFound || healthStatus != "Healthy" {
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, "istio-istiod Application is not healthy"), nil
+				recordWaiting(ctx, inst, "istio-istiod Application is not healthy")
+				return subroutines.StopWithRequeue(r.requeue.Next(inst), "istio-istiod Application is not healthy"), nil
 			}
 		}
 
 		if deploymentTech == deploymentTechFluxCD {
 			// For FluxCD HelmReleases, check Ready condition
 			if !matchesConditionWithStatus(rel, "Ready", "True") {
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, "istio-istiod Release is not ready"), nil
+				recordWaiting(ctx, inst, "istio-istiod Release is not ready")
+				return subroutines.StopWithRequeue(r.requeue.Next(inst), "istio-istiod Release is not ready"), nil
 			}
 		}
 
-		hasProxy, pod, err := r.hasIstioProxyInjected(ctx, "platform-mesh-operator", "platform-mesh-system")
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to check if istio-proxy is injected")
-			return subroutines.OK(), err
-		}
-		// When running the operator locally there will never be a proxy
-		if !r.cfg.IsLocal && !hasProxy {
-			log.Info().Msg("Restarting operator to ensure istio-proxy is injected")
-			err := r.clientInfra.Delete(ctx, pod)
+		// When running the operator locally there is no operator pod to get a proxy
+		if r.runsInCluster() {
+			operatorDeploy, err := r.operatorDeployment(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to find the operator Deployment")
+				return subroutines.OK(), err
+			}
+			hasProxy, err := r.hasIstioProxyInjected(ctx, operatorDeploy.Name, operatorDeploy.Namespace)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to delete istio-proxy pod")
+				log.Error().Err(err).Msg("Failed to check if istio-proxy is injected")
 				return subroutines.OK(), err
 			}
-			// Forcing a pod restart
-			os.Exit(0)
+			if hasProxy {
+				if err := resetIstioRestarts(ctx, r.clientInfra, inst, operatorDeploy.Namespace); err != nil {
+					log.Error().Err(err).Msg("Failed to reset istio restart marker")
+					return subroutines.OK(), err
+				}
+			} else {
+				msg, err := r.restartForIstio(ctx, inst, operatorDeploy)
+				if err != nil {
+					return subroutines.OK(), err
+				}
+				if msg != "" {
+					recordWaiting(ctx, inst, msg)
+					return subroutines.StopWithRequeue(r.requeue.Next(inst), msg), nil
+				}
+			}
 		}
+	} else if !r.cfgOperator.Subroutines.Deployment.EnableIstio {
+		clearIstioConditions(inst)
 	}
 
 	// Wait for kcp release to be ready before continuing
+	status.enter("WaitingForRootShard")
 	rootShard := &unstructured.Unstructured{}
 	rootShard.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: "RootShard"})
 	// Wait for root shard to be ready
 	err = r.clientRuntime.Get(ctx, types.NamespacedName{Name: operatorCfg.KCP.RootShardName, Namespace: operatorCfg.KCP.Namespace}, rootShard)
 	if err != nil || !matchesConditionWithStatus(rootShard, "Available", "True") {
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "RootShard is not ready"), nil
+		recordWaiting(ctx, inst, "RootShard is not ready")
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "RootShard is not ready"), nil
 	}
 
+	status.enter("WaitingForFrontProxy")
 	frontProxy := &unstructured.Unstructured{}
 	frontProxy.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: "FrontProxy"})
 	// Wait for root shard to be ready
 	err = r.clientRuntime.Get(ctx, types.NamespacedName{Name: operatorCfg.KCP.FrontProxyName, Namespace: operatorCfg.KCP.Namespace}, frontProxy)
 	if err != nil || !matchesConditionWithStatus(frontProxy, "Available", "True") {
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "FrontProxy is not ready"), nil
+		recordWaiting(ctx, inst, "FrontProxy is not ready")
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "FrontProxy is not ready"), nil
 	}
-	return subroutines.OK(), nil
+
+	// Disabled components are pruned last, so their pre-delete hooks do not
+	// hold up the rest of the deployment.
+	status.enter("PruningComponents")
+	prune := r.cfgOperator.Subroutines.Deployment.PruneDisabledComponents || hasSwitchedOffComponents(inst)
+	if prune && deploymentTech == deploymentTechFluxCD && clusters.available(plan.ClusterInfra) {
+		msg, err := r.pruneDisabledComponents(ctx, inst, templateVars)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to prune disabled components")
+			return subroutines.OK(), err
+		}
+		if msg != "" {
+			recordWaiting(ctx, inst, msg)
+			return subroutines.Pending(r.requeue.Next(inst), msg), nil
+		}
+	}
+
+	// kcp runs on the runtime cluster, so the subroutines after this one can
+	// go on while the infra work is retried.
+	if !clusters.available(plan.ClusterInfra) {
+		return subroutines.Pending(r.requeue.Next(inst), clusters.message()), nil
+	}
+	if msg := upgrades.message(); msg != "" {
+		recordWaiting(ctx, inst, msg)
+		return subroutines.Pending(r.requeue.Next(inst), msg), nil
+	}
+	return maintenanceGate.result(), nil
 }
 
 // templateVarsFromProfileInfra parses the infra profile and merges it with templateVars for rendering gotemplates/infra
@@ -369,7 +626,11 @@ func (r *DeploymentSubroutine) templateVarsFromProfileInfra(ctx context.Context,
 	// Merge infra profile (base) with templateVars (overrides)
 	// templateVars take precedence over profile values
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
-	tmplVars, err := merge.MergeMaps(infraProfileMap, templateVarsMap, log)
+	strategies, err := profileListStrategies(infraProfileMap)
+	if err != nil {
+		return nil, err
+	}
+	tmplVars, err := merge.MergeMapsWithStrategies(infraProfileMap, templateVarsMap, strategies, log)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to merge infra profile with templateVars")
 	}
@@ -409,8 +670,13 @@ func (r *DeploymentSubroutine) buildRuntimeTemplateVars(ctx context.Context, ins
 		templateVarsMap = make(map[string]interface{})
 	}
 
+	infraStrategies, err := profileListStrategies(profileData)
+	if err != nil {
+		return nil, err
+	}
+
 	// Merge infra profile (base) with templateVars (overrides)
-	baseVars, err := merge.MergeMaps(profileData, templateVarsMap, log)
+	baseVars, err := merge.MergeMapsWithStrategies(profileData, templateVarsMap, infraStrategies, log)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to merge infra profile with templateVars")
 	}
@@ -422,7 +688,7 @@ func (r *DeploymentSubroutine) buildRuntimeTemplateVars(ctx context.Context, ins
 			return nil, errors.Wrap(err, "Failed to parse PlatformMesh.spec.Values")
 		}
 		var err error
-		baseVars, err = merge.MergeMaps(baseVars, specValues, log)
+		baseVars, err = merge.MergeMapsWithStrategies(baseVars, specValues, infraStrategies, log)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to merge PlatformMesh.spec.Values")
 		}
@@ -465,14 +731,14 @@ func (r *DeploymentSubroutine) buildRuntimeTemplateVars(ctx context.Context, ins
 	// Render profile-components.yaml as a Go template with templateVars
 	tmpl, err := template.New("profile-components").Funcs(templateFuncMap()).Parse(componentsProfile)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to parse profile-components.yaml template")
+		return nil, newClassifiedError(v1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to parse profile-components.yaml template"))
 	}
 
 	var buf bytes.Buffer
 	// Render profile-components.yaml template with baseVars directly (not wrapped in Values)
 	// This allows templates to use {{ .baseDomain }} instead of {{ .Values.baseDomain }}
 	if err := tmpl.Execute(&buf, baseVars); err != nil {
-		return nil, errors.Wrap(err, "Failed to execute profile-components.yaml template")
+		return nil, newClassifiedError(v1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to execute profile-components.yaml template"))
 	}
 
 	// Parse the rendered YAML
@@ -485,8 +751,12 @@ func (r *DeploymentSubroutine) buildRuntimeTemplateVars(ctx context.Context, ins
 	if services, ok := profileComponentsData["services"].(map[string]interface{}); ok {
 		// Merge services into baseVars
 		if existingServices, ok := baseVars["services"].(map[string]interface{}); ok {
+			componentsStrategies, err := profileListStrategies(profileComponentsData)
+			if err != nil {
+				return nil, err
+			}
 			// Merge services from profile into existing services
-			mergedServices, err := merge.MergeMaps(existingServices, services, log)
+			mergedServices, err := merge.MergeMapsWithStrategies(existingServices, services, componentsStrategies.Under("services"), log)
 			if err != nil {
 				return nil, errors.Wrap(err, "Failed to merge services from profile-components.yaml")
 			}
@@ -511,13 +781,42 @@ func (r *DeploymentSubroutine) buildRuntimeTemplateVars(ctx context.Context, ins
 // buildComponentsTemplateVars parses components profile using TemplateVars and produces the data
 // structure expected by gotemplates/components (root keys: values, releaseNamespace).
 func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context, inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON) (map[string]interface{}, error) {
-	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
-
 	// Load components profile from ConfigMap
 	_, componentsProfileYaml, err := r.loadProfileSections(ctx, inst)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to load profile from ConfigMap")
 	}
+	return r.renderComponentsTemplateVars(ctx, inst, templateVars, componentsProfileYaml)
+}
+
+// componentServices returns the rendered services of the components profile
+// of inst without writing anything, for checks that run before the
+// subroutine. Values pins are applied to a copy of inst.
+func (r *DeploymentSubroutine) componentServices(ctx context.Context, inst *v1alpha1.PlatformMesh) (map[string]interface{}, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	profileYAML, err := r.readProfile(ctx, inst)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read profile")
+	}
+	_, componentsProfile, err := profileSections(profileYAML, inst, log)
+	if err != nil {
+		return nil, err
+	}
+	templateVars, err := TemplateVars(ctx, inst, r.clientRuntime)
+	if err != nil {
+		return nil, err
+	}
+	tmplVars, err := r.renderComponentsTemplateVars(ctx, inst.DeepCopy(), templateVars, componentsProfile)
+	if err != nil {
+		return nil, err
+	}
+	return templateServices(tmplVars), nil
+}
+
+// renderComponentsTemplateVars renders componentsProfileYaml for inst, see
+// buildComponentsTemplateVars.
+func (r *DeploymentSubroutine) renderComponentsTemplateVars(ctx context.Context, inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON, componentsProfileYaml string) (map[string]interface{}, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 
 	// Parse components profile as YAML to get the base structure
 	var componentsProfileMap map[string]interface{}
@@ -535,9 +834,14 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 		templateVarsMap = make(map[string]interface{})
 	}
 
+	strategies, err := profileListStrategies(componentsProfileMap)
+	if err != nil {
+		return nil, err
+	}
+
 	// Merge components profile (base) with templateVars (overrides)
 	// templateVars take precedence over profile values
-	templateVarsMap, err = merge.MergeMaps(componentsProfileMap, templateVarsMap, log)
+	templateVarsMap, err = merge.MergeMapsWithStrategies(componentsProfileMap, templateVarsMap, strategies, log)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to merge profile-components.yaml with templateVars")
 	}
@@ -546,14 +850,14 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 	// Templates can use {{ .baseDomain }} instead of {{ .Values.baseDomain }}
 	tmpl, err := template.New("profile-components").Funcs(templateFuncMap()).Parse(componentsProfileYaml)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to parse profile-components.yaml template")
+		return nil, newClassifiedError(v1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to parse profile-components.yaml template"))
 	}
 
 	var buf bytes.Buffer
 	// Render profile-components.yaml template with tv directly (not wrapped in Values)
 	// This allows templates to use {{ .baseDomain }} instead of {{ .Values.baseDomain }}
 	if err := tmpl.Execute(&buf, templateVarsMap); err != nil {
-		return nil, errors.Wrap(err, "Failed to execute profile-components.yaml template")
+		return nil, newClassifiedError(v1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to execute profile-components.yaml template"))
 	}
 
 	// Now parse the rendered YAML into a generic values map
@@ -562,20 +866,13 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 		return nil, errors.Wrap(err, "Failed to unmarshal rendered profile-components.yaml")
 	}
 
-	// Extract services from the rendered profile-components.yaml
-	var baseServices map[string]interface{}
-	if services, ok := values["services"].(map[string]interface{}); ok {
-		baseServices = services
-	} else {
-		baseServices = make(map[string]interface{})
-	}
-
 	// Build template data for rendering templates in spec.Values
 	templateData := make(map[string]interface{})
 	_, baseDomainPort, _, _ := baseDomainPortProtocol(inst)
 
 	templateData["baseDomain"] = getBaseDomainFromInstance(inst)
 	templateData["baseDomainPort"] = baseDomainPort
+	maps.Copy(templateData, domainTemplateData(inst))
 	templateData["port"] = "443"
 	if inst.Spec.Exposure != nil && inst.Spec.Exposure.Port != 0 {
 		templateData["port"] = fmt.Sprintf("%d", inst.Spec.Exposure.Port)
@@ -586,50 +883,27 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 		templateData["baseDomainWithPort"] = templateData["baseDomain"]
 	}
 
-	// Extract services from PlatformMesh.spec.Values
-	// spec.Values can either have services under a "services" key, or the entire spec.Values can be services
-	var specServices map[string]interface{}
-	if len(inst.Spec.Values.Raw) > 0 {
-		var specValues map[string]interface{}
-		if err := json.Unmarshal(inst.Spec.Values.Raw, &specValues); err != nil {
-			return nil, errors.Wrap(err, "Failed to parse PlatformMesh.spec.Values")
-		}
-		// Check if services are under a "services" key
-		if services, ok := specValues["services"].(map[string]interface{}); ok {
-			specServices = services
-		} else {
-			// If no "services" key, treat the entire specValues as services (flat structure)
-			// This matches the behavior in MergeValuesAndServices
-			specServices = specValues
-		}
-
-		// Render any template syntax in specServices before merging
-		// Wrap templateData in Values key to support {{ .Values.* }} syntax in spec.Values
-		wrappedTemplateData := map[string]interface{}{
-			"Values": templateData,
-		}
-		// Also add top-level keys for backward compatibility with {{ .baseDomain }} syntax
-		for k, v := range templateData {
-			wrappedTemplateData[k] = v
-		}
-		renderedServices, err := renderTemplatesInValue(specServices, wrappedTemplateData)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to render templates in PlatformMesh.spec.Values services")
-		}
-		if renderedMap, ok := renderedServices.(map[string]interface{}); ok {
-			specServices = renderedMap
-		}
-	}
-
-	// Deep merge specServices into baseServices (specServices takes precedence)
-	mergedServices, err := merge.MergeMaps(baseServices, specServices, log)
+	mergedServices, err := mergeValuesAndServices(values, inst.Spec.Values, templateData, log)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to merge services from PlatformMesh.spec.Values with profile-components.yaml services")
+		return nil, err
 	}
 
+	// spec.components switches services on or off over the profile and spec.values
+	applyComponentSwitches(mergedServices, inst, log)
+	// spec.versions pins chart and image versions over the profile version blocks
+	applyVersionPins(mergedServices, inst, log)
+
 	// Put the merged services back into values
 	values["services"] = mergedServices
 
+	if err := applySizing(values, mergedServices, log); err != nil {
+		return nil, errors.Wrap(err, "Failed to apply sizing to profile-components.yaml services")
+	}
+
+	if err := r.applyValuesPins(ctx, inst, mergedServices, log); err != nil {
+		return nil, errors.Wrap(err, "Failed to apply values rollback")
+	}
+
 	// Root data passed to component gotemplates
 	data := map[string]interface{}{
 		"values":           values,
@@ -671,6 +945,8 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 	}
 
 	data["baseDomain"] = getBaseDomainFromInstance(inst)
+	maps.Copy(data, domainTemplateData(inst))
+	data["exposureMode"] = string(exposureMode(inst, r.cfgOperator.Subroutines.Deployment.EnableIstio))
 	data["port"] = "443"
 	if inst.Spec.Exposure != nil && inst.Spec.Exposure.Port != 0 {
 		data["port"] = fmt.Sprintf("%d", inst.Spec.Exposure.Port)
@@ -687,7 +963,7 @@ func (r *DeploymentSubroutine) buildComponentsTemplateVars(ctx context.Context,
 // getBaseDomainFromInstance extracts the base domain from PlatformMesh instance
 func getBaseDomainFromInstance(inst *v1alpha1.PlatformMesh) string {
 	if inst.Spec.Exposure == nil || inst.Spec.Exposure.BaseDomain == "" {
-		return "portal.localhost"
+		return DefaultExposureBaseDomain
 	}
 	return inst.Spec.Exposure.BaseDomain
 }
@@ -699,6 +975,45 @@ func calculateSyncWaves(services map[string]interface{}) error {
 		return nil
 	}
 
+	syncWaves, userConfiguredSyncWaves := serviceSyncWaves(services)
+
+	// Add sync wave to each service config
+	// Only overwrite if not user-configured, otherwise preserve user value
+	// Note: ignoreDifferences is also preserved from the profile components section
+	// and will be available in the config for use in templates
+	for serviceName, serviceConfig := range services {
+		config, ok := serviceConfig.(map[string]interface{})
+		if !ok || config == nil {
+			// Skip non-map service configs
+			continue
+		}
+		if _, isUserConfigured := userConfiguredSyncWaves[serviceName]; isUserConfigured {
+			// Keep user-configured value, but still respect dependencies if needed
+			// For user-configured values, we keep them as-is
+			continue
+		}
+
+		wave, exists := syncWaves[serviceName]
+		if !exists {
+			// Service not in syncWaves map, default to wave 0
+			wave = 0
+		}
+
+		// Set syncWave field (only if not user-configured)
+		config["syncWave"] = wave
+
+		// ignoreDifferences is preserved from the profile components section
+		// It is already in the config map from the merged services, so no explicit
+		// handling is needed here - it will be available in templates via $config.ignoreDifferences
+	}
+
+	return nil
+}
+
+// serviceSyncWaves returns the sync wave of every service, the configured
+// syncWave or else one more than the highest wave of its dependsOn, and the
+// services whose wave is configured.
+func serviceSyncWaves(services map[string]interface{}) (map[string]int, map[string]int) {
 	// Build dependency graph: service -> list of dependencies
 	dependencies := make(map[string][]string)
 	serviceNames := make([]string, 0)
@@ -818,37 +1133,66 @@ func calculateSyncWaves(services map[string]interface{}) error {
 		}
 	}
 
-	// Add sync wave to each service config
-	// Only overwrite if not user-configured, otherwise preserve user value
-	// Note: ignoreDifferences is also preserved from the profile components section
-	// and will be available in the config for use in templates
-	for serviceName, serviceConfig := range services {
-		config, ok := serviceConfig.(map[string]interface{})
-		if !ok || config == nil {
-			// Skip non-map service configs
-			continue
+	return syncWaves, userConfiguredSyncWaves
+}
+
+// mergeValuesAndServices merges the services of specValues over the services
+// of the rendered profile values and returns the result. specValues either
+// holds the services under a "services" key or is the services map itself.
+// Its template syntax is rendered with templateData first. The services of
+// specValues take precedence, lists are merged with the listMergeStrategies
+// of values. values is not modified.
+func mergeValuesAndServices(values map[string]interface{}, specValues apiextensionsv1.JSON, templateData map[string]interface{}, log *logger.Logger) (map[string]interface{}, error) {
+	// Extract services from the rendered profile-components.yaml
+	baseServices, ok := values["services"].(map[string]interface{})
+	if !ok {
+		baseServices = make(map[string]interface{})
+	}
+
+	var specServices map[string]interface{}
+	if len(specValues.Raw) > 0 {
+		var specMap map[string]interface{}
+		if err := json.Unmarshal(specValues.Raw, &specMap); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse PlatformMesh.spec.Values")
 		}
-		if _, isUserConfigured := userConfiguredSyncWaves[serviceName]; isUserConfigured {
-			// Keep user-configured value, but still respect dependencies if needed
-			// For user-configured values, we keep them as-is
-			continue
+		// Check if services are under a "services" key
+		if services, ok := specMap["services"].(map[string]interface{}); ok {
+			specServices = services
+		} else {
+			// If no "services" key, treat the entire specValues as services (flat structure)
+			specServices = specMap
 		}
 
-		wave, exists := syncWaves[serviceName]
-		if !exists {
-			// Service not in syncWaves map, default to wave 0
-			wave = 0
+		// Render any template syntax in specServices before merging
+		// Wrap templateData in Values key to support {{ .Values.* }} syntax in spec.Values
+		wrappedTemplateData := map[string]interface{}{
+			"Values": templateData,
 		}
+		// Also add top-level keys for backward compatibility with {{ .baseDomain }} syntax
+		for k, v := range templateData {
+			wrappedTemplateData[k] = v
+		}
+		renderedServices, err := renderTemplatesInValue(specServices, wrappedTemplateData)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to render templates in PlatformMesh.spec.Values services")
+		}
+		if renderedMap, ok := renderedServices.(map[string]interface{}); ok {
+			specServices = renderedMap
+		}
+	}
 
-		// Set syncWave field (only if not user-configured)
-		config["syncWave"] = wave
-
-		// ignoreDifferences is preserved from the profile components section
-		// It is already in the config map from the merged services, so no explicit
-		// handling is needed here - it will be available in templates via $config.ignoreDifferences
+	// The rendered profile may carry templated strategies, so they are read again
+	strategies, err := profileListStrategies(values)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Deep merge specServices into baseServices (specServices takes precedence)
+	mergedServices, err := merge.MergeMapsWithStrategies(baseServices, specServices, strategies.Under("services"), log)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to merge services from PlatformMesh.spec.Values with profile-components.yaml services")
+	}
+	return mergedServices, nil
 }
 
 // renderTemplatesInValue recursively traverses a value (map, slice, or string) and renders
@@ -1020,7 +1364,13 @@ func (r *DeploymentSubroutine) renderAndApplyInfraTemplates(ctx context.Context,
 	skipFile := deploymentTechFileFilter(deploymentTech, log)
 	postProcess := r.infraManifestPostProcess(ctx, log)
 
-	return r.renderAndApplyTemplates(ctx, r.gotemplatesInfraDir+"/infra", tmplVars, r.clientInfra, log, "infra", skipFile, postProcess)
+	inv := newInventory("infra", plan.ClusterInfra)
+	applied, err := r.renderAndApplyTemplates(ctx, r.gotemplatesInfraDir+"/infra", tmplVars, r.clientInfra, NewSharedObjectClaims(r.clientRuntime, inst), inv, log, "infra", skipFile, postProcess)
+	recordManifestsApplied(ctx, inst, "infra", applied, err)
+	if err != nil {
+		return err
+	}
+	return r.pruneInventory(ctx, inst, inv, log)
 }
 
 // renderAndApplyRuntimeTemplates renders all templates in gotemplates/infra/runtime and applies them.
@@ -1043,21 +1393,35 @@ func (r *DeploymentSubroutine) renderAndApplyRuntimeTemplates(ctx context.Contex
 	// Route each rendered object to the correct cluster client based on its GVK.
 	// OCM Resources → runtime cluster (OCM controller lives there).
 	// Everything else (FluxCD HelmReleases, etc.) → infra cluster.
+	claims := NewSharedObjectClaims(r.clientRuntime, inst)
+	inv := newInventory("runtime", plan.ClusterInfra)
 	routingPostProcess := func(ctx context.Context, obj *unstructured.Unstructured) error {
-		targetClient := r.clientInfra
-		if obj.GetAPIVersion() == "delivery.ocm.software/v1alpha1" && obj.GetKind() == "Resource" {
+		targetClient, cluster := r.clientInfra, templateCluster("runtime", obj)
+		if cluster == plan.ClusterRuntime {
 			targetClient = r.clientRuntime
 		}
-		return targetClient.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManagerDeployment), client.ForceOwnership) //nolint:staticcheck // Apply via Patch is required for unstructured objects
+		if err := claims.claim(ctx, targetClient, obj, ""); err != nil {
+			return err
+		}
+		if err := applyManifest(ctx, targetClient, obj, fieldManagerDeployment); err != nil {
+			return err
+		}
+		inv.add(cluster, "", obj)
+		return nil
 	}
 
 	// Use clientInfra as default (it will be overridden per-object by routingPostProcess).
 	// We pass a no-op postProcessObj and handle the actual Apply inside routingPostProcess.
-	return r.renderAndApplyTemplatesWithRouter(ctx, r.gotemplatesInfraDir+"/runtime", tmplVars, log, "runtime", nil, routingPostProcess)
+	applied, err := r.renderAndApplyTemplatesWithRouter(ctx, r.gotemplatesInfraDir+"/runtime", tmplVars, log, "runtime", nil, routingPostProcess)
+	recordManifestsApplied(ctx, inst, "runtime", applied, err)
+	if err != nil {
+		return err
+	}
+	return r.pruneInventory(ctx, inst, inv, log)
 }
 
 // renderAndApplyComponentsInfraTemplates renders gotemplates/components/infra with profile-components.yaml
-// and applies the resulting manifests to the infra cluster.
+// and applies the resulting manifests to the infra cluster. Each component is rendered on its own.
 func (r *DeploymentSubroutine) renderAndApplyComponentsInfraTemplates(ctx context.Context, inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON) error {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 
@@ -1073,11 +1437,53 @@ func (r *DeploymentSubroutine) renderAndApplyComponentsInfraTemplates(ctx contex
 	skipFile := deploymentTechFileFilter(deploymentTech, log)
 	postProcess := r.infraManifestPostProcess(ctx, log)
 
-	return r.renderAndApplyTemplates(ctx, r.gotemplatesComponentsDir+"/infra", tmplVars, r.clientInfra, log, "components-infra", skipFile, postProcess)
+	releaseNamespace, _ := tmplVars["releaseNamespace"].(string)
+	adoption, err := r.discoverReleaseAdoption(ctx, inst, releaseNamespace, log)
+	if err != nil {
+		return err
+	}
+	if adoption != nil {
+		postProcess = adoption.postProcess(postProcess)
+	}
+	inv := newInventory("components-infra", plan.ClusterInfra)
+	upgradeOrderFrom(ctx).plan(ctx, tmplVars)
+	postProcess = holdUpgrades(r.clientInfra, inv, deferUpgrades(r.clientInfra, inv, postProcess))
+	inventories, err := r.loadInventory(ctx, inst)
+	if err != nil {
+		return err
+	}
+	var lastGood map[string]map[string]interface{}
+	if r.cfgOperator.Subroutines.Deployment.RollbackAfter > 0 {
+		if lastGood, err = r.loadLastGoodSpecs(ctx, inst); err != nil {
+			return err
+		}
+	}
+	rollback := newReleaseRollback(inst, r.cfgOperator.Subroutines.Deployment.RollbackAfter, time.Now(), inventories[inv.templateType], lastGood)
+	postProcess = rollback.postProcess(r.clientInfra, inv, postProcess)
+
+	applied, err := r.renderAndApplyComponentTemplates(ctx, inst, r.gotemplatesComponentsDir+"/infra", tmplVars, r.clientInfra, NewSharedObjectClaims(r.clientRuntime, inst), inv, log, "components-infra", skipFile, postProcess)
+	recordManifestsApplied(ctx, inst, "components-infra", applied, err)
+	if err != nil {
+		return err
+	}
+	rollback.report(inst)
+	if err := r.storeLastGoodSpecs(ctx, inst, rollback); err != nil {
+		return err
+	}
+	if adoption != nil {
+		adoption.report(ctx, inst)
+	}
+	if err := r.pruneInventory(ctx, inst, inv, log); err != nil {
+		return err
+	}
+
+	// Snapshot the values that were just applied, so a later bad change can
+	// be rolled back with the rollback-values annotation.
+	return r.recordValuesSnapshots(ctx, inst, tmplVars)
 }
 
 // renderAndApplyComponentsRuntimeTemplates renders gotemplates/components/runtime with profile-components.yaml
-// and applies the resulting manifests to the runtime cluster (OCM Resources).
+// and applies the resulting manifests to the runtime cluster (OCM Resources). Each component is rendered on its own.
 func (r *DeploymentSubroutine) renderAndApplyComponentsRuntimeTemplates(ctx context.Context, inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON) error {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 
@@ -1087,45 +1493,47 @@ func (r *DeploymentSubroutine) renderAndApplyComponentsRuntimeTemplates(ctx cont
 		return err
 	}
 
-	return r.renderAndApplyTemplates(ctx, r.gotemplatesComponentsDir+"/runtime", tmplVars, r.clientRuntime, log, "components-runtime", nil, nil)
+	inv := newInventory("components-runtime", plan.ClusterRuntime)
+	upgradeOrderFrom(ctx).plan(ctx, tmplVars)
+	applied, err := r.renderAndApplyComponentTemplates(ctx, inst, r.gotemplatesComponentsDir+"/runtime", tmplVars, r.clientRuntime, NewSharedObjectClaims(r.clientRuntime, inst), inv, log, "components-runtime", nil, holdUpgrades(r.clientRuntime, inv, deferUpgrades(r.clientRuntime, inv, nil)))
+	recordManifestsApplied(ctx, inst, "components-runtime", applied, err)
+	if err != nil {
+		return err
+	}
+	return r.pruneInventory(ctx, inst, inv, log)
 }
 
-func mergeOCMConfig(mapValues map[string]interface{}, inst *v1alpha1.PlatformMesh) {
-	if inst.Spec.OCM != nil {
-		repoConfig := map[string]interface{}{}
-		compConfig := map[string]interface{}{}
-
-		if inst.Spec.OCM.Repo != nil {
-			repoConfig = map[string]interface{}{
-				"name": inst.Spec.OCM.Repo.Name,
-			}
-		}
-
-		if inst.Spec.OCM.Component != nil {
-			compConfig = map[string]interface{}{
-				"name": inst.Spec.OCM.Component.Name,
-			}
-		}
-		var referencePath []interface{}
-		for _, element := range inst.Spec.OCM.ReferencePath {
-			referencePath = append(referencePath, map[string]interface{}{"name": element.Name})
-		}
-		ocmConfig := map[string]interface{}{
-			"repo":          repoConfig,
-			"component":     compConfig,
-			"referencePath": referencePath,
-		}
-		mapValues["ocm"] = ocmConfig
+// authorizationWebhookSecretKeys returns where the kubeconfig secret kcp
+// mounts to reach the authorization webhook and the CA secret of the webhook
+// live. Unset namespaces default to the KCP namespace and to the namespace of
+// inst, where the webhook certificate is issued.
+func authorizationWebhookSecretKeys(operatorCfg config.OperatorConfig, inst *v1alpha1.PlatformMesh) (kubeconfig, ca types.NamespacedName) {
+	deploymentCfg := operatorCfg.Subroutines.Deployment
+	kubeconfig = types.NamespacedName{Name: deploymentCfg.AuthorizationWebhookSecretName, Namespace: deploymentCfg.AuthorizationWebhookSecretNamespace}
+	if kubeconfig.Namespace == "" {
+		kubeconfig.Namespace = operatorCfg.KCP.Namespace
 	}
+	if kubeconfig.Namespace == "" {
+		kubeconfig.Namespace = inst.Namespace
+	}
+	ca = types.NamespacedName{Name: deploymentCfg.AuthorizationWebhookSecretCAName, Namespace: deploymentCfg.AuthorizationWebhookSecretCANamespace}
+	if ca.Namespace == "" {
+		ca.Namespace = inst.Namespace
+	}
+	return kubeconfig, ca
 }
 
 func (r *DeploymentSubroutine) createKCPWebhookSecret(ctx context.Context, inst *v1alpha1.PlatformMesh) error {
 	log := logger.LoadLoggerFromContext(ctx)
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
-	webhookSecret := operatorCfg.Subroutines.Deployment.AuthorizationWebhookSecretName
-	_, err := GetSecret(r.clientRuntime, webhookSecret, inst.Namespace)
+	key, _ := authorizationWebhookSecretKeys(operatorCfg, inst)
+	if err := r.migrateKcpWebhookSecret(ctx, inst, key); err != nil {
+		return err
+	}
+
+	_, err := GetSecret(r.clientRuntime, key.Name, key.Namespace)
 	if err != nil && !kerrors.IsNotFound(err) {
-		log.Error().Err(err).Str("secret", webhookSecret).Str("namespace", inst.Namespace).Msg("Failed to get kcp webhook secret")
+		log.Error().Err(err).Str("secret", key.Name).Str("namespace", key.Namespace).Msg("Failed to get kcp webhook secret")
 		return err
 	}
 	if err == nil {
@@ -1137,50 +1545,91 @@ func (r *DeploymentSubroutine) createKCPWebhookSecret(ctx context.Context, inst
 	if err != nil {
 		return err
 	}
-	obj.SetNamespace(inst.Namespace)
+	if key.Name != "" {
+		obj.SetName(key.Name)
+	}
+	obj.SetNamespace(key.Namespace)
+	setInstanceLabels(&obj, inst)
+	LabelSecretPurpose(&obj, SecretPurposeWebhook, "")
+	markSecretRotated(&obj)
 
 	// Apply the secret using SSA (idempotent - creates if not exists, updates if exists)
-	if err := r.clientRuntime.Patch(ctx, &obj, client.Apply, client.FieldOwner(fieldManagerDeployment), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	return applyManifest(ctx, r.clientRuntime, &obj, fieldManagerDeployment)
+}
+
+// migrateKcpWebhookSecret moves the kcp webhook secret that earlier versions
+// kept in the namespace of inst to key, so kcp running in its own namespace
+// can mount it. An existing secret at key is kept.
+func (r *DeploymentSubroutine) migrateKcpWebhookSecret(ctx context.Context, inst *v1alpha1.PlatformMesh, key types.NamespacedName) error {
+	if key.Namespace == inst.Namespace {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	legacy, err := GetSecret(r.clientRuntime, key.Name, inst.Namespace)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = GetSecret(r.clientRuntime, key.Name, key.Namespace)
+	if kerrors.IsNotFound(err) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: legacy.Labels},
+			Type:       legacy.Type,
+			Data:       legacy.Data,
+		}
+		if err := r.clientRuntime.Create(ctx, secret); err != nil {
+			return errors.Wrap(err, "Failed to create kcp webhook secret %s", key)
+		}
+	} else if err != nil {
 		return err
 	}
+
+	if err := r.clientRuntime.Delete(ctx, legacy); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "Failed to delete kcp webhook secret %s/%s", inst.Namespace, key.Name)
+	}
+	log.Info().Str("secret", key.Name).Str("from", inst.Namespace).Str("to", key.Namespace).Msg("Migrated kcp webhook secret")
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonWebhookSecretMigrated, "MigrateWebhookSecret",
+		"Moved kcp webhook secret %s from namespace %s to %s", key.Name, inst.Namespace, key.Namespace)
 	return nil
 }
 
 func (r *DeploymentSubroutine) updateKcpWebhookSecret(ctx context.Context, inst *v1alpha1.PlatformMesh) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
+	key, caKey := authorizationWebhookSecretKeys(operatorCfg, inst)
 
 	// Retrieve the ca.crt from the rebac-authz-webhook-cert secret
-	caSecretName := operatorCfg.Subroutines.Deployment.AuthorizationWebhookSecretCAName
-	webhookCertSecret, err := GetSecret(r.clientRuntime, caSecretName, inst.Namespace)
+	webhookCertSecret, err := GetSecret(r.clientRuntime, caKey.Name, caKey.Namespace)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
-			log.Info().Str("name", caSecretName).Msg("Webhook secret does not exist")
-			return subroutines.StopWithRequeue(DefaultRequeueInterval, "Webhook secret does not exist"), nil
+			log.Info().Str("name", caKey.Name).Str("namespace", caKey.Namespace).Msg("Webhook secret does not exist")
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), "Webhook secret does not exist"), nil
 		}
-		log.Error().Err(err).Str("secret", caSecretName).Str("namespace", inst.Namespace).Msg("Failed to get webhook cert secret")
+		log.Error().Err(err).Str("secret", caKey.Name).Str("namespace", caKey.Namespace).Msg("Failed to get webhook cert secret")
 		return subroutines.OK(), err
 	}
 
 	caCrt, ok := webhookCertSecret.Data["ca.crt"]
 	if !ok || len(caCrt) == 0 {
-		err := fmt.Errorf("ca.crt not found or empty in secret %s/%s", inst.Namespace, caSecretName)
+		err := fmt.Errorf("ca.crt not found or empty in secret %s", caKey)
 		log.Error().Err(err).Msg("ca.crt missing from webhook cert secret")
 		return subroutines.OK(), err
 	}
 
 	// Get the kcp-webhook-secret
-	webhookSecret := operatorCfg.Subroutines.Deployment.AuthorizationWebhookSecretName
-	kcpWebhookSecret, err := GetSecret(r.clientRuntime, webhookSecret, inst.Namespace)
+	kcpWebhookSecret, err := GetSecret(r.clientRuntime, key.Name, key.Namespace)
 	if err != nil {
-		log.Error().Err(err).Str("secret", webhookSecret).Str("namespace", inst.Namespace).Msg("Failed to get kcp webhook secret")
+		log.Error().Err(err).Str("secret", key.Name).Str("namespace", key.Namespace).Msg("Failed to get kcp webhook secret")
 		return subroutines.OK(), err
 	}
 
 	// Get the kubeconfig from the secret
 	kubeconfigData, ok := kcpWebhookSecret.Data["kubeconfig"]
 	if !ok || len(kubeconfigData) == 0 {
-		err := fmt.Errorf("kubeconfig not found or empty in secret %s/%s", inst.Namespace, webhookSecret)
+		err := fmt.Errorf("kubeconfig not found or empty in secret %s", key)
 		log.Error().Err(err).Msg("kubeconfig missing from kcp webhook secret")
 		return subroutines.OK(), err
 	}
@@ -1217,6 +1666,9 @@ func (r *DeploymentSubroutine) updateKcpWebhookSecret(ctx context.Context, inst
 
 	// Update the secret with the new kubeconfig
 	kcpWebhookSecret.Data["kubeconfig"] = updatedKubeconfigData
+	setInstanceLabels(kcpWebhookSecret, inst)
+	LabelSecretPurpose(kcpWebhookSecret, SecretPurposeWebhook, "")
+	markSecretRotated(kcpWebhookSecret)
 
 	// Clear managedFields before applying with SSA (required for SSA)
 	kcpWebhookSecret.SetManagedFields(nil)
@@ -1224,11 +1676,11 @@ func (r *DeploymentSubroutine) updateKcpWebhookSecret(ctx context.Context, inst
 	// Apply the updated secret using SSA
 	err = r.clientRuntime.Patch(ctx, kcpWebhookSecret, client.Apply, client.FieldOwner(fieldManagerDeployment), client.ForceOwnership) //nolint:staticcheck // Apply via Patch is required for unstructured objects
 	if err != nil {
-		log.Error().Err(err).Str("secret", webhookSecret).Str("namespace", operatorCfg.KCP.Namespace).Msg("Failed to update kcp webhook secret")
+		log.Error().Err(err).Str("secret", key.Name).Str("namespace", key.Namespace).Msg("Failed to update kcp webhook secret")
 		return subroutines.OK(), err
 	}
 
-	log.Info().Str("secret", webhookSecret).Str("namespace", operatorCfg.KCP.Namespace).Msg("Successfully updated kcp webhook secret with new certificate-authority-data")
+	log.Info().Str("secret", key.Name).Str("namespace", key.Namespace).Msg("Successfully updated kcp webhook secret with new certificate-authority-data")
 
 	// Delete all kcp pods so they pick up the new webhook secret
 	log.Info().Msg("kcp-webhook-secret was updated, deleting kcp pods to pick up new certificate-authority-data")
@@ -1324,7 +1776,12 @@ func isCRDEstablished(ctx context.Context, c client.Client, name string) (bool,
 	return matchesConditionWithStatus(crd, "Established", "True"), nil
 }
 
-func (r *DeploymentSubroutine) hasIstioProxyInjected(ctx context.Context, labelSelector, namespace string) (bool, *unstructured.Unstructured, error) {
+// runsInCluster reports whether the operator runs as a pod of its Deployment, which it can restart.
+func (r *DeploymentSubroutine) runsInCluster() bool {
+	return !r.cfg.IsLocal && !r.cfgOperator.Dev.IsEnabled()
+}
+
+func (r *DeploymentSubroutine) hasIstioProxyInjected(ctx context.Context, labelSelector, namespace string) (bool, error) {
 	pods := &unstructured.UnstructuredList{}
 	pods.SetGroupVersionKind(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"})
 	err := r.clientInfra.List(ctx, pods, &client.ListOptions{
@@ -1333,7 +1790,7 @@ func (r *DeploymentSubroutine) hasIstioProxyInjected(ctx context.Context, labelS
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to list pods with label selector: " + labelSelector)
-		return false, nil, err
+		return false, err
 	}
 
 	if len(pods.Items) > 0 {
@@ -1349,7 +1806,7 @@ func (r *DeploymentSubroutine) hasIstioProxyInjected(ctx context.Context, labelS
 				log.Debug().Msgf("Container name: %s", name)
 				if name == "istio-proxy" {
 					log.Info().Msgf("Found Istio proxy container: %s", containerMap["image"])
-					return true, &pod, nil
+					return true, nil
 				}
 			}
 		}
@@ -1362,18 +1819,18 @@ func (r *DeploymentSubroutine) hasIstioProxyInjected(ctx context.Context, labelS
 				log.Debug().Msgf("Container name: %s", name)
 				if name == "istio-proxy" {
 					log.Info().Msgf("Found Istio proxy container: %s", containerMap["image"])
-					return true, &pod, nil
+					return true, nil
 				}
 			}
 		}
 		log.Info().Msgf("Istio proxy containers not found")
-		return false, &pod, nil
+		return false, nil
 	}
 
-	return false, nil, errors.New("pod not found")
+	return false, errors.New("pod not found")
 }
 
-func (r *DeploymentSubroutine) manageAuthorizationWebhookSecrets(ctx context.Context, inst *v1alpha1.PlatformMesh) (subroutines.Result, error) {
+func (r *DeploymentSubroutine) manageAuthorizationWebhookSecrets(ctx context.Context, inst *v1alpha1.PlatformMesh, status *stepStatus) (subroutines.Result, error) {
 	// Create Issuer
 	caIssuerPath := fmt.Sprintf("%s/rebac-auth-webhook/ca-issuer.yaml", r.workspaceDirectory)
 	err := r.ApplyManifestFromFileWithMergedValues(ctx, caIssuerPath, r.clientRuntime, map[string]any{})
@@ -1382,6 +1839,7 @@ func (r *DeploymentSubroutine) manageAuthorizationWebhookSecrets(ctx context.Con
 	}
 
 	// Create Certificate
+	status.enter("ApplyingCertificate")
 	certPath := fmt.Sprintf("%s/rebac-auth-webhook/webhook-cert.yaml", r.workspaceDirectory)
 	err = r.ApplyManifestFromFileWithMergedValues(ctx, certPath, r.clientRuntime, map[string]any{})
 	if err != nil {
@@ -1389,12 +1847,14 @@ func (r *DeploymentSubroutine) manageAuthorizationWebhookSecrets(ctx context.Con
 	}
 
 	// Prepare KCP Webhook secret
+	status.enter("CreatingKcpWebhookSecret")
 	oErr := r.createKCPWebhookSecret(ctx, inst)
 	if oErr != nil {
 		return subroutines.OK(), oErr
 	}
 
 	// Update KCP Webhook secret with the latest CA bundle
+	status.enter("UpdatingKcpWebhookSecret")
 	return r.updateKcpWebhookSecret(ctx, inst)
 }
 
@@ -1406,8 +1866,7 @@ func applyManifestFromFileWithMergedValues(ctx context.Context, path string, k8s
 		return err
 	}
 
-	err = k8sClient.Patch(ctx, &obj, client.Apply, client.FieldOwner(fieldManagerDeployment), client.ForceOwnership) //nolint:staticcheck // Apply via Patch is required for unstructured objects
-	if err != nil {
+	if err := applyManifest(ctx, k8sClient, &obj, fieldManagerDeployment); err != nil {
 		return errors.Wrap(err, "Failed to apply manifest file: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
 	}
 	return nil
@@ -6,12 +6,17 @@ import (
 	"testing"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/stretchr/testify/suite"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
@@ -738,8 +743,12 @@ func (s *TemplateVarsTestSuite) Test_buildRuntimeTemplateVars_MissingConfigMap()
 	}
 	inst := &v1alpha1.PlatformMesh{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-pm", Namespace: "test-ns"},
+		Spec: v1alpha1.PlatformMeshSpec{
+			ProfileConfigMap: &v1alpha1.ConfigMapReference{Name: "custom-profile"},
+		},
 	}
 
+	// Only the default profile ConfigMap is created when missing.
 	_, err := sub.buildRuntimeTemplateVars(context.Background(), inst, apiextensionsv1.JSON{})
 	s.Error(err, "expected error when profile ConfigMap is missing")
 }
@@ -930,6 +939,61 @@ func (s *DeploymentFuncsTestSuite) Test_loadProfileSections_MissingConfigMap() {
 	s.Require().Error(err)
 }
 
+func (s *DeploymentFuncsTestSuite) Test_loadProfileSections_Channel() {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	profileYAML := `infra:
+  deploymentTechnology: fluxcd
+components:
+  services:
+    keycloak:
+      version: 1.0.0
+    iam:
+      version: 2.0.0
+channels:
+  edge:
+    infra:
+      deploymentTechnology: argocd
+    components:
+      services:
+        keycloak:
+          version: 1.1.0-rc.1
+`
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-profile", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"profile.yaml": profileYAML},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	sub := &DeploymentSubroutine{clientRuntime: cl}
+
+	inst := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"},
+	}
+
+	infraYAML, componentsYAML, err := sub.loadProfileSections(context.Background(), inst)
+	s.Require().NoError(err)
+	s.Contains(infraYAML, "fluxcd")
+	s.Contains(componentsYAML, "version: 1.0.0")
+	s.NotContains(componentsYAML, "rc.1")
+
+	inst.Spec.Channel = "edge"
+	infraYAML, componentsYAML, err = sub.loadProfileSections(context.Background(), inst)
+	s.Require().NoError(err)
+	s.Contains(infraYAML, "argocd")
+	s.Contains(componentsYAML, "version: 1.1.0-rc.1")
+	s.Contains(componentsYAML, "version: 2.0.0")
+
+	tech, err := GetDeploymentTechnologyFromProfile(context.Background(), cl, inst)
+	s.Require().NoError(err)
+	s.Equal("argocd", tech)
+
+	inst.Spec.Channel = "beta"
+	_, _, err = sub.loadProfileSections(context.Background(), inst)
+	s.Require().EqualError(err, `profile has no section for channel "beta", available channels: [edge]`)
+}
+
 func (s *DeploymentFuncsTestSuite) Test_loadProfileSections_CustomConfigMapRef() {
 	scheme := runtime.NewScheme()
 	_ = clientgoscheme.AddToScheme(scheme)
@@ -954,3 +1018,92 @@ func (s *DeploymentFuncsTestSuite) Test_loadProfileSections_CustomConfigMapRef()
 	s.Contains(infraYAML, "enabled")
 	s.Contains(componentsYAML, "svc")
 }
+
+func (s *DeploymentFuncsTestSuite) Test_loadProfileSections_ProfileHash() {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "custom-profile", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"profile.yaml": "infra:\n  enabled: true\ncomponents:\n  svc: true\n"},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	sub := &DeploymentSubroutine{clientRuntime: cl}
+	inst := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"},
+		Spec:       v1alpha1.PlatformMeshSpec{ProfileConfigMap: &v1alpha1.ConfigMapReference{Name: "custom-profile"}},
+	}
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+
+	// The first render records the hash without an event.
+	_, _, err := sub.loadProfileSections(ctx, inst)
+	s.Require().NoError(err)
+	s.Equal(profileChecksum(cm.Data["profile.yaml"]), inst.Status.ProfileHash)
+	s.Empty(rec.Events)
+
+	_, _, err = sub.loadProfileSections(ctx, inst)
+	s.Require().NoError(err)
+	s.Empty(rec.Events)
+
+	cm.Data["profile.yaml"] = "infra:\n  enabled: false\ncomponents:\n  svc: true\n"
+	s.Require().NoError(cl.Update(ctx, cm))
+	_, _, err = sub.loadProfileSections(ctx, inst)
+	s.Require().NoError(err)
+	s.Equal(profileChecksum(cm.Data["profile.yaml"]), inst.Status.ProfileHash)
+	s.Require().Len(rec.Events, 1)
+	s.Equal("Normal ProfileChanged Profile ConfigMap platform-mesh-system/custom-profile changed, re-rendering components", <-rec.Events)
+}
+
+func (s *DeploymentFuncsTestSuite) Test_authorizationWebhookSecretKeys() {
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"}}
+	operatorCfg := config.NewOperatorConfig()
+	operatorCfg.KCP.Namespace = "kcp-system"
+
+	kubeconfig, ca := authorizationWebhookSecretKeys(operatorCfg, inst)
+	s.Equal(types.NamespacedName{Name: "kcp-webhook-secret", Namespace: "kcp-system"}, kubeconfig)
+	s.Equal(types.NamespacedName{Name: "rebac-authz-webhook-cert", Namespace: "platform-mesh-system"}, ca)
+
+	operatorCfg.Subroutines.Deployment.AuthorizationWebhookSecretNamespace = "webhooks"
+	operatorCfg.Subroutines.Deployment.AuthorizationWebhookSecretCANamespace = "certs"
+	kubeconfig, ca = authorizationWebhookSecretKeys(operatorCfg, inst)
+	s.Equal("webhooks", kubeconfig.Namespace)
+	s.Equal("certs", ca.Namespace)
+}
+
+func (s *DeploymentFuncsTestSuite) Test_createKCPWebhookSecret_MigratesLegacySecret() {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	legacy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "kcp-webhook-secret", Namespace: "platform-mesh-system"},
+		Data:       map[string][]byte{"kubeconfig": []byte("kubeconfig-with-ca")},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(legacy).Build()
+	sub := &DeploymentSubroutine{clientRuntime: cl}
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"}}
+
+	operatorCfg := config.NewOperatorConfig()
+	operatorCfg.KCP.Namespace = "kcp-system"
+	log, err := logger.New(logger.DefaultConfig())
+	s.Require().NoError(err)
+	rec := events.NewFakeRecorder(10)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	ctx = WithEventRecorder(context.WithValue(ctx, keys.ConfigCtxKey, operatorCfg), rec)
+
+	s.Require().NoError(sub.createKCPWebhookSecret(ctx, inst))
+
+	migrated := &corev1.Secret{}
+	s.Require().NoError(cl.Get(ctx, types.NamespacedName{Name: "kcp-webhook-secret", Namespace: "kcp-system"}, migrated))
+	s.Equal("kubeconfig-with-ca", string(migrated.Data["kubeconfig"]))
+	err = cl.Get(ctx, types.NamespacedName{Name: "kcp-webhook-secret", Namespace: "platform-mesh-system"}, &corev1.Secret{})
+	s.True(kerrors.IsNotFound(err))
+	s.Require().Len(rec.Events, 1)
+	s.Equal("Normal WebhookSecretMigrated Moved kcp webhook secret kcp-webhook-secret from namespace platform-mesh-system to kcp-system", <-rec.Events)
+
+	// Once moved, later runs leave the secret alone.
+	s.Require().NoError(sub.createKCPWebhookSecret(ctx, inst))
+	s.Empty(rec.Events)
+}
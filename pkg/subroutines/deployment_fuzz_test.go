@@ -0,0 +1,164 @@
+package subroutines
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// FuzzMergeValuesAndServices merges arbitrary spec.values over arbitrary
+// rendered profile values. mergeValuesAndServices must never panic and the
+// result must satisfy the invariants checked by checkValuesMergeInvariants.
+func FuzzMergeValuesAndServices(f *testing.F) {
+	seeds := [][2]string{
+		{`{}`, ``},
+		{`null`, `null`},
+		{`{}`, `{}`},
+		{`{"baseDomain":"example.com","services":{"iam":{"enabled":true}}}`, `{"iam":{"enabled":false},"portal":{"enabled":true}}`},
+		{`{"services":{"iam":{"enabled":true}}}`, `{"services":{"iam":{"enabled":false}}}`},
+		{`{"services":"not a map"}`, `{"a":1}`},
+		{`{"services":{"a":{"b":1}}}`, `{"a":"x"}`},
+		{`{"services":{"a":"x"}}`, `{"a":{"b":1}}`},
+		{`{"services":{"a":{"env":[{"name":"A"}]}},"listMergeStrategies":{"services.*.env":"append"}}`, `{"a":{"env":[{"name":"B"}]}}`},
+		{`{"listMergeStrategies":{"services.a":"merge-by-key"}}`, `{}`},
+		{`{"services":{"a":{"host":"old"}}}`, `{"a":{"host":"{{ .baseDomain }}"}}`},
+		{`{}`, `[]`},
+	}
+	for _, s := range seeds {
+		f.Add([]byte(s[0]), []byte(s[1]))
+	}
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, valuesRaw, specValuesRaw []byte) {
+		var values map[string]interface{}
+		if json.Unmarshal(valuesRaw, &values) != nil {
+			return
+		}
+		checkValuesMergeInvariants(t, values, specValuesRaw, log)
+	})
+}
+
+// TestMergeValuesAndServicesInvariants checks the merge invariants on hand
+// picked edge cases so they run on every test run and not only while fuzzing.
+func TestMergeValuesAndServicesInvariants(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+
+	cases := []struct{ values, specValues string }{
+		{`{}`, ``},
+		{`{"services":{"iam":{"enabled":true,"replicas":2}}}`, `{"iam":{"enabled":false}}`},
+		{`{"services":{"iam":{"enabled":true}}}`, `{"services":{"iam":{"enabled":false},"portal":{"enabled":true}}}`},
+		{`{"services":"not a map"}`, `{"a":{"b":1}}`},
+		{`{"services":{"a":{"b":{"c":1,"d":[1]},"e":true}}}`, `{"a":{"b":{"c":2,"d":[]}}}`},
+		{`{"services":{"a":{"b":1}}}`, `{"a":null}`},
+	}
+	for _, tc := range cases {
+		var values map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(tc.values), &values))
+		checkValuesMergeInvariants(t, values, []byte(tc.specValues), log)
+	}
+}
+
+func TestMergeValuesAndServicesRendersSpecValues(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	values := map[string]interface{}{"services": map[string]interface{}{
+		"portal": map[string]interface{}{"host": "old", "enabled": true},
+	}}
+
+	merged, err := mergeValuesAndServices(values, apiextensionsv1.JSON{Raw: []byte(`{"portal":{"host":"portal.{{ .Values.baseDomain }}"}}`)},
+		map[string]interface{}{"baseDomain": "example.com"}, log)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "portal.example.com", "enabled": true}, merged["portal"])
+}
+
+// checkValuesMergeInvariants merges specValuesRaw over values and checks that
+//   - values is not modified,
+//   - every value of the spec.values services that is not an object ends up
+//     in the result,
+//   - every profile service value that spec.values does not replace ends up in
+//     the result,
+//   - merging the same spec.values over the result again changes nothing.
+//
+// Precedence and idempotence are not checked for templated spec.values, which
+// render to other values, nor with listMergeStrategies, since appending lists
+// is not idempotent.
+func checkValuesMergeInvariants(t *testing.T, values map[string]interface{}, specValuesRaw []byte, log *logger.Logger) {
+	t.Helper()
+	valuesBefore := normalizeJSON(t, values)
+	specValues := apiextensionsv1.JSON{Raw: specValuesRaw}
+
+	merged, err := mergeValuesAndServices(values, specValues, map[string]interface{}{"baseDomain": "example.com"}, log)
+	if err != nil {
+		return
+	}
+	require.NotNil(t, merged)
+	assert.Equal(t, valuesBefore, normalizeJSON(t, values), "values was modified")
+
+	if _, ok := values["listMergeStrategies"]; ok || strings.Contains(string(specValuesRaw), "{{") {
+		return
+	}
+	base, _ := values["services"].(map[string]interface{})
+	checkServicesPrecedence(t, merged, base, specServicesOf(t, specValuesRaw), "")
+
+	again, err := mergeValuesAndServices(map[string]interface{}{"services": merged}, specValues, nil, log)
+	require.NoError(t, err)
+	assert.Equal(t, normalizeJSON(t, merged), normalizeJSON(t, again), "merge is not idempotent")
+}
+
+// specServicesOf returns the services of spec.values the way
+// mergeValuesAndServices reads them.
+func specServicesOf(t *testing.T, raw []byte) map[string]interface{} {
+	t.Helper()
+	if len(raw) == 0 {
+		return nil
+	}
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &spec))
+	if services, ok := spec["services"].(map[string]interface{}); ok {
+		return services
+	}
+	return spec
+}
+
+// checkServicesPrecedence walks base and spec below path alongside merged.
+func checkServicesPrecedence(t *testing.T, merged, base, spec map[string]interface{}, path string) {
+	t.Helper()
+	for key, sv := range spec {
+		mv, ok := merged[key]
+		require.True(t, ok, "%s%s of spec.values is missing", path, key)
+		svObj, isObj := sv.(map[string]interface{})
+		if !isObj {
+			assert.Equal(t, sv, mv, "%s%s of spec.values does not take precedence", path, key)
+			continue
+		}
+		mvObj, isObj := mv.(map[string]interface{})
+		require.True(t, isObj, "%s%s is no longer an object", path, key)
+		baseObj, _ := base[key].(map[string]interface{})
+		checkServicesPrecedence(t, mvObj, baseObj, svObj, path+key+".")
+	}
+	for key, bv := range base {
+		if _, replaced := spec[key]; replaced {
+			continue
+		}
+		assert.Equal(t, bv, merged[key], "%s%s of the profile is lost", path, key)
+	}
+}
+
+// normalizeJSON returns v in its JSON form, so results built from Go values
+// and from JSON compare equal.
+func normalizeJSON(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out interface{}
+	require.NoError(t, json.Unmarshal(raw, &out))
+	return out
+}
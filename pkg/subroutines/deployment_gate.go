@@ -0,0 +1,119 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+const (
+	DeploymentGateSubroutineName = "DeploymentGateSubroutine"
+
+	deploymentGateRequeueInterval = 30 * time.Second
+)
+
+// DeploymentGateSubroutine runs first in the kcp controller when the kcp and
+// deployment halves of the reconciliation run in separate pods. In a single
+// controller the Deployment subroutine runs before the kcp setup; split, the
+// kcp controller waits for the DeploymentReady condition the deployment
+// controller reports for the current generation instead.
+type DeploymentGateSubroutine struct{}
+
+func NewDeploymentGateSubroutine() *DeploymentGateSubroutine {
+	return &DeploymentGateSubroutine{}
+}
+
+func (r *DeploymentGateSubroutine) GetName() string {
+	return DeploymentGateSubroutineName
+}
+
+func (r *DeploymentGateSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *DeploymentGateSubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *DeploymentGateSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), "success").Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+
+	if msg := deploymentGateBlocked(inst); msg != "" {
+		logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName()).Info().Msg(msg)
+		return subroutines.StopWithRequeue(deploymentGateRequeueInterval, msg), nil
+	}
+	return subroutines.OK(), nil
+}
+
+// deploymentGateBlocked returns why the kcp setup of inst has to wait for the
+// deployment controller, or "" once DeploymentReady is True for the current
+// generation.
+func deploymentGateBlocked(inst *corev1alpha1.PlatformMesh) string {
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, DeploymentReadyConditionType)
+	switch {
+	case cond == nil:
+		return fmt.Sprintf("waiting for the deployment controller to report %s", DeploymentReadyConditionType)
+	case cond.ObservedGeneration < inst.Generation:
+		return fmt.Sprintf("waiting for the deployment controller to deploy generation %d, %s reports generation %d",
+			inst.Generation, DeploymentReadyConditionType, cond.ObservedGeneration)
+	case cond.Status != metav1.ConditionTrue:
+		return fmt.Sprintf("waiting for the deployment controller, %s is %s: %s", DeploymentReadyConditionType, cond.Status, cond.Reason)
+	}
+	return ""
+}
+
+// WithoutFinalizers returns sub running only its Process. Split, the
+// deployment controller runs its subroutines this way, so only the kcp
+// controller adds and removes finalizers, see FinalizerOnly.
+func WithoutFinalizers(sub subroutines.Subroutine) subroutines.Subroutine {
+	return &processOnly{sub: sub}
+}
+
+// FinalizerOnly returns sub running only its Finalize and holding its
+// finalizers. Split, the kcp controller runs the finalizing subroutines of the
+// deployment half this way.
+func FinalizerOnly(sub subroutines.Subroutine) subroutines.Subroutine {
+	return &finalizeOnly{sub: sub}
+}
+
+type processOnly struct {
+	sub subroutines.Subroutine
+}
+
+func (p *processOnly) GetName() string {
+	return p.sub.GetName()
+}
+
+func (p *processOnly) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return p.sub.(subroutines.Processor).Process(ctx, obj)
+}
+
+type finalizeOnly struct {
+	sub subroutines.Subroutine
+}
+
+func (f *finalizeOnly) GetName() string {
+	return f.sub.GetName()
+}
+
+func (f *finalizeOnly) Finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	return f.sub.(subroutines.Finalizer).Finalize(ctx, obj)
+}
+
+func (f *finalizeOnly) Finalizers(obj client.Object) []string {
+	return f.sub.(subroutines.Finalizer).Finalizers(obj)
+}
@@ -0,0 +1,57 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestDeploymentGateSubroutine(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	sub := NewDeploymentGateSubroutine()
+	assert.Equal(t, DeploymentGateSubroutineName, sub.GetName())
+
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+	setReady := func(status metav1.ConditionStatus, generation int64) {
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type: DeploymentReadyConditionType, Status: status, Reason: "RenderingInfraTemplates", ObservedGeneration: generation,
+		})
+	}
+
+	res, err := sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsStopWithRequeue())
+	assert.Contains(t, res.Message(), "to report DeploymentReady")
+
+	setReady(metav1.ConditionTrue, 1)
+	res, err = sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsStopWithRequeue())
+	assert.Contains(t, res.Message(), "generation 2")
+
+	setReady(metav1.ConditionFalse, 2)
+	res, err = sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsStopWithRequeue())
+	assert.Contains(t, res.Message(), "RenderingInfraTemplates")
+
+	setReady(metav1.ConditionTrue, 2)
+	res, err = sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, subroutines.OK(), res)
+
+	res, err = sub.Finalize(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, subroutines.OK(), res)
+}
@@ -3,21 +3,35 @@ package subroutines
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	stderrors "errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/platform-mesh/golang-commons/errors"
 	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/merge"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/validate"
 )
 
 var argoApplicationGVK = schema.GroupVersionKind{
@@ -45,76 +59,157 @@ func updateObjectMetadata(existing, desired *unstructured.Unstructured) {
 	}
 }
 
+// renderedManifest is an object rendered from the template at path. component
+// is the service it was rendered for, if the templates are rendered per service.
+type renderedManifest struct {
+	path      string
+	component string
+	obj       *unstructured.Unstructured
+}
+
 // renderAndApplyTemplates renders and applies all YAML templates in a directory.
 // skipFile, if non-nil, is called for each file; returning true skips that file.
 // postProcessObj, if non-nil, is called on each rendered object before applying.
+// Cluster-scoped objects are claimed through claims before they are applied,
+// and applied objects are recorded in inv.
+// Nothing is applied unless every template renders and passes validation.
+// It returns the number of applied manifests.
 func (r *DeploymentSubroutine) renderAndApplyTemplates(
 	ctx context.Context,
 	dir string,
 	tmplVars map[string]interface{},
 	k8sClient client.Client,
+	claims *SharedObjectClaims,
+	inv *inventory,
 	log *logger.Logger,
 	templateType string,
 	skipFile func(fileName string) bool,
 	postProcessObj func(ctx context.Context, obj *unstructured.Unstructured) error,
-) error {
-	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+) (int, error) {
+	applied, err := r.renderValidateAndApply(ctx, dir, tmplVars, k8sClient, log, templateType, skipFile, inv.recording(applyClaimed(k8sClient, claims)), postProcessObj)
 
-		if d.IsDir() || !strings.HasSuffix(d.Name(), ".yaml") {
-			return nil
-		}
+	if err != nil {
+		log.Error().Err(err).Str("type", templateType).Msg("Failed to render and apply templates")
+		return applied, err
+	}
 
-		if skipFile != nil && skipFile(d.Name()) {
-			return nil
-		}
+	return applied, nil
+}
 
-		// Read and render template (supports multi-document YAML)
-		objs, err := r.renderTemplateFile(path, tmplVars, log)
-		if err != nil {
-			return errors.Wrap(err, "Failed to render template: %s", path)
+// applyClaimed returns an apply function that claims each manifest through
+// claims and applies it to k8sClient.
+func applyClaimed(k8sClient client.Client, claims *SharedObjectClaims) func(ctx context.Context, m renderedManifest) error {
+	return func(ctx context.Context, m renderedManifest) error {
+		if err := claims.claim(ctx, k8sClient, m.obj, ""); err != nil {
+			return err
 		}
-
-		for _, obj := range objs {
-			if postProcessObj != nil {
-				if err := postProcessObj(ctx, obj); err != nil {
-					if stderrors.Is(err, errSkipObject) {
-						continue
-					}
-					return errors.Wrap(err, "Failed to post-process rendered object from template: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
-				}
-			}
-
-			// Apply the rendered manifest
-			if err := k8sClient.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManagerDeployment), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
-				return errors.Wrap(err, "Failed to apply rendered manifest from template: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
-			}
+		// Apply the rendered manifest
+		if err := applyManifest(ctx, k8sClient, m.obj, fieldManagerDeployment); err != nil {
+			return errors.Wrap(err, "Failed to apply rendered manifest from template: %s (%s/%s)", m.path, m.obj.GetKind(), m.obj.GetName())
 		}
+		return nil
+	}
+}
 
+// renderAndApplyTemplatesWithRouter is like renderAndApplyTemplates but instead of applying to a
+// single client it delegates the Apply to applyFunc, which can route each object to a different client.
+// Checksum template functions are resolved against the infra cluster.
+func (r *DeploymentSubroutine) renderAndApplyTemplatesWithRouter(
+	ctx context.Context,
+	dir string,
+	tmplVars map[string]interface{},
+	log *logger.Logger,
+	templateType string,
+	skipFile func(fileName string) bool,
+	applyFunc func(ctx context.Context, obj *unstructured.Unstructured) error,
+) (int, error) {
+	applied, err := r.renderValidateAndApply(ctx, dir, tmplVars, r.clientInfra, log, templateType, skipFile, func(ctx context.Context, m renderedManifest) error {
+		if err := applyFunc(ctx, m.obj); err != nil {
+			return errors.Wrap(err, "Failed to apply rendered manifest from template: %s (%s/%s)", m.path, m.obj.GetKind(), m.obj.GetName())
+		}
 		return nil
-	})
+	}, nil)
 
 	if err != nil {
 		log.Error().Err(err).Str("type", templateType).Msg("Failed to render and apply templates")
-		return err
+		return applied, err
 	}
 
-	return nil
+	return applied, nil
 }
 
-// renderAndApplyTemplatesWithRouter is like renderAndApplyTemplates but instead of applying to a
-// single client it delegates the Apply to applyFunc, which can route each object to a different client.
-func (r *DeploymentSubroutine) renderAndApplyTemplatesWithRouter(
+// renderValidateAndApply renders every template in dir, runs the configured validators on the
+// complete result and only then hands each object to apply, so a policy violation never leaves
+// a partially applied pass behind. lookup is the cluster the checksum template functions read from.
+func (r *DeploymentSubroutine) renderValidateAndApply(
 	ctx context.Context,
 	dir string,
 	tmplVars map[string]interface{},
+	lookup client.Client,
 	log *logger.Logger,
 	templateType string,
 	skipFile func(fileName string) bool,
-	applyFunc func(ctx context.Context, obj *unstructured.Unstructured) error,
-) error {
+	apply func(ctx context.Context, m renderedManifest) error,
+	postProcessObj func(ctx context.Context, obj *unstructured.Unstructured) error,
+) (int, error) {
+	manifests, err := r.renderTemplatesDir(ctx, dir, tmplVars, lookup, log, skipFile, postProcessObj)
+	if err != nil {
+		return 0, err
+	}
+	return r.validateAndApply(ctx, manifests, templateType, apply)
+}
+
+// validateAndApply checks manifests of well-known kinds, runs the configured
+// validators on manifests and hands each object to apply once all of them passed.
+// It returns the number of manifests handed to apply without error.
+func (r *DeploymentSubroutine) validateAndApply(
+	ctx context.Context,
+	manifests []renderedManifest,
+	templateType string,
+	apply func(ctx context.Context, m renderedManifest) error,
+) (int, error) {
+	if err := validateTypedManifests(manifests); err != nil {
+		return 0, errors.Wrap(err, "Rendered %s manifests failed validation", templateType)
+	}
+	if len(r.validators) > 0 {
+		objs := make([]*unstructured.Unstructured, 0, len(manifests))
+		for _, m := range manifests {
+			objs = append(objs, m.obj)
+		}
+		if err := validate.Run(ctx, r.validators, objs); err != nil {
+			return 0, errors.Wrap(err, "Rendered %s manifests failed validation", templateType)
+		}
+	}
+
+	rec := renderRecordFrom(ctx)
+	if err := rec.rendered(templateType, manifests); err != nil {
+		return 0, err
+	}
+	for i, m := range manifests {
+		err := apply(ctx, m)
+		rec.result(templateType, m, err)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(manifests), nil
+}
+
+// renderTemplatesDir renders all YAML templates in dir and returns the post-processed objects
+// in walk order. Objects for which postProcessObj returns errSkipObject are dropped.
+// Templates whose contract header requires inputs missing in tmplVars fail the
+// whole dir with the missing inputs of all of them.
+func (r *DeploymentSubroutine) renderTemplatesDir(
+	ctx context.Context,
+	dir string,
+	tmplVars map[string]interface{},
+	lookup client.Client,
+	log *logger.Logger,
+	skipFile func(fileName string) bool,
+	postProcessObj func(ctx context.Context, obj *unstructured.Unstructured) error,
+) ([]renderedManifest, error) {
+	var manifests []renderedManifest
+	var contractErrs []string
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -128,45 +223,66 @@ func (r *DeploymentSubroutine) renderAndApplyTemplatesWithRouter(
 			return nil
 		}
 
-		objs, err := r.renderTemplateFile(path, tmplVars, log)
+		// Read and render template (supports multi-document YAML)
+		objs, err := r.renderTemplateFile(ctx, path, tmplVars, lookup, log)
+		var contractErr *templateContractError
+		if stderrors.As(err, &contractErr) {
+			contractErrs = append(contractErrs, contractErr.Error())
+			return nil
+		}
 		if err != nil {
 			return errors.Wrap(err, "Failed to render template: %s", path)
 		}
 
 		for _, obj := range objs {
-			if err := applyFunc(ctx, obj); err != nil {
-				return errors.Wrap(err, "Failed to apply rendered manifest from template: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
+			if postProcessObj != nil {
+				if err := postProcessObj(ctx, obj); err != nil {
+					if stderrors.Is(err, errSkipObject) {
+						continue
+					}
+					return errors.Wrap(err, "Failed to post-process rendered object from template: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
+				}
 			}
+			manifests = append(manifests, renderedManifest{path: path, obj: obj})
 		}
 
 		return nil
 	})
-
-	if err != nil {
-		log.Error().Err(err).Str("type", templateType).Msg("Failed to render and apply templates")
-		return err
+	if err == nil && len(contractErrs) > 0 {
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryTemplate, errors.New("Template inputs do not match the template contracts: %s", strings.Join(contractErrs, "; ")))
 	}
-
-	return nil
+	return manifests, err
 }
 
 // renderTemplateFile reads a template file, renders it, and returns all unstructured objects.
 // Supports multi-document YAML (documents separated by "---").
 // Returns an empty slice if the template renders empty.
-func (r *DeploymentSubroutine) renderTemplateFile(path string, tmplVars map[string]interface{}, log *logger.Logger) ([]*unstructured.Unstructured, error) {
+// The checksum functions read Secrets and ConfigMaps from lookup, the lookup
+// functions always read from the runtime cluster.
+func (r *DeploymentSubroutine) renderTemplateFile(
+	ctx context.Context, path string, tmplVars map[string]interface{}, lookup client.Client, log *logger.Logger,
+) ([]*unstructured.Unstructured, error) {
 	templateBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to read template file")
 	}
+	if err := checkTemplateContract(path, templateBytes, tmplVars); err != nil {
+		return nil, err
+	}
 
-	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncMap()).Parse(string(templateBytes))
+	releaseNamespace, _ := tmplVars["releaseNamespace"].(string)
+	tmpl, err := template.New(filepath.Base(path)).
+		Funcs(templateFuncMap()).
+		Funcs(checksumFuncMap(ctx, lookup)).
+		Funcs(lookupFuncMap(ctx, r.clientRuntime, releaseNamespace, r.lookupNamespaces())).
+		Parse(string(templateBytes))
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to parse template")
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to parse template"))
 	}
 
 	var rendered bytes.Buffer
 	if err := tmpl.Execute(&rendered, tmplVars); err != nil {
-		return nil, errors.Wrap(err, "Failed to execute template")
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryTemplate, errors.Wrap(err, "Failed to execute template"))
 	}
 
 	renderedStr := strings.TrimSpace(rendered.String())
@@ -210,6 +326,19 @@ func isZeroValue(v interface{}) bool {
 	return rv.IsZero()
 }
 
+// profileListStrategies reads the listMergeStrategies of a profile section.
+func profileListStrategies(section map[string]interface{}) (merge.ListStrategies, error) {
+	raw, ok := section["listMergeStrategies"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	strategies, err := merge.ParseListStrategies(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse listMergeStrategies from profile")
+	}
+	return strategies, nil
+}
+
 func templateFuncMap() template.FuncMap {
 	return template.FuncMap{
 		"default": func(d, v interface{}) interface{} {
@@ -265,7 +394,284 @@ func templateFuncMap() template.FuncMap {
 		"not": func(v interface{}) bool {
 			return isZeroValue(v)
 		},
+		"indent": func(spaces int, s string) string {
+			pad := strings.Repeat(" ", spaces)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"fromYaml": func(s string) (map[string]interface{}, error) {
+			m := map[string]interface{}{}
+			err := yaml.Unmarshal([]byte(s), &m)
+			return m, err
+		},
+		"toJson": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"b64dec": func(s string) (string, error) {
+			b, err := base64.StdEncoding.DecodeString(s)
+			return string(b), err
+		},
+		"ternary": func(vt, vf interface{}, cond bool) interface{} {
+			if cond {
+				return vt
+			}
+			return vf
+		},
+		"required": func(msg string, v interface{}) (interface{}, error) {
+			if isZeroValue(v) {
+				return nil, stderrors.New(msg)
+			}
+			return v, nil
+		},
+		"quote": func(v interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(v))
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(oldStr, newStr, s string) string { return strings.ReplaceAll(s, oldStr, newStr) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join": func(sep string, v interface{}) string {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				return fmt.Sprint(v)
+			}
+			parts := make([]string, rv.Len())
+			for i := range parts {
+				parts[i] = fmt.Sprint(rv.Index(i).Interface())
+			}
+			return strings.Join(parts, sep)
+		},
+		"list": func(v ...interface{}) []interface{} {
+			return v
+		},
+		"dict": func(kv ...interface{}) (map[string]interface{}, error) {
+			if len(kv)%2 != 0 {
+				return nil, stderrors.New("dict requires an even number of arguments")
+			}
+			m := make(map[string]interface{}, len(kv)/2)
+			for i := 0; i < len(kv); i += 2 {
+				m[fmt.Sprint(kv[i])] = kv[i+1]
+			}
+			return m, nil
+		},
+		"hasKey": func(m map[string]interface{}, key string) bool {
+			_, ok := m[key]
+			return ok
+		},
+		"sha256sum": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+}
+
+// lookupFuncMap returns the lookupSecret, lookupSecretKey, lookupConfigMap
+// and lookupService template functions. They read one object from lookup.
+// Lookups are read-only and limited to namespace, the PlatformMesh namespace,
+// and the allowed namespaces, so templates cannot read credentials of other
+// tenants. Objects are read once per lookupCache in ctx.
+func lookupFuncMap(ctx context.Context, lookup client.Client, namespace string, allowed []string) template.FuncMap {
+	cache := lookupCacheFrom(ctx)
+	get := func(fn, ns, name string, obj client.Object) (bool, error) {
+		if lookup == nil {
+			return false, fmt.Errorf("%s %s/%s: no cluster to resolve against", fn, ns, name)
+		}
+		if ns != namespace && !slices.Contains(allowed, ns) {
+			return false, fmt.Errorf("%s %s/%s: lookups are limited to namespace %q and the allowed lookup namespaces", fn, ns, name, namespace)
+		}
+		if err := cache.get(ctx, lookup, types.NamespacedName{Namespace: ns, Name: name}, obj); err != nil {
+			if kerrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, errors.Wrap(err, "%s %s/%s", fn, ns, name)
+		}
+		return true, nil
+	}
+	return template.FuncMap{
+		"lookupSecret": func(ns, name, key string) (string, error) {
+			secret := &corev1.Secret{}
+			if _, err := get("lookupSecret", ns, name, secret); err != nil {
+				return "", err
+			}
+			return string(secret.Data[key]), nil
+		},
+		// lookupSecretKey fails rendering while the Secret or its key does not
+		// exist, e.g. a password not generated yet, instead of rendering an
+		// empty value.
+		"lookupSecretKey": func(ns, name, key string) (string, error) {
+			secret := &corev1.Secret{}
+			found, err := get("lookupSecretKey", ns, name, secret)
+			if err != nil {
+				return "", err
+			}
+			if !found {
+				return "", fmt.Errorf("lookupSecretKey %s/%s: secret not found", ns, name)
+			}
+			value, ok := secret.Data[key]
+			if !ok {
+				return "", fmt.Errorf("lookupSecretKey %s/%s: key %q not found", ns, name, key)
+			}
+			return string(value), nil
+		},
+		"lookupConfigMap": func(ns, name, key string) (string, error) {
+			configMap := &corev1.ConfigMap{}
+			if _, err := get("lookupConfigMap", ns, name, configMap); err != nil {
+				return "", err
+			}
+			if v, ok := configMap.Data[key]; ok {
+				return v, nil
+			}
+			return string(configMap.BinaryData[key]), nil
+		},
+		// lookupService returns the clusterIP, the loadBalancerIP and
+		// loadBalancerHostname of the first ingress, and the ports by name of
+		// a Service. All fields are empty if it does not exist.
+		"lookupService": func(ns, name string) (map[string]interface{}, error) {
+			svc := &corev1.Service{}
+			if _, err := get("lookupService", ns, name, svc); err != nil {
+				return nil, err
+			}
+			ports := map[string]interface{}{}
+			for _, p := range svc.Spec.Ports {
+				ports[p.Name] = p.Port
+			}
+			result := map[string]interface{}{
+				"clusterIP":            svc.Spec.ClusterIP,
+				"loadBalancerIP":       "",
+				"loadBalancerHostname": "",
+				"ports":                ports,
+			}
+			if ingress := svc.Status.LoadBalancer.Ingress; len(ingress) > 0 {
+				result["loadBalancerIP"] = ingress[0].IP
+				result["loadBalancerHostname"] = ingress[0].Hostname
+			}
+			return result, nil
+		},
+	}
+}
+
+// lookupNamespaces returns the namespaces the lookup template functions may
+// read from in addition to the PlatformMesh namespace.
+func (r *DeploymentSubroutine) lookupNamespaces() []string {
+	if r.cfgOperator == nil {
+		return nil
+	}
+	return r.cfgOperator.Subroutines.Deployment.LookupNamespaces
+}
+
+// lookupCache keeps the objects read by the lookup template functions, so
+// that every object is read once while the templates of a reconcile are
+// rendered. A nil *lookupCache reads through.
+type lookupCache struct {
+	mu      sync.Mutex
+	entries map[string]lookupCacheEntry
+}
+
+type lookupCacheEntry struct {
+	obj client.Object
+	err error
+}
+
+type lookupCacheKey struct{}
+
+// withLookupCache returns ctx carrying an empty lookupCache.
+func withLookupCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lookupCacheKey{}, &lookupCache{entries: map[string]lookupCacheEntry{}})
+}
+
+func lookupCacheFrom(ctx context.Context) *lookupCache {
+	c, _ := ctx.Value(lookupCacheKey{}).(*lookupCache)
+	return c
+}
+
+// get reads the object key into obj, from the cache if it was read before.
+// Errors, including NotFound, are cached as well.
+func (c *lookupCache) get(ctx context.Context, cl client.Client, key types.NamespacedName, obj client.Object) error {
+	if c == nil {
+		return cl.Get(ctx, key, obj)
+	}
+	cacheKey := fmt.Sprintf("%T/%s", obj, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[cacheKey]
+	if !ok {
+		err := cl.Get(ctx, key, obj)
+		entry = lookupCacheEntry{obj: obj.DeepCopyObject().(client.Object), err: err}
+		c.entries[cacheKey] = entry
+		return err
+	}
+	if entry.err != nil {
+		return entry.err
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(entry.obj.DeepCopyObject()).Elem())
+	return nil
+}
+
+// checksumFuncMap returns the secretChecksum and configMapChecksum template
+// functions. They return the SHA-256 of the data of a Secret or ConfigMap in
+// lookup, so that templates can roll out pods when it changes, like Helm's
+// checksum annotations. A missing object yields an empty checksum.
+func checksumFuncMap(ctx context.Context, lookup client.Client) template.FuncMap {
+	return template.FuncMap{
+		"secretChecksum": func(namespace, name string) (string, error) {
+			if lookup == nil {
+				return "", fmt.Errorf("secretChecksum %s/%s: no cluster to resolve against", namespace, name)
+			}
+			secret := &corev1.Secret{}
+			if err := lookup.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+				if kerrors.IsNotFound(err) {
+					return "", nil
+				}
+				return "", errors.Wrap(err, "secretChecksum %s/%s", namespace, name)
+			}
+			return dataChecksum(secret.Data), nil
+		},
+		"configMapChecksum": func(namespace, name string) (string, error) {
+			if lookup == nil {
+				return "", fmt.Errorf("configMapChecksum %s/%s: no cluster to resolve against", namespace, name)
+			}
+			configMap := &corev1.ConfigMap{}
+			if err := lookup.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+				if kerrors.IsNotFound(err) {
+					return "", nil
+				}
+				return "", errors.Wrap(err, "configMapChecksum %s/%s", namespace, name)
+			}
+			data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+			for k, v := range configMap.Data {
+				data[k] = []byte(v)
+			}
+			for k, v := range configMap.BinaryData {
+				data[k] = v
+			}
+			return dataChecksum(data), nil
+		},
+	}
+}
+
+// dataChecksum hashes data in key order, so equal data always has the same checksum.
+func dataChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%d:", k, len(data[k]))
+		h.Write(data[k])
+		h.Write([]byte{0})
 	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // preserveExistingArgoSourceFields checks if an existing ArgoCD Application has repoURL/targetRevision
@@ -1,14 +1,23 @@
 package subroutines
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"text/template"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/validate"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -738,3 +747,517 @@ func (s *DeploymentHelpersTestSuite) Test_mergeImageVersionsIntoHelmReleaseValue
 }
 
 func boolPtr(b bool) *bool { return &b }
+
+func (s *DeploymentHelpersTestSuite) Test_renderAvailabilityTemplate_ZeroAndMissingMax() {
+	sub := &DeploymentSubroutine{}
+	availability := func(a map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"releaseNamespace": "platform-mesh-system",
+			"values": map[string]interface{}{"services": map[string]interface{}{
+				"portal": map[string]interface{}{"enabled": true, "availability": a},
+			}},
+		}
+	}
+
+	// An explicit 0 is kept instead of falling back to the default.
+	objs, err := sub.renderTemplateFile(context.Background(), "../../gotemplates/components/runtime/availability.yaml",
+		availability(map[string]interface{}{"pdb": map[string]interface{}{"minAvailable": 0}}), nil, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 1)
+	minAvailable, found, _ := unstructured.NestedFieldNoCopy(objs[0].Object, "spec", "minAvailable")
+	s.True(found)
+	s.EqualValues(0, minAvailable)
+
+	objs, err = sub.renderTemplateFile(context.Background(), "../../gotemplates/components/runtime/availability.yaml",
+		availability(map[string]interface{}{"pdb": map[string]interface{}{"maxUnavailable": 0}}), nil, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 1)
+	maxUnavailable, found, _ := unstructured.NestedFieldNoCopy(objs[0].Object, "spec", "maxUnavailable")
+	s.True(found)
+	s.EqualValues(0, maxUnavailable)
+
+	// An HPA without max is an error instead of rendering "<no value>".
+	_, err = sub.renderTemplateFile(context.Background(), "../../gotemplates/components/runtime/availability.yaml",
+		availability(map[string]interface{}{"hpa": map[string]interface{}{"min": 2}}), nil, s.log)
+	s.ErrorContains(err, "availability.hpa.max of service portal is required")
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderAvailabilityTemplate() {
+	sub := &DeploymentSubroutine{}
+	tmplVars := map[string]interface{}{
+		"releaseNamespace": "platform-mesh-system",
+		"values": map[string]interface{}{
+			"services": map[string]interface{}{
+				"portal": map[string]interface{}{
+					"enabled": true,
+					"availability": map[string]interface{}{
+						"pdb": map[string]interface{}{"minAvailable": 2},
+						"hpa": map[string]interface{}{"min": 2, "max": 5},
+					},
+				},
+				"gateway": map[string]interface{}{
+					"enabled":         true,
+					"targetNamespace": "gateway-system",
+					"availability": map[string]interface{}{
+						"pdb":      map[string]interface{}{"maxUnavailable": "25%"},
+						"selector": map[string]interface{}{"app": "gateway"},
+					},
+				},
+				"disabled": map[string]interface{}{
+					"enabled":      false,
+					"availability": map[string]interface{}{"pdb": map[string]interface{}{"minAvailable": 1}},
+				},
+				"plain": map[string]interface{}{"enabled": true},
+			},
+		},
+	}
+
+	objs, err := sub.renderTemplateFile(context.Background(), "../../gotemplates/components/runtime/availability.yaml", tmplVars, nil, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 3)
+
+	byKey := map[string]*unstructured.Unstructured{}
+	for _, obj := range objs {
+		byKey[obj.GetKind()+"/"+obj.GetName()] = obj
+	}
+
+	gatewayPDB := byKey["PodDisruptionBudget/gateway"]
+	s.Require().NotNil(gatewayPDB)
+	s.Equal("gateway-system", gatewayPDB.GetNamespace())
+	maxUnavailable, _, _ := unstructured.NestedString(gatewayPDB.Object, "spec", "maxUnavailable")
+	s.Equal("25%", maxUnavailable)
+	selector, _, _ := unstructured.NestedStringMap(gatewayPDB.Object, "spec", "selector", "matchLabels")
+	s.Equal(map[string]string{"app": "gateway"}, selector)
+
+	portalPDB := byKey["PodDisruptionBudget/portal"]
+	s.Require().NotNil(portalPDB)
+	minAvailable, _, _ := unstructured.NestedFieldNoCopy(portalPDB.Object, "spec", "minAvailable")
+	s.EqualValues(2, minAvailable)
+	selector, _, _ = unstructured.NestedStringMap(portalPDB.Object, "spec", "selector", "matchLabels")
+	s.Equal(map[string]string{"app.kubernetes.io/instance": "portal"}, selector)
+
+	portalHPA := byKey["HorizontalPodAutoscaler/portal"]
+	s.Require().NotNil(portalHPA)
+	targetName, _, _ := unstructured.NestedString(portalHPA.Object, "spec", "scaleTargetRef", "name")
+	s.Equal("portal", targetName)
+	maxReplicas, _, _ := unstructured.NestedFieldNoCopy(portalHPA.Object, "spec", "maxReplicas")
+	s.EqualValues(5, maxReplicas)
+	metrics, _, _ := unstructured.NestedSlice(portalHPA.Object, "spec", "metrics")
+	s.Len(metrics, 1)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderExposureTemplate() {
+	sub := &DeploymentSubroutine{}
+	services := map[string]interface{}{
+		"infra": map[string]interface{}{
+			"enabled": true,
+			"exposure": map[string]interface{}{
+				"gateway": map[string]interface{}{"passthroughHosts": []interface{}{"kcp.api"}},
+				"routes": []interface{}{
+					map[string]interface{}{"name": "keycloak", "hostPrefix": "portal", "path": "/keycloak", "service": "keycloak-service", "port": 8080},
+					map[string]interface{}{"name": "kcp-front-proxy", "hostPrefix": "kcp.api", "service": "frontproxy-front-proxy", "port": 8443, "passthrough": true},
+				},
+			},
+		},
+		"portal": map[string]interface{}{
+			"enabled":         true,
+			"targetNamespace": "portal-system",
+			"exposure": map[string]interface{}{
+				"routes": []interface{}{
+					map[string]interface{}{"name": "portal", "hostPrefix": "portal", "service": "portal", "port": 80},
+				},
+			},
+		},
+		"disabled": map[string]interface{}{
+			"enabled":  false,
+			"exposure": map[string]interface{}{"routes": []interface{}{map[string]interface{}{"name": "disabled"}}},
+		},
+	}
+	render := func(mode string) map[string]*unstructured.Unstructured {
+		tmplVars := map[string]interface{}{
+			"exposureMode":     mode,
+			"releaseNamespace": "platform-mesh-system",
+			"port":             "8443",
+			"baseDomains":      []interface{}{"example.com", "legacy.example.com"},
+			"values":           map[string]interface{}{"services": services},
+		}
+		objs, err := sub.renderTemplateFile(context.Background(), "../../gotemplates/components/runtime/exposure.yaml", tmplVars, nil, s.log)
+		s.Require().NoError(err)
+		byKey := map[string]*unstructured.Unstructured{}
+		for _, obj := range objs {
+			byKey[obj.GetKind()+"/"+obj.GetName()] = obj
+		}
+		return byKey
+	}
+
+	s.Empty(render("istio"))
+
+	objs := render("gatewayapi")
+	s.Len(objs, 4)
+	gateway := objs["Gateway/platform-mesh"]
+	s.Require().NotNil(gateway)
+	s.Equal("platform-mesh-system", gateway.GetNamespace())
+	className, _, _ := unstructured.NestedString(gateway.Object, "spec", "gatewayClassName")
+	s.Equal("traefik", className)
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	s.Len(listeners, 6)
+
+	portal := objs["HTTPRoute/portal"]
+	s.Require().NotNil(portal)
+	s.Equal("portal-system", portal.GetNamespace())
+	hostnames, _, _ := unstructured.NestedStringSlice(portal.Object, "spec", "hostnames")
+	s.Equal([]string{"portal.example.com", "portal.legacy.example.com"}, hostnames)
+
+	keycloak := objs["HTTPRoute/keycloak"]
+	s.Require().NotNil(keycloak)
+	rules, _, _ := unstructured.NestedSlice(keycloak.Object, "spec", "rules")
+	s.Require().Len(rules, 1)
+	matches, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "matches")
+	s.Require().Len(matches, 1)
+	path, _, _ := unstructured.NestedString(matches[0].(map[string]interface{}), "path", "value")
+	s.Equal("/keycloak", path)
+
+	kcp := objs["TLSRoute/kcp-front-proxy"]
+	s.Require().NotNil(kcp)
+	hostnames, _, _ = unstructured.NestedStringSlice(kcp.Object, "spec", "hostnames")
+	s.Equal([]string{"kcp.api.example.com", "kcp.api.legacy.example.com"}, hostnames)
+
+	objs = render("ingress")
+	s.Len(objs, 3)
+	ingress := objs["Ingress/kcp-front-proxy"]
+	s.Require().NotNil(ingress)
+	s.Equal("true", ingress.GetAnnotations()["nginx.ingress.kubernetes.io/ssl-passthrough"])
+	ingressRules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	s.Len(ingressRules, 2)
+	s.NotNil(objs["Ingress/portal"])
+}
+
+func (s *DeploymentHelpersTestSuite) Test_applySizing() {
+	values := map[string]interface{}{"sizing": "M"}
+	services := map[string]interface{}{
+		"portal": map[string]interface{}{"enabled": true},
+		"openfga": map[string]interface{}{
+			"enabled": true,
+			"values": map[string]interface{}{
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "2Gi"}},
+			},
+			"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "1"}},
+		},
+		"keycloak": map[string]interface{}{
+			"enabled":       true,
+			"resourcesPath": "keycloak.resources",
+		},
+		"disabled": map[string]interface{}{"enabled": false},
+	}
+
+	s.Require().NoError(applySizing(values, services, s.log))
+
+	portal, _, _ := unstructured.NestedMap(services, "portal", "values", "resources")
+	s.Equal(sizingPresets[SizingMedium], portal)
+
+	openfga, _, _ := unstructured.NestedMap(services, "openfga", "values", "resources")
+	s.Equal(map[string]interface{}{
+		"requests": map[string]interface{}{"cpu": "1", "memory": "128Mi"},
+		"limits":   map[string]interface{}{"memory": "2Gi"},
+	}, openfga)
+
+	keycloak, found, _ := unstructured.NestedMap(services, "keycloak", "values", "keycloak", "resources")
+	s.True(found)
+	s.Equal(sizingPresets[SizingMedium], keycloak)
+
+	_, found, _ = unstructured.NestedFieldNoCopy(services, "disabled", "values")
+	s.False(found)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_applySizing_custom() {
+	values := map[string]interface{}{"sizing": SizingCustom}
+	services := map[string]interface{}{
+		"portal": map[string]interface{}{
+			"enabled":   true,
+			"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "10m"}},
+		},
+		"plain": map[string]interface{}{"enabled": true},
+	}
+
+	s.Require().NoError(applySizing(values, services, s.log))
+
+	portal, _, _ := unstructured.NestedMap(services, "portal", "values", "resources")
+	s.Equal(map[string]interface{}{"requests": map[string]interface{}{"cpu": "10m"}}, portal)
+	_, found, _ := unstructured.NestedFieldNoCopy(services, "plain", "values")
+	s.False(found)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_applySizing_unknown() {
+	err := applySizing(map[string]interface{}{"sizing": "xl"}, map[string]interface{}{}, s.log)
+	s.ErrorContains(err, "unknown sizing")
+}
+
+type denyNameValidator struct{ name string }
+
+func (d denyNameValidator) Name() string { return "deny-name" }
+
+func (d denyNameValidator) Validate(_ context.Context, objs []*unstructured.Unstructured) ([]validate.Violation, error) {
+	var violations []validate.Violation
+	for _, obj := range objs {
+		if obj.GetName() == d.name {
+			violations = append(violations, validate.Violation{Validator: d.Name(), Policy: "deny", Kind: obj.GetKind(), Name: obj.GetName(), Message: "name is reserved"})
+		}
+	}
+	return violations, nil
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderValidators() {
+	dir := s.T().TempDir()
+	kyverno := filepath.Join(dir, "kyverno")
+	s.Require().NoError(os.WriteFile(kyverno, []byte("#!/bin/sh\n"), 0o700))
+
+	s.Empty(renderValidators(config.RenderValidationConfig{KyvernoBinary: kyverno, OPABinary: "opa"}), "disabled without policy dirs")
+
+	validators := renderValidators(config.RenderValidationConfig{
+		KyvernoPolicyDir: "/policies/kyverno",
+		KyvernoBinary:    kyverno,
+		OPAPolicyDir:     "/policies/opa",
+		OPABinary:        filepath.Join(dir, "opa"),
+	})
+	s.Require().Len(validators, 1, "opa is skipped when its binary is missing")
+	s.Equal("kyverno", validators[0].Name())
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderAndApplyTemplatesWithRouter_validation() {
+	dir := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: first\n"), 0o600))
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .name }}\n"), 0o600))
+
+	tests := []struct {
+		name        string
+		validators  []validate.Validator
+		expectError bool
+		expectApply []string
+	}{
+		{name: "no validators", expectApply: []string{"first", "second"}},
+		{name: "passing validator", validators: []validate.Validator{denyNameValidator{name: "other"}}, expectApply: []string{"first", "second"}},
+		{name: "violation applies nothing", validators: []validate.Validator{denyNameValidator{name: "second"}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			sub := &DeploymentSubroutine{validators: tt.validators}
+			var applied []string
+			n, err := sub.renderAndApplyTemplatesWithRouter(context.Background(), dir, map[string]interface{}{"name": "second"}, s.log, "test", nil,
+				func(_ context.Context, obj *unstructured.Unstructured) error {
+					applied = append(applied, obj.GetName())
+					return nil
+				})
+
+			if tt.expectError {
+				var vErr *validate.ViolationsError
+				s.Require().ErrorAs(err, &vErr)
+				s.Len(vErr.Violations, 1)
+				s.Empty(applied)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(tt.expectApply, applied)
+			s.Equal(len(tt.expectApply), n)
+		})
+	}
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderTemplatesDir_contract() {
+	dir := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("{{- /* requires: name */ -}}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .name }}\n"), 0o600))
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("{{- /* requires: baseDomainWithPort, values.services.*.chart */ -}}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n"), 0o600))
+	sub := &DeploymentSubroutine{}
+
+	// The missing inputs of all templates are reported and nothing is rendered.
+	manifests, err := sub.renderTemplatesDir(context.Background(), dir, map[string]interface{}{"baseDomainPort": "example.com"}, nil, s.log, nil, nil)
+	s.Require().Error(err)
+	s.Nil(manifests)
+	s.ErrorContains(err, filepath.Join(dir, "a.yaml")+" misses required inputs: name")
+	s.ErrorContains(err, filepath.Join(dir, "b.yaml")+" misses required inputs: baseDomainWithPort (renamed to baseDomainPort), values.services.*.chart")
+
+	tmplVars := map[string]interface{}{
+		"name":               "a",
+		"baseDomainWithPort": "example.com",
+		"values":             map[string]interface{}{"services": map[string]interface{}{"portal": map[string]interface{}{"chart": "portal"}}},
+	}
+	manifests, err = sub.renderTemplatesDir(context.Background(), dir, tmplVars, nil, s.log, nil, nil)
+	s.Require().NoError(err)
+	s.Len(manifests, 2)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderTemplateFile_checksums() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "deployment.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  annotations:
+    checksum/secret: "{{ secretChecksum "default" "app" }}"
+    checksum/config: "{{ configMapChecksum "default" "app" }}"
+    checksum/missing: "{{ secretChecksum "default" "missing" }}"
+`), 0o600))
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Data: map[string][]byte{"a": []byte("1"), "b": []byte("2")}}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Data: map[string]string{"a": "1"}, BinaryData: map[string][]byte{"b": []byte("2")}}
+	cl := fake.NewClientBuilder().WithObjects(secret, configMap).Build()
+	sub := &DeploymentSubroutine{}
+
+	objs, err := sub.renderTemplateFile(context.Background(), path, nil, cl, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 1)
+	annotations := objs[0].GetAnnotations()
+	s.Len(annotations["checksum/secret"], 64)
+	// Secret and ConfigMap with the same data have the same checksum.
+	s.Equal(annotations["checksum/secret"], annotations["checksum/config"])
+	s.Empty(annotations["checksum/missing"])
+
+	// Changed data changes the checksum.
+	secret.Data["a"] = []byte("changed")
+	s.Require().NoError(cl.Update(context.Background(), secret))
+	objs, err = sub.renderTemplateFile(context.Background(), path, nil, cl, s.log)
+	s.Require().NoError(err)
+	s.NotEqual(annotations["checksum/secret"], objs[0].GetAnnotations()["checksum/secret"])
+
+	_, err = sub.renderTemplateFile(context.Background(), path, nil, nil, s.log)
+	s.ErrorContains(err, "no cluster to resolve against")
+}
+
+func (s *DeploymentHelpersTestSuite) Test_templateFuncMap_library() {
+	tests := []struct {
+		tmpl     string
+		expected string
+	}{
+		{`{{ "admin" | b64enc }}`, "YWRtaW4="},
+		{`{{ "YWRtaW4=" | b64dec }}`, "admin"},
+		{`{{ "a: 1\nb: 2" | indent 2 }}`, "  a: 1\n  b: 2"},
+		{`{{ ternary "yes" "no" true }} {{ ternary "yes" "no" false }}`, "yes no"},
+		{`{{ "x" | quote }}`, `"x"`},
+		{`{{ "  Portal " | trim | upper }}`, "PORTAL"},
+		{`{{ "portal-ui" | trimSuffix "-ui" | replace "portal" "iam" }}`, "iam"},
+		{`{{ if contains "mesh" "platform-mesh" }}ok{{ end }}`, "ok"},
+		{`{{ split "," "a,b" | join ";" }}`, "a;b"},
+		{`{{ list 1 2 | toJson }}`, "[1,2]"},
+		{`{{ $d := dict "a" 1 }}{{ hasKey $d "a" }} {{ hasKey $d "b" }}`, "true false"},
+		{`{{ (fromYaml "a: b").a }}`, "b"},
+		{`{{ "abc" | sha256sum }}`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, tt := range tests {
+		s.Run(tt.tmpl, func() {
+			tmpl, err := template.New("t").Funcs(templateFuncMap()).Parse(tt.tmpl)
+			s.Require().NoError(err)
+			var out bytes.Buffer
+			s.Require().NoError(tmpl.Execute(&out, nil))
+			s.Equal(tt.expected, out.String())
+		})
+	}
+
+	tmpl, err := template.New("t").Funcs(templateFuncMap()).Parse(`{{ required "host is required" .host }}`)
+	s.Require().NoError(err)
+	s.ErrorContains(tmpl.Execute(&bytes.Buffer{}, map[string]interface{}{}), "host is required")
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderTemplateFile_lookup() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "configmap.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  password: "{{ lookupSecret .releaseNamespace "app" "password" }}"
+  url: "{{ lookupConfigMap .releaseNamespace "app" "url" }}"
+  missing: "{{ lookupSecret .releaseNamespace "missing" "password" }}"
+`), 0o600))
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "platform-mesh-system"}, Data: map[string][]byte{"password": []byte("s3cret")}}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "platform-mesh-system"}, Data: map[string]string{"url": "https://example.com"}}
+	sub := &DeploymentSubroutine{clientRuntime: fake.NewClientBuilder().WithObjects(secret, configMap).Build()}
+	tmplVars := map[string]interface{}{"releaseNamespace": "platform-mesh-system"}
+
+	objs, err := sub.renderTemplateFile(context.Background(), path, tmplVars, nil, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 1)
+	data, _, _ := unstructured.NestedStringMap(objs[0].Object, "data")
+	s.Equal(map[string]string{"password": "s3cret", "url": "https://example.com", "missing": ""}, data)
+
+	// Lookups outside of the release namespace are rejected.
+	s.Require().NoError(os.WriteFile(path, []byte(`{{ lookupSecret "kube-system" "app" "password" }}`), 0o600))
+	_, err = sub.renderTemplateFile(context.Background(), path, tmplVars, nil, s.log)
+	s.ErrorContains(err, `lookups are limited to namespace "platform-mesh-system"`)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_renderTemplateFile_lookupServiceAndSecretKey() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "configmap.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  {{- $gw := lookupService "istio-system" "gateway" }}
+  ip: "{{ $gw.loadBalancerIP }}"
+  port: "{{ $gw.ports.https }}"
+  missing: "{{ (lookupService .releaseNamespace "missing").clusterIP }}"
+  password: "{{ lookupSecretKey .releaseNamespace "db" "password" }}"
+`), 0o600))
+	gateway := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "istio-system"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.1", Ports: []corev1.ServicePort{{Name: "https", Port: 443}}},
+		Status:     corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "203.0.113.7"}}}},
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "platform-mesh-system"}, Data: map[string][]byte{"password": []byte("generated")}}
+	sub := &DeploymentSubroutine{
+		clientRuntime: fake.NewClientBuilder().WithObjects(gateway, secret).Build(),
+		cfgOperator:   &config.OperatorConfig{},
+	}
+	tmplVars := map[string]interface{}{"releaseNamespace": "platform-mesh-system"}
+
+	// istio-system is not allowed yet.
+	_, err := sub.renderTemplateFile(context.Background(), path, tmplVars, nil, s.log)
+	s.ErrorContains(err, "lookupService istio-system/gateway: lookups are limited to namespace")
+
+	sub.cfgOperator.Subroutines.Deployment.LookupNamespaces = []string{"istio-system"}
+	objs, err := sub.renderTemplateFile(context.Background(), path, tmplVars, nil, s.log)
+	s.Require().NoError(err)
+	s.Require().Len(objs, 1)
+	data, _, _ := unstructured.NestedStringMap(objs[0].Object, "data")
+	s.Equal(map[string]string{"ip": "203.0.113.7", "port": "443", "missing": "", "password": "generated"}, data)
+
+	// lookupSecretKey fails while the key has not been generated.
+	s.Require().NoError(os.WriteFile(path, []byte(`{{ lookupSecretKey .releaseNamespace "db" "admin-password" }}`), 0o600))
+	_, err = sub.renderTemplateFile(context.Background(), path, tmplVars, nil, s.log)
+	s.ErrorContains(err, `lookupSecretKey platform-mesh-system/db: key "admin-password" not found`)
+}
+
+func (s *DeploymentHelpersTestSuite) Test_lookupCache() {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"}, Data: map[string][]byte{"password": []byte("v1")}}
+	cl := fake.NewClientBuilder().WithObjects(secret).Build()
+	ctx := withLookupCache(context.Background())
+	cache := lookupCacheFrom(ctx)
+	key := types.NamespacedName{Namespace: "ns", Name: "db"}
+
+	got := &corev1.Secret{}
+	s.Require().NoError(cache.get(ctx, cl, key, got))
+	s.Equal("v1", string(got.Data["password"]))
+
+	// Later reads within the same cache do not see the update.
+	secret.Data["password"] = []byte("v2")
+	s.Require().NoError(cl.Update(ctx, secret))
+	got = &corev1.Secret{}
+	s.Require().NoError(cache.get(ctx, cl, key, got))
+	s.Equal("v1", string(got.Data["password"]))
+
+	// Missing objects are cached as NotFound.
+	s.True(kerrors.IsNotFound(cache.get(ctx, cl, types.NamespacedName{Namespace: "ns", Name: "missing"}, &corev1.Secret{})))
+
+	// Without a cache every read goes to the cluster.
+	got = &corev1.Secret{}
+	s.Require().NoError(lookupCacheFrom(context.Background()).get(ctx, cl, key, got))
+	s.Equal("v2", string(got.Data["password"]))
+}
+
+func (s *DeploymentHelpersTestSuite) Test_dataChecksum() {
+	s.Equal(dataChecksum(map[string][]byte{"a": []byte("1"), "b": []byte("2")}), dataChecksum(map[string][]byte{"b": []byte("2"), "a": []byte("1")}))
+	// Key and value boundaries are part of the checksum.
+	s.NotEqual(dataChecksum(map[string][]byte{"a": []byte("b=1")}), dataChecksum(map[string][]byte{"a=b": []byte("1")}))
+}
@@ -2,8 +2,10 @@ package subroutines
 
 import (
 	"context"
+	"net"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
@@ -11,16 +13,20 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/stretchr/testify/suite"
 	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
 )
 
 type DeploymentProcessTestSuite struct {
@@ -135,6 +141,8 @@ spec:
   secretName: rebac-authz-webhook-cert
   issuerRef:
     name: rebac-authz-webhook-issuer
+  dnsNames:
+  - rebac-authz-webhook.{{ .namespace }}.svc.cluster.local
 `
 	s.writeFile("manifests/k8s/rebac-auth-webhook/webhook-cert.yaml", webhookCert)
 
@@ -434,6 +442,56 @@ func (s *DeploymentProcessTestSuite) Test_Process_MissingProfile() {
 	s.NotNil(result)
 }
 
+func (s *DeploymentProcessTestSuite) Test_Process_ExposureNeedsIstio() {
+	ns := "platform-mesh-system"
+	operatorCfg := s.newOperatorConfig()
+	ctx := s.newContext(operatorCfg)
+
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: ns},
+		Spec: corev1alpha1.PlatformMeshSpec{
+			Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "localhost", Port: 8443, Protocol: "https"},
+		},
+	}
+	profileCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-profile", Namespace: ns},
+		Data: map[string]string{profileConfigMapKey: `
+infra:
+  deploymentTechnology: fluxcd
+components:
+  services:
+    portal:
+      values:
+        istio:
+          enabled: true
+`},
+	}
+	cl := fake.NewClientBuilder().
+		WithScheme(s.scheme).
+		WithObjects(inst, profileCM).
+		WithStatusSubresource(inst).
+		Build()
+
+	sub := &DeploymentSubroutine{
+		clientRuntime:            cl,
+		clientInfra:              cl,
+		cfg:                      &pmconfig.CommonServiceConfig{IsLocal: true},
+		cfgOperator:              &operatorCfg,
+		gotemplatesInfraDir:      filepath.Join(s.tmpDir, "gotemplates/infra"),
+		gotemplatesComponentsDir: filepath.Join(s.tmpDir, "gotemplates/components"),
+		workspaceDirectory:       filepath.Join(s.tmpDir, "manifests/k8s"),
+	}
+
+	result, err := sub.Process(ctx, inst)
+
+	s.NoError(err)
+	s.True(result.IsStopWithRequeue())
+	s.Contains(result.Message(), "portal: values.istio.enabled needs istio")
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, DeploymentReadyConditionType)
+	s.Require().NotNil(cond)
+	s.Equal("ValidatingExposure", cond.Reason)
+}
+
 func (s *DeploymentProcessTestSuite) Test_Process_RootShardNotReady() {
 	ns := "platform-mesh-system"
 	operatorCfg := s.newOperatorConfig()
@@ -475,3 +533,66 @@ func (s *DeploymentProcessTestSuite) Test_Process_RootShardNotReady() {
 	s.NoError(err)
 	s.False(result.IsContinue(), "expected StopWithRequeue when RootShard not found")
 }
+
+func (s *DeploymentProcessTestSuite) Test_Process_InfraClusterUnavailable() {
+	ns := "platform-mesh-system"
+	operatorCfg := s.newOperatorConfig()
+	ctx := s.newContext(operatorCfg)
+
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: ns},
+		Spec: corev1alpha1.PlatformMeshSpec{
+			Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "localhost", Port: 8443, Protocol: "https"},
+		},
+	}
+
+	profileCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-profile", Namespace: ns},
+		Data:       map[string]string{profileConfigMapKey: testProfileFluxCD},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(s.scheme).
+		WithObjects(inst, profileCM).
+		WithStatusSubresource(inst).
+		Build()
+	s.Require().NoError(cl.Create(ctx, s.newReadyRootShard(ns)))
+	s.Require().NoError(cl.Create(ctx, s.newReadyFrontProxy(ns)))
+	s.seedCertManagerCRDs(ctx, cl)
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	infra := fake.NewClientBuilder().WithScheme(s.scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+			return refused
+		},
+		List: func(context.Context, client.WithWatch, client.ObjectList, ...client.ListOption) error {
+			return refused
+		},
+		Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+			return refused
+		},
+	}).Build()
+
+	sub := &DeploymentSubroutine{
+		clientRuntime:            withClusterErrors(plan.ClusterRuntime, cl),
+		clientInfra:              withClusterErrors(plan.ClusterInfra, infra),
+		cfg:                      &pmconfig.CommonServiceConfig{IsLocal: true},
+		cfgOperator:              &operatorCfg,
+		gotemplatesInfraDir:      filepath.Join(s.tmpDir, "gotemplates/infra"),
+		gotemplatesComponentsDir: filepath.Join(s.tmpDir, "gotemplates/components"),
+		workspaceDirectory:       filepath.Join(s.tmpDir, "manifests/k8s"),
+	}
+
+	result, err := sub.Process(ctx, inst)
+
+	// The runtime work is done and the subroutines after this one may go on.
+	s.NoError(err)
+	s.True(result.IsPending(), "expected pending while the infra cluster is unavailable")
+	s.Require().NoError(cl.Get(ctx, types.NamespacedName{Name: "kcp-webhook-secret", Namespace: ns}, &corev1.Secret{}))
+
+	infraCond := apimeta.FindStatusCondition(inst.Status.Conditions, InfraClusterAvailableConditionType)
+	s.Require().NotNil(infraCond)
+	s.Equal(metav1.ConditionFalse, infraCond.Status)
+	s.Contains(infraCond.Message, "connection refused")
+	s.True(apimeta.IsStatusConditionTrue(inst.Status.Conditions, RuntimeClusterAvailableConditionType))
+}
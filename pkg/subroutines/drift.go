@@ -0,0 +1,281 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+// DriftedObject is a rendered object whose cluster state differs from its
+// manifest.
+type DriftedObject struct {
+	Templates string
+	Kind      string
+	Namespace string
+	Name      string
+	// Fields are the paths of the rendered fields with a different value in
+	// the cluster. It is empty when the object does not exist.
+	Fields []string
+}
+
+// DetectDrift renders the infra and components infra templates of inst like
+// Process does and compares the result with the infra cluster. Nothing is
+// applied and the status of inst is left untouched.
+func (r *DeploymentSubroutine) DetectDrift(ctx context.Context, inst *corev1alpha1.PlatformMesh) ([]DriftedObject, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	inst = inst.DeepCopy()
+	ctx = withLookupCache(ctx)
+
+	templateVars, err := TemplateVars(ctx, inst, r.clientRuntime)
+	if err != nil {
+		return nil, err
+	}
+
+	infraVars, err := r.templateVarsFromProfileInfra(ctx, inst, templateVars, r.cfgOperator)
+	if err != nil {
+		return nil, err
+	}
+	deploymentTech, _ := infraVars["deploymentTechnology"].(string)
+	skipFile := deploymentTechFileFilter(strings.ToLower(deploymentTech), log)
+	postProcess := r.infraManifestPostProcess(ctx, log)
+
+	manifests, err := r.renderTemplatesDir(ctx, r.gotemplatesInfraDir+"/infra", infraVars, r.clientInfra, log, skipFile, postProcess)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to render infra templates")
+	}
+	drifted, err := r.compareManifests(ctx, "infra", manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	componentVars, err := r.buildComponentsTemplateVars(ctx, inst, templateVars)
+	if err != nil {
+		return nil, err
+	}
+	// Adopted releases are compared under their own name. The adoptions are
+	// reported by the reconcile, like components that fail to render.
+	releaseNamespace, _ := componentVars["releaseNamespace"].(string)
+	adoption, err := r.discoverReleaseAdoption(ctx, inst, releaseNamespace, log)
+	if err != nil {
+		return nil, err
+	}
+	if adoption != nil {
+		postProcess = adoption.postProcess(postProcess)
+	}
+	manifests, _ = r.renderComponentTemplatesDir(ctx, r.gotemplatesComponentsDir+"/infra", componentVars, r.clientInfra, log, "components-infra", skipFile, postProcess)
+	componentDrift, err := r.compareManifests(ctx, "components-infra", manifests)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifted, componentDrift...), nil
+}
+
+// compareManifests returns the manifests whose object in the infra cluster is
+// missing or differs. Kinds whose CRD is not installed yet are skipped, the
+// reconcile is still rolling them out.
+func (r *DeploymentSubroutine) compareManifests(ctx context.Context, templateType string, manifests []renderedManifest) ([]DriftedObject, error) {
+	var drifted []DriftedObject
+	for _, m := range manifests {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(m.obj.GroupVersionKind())
+		err := r.clientInfra.Get(ctx, client.ObjectKeyFromObject(m.obj), live)
+		if apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil && !kerrors.IsNotFound(err) {
+			return nil, errors.Wrap(err, "Failed to get %s %s", m.obj.GetKind(), m.obj.GetName())
+		}
+		var fields []string
+		if err == nil {
+			if fields = driftedFields(m.obj.Object, live.Object); len(fields) == 0 {
+				continue
+			}
+		}
+		drifted = append(drifted, DriftedObject{
+			Templates: templateType,
+			Kind:      m.obj.GetKind(),
+			Namespace: m.obj.GetNamespace(),
+			Name:      m.obj.GetName(),
+			Fields:    fields,
+		})
+	}
+	return drifted, nil
+}
+
+// driftedFields returns the paths of the fields set in desired whose value in
+// live differs. Fields only present in live, e.g. defaults or fields of other
+// managers, are no drift. Of the metadata only labels and annotations are
+// compared, status and the write-only stringData of Secrets are ignored.
+func driftedFields(desired, live map[string]interface{}) []string {
+	var fields []string
+	for _, key := range []string{"labels", "annotations"} {
+		d, _, _ := unstructured.NestedFieldNoCopy(desired, "metadata", key)
+		l, _, _ := unstructured.NestedFieldNoCopy(live, "metadata", key)
+		fields = appendDrift(fields, "metadata."+key, d, l)
+	}
+	for _, key := range sortedKeys(desired) {
+		switch key {
+		case "apiVersion", "kind", "metadata", "status", "stringData":
+			continue
+		}
+		fields = appendDrift(fields, key, desired[key], live[key])
+	}
+	return fields
+}
+
+func appendDrift(fields []string, path string, desired, live interface{}) []string {
+	switch d := desired.(type) {
+	case nil:
+		// A null in the manifest does not set the field.
+		return fields
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			if len(d) == 0 && live == nil {
+				return fields
+			}
+			return append(fields, path)
+		}
+		for _, key := range sortedKeys(d) {
+			fields = appendDrift(fields, path+"."+key, d[key], l[key])
+		}
+		return fields
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			if len(d) == 0 && live == nil {
+				return fields
+			}
+			return append(fields, path)
+		}
+		for i := range d {
+			fields = appendDrift(fields, fmt.Sprintf("%s[%d]", path, i), d[i], l[i])
+		}
+		return fields
+	default:
+		if live == nil || scalarString(d) != scalarString(live) {
+			return append(fields, path)
+		}
+		return fields
+	}
+}
+
+// scalarString formats v so that numbers decoded as int64 from the cluster
+// and as float64 from rendered YAML compare equal.
+func scalarString(v interface{}) string {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(n, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// DriftDetector periodically compares the rendered infra manifests of all
+// deployed PlatformMesh instances with the infra cluster, so manual edits do
+// not persist unnoticed until the next reconcile. It implements
+// manager.Runnable. Drift is logged and exported as metrics; with autoCorrect
+// a reconcile is requested through trigger that applies the manifests again.
+type DriftDetector struct {
+	client      client.Client
+	trigger     *ReconcileTrigger
+	deployment  *DeploymentSubroutine
+	interval    time.Duration
+	autoCorrect bool
+	log         *logger.Logger
+}
+
+func NewDriftDetector(cl client.Client, trigger *ReconcileTrigger, deployment *DeploymentSubroutine, interval time.Duration, autoCorrect bool, log *logger.Logger) *DriftDetector {
+	return &DriftDetector{
+		client:      cl,
+		trigger:     trigger,
+		deployment:  deployment,
+		interval:    interval,
+		autoCorrect: autoCorrect,
+		log:         log.ChildLogger("component", "driftdetector"),
+	}
+}
+
+func (d *DriftDetector) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, d.Check, d.interval)
+	return nil
+}
+
+func (d *DriftDetector) NeedLeaderElection() bool {
+	return true
+}
+
+// Check compares the manifests of every instance whose deployment completed.
+// Instances still rolling out are skipped, the reconcile applies them anyway.
+func (d *DriftDetector) Check(ctx context.Context) {
+	ctx = context.WithValue(ctx, keys.LoggerCtxKey, d.log)
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := d.client.List(ctx, list); err != nil {
+		d.log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return
+	}
+
+	for i := range list.Items {
+		inst := &list.Items[i]
+		if inst.DeletionTimestamp != nil || !apimeta.IsStatusConditionTrue(inst.Status.Conditions, DeploymentReadyConditionType) {
+			continue
+		}
+		drifted, err := d.deployment.DetectDrift(ctx, inst)
+		if err != nil {
+			metrics.DriftChecksTotal.WithLabelValues("error").Inc()
+			d.log.Error().Err(err).Str("instance", instanceKey(inst)).Msg("Failed to check rendered manifests for drift")
+			continue
+		}
+		recordDriftMetrics(inst, drifted)
+		if len(drifted) == 0 {
+			metrics.DriftChecksTotal.WithLabelValues("clean").Inc()
+			continue
+		}
+		metrics.DriftChecksTotal.WithLabelValues("drifted").Inc()
+		for _, o := range drifted {
+			d.log.Warn().Str("instance", instanceKey(inst)).Str("templates", o.Templates).Str("kind", o.Kind).
+				Str("object", o.Namespace+"/"+o.Name).Strs("fields", o.Fields).Msg("Rendered object drifted from its manifest")
+		}
+		if !d.autoCorrect {
+			continue
+		}
+		if err := d.requestResync(ctx, inst); err != nil {
+			d.log.Error().Err(err).Str("instance", instanceKey(inst)).Msg("Failed to request resync of drifted manifests")
+		}
+	}
+}
+
+// recordDriftMetrics replaces the drifted object gauges of inst.
+func recordDriftMetrics(inst *corev1alpha1.PlatformMesh, drifted []DriftedObject) {
+	metrics.DriftedObjects.DeletePartialMatch(prometheus.Labels{"namespace": inst.Namespace, "name": inst.Name})
+	for _, o := range drifted {
+		metrics.DriftedObjects.WithLabelValues(inst.Namespace, inst.Name, o.Templates, o.Kind).Inc()
+	}
+}
+
+// requestResync requests a reconcile of inst that applies its manifests
+// again.
+func (d *DriftDetector) requestResync(ctx context.Context, inst *corev1alpha1.PlatformMesh) error {
+	if err := d.trigger.Request(ctx, client.ObjectKeyFromObject(inst)); err != nil {
+		return err
+	}
+	d.log.Info().Str("instance", instanceKey(inst)).Msg("Rendered manifests drifted, requested resync")
+	return nil
+}
@@ -0,0 +1,113 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+func TestDriftedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired string
+		live    string
+		fields  []string
+	}{
+		{
+			name:    "unchanged with defaults and status",
+			desired: "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a, labels: {app: a}}\nspec: {replicas: 2, template: {spec: {containers: [{name: a, image: a:1}]}}}",
+			live:    "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a, uid: x, labels: {app: a, extra: b}}\nspec: {replicas: 2, strategy: {}, template: {spec: {containers: [{name: a, image: a:1, imagePullPolicy: IfNotPresent}]}}}\nstatus: {replicas: 1}",
+		},
+		{
+			name:    "edited fields",
+			desired: "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a, annotations: {note: x}}\nspec: {replicas: 2, template: {spec: {containers: [{name: a, image: a:1}]}}}",
+			live:    "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a}\nspec: {replicas: 3, template: {spec: {containers: [{name: a, image: a:2}]}}}",
+			fields:  []string{"metadata.annotations", "spec.replicas", "spec.template.spec.containers[0].image"},
+		},
+		{
+			name:    "removed list item",
+			desired: "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: a}\ndata: {list: x}\nbinaryData: {}\nitems: [1, 2]",
+			live:    "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: a}\ndata: {list: x}\nitems: [1]",
+			fields:  []string{"items"},
+		},
+		{
+			name:    "write-only secret data",
+			desired: "apiVersion: v1\nkind: Secret\nmetadata: {name: a}\nstringData: {key: value}",
+			live:    "apiVersion: v1\nkind: Secret\nmetadata: {name: a}\ndata: {key: dmFsdWU=}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := driftedFields(manifestFromYAML(t, tt.desired).Object, manifestFromYAML(t, tt.live).Object)
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestScalarString(t *testing.T) {
+	assert.Equal(t, scalarString(int64(1000000)), scalarString(float64(1000000)))
+	assert.Equal(t, "0.5", scalarString(0.5))
+	assert.Equal(t, "true", scalarString(true))
+}
+
+func TestCompareManifests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	infra := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "same", Namespace: "ns"}, Data: map[string]string{"k": "v"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "edited", Namespace: "ns"}, Data: map[string]string{"k": "manual"}},
+	).Build()
+	r := &DeploymentSubroutine{clientInfra: infra}
+
+	drifted, err := r.compareManifests(context.Background(), "infra", []renderedManifest{
+		{path: "a.yaml", obj: manifestFromYAML(t, "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: same, namespace: ns}\ndata: {k: v}")},
+		{path: "b.yaml", obj: manifestFromYAML(t, "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: edited, namespace: ns}\ndata: {k: v}")},
+		{path: "c.yaml", obj: manifestFromYAML(t, "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: missing, namespace: ns}\ndata: {k: v}")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []DriftedObject{
+		{Templates: "infra", Kind: "ConfigMap", Namespace: "ns", Name: "edited", Fields: []string{"data.k"}},
+		{Templates: "infra", Kind: "ConfigMap", Namespace: "ns", Name: "missing"},
+	}, drifted)
+}
+
+func TestRecordDriftMetrics(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "drift", Namespace: "platform-mesh-system"}}
+
+	recordDriftMetrics(inst, []DriftedObject{
+		{Templates: "infra", Kind: "HelmRelease"},
+		{Templates: "infra", Kind: "HelmRelease"},
+		{Templates: "components-infra", Kind: "HelmRelease"},
+	})
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.DriftedObjects.WithLabelValues(inst.Namespace, inst.Name, "infra", "HelmRelease")))
+
+	// A clean check removes the gauges of the instance.
+	recordDriftMetrics(inst, nil)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.DriftedObjects.WithLabelValues(inst.Namespace, inst.Name, "components-infra", "HelmRelease")))
+}
+
+func TestDriftDetector_RequestResync(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	trigger := NewReconcileTrigger()
+	d := NewDriftDetector(nil, trigger, nil, time.Minute, true, log)
+	require.NoError(t, d.requestResync(context.Background(), inst))
+
+	e := <-trigger.events
+	assert.Equal(t, client.ObjectKeyFromObject(inst), client.ObjectKeyFromObject(e.Object))
+}
@@ -0,0 +1,94 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	kcpapiv1alpha1 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	kcpcorev1alpha "github.com/kcp-dev/kcp/sdk/apis/core/v1alpha1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// resolveSliceEndpoint returns the URL of the endpoint of slice selected by
+// selector, resolving the virtual workspace URL of selector.Shard in the root
+// workspace. Without selector it is the first endpoint.
+func resolveSliceEndpoint(
+	ctx context.Context, kcpHelper KcpHelper, cfg *rest.Config, slice *kcpapiv1alpha1.APIExportEndpointSlice, selector *corev1alpha1.EndpointSelector,
+) (string, error) {
+	shardURL := ""
+	if selector != nil && selector.Shard != "" {
+		rootClient, err := kcpHelper.NewKcpClient(rest.CopyConfig(cfg), "root")
+		if err != nil {
+			return "", fmt.Errorf("kcp client for root workspace: %w", err)
+		}
+		shard := &kcpcorev1alpha.Shard{}
+		if err := rootClient.Get(ctx, client.ObjectKey{Name: selector.Shard}, shard); err != nil {
+			return "", fmt.Errorf("get shard %q of endpoint selector: %w", selector.Shard, err)
+		}
+		shardURL = shard.Spec.VirtualWorkspaceURL
+		if shardURL == "" {
+			shardURL = shard.Spec.BaseURL
+		}
+	}
+	return selectSliceEndpoint(slice, selector, shardURL)
+}
+
+// selectSliceEndpoint returns the URL of the endpoint of slice selected by
+// selector. Candidates are the endpoints on the host of shardURL if
+// selector.Shard is set and matching selector.URLPattern if set; the one at
+// selector.Index is selected. Without selector it is the first endpoint.
+func selectSliceEndpoint(slice *kcpapiv1alpha1.APIExportEndpointSlice, selector *corev1alpha1.EndpointSelector, shardURL string) (string, error) {
+	if slice == nil {
+		return "", fmt.Errorf("nil APIExportEndpointSlice")
+	}
+	if len(slice.Status.APIExportEndpoints) == 0 {
+		return "", fmt.Errorf("no endpoints in APIExportEndpointSlice %q", slice.Name)
+	}
+	if selector == nil {
+		return slice.Status.APIExportEndpoints[0].URL, nil
+	}
+
+	var shardHost string
+	if selector.Shard != "" {
+		u, err := url.Parse(shardURL)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("shard %q has no valid virtual workspace URL %q", selector.Shard, shardURL)
+		}
+		shardHost = u.Host
+	}
+	var pattern *regexp.Regexp
+	if selector.URLPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(selector.URLPattern); err != nil {
+			return "", fmt.Errorf("invalid endpoint selector urlPattern %q: %w", selector.URLPattern, err)
+		}
+	}
+
+	var candidates []string
+	for _, endpoint := range slice.Status.APIExportEndpoints {
+		if shardHost != "" {
+			u, err := url.Parse(endpoint.URL)
+			if err != nil || u.Host != shardHost {
+				continue
+			}
+		}
+		if pattern != nil && !pattern.MatchString(endpoint.URL) {
+			continue
+		}
+		candidates = append(candidates, endpoint.URL)
+	}
+	index := 0
+	if selector.Index != nil {
+		index = *selector.Index
+	}
+	if index < 0 || index >= len(candidates) {
+		return "", fmt.Errorf("no endpoint of APIExportEndpointSlice %q matches the endpoint selector (shard %q, urlPattern %q, index %d): %d of %d endpoints match",
+			slice.Name, selector.Shard, selector.URLPattern, index, len(candidates), len(slice.Status.APIExportEndpoints))
+	}
+	return candidates[index], nil
+}
@@ -0,0 +1,122 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	kcpapiv1alpha1 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	kcpcorev1alpha "github.com/kcp-dev/kcp/sdk/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+)
+
+func multiShardSlice() *kcpapiv1alpha1.APIExportEndpointSlice {
+	return &kcpapiv1alpha1.APIExportEndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "core.platform-mesh.io"},
+		Status: kcpapiv1alpha1.APIExportEndpointSliceStatus{APIExportEndpoints: []kcpapiv1alpha1.APIExportEndpoint{
+			{URL: "https://root.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io"},
+			{URL: "https://eu-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io"},
+			{URL: "https://us-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io"},
+		}},
+	}
+}
+
+func TestSelectSliceEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector *corev1alpha1.EndpointSelector
+		shardURL string
+		want     string
+		wantErr  string
+	}{
+		{name: "no selector uses the first endpoint", want: "https://root.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io"},
+		{
+			name:     "shard",
+			selector: &corev1alpha1.EndpointSelector{Shard: "eu-1"},
+			shardURL: "https://eu-1.kcp.example:6443",
+			want:     "https://eu-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io",
+		},
+		{
+			name:     "url pattern",
+			selector: &corev1alpha1.EndpointSelector{URLPattern: `^https://us-`},
+			want:     "https://us-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io",
+		},
+		{
+			name:     "index of the candidates",
+			selector: &corev1alpha1.EndpointSelector{URLPattern: `-1\.kcp`, Index: ptr.To(1)},
+			want:     "https://us-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io",
+		},
+		{
+			name:     "no matching endpoint",
+			selector: &corev1alpha1.EndpointSelector{Shard: "ap-1", URLPattern: "ap"},
+			shardURL: "https://ap-1.kcp.example:6443",
+			wantErr:  `no endpoint of APIExportEndpointSlice "core.platform-mesh.io" matches the endpoint selector`,
+		},
+		{
+			name:     "index out of range",
+			selector: &corev1alpha1.EndpointSelector{Index: ptr.To(3)},
+			wantErr:  "0 of 3 endpoints match",
+		},
+		{
+			name:     "invalid url pattern",
+			selector: &corev1alpha1.EndpointSelector{URLPattern: "("},
+			wantErr:  "invalid endpoint selector urlPattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectSliceEndpoint(multiShardSlice(), tt.selector, tt.shardURL)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := selectSliceEndpoint(&kcpapiv1alpha1.APIExportEndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: "empty"}}, nil, "")
+	assert.ErrorContains(t, err, `no endpoints in APIExportEndpointSlice "empty"`)
+}
+
+func TestResolveSliceEndpointShard(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kcpcorev1alpha.AddToScheme(scheme))
+	root := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&kcpcorev1alpha.Shard{
+			ObjectMeta: metav1.ObjectMeta{Name: "us-1"},
+			Spec:       kcpcorev1alpha.ShardSpec{BaseURL: "https://us-1.kcp.example:6443"},
+		},
+		&kcpcorev1alpha.Shard{
+			ObjectMeta: metav1.ObjectMeta{Name: "eu-1"},
+			Spec: kcpcorev1alpha.ShardSpec{
+				BaseURL:             "https://eu-1.internal:6443",
+				VirtualWorkspaceURL: "https://eu-1.kcp.example:6443",
+			},
+		},
+	).Build()
+	helper := mocks.NewKcpHelper(t)
+	helper.EXPECT().NewKcpClient(mock.Anything, "root").Return(root, nil)
+	ctx := context.Background()
+
+	// The virtual workspace URL of the shard wins over its base URL.
+	got, err := resolveSliceEndpoint(ctx, helper, &rest.Config{}, multiShardSlice(), &corev1alpha1.EndpointSelector{Shard: "eu-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io", got)
+
+	got, err = resolveSliceEndpoint(ctx, helper, &rest.Config{}, multiShardSlice(), &corev1alpha1.EndpointSelector{Shard: "us-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://us-1.kcp.example:6443/services/apiexport/abc/core.platform-mesh.io", got)
+
+	_, err = resolveSliceEndpoint(ctx, helper, &rest.Config{}, multiShardSlice(), &corev1alpha1.EndpointSelector{Shard: "missing"})
+	assert.ErrorContains(t, err, `get shard "missing" of endpoint selector`)
+}
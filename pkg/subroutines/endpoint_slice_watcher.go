@@ -0,0 +1,271 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// EndpointSliceWatcher watches the APIExportEndpointSlices referenced by
+// provider connections of all PlatformMesh instances. It implements
+// manager.Runnable: the set of watched workspaces is resynced every interval
+// and each watch is re-established when it ends. Instances whose connection
+// secrets point at an old slice URL are reconciled through trigger.
+type EndpointSliceWatcher struct {
+	client         client.Client
+	trigger        *ReconcileTrigger
+	kcpConfig      config.KCPConfig
+	kcpUrl         string
+	interval       time.Duration
+	log            *logger.Logger
+	newWatchClient func(cfg *rest.Config, workspacePath string) (client.WithWatch, error)
+
+	mu      sync.Mutex
+	watches map[string]*endpointSliceWatch
+}
+
+type endpointSliceWatch struct {
+	// slices maps slice names to the connection secrets using them.
+	slices map[string][]string
+	cancel context.CancelFunc
+}
+
+func NewEndpointSliceWatcher(cl client.Client, trigger *ReconcileTrigger, kcpConfig config.KCPConfig, kcpUrl string, interval time.Duration, log *logger.Logger) *EndpointSliceWatcher {
+	return &EndpointSliceWatcher{
+		client:         cl,
+		trigger:        trigger,
+		kcpConfig:      kcpConfig,
+		kcpUrl:         kcpUrl,
+		interval:       interval,
+		log:            log.ChildLogger("component", "endpointslicewatcher"),
+		newWatchClient: newKcpWatchClient,
+		watches:        map[string]*endpointSliceWatch{},
+	}
+}
+
+func (w *EndpointSliceWatcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, w.Sync, w.interval)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, sw := range w.watches {
+		sw.cancel()
+		delete(w.watches, key)
+	}
+	return nil
+}
+
+func (w *EndpointSliceWatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync starts watches for workspaces whose slices are referenced by provider
+// connections and stops the ones no longer referenced.
+func (w *EndpointSliceWatcher) Sync(ctx context.Context) {
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := w.client.List(ctx, list); err != nil {
+		w.log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return
+	}
+
+	desired := map[string]bool{}
+	for i := range list.Items {
+		inst := &list.Items[i]
+		if inst.DeletionTimestamp != nil {
+			continue
+		}
+		for path, slices := range endpointSliceConnections(inst) {
+			key := instanceKey(inst) + "|" + path
+			desired[key] = true
+			if !w.needsWatch(key, slices) {
+				continue
+			}
+			cfg, _, err := BuildKubeconfigWithFallback(w.client, &w.kcpConfig, inst.Spec.Kcp.AdminSecretRefs, w.kcpUrl)
+			if err != nil {
+				w.log.Debug().Err(err).Str("instance", instanceKey(inst)).Msg("Cannot build kcp kubeconfig yet, not watching endpoint slices")
+				continue
+			}
+			w.startWatch(ctx, key, inst.Namespace, inst.Name, path, slices, cfg)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, sw := range w.watches {
+		if !desired[key] {
+			sw.cancel()
+			delete(w.watches, key)
+		}
+	}
+}
+
+// endpointSliceConnections returns the workspaces of the provider connections
+// of inst that use an APIExportEndpointSlice, with the secrets per slice.
+func endpointSliceConnections(inst *corev1alpha1.PlatformMesh) map[string]map[string][]string {
+	workspaces := map[string]map[string][]string{}
+	for _, pc := range providerConnections(inst) {
+		name := ptr.Deref(pc.EndpointSliceName, "")
+		if name == "" || pc.Path == "" {
+			continue
+		}
+		if workspaces[pc.Path] == nil {
+			workspaces[pc.Path] = map[string][]string{}
+		}
+		workspaces[pc.Path][name] = append(workspaces[pc.Path][name], pc.Secret)
+	}
+	return workspaces
+}
+
+func (w *EndpointSliceWatcher) needsWatch(key string, slices map[string][]string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sw, ok := w.watches[key]
+	if !ok {
+		return true
+	}
+	if sliceSignature(sw.slices) == sliceSignature(slices) {
+		return false
+	}
+	sw.cancel()
+	delete(w.watches, key)
+	return true
+}
+
+func sliceSignature(slices map[string][]string) string {
+	var parts []string
+	for name, secrets := range slices {
+		parts = append(parts, name+"="+strings.Join(secrets, ","))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
+
+func (w *EndpointSliceWatcher) startWatch(ctx context.Context, key, namespace, name, path string, slices map[string][]string, cfg *rest.Config) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.watches[key] = &endpointSliceWatch{slices: slices, cancel: cancel}
+	w.mu.Unlock()
+
+	go wait.UntilWithContext(watchCtx, func(ctx context.Context) {
+		if err := w.watchWorkspace(ctx, namespace, name, path, slices, cfg); err != nil {
+			w.log.Warn().Err(err).Str("workspace", path).Msg("Watch of APIExportEndpointSlices ended")
+		}
+	}, w.interval)
+	w.log.Debug().Str("instance", namespace+"/"+name).Str("workspace", path).Msg("Watching APIExportEndpointSlices")
+}
+
+// watchWorkspace watches the slices in path until the watch ends. The initial
+// events cover changes made while no watch was running.
+func (w *EndpointSliceWatcher) watchWorkspace(ctx context.Context, namespace, name, path string, slices map[string][]string, cfg *rest.Config) error {
+	kcpClient, err := w.newWatchClient(rest.CopyConfig(cfg), path)
+	if err != nil {
+		return err
+	}
+	watcher, err := kcpClient.Watch(ctx, &kcpapiv1alpha.APIExportEndpointSliceList{})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if event.Type != watch.Added && event.Type != watch.Modified {
+				continue
+			}
+			slice, ok := event.Object.(*kcpapiv1alpha.APIExportEndpointSlice)
+			if !ok {
+				continue
+			}
+			secrets, ok := slices[slice.Name]
+			if !ok {
+				continue
+			}
+			if err := w.requestReconcileOnChange(ctx, namespace, name, slice, secrets); err != nil {
+				w.log.Error().Err(err).Str("instance", namespace+"/"+name).Str("endpointSlice", slice.Name).Msg("Failed to request reconcile for changed APIExportEndpointSlice")
+			}
+		}
+	}
+}
+
+// requestReconcileOnChange requests a reconcile of the PlatformMesh when a
+// connection secret using slice was written for a different endpoint URL.
+// Connections without a recorded URL are left to the regular reconcile.
+func (w *EndpointSliceWatcher) requestReconcileOnChange(ctx context.Context, namespace, name string, slice *kcpapiv1alpha.APIExportEndpointSlice, secrets []string) error {
+	endpointPath, err := virtualWorkspacePathFromSlice(slice)
+	if err != nil {
+		// Not published yet, the subroutine requeues until it is.
+		return nil
+	}
+	inst := &corev1alpha1.PlatformMesh{}
+	if err := w.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, inst); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !connectionURLChanged(inst, secrets, endpointPath) {
+		return nil
+	}
+	if err := w.trigger.Request(ctx, client.ObjectKeyFromObject(inst)); err != nil {
+		return err
+	}
+	w.log.Info().Str("instance", namespace+"/"+name).Str("endpointSlice", slice.Name).Str("url", slice.Status.APIExportEndpoints[0].URL).
+		Msg("APIExportEndpointSlice URL changed, requested reconcile")
+	return nil
+}
+
+// connectionURLChanged reports whether the recorded server URL of one of the
+// secrets no longer ends in endpointPath. Provider secrets replace the host of
+// the slice URL, so only the path is compared.
+func connectionURLChanged(inst *corev1alpha1.PlatformMesh, secrets []string, endpointPath string) bool {
+	for _, c := range inst.Status.Connections {
+		if c.Type != corev1alpha1.ConnectionTypeProvider || c.ResolvedURL == "" {
+			continue
+		}
+		for _, secret := range secrets {
+			if c.Name != secret {
+				continue
+			}
+			u, err := url.Parse(c.ResolvedURL)
+			if err != nil || strings.TrimSuffix(u.Path, "/") != endpointPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newKcpWatchClient(cfg *rest.Config, workspacePath string) (client.WithWatch, error) {
+	u, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kcp host %s: %w", cfg.Host, err)
+	}
+	cfg.Host = u.Scheme + "://" + u.Host + "/clusters/" + workspacePath
+	scheme := runtime.NewScheme()
+	if err := kcpapiv1alpha.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	cl, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create KCP watch client: %w", err)
+	}
+	return cl, nil
+}
@@ -0,0 +1,105 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestEndpointSliceConnections(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{
+		ProviderConnections: []corev1alpha1.ProviderConnection{
+			{EndpointSliceName: ptr.To("core.platform-mesh.io"), Path: "root:platform-mesh-system", Secret: "a"},
+			{EndpointSliceName: ptr.To("core.platform-mesh.io"), Path: "root:platform-mesh-system", Secret: "b"},
+			{APIExportName: ptr.To("core.platform-mesh.io"), Path: "root:platform-mesh-system", Secret: "c"},
+		},
+	}}}
+
+	assert.Equal(t, map[string]map[string][]string{
+		"root:platform-mesh-system": {"core.platform-mesh.io": {"a", "b"}},
+	}, endpointSliceConnections(inst))
+}
+
+func TestConnectionURLChanged(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{Status: corev1alpha1.PlatformMeshStatus{Connections: []corev1alpha1.ConnectionStatus{
+		{Name: "a", Type: corev1alpha1.ConnectionTypeProvider, ResolvedURL: "https://frontproxy-front-proxy.platform-mesh-system:8443/services/apiexport/abc/core.platform-mesh.io"},
+		{Name: "b", Type: corev1alpha1.ConnectionTypeProvider},
+	}}}
+
+	assert.False(t, connectionURLChanged(inst, []string{"a"}, "/services/apiexport/abc/core.platform-mesh.io"))
+	assert.True(t, connectionURLChanged(inst, []string{"a"}, "/services/apiexport/def/core.platform-mesh.io"))
+	// Connections without a recorded URL are left to the regular reconcile.
+	assert.False(t, connectionURLChanged(inst, []string{"b"}, "/services/apiexport/def/core.platform-mesh.io"))
+}
+
+func TestEndpointSliceWatcher_RequestsReconcileOnURLChange(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	require.NoError(t, kcpapiv1alpha.AddToScheme(scheme))
+
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"},
+		Status: corev1alpha1.PlatformMeshStatus{Connections: []corev1alpha1.ConnectionStatus{{
+			Name:        "provider-kubeconfig",
+			Type:        corev1alpha1.ConnectionTypeProvider,
+			ResolvedURL: "https://frontproxy-front-proxy.platform-mesh-system:8443/services/apiexport/abc/core.platform-mesh.io",
+		}}},
+	}
+	runtimeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(inst).Build()
+	slice := &kcpapiv1alpha.APIExportEndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "core.platform-mesh.io"},
+		Status: kcpapiv1alpha.APIExportEndpointSliceStatus{APIExportEndpoints: []kcpapiv1alpha.APIExportEndpoint{
+			{URL: "https://kcp.example.com:6443/services/apiexport/abc/core.platform-mesh.io"},
+		}},
+	}
+	kcpClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(slice).Build()
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	trigger := NewReconcileTrigger()
+	w := NewEndpointSliceWatcher(runtimeClient, trigger, config.KCPConfig{}, "https://kcp.example.com:6443", time.Second, log)
+	w.newWatchClient = func(_ *rest.Config, _ string) (client.WithWatch, error) { return kcpClient, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- w.watchWorkspace(ctx, inst.Namespace, inst.Name, "root:platform-mesh-system",
+			map[string][]string{"core.platform-mesh.io": {"provider-kubeconfig"}}, &rest.Config{})
+	}()
+
+	newURL := "https://kcp.example.com:6443/services/apiexport/def/core.platform-mesh.io"
+	assert.Eventually(t, func() bool {
+		current := &kcpapiv1alpha.APIExportEndpointSlice{}
+		if err := kcpClient.Get(ctx, client.ObjectKeyFromObject(slice), current); err != nil {
+			return false
+		}
+		current.Status.APIExportEndpoints[0].URL = newURL
+		if err := kcpClient.Update(ctx, current); err != nil {
+			return false
+		}
+		select {
+		case e := <-trigger.events:
+			return client.ObjectKeyFromObject(e.Object) == client.ObjectKeyFromObject(inst)
+		default:
+			return false
+		}
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,82 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/runbook"
+)
+
+// Reasons of the events recorded on a PlatformMesh.
+const (
+	EventReasonManifestsApplied            = "ManifestsApplied"
+	EventReasonApplyFailed                 = "ApplyFailed"
+	EventReasonWaiting                     = "Waiting"
+	EventReasonProviderSecretCreated       = "ProviderSecretCreated"
+	EventReasonProviderSecretRotated       = "ProviderSecretRotated"
+	EventReasonProtectionTampered          = "ProtectionTampered"
+	EventReasonDeletionBlocked             = "DeletionBlocked"
+	EventReasonProtectionReleased          = "ProtectionReleased"
+	EventReasonRBACApprovalRequired        = "RBACApprovalRequired"
+	EventReasonAPIBindingNotReady          = "APIBindingNotReady"
+	EventReasonDefaultAPIBindingConflict   = "DefaultAPIBindingConflict"
+	EventReasonRawManifestApplied          = "RawManifestApplied"
+	EventReasonWebhookSecretMigrated       = "WebhookSecretMigrated"
+	EventReasonProfileChanged              = "ProfileChanged"
+	EventReasonTenantNamespacesProvisioned = "TenantNamespacesProvisioned"
+	EventReasonComponentPruned             = "ComponentPruned"
+	EventReasonPreDeleteHookStarted        = "PreDeleteHookStarted"
+	EventReasonPreDeleteHookFailed         = "PreDeleteHookFailed"
+	EventReasonOrphanPruned                = "OrphanPruned"
+	EventReasonIstioRestarted              = "IstioRestarted"
+	EventReasonWebhookUnreachable          = "WebhookUnreachable"
+	EventReasonReadinessGateFailed         = "ReadinessGateFailed"
+	EventReasonReleaseDiscovered           = "ReleaseDiscovered"
+	EventReasonComponentAdopted            = "ComponentAdopted"
+	EventReasonWorkspaceNotReady           = "WorkspaceNotReady"
+	EventReasonOperationDeferred           = "OperationDeferred"
+	EventReasonRecoveryStarted             = "RecoveryStarted"
+	EventReasonRecoveryCompleted           = "RecoveryCompleted"
+	EventReasonExposureInvalid             = "ExposureInvalid"
+	EventReasonUpgradeHeld                 = "UpgradeHeld"
+	EventReasonReleaseRolledBack           = "ReleaseRolledBack"
+	EventReasonRenderedManifestsOmitted    = "RenderedManifestsOmitted"
+	EventReasonSubroutineFailed            = "SubroutineFailed"
+)
+
+type eventRecorderKey struct{}
+
+// WithEventRecorder returns ctx carrying rec. Subroutines record the events
+// of the object they process with it, so they show up in kubectl describe.
+func WithEventRecorder(ctx context.Context, rec events.EventRecorder) context.Context {
+	return context.WithValue(ctx, eventRecorderKey{}, rec)
+}
+
+// recordEvent records an event on obj with the recorder of ctx. Nothing is
+// recorded without one, e.g. in tests and while planning.
+func recordEvent(ctx context.Context, obj runtime.Object, eventType, reason, action, note string, args ...interface{}) {
+	rec, ok := ctx.Value(eventRecorderKey{}).(events.EventRecorder)
+	if !ok || rec == nil || obj == nil {
+		return
+	}
+	rec.Eventf(obj, nil, eventType, reason, action, "%s", runbook.Default().Annotate(reason, fmt.Sprintf(note, args...)))
+}
+
+// recordWaiting records that the reconcile waits for a dependency.
+func recordWaiting(ctx context.Context, obj runtime.Object, note string) {
+	recordEvent(ctx, obj, corev1.EventTypeNormal, EventReasonWaiting, "Wait", note)
+}
+
+// recordManifestsApplied records how many manifests of templateType were
+// applied, or a warning if rendering or applying them failed.
+func recordManifestsApplied(ctx context.Context, obj runtime.Object, templateType string, applied int, err error) {
+	if err != nil {
+		recordEvent(ctx, obj, corev1.EventTypeWarning, EventReasonApplyFailed, "Apply", "Failed to apply %s manifests after %d applied: %v", templateType, applied, err)
+		return
+	}
+	recordEvent(ctx, obj, corev1.EventTypeNormal, EventReasonManifestsApplied, "Apply", "Applied %d %s manifests", applied, templateType)
+}
@@ -0,0 +1,54 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestRecordManifestsApplied(t *testing.T) {
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	recordManifestsApplied(ctx, inst, "infra", 42, nil)
+	recordManifestsApplied(ctx, inst, "runtime", 3, fmt.Errorf("boom"))
+	assert.Equal(t, "Normal ManifestsApplied Applied 42 infra manifests", <-rec.Events)
+	assert.Equal(t, "Warning ApplyFailed Failed to apply runtime manifests after 3 applied: boom", <-rec.Events)
+
+	// Without a recorder nothing is recorded.
+	recordManifestsApplied(context.Background(), inst, "infra", 1, nil)
+	assert.Empty(t, rec.Events)
+}
+
+func TestWriteProviderSecret_Events(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system", UID: "uid"}}
+
+	_, err := writeProviderSecret(ctx, cl, inst, "portal-kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("a")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Normal ProviderSecretCreated Created provider secret platform-mesh-system/portal-kubeconfig", <-rec.Events)
+
+	// Writing the same content again is not recorded.
+	_, err = writeProviderSecret(ctx, cl, inst, "portal-kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("a")}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, rec.Events)
+
+	_, err = writeProviderSecret(ctx, cl, inst, "portal-kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("b")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Normal ProviderSecretRotated Rotated provider secret platform-mesh-system/portal-kubeconfig", <-rec.Events)
+}
@@ -0,0 +1,138 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// exposureMode returns how the endpoints of inst are exposed: spec.exposure.mode,
+// or else istio with the istio integration enabled and gatewayapi without it.
+func exposureMode(inst *v1alpha1.PlatformMesh, enableIstio bool) v1alpha1.ExposureMode {
+	if inst.Spec.Exposure != nil && inst.Spec.Exposure.Mode != "" {
+		return inst.Spec.Exposure.Mode
+	}
+	if enableIstio {
+		return v1alpha1.ExposureModeIstio
+	}
+	return v1alpha1.ExposureModeGatewayAPI
+}
+
+// exposureControllers reports whether the infra profile deploys a Gateway API
+// controller, the Gateway API CRDs and traefik, and an Ingress controller,
+// ingress-nginx or traefik with its Ingress provider.
+func exposureControllers(infra map[string]interface{}) (gatewayAPI, ingress bool) {
+	gatewayAPI = nestedBool(infra, "gatewayApi", "enabled") && nestedBool(infra, "traefik", "enabled")
+	ingress = nestedBool(infra, "ingressNginx", "enabled") ||
+		nestedBool(infra, "traefik", "enabled") && nestedBool(infra, "traefik", "values", "providers", "kubernetesIngress", "enabled")
+	return gatewayAPI, ingress
+}
+
+// enabledServices returns the services of the components profile that are not
+// disabled, by name, and their names in order.
+func enabledServices(components map[string]interface{}) ([]string, map[string]map[string]interface{}) {
+	services, _, _ := unstructured.NestedMap(components, "services")
+	enabled := make(map[string]map[string]interface{}, len(services))
+	names := make([]string, 0, len(services))
+	for name, value := range services {
+		service, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if on, found, _ := unstructured.NestedBool(service, "enabled"); found && !on {
+			continue
+		}
+		enabled[name] = service
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, enabled
+}
+
+// exposureProblems returns the components of the profile that are exposed in
+// a way that needs istio, for a landscape without istio. Without the mesh a
+// component is exposed with Gateway API, which needs the Gateway API CRDs and
+// traefik as gateway controller, or with a plain Ingress, which needs
+// ingress-nginx or traefik with its Ingress provider.
+func exposureProblems(infra, components map[string]interface{}) []string {
+	gatewayAPI, ingress := exposureControllers(infra)
+	names, services := enabledServices(components)
+
+	var problems []string
+	for _, name := range names {
+		service := services[name]
+		if nestedBool(service, "values", "istio", "enabled") {
+			problems = append(problems, fmt.Sprintf("%s: values.istio.enabled needs istio", name))
+		}
+		if nestedBool(service, "values", "istio", "exposed") {
+			problems = append(problems, fmt.Sprintf("%s: values.istio.exposed needs istio", name))
+		}
+		if nestedBool(service, "values", "gatewayApi", "enabled") && !gatewayAPI {
+			problems = append(problems, fmt.Sprintf("%s: values.gatewayApi.enabled needs infra.gatewayApi and infra.traefik", name))
+		}
+		if nestedBool(service, "values", "ingress", "enabled") && !ingress {
+			problems = append(problems, fmt.Sprintf("%s: values.ingress.enabled needs infra.ingressNginx or infra.traefik with providers.kubernetesIngress", name))
+		}
+	}
+	return problems
+}
+
+// exposureModeProblems returns why mode cannot be served: istio needs the
+// istio integration of the operator, and the exposure blocks of the components
+// need the controller of their mode in the infra profile.
+func exposureModeProblems(mode v1alpha1.ExposureMode, enableIstio bool, infra, components map[string]interface{}) []string {
+	gatewayAPI, ingress := exposureControllers(infra)
+	names, services := enabledServices(components)
+
+	var problems []string
+	if mode == v1alpha1.ExposureModeIstio && !enableIstio {
+		problems = append(problems, "spec.exposure.mode istio needs --subroutines-deployment-enable-istio")
+	}
+	for _, name := range names {
+		if _, found, _ := unstructured.NestedFieldNoCopy(services[name], "exposure"); !found {
+			continue
+		}
+		switch {
+		case mode == v1alpha1.ExposureModeGatewayAPI && !gatewayAPI:
+			problems = append(problems, fmt.Sprintf("%s: exposure in mode gatewayapi needs infra.gatewayApi and infra.traefik", name))
+		case mode == v1alpha1.ExposureModeIngress && !ingress:
+			problems = append(problems, fmt.Sprintf("%s: exposure in mode ingress needs infra.ingressNginx or infra.traefik with providers.kubernetesIngress", name))
+		}
+	}
+	return problems
+}
+
+// nestedBool returns the bool at fields of obj, false if it is missing or no
+// bool.
+func nestedBool(obj map[string]interface{}, fields ...string) bool {
+	value, _, _ := unstructured.NestedBool(obj, fields...)
+	return value
+}
+
+// checkExposure returns the exposure problems of the profile of inst in mode,
+// see exposureProblems and exposureModeProblems.
+func (r *DeploymentSubroutine) checkExposure(ctx context.Context, inst *v1alpha1.PlatformMesh, mode v1alpha1.ExposureMode) ([]string, error) {
+	infraYAML, componentsYAML, err := r.loadProfileSections(ctx, inst)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load profile from ConfigMap")
+	}
+	var infra, components map[string]interface{}
+	if err := yaml.Unmarshal([]byte(infraYAML), &infra); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse infra profile")
+	}
+	if err := yaml.Unmarshal([]byte(componentsYAML), &components); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse components profile")
+	}
+	enableIstio := r.cfgOperator.Subroutines.Deployment.EnableIstio
+	var problems []string
+	if !enableIstio {
+		problems = exposureProblems(infra, components)
+	}
+	return append(problems, exposureModeProblems(mode, enableIstio, infra, components)...), nil
+}
@@ -0,0 +1,132 @@
+package subroutines
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestExposureProblems(t *testing.T) {
+	parse := func(t *testing.T, in string) map[string]interface{} {
+		var out map[string]interface{}
+		assert.NoError(t, yaml.Unmarshal([]byte(in), &out))
+		return out
+	}
+	components := `
+services:
+  portal:
+    values:
+      gatewayApi:
+        enabled: true
+  webhook:
+    values:
+      istio:
+        exposed: false
+  ui:
+    values:
+      ingress:
+        enabled: true
+  mesh:
+    values:
+      istio:
+        enabled: true
+  disabled:
+    enabled: false
+    values:
+      istio:
+        exposed: true
+`
+
+	tests := []struct {
+		name  string
+		infra string
+		want  []string
+	}{
+		{
+			name: "gateway api and traefik ingress",
+			infra: `
+gatewayApi:
+  enabled: true
+traefik:
+  enabled: true
+  values:
+    providers:
+      kubernetesIngress:
+        enabled: true
+`,
+			want: []string{"mesh: values.istio.enabled needs istio"},
+		},
+		{
+			name: "ingress-nginx without gateway api",
+			infra: `
+gatewayApi:
+  enabled: false
+traefik:
+  enabled: true
+ingressNginx:
+  enabled: true
+`,
+			want: []string{
+				"mesh: values.istio.enabled needs istio",
+				"portal: values.gatewayApi.enabled needs infra.gatewayApi and infra.traefik",
+			},
+		},
+		{
+			name:  "no gateway or ingress controller",
+			infra: `{}`,
+			want: []string{
+				"mesh: values.istio.enabled needs istio",
+				"portal: values.gatewayApi.enabled needs infra.gatewayApi and infra.traefik",
+				"ui: values.ingress.enabled needs infra.ingressNginx or infra.traefik with providers.kubernetesIngress",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exposureProblems(parse(t, tt.infra), parse(t, components)))
+		})
+	}
+}
+
+func TestExposureMode(t *testing.T) {
+	inst := &v1alpha1.PlatformMesh{}
+	assert.Equal(t, v1alpha1.ExposureModeIstio, exposureMode(inst, true))
+	assert.Equal(t, v1alpha1.ExposureModeGatewayAPI, exposureMode(inst, false))
+
+	inst.Spec.Exposure = &v1alpha1.ExposureConfig{Mode: v1alpha1.ExposureModeIngress}
+	assert.Equal(t, v1alpha1.ExposureModeIngress, exposureMode(inst, true))
+	assert.Equal(t, v1alpha1.ExposureModeIngress, exposureMode(inst, false))
+}
+
+func TestExposureModeProblems(t *testing.T) {
+	var components map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+services:
+  infra:
+    exposure:
+      routes:
+      - name: keycloak
+  portal:
+    enabled: false
+    exposure:
+      routes:
+      - name: portal
+  plain: {}
+`), &components))
+	gatewayAPI := map[string]interface{}{
+		"gatewayApi": map[string]interface{}{"enabled": true},
+		"traefik":    map[string]interface{}{"enabled": true},
+	}
+
+	assert.Empty(t, exposureModeProblems(v1alpha1.ExposureModeIstio, true, nil, components))
+	assert.Equal(t, []string{"spec.exposure.mode istio needs --subroutines-deployment-enable-istio"},
+		exposureModeProblems(v1alpha1.ExposureModeIstio, false, nil, components))
+	assert.Empty(t, exposureModeProblems(v1alpha1.ExposureModeGatewayAPI, false, gatewayAPI, components))
+	assert.Equal(t, []string{"infra: exposure in mode gatewayapi needs infra.gatewayApi and infra.traefik"},
+		exposureModeProblems(v1alpha1.ExposureModeGatewayAPI, true, nil, components))
+	assert.Equal(t, []string{"infra: exposure in mode ingress needs infra.ingressNginx or infra.traefik with providers.kubernetesIngress"},
+		exposureModeProblems(v1alpha1.ExposureModeIngress, true, gatewayAPI, components))
+}
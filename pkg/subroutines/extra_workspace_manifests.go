@@ -0,0 +1,160 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// workspaceManifest is a manifest template of an extra workspace with the
+// file or ConfigMap key it was read from.
+type workspaceManifest struct {
+	source string
+	data   []byte
+}
+
+func isManifestName(name string) bool {
+	return strings.HasSuffix(name, ".yaml")
+}
+
+// loadWorkspaceManifests reads the manifests src references, in apply order.
+// ConfigMaps are read with k8sClient, directories below workspaceDir.
+func loadWorkspaceManifests(
+	ctx context.Context, k8sClient client.Client, workspaceDir string, inst *corev1alpha1.PlatformMesh, src corev1alpha1.WorkspaceManifestSource,
+) ([]workspaceManifest, error) {
+	switch {
+	case src.ConfigMapRef != nil && src.Directory != "":
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("manifests reference both ConfigMap %s and directory %s", src.ConfigMapRef.Name, src.Directory))
+	case src.ConfigMapRef != nil:
+		// The manifests are applied as kcp admin, so they are only read from
+		// the namespace of the instance.
+		namespace := inst.Namespace
+		if ns := src.ConfigMapRef.Namespace; ns != "" && ns != namespace {
+			return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("manifest ConfigMap %s/%s is not in the namespace %s of the instance", ns, src.ConfigMapRef.Name, namespace))
+		}
+		cm := &corev1.ConfigMap{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: src.ConfigMapRef.Name, Namespace: namespace}, cm); err != nil {
+			return nil, errors.Wrap(err, "Failed to get manifest ConfigMap %s/%s", namespace, src.ConfigMapRef.Name)
+		}
+		var manifests []workspaceManifest
+		for _, key := range slices.Sorted(maps.Keys(cm.Data)) {
+			if isManifestName(key) {
+				manifests = append(manifests, workspaceManifest{source: namespace + "/" + cm.Name + "/" + key, data: []byte(cm.Data[key])})
+			}
+		}
+		return manifests, nil
+	case src.Directory != "":
+		// The directory must not leave the workspace directory of the operator.
+		if !filepath.IsLocal(src.Directory) {
+			return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("manifest directory %s is not below the workspace directory", src.Directory))
+		}
+		dir := filepath.Join(workspaceDir, src.Directory)
+		files, err := ListFiles(dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list manifest directory %s", src.Directory)
+		}
+		var manifests []workspaceManifest
+		for _, file := range files {
+			if !isManifestName(file) {
+				continue
+			}
+			path := filepath.Join(dir, file)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to read manifest %s", path)
+			}
+			manifests = append(manifests, workspaceManifest{source: path, data: data})
+		}
+		return manifests, nil
+	}
+	return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("manifests reference neither a ConfigMap nor a directory"))
+}
+
+// validateExtraWorkspacePath returns an error unless path is a workspace
+// below root whose segments are valid workspace names.
+func validateExtraWorkspacePath(path string) error {
+	segments := strings.Split(path, ":")
+	if len(segments) < 2 || segments[0] != "root" {
+		return errors.New("workspace path %s is not below root", path)
+	}
+	for _, name := range segments[1:] {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return errors.New("workspace path %s has an invalid workspace name %q: %s", path, name, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// workspaceTemplateData returns templateData with the template data of src
+// added to it.
+func workspaceTemplateData(templateData map[string]any, src corev1alpha1.WorkspaceManifestSource) (map[string]any, error) {
+	data := maps.Clone(templateData)
+	if data == nil {
+		data = map[string]any{}
+	}
+	if src.TemplateData == nil || len(src.TemplateData.Raw) == 0 {
+		return data, nil
+	}
+	var extra map[string]any
+	if err := json.Unmarshal(src.TemplateData.Raw, &extra); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse template data")
+	}
+	maps.Copy(data, extra)
+	return data, nil
+}
+
+// applyWorkspaceManifests applies the manifests of the extra workspace decl
+// once the workspace is ready. A workspace that does not become ready is
+// recorded by the workspace wait of ctx and skipped. Like the files of a kcp
+// manifest directory, a failing manifest does not stop the others.
+func (r *KcpsetupSubroutine) applyWorkspaceManifests(
+	ctx context.Context, config *rest.Config, decl corev1alpha1.WorkspaceDeclaration, templateData map[string]any,
+	inst *corev1alpha1.PlatformMesh, claims *SharedObjectClaims,
+) error {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	manifests, err := loadWorkspaceManifests(ctx, r.client, r.cfg.WorkspaceDir, inst, *decl.Manifests)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load manifests of extra workspace %s", decl.Path)
+	}
+	data, err := workspaceTemplateData(templateData, *decl.Manifests)
+	if err != nil {
+		return errors.Wrap(err, "Failed to build template data of extra workspace %s", decl.Path)
+	}
+
+	parentPath, name, _ := splitWorkspacePath(decl.Path)
+	err = checkWorkspaceIn(ctx, config, parentPath, name, log, r.kcpHelper)
+	if workspaceWaitFrom(ctx).skip(ctx, err, inst) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := r.kcpHelper.NewKcpClient(config, decl.Path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create kcp client for workspace %s", decl.Path)
+	}
+	var errApplyManifests error
+	for _, m := range manifests {
+		if err := applyManifestTemplate(ctx, m.source, m.data, k8sClient, data, decl.Path, inst, claims); err != nil {
+			log.Warn().Err(err).Str("manifest", m.source).Str("workspace", decl.Path).Msg("Failed to apply manifest, continuing to next manifest of workspace")
+			errApplyManifests = keepApplyError(errApplyManifests, err)
+		}
+	}
+	return errApplyManifests
+}
@@ -0,0 +1,142 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+const teamClusterRole = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: {{ .team }}-admin
+  labels:
+    domain: "{{ .baseDomain }}"
+rules: []
+`
+
+func TestLoadWorkspaceManifests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-manifests", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"b-binding.yaml": "b", "a-role.yaml": "a", "README.md": "docs"},
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "manifests", "team"), 0o755))
+	for name, content := range map[string]string{"02-binding.yaml": "2", "01-role.yaml": "1", "notes.txt": "notes"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "manifests", "team", name), []byte(content), 0o600))
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	ctx := context.Background()
+
+	manifests, err := loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-manifests"}})
+	require.NoError(t, err)
+	assert.Equal(t, []workspaceManifest{
+		{source: "platform-mesh-system/team-manifests/a-role.yaml", data: []byte("a")},
+		{source: "platform-mesh-system/team-manifests/b-binding.yaml", data: []byte("b")},
+	}, manifests)
+
+	manifests, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{Directory: "manifests/team"})
+	require.NoError(t, err)
+	assert.Equal(t, []workspaceManifest{
+		{source: filepath.Join(dir, "manifests", "team", "01-role.yaml"), data: []byte("1")},
+		{source: filepath.Join(dir, "manifests", "team", "02-binding.yaml"), data: []byte("2")},
+	}, manifests)
+
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "missing"}})
+	assert.ErrorContains(t, err, "Failed to get manifest ConfigMap platform-mesh-system/missing")
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-manifests", Namespace: "kube-system"}})
+	assert.ErrorContains(t, err, "is not in the namespace platform-mesh-system of the instance")
+	assert.Equal(t, corev1alpha1.ErrorCategoryConfig, ClassifyError(err).Category)
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-manifests", Namespace: inst.Namespace}})
+	assert.NoError(t, err)
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{Directory: "../etc"})
+	assert.ErrorContains(t, err, "is not below the workspace directory")
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-manifests"}, Directory: "manifests/team"})
+	assert.Error(t, err)
+	_, err = loadWorkspaceManifests(ctx, cl, dir, inst, corev1alpha1.WorkspaceManifestSource{})
+	assert.Error(t, err)
+}
+
+func TestValidateExtraWorkspacePath(t *testing.T) {
+	assert.NoError(t, validateExtraWorkspacePath("root:orgs"))
+	assert.NoError(t, validateExtraWorkspacePath("root:orgs:team-alpha"))
+	assert.ErrorContains(t, validateExtraWorkspacePath("root"), "is not below root")
+	assert.ErrorContains(t, validateExtraWorkspacePath("system:admin"), "is not below root")
+	assert.ErrorContains(t, validateExtraWorkspacePath("root:orgs:"), "invalid workspace name")
+	assert.ErrorContains(t, validateExtraWorkspacePath("root:Orgs"), "invalid workspace name")
+	assert.ErrorContains(t, validateExtraWorkspacePath("root:orgs/../system"), "invalid workspace name")
+}
+
+func TestWorkspaceTemplateData(t *testing.T) {
+	base := map[string]any{"baseDomain": "example.com", "team": "default"}
+
+	data, err := workspaceTemplateData(base, corev1alpha1.WorkspaceManifestSource{})
+	require.NoError(t, err)
+	assert.Equal(t, base, data)
+
+	data, err = workspaceTemplateData(base, corev1alpha1.WorkspaceManifestSource{TemplateData: &apiextensionsv1.JSON{Raw: []byte(`{"team":"alpha","owners":["a","b"]}`)}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"baseDomain": "example.com", "team": "alpha", "owners": []any{"a", "b"}}, data)
+	assert.Equal(t, "default", base["team"])
+
+	_, err = workspaceTemplateData(base, corev1alpha1.WorkspaceManifestSource{TemplateData: &apiextensionsv1.JSON{Raw: []byte(`["team"]`)}})
+	assert.Error(t, err)
+}
+
+func TestApplyWorkspaceManifests(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-manifests", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"role.yaml": teamClusterRole},
+	}
+	helper := &Helper{}
+	r := &KcpsetupSubroutine{
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build(),
+		kcpHelper: helper,
+		cfg:       &config.OperatorConfig{},
+	}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	decl := corev1alpha1.WorkspaceDeclaration{
+		Path: "root:team",
+		Type: corev1alpha1.WorkspaceTypeReference{Name: "universal", Path: "root"},
+		Manifests: &corev1alpha1.WorkspaceManifestSource{
+			ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-manifests"},
+			TemplateData: &apiextensionsv1.JSON{Raw: []byte(`{"team":"alpha"}`)},
+		},
+	}
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	require.NoError(t, applyWorkspace(ctx, root, "root", "team", decl.Type, nil, inst))
+	require.NoError(t, r.applyWorkspaceManifests(ctx, server.RestConfig(), decl, map[string]any{"baseDomain": "example.com"}, inst, nil))
+
+	role, ok := server.Get("root:team", schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, "", "alpha-admin")
+	require.True(t, ok)
+	assert.Equal(t, "example.com", role.GetLabels()["domain"])
+}
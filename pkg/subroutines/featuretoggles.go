@@ -3,6 +3,7 @@ package subroutines
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"time"
 
@@ -27,7 +28,7 @@ type KubeconfigBuilder interface {
 type defaultKubeconfigBuilder struct{}
 
 func (defaultKubeconfigBuilder) Build(ctx context.Context, client client.Client, kcpUrl string) (*rest.Config, error) {
-	return buildKubeconfig(ctx, client, kcpUrl)
+	return buildKubeconfig(ctx, client, nil, kcpUrl)
 }
 
 type FeatureToggleSubroutine struct {
@@ -36,6 +37,7 @@ type FeatureToggleSubroutine struct {
 	kcpUrl             string
 	kubeconfigBuilder  KubeconfigBuilder
 	kcpHelper          KcpHelper
+	requeue            *RequeueBackoff
 }
 
 func NewFeatureToggleSubroutine(client client.Client, helper KcpHelper, operatorCfg *config.OperatorConfig, kcpUrl string) *FeatureToggleSubroutine {
@@ -45,6 +47,7 @@ func NewFeatureToggleSubroutine(client client.Client, helper KcpHelper, operator
 		kcpUrl:             kcpUrl,
 		kubeconfigBuilder:  defaultKubeconfigBuilder{},
 		kcpHelper:          helper,
+		requeue:            NewRequeueBackoff(FeatureToggleSubroutineName, operatorCfg.Subroutines.FeatureToggles.Requeue),
 	}
 }
 
@@ -74,11 +77,15 @@ func (r *FeatureToggleSubroutine) Process(ctx context.Context, runtimeObj client
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
 
 	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 	for _, ft := range inst.Spec.FeatureToggles {
 		switch ft.Name {
 		case "feature-enable-getting-started":
 			// Implement the logic to enable the getting started feature
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-getting-started")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-getting-started")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply getting started manifests")
 				return subroutines.OK(), applyErr
@@ -86,7 +93,10 @@ func (r *FeatureToggleSubroutine) Process(ctx context.Context, runtimeObj client
 			log.Info().Msg("Enabled 'Getting started configuration' feature")
 		case "feature-enable-marketplace-account":
 			// Implement the logic to enable the marketplace feature
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-marketplace-account")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-marketplace-account")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply marketplace manifests")
 				return subroutines.OK(), applyErr
@@ -94,28 +104,40 @@ func (r *FeatureToggleSubroutine) Process(ctx context.Context, runtimeObj client
 			log.Info().Msg("Enabled 'Marketplace configuration' feature")
 		case "feature-enable-marketplace-org":
 			// Implement the logic to enable the marketplace feature
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-marketplace-org")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-marketplace-org")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply marketplace manifests")
 				return subroutines.OK(), applyErr
 			}
 			log.Info().Msg("Enabled 'Marketplace configuration' feature")
 		case "feature-accounts-in-accounts":
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-accounts-in-accounts")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-accounts-in-accounts")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply accounts-in-accounts manifests")
 				return subroutines.OK(), applyErr
 			}
 			log.Info().Msg("Enabled 'Accounts in accounts' feature")
 		case "feature-enable-account-iam-ui":
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-account-iam-ui")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-account-iam-ui")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply account-iam-ui manifests")
 				return subroutines.OK(), applyErr
 			}
 			log.Info().Msg("Enabled 'Account IAM UI' feature")
 		case "feature-enable-terminal-controller-manager":
-			_, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-terminal-controller-manager")
+			applyRes, applyErr := r.applyKcpManifests(ctx, inst, operatorCfg, "/feature-enable-terminal-controller-manager")
+			if !applyRes.IsContinue() {
+				return applyRes, nil
+			}
 			if applyErr != nil {
 				log.Error().Err(applyErr).Msg("Failed to apply terminal-controller-manager manifests")
 				return subroutines.OK(), applyErr
@@ -127,6 +149,7 @@ func (r *FeatureToggleSubroutine) Process(ctx context.Context, runtimeObj client
 			log.Warn().Str("featureToggle", ft.Name).Msg("Unknown feature toggle")
 		}
 	}
+	clearSharedObjectConflict(inst, sharedObjectConflictReasonFeature)
 
 	return subroutines.OK(), nil
 }
@@ -143,7 +166,7 @@ func (r *FeatureToggleSubroutine) applyKcpManifests(
 	log.Info().Str("Directory", kcpDir).Msg("Applying KCP manifests for feature toggle")
 
 	// Build kcp kubeconfig
-	cfg, err := buildKubeconfig(ctx, r.client, r.kcpUrl)
+	cfg, err := buildKubeconfig(ctx, r.client, inst, r.kcpUrl)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to build kubeconfig")
 		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
@@ -158,8 +181,12 @@ func (r *FeatureToggleSubroutine) applyKcpManifests(
 		"port":           fmt.Sprintf("%d", port),
 		"baseDomainPort": baseDomainPort,
 	}
+	maps.Copy(tplValues, domainTemplateData(inst))
 
-	err = ApplyDirStructure(ctx, dir, "root", cfg, tplValues, inst, r.kcpHelper)
+	err = ApplyDirStructure(ctx, dir, "root", cfg, tplValues, inst, NewSharedObjectClaims(r.client, inst), r.kcpHelper)
+	if res, ok := sharedObjectConflict(inst, err, sharedObjectConflictReasonFeature, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
 	if err != nil {
 		log.Err(err).Msg("Failed to apply dir structure")
 		return subroutines.OK(), gcerrors.Wrap(err, "Failed to apply dir structure")
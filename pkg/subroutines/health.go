@@ -0,0 +1,332 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const (
+	// ComponentsReadyConditionType aggregates the health of the components
+	// probed by the HealthAggregator. It is independent of the Ready
+	// condition of the reconcile.
+	ComponentsReadyConditionType = "ComponentsReady"
+
+	componentsReasonHealthy   = "ComponentsHealthy"
+	componentsReasonUnhealthy = "ComponentsUnhealthy"
+
+	// operatorCreatedLabel marks the releases rendered by the operator.
+	operatorCreatedLabel = "core.platform-mesh.io/operator-created"
+)
+
+// namedReleases are the releases reported as components of their own, by
+// release name. All other releases are aggregated into one component.
+var namedReleases = map[string]string{
+	"cert-manager": "cert-manager",
+	"istio-istiod": "istiod",
+	"keycloak":     "keycloak",
+}
+
+var releaseKinds = []schema.GroupVersionKind{
+	{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmReleaseList"},
+	{Group: "argoproj.io", Version: "v1alpha1", Kind: "ApplicationList"},
+}
+
+// HealthAggregator periodically probes the components deployed for every
+// PlatformMesh instance: the RootShard and FrontProxy, cert-manager, istiod,
+// keycloak and all other releases rendered by the operator. The result is
+// written to status.components and the ComponentsReady condition and kept in
+// a HealthReport. It implements manager.Runnable.
+type HealthAggregator struct {
+	client      client.Client
+	clientInfra client.Client
+	kcp         config.KCPConfig
+	interval    time.Duration
+	report      *HealthReport
+	log         *logger.Logger
+}
+
+func NewHealthAggregator(cl client.Client, clientInfra client.Client, kcp config.KCPConfig, interval time.Duration, report *HealthReport, log *logger.Logger) *HealthAggregator {
+	return &HealthAggregator{
+		client:      cl,
+		clientInfra: clientInfra,
+		kcp:         kcp,
+		interval:    interval,
+		report:      report,
+		log:         log.ChildLogger("component", "healthaggregator"),
+	}
+}
+
+func (h *HealthAggregator) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, h.Check, h.interval)
+	return nil
+}
+
+func (h *HealthAggregator) NeedLeaderElection() bool {
+	return true
+}
+
+// Check probes the components of every instance and updates its status
+// when their health changed.
+func (h *HealthAggregator) Check(ctx context.Context) {
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := h.client.List(ctx, list); err != nil {
+		h.log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return
+	}
+
+	reports := make(map[string][]corev1alpha1.ComponentHealth, len(list.Items))
+	for i := range list.Items {
+		inst := &list.Items[i]
+		if inst.DeletionTimestamp != nil {
+			continue
+		}
+		components := mergeComponentHealth(inst.Status.Components, h.Probe(ctx, inst), metav1.Now())
+		reports[instanceKey(inst)] = components
+		if err := h.updateStatus(ctx, inst, components); err != nil {
+			h.log.Error().Err(err).Str("instance", instanceKey(inst)).Msg("Failed to update component health")
+		}
+	}
+
+	h.report.set(reports)
+}
+
+// Probe returns the current health of the components of inst. Named
+// releases that are not deployed are left out.
+func (h *HealthAggregator) Probe(ctx context.Context, inst *corev1alpha1.PlatformMesh) []corev1alpha1.ComponentHealth {
+	components := []corev1alpha1.ComponentHealth{
+		h.probeKcp(ctx, "RootShard", h.kcp.RootShardName),
+		h.probeKcp(ctx, "FrontProxy", h.kcp.FrontProxyName),
+	}
+
+	releases, err := h.listReleases(ctx, inst.Namespace)
+	if err != nil {
+		return append(components, corev1alpha1.ComponentHealth{Name: "releases", Message: err.Error()})
+	}
+	var named []corev1alpha1.ComponentHealth
+	var notReady []string
+	for _, rel := range releases {
+		healthy, message := releaseHealthy(rel)
+		if name, ok := namedReleases[rel.GetName()]; ok {
+			named = append(named, corev1alpha1.ComponentHealth{Name: name, Healthy: healthy, Message: message})
+			continue
+		}
+		if !healthy {
+			notReady = append(notReady, fmt.Sprintf("%s %s: %s", rel.GetKind(), rel.GetName(), message))
+		}
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Name < named[j].Name })
+	components = append(components, named...)
+	return append(components, corev1alpha1.ComponentHealth{
+		Name:    "releases",
+		Healthy: len(notReady) == 0,
+		Message: strings.Join(notReady, "; "),
+	})
+}
+
+// probeKcp reports whether the kcp-operator object of kind is Available.
+func (h *HealthAggregator) probeKcp(ctx context.Context, kind, name string) corev1alpha1.ComponentHealth {
+	health := corev1alpha1.ComponentHealth{Name: kind}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: kind})
+	if err := h.client.Get(ctx, types.NamespacedName{Name: name, Namespace: h.kcp.Namespace}, obj); err != nil {
+		health.Message = err.Error()
+		return health
+	}
+	health.Healthy, health.Message = conditionHealthy(obj, "Available")
+	return health
+}
+
+// listReleases returns the HelmReleases and ArgoCD Applications the operator
+// rendered into namespace. Kinds whose CRD is not installed are skipped.
+func (h *HealthAggregator) listReleases(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	var releases []unstructured.Unstructured
+	for _, gvk := range releaseKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		err := h.clientInfra.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{operatorCreatedLabel: "true"})
+		if apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list %s", gvk.Kind)
+		}
+		releases = append(releases, list.Items...)
+	}
+	return releases, nil
+}
+
+// releaseHealthy reports whether a HelmRelease is Ready or an ArgoCD
+// Application is synced and healthy. Suspended HelmReleases are healthy.
+func releaseHealthy(rel unstructured.Unstructured) (bool, string) {
+	if rel.GetKind() == "Application" {
+		syncStatus, _, _ := unstructured.NestedString(rel.Object, "status", "sync", "status")
+		healthStatus, _, _ := unstructured.NestedString(rel.Object, "status", "health", "status")
+		if syncStatus == "Synced" && healthStatus == "Healthy" {
+			return true, ""
+		}
+		return false, fmt.Sprintf("sync status %q, health status %q", syncStatus, healthStatus)
+	}
+	if suspended, _, _ := unstructured.NestedBool(rel.Object, "spec", "suspend"); suspended {
+		return true, ""
+	}
+	return conditionHealthy(&rel, "Ready")
+}
+
+// conditionHealthy reports whether the condition of conditionType is True.
+// Otherwise it returns the message of the condition.
+func conditionHealthy(obj *unstructured.Unstructured, conditionType string) (bool, string) {
+	if matchesConditionWithStatus(obj, conditionType, "True") {
+		return true, ""
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != conditionType {
+			continue
+		}
+		msg := fmt.Sprintf("%s=%v", conditionType, cond["status"])
+		if m, _ := cond["message"].(string); m != "" {
+			msg += ": " + m
+		}
+		return false, msg
+	}
+	return false, fmt.Sprintf("%s condition not reported", conditionType)
+}
+
+// mergeComponentHealth carries the transition times of previous over to
+// current for components whose health did not change.
+func mergeComponentHealth(previous, current []corev1alpha1.ComponentHealth, now metav1.Time) []corev1alpha1.ComponentHealth {
+	for i := range current {
+		current[i].LastTransitionTime = now
+		for _, p := range previous {
+			if p.Name == current[i].Name && p.Healthy == current[i].Healthy {
+				current[i].LastTransitionTime = p.LastTransitionTime
+				break
+			}
+		}
+	}
+	return current
+}
+
+// setComponentsReady stores components in the status of inst and sets
+// ComponentsReadyConditionType.
+func setComponentsReady(inst *corev1alpha1.PlatformMesh, components []corev1alpha1.ComponentHealth) {
+	inst.Status.Components = components
+	unhealthy := unhealthyComponents(components)
+	cond := metav1.Condition{
+		Type:               ComponentsReadyConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             componentsReasonHealthy,
+		Message:            "All components are healthy",
+		ObservedGeneration: inst.Generation,
+	}
+	if len(unhealthy) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = componentsReasonUnhealthy
+		cond.Message = "Unhealthy components: " + strings.Join(unhealthy, ", ")
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, cond)
+}
+
+// updateStatus patches the status of inst if the health of its components
+// changed. The optimistic lock keeps the patch from overwriting a status
+// written by a concurrent reconcile; it is retried with the next check.
+func (h *HealthAggregator) updateStatus(ctx context.Context, inst *corev1alpha1.PlatformMesh, components []corev1alpha1.ComponentHealth) error {
+	base := inst.DeepCopy()
+	setComponentsReady(inst, components)
+	if equality.Semantic.DeepEqual(base.Status, inst.Status) {
+		return nil
+	}
+	return h.client.Status().Patch(ctx, inst, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+}
+
+func unhealthyComponents(components []corev1alpha1.ComponentHealth) []string {
+	var unhealthy []string
+	for _, c := range components {
+		if !c.Healthy {
+			unhealthy = append(unhealthy, c.Name)
+		}
+	}
+	return unhealthy
+}
+
+// HealthReport holds the components last probed by the HealthAggregator.
+// It is created before the manager, so it can be served by the metrics
+// server.
+type HealthReport struct {
+	mu        sync.RWMutex
+	instances map[string][]corev1alpha1.ComponentHealth
+}
+
+func NewHealthReport() *HealthReport {
+	return &HealthReport{instances: map[string][]corev1alpha1.ComponentHealth{}}
+}
+
+func (r *HealthReport) set(instances map[string][]corev1alpha1.ComponentHealth) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances = instances
+}
+
+// Checker is a healthz.Checker failing while components of any instance are
+// unhealthy. Its error names them per instance.
+func (r *HealthReport) Checker(_ *http.Request) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.instances))
+	for key := range r.instances {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var failing []string
+	for _, key := range keys {
+		for _, c := range r.instances[key] {
+			if !c.Healthy {
+				failing = append(failing, fmt.Sprintf("%s: %s: %s", key, c.Name, c.Message))
+			}
+		}
+	}
+	if len(failing) > 0 {
+		return fmt.Errorf("unhealthy components:\n%s", strings.Join(failing, "\n"))
+	}
+	return nil
+}
+
+// ServeHTTP writes the last probed components of every instance as JSON,
+// keyed by <namespace>/<name>. The status is 503 while any is unhealthy.
+func (r *HealthReport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	body, err := json.Marshal(r.instances)
+	r.mu.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Checker(req) != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,134 @@
+package subroutines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func healthObject(gvk schema.GroupVersionKind, name, namespace string, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(map[string]string{operatorCreatedLabel: "true"})
+	conds := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		conds = append(conds, c)
+	}
+	_ = unstructured.SetNestedSlice(obj.Object, conds, "status", "conditions")
+	return obj
+}
+
+func TestHealthAggregator_Check(t *testing.T) {
+	ns := "platform-mesh-system"
+	kcpKind := func(kind string) schema.GroupVersionKind {
+		return schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: kind}
+	}
+	helmRelease := schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: ns}}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(inst).WithStatusSubresource(inst).Build()
+	ctx := context.Background()
+	require.NoError(t, cl.Create(ctx, healthObject(kcpKind("RootShard"), "root", ns, map[string]interface{}{"type": "Available", "status": "True"})))
+	require.NoError(t, cl.Create(ctx, healthObject(kcpKind("FrontProxy"), "frontproxy", ns, map[string]interface{}{"type": "Available", "status": "True"})))
+	require.NoError(t, cl.Create(ctx, healthObject(helmRelease, "cert-manager", ns, map[string]interface{}{"type": "Ready", "status": "True"})))
+	require.NoError(t, cl.Create(ctx, healthObject(helmRelease, "keycloak", ns,
+		map[string]interface{}{"type": "Ready", "status": "False", "message": "install retries exhausted"})))
+	require.NoError(t, cl.Create(ctx, healthObject(helmRelease, "portal", ns,
+		map[string]interface{}{"type": "Ready", "status": "False", "message": "upgrade failed"})))
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	report := NewHealthReport()
+	kcp := config.KCPConfig{Namespace: ns, RootShardName: "root", FrontProxyName: "frontproxy"}
+	h := NewHealthAggregator(cl, cl, kcp, time.Minute, report, log)
+	h.Check(ctx)
+
+	updated := &corev1alpha1.PlatformMesh{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(inst), updated))
+	health := map[string]corev1alpha1.ComponentHealth{}
+	for _, c := range updated.Status.Components {
+		health[c.Name] = c
+	}
+	assert.Len(t, health, 5)
+	assert.True(t, health["RootShard"].Healthy)
+	assert.True(t, health["FrontProxy"].Healthy)
+	assert.True(t, health["cert-manager"].Healthy)
+	assert.False(t, health["keycloak"].Healthy)
+	assert.Equal(t, "Ready=False: install retries exhausted", health["keycloak"].Message)
+	assert.False(t, health["releases"].Healthy)
+	assert.Equal(t, "HelmRelease portal: Ready=False: upgrade failed", health["releases"].Message)
+
+	cond := apimeta.FindStatusCondition(updated.Status.Conditions, ComponentsReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "Unhealthy components: keycloak, releases", cond.Message)
+
+	err = report.Checker(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "platform-mesh-system/pm: keycloak: Ready=False: install retries exhausted")
+
+	rec := httptest.NewRecorder()
+	report.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/components", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"keycloak","healthy":false`)
+}
+
+func TestMergeComponentHealth(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	previous := []corev1alpha1.ComponentHealth{
+		{Name: "keycloak", Healthy: true, LastTransitionTime: before},
+		{Name: "istiod", Healthy: true, LastTransitionTime: before},
+	}
+	merged := mergeComponentHealth(previous, []corev1alpha1.ComponentHealth{
+		{Name: "keycloak", Healthy: true},
+		{Name: "istiod", Healthy: false, Message: "Ready=False"},
+		{Name: "cert-manager", Healthy: true},
+	}, now)
+
+	assert.Equal(t, before, merged[0].LastTransitionTime)
+	assert.Equal(t, now, merged[1].LastTransitionTime)
+	assert.Equal(t, now, merged[2].LastTransitionTime)
+}
+
+func TestReleaseHealthy(t *testing.T) {
+	app := unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":   "Application",
+		"status": map[string]interface{}{"sync": map[string]interface{}{"status": "OutOfSync"}, "health": map[string]interface{}{"status": "Healthy"}},
+	}}
+	healthy, msg := releaseHealthy(app)
+	assert.False(t, healthy)
+	assert.Equal(t, `sync status "OutOfSync", health status "Healthy"`, msg)
+
+	suspended := unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "HelmRelease",
+		"spec": map[string]interface{}{"suspend": true},
+	}}
+	healthy, _ = releaseHealthy(suspended)
+	assert.True(t, healthy)
+
+	missing := unstructured.Unstructured{Object: map[string]interface{}{"kind": "HelmRelease"}}
+	healthy, msg = releaseHealthy(missing)
+	assert.False(t, healthy)
+	assert.Equal(t, "Ready condition not reported", msg)
+}
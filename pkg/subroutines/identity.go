@@ -0,0 +1,345 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	gcerrors "github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/keycloak"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+const (
+	IdentitySubroutineName = "IdentitySubroutine"
+	// IdentityReadyConditionType is True once the Keycloak realm and the OIDC
+	// clients exist and their secrets are written.
+	IdentityReadyConditionType = "IdentityReady"
+
+	// OIDCClientLabel is set on the Secrets holding the credentials of an
+	// OIDC client, with the client ID as value.
+	OIDCClientLabel = "core.platform-mesh.io/oidc-client"
+
+	keycloakReleaseName    = "keycloak"
+	keycloakRequestTimeout = 10 * time.Second
+
+	oidcClientIDKey     = "client-id"
+	oidcClientSecretKey = "client-secret"
+	oidcIssuerURLKey    = "issuer-url"
+)
+
+// KeycloakClient is the part of the Keycloak admin API the identity
+// subroutine uses.
+type KeycloakClient interface {
+	Login(ctx context.Context, username, password string) error
+	GetRealm(ctx context.Context, realm string) error
+	CreateRealm(ctx context.Context, realm string) error
+	FindClient(ctx context.Context, realm, clientID string) (*keycloak.ClientRepresentation, error)
+	CreateClient(ctx context.Context, realm string, client keycloak.ClientRepresentation) error
+	UpdateClient(ctx context.Context, realm string, client keycloak.ClientRepresentation) error
+	GetClientSecret(ctx context.Context, realm, id string) (string, error)
+}
+
+// IdentitySubroutine provisions the Keycloak realm of the platform and the
+// OIDC clients of the portal and the kcp front-proxy once the keycloak release
+// is ready. The redirect URIs follow spec.exposure, and the client
+// credentials are written to Secrets next to the PlatformMesh.
+type IdentitySubroutine struct {
+	client      client.Client
+	clientInfra client.Client
+	cfg         config.IdentitySubroutineConfig
+	newClient   func(url string) KeycloakClient
+	requeue     *RequeueBackoff
+}
+
+func NewIdentitySubroutine(client, clientInfra client.Client, cfg *config.OperatorConfig) *IdentitySubroutine {
+	return &IdentitySubroutine{
+		client:      client,
+		clientInfra: clientInfra,
+		cfg:         cfg.Subroutines.Identity,
+		newClient: func(url string) KeycloakClient {
+			return keycloak.NewClient(url, &http.Client{Timeout: keycloakRequestTimeout})
+		},
+		requeue: NewRequeueBackoff(IdentitySubroutineName, cfg.Subroutines.Identity.Requeue),
+	}
+}
+
+func (r *IdentitySubroutine) GetName() string {
+	return IdentitySubroutineName
+}
+
+// Finalize leaves the realm in place, it holds the users of the platform.
+// The client Secrets are owned by the PlatformMesh and garbage collected.
+func (r *IdentitySubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *IdentitySubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *IdentitySubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		labelResult := "success"
+		if err != nil {
+			labelResult = "error"
+		}
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), labelResult).Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	if plan.IsPlanning(ctx) {
+		return subroutines.OK(), nil
+	}
+
+	ready, msg, err := r.keycloakReady(ctx, inst)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+	if !ready {
+		setIdentityCondition(inst, metav1.ConditionFalse, "WaitingForKeycloak", msg)
+		return subroutines.Pending(r.requeue.Next(inst), msg), nil
+	}
+
+	password, err := r.adminPassword(ctx, inst)
+	if err != nil {
+		log.Warn().Err(err).Msg("Keycloak admin password not available")
+		setIdentityCondition(inst, metav1.ConditionFalse, "AdminSecretUnavailable", err.Error())
+		return subroutines.Pending(r.requeue.Next(inst), "keycloak admin password not available"), nil
+	}
+
+	kc := r.newClient(r.url(inst))
+	secrets, err := r.provision(ctx, kc, inst, password)
+	if err != nil {
+		log.Info().Err(err).Msg("Failed to provision keycloak")
+		setIdentityCondition(inst, metav1.ConditionFalse, "ProvisioningFailed", err.Error())
+		return subroutines.Pending(r.requeue.Next(inst), "keycloak not provisioned"), nil
+	}
+
+	clientIDs := make([]string, 0, len(secrets))
+	for _, c := range identityClients(inst) {
+		if err := r.writeClientSecret(ctx, inst, c.ClientID, secrets[c.ClientID]); err != nil {
+			log.Error().Err(err).Str("client", c.ClientID).Msg("Failed to write OIDC client secret")
+			return subroutines.OK(), gcerrors.Wrap(err, "Failed to write secret of OIDC client %s", c.ClientID)
+		}
+		clientIDs = append(clientIDs, c.ClientID)
+	}
+
+	setIdentityCondition(inst, metav1.ConditionTrue, "Ready",
+		fmt.Sprintf("Realm %s is provisioned with clients %s", r.cfg.Realm, strings.Join(clientIDs, ", ")))
+	return subroutines.OK(), nil
+}
+
+// provision creates the realm and the OIDC clients of inst and returns the
+// client secrets by client ID.
+func (r *IdentitySubroutine) provision(ctx context.Context, kc KeycloakClient, inst *corev1alpha1.PlatformMesh, password string) (map[string]string, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	if err := kc.Login(ctx, r.cfg.AdminUser, password); err != nil {
+		return nil, err
+	}
+
+	err := kc.GetRealm(ctx, r.cfg.Realm)
+	if errors.Is(err, keycloak.ErrNotFound) {
+		log.Info().Str("realm", r.cfg.Realm).Msg("Creating keycloak realm")
+		err = kc.CreateRealm(ctx, r.cfg.Realm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ensuring realm %s: %w", r.cfg.Realm, err)
+	}
+
+	secrets := map[string]string{}
+	for _, desired := range identityClients(inst) {
+		id, err := r.ensureClient(ctx, kc, desired)
+		if err != nil {
+			return nil, fmt.Errorf("ensuring client %s: %w", desired.ClientID, err)
+		}
+		secret, err := kc.GetClientSecret(ctx, r.cfg.Realm, id)
+		if err != nil {
+			return nil, fmt.Errorf("getting secret of client %s: %w", desired.ClientID, err)
+		}
+		secrets[desired.ClientID] = secret
+	}
+	return secrets, nil
+}
+
+// ensureClient creates desired or updates the existing client when it
+// differs, and returns its internal ID.
+func (r *IdentitySubroutine) ensureClient(ctx context.Context, kc KeycloakClient, desired keycloak.ClientRepresentation) (string, error) {
+	existing, err := kc.FindClient(ctx, r.cfg.Realm, desired.ClientID)
+	if errors.Is(err, keycloak.ErrNotFound) {
+		if err := kc.CreateClient(ctx, r.cfg.Realm, desired); err != nil {
+			return "", err
+		}
+		existing, err = kc.FindClient(ctx, r.cfg.Realm, desired.ClientID)
+		if err != nil {
+			return "", err
+		}
+		return existing.ID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !clientUpToDate(existing, desired) {
+		desired.ID = existing.ID
+		if err := kc.UpdateClient(ctx, r.cfg.Realm, desired); err != nil {
+			return "", err
+		}
+	}
+	return existing.ID, nil
+}
+
+func (r *IdentitySubroutine) writeClientSecret(ctx context.Context, inst *corev1alpha1.PlatformMesh, clientID, clientSecret string) error {
+	_, baseDomainPort, _, protocol := baseDomainPortProtocol(inst)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: oidcClientSecretName(inst, clientID), Namespace: inst.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.client, secret, func() error {
+		setInstanceLabels(secret, inst)
+		LabelSecretPurpose(secret, SecretPurposeOIDCClient, "")
+		secret.Labels[OIDCClientLabel] = clientID
+		if !metav1.IsControlledBy(secret, inst) {
+			secret.SetOwnerReferences(append(secret.GetOwnerReferences(), *metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))))
+		}
+		SetSecretData(secret, map[string][]byte{
+			oidcClientIDKey:     []byte(clientID),
+			oidcClientSecretKey: []byte(clientSecret),
+			oidcIssuerURLKey:    []byte(fmt.Sprintf("%s://%s/keycloak/realms/%s", protocol, baseDomainPort, r.cfg.Realm)),
+		})
+		return nil
+	})
+	return err
+}
+
+// keycloakReady reports whether the keycloak release of inst is ready, and
+// why not otherwise.
+func (r *IdentitySubroutine) keycloakReady(ctx context.Context, inst *corev1alpha1.PlatformMesh) (bool, string, error) {
+	for _, gvk := range releaseKinds {
+		rel := &unstructured.Unstructured{}
+		rel.SetGroupVersionKind(gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List")))
+		err := r.clientInfra.Get(ctx, types.NamespacedName{Name: keycloakReleaseName, Namespace: inst.Namespace}, rel)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return false, "", gcerrors.Wrap(err, "Failed to get keycloak %s", rel.GetKind())
+		}
+		ready, msg := releaseHealthy(*rel)
+		if !ready {
+			return false, fmt.Sprintf("keycloak %s is not ready: %s", rel.GetKind(), msg), nil
+		}
+		return true, "", nil
+	}
+	return false, "keycloak release not found", nil
+}
+
+func (r *IdentitySubroutine) adminPassword(ctx context.Context, inst *corev1alpha1.PlatformMesh) (string, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: instanceNamespace(inst), Name: r.cfg.AdminSecretName}
+	if err := r.client.Get(ctx, key, secret); err != nil {
+		return "", fmt.Errorf("getting Secret %s: %w", key, err)
+	}
+	password := secret.Data[r.cfg.AdminSecretKey]
+	if len(password) == 0 {
+		return "", fmt.Errorf("secret %s has no %s key", key, r.cfg.AdminSecretKey)
+	}
+	return string(password), nil
+}
+
+func (r *IdentitySubroutine) url(inst *corev1alpha1.PlatformMesh) string {
+	if r.cfg.URL != "" {
+		return r.cfg.URL
+	}
+	return fmt.Sprintf("http://keycloak.%s/keycloak", instanceNamespace(inst))
+}
+
+// identityClients returns the OIDC clients of inst: the portal, redirecting to
+// each base domain and its organization subdomains, and the kcp front-proxy,
+// redirecting to the local callback of kubectl oidc-login.
+func identityClients(inst *corev1alpha1.PlatformMesh) []keycloak.ClientRepresentation {
+	_, _, _, protocol := baseDomainPortProtocol(inst)
+	_, domainPorts := exposureDomains(inst)
+	var portalRedirectURIs []string
+	for _, domainPort := range domainPorts {
+		portalRedirectURIs = append(portalRedirectURIs,
+			fmt.Sprintf("%s://%s/*", protocol, domainPort),
+			fmt.Sprintf("%s://*.%s/*", protocol, domainPort),
+		)
+	}
+	return []keycloak.ClientRepresentation{
+		{
+			ClientID:            "portal",
+			Enabled:             true,
+			Protocol:            "openid-connect",
+			StandardFlowEnabled: true,
+			RedirectURIs:        portalRedirectURIs,
+			WebOrigins:          []string{"+"},
+			Attributes:          map[string]string{"post.logout.redirect.uris": "+"},
+		},
+		{
+			ClientID:            "kcp",
+			Enabled:             true,
+			Protocol:            "openid-connect",
+			StandardFlowEnabled: true,
+			RedirectURIs:        []string{"http://localhost:8000", "http://localhost:18000"},
+		},
+	}
+}
+
+// clientUpToDate reports whether existing has the settings of desired.
+// Keycloak does not keep the order of redirect URIs and web origins.
+func clientUpToDate(existing *keycloak.ClientRepresentation, desired keycloak.ClientRepresentation) bool {
+	if existing.Enabled != desired.Enabled || existing.PublicClient != desired.PublicClient ||
+		existing.StandardFlowEnabled != desired.StandardFlowEnabled || existing.Protocol != desired.Protocol {
+		return false
+	}
+	if !sameElements(existing.RedirectURIs, desired.RedirectURIs) || !sameElements(existing.WebOrigins, desired.WebOrigins) {
+		return false
+	}
+	for k, v := range desired.Attributes {
+		if existing.Attributes[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sameElements(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func oidcClientSecretName(inst *corev1alpha1.PlatformMesh, clientID string) string {
+	return inst.Name + "-oidc-" + clientID
+}
+
+func setIdentityCondition(inst *corev1alpha1.PlatformMesh, status metav1.ConditionStatus, reason, message string) {
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               IdentityReadyConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: inst.Generation,
+	})
+}
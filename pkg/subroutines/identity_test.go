@@ -0,0 +1,185 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/keycloak"
+)
+
+type fakeKeycloakClient struct {
+	password string
+	realms   map[string]bool
+	clients  map[string]*keycloak.ClientRepresentation
+	updates  int
+}
+
+func (f *fakeKeycloakClient) Login(_ context.Context, _, password string) error {
+	if password != f.password {
+		return fmt.Errorf("401 Unauthorized")
+	}
+	return nil
+}
+
+func (f *fakeKeycloakClient) GetRealm(_ context.Context, realm string) error {
+	if !f.realms[realm] {
+		return keycloak.ErrNotFound
+	}
+	return nil
+}
+
+func (f *fakeKeycloakClient) CreateRealm(_ context.Context, realm string) error {
+	f.realms[realm] = true
+	return nil
+}
+
+func (f *fakeKeycloakClient) FindClient(_ context.Context, _, clientID string) (*keycloak.ClientRepresentation, error) {
+	if c, ok := f.clients[clientID]; ok {
+		cl := *c
+		return &cl, nil
+	}
+	return nil, keycloak.ErrNotFound
+}
+
+func (f *fakeKeycloakClient) CreateClient(_ context.Context, _ string, client keycloak.ClientRepresentation) error {
+	client.ID = "id-" + client.ClientID
+	f.clients[client.ClientID] = &client
+	return nil
+}
+
+func (f *fakeKeycloakClient) UpdateClient(_ context.Context, _ string, client keycloak.ClientRepresentation) error {
+	f.updates++
+	f.clients[client.ClientID] = &client
+	return nil
+}
+
+func (f *fakeKeycloakClient) GetClientSecret(_ context.Context, _, id string) (string, error) {
+	return "secret-of-" + id, nil
+}
+
+func TestIdentitySubroutine_Process(t *testing.T) {
+	ns := "platform-mesh-system"
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "keycloak-admin", Namespace: ns}, Data: map[string][]byte{"secret": []byte("admin")}},
+	).Build()
+
+	cfg := config.NewOperatorConfig()
+	r := NewIdentitySubroutine(cl, cl, &cfg)
+	kc := &fakeKeycloakClient{password: "admin", realms: map[string]bool{}, clients: map[string]*keycloak.ClientRepresentation{}}
+	var url string
+	r.newClient = func(u string) KeycloakClient {
+		url = u
+		return kc
+	}
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: ns, UID: "uid"},
+		Spec:       corev1alpha1.PlatformMeshSpec{Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "example.com", Port: 443}},
+	}
+
+	// Nothing is provisioned before the keycloak release is ready.
+	res, err := r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, IdentityReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, "WaitingForKeycloak", cond.Reason)
+
+	helmRelease := schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"}
+	release := healthObject(helmRelease, "keycloak", ns, map[string]interface{}{"type": "Ready", "status": "False"})
+	require.NoError(t, cl.Create(ctx, release))
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	assert.Empty(t, kc.realms)
+
+	require.NoError(t, unstructured.SetNestedSlice(release.Object, []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}, "status", "conditions"))
+	require.NoError(t, cl.Update(ctx, release))
+
+	// The first ready run creates the realm, the clients and their Secrets.
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsPending())
+	assert.Equal(t, "http://keycloak.platform-mesh-system/keycloak", url)
+	assert.True(t, kc.realms["platform-mesh"])
+	require.Contains(t, kc.clients, "portal")
+	assert.Equal(t, []string{"https://example.com/*", "https://*.example.com/*"}, kc.clients["portal"].RedirectURIs)
+	require.Contains(t, kc.clients, "kcp")
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, IdentityReadyConditionType))
+
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "pm-oidc-portal", Namespace: ns}, secret))
+	assert.Equal(t, "portal", string(secret.Data["client-id"]))
+	assert.Equal(t, "secret-of-id-portal", string(secret.Data["client-secret"]))
+	assert.Equal(t, "https://example.com/keycloak/realms/platform-mesh", string(secret.Data["issuer-url"]))
+	assert.Equal(t, "portal", secret.Labels[OIDCClientLabel])
+	assert.True(t, metav1.IsControlledBy(secret, inst))
+
+	// Unchanged clients are not updated, a changed exposure updates them.
+	_, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Zero(t, kc.updates)
+
+	inst.Spec.Exposure.Port = 8443
+	_, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, 1, kc.updates)
+	assert.Equal(t, []string{"https://example.com:8443/*", "https://*.example.com:8443/*"}, kc.clients["portal"].RedirectURIs)
+	assert.Equal(t, "id-portal", kc.clients["portal"].ID)
+
+	// A wrong admin password retries without failing the reconcile.
+	kc.password = "rotated"
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, IdentityReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, "ProvisioningFailed", cond.Reason)
+}
+
+func TestClientUpToDate(t *testing.T) {
+	desired := identityClients(&corev1alpha1.PlatformMesh{})[0]
+
+	existing := desired
+	existing.ID = "1"
+	existing.RedirectURIs = []string{desired.RedirectURIs[1], desired.RedirectURIs[0]}
+	existing.Attributes = map[string]string{"post.logout.redirect.uris": "+", "pkce.code.challenge.method": "S256"}
+	assert.True(t, clientUpToDate(&existing, desired))
+
+	existing.WebOrigins = nil
+	assert.False(t, clientUpToDate(&existing, desired))
+}
+
+func TestIdentityClientsAdditionalBaseDomains(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Exposure: &corev1alpha1.ExposureConfig{
+		BaseDomain:            "example.com",
+		Port:                  8443,
+		AdditionalBaseDomains: []string{"example.org", "example.com"},
+	}}}
+	assert.Equal(t, []string{
+		"https://example.com:8443/*", "https://*.example.com:8443/*",
+		"https://example.org:8443/*", "https://*.example.org:8443/*",
+	}, identityClients(inst)[0].RedirectURIs)
+}
@@ -0,0 +1,134 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	RequiresRecreateConditionType  = "RequiresRecreate"
+	requiresRecreateReasonChanged  = "ImmutableFieldChanged"
+	requiresRecreateReasonDeleted  = "Recreating"
+	requiresRecreateReasonDeferred = "DeferredToMaintenanceWindow"
+)
+
+// immutableFields lists the fields of KCP kinds that cannot be changed on an
+// existing object.
+var immutableFields = map[schema.GroupKind][][]string{
+	{Group: "tenancy.kcp.io", Kind: "Workspace"}: {
+		{"spec", "type", "name"},
+		{"spec", "type", "path"},
+	},
+	{Group: "apis.kcp.io", Kind: "APIBinding"}: {
+		{"spec", "reference", "export", "name"},
+		{"spec", "reference", "export", "path"},
+	},
+}
+
+// RecreateRequiredError is returned when a desired KCP object differs from the
+// live object in immutable fields and can only be applied by recreating it.
+type RecreateRequiredError struct {
+	Kind      string
+	Name      string
+	Workspace string
+	Fields    []string
+	// Deleting is true when the live object is being deleted so that it can be
+	// recreated with the desired state.
+	Deleting bool
+	// Deferred is true when the deletion waits for the next maintenance
+	// window.
+	Deferred bool
+}
+
+func (e *RecreateRequiredError) Error() string {
+	return fmt.Sprintf("%s %s in workspace %s requires recreation, immutable fields changed: %s",
+		e.Kind, e.Name, e.Workspace, strings.Join(e.Fields, ", "))
+}
+
+// immutableFieldConflicts returns the immutable fields set in desired that
+// differ from live, formatted as "field: live -> desired".
+func immutableFieldConflicts(desired, live *unstructured.Unstructured) []string {
+	paths, ok := immutableFields[desired.GroupVersionKind().GroupKind()]
+	if !ok {
+		return nil
+	}
+	var conflicts []string
+	for _, path := range paths {
+		desiredValue, found, err := unstructured.NestedFieldNoCopy(desired.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		liveValue, found, err := unstructured.NestedFieldNoCopy(live.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		if !reflect.DeepEqual(desiredValue, liveValue) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v -> %v", strings.Join(path, "."), liveValue, desiredValue))
+		}
+	}
+	return conflicts
+}
+
+// detectRecreateRequired is called after applying desired failed. It compares
+// desired with the live object and returns a *RecreateRequiredError if an
+// immutable field changed. With the Delete policy the live object is deleted so
+// that the next reconciliation recreates it, unless the maintenance gate of ctx
+// defers the recreation. Otherwise it returns nil.
+func detectRecreateRequired(ctx context.Context, k8sClient client.Client, desired *unstructured.Unstructured, wsPath string, policy v1alpha1.DeletionPolicy) error {
+	if _, ok := immutableFields[desired.GroupVersionKind().GroupKind()]; !ok {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx)
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, live); err != nil {
+		return nil
+	}
+
+	conflicts := immutableFieldConflicts(desired, live)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	recreateErr := &RecreateRequiredError{
+		Kind:      desired.GetKind(),
+		Name:      desired.GetName(),
+		Workspace: wsPath,
+		Fields:    conflicts,
+		Deleting:  live.GetDeletionTimestamp() != nil,
+	}
+	if policy == v1alpha1.DeletionPolicyDelete && !recreateErr.Deleting {
+		target := fmt.Sprintf("%s %s in workspace %s", recreateErr.Kind, recreateErr.Name, wsPath)
+		if !maintenanceGateFrom(ctx).allow(ctx, v1alpha1.MaintenanceRecreate, target, strings.Join(conflicts, ", ")) {
+			recreateErr.Deferred = true
+			return recreateErr
+		}
+		if err := k8sClient.Delete(ctx, live, client.PropagationPolicy("Background")); err != nil {
+			log.Error().Err(err).Str("kind", recreateErr.Kind).Str("name", recreateErr.Name).Str("workspace", wsPath).
+				Msg("Failed to delete object for recreation")
+			return recreateErr
+		}
+		log.Info().Str("kind", recreateErr.Kind).Str("name", recreateErr.Name).Str("workspace", wsPath).
+			Strs("fields", conflicts).Msg("Deleted object to recreate it with changed immutable fields")
+		recreateErr.Deleting = true
+	}
+	return recreateErr
+}
+
+func deletionPolicy(inst *v1alpha1.PlatformMesh) v1alpha1.DeletionPolicy {
+	if inst == nil || inst.Spec.Kcp.DeletionPolicy == "" {
+		return v1alpha1.DeletionPolicyRetain
+	}
+	return inst.Spec.Kcp.DeletionPolicy
+}
@@ -0,0 +1,182 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+)
+
+type ImmutableFieldsTestSuite struct {
+	suite.Suite
+	clientMock *mocks.Client
+	log        *logger.Logger
+	ctx        context.Context
+}
+
+func TestImmutableFieldsTestSuite(t *testing.T) {
+	suite.Run(t, new(ImmutableFieldsTestSuite))
+}
+
+func (s *ImmutableFieldsTestSuite) SetupTest() {
+	s.clientMock = new(mocks.Client)
+
+	logCfg := logger.DefaultConfig()
+	logCfg.Level = "debug"
+	logCfg.NoJSON = true
+	logCfg.Name = "ImmutableFieldsTestSuite"
+	s.log, _ = logger.New(logCfg)
+	s.ctx = context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+}
+
+func workspace(name, typeName, typePath string) *unstructured.Unstructured {
+	ws := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tenancy.kcp.io/v1alpha1",
+		"kind":       "Workspace",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"type": map[string]interface{}{"name": typeName, "path": typePath},
+		},
+	}}
+	return ws
+}
+
+func (s *ImmutableFieldsTestSuite) mockLiveWorkspace(live *unstructured.Unstructured) {
+	s.clientMock.EXPECT().
+		Get(mock.Anything, types.NamespacedName{Name: live.GetName()}, mock.AnythingOfType("*unstructured.Unstructured")).
+		RunAndReturn(func(ctx context.Context, nn types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+			live.DeepCopyInto(obj.(*unstructured.Unstructured))
+			return nil
+		})
+}
+
+func (s *ImmutableFieldsTestSuite) TestImmutableFieldConflicts() {
+	desired := workspace("orgs", "orgs", "root")
+	live := workspace("orgs", "organization", "root")
+
+	s.Equal([]string{"spec.type.name: organization -> orgs"}, immutableFieldConflicts(desired, live))
+	s.Empty(immutableFieldConflicts(desired, desired))
+
+	// fields omitted in the desired object are defaulted by kcp and not compared
+	unstructured.RemoveNestedField(desired.Object, "spec", "type", "path")
+	live = workspace("orgs", "orgs", "root:other")
+	s.Empty(immutableFieldConflicts(desired, live))
+
+	other := &unstructured.Unstructured{}
+	other.SetAPIVersion("v1")
+	other.SetKind("ConfigMap")
+	s.Empty(immutableFieldConflicts(other, other))
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_Retain() {
+	s.mockLiveWorkspace(workspace("orgs", "organization", "root"))
+
+	err := detectRecreateRequired(s.ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyRetain)
+
+	var recreateErr *RecreateRequiredError
+	s.Require().ErrorAs(err, &recreateErr)
+	s.Equal("Workspace", recreateErr.Kind)
+	s.Equal("root", recreateErr.Workspace)
+	s.Equal([]string{"spec.type.name: organization -> orgs"}, recreateErr.Fields)
+	s.False(recreateErr.Deleting)
+	s.clientMock.AssertNotCalled(s.T(), "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_Delete() {
+	s.mockLiveWorkspace(workspace("orgs", "organization", "root"))
+	s.clientMock.EXPECT().Delete(mock.Anything, mock.AnythingOfType("*unstructured.Unstructured"), mock.Anything).Return(nil).Once()
+
+	err := detectRecreateRequired(s.ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyDelete)
+
+	var recreateErr *RecreateRequiredError
+	s.Require().ErrorAs(err, &recreateErr)
+	s.True(recreateErr.Deleting)
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_Deferred() {
+	s.mockLiveWorkspace(workspace("orgs", "organization", "root"))
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{MaintenanceWindows: []corev1alpha1.MaintenanceWindow{
+		{Cron: "0 2 * * sat", Duration: metav1.Duration{Duration: time.Hour}},
+	}}}
+	ctx, gate := withMaintenanceGate(s.ctx, inst, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+
+	err := detectRecreateRequired(ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyDelete)
+
+	var recreateErr *RecreateRequiredError
+	s.Require().ErrorAs(err, &recreateErr)
+	s.True(recreateErr.Deferred)
+	s.False(recreateErr.Deleting)
+	s.clientMock.AssertNotCalled(s.T(), "Delete", mock.Anything, mock.Anything, mock.Anything)
+	s.Equal([]corev1alpha1.DeferredOperation{{
+		Operation: corev1alpha1.MaintenanceRecreate,
+		Target:    "Workspace orgs in workspace root",
+		Message:   "spec.type.name: organization -> orgs",
+	}}, gate.deferred)
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_AlreadyDeleting() {
+	live := workspace("orgs", "organization", "root")
+	now := metav1.Now()
+	live.SetDeletionTimestamp(&now)
+	s.mockLiveWorkspace(live)
+
+	err := detectRecreateRequired(s.ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyDelete)
+
+	var recreateErr *RecreateRequiredError
+	s.Require().ErrorAs(err, &recreateErr)
+	s.True(recreateErr.Deleting)
+	s.clientMock.AssertNotCalled(s.T(), "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_NoConflict() {
+	s.mockLiveWorkspace(workspace("orgs", "orgs", "root"))
+
+	s.NoError(detectRecreateRequired(s.ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyDelete))
+}
+
+func (s *ImmutableFieldsTestSuite) TestDetectRecreateRequired_GetError() {
+	s.clientMock.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("boom"))
+
+	s.NoError(detectRecreateRequired(s.ctx, s.clientMock, workspace("orgs", "orgs", "root"), "root", corev1alpha1.DeletionPolicyRetain))
+}
+
+func (s *ImmutableFieldsTestSuite) TestRequiresRecreate() {
+	r := &KcpsetupSubroutine{}
+	inst := &corev1alpha1.PlatformMesh{}
+	err := &RecreateRequiredError{Kind: "Workspace", Name: "orgs", Workspace: "root", Fields: []string{"spec.type.name: organization -> orgs"}}
+
+	res, ok := r.requiresRecreate(inst, err, s.log)
+	s.True(ok)
+	s.True(res.IsStopWithRequeue())
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, RequiresRecreateConditionType)
+	s.Require().NotNil(cond)
+	s.Equal(metav1.ConditionTrue, cond.Status)
+	s.Equal(requiresRecreateReasonChanged, cond.Reason)
+	s.Contains(cond.Message, "spec.type.name: organization -> orgs")
+
+	err.Deleting = true
+	_, ok = r.requiresRecreate(inst, err, s.log)
+	s.True(ok)
+	s.Equal(requiresRecreateReasonDeleted, apimeta.FindStatusCondition(inst.Status.Conditions, RequiresRecreateConditionType).Reason)
+
+	err.Deleting, err.Deferred = false, true
+	_, ok = r.requiresRecreate(inst, err, s.log)
+	s.True(ok)
+	s.Equal(requiresRecreateReasonDeferred, apimeta.FindStatusCondition(inst.Status.Conditions, RequiresRecreateConditionType).Reason)
+
+	_, ok = r.requiresRecreate(inst, errors.New("other"), s.log)
+	s.False(ok)
+}
@@ -0,0 +1,250 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	kcpcorev1alpha "github.com/kcp-dev/kcp/sdk/apis/core/v1alpha1"
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// logicalClusterAnnotation holds the logical cluster name of a kcp object.
+const logicalClusterAnnotation = "kcp.io/cluster"
+
+// Initializer runs the WorkspaceType initializers of spec.kcp.initializers of
+// all PlatformMesh instances. It implements manager.Runnable: every interval
+// it lists the logical clusters waiting for each initializer in the
+// initializing virtual workspaces of the WorkspaceType, applies the manifests
+// into them and removes the initializer, so kcp can finish the workspace.
+type Initializer struct {
+	client       client.Client
+	kcpConfig    config.KCPConfig
+	kcpUrl       string
+	workspaceDir string
+	interval     time.Duration
+	log          *logger.Logger
+	// newClient returns a client for the kcp URL host with the credentials
+	// of cfg.
+	newClient func(cfg *rest.Config, host string) (client.Client, error)
+}
+
+func NewInitializer(cl client.Client, kcpConfig config.KCPConfig, kcpUrl, workspaceDir string, interval time.Duration, log *logger.Logger) *Initializer {
+	return &Initializer{
+		client:       cl,
+		kcpConfig:    kcpConfig,
+		kcpUrl:       kcpUrl,
+		workspaceDir: workspaceDir,
+		interval:     interval,
+		log:          log.ChildLogger("component", "initializer"),
+		newClient:    newInitializerClient,
+	}
+}
+
+func (i *Initializer) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, i.Sync, i.interval)
+	return nil
+}
+
+func (i *Initializer) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync runs every initializer of every instance once. Failures are logged,
+// the logical clusters keep waiting and are retried on the next sync.
+func (i *Initializer) Sync(ctx context.Context) {
+	ctx = context.WithValue(ctx, keys.LoggerCtxKey, i.log)
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := i.client.List(ctx, list); err != nil {
+		i.log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return
+	}
+
+	for idx := range list.Items {
+		inst := &list.Items[idx]
+		if inst.DeletionTimestamp != nil || len(inst.Spec.Kcp.Initializers) == 0 {
+			continue
+		}
+		cfg, _, err := BuildKubeconfigWithFallback(i.client, &i.kcpConfig, inst.Spec.Kcp.AdminSecretRefs, i.kcpUrl)
+		if err != nil {
+			i.log.Debug().Err(err).Str("instance", instanceKey(inst)).Msg("Cannot build kcp kubeconfig yet, not running initializers")
+			continue
+		}
+		for _, wi := range inst.Spec.Kcp.Initializers {
+			if err := i.runInitializer(ctx, inst, wi, cfg); err != nil {
+				i.log.Error().Err(err).Str("instance", instanceKey(inst)).Str("workspaceType", wi.Path+":"+wi.WorkspaceTypeName).
+					Msg("Failed to run initializer")
+			}
+		}
+	}
+}
+
+// runInitializer initializes the logical clusters waiting for wi in the
+// initializing virtual workspaces of all shards.
+func (i *Initializer) runInitializer(ctx context.Context, inst *corev1alpha1.PlatformMesh, wi corev1alpha1.WorkspaceInitializer, cfg *rest.Config) error {
+	kcpURL, err := url.Parse(cfg.Host)
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse kcp host: %s", cfg.Host)
+	}
+	kcpHost := kcpURL.Scheme + "://" + kcpURL.Host
+
+	typeClient, err := i.newClient(cfg, kcpHost+"/clusters/"+wi.Path)
+	if err != nil {
+		return err
+	}
+	wt := &kcptenancyv1alpha.WorkspaceType{}
+	if err := typeClient.Get(ctx, types.NamespacedName{Name: wi.WorkspaceTypeName}, wt); err != nil {
+		return errors.Wrap(err, "Failed to get WorkspaceType %s in workspace %s", wi.WorkspaceTypeName, wi.Path)
+	}
+	if len(wt.Status.VirtualWorkspaces) == 0 {
+		return errors.New("WorkspaceType %s in workspace %s has no initializing virtual workspace", wi.WorkspaceTypeName, wi.Path)
+	}
+
+	initializer := initializerName(wt)
+	var manifests []workspaceManifest
+	loadManifests := func() ([]workspaceManifest, error) {
+		if manifests == nil {
+			if manifests, err = loadWorkspaceManifests(ctx, i.client, i.workspaceDir, inst, wi.Manifests); err != nil {
+				return nil, errors.Wrap(err, "Failed to load manifests of initializer %s", initializer)
+			}
+		}
+		return manifests, nil
+	}
+	// Each shard serves the logical clusters it holds in its own initializing
+	// virtual workspace. A shard that fails does not hold up the others.
+	for _, vw := range wt.Status.VirtualWorkspaces {
+		if err := i.initializeVirtualWorkspace(ctx, cfg, vw.URL, initializer, loadManifests, inst, wi); err != nil {
+			i.log.Error().Err(err).Str("initializer", string(initializer)).Str("virtualWorkspace", vw.URL).
+				Msg("Failed to initialize the logical clusters of a virtual workspace")
+		}
+	}
+	return nil
+}
+
+// initializeVirtualWorkspace initializes the logical clusters waiting for
+// initializer in the initializing virtual workspace at vwHost. A logical
+// cluster that fails is logged and keeps waiting, the others go on.
+func (i *Initializer) initializeVirtualWorkspace(
+	ctx context.Context, cfg *rest.Config, vwHost string, initializer kcpcorev1alpha.LogicalClusterInitializer,
+	loadManifests func() ([]workspaceManifest, error), inst *corev1alpha1.PlatformMesh, wi corev1alpha1.WorkspaceInitializer,
+) error {
+	vwClient, err := i.newClient(cfg, vwHost+"/clusters/*")
+	if err != nil {
+		return err
+	}
+	lcs := &kcpcorev1alpha.LogicalClusterList{}
+	if err := vwClient.List(ctx, lcs); err != nil {
+		return errors.Wrap(err, "Failed to list initializing logical clusters of WorkspaceType %s", wi.WorkspaceTypeName)
+	}
+
+	for idx := range lcs.Items {
+		lc := &lcs.Items[idx]
+		cluster := lc.Annotations[logicalClusterAnnotation]
+		if cluster == "" || !slices.Contains(lc.Status.Initializers, initializer) {
+			continue
+		}
+		manifests, err := loadManifests()
+		if err != nil {
+			return err
+		}
+		if err := i.initializeCluster(ctx, cfg, vwHost, cluster, initializer, manifests, inst, wi); err != nil {
+			i.log.Error().Err(err).Str("initializer", string(initializer)).Str("logicalCluster", cluster).Msg("Failed to initialize logical cluster")
+			continue
+		}
+		i.log.Info().Str("initializer", string(initializer)).Str("logicalCluster", cluster).Msg("Initialized logical cluster")
+	}
+	return nil
+}
+
+// initializeCluster applies manifests into the logical cluster and removes
+// initializer from it once all of them were applied. Both go through the
+// initializing virtual workspace at vwHost, as kcp does not serve a logical
+// cluster that is not ready yet anywhere else.
+func (i *Initializer) initializeCluster(
+	ctx context.Context, cfg *rest.Config, vwHost, cluster string, initializer kcpcorev1alpha.LogicalClusterInitializer,
+	manifests []workspaceManifest, inst *corev1alpha1.PlatformMesh, wi corev1alpha1.WorkspaceInitializer,
+) error {
+	baseDomain, baseDomainPort, port, protocol := baseDomainPortProtocol(inst)
+	base := map[string]any{
+		"baseDomain":     baseDomain,
+		"baseDomainPort": baseDomainPort,
+		"port":           fmt.Sprintf("%d", port),
+		"protocol":       protocol,
+		"logicalCluster": cluster,
+		"workspaceType":  wi.WorkspaceTypeName,
+	}
+	maps.Copy(base, domainTemplateData(inst))
+	data, err := workspaceTemplateData(base, wi.Manifests)
+	if err != nil {
+		return err
+	}
+
+	lcClient, err := i.newClient(cfg, vwHost+"/clusters/"+cluster)
+	if err != nil {
+		return err
+	}
+	var errApplyManifests error
+	for _, m := range manifests {
+		if err := applyManifestTemplate(ctx, m.source, m.data, lcClient, data, cluster, inst, nil); err != nil {
+			errApplyManifests = keepApplyError(errApplyManifests, err)
+		}
+	}
+	if errApplyManifests != nil {
+		return errApplyManifests
+	}
+
+	lc := &kcpcorev1alpha.LogicalCluster{}
+	if err := lcClient.Get(ctx, types.NamespacedName{Name: kcpcorev1alpha.LogicalClusterName}, lc); err != nil {
+		return errors.Wrap(err, "Failed to get logical cluster %s", cluster)
+	}
+	// Other initializers may remove theirs concurrently.
+	patch := client.MergeFromWithOptions(lc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	lc.Status.Initializers = slices.DeleteFunc(lc.Status.Initializers, func(in kcpcorev1alpha.LogicalClusterInitializer) bool {
+		return in == initializer
+	})
+	if err := lcClient.Status().Patch(ctx, lc, patch); err != nil {
+		return errors.Wrap(err, "Failed to remove initializer %s from logical cluster %s", initializer, cluster)
+	}
+	return nil
+}
+
+// initializerName is the initializer of wt, <logical cluster of wt>:<name>, as
+// kcp names it.
+func initializerName(wt *kcptenancyv1alpha.WorkspaceType) kcpcorev1alpha.LogicalClusterInitializer {
+	return kcpcorev1alpha.LogicalClusterInitializer(wt.Annotations[logicalClusterAnnotation] + ":" + wt.Name)
+}
+
+func newInitializerClient(cfg *rest.Config, host string) (client.Client, error) {
+	cfg = rest.CopyConfig(cfg)
+	cfg.Host = host
+	scheme := runtime.NewScheme()
+	if err := kcpapiv1alpha.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := kcpcorev1alpha.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := kcptenancyv1alpha.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create KCP initializer client: %w", err)
+	}
+	return cl, nil
+}
@@ -0,0 +1,143 @@
+package subroutines
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kcpcorev1alpha "github.com/kcp-dev/kcp/sdk/apis/core/v1alpha1"
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+const initializedClusterRole = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: initialized-by-{{ .workspaceType }}
+  labels:
+    owner: "{{ .owner }}"
+rules: []
+`
+
+func TestInitializerName(t *testing.T) {
+	wt := &kcptenancyv1alpha.WorkspaceType{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team",
+		Annotations: map[string]string{logicalClusterAnnotation: "2x7dkw9hz3bc"},
+	}}
+	assert.Equal(t, kcpcorev1alpha.LogicalClusterInitializer("2x7dkw9hz3bc:team"), initializerName(wt))
+}
+
+func TestInitializerRunInitializer(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	initializer := kcpcorev1alpha.LogicalClusterInitializer("root:team-init")
+	other := kcpcorev1alpha.LogicalClusterInitializer("root:other")
+	shardA := "https://a.shard.internal:6443/services/initializingworkspaces/root:team-init"
+	shardB := "https://b.shard.internal:6443/services/initializingworkspaces/root:team-init"
+
+	// The WorkspaceType and a workspace of it waiting for its initializer.
+	require.NoError(t, server.AddObjects("root", &kcptenancyv1alpha.WorkspaceType{
+		TypeMeta: metav1.TypeMeta{APIVersion: "tenancy.kcp.io/v1alpha1", Kind: "WorkspaceType"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-init",
+			Annotations: map[string]string{logicalClusterAnnotation: "root"},
+		},
+		Status: kcptenancyv1alpha.WorkspaceTypeStatus{VirtualWorkspaces: []kcptenancyv1alpha.VirtualWorkspace{
+			{URL: shardB}, {URL: shardA},
+		}},
+	}))
+	root, err := newInitializerClient(server.RestConfig(), server.URL()+"/clusters/root")
+	require.NoError(t, err)
+	require.NoError(t, applyWorkspace(ctx, root, "root", "team", corev1alpha1.WorkspaceTypeReference{Name: "universal", Path: "root"}, nil, inst))
+	ws, ok := server.Get("root", schema.GroupVersionResource{Group: "tenancy.kcp.io", Version: "v1alpha1", Resource: "workspaces"}, "", "team")
+	require.True(t, ok)
+	cluster, _, _ := unstructured.NestedString(ws.Object, "spec", "cluster")
+	require.NotEmpty(t, cluster)
+	team, err := newInitializerClient(server.RestConfig(), server.URL()+"/clusters/"+cluster)
+	require.NoError(t, err)
+	lc := &kcpcorev1alpha.LogicalCluster{}
+	require.NoError(t, team.Get(ctx, types.NamespacedName{Name: kcpcorev1alpha.LogicalClusterName}, lc))
+	lc.Status.Initializers = []kcpcorev1alpha.LogicalClusterInitializer{initializer, other}
+	require.NoError(t, team.Status().Update(ctx, lc))
+
+	// The virtual workspace lists the waiting logical clusters of all
+	// initializers, only the one of team-init is initialized.
+	vwScheme := runtime.NewScheme()
+	require.NoError(t, kcpcorev1alpha.AddToScheme(vwScheme))
+	vwList := fake.NewClientBuilder().WithScheme(vwScheme).WithObjects(
+		&kcpcorev1alpha.LogicalCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Annotations: map[string]string{logicalClusterAnnotation: cluster}},
+			Status:     kcpcorev1alpha.LogicalClusterStatus{Initializers: []kcpcorev1alpha.LogicalClusterInitializer{initializer, other}},
+		},
+		&kcpcorev1alpha.LogicalCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Annotations: map[string]string{logicalClusterAnnotation: "unrelated"}},
+			Status:     kcpcorev1alpha.LogicalClusterStatus{Initializers: []kcpcorev1alpha.LogicalClusterInitializer{other}},
+		},
+	).Build()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-init", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"role.yaml": initializedClusterRole},
+	}
+	var hosts []string
+	i := &Initializer{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build(),
+		log:    log,
+		newClient: func(cfg *rest.Config, host string) (client.Client, error) {
+			hosts = append(hosts, host)
+			switch host {
+			case shardA + "/clusters/*":
+				return vwList, nil
+			case shardB + "/clusters/*":
+				return nil, assert.AnError
+			}
+			// The fake kcp serves the virtual workspace of single logical
+			// clusters under their plain cluster URL.
+			return newInitializerClient(cfg, strings.Replace(host, shardA, server.URL(), 1))
+		},
+	}
+	wi := corev1alpha1.WorkspaceInitializer{
+		WorkspaceTypeName: "team-init",
+		Path:              "root",
+		Manifests: corev1alpha1.WorkspaceManifestSource{
+			ConfigMapRef: &corev1alpha1.ConfigMapReference{Name: "team-init"},
+			TemplateData: &apiextensionsv1.JSON{Raw: []byte(`{"owner":"platform"}`)},
+		},
+	}
+	require.NoError(t, i.runInitializer(ctx, inst, wi, server.RestConfig()))
+
+	// The virtual workspaces of all shards are listed, one that fails does
+	// not hold up the others. The logical cluster is written through the
+	// virtual workspace of its shard.
+	assert.Contains(t, hosts, shardB+"/clusters/*")
+	assert.Contains(t, hosts, shardA+"/clusters/*")
+	assert.Contains(t, hosts, shardA+"/clusters/"+cluster)
+	assert.NotContains(t, hosts, server.URL()+"/clusters/"+cluster)
+	role, ok := server.Get("root:team", schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, "", "initialized-by-team-init")
+	require.True(t, ok)
+	assert.Equal(t, "platform", role.GetLabels()["owner"])
+	require.NoError(t, team.Get(ctx, types.NamespacedName{Name: kcpcorev1alpha.LogicalClusterName}, lc))
+	assert.Equal(t, []kcpcorev1alpha.LogicalClusterInitializer{other}, lc.Status.Initializers)
+}
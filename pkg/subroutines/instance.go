@@ -0,0 +1,79 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// defaultInstanceNamespace is the namespace of instances without one, such
+// as connections handled without an instance.
+const defaultInstanceNamespace = "platform-mesh-system"
+
+// instanceNamespace returns the namespace the components of inst are released
+// into, and defaultInstanceNamespace for instances without one.
+func instanceNamespace(inst *corev1alpha1.PlatformMesh) string {
+	if inst == nil || inst.Namespace == "" {
+		return defaultInstanceNamespace
+	}
+	return inst.Namespace
+}
+
+// kcpOwner returns the key of the instance that sets up the kcp of inst. The
+// workspace paths of a kcp, such as root:platform-mesh-system, exist once, so
+// of the instances reaching the same kcp endpoint only the oldest one that is
+// not being deleted sets it up. Without another instance that is inst.
+func kcpOwner(ctx context.Context, cl client.Client, cfg *config.OperatorConfig, inst *corev1alpha1.PlatformMesh) (string, error) {
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := cl.List(ctx, list); err != nil {
+		return "", err
+	}
+	endpoint := getExternalKcpHost(inst, cfg)
+	owner := inst
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.DeletionTimestamp != nil || instanceKey(other) == instanceKey(inst) || getExternalKcpHost(other, cfg) != endpoint {
+			continue
+		}
+		older := other.CreationTimestamp.Before(&owner.CreationTimestamp)
+		if older || (other.CreationTimestamp.Equal(&owner.CreationTimestamp) && instanceKey(other) < instanceKey(owner)) {
+			owner = other
+		}
+	}
+	return instanceKey(owner), nil
+}
+
+// labeledInstance returns the instance obj is labeled for as
+// <namespace>/<name>, or "" if it carries no instance labels.
+func labeledInstance(obj client.Object) string {
+	labels := obj.GetLabels()
+	if labels[InstanceNameLabel] == "" {
+		return ""
+	}
+	return labels[InstanceNamespaceLabel] + "/" + labels[InstanceNameLabel]
+}
+
+// setInstanceLabels labels obj as belonging to inst.
+func setInstanceLabels(obj client.Object, inst *corev1alpha1.PlatformMesh) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[InstanceNameLabel] = inst.Name
+	labels[InstanceNamespaceLabel] = inst.Namespace
+	obj.SetLabels(labels)
+}
+
+// claimLabeledObject labels obj for inst unless it is already labeled for
+// another instance.
+func claimLabeledObject(obj client.Object, inst *corev1alpha1.PlatformMesh) error {
+	if owner := labeledInstance(obj); owner != "" && owner != instanceKey(inst) {
+		return fmt.Errorf("%s/%s is labeled for PlatformMesh %s", obj.GetNamespace(), obj.GetName(), owner)
+	}
+	setInstanceLabels(obj, inst)
+	return nil
+}
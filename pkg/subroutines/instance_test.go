@@ -0,0 +1,114 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func webhookCASecret(cfg corev1alpha1.WebhookConfiguration) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: cfg.SecretRef.Name, Namespace: cfg.SecretRef.Namespace},
+		Data:       map[string][]byte{cfg.SecretData: []byte("webhook-ca")},
+	}
+}
+
+func domainCASecret(namespace, ca string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "domain-certificate", Namespace: namespace},
+		Data:       map[string][]byte{"tls.crt": []byte(ca)},
+	}
+}
+
+func TestGetCABundleInventoryPerInstance(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(
+		webhookCASecret(DEFAULT_WEBHOOK_CONFIGURATION),
+		webhookCASecret(DEFAULT_VALIDATING_WEBHOOK_CONFIGURATION),
+		webhookCASecret(DEFAULT_IDENTITY_PROVIDER_VALIDATING_WEBHOOK_CONFIGURATION),
+		domainCASecret("tenant-a", "ca-a"),
+		domainCASecret("tenant-b", "ca-b"),
+	).Build()
+	r := NewKcpsetupSubroutine(cl, nil, defaultTestOperatorConfig(), "", "")
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	instA := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-a"}}
+	instB := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-b"}}
+
+	inventoryA, err := r.getCABundleInventory(ctx, instA)
+	require.NoError(t, err)
+	inventoryB, err := r.getCABundleInventory(ctx, instB)
+	require.NoError(t, err)
+	assert.Equal(t, "ca-a", inventoryA["domainCADec"])
+	assert.Equal(t, "ca-b", inventoryB["domainCADec"])
+
+	// A rotated domain CA of one instance does not leak into the cache of the other.
+	require.NoError(t, cl.Update(ctx, domainCASecret("tenant-b", "ca-b2")))
+	inventoryA, err = r.getCABundleInventory(ctx, instA)
+	require.NoError(t, err)
+	assert.Equal(t, "ca-a", inventoryA["domainCADec"])
+}
+
+func TestClaimLabeledObject(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-a"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "initializer", Namespace: "platform-mesh-system"}}
+
+	require.NoError(t, claimLabeledObject(secret, inst))
+	assert.Equal(t, "tenant-a/pm", labeledInstance(secret))
+	require.NoError(t, claimLabeledObject(secret, inst))
+
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-b"}}
+	err := claimLabeledObject(secret, other)
+	assert.EqualError(t, err, "platform-mesh-system/initializer is labeled for PlatformMesh tenant-a/pm")
+	assert.Equal(t, "tenant-a/pm", labeledInstance(secret))
+
+	assert.Empty(t, labeledInstance(&corev1.Secret{}))
+	assert.Equal(t, defaultInstanceNamespace, instanceNamespace(&corev1alpha1.PlatformMesh{}))
+}
+
+func TestKcpOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	older := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-a", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))}}
+	newer := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-b", CreationTimestamp: metav1.Now()}}
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "tenant-c", CreationTimestamp: metav1.Now()}}
+	other.Spec.Exposure = &corev1alpha1.ExposureConfig{Protocol: "https", BaseDomain: "tenant-c.example.com", Port: 443}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(older, newer, other).Build()
+	cfg := defaultTestOperatorConfig()
+	ctx := context.Background()
+
+	// Instances reaching the same kcp leave it to the oldest one.
+	owner, err := kcpOwner(ctx, cl, cfg, newer)
+	require.NoError(t, err)
+	assert.Equal(t, instanceKey(older), owner)
+	owner, err = kcpOwner(ctx, cl, cfg, older)
+	require.NoError(t, err)
+	assert.Equal(t, instanceKey(older), owner)
+
+	// An instance exposing another kcp sets up its own.
+	owner, err = kcpOwner(ctx, cl, cfg, other)
+	require.NoError(t, err)
+	assert.Equal(t, instanceKey(other), owner)
+
+	// An instance being deleted hands the kcp over.
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(older), older))
+	older.Finalizers = []string{"test"}
+	require.NoError(t, cl.Update(ctx, older))
+	require.NoError(t, cl.Delete(ctx, older))
+	owner, err = kcpOwner(ctx, cl, cfg, newer)
+	require.NoError(t, err)
+	assert.Equal(t, instanceKey(newer), owner)
+}
@@ -0,0 +1,309 @@
+package subroutines
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+const inventoryConfigMapSuffix = "-inventory"
+
+func inventoryConfigMapName(inst *corev1alpha1.PlatformMesh) string {
+	return inst.Name + inventoryConfigMapSuffix
+}
+
+// inventoryEntry is an object applied from the templates of an instance.
+type inventoryEntry struct {
+	// Cluster is plan.ClusterInfra, plan.ClusterRuntime or the name of a
+	// cluster of spec.runtimeClusters.
+	Cluster    string `json:"cluster"`
+	Component  string `json:"component,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// LastGoodDigest is the digest of the last rendered spec of a
+	// HelmRelease that was Ready, which is stored in the last good Secret,
+	// FailedDigest the digest of the rendered spec that was rolled back to it
+	// and AppliedDigest the digest of the rendered spec first seen applied at
+	// AppliedAt, see releaseRollback.
+	LastGoodDigest string       `json:"lastGoodDigest,omitempty"`
+	FailedDigest   string       `json:"failedDigest,omitempty"`
+	AppliedDigest  string       `json:"appliedDigest,omitempty"`
+	AppliedAt      *metav1.Time `json:"appliedAt,omitempty"`
+}
+
+// key identifies the object of e independent of its API version and component.
+func (e inventoryEntry) key() string {
+	return e.Cluster + "/" + schema.FromAPIVersionAndKind(e.APIVersion, e.Kind).GroupKind().String() + "/" + e.Namespace + "/" + e.Name
+}
+
+func compareInventoryEntries(a, b inventoryEntry) int {
+	return cmp.Or(cmp.Compare(a.Cluster, b.Cluster), cmp.Compare(a.Kind, b.Kind),
+		cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name), cmp.Compare(a.APIVersion, b.APIVersion))
+}
+
+// inventory collects the objects applied from the templates of one template
+// type. A nil *inventory records nothing.
+type inventory struct {
+	templateType string
+	// cluster is where recording assumes the manifests are applied.
+	cluster string
+	// client reaches cluster when it is a cluster of spec.runtimeClusters.
+	client  client.Client
+	entries []inventoryEntry
+	// kept are the components whose previous objects stay in the inventory,
+	// because their templates failed to render.
+	kept map[string]bool
+	// released are the disabled components, whose previous objects are left
+	// to pruneDisabledComponents.
+	released map[string]bool
+	// history is the release history to record with the objects, by key.
+	history map[string]inventoryEntry
+}
+
+func newInventory(templateType, cluster string) *inventory {
+	return &inventory{templateType: templateType, cluster: cluster, kept: map[string]bool{}, released: map[string]bool{}, history: map[string]inventoryEntry{}}
+}
+
+// newRuntimeClusterInventory returns the inventory of templateType for the
+// runtime cluster name, which cl reaches.
+func newRuntimeClusterInventory(templateType, name string, cl client.Client) *inventory {
+	inv := newInventory(templateType, name)
+	inv.client = cl
+	return inv
+}
+
+// inventoryClient returns the client of the cluster of e, which inv reaches
+// for the clusters of spec.runtimeClusters.
+func (r *DeploymentSubroutine) inventoryClient(inv *inventory, e inventoryEntry) client.Client {
+	switch {
+	case inv != nil && inv.client != nil:
+		return inv.client
+	case e.Cluster == plan.ClusterInfra:
+		return r.clientInfra
+	default:
+		return r.clientRuntime
+	}
+}
+
+// add records obj of component as applied to cluster.
+func (inv *inventory) add(cluster, component string, obj *unstructured.Unstructured) {
+	if inv == nil {
+		return
+	}
+	e := inventoryEntry{
+		Cluster:    cluster,
+		Component:  component,
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+	if h, ok := inv.history[e.key()]; ok {
+		e.LastGoodDigest, e.FailedDigest, e.AppliedDigest, e.AppliedAt = h.LastGoodDigest, h.FailedDigest, h.AppliedDigest, h.AppliedAt
+	}
+	inv.entries = append(inv.entries, e)
+}
+
+// remember records the release history of e, which is stored with the object
+// of e once it is applied.
+func (inv *inventory) remember(e inventoryEntry) {
+	if inv == nil {
+		return
+	}
+	inv.history[e.key()] = e
+}
+
+// keep leaves the previous objects of component in the inventory.
+func (inv *inventory) keep(component string) {
+	if inv == nil {
+		return
+	}
+	inv.kept[component] = true
+}
+
+// release drops the previous objects of component from the inventory without
+// deleting them.
+func (inv *inventory) release(component string) {
+	if inv == nil {
+		return
+	}
+	inv.released[component] = true
+}
+
+// recording wraps apply to record each manifest it applied without error.
+func (inv *inventory) recording(apply func(ctx context.Context, m renderedManifest) error) func(ctx context.Context, m renderedManifest) error {
+	if inv == nil {
+		return apply
+	}
+	return func(ctx context.Context, m renderedManifest) error {
+		if err := apply(ctx, m); err != nil {
+			return err
+		}
+		inv.add(inv.cluster, m.component, m.obj)
+		return nil
+	}
+}
+
+// loadInventory returns the inventories of inst by template type.
+func (r *DeploymentSubroutine) loadInventory(ctx context.Context, inst *corev1alpha1.PlatformMesh) (map[string][]inventoryEntry, error) {
+	cm := &corev1.ConfigMap{}
+	err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: inventoryConfigMapName(inst), Namespace: inst.Namespace}, cm)
+	if kerrors.IsNotFound(err) {
+		return map[string][]inventoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get inventory")
+	}
+	inventories := map[string][]inventoryEntry{}
+	for templateType, data := range cm.Data {
+		var entries []inventoryEntry
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse %s inventory", templateType)
+		}
+		inventories[templateType] = entries
+	}
+	return inventories, nil
+}
+
+// pruneInventory compares inv with the inventory recorded by the previous
+// apply of its template type and records inv in its place. Objects that are
+// no longer rendered are deleted when PruneOrphanedObjects is set, and kept in
+// the inventory otherwise. Objects another template type applies are only
+// dropped from the inventory. It must only be called once every manifest of
+// inv was applied.
+func (r *DeploymentSubroutine) pruneInventory(ctx context.Context, inst *corev1alpha1.PlatformMesh, inv *inventory, log *logger.Logger) error {
+	if inv == nil {
+		return nil
+	}
+	inventories, err := r.loadInventory(ctx, inst)
+	if err != nil {
+		return err
+	}
+
+	rendered := map[string]bool{}
+	for _, e := range inv.entries {
+		rendered[e.key()] = true
+	}
+	elsewhere := map[string]bool{}
+	for templateType, entries := range inventories {
+		if templateType == inv.templateType {
+			continue
+		}
+		for _, e := range entries {
+			elsewhere[e.key()] = true
+		}
+	}
+
+	next := slices.Clone(inv.entries)
+	for _, e := range inventories[inv.templateType] {
+		switch {
+		case rendered[e.key()], inv.released[e.Component], elsewhere[e.key()]:
+		case inv.kept[e.Component]:
+			next = append(next, e)
+		case !r.cfgOperator.Subroutines.Deployment.PruneOrphanedObjects:
+			log.Info().Str("type", inv.templateType).Str("kind", e.Kind).Str("namespace", e.Namespace).Str("name", e.Name).
+				Msg("Keeping object that is no longer rendered, pruning orphaned objects is disabled")
+			next = append(next, e)
+		default:
+			if err := r.deleteOrphan(ctx, inst, r.inventoryClient(inv, e), inv.templateType, e, log); err != nil {
+				return err
+			}
+		}
+	}
+	return r.writeInventory(ctx, inst, inv.templateType, next)
+}
+
+// deleteOrphan deletes the object of e from k8sClient if it is still labeled
+// for inst.
+func (r *DeploymentSubroutine) deleteOrphan(ctx context.Context, inst *corev1alpha1.PlatformMesh, k8sClient client.Client, templateType string, e inventoryEntry, log *logger.Logger) error {
+	live := &unstructured.Unstructured{}
+	live.SetAPIVersion(e.APIVersion)
+	live.SetKind(e.Kind)
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: e.Name, Namespace: e.Namespace}, live)
+	if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to get orphaned %s %s", e.Kind, e.Name)
+	}
+	if labeledInstance(live) != instanceKey(inst) {
+		log.Info().Str("kind", e.Kind).Str("namespace", e.Namespace).Str("name", e.Name).Msg("Not pruning orphaned object that belongs to another instance")
+		return nil
+	}
+	if !live.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+	if err := client.IgnoreNotFound(k8sClient.Delete(ctx, live)); err != nil {
+		return errors.Wrap(err, "Failed to delete orphaned %s %s", e.Kind, e.Name)
+	}
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + name
+	}
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonOrphanPruned, "Prune", "Pruned %s %s that the %s templates no longer render", e.Kind, name, templateType)
+	return nil
+}
+
+// writeInventory records entries as the inventory of templateType.
+func (r *DeploymentSubroutine) writeInventory(ctx context.Context, inst *corev1alpha1.PlatformMesh, templateType string, entries []inventoryEntry) error {
+	slices.SortFunc(entries, compareInventoryEntries)
+	entries = slices.CompactFunc(entries, func(a, b inventoryEntry) bool { return a.key() == b.key() })
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal %s inventory", templateType)
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: inventoryConfigMapName(inst), Namespace: inst.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.clientRuntime, cm, func() error {
+		setInstanceLabels(cm, inst)
+		if !metav1.IsControlledBy(cm, inst) {
+			cm.SetOwnerReferences(append(cm.GetOwnerReferences(), *metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))))
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[templateType] = string(data)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to write %s inventory", templateType)
+	}
+	return nil
+}
+
+// dropInventory removes the inventory of templateType.
+func (r *DeploymentSubroutine) dropInventory(ctx context.Context, inst *corev1alpha1.PlatformMesh, templateType string) error {
+	cm := &corev1.ConfigMap{}
+	err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: inventoryConfigMapName(inst), Namespace: inst.Namespace}, cm)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to get inventory")
+	}
+	if _, ok := cm.Data[templateType]; !ok {
+		return nil
+	}
+	delete(cm.Data, templateType)
+	if err := r.clientRuntime.Update(ctx, cm); err != nil {
+		return errors.Wrap(err, "Failed to drop %s inventory", templateType)
+	}
+	return nil
+}
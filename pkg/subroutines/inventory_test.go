@@ -0,0 +1,103 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+func TestPruneInventory(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns", UID: "uid"}}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(inst).Build()
+	cfg := &config.OperatorConfig{}
+	sub := &DeploymentSubroutine{clientRuntime: cl, clientInfra: cl, cfgOperator: cfg}
+
+	configMap := func(name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetName(name)
+		obj.SetNamespace("pm-ns")
+		return obj
+	}
+	for _, name := range []string{"iam", "portal", "openfga", "shared", "moved", "foreign"} {
+		obj := configMap(name)
+		if name != "foreign" {
+			setInstanceLabels(obj, inst)
+		}
+		require.NoError(t, cl.Create(ctx, obj))
+	}
+	exists := func(name string) bool {
+		err := cl.Get(ctx, client.ObjectKey{Name: name, Namespace: "pm-ns"}, &corev1.ConfigMap{})
+		require.True(t, err == nil || kerrors.IsNotFound(err))
+		return err == nil
+	}
+	recorded := func(templateType string) []string {
+		inventories, err := sub.loadInventory(ctx, inst)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range inventories[templateType] {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+
+	first := newInventory("components-runtime", plan.ClusterRuntime)
+	for _, name := range []string{"iam", "portal", "openfga", "shared", "moved", "foreign"} {
+		first.add(plan.ClusterRuntime, name, configMap(name))
+	}
+	require.NoError(t, sub.pruneInventory(ctx, inst, first, log))
+	other := newInventory("runtime", plan.ClusterInfra)
+	other.add(plan.ClusterRuntime, "", configMap("moved"))
+	require.NoError(t, sub.pruneInventory(ctx, inst, other, log))
+
+	// Without PruneOrphanedObjects orphans stay in the inventory.
+	second := newInventory("components-runtime", plan.ClusterRuntime)
+	second.add(plan.ClusterRuntime, "iam", configMap("iam"))
+	require.NoError(t, sub.pruneInventory(ctx, inst, second, log))
+	assert.Equal(t, []string{"foreign", "iam", "openfga", "portal", "shared"}, recorded("components-runtime"))
+	assert.True(t, exists("portal"))
+
+	cfg.Subroutines.Deployment.PruneOrphanedObjects = true
+	third := newInventory("components-runtime", plan.ClusterRuntime)
+	third.add(plan.ClusterRuntime, "iam", configMap("iam"))
+	third.keep("openfga")
+	third.release("shared")
+	require.NoError(t, sub.pruneInventory(ctx, inst, third, log))
+	assert.Equal(t, "Normal OrphanPruned Pruned ConfigMap pm-ns/portal that the components-runtime templates no longer render", <-rec.Events)
+	assert.Empty(t, rec.Events)
+
+	assert.Equal(t, []string{"iam", "openfga"}, recorded("components-runtime"))
+	assert.Equal(t, []string{"moved"}, recorded("runtime"))
+	assert.False(t, exists("portal"))
+	// Components that failed to render, released components, objects another
+	// template type applies and objects of other instances are not deleted.
+	assert.True(t, exists("openfga"))
+	assert.True(t, exists("shared"))
+	assert.True(t, exists("moved"))
+	assert.True(t, exists("foreign"))
+}
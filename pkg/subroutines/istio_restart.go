@@ -0,0 +1,241 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// IstioInjectionFailedConditionType is True once the operator gave up
+	// restarting its Deployment to get an istio-proxy injected.
+	IstioInjectionFailedConditionType = "IstioInjectionFailed"
+	// IstioProxyInjectedConditionType tells whether the operator pod runs with
+	// an istio-proxy, or why it does not yet.
+	IstioProxyInjectedConditionType = "IstioProxyInjected"
+
+	// IstioRestartConfigMapName is the ConfigMap in the operator namespace that
+	// counts the restarts for istio-proxy injection across operator pods.
+	IstioRestartConfigMapName = "platform-mesh-operator-istio-restarts"
+	// IstioRestartedAtAnnotation is set on the pod template of the operator
+	// Deployment to roll it out, like kubectl rollout restart does.
+	IstioRestartedAtAnnotation = "core.platform-mesh.io/istio-restarted-at"
+
+	istioRestartsKey    = "restarts"
+	istioLastRestartKey = "lastRestart"
+	istioInjectLabel    = "sidecar.istio.io/inject"
+
+	// podNameEnv and podNamespaceEnv are set from the downward API in the
+	// operator Deployment.
+	podNameEnv      = "POD_NAME"
+	podNamespaceEnv = "POD_NAMESPACE"
+)
+
+// operatorDeployment returns the Deployment the operator pod belongs to. The
+// namespace and name configured for the Deployment subroutine take
+// precedence; otherwise the namespace is POD_NAMESPACE and the Deployment is
+// the one owning the ReplicaSet of the pod POD_NAME.
+func (r *DeploymentSubroutine) operatorDeployment(ctx context.Context) (types.NamespacedName, error) {
+	cfg := r.cfgOperator.Subroutines.Deployment
+	key := types.NamespacedName{Namespace: cfg.OperatorNamespace, Name: cfg.OperatorDeploymentName}
+	if key.Namespace == "" {
+		key.Namespace = os.Getenv(podNamespaceEnv)
+	}
+	if key.Namespace == "" {
+		return key, newClassifiedError(v1alpha1.ErrorCategoryConfig,
+			errors.New("the operator namespace is unknown, set %s or --subroutines-deployment-operator-namespace", podNamespaceEnv))
+	}
+	if key.Name != "" {
+		return key, nil
+	}
+	podName := os.Getenv(podNameEnv)
+	if podName == "" {
+		return key, newClassifiedError(v1alpha1.ErrorCategoryConfig,
+			errors.New("the operator Deployment is unknown, set %s or --subroutines-deployment-operator-deployment-name", podNameEnv))
+	}
+	// Unstructured reads are not cached, so looking up the owners does not
+	// start informers for all pods and ReplicaSets.
+	pod := &unstructured.Unstructured{}
+	pod.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	if err := r.clientInfra.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: podName}, pod); err != nil {
+		return key, errors.Wrap(err, "Failed to get operator pod %s/%s", key.Namespace, podName)
+	}
+	rsRef := metav1.GetControllerOf(pod)
+	if rsRef == nil || rsRef.Kind != "ReplicaSet" {
+		return key, newClassifiedError(v1alpha1.ErrorCategoryConfig,
+			errors.New("operator pod %s/%s is not owned by a ReplicaSet, set --subroutines-deployment-operator-deployment-name", key.Namespace, podName))
+	}
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))
+	if err := r.clientInfra.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: rsRef.Name}, rs); err != nil {
+		return key, errors.Wrap(err, "Failed to get ReplicaSet %s/%s of the operator pod", key.Namespace, rsRef.Name)
+	}
+	deployRef := metav1.GetControllerOf(rs)
+	if deployRef == nil || deployRef.Kind != "Deployment" {
+		return key, newClassifiedError(v1alpha1.ErrorCategoryConfig,
+			errors.New("ReplicaSet %s/%s of the operator pod is not owned by a Deployment, set --subroutines-deployment-operator-deployment-name", key.Namespace, rsRef.Name))
+	}
+	key.Name = deployRef.Name
+	return key, nil
+}
+
+// istioRestarts returns the number of restarts recorded in the restart marker.
+func istioRestarts(ctx context.Context, c client.Client, namespace string) (int, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, client.ObjectKey{Name: IstioRestartConfigMapName, Namespace: namespace}, cm)
+	if kerrors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	restarts, err := strconv.Atoi(cm.Data[istioRestartsKey])
+	if err != nil {
+		// A marker that cannot be read counts as a single restart so that the
+		// cap is still reached.
+		return 1, nil
+	}
+	return restarts, nil
+}
+
+// recordIstioRestart stores restarts in the restart marker.
+func recordIstioRestart(ctx context.Context, c client.Client, namespace string, restarts int) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: IstioRestartConfigMapName, Namespace: namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, cm, func() error {
+		cm.Data = map[string]string{
+			istioRestartsKey:    strconv.Itoa(restarts),
+			istioLastRestartKey: time.Now().UTC().Format(time.RFC3339),
+		}
+		return nil
+	})
+	return err
+}
+
+// resetIstioRestarts removes the restart marker and the IstioInjectionFailed
+// condition once the istio-proxy is injected.
+func resetIstioRestarts(ctx context.Context, c client.Client, inst *v1alpha1.PlatformMesh, namespace string) error {
+	apimeta.RemoveStatusCondition(&inst.Status.Conditions, IstioInjectionFailedConditionType)
+	setIstioProxyInjectedCondition(inst, metav1.ConditionTrue, "Injected", "The operator pod runs with an istio-proxy")
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: IstioRestartConfigMapName, Namespace: namespace}}
+	return client.IgnoreNotFound(c.Delete(ctx, cm))
+}
+
+// clearIstioConditions removes the istio conditions left over from before
+// istio was disabled.
+func clearIstioConditions(inst *v1alpha1.PlatformMesh) {
+	apimeta.RemoveStatusCondition(&inst.Status.Conditions, IstioProxyInjectedConditionType)
+	apimeta.RemoveStatusCondition(&inst.Status.Conditions, IstioInjectionFailedConditionType)
+}
+
+func setIstioProxyInjectedCondition(inst *v1alpha1.PlatformMesh, status metav1.ConditionStatus, reason, msg string) {
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               IstioProxyInjectedConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            msg,
+		ObservedGeneration: inst.Generation,
+	})
+}
+
+// rollingOut reports whether deploy has not yet replaced all of its pods with
+// the current pod template.
+func rollingOut(deploy *appsv1.Deployment) bool {
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return true
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.UpdatedReplicas < replicas || deploy.Status.Replicas > deploy.Status.UpdatedReplicas
+}
+
+// restartForIstio rolls out the operator Deployment key with istio injection
+// enabled on its pod template, so that the new pods get an istio-proxy, and
+// counts the restart in the restart marker. It returns what the reconcile
+// waits for, the restart or a rollout still in progress. Once IstioMaxRestarts
+// restarts did not get an istio-proxy injected, it sets IstioInjectionFailed
+// and returns an empty message instead. Deleting the marker allows new
+// attempts. Outside of the maintenance windows the restart is deferred and
+// the message is empty as well, so the reconcile goes on without the proxy.
+func (r *DeploymentSubroutine) restartForIstio(ctx context.Context, inst *v1alpha1.PlatformMesh, key types.NamespacedName) (string, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	operatorNamespace, operatorDeploymentName := key.Namespace, key.Name
+
+	deploy := &appsv1.Deployment{}
+	if err := r.clientInfra.Get(ctx, key, deploy); err != nil {
+		return "", errors.Wrap(err, "Failed to get operator Deployment %s/%s", operatorNamespace, operatorDeploymentName)
+	}
+	if rollingOut(deploy) {
+		msg := fmt.Sprintf("Waiting for the rollout of Deployment %s/%s to inject istio-proxy", operatorNamespace, operatorDeploymentName)
+		setIstioProxyInjectedCondition(inst, metav1.ConditionFalse, "RolloutInProgress", msg)
+		return msg, nil
+	}
+
+	restarts, err := istioRestarts(ctx, r.clientInfra, operatorNamespace)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read istio restart marker")
+	}
+	maxRestarts := r.cfgOperator.Subroutines.Deployment.IstioMaxRestarts
+	if restarts >= maxRestarts {
+		msg := fmt.Sprintf("istio-proxy was not injected after %d operator restarts, delete ConfigMap %s/%s to retry", restarts, operatorNamespace, IstioRestartConfigMapName)
+		log.Warn().Int("restarts", restarts).Msg("Giving up restarting the operator for istio-proxy injection")
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               IstioInjectionFailedConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             "RestartLimitReached",
+			Message:            msg,
+			ObservedGeneration: inst.Generation,
+		})
+		setIstioProxyInjectedCondition(inst, metav1.ConditionFalse, "RestartLimitReached", msg)
+		return "", nil
+	}
+
+	target := fmt.Sprintf("Deployment %s/%s", operatorNamespace, operatorDeploymentName)
+	if !maintenanceGateFrom(ctx).allow(ctx, v1alpha1.MaintenanceRestart, target, "restart to get istio-proxy injected") {
+		setIstioProxyInjectedCondition(inst, metav1.ConditionFalse, "RestartDeferred",
+			fmt.Sprintf("The restart of %s to get istio-proxy injected is deferred until the next maintenance window", target))
+		return "", nil
+	}
+
+	if err := recordIstioRestart(ctx, r.clientInfra, operatorNamespace, restarts+1); err != nil {
+		return "", errors.Wrap(err, "Failed to record istio restart")
+	}
+	base := deploy.DeepCopy()
+	template := &deploy.Spec.Template
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[istioInjectLabel] = "true"
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[IstioRestartedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.clientInfra.Patch(ctx, deploy, client.MergeFrom(base)); err != nil {
+		return "", errors.Wrap(err, "Failed to restart operator Deployment %s/%s", operatorNamespace, operatorDeploymentName)
+	}
+
+	log.Info().Int("restart", restarts+1).Int("maxRestarts", maxRestarts).Msg("Restarted operator Deployment to get istio-proxy injected")
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonIstioRestarted, "Restart",
+		"Restarted Deployment %s/%s to get istio-proxy injected, restart %d of %d", operatorNamespace, operatorDeploymentName, restarts+1, maxRestarts)
+	msg := fmt.Sprintf("Restarted Deployment %s/%s to get istio-proxy injected", operatorNamespace, operatorDeploymentName)
+	setIstioProxyInjectedCondition(inst, metav1.ConditionFalse, "Restarted", msg)
+	return msg, nil
+}
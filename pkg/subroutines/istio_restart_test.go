@@ -0,0 +1,138 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestRestartForIstio(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.Deployment.IstioMaxRestarts = 2
+	operatorNamespace := "platform-mesh-system"
+	key := types.NamespacedName{Namespace: operatorNamespace, Name: "platform-mesh-operator"}
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deploy).Build()
+	r := &DeploymentSubroutine{clientInfra: cl, cfgOperator: &cfg}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Generation: 4}}
+	setRollout := func(updated int32) {
+		live := &appsv1.Deployment{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(deploy), live))
+		live.Status = appsv1.DeploymentStatus{ObservedGeneration: live.Generation, Replicas: 1, UpdatedReplicas: updated}
+		require.NoError(t, cl.Update(ctx, live))
+	}
+
+	setRollout(1)
+	msg, err := r.restartForIstio(ctx, inst, key)
+	require.NoError(t, err)
+	assert.Equal(t, "Restarted Deployment platform-mesh-system/platform-mesh-operator to get istio-proxy injected", msg)
+	live := &appsv1.Deployment{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(deploy), live))
+	assert.Equal(t, "true", live.Spec.Template.Labels["sidecar.istio.io/inject"])
+	assert.NotEmpty(t, live.Spec.Template.Annotations[IstioRestartedAtAnnotation])
+	restarts, err := istioRestarts(ctx, cl, operatorNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restarts)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, IstioProxyInjectedConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, "Restarted", cond.Reason)
+
+	// A rollout in progress is waited for without counting a restart.
+	setRollout(0)
+	msg, err = r.restartForIstio(ctx, inst, key)
+	require.NoError(t, err)
+	assert.Equal(t, "Waiting for the rollout of Deployment platform-mesh-system/platform-mesh-operator to inject istio-proxy", msg)
+	restarts, err = istioRestarts(ctx, cl, operatorNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restarts)
+	assert.Equal(t, "RolloutInProgress", apimeta.FindStatusCondition(inst.Status.Conditions, IstioProxyInjectedConditionType).Reason)
+
+	setRollout(1)
+	msg, err = r.restartForIstio(ctx, inst, key)
+	require.NoError(t, err)
+	assert.NotEmpty(t, msg)
+
+	// The cap is reached: no further restart, the condition reports it instead.
+	msg, err = r.restartForIstio(ctx, inst, key)
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+	restarts, err = istioRestarts(ctx, cl, operatorNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restarts)
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, IstioInjectionFailedConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, "RestartLimitReached", cond.Reason)
+	assert.Equal(t, int64(4), cond.ObservedGeneration)
+
+	// Once the proxy is injected, the marker and the condition are cleared.
+	require.NoError(t, resetIstioRestarts(ctx, cl, inst, operatorNamespace))
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, IstioInjectionFailedConditionType))
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, IstioProxyInjectedConditionType))
+	restarts, err = istioRestarts(ctx, cl, operatorNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, 0, restarts)
+	require.NoError(t, resetIstioRestarts(ctx, cl, inst, operatorNamespace))
+}
+
+func TestOperatorDeployment(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.NewOperatorConfig()
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "pm-operator-7d9c", Namespace: "operators",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "pm-operator", UID: "deploy", Controller: ptr.To(true)}},
+	}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "pm-operator-7d9c-x2x5z", Namespace: "operators",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, UID: "rs", Controller: ptr.To(true)}},
+	}}
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(rs, pod).Build()
+	r := &DeploymentSubroutine{clientInfra: cl, cfgOperator: &cfg}
+
+	// Without the downward API the Deployment cannot be found.
+	t.Setenv(podNamespaceEnv, "")
+	t.Setenv(podNameEnv, "")
+	_, err := r.operatorDeployment(ctx)
+	require.Error(t, err)
+	assert.Equal(t, corev1alpha1.ErrorCategoryConfig, ClassifyError(err).Category)
+
+	t.Setenv(podNamespaceEnv, "operators")
+	t.Setenv(podNameEnv, pod.Name)
+	key, err := r.operatorDeployment(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.NamespacedName{Namespace: "operators", Name: "pm-operator"}, key)
+
+	// The configured Deployment takes precedence over the owner references.
+	cfg.Subroutines.Deployment.OperatorNamespace = "platform-mesh-system"
+	cfg.Subroutines.Deployment.OperatorDeploymentName = "platform-mesh-operator"
+	key, err = r.operatorDeployment(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.NamespacedName{Namespace: "platform-mesh-system", Name: "platform-mesh-operator"}, key)
+}
+
+func TestClearIstioConditions(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{}
+	setIstioProxyInjectedCondition(inst, metav1.ConditionFalse, "RestartLimitReached", "no istio-proxy")
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{Type: IstioInjectionFailedConditionType, Status: metav1.ConditionTrue, Reason: "RestartLimitReached"})
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{Type: DeploymentReadyConditionType, Status: metav1.ConditionTrue, Reason: "Ready"})
+
+	clearIstioConditions(inst)
+
+	require.Len(t, inst.Status.Conditions, 1)
+	assert.Equal(t, DeploymentReadyConditionType, inst.Status.Conditions[0].Type)
+}
@@ -0,0 +1,126 @@
+package subroutines
+
+import (
+	"context"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+)
+
+// managedKcpObjects returns the objects the kcp manifests and
+// applyExtraWorkspaces apply for inst, in apply order. Objects inside
+// workspaces that are applied themselves are left out, deleting the workspace
+// removes them.
+func (r *KcpsetupSubroutine) managedKcpObjects(ctx context.Context, inst *corev1alpha1.PlatformMesh) ([]kcpObject, error) {
+	objs, _, err := renderKcpManifests(ctx, r.kcpDirectory, inst, r.instanceTemplateData(inst))
+	if err != nil {
+		return nil, err
+	}
+	for _, wsDecl := range inst.Spec.Kcp.ExtraWorkspaces {
+		lastColon := strings.LastIndex(wsDecl.Path, ":")
+		if lastColon == -1 {
+			continue
+		}
+		ws := unstructured.Unstructured{}
+		ws.SetGroupVersionKind(kcptenancyv1alpha.SchemeGroupVersion.WithKind("Workspace"))
+		ws.SetName(wsDecl.Path[lastColon+1:])
+		objs = append(objs, kcpObject{path: wsDecl.Path[:lastColon], obj: ws})
+	}
+
+	workspaces := map[string]bool{}
+	for _, o := range objs {
+		if isWorkspace(o.obj) {
+			workspaces[o.path+":"+o.obj.GetName()] = true
+		}
+	}
+	managed := objs[:0]
+	for _, o := range objs {
+		if !insideWorkspace(o.path, workspaces) {
+			managed = append(managed, o)
+		}
+	}
+	return managed, nil
+}
+
+func isWorkspace(obj unstructured.Unstructured) bool {
+	return obj.GetKind() == "Workspace" && obj.GroupVersionKind().Group == kcptenancyv1alpha.SchemeGroupVersion.Group
+}
+
+// insideWorkspace reports whether path is one of workspaces or below one.
+func insideWorkspace(path string, workspaces map[string]bool) bool {
+	for {
+		if workspaces[path] {
+			return true
+		}
+		lastColon := strings.LastIndex(path, ":")
+		if lastColon == -1 {
+			return false
+		}
+		path = path[:lastColon]
+	}
+}
+
+// deleteKcpObjects deletes objs in reverse apply order and returns how many of
+// them still exist, including the ones whose deletion is in progress. Objects
+// claimed by another PlatformMesh instance are kept.
+func deleteKcpObjects(ctx context.Context, config *rest.Config, kcpHelper KcpHelper, objs []kcpObject, claims *SharedObjectClaims) (int, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	clients := map[string]client.Client{}
+	remaining := 0
+
+	for i := len(objs) - 1; i >= 0; i-- {
+		o := objs[i]
+		kcpClient, ok := clients[o.path]
+		if !ok {
+			var err error
+			kcpClient, err = kcpHelper.NewKcpClient(config, o.path)
+			if err != nil {
+				return remaining, errors.Wrap(err, "Failed to create kcp client for workspace %s", o.path)
+			}
+			clients[o.path] = kcpClient
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(o.obj.GroupVersionKind())
+		err := kcpClient.Get(ctx, types.NamespacedName{Name: o.obj.GetName(), Namespace: o.obj.GetNamespace()}, live)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return remaining, errors.Wrap(err, "Failed to get %s %s in workspace %s", o.obj.GetKind(), o.obj.GetName(), o.path)
+		}
+
+		if owner := live.GetAnnotations()[SharedObjectClaimAnnotation]; owner != "" && owner != claims.owner() {
+			exists, err := claims.instanceExists(ctx, owner)
+			if err != nil {
+				return remaining, err
+			}
+			if exists {
+				log.Info().Str("kind", o.obj.GetKind()).Str("name", o.obj.GetName()).Str("workspace", o.path).Str("owner", owner).
+					Msg("Keeping kcp object claimed by another PlatformMesh instance")
+				continue
+			}
+		}
+
+		remaining++
+		if live.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err := kcpClient.Delete(ctx, live); client.IgnoreNotFound(err) != nil {
+			return remaining, errors.Wrap(err, "Failed to delete %s %s in workspace %s", o.obj.GetKind(), o.obj.GetName(), o.path)
+		}
+		log.Info().Str("kind", o.obj.GetKind()).Str("name", o.obj.GetName()).Str("workspace", o.path).Msg("Deleted kcp object")
+	}
+	return remaining, nil
+}
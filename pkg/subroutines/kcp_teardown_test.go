@@ -0,0 +1,123 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func kcpTeardownDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeManifest(t, dir, "workspace-type-custom.yaml", `apiVersion: tenancy.kcp.io/v1alpha1
+kind: WorkspaceType
+metadata:
+  name: custom
+`)
+	writeManifest(t, dir, "workspace-orgs.yaml", `apiVersion: tenancy.kcp.io/v1alpha1
+kind: Workspace
+metadata:
+  name: orgs
+spec:
+  type:
+    name: organization
+    path: root
+`)
+	writeManifest(t, dir+"/01-orgs", "apibinding-core.yaml", `apiVersion: apis.kcp.io/v1alpha2
+kind: APIBinding
+metadata:
+  name: core
+spec:
+  reference:
+    export:
+      name: core.platform-mesh.io
+      path: root
+`)
+	return dir
+}
+
+func TestManagedKcpObjects(t *testing.T) {
+	r := &KcpsetupSubroutine{kcpDirectory: kcpTeardownDir(t), cfg: &config.OperatorConfig{}}
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{
+		ExtraWorkspaces: []corev1alpha1.WorkspaceDeclaration{{Path: "root:orgs:acme"}, {Path: "root:extra"}},
+	}}}
+
+	objs, err := r.managedKcpObjects(context.Background(), inst)
+	require.NoError(t, err)
+
+	// Content of root:orgs goes with the workspace.
+	var names []string
+	for _, o := range objs {
+		assert.Equal(t, "root", o.path)
+		names = append(names, o.obj.GetKind()+"/"+o.obj.GetName())
+	}
+	assert.Equal(t, []string{"Workspace/orgs", "WorkspaceType/custom", "Workspace/extra"}, names)
+}
+
+func TestDeleteKcpObjects_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	helper := &Helper{}
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	orgType := &kcptenancyv1alpha.WorkspaceTypeReference{Name: "organization", Path: "root"}
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "orgs"}, Spec: kcptenancyv1alpha.WorkspaceSpec{Type: orgType}}))
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "extra"}, Spec: kcptenancyv1alpha.WorkspaceSpec{Type: orgType}}))
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.WorkspaceType{ObjectMeta: metav1.ObjectMeta{
+		Name:        "custom",
+		Annotations: map[string]string{SharedObjectClaimAnnotation: "other/pm"},
+	}}))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "other"}}
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"},
+		Spec:       corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{ExtraWorkspaces: []corev1alpha1.WorkspaceDeclaration{{Path: "root:extra"}}}},
+	}
+	claims := NewSharedObjectClaims(fake.NewClientBuilder().WithScheme(scheme).WithObjects(other).Build(), inst)
+
+	r := &KcpsetupSubroutine{kcpDirectory: kcpTeardownDir(t), cfg: &config.OperatorConfig{}}
+	objs, err := r.managedKcpObjects(ctx, inst)
+	require.NoError(t, err)
+
+	remaining, err := deleteKcpObjects(ctx, server.RestConfig(), helper, objs, claims)
+	require.NoError(t, err)
+	assert.Equal(t, 2, remaining)
+
+	remaining, err = deleteKcpObjects(ctx, server.RestConfig(), helper, objs, claims)
+	require.NoError(t, err)
+	assert.Equal(t, 0, remaining)
+
+	// The WorkspaceType claimed by another instance is kept.
+	require.NoError(t, root.Get(ctx, client.ObjectKey{Name: "custom"}, &kcptenancyv1alpha.WorkspaceType{}))
+}
+
+func TestKcpsetupFinalize_Orphan(t *testing.T) {
+	r := &KcpsetupSubroutine{}
+	for _, policy := range []corev1alpha1.TeardownPolicy{"", corev1alpha1.TeardownPolicyOrphan} {
+		inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{TeardownPolicy: policy}}
+
+		res, err := r.Finalize(context.Background(), inst)
+		require.NoError(t, err)
+		assert.True(t, res.IsContinue())
+	}
+}
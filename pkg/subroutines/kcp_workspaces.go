@@ -0,0 +1,171 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+)
+
+// kcpWorkspaceStep is a workspace of spec.kcp.workspaces with its manifest
+// patterns resolved to files.
+type kcpWorkspaceStep struct {
+	path          string
+	workspaceType *corev1alpha1.WorkspaceTypeReference
+	files         []string
+}
+
+// splitWorkspacePath returns the parent path and the name of the workspace
+// path. It reports false for root, which has no parent.
+func splitWorkspacePath(path string) (string, string, bool) {
+	lastColon := strings.LastIndex(path, ":")
+	if lastColon == -1 {
+		return "", path, false
+	}
+	return path[:lastColon], path[lastColon+1:], true
+}
+
+// resolveWorkspaceSteps resolves the manifest patterns of decls against dir.
+// A pattern that matches no file is an error, as it is most likely a typo.
+func resolveWorkspaceSteps(dir string, decls []corev1alpha1.KcpWorkspaceManifests) ([]kcpWorkspaceStep, error) {
+	declared := map[string]bool{"root": true}
+	steps := make([]kcpWorkspaceStep, 0, len(decls))
+	for _, decl := range decls {
+		if parent, _, ok := splitWorkspacePath(decl.Path); ok && !declared[parent] {
+			return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("workspace %s is declared before its parent %s", decl.Path, parent))
+		}
+		declared[decl.Path] = true
+
+		step := kcpWorkspaceStep{path: decl.Path, workspaceType: decl.Type}
+		for _, pattern := range decl.Manifests {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid manifest pattern %s of workspace %s", pattern, decl.Path)
+			}
+			matched := 0
+			for _, match := range matches {
+				if info, err := os.Stat(match); err != nil || info.IsDir() {
+					continue
+				}
+				step.files = append(step.files, match)
+				matched++
+			}
+			if matched == 0 {
+				return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("manifest pattern %s of workspace %s matches no file", pattern, decl.Path))
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// applyWorkspaceSteps applies steps in order the way ApplyDirStructure applies
+// the numbered directories: a workspace is ready before its manifests are
+// applied, the subtree of a workspace that does not become ready is skipped,
+// and a failing file does not stop the other files of its workspace.
+func (r *KcpsetupSubroutine) applyWorkspaceSteps(
+	ctx context.Context, config *rest.Config, steps []kcpWorkspaceStep, templateData map[string]any,
+	inst *corev1alpha1.PlatformMesh, claims *SharedObjectClaims,
+) error {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	for _, step := range steps {
+		if _, skipped := workspaceWaitFrom(ctx).skipped(step.path); skipped {
+			continue
+		}
+		if parentPath, name, ok := splitWorkspacePath(step.path); ok {
+			if step.workspaceType != nil {
+				parentClient, err := r.kcpHelper.NewKcpClient(config, parentPath)
+				if err != nil {
+					return errors.Wrap(err, "Failed to create kcp client for parent workspace %s", parentPath)
+				}
+				if err := applyWorkspace(ctx, parentClient, parentPath, name, *step.workspaceType, claims, inst); err != nil {
+					return err
+				}
+			}
+			err := checkWorkspaceIn(ctx, config, parentPath, name, log, r.kcpHelper)
+			if workspaceWaitFrom(ctx).skip(ctx, err, inst) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		k8sClient, err := r.kcpHelper.NewKcpClient(config, step.path)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create kcp client for workspace %s", step.path)
+		}
+		var errApplyManifests error
+		for _, file := range step.files {
+			if err := ApplyManifestFromFile(ctx, file, k8sClient, templateData, step.path, inst, claims); err != nil {
+				log.Warn().Err(err).Str("file", file).Str("workspace", step.path).Msg("Failed to apply manifest file, continuing to next file of workspace")
+				errApplyManifests = keepApplyError(errApplyManifests, err)
+			}
+		}
+		if errApplyManifests != nil {
+			return errApplyManifests
+		}
+		apiBindingWaitFrom(ctx).check(ctx, k8sClient, step.path, inst, log)
+	}
+	return nil
+}
+
+// renderWorkspaceSteps renders steps the way renderDirStructure renders the
+// numbered directories. A declared workspace type adds the Workspace to its
+// parent workspace. Files that fail to render are skipped.
+func renderWorkspaceSteps(ctx context.Context, steps []kcpWorkspaceStep, templateData map[string]any) ([]kcpObject, []string) {
+	log := logger.LoadLoggerFromContext(ctx)
+	var objs []kcpObject
+	var order []string
+	seen := map[string]bool{}
+
+	for _, step := range steps {
+		if parentPath, name, ok := splitWorkspacePath(step.path); ok && step.workspaceType != nil {
+			ws := unstructured.Unstructured{}
+			ws.SetGroupVersionKind(kcptenancyv1alpha.SchemeGroupVersion.WithKind("Workspace"))
+			ws.SetName(name)
+			objs = append(objs, kcpObject{path: parentPath, obj: ws})
+		}
+		if !seen[step.path] {
+			seen[step.path] = true
+			order = append(order, step.path)
+		}
+		for _, file := range step.files {
+			obj, err := unstructuredFromFile(file, templateData, log)
+			if err != nil {
+				log.Debug().Err(err).Str("file", file).Msg("Skipping manifest file that failed to render")
+				continue
+			}
+			if obj.Object == nil {
+				continue
+			}
+			objs = append(objs, kcpObject{path: step.path, obj: obj})
+		}
+	}
+	return objs, order
+}
+
+// renderKcpManifests renders the kcp manifests of inst below dir as declared
+// in spec.kcp.workspaces, or from the numbered directories when none are
+// declared.
+func renderKcpManifests(ctx context.Context, dir string, inst *corev1alpha1.PlatformMesh, templateData map[string]any) ([]kcpObject, []string, error) {
+	if len(inst.Spec.Kcp.Workspaces) == 0 {
+		return renderDirStructure(ctx, dir, "root", templateData)
+	}
+	steps, err := resolveWorkspaceSteps(dir, inst.Spec.Kcp.Workspaces)
+	if err != nil {
+		return nil, nil, err
+	}
+	objs, order := renderWorkspaceSteps(ctx, steps, templateData)
+	return objs, order, nil
+}
@@ -0,0 +1,119 @@
+package subroutines
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func declaredWorkspacesDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "root"), "workspace-type-org.yaml", `apiVersion: tenancy.kcp.io/v1alpha1
+kind: WorkspaceType
+metadata:
+  name: org
+`)
+	writeManifest(t, filepath.Join(dir, "orgs"), "configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: orgs
+  namespace: default
+data:
+  domain: {{ .baseDomain }}
+`)
+	return dir
+}
+
+func TestResolveWorkspaceSteps(t *testing.T) {
+	dir := declaredWorkspacesDir(t)
+	orgType := &corev1alpha1.WorkspaceTypeReference{Name: "organization", Path: "root"}
+
+	steps, err := resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{
+		{Path: "root", Manifests: []string{"root/*.yaml"}},
+		{Path: "root:orgs", Type: orgType, Manifests: []string{"orgs/configmap.yaml"}},
+		{Path: "root:orgs:default"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []kcpWorkspaceStep{
+		{path: "root", files: []string{filepath.Join(dir, "root", "workspace-type-org.yaml")}},
+		{path: "root:orgs", workspaceType: orgType, files: []string{filepath.Join(dir, "orgs", "configmap.yaml")}},
+		{path: "root:orgs:default"},
+	}, steps)
+
+	_, err = resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{{Path: "root:orgs:default"}})
+	assert.ErrorContains(t, err, "declared before its parent root:orgs")
+	_, err = resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{{Path: "root", Manifests: []string{"missing/*.yaml"}}})
+	assert.ErrorContains(t, err, "matches no file")
+	// Directories are not manifests.
+	_, err = resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{{Path: "root", Manifests: []string{"*"}}})
+	assert.ErrorContains(t, err, "matches no file")
+}
+
+func TestRenderKcpManifests(t *testing.T) {
+	dir := declaredWorkspacesDir(t)
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{
+		Workspaces: []corev1alpha1.KcpWorkspaceManifests{
+			{Path: "root", Manifests: []string{"root/*.yaml"}},
+			{Path: "root:orgs", Type: &corev1alpha1.WorkspaceTypeReference{Name: "organization", Path: "root"}, Manifests: []string{"orgs/*.yaml"}},
+		},
+	}}}
+
+	objs, order, err := renderKcpManifests(context.Background(), dir, inst, map[string]any{"baseDomain": "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root", "root:orgs"}, order)
+	require.Len(t, objs, 3)
+	assert.Equal(t, "root", objs[1].path)
+	assert.True(t, isWorkspace(objs[1].obj))
+	assert.Equal(t, "orgs", objs[1].obj.GetName())
+	assert.Equal(t, "root:orgs", objs[2].path)
+
+	// Without declarations the numbered directories are rendered.
+	objs, order, err = renderKcpManifests(context.Background(), workspaceContentDir(t), &corev1alpha1.PlatformMesh{}, map[string]any{"name": "account"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root", "root:orgs"}, order)
+	assert.Len(t, objs, 4)
+}
+
+func TestApplyWorkspaceSteps_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	helper := &Helper{}
+
+	dir := declaredWorkspacesDir(t)
+	steps, err := resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{
+		{Path: "root:orgs", Type: &corev1alpha1.WorkspaceTypeReference{Name: "organization", Path: "root"}, Manifests: []string{"orgs/*.yaml"}},
+	})
+	require.NoError(t, err)
+
+	r := &KcpsetupSubroutine{kcpHelper: helper, cfg: &config.OperatorConfig{}}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	require.NoError(t, r.applyWorkspaceSteps(ctx, server.RestConfig(), steps, map[string]any{"baseDomain": "example.com"}, inst, nil))
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	ws := &kcptenancyv1alpha.Workspace{}
+	require.NoError(t, root.Get(ctx, client.ObjectKey{Name: "orgs"}, ws))
+	assert.Equal(t, kcptenancyv1alpha.WorkspaceTypeName("organization"), ws.Spec.Type.Name)
+
+	orgs, err := helper.NewKcpClient(server.RestConfig(), "root:orgs")
+	require.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, orgs.Get(ctx, client.ObjectKey{Namespace: "default", Name: "orgs"}, cm))
+	assert.Equal(t, "example.com", cm.Data["domain"])
+}
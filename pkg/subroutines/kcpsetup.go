@@ -3,8 +3,11 @@ package subroutines
 import (
 	"context"
 	"encoding/base64"
+	stderrors "errors"
 	"fmt"
+	"maps"
 	"strings"
+	"sync"
 	"time"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
@@ -12,11 +15,14 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/platform-mesh/subroutines"
 	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
@@ -32,37 +38,94 @@ type KcpsetupSubroutine struct {
 	kcpHelper    KcpHelper
 	helm         HelmGetter
 	kcpDirectory string
-	// Cache for CA bundles to avoid redundant secret lookups
-	caBundleCache map[string]string
+	// CA bundles per instance key, to avoid redundant secret lookups
+	caBundleMu    sync.Mutex
+	caBundleCache map[string]map[string]string
 	cfg           *config.OperatorConfig
 	kcpUrl        string
+	requeue       *RequeueBackoff
+	// webhooks probes the endpoints of the managed webhook configurations,
+	// nil skips the checks.
+	webhooks *WebhookProber
 }
 
 const (
 	KcpsetupSubroutineName      = "KcpsetupSubroutine"
 	KcpsetupSubroutineFinalizer = "platform-mesh.core.platform-mesh.io/finalizer"
 	fieldManagerKcpSetup        = "platform-mesh-kcp-setup"
+	recreateRequeueInterval     = 5 * time.Minute
 )
 
 func NewKcpsetupSubroutine(client client.Client, helper KcpHelper, cfg *config.OperatorConfig, kcpdir string, kcpUrl string) *KcpsetupSubroutine {
-	return &KcpsetupSubroutine{
+	r := &KcpsetupSubroutine{
 		client:        client,
 		kcpDirectory:  kcpdir,
 		kcpHelper:     helper,
 		helm:          DefaultHelmGetter{},
-		caBundleCache: make(map[string]string),
+		caBundleCache: make(map[string]map[string]string),
 		cfg:           cfg,
 		kcpUrl:        kcpUrl,
+		requeue:       NewRequeueBackoff(KcpsetupSubroutineName, cfg.Subroutines.KcpSetup.Requeue),
 	}
+	return r
+}
+
+// SetWebhookProber sets the prober whose results are recorded for the
+// endpoints of the managed webhook configurations.
+func (r *KcpsetupSubroutine) SetWebhookProber(prober *WebhookProber) {
+	r.webhooks = prober
 }
 
 func (r *KcpsetupSubroutine) GetName() string {
 	return KcpsetupSubroutineName
 }
 
+// Finalize deletes the kcp workspaces and objects created for the instance
+// unless its deletionPolicy is Orphan. Protected workspaces that are not
+// unlocked are kept. It keeps the finalizer until the others are gone.
 func (r *KcpsetupSubroutine) Finalize(
-	_ context.Context, _ client.Object,
+	ctx context.Context, runtimeObj client.Object,
 ) (subroutines.Result, error) {
+	inst, ok := runtimeObj.(*corev1alpha1.PlatformMesh)
+	if !ok {
+		return subroutines.OK(), nil
+	}
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	if r.webhooks != nil {
+		r.webhooks.Forget(client.ObjectKeyFromObject(inst))
+	}
+	if inst.Spec.TeardownPolicy != corev1alpha1.TeardownPolicyDelete {
+		log.Info().Msg("Orphaning kcp workspaces and objects")
+		return subroutines.OK(), nil
+	}
+
+	cfg, err := buildKubeconfig(ctx, r.client, inst, getExternalKcpHost(inst, r.cfg))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build kubeconfig")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
+	}
+	objs, err := r.managedKcpObjects(ctx, inst)
+	if err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to collect kcp objects")
+	}
+	// Unlocked workspaces are released, locked ones are kept.
+	if err := r.protectWorkspaces(ctx, cfg, inst); err != nil {
+		log.Error().Err(err).Msg("Failed to protect workspaces")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to protect workspaces")
+	}
+	for _, path := range inst.Status.ProtectedWorkspaces {
+		log.Info().Str("workspace", path).Msg("Keeping protected workspace")
+	}
+	objs = withoutProtectedWorkspaces(objs, inst.Status.ProtectedWorkspaces)
+	remaining, err := deleteKcpObjects(ctx, cfg, r.kcpHelper, objs, NewSharedObjectClaims(r.client, inst))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete kcp objects")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to delete kcp objects")
+	}
+	if remaining > 0 {
+		return subroutines.Pending(r.requeue.Next(inst), fmt.Sprintf("waiting for %d kcp objects to be deleted", remaining)), nil
+	}
+	log.Info().Msg("Deleted kcp workspaces and objects")
 	return subroutines.OK(), nil
 }
 
@@ -85,6 +148,22 @@ func (r *KcpsetupSubroutine) Process(ctx context.Context, runtimeObj client.Obje
 
 	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
 	log.Debug().Str("subroutine", r.GetName()).Str("name", inst.Name).Msg("Processing Platform Mesh resource")
+	status := trackSteps(inst, KcpSetupReadyConditionType, "WaitingForRootShard")
+	defer func() { status.done(res, err) }()
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	ctx, maintenanceGate := withMaintenanceGate(ctx, inst, time.Now())
+	defer maintenanceGate.report(inst, corev1alpha1.MaintenanceRecreate)
+
+	owner, err := kcpOwner(ctx, r.client, &operatorCfg, inst)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return subroutines.OK(), err
+	}
+	if owner != instanceKey(inst) {
+		log.Warn().Str("owner", owner).Msg("kcp is set up by another PlatformMesh instance")
+		status.enter("KcpOwnedByOtherInstance")
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), fmt.Sprintf("kcp at %s is set up by PlatformMesh %s", getExternalKcpHost(inst, &operatorCfg), owner)), nil
+	}
 
 	rootShard := &unstructured.Unstructured{}
 	rootShard.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: "RootShard"})
@@ -92,49 +171,106 @@ func (r *KcpsetupSubroutine) Process(ctx context.Context, runtimeObj client.Obje
 	err = r.client.Get(ctx, types.NamespacedName{Name: operatorCfg.KCP.RootShardName, Namespace: operatorCfg.KCP.Namespace}, rootShard)
 	if err != nil || !matchesConditionWithStatus(rootShard, "Available", "True") {
 		log.Info().Msg("RootShard is not ready..")
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "RootShard is not ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "RootShard is not ready"), nil
 	}
 
+	status.enter("WaitingForFrontProxy")
 	frontProxy := &unstructured.Unstructured{}
 	frontProxy.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: "FrontProxy"})
 	// Wait for front proxy to be ready
 	err = r.client.Get(ctx, types.NamespacedName{Name: operatorCfg.KCP.FrontProxyName, Namespace: operatorCfg.KCP.Namespace}, frontProxy)
 	if err != nil || !matchesConditionWithStatus(frontProxy, "Available", "True") {
 		log.Info().Msg("FrontProxy is not ready..")
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "FrontProxy is not ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "FrontProxy is not ready"), nil
 	}
 
 	// Build kcp kubeconfig
-	cfg, err := buildKubeconfig(ctx, r.client, getExternalKcpHost(inst, r.cfg))
+	status.enter("BuildingKubeconfig")
+	cfg, err := buildKubeconfig(ctx, r.client, inst, getExternalKcpHost(inst, r.cfg))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to build kubeconfig")
 		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
 	}
 
 	// Create kcp workspaces recursively
+	status.enter("ApplyingKcpManifests")
+	inst.Status.WorkspaceTypeBindings = nil
 	err = r.createKcpResources(ctx, cfg, r.kcpDirectory, inst)
+	if res, ok := r.requiresRecreate(inst, err, log); ok {
+		return res, nil
+	}
+	if res, ok := sharedObjectConflict(inst, err, sharedObjectConflictReasonKcp, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if res, ok := apiBindingsNotReady(err, r.requeue.Next(inst)); ok {
+		return res, nil
+	}
+	if res, ok := workspacesNotReady(err, r.requeue.Next(inst)); ok {
+		return res, nil
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create kcp workspaces")
 		return subroutines.OK(), gcerrors.Wrap(err, "Failed to create kcp workspaces")
 	}
 
+	// Shards that are not set up are retried after the rest of the setup,
+	// which does not depend on them.
+	status.enter("SettingUpShards")
+	shardsNotSetUp := ""
+	failedShards, err := r.setupShards(ctx, cfg, inst)
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Msg("Failed to set up kcp shards")
+		shardsNotSetUp = err.Error()
+	case failedShards > 0:
+		shardsNotSetUp = fmt.Sprintf("%d kcp shard(s) not set up", failedShards)
+	}
+
 	// apply extra workspaces
+	status.enter("ApplyingExtraWorkspaces")
 	err = r.applyExtraWorkspaces(ctx, cfg, inst)
+	if res, ok := r.requiresRecreate(inst, err, log); ok {
+		return res, nil
+	}
+	if res, ok := sharedObjectConflict(inst, err, sharedObjectConflictReasonKcp, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if res, ok := workspacesNotReady(err, r.requeue.Next(inst)); ok {
+		return res, nil
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to apply extra workspaces")
 		return subroutines.OK(), gcerrors.Wrap(err, "Failed to apply extra workspaces")
 	}
 
-	// update workspace status
-	inst.Status.KcpWorkspaces = []corev1alpha1.KcpWorkspace{
-		{
-			Name:  "root:platform-mesh-system",
-			Phase: "Ready",
-		},
-		{
-			Name:  "root:orgs",
-			Phase: "Ready",
-		},
+	status.enter("ApplyingRawManifests")
+	err = r.applyRawManifests(ctx, cfg, inst)
+	if res, ok := sharedObjectConflict(inst, err, sharedObjectConflictReasonKcp, r.requeue.Next(inst), log); ok {
+		return res, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to apply raw manifests")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to apply raw manifests")
+	}
+
+	status.enter("ProtectingWorkspaces")
+	if err := r.protectWorkspaces(ctx, cfg, inst); err != nil {
+		log.Error().Err(err).Msg("Failed to protect workspaces")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to protect workspaces")
+	}
+
+	status.enter("ProvisioningTenantNamespaces")
+	if err := r.provisionTenantNamespaces(ctx, cfg, inst); err != nil {
+		log.Error().Err(err).Msg("Failed to provision tenant namespaces")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to provision tenant namespaces")
+	}
+
+	apimeta.RemoveStatusCondition(&inst.Status.Conditions, RequiresRecreateConditionType)
+	clearSharedObjectConflict(inst, sharedObjectConflictReasonKcp)
+
+	if shardsNotSetUp != "" {
+		status.enter("SettingUpShards")
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), shardsNotSetUp), nil
 	}
 
 	log.Debug().Msg("Successful kcp setup")
@@ -142,20 +278,108 @@ func (r *KcpsetupSubroutine) Process(ctx context.Context, runtimeObj client.Obje
 	return subroutines.OK(), nil
 }
 
+// requiresRecreate reports a RecreateRequiredError through the RequiresRecreate
+// condition instead of failing the reconciliation, which would retry an apply
+// that can never succeed.
+func (r *KcpsetupSubroutine) requiresRecreate(inst *corev1alpha1.PlatformMesh, err error, log *logger.Logger) (subroutines.Result, bool) {
+	var recreateErr *RecreateRequiredError
+	if !stderrors.As(err, &recreateErr) {
+		return subroutines.Result{}, false
+	}
+
+	reason := requiresRecreateReasonChanged
+	requeue := recreateRequeueInterval
+	switch {
+	case recreateErr.Deleting:
+		reason = requiresRecreateReasonDeleted
+		requeue = r.requeue.Next(inst)
+	case recreateErr.Deferred:
+		reason = requiresRecreateReasonDeferred
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               RequiresRecreateConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            recreateErr.Error(),
+		ObservedGeneration: inst.Generation,
+	})
+	log.Warn().Str("kind", recreateErr.Kind).Str("name", recreateErr.Name).Str("workspace", recreateErr.Workspace).
+		Strs("fields", recreateErr.Fields).Bool("deleting", recreateErr.Deleting).Bool("deferred", recreateErr.Deferred).Msg("KCP object requires recreation")
+	return subroutines.StopWithRequeue(requeue, recreateErr.Error()), true
+}
+
 func (r *KcpsetupSubroutine) createKcpResources(ctx context.Context, config *rest.Config, dir string, inst *corev1alpha1.PlatformMesh) error {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	templateData, err := r.kcpTemplateData(ctx, config, inst)
+	if err != nil {
+		return err
+	}
+
+	ctx, bindings := withAPIBindingWait(ctx, r.cfg.Subroutines.KcpSetup.APIBindingTimeout, time.Now())
+	ctx, workspaceWait := withWorkspaceWait(ctx, r.cfg.Subroutines.KcpSetup.WorkspaceTimeout)
+	claims := NewSharedObjectClaims(r.client, inst)
+	if len(inst.Spec.Kcp.Workspaces) > 0 {
+		var steps []kcpWorkspaceStep
+		steps, err = resolveWorkspaceSteps(dir, inst.Spec.Kcp.Workspaces)
+		if err == nil {
+			err = r.applyWorkspaceSteps(ctx, config, steps, templateData, inst, claims)
+		}
+	} else {
+		err = ApplyDirStructure(ctx, dir, "root", config, templateData, inst, claims, r.kcpHelper)
+	}
+	var recreateErr *RecreateRequiredError
+	if stderrors.As(err, &recreateErr) {
+		return recreateErr
+	}
+	var claimedErr *SharedObjectClaimedError
+	if stderrors.As(err, &claimedErr) {
+		return claimedErr
+	}
+	if err != nil {
+		log.Err(err).Msg("Failed to apply dir structure")
+		return gcerrors.Wrap(err, "Failed to apply dir structure")
+	}
+
+	// update workspace status with the managed content found in each workspace
+	objs, order, err := renderKcpManifests(ctx, dir, inst, templateData)
+	if err != nil {
+		log.Err(err).Msg("Failed to collect expected workspace content")
+		return gcerrors.Wrap(err, "Failed to collect expected workspace content")
+	}
+	expected := expectedWorkspaceContent(objs, order)
+	workspaces, err := summarizeWorkspaceContent(ctx, config, r.kcpHelper, expected, order)
+	if err != nil {
+		log.Err(err).Msg("Failed to summarize workspace content")
+		return gcerrors.Wrap(err, "Failed to summarize workspace content")
+	}
+	if err := r.verifyWebhookEndpoints(ctx, inst, config, expected, workspaces); err != nil {
+		log.Err(err).Msg("Failed to verify webhook endpoints")
+		return gcerrors.Wrap(err, "Failed to verify webhook endpoints")
+	}
+	inst.Status.KcpWorkspaces = workspaces
+
+	errBindings := bindings.report(inst)
+	if err := workspaceWait.report(inst); err != nil {
+		return err
+	}
+	return errBindings
+}
+
+// kcpTemplateData returns the template data of the kcp manifests of inst.
+func (r *KcpsetupSubroutine) kcpTemplateData(ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh) (map[string]any, error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	// Get API export hashes
 	apiExportHashes, err := r.getAPIExportHashInventory(ctx, config)
 	if err != nil {
 		log.Err(err).Msg("Failed to get APIExport hash inventory")
-		return gcerrors.Wrap(err, "Failed to get APIExport hash inventory")
+		return nil, gcerrors.Wrap(err, "Failed to get APIExport hash inventory")
 	}
 
 	// Get CA bundle data
-	caBundles, err := r.getCABundleInventory(ctx)
+	caBundles, err := r.getCABundleInventory(ctx, inst)
 	if err != nil {
 		log.Err(err).Msg("Failed to get CA bundle inventory")
-		return gcerrors.Wrap(err, "Failed to get CA bundle inventory")
+		return nil, gcerrors.Wrap(err, "Failed to get CA bundle inventory")
 	}
 
 	// Build templateData as map[string]any to support both strings and arrays
@@ -167,22 +391,14 @@ func (r *KcpsetupSubroutine) createKcpResources(ctx context.Context, config *res
 		templateData[k] = v
 	}
 
-	baseDomain, baseDomainPort, port, protocol := baseDomainPortProtocol(inst)
-	templateData["baseDomain"] = baseDomain
-	templateData["baseDomainPort"] = baseDomainPort
-	templateData["port"] = fmt.Sprintf("%d", port)
-	templateData["protocol"] = protocol
-	templateData["featureDisableEmailVerification"] = HasFeatureToggle(inst, "feature-disable-email-verification")
-	templateData["featureDisableContentConfigurations"] = HasFeatureToggle(inst, "feature-disable-contentconfigurations")
-	templateData["featureEnableTerminalControllerManager"] = HasFeatureToggle(inst, "feature-enable-terminal-controller-manager")
-	templateData["registrationAllowed"] = r.cfg.IDP.RegistrationAllowed
-	templateData["welcomeAdditionalRedirectUris"] = r.cfg.IDP.WelcomeAdditionalRedirectUris
-	templateData["welcomeAdditionalPostLogoutRedirectUris"] = r.cfg.IDP.WelcomeAdditionalPostLogoutRedirectUris
+	for k, v := range r.instanceTemplateData(inst) {
+		templateData[k] = v
+	}
 
 	pmSystemClient, err := r.kcpHelper.NewKcpClient(config, "root:platform-mesh-system")
 	if err != nil {
 		log.Err(err).Msg("Failed to create kcp client for platform-mesh-system workspace")
-		return gcerrors.Wrap(err, "Failed to create kcp client for platform-mesh-system workspace")
+		return nil, gcerrors.Wrap(err, "Failed to create kcp client for platform-mesh-system workspace")
 	}
 
 	templateData["welcomeAudiences"] = []string{}
@@ -195,7 +411,7 @@ func (r *KcpsetupSubroutine) createKcpResources(ctx context.Context, config *res
 		managedClients, found, err := unstructured.NestedMap(ipc.Object, "status", "managedClients")
 		if err != nil {
 			log.Err(err).Msg("Failed to get managedClients from IdentityProviderConfiguration 'welcome'")
-			return gcerrors.Wrap(err, "Failed to get managedClients from IdentityProviderConfiguration 'welcome'")
+			return nil, gcerrors.Wrap(err, "Failed to get managedClients from IdentityProviderConfiguration 'welcome'")
 		}
 
 		if found && len(managedClients) > 0 {
@@ -219,24 +435,48 @@ func (r *KcpsetupSubroutine) createKcpResources(ctx context.Context, config *res
 			}
 		}
 	}
+	return templateData, nil
+}
 
-	err = ApplyDirStructure(ctx, dir, "root", config, templateData, inst, r.kcpHelper)
-	if err != nil {
-		log.Err(err).Msg("Failed to apply dir structure")
-		return gcerrors.Wrap(err, "Failed to apply dir structure")
+// instanceTemplateData returns the template data of the kcp manifests that is
+// derived from inst and the operator configuration alone.
+func (r *KcpsetupSubroutine) instanceTemplateData(inst *corev1alpha1.PlatformMesh) map[string]any {
+	baseDomain, baseDomainPort, port, protocol := baseDomainPortProtocol(inst)
+	data := map[string]any{
+		"baseDomain":                              baseDomain,
+		"baseDomainPort":                          baseDomainPort,
+		"port":                                    fmt.Sprintf("%d", port),
+		"protocol":                                protocol,
+		"featureDisableEmailVerification":         HasFeatureToggle(inst, "feature-disable-email-verification"),
+		"featureDisableContentConfigurations":     HasFeatureToggle(inst, "feature-disable-contentconfigurations"),
+		"featureEnableTerminalControllerManager":  HasFeatureToggle(inst, "feature-enable-terminal-controller-manager"),
+		"registrationAllowed":                     r.cfg.IDP.RegistrationAllowed,
+		"welcomeAdditionalRedirectUris":           r.cfg.IDP.WelcomeAdditionalRedirectUris,
+		"welcomeAdditionalPostLogoutRedirectUris": r.cfg.IDP.WelcomeAdditionalPostLogoutRedirectUris,
 	}
-
-	return nil
+	maps.Copy(data, domainTemplateData(inst))
+	return data
 }
 
+// getCABundleInventory returns the webhook CA bundles and the domain CA of
+// inst. The domain certificate is read from the namespace the components of
+// inst are released into. Results are cached per instance.
 func (r *KcpsetupSubroutine) getCABundleInventory(
 	ctx context.Context,
+	inst *corev1alpha1.PlatformMesh,
 ) (map[string]string, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 
+	r.caBundleMu.Lock()
+	defer r.caBundleMu.Unlock()
+
+	// A recovery reads the bundles again.
+	if RecoveryInProgress(inst) {
+		delete(r.caBundleCache, instanceKey(inst))
+	}
 	// If we already have cached results, return them
-	if len(r.caBundleCache) > 0 {
-		return r.caBundleCache, nil
+	if cached := r.caBundleCache[instanceKey(inst)]; len(cached) > 0 {
+		return cached, nil
 	}
 
 	caBundles := make(map[string]string)
@@ -279,7 +519,7 @@ func (r *KcpsetupSubroutine) getCABundleInventory(
 		SecretData: r.cfg.Subroutines.KcpSetup.DomainCertificateCASecretKey,
 		SecretRef: corev1alpha1.SecretReference{
 			Name:      r.cfg.Subroutines.KcpSetup.DomainCertificateCASecretName,
-			Namespace: "platform-mesh-system",
+			Namespace: instanceNamespace(inst),
 		},
 	})
 	if err != nil {
@@ -291,7 +531,10 @@ func (r *KcpsetupSubroutine) getCABundleInventory(
 	caBundles["domainCADec"] = string(domainCA)
 
 	// Cache the results
-	r.caBundleCache = caBundles
+	if r.caBundleCache == nil {
+		r.caBundleCache = make(map[string]map[string]string)
+	}
+	r.caBundleCache[instanceKey(inst)] = caBundles
 
 	return caBundles, nil
 }
@@ -362,13 +605,16 @@ func (r *KcpsetupSubroutine) applyExtraWorkspaces(ctx context.Context, config *r
 	if inst.Spec.Kcp.ExtraWorkspaces == nil {
 		return nil
 	}
+	claims := NewSharedObjectClaims(r.client, inst)
+	ctx, workspaceWait := withWorkspaceWait(ctx, r.cfg.Subroutines.KcpSetup.WorkspaceTimeout)
+	var templateData map[string]any
 
 	for _, wsDecl := range inst.Spec.Kcp.ExtraWorkspaces {
-		lastColon := strings.LastIndex(wsDecl.Path, ":")
-		if lastColon == -1 {
-			log.Warn().Str("path", wsDecl.Path).Msg("Invalid workspace path format for extraWorkspace, skipping. Must be 'parent:name'.")
+		if err := validateExtraWorkspacePath(wsDecl.Path); err != nil {
+			log.Warn().Err(err).Str("path", wsDecl.Path).Msg("Invalid workspace path for extraWorkspace, skipping. Must be 'root:...:name'.")
 			continue
 		}
+		lastColon := strings.LastIndex(wsDecl.Path, ":")
 		parentPath := wsDecl.Path[:lastColon]
 		workspaceName := wsDecl.Path[lastColon+1:]
 
@@ -379,27 +625,62 @@ func (r *KcpsetupSubroutine) applyExtraWorkspaces(ctx context.Context, config *r
 			return gcerrors.Wrap(err, "Failed to create kcp client for parent workspace %s", parentPath)
 		}
 
-		ws := &kcptenancyv1alpha.Workspace{}
-		ws.APIVersion = kcptenancyv1alpha.SchemeGroupVersion.String()
-		ws.Kind = "Workspace"
-		ws.Name = workspaceName
-		ws.Spec.Type = &kcptenancyv1alpha.WorkspaceTypeReference{
-			Name: kcptenancyv1alpha.WorkspaceTypeName(wsDecl.Type.Name),
-			Path: wsDecl.Type.Path,
+		if err := applyWorkspace(ctx, k8sClient, parentPath, workspaceName, wsDecl.Type, claims, inst); err != nil {
+			return err
 		}
+		log.Info().Str("workspace", wsDecl.Path).Msg("Applied extra workspace")
 
-		unstructuredWs, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ws)
-		if err != nil {
-			return gcerrors.Wrap(err, "failed to convert workspace to unstructured")
+		if wsDecl.Manifests == nil {
+			continue
 		}
-		obj := unstructured.Unstructured{Object: unstructuredWs}
-
-		err = k8sClient.Patch(ctx, &obj, client.Apply, client.FieldOwner(fieldManagerKcpSetup)) //nolint:staticcheck // Apply via Patch is required for unstructured objects
-		if err != nil {
-			return gcerrors.Wrap(err, "Failed to apply extra workspace: %s", obj.GetName())
+		if templateData == nil {
+			templateData, err = r.kcpTemplateData(ctx, config, inst)
+			if err != nil {
+				return err
+			}
 		}
-		log.Info().Str("workspace", wsDecl.Path).Msg("Applied extra workspace")
+		if err := r.applyWorkspaceManifests(ctx, config, wsDecl, templateData, inst, claims); err != nil {
+			return err
+		}
+	}
+	// The kcp manifests reported their workspaces as ready already.
+	if len(workspaceWait.notReady) > 0 {
+		return workspaceWait.report(inst)
+	}
+	return nil
+}
 
+// applyWorkspace applies the workspace name of type wsType in the workspace
+// parentPath with k8sClient.
+func applyWorkspace(
+	ctx context.Context, k8sClient client.Client, parentPath, name string, wsType corev1alpha1.WorkspaceTypeReference,
+	claims *SharedObjectClaims, inst *corev1alpha1.PlatformMesh,
+) error {
+	ws := &kcptenancyv1alpha.Workspace{}
+	ws.APIVersion = kcptenancyv1alpha.SchemeGroupVersion.String()
+	ws.Kind = "Workspace"
+	ws.Name = name
+	ws.Spec.Type = &kcptenancyv1alpha.WorkspaceTypeReference{
+		Name: kcptenancyv1alpha.WorkspaceTypeName(wsType.Name),
+		Path: wsType.Path,
+	}
+
+	unstructuredWs, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ws)
+	if err != nil {
+		return gcerrors.Wrap(err, "failed to convert workspace to unstructured")
+	}
+	obj := unstructured.Unstructured{Object: unstructuredWs}
+
+	if err := claims.claim(ctx, k8sClient, &obj, parentPath); err != nil {
+		return err
+	}
+
+	err = k8sClient.Patch(ctx, &obj, client.Apply, client.FieldOwner(fieldManagerKcpSetup)) //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err != nil {
+		if recreateErr := detectRecreateRequired(ctx, k8sClient, &obj, parentPath, deletionPolicy(inst)); recreateErr != nil {
+			return recreateErr
+		}
+		return gcerrors.Wrap(err, "Failed to apply workspace: %s", obj.GetName())
 	}
 	return nil
 }
@@ -420,6 +701,98 @@ func getExtraDefaultApiBindings(obj unstructured.Unstructured, workspacePath str
 	return res
 }
 
+// addExtraDefaultAPIBindings adds the extra default APIBindings of inst to the
+// WorkspaceType obj applied to wsPath and reports the merge in the status.
+func addExtraDefaultAPIBindings(ctx context.Context, obj *unstructured.Unstructured, wsPath string, inst *corev1alpha1.PlatformMesh) error {
+	extra := getExtraDefaultApiBindings(*obj, wsPath, inst)
+	if len(extra) == 0 {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx)
+	current, found, err := unstructured.NestedSlice(obj.Object, "spec", "defaultAPIBindings")
+	if err != nil || !found {
+		current = []interface{}{}
+	}
+
+	merged := mergeExtraDefaultAPIBindings(current, extra)
+	merged.WorkspaceTypePath = fmt.Sprintf("%s:%s", wsPath, obj.GetName())
+	for _, v := range merged.Added {
+		newExport := kcptenancyv1alpha.APIExportReference{Path: v.Path, Export: v.Export}
+		var m map[string]interface{}
+		b, err := yaml.Marshal(newExport)
+		if err != nil {
+			return gcerrors.Wrap(err, "Failed to marshal APIExportReference")
+		}
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return gcerrors.Wrap(err, "Failed to unmarshal APIExportReference")
+		}
+		current = append(current, m)
+	}
+	for _, v := range merged.Conflicts {
+		log.Warn().Str("workspaceType", merged.WorkspaceTypePath).Str("export", v.Export).Str("path", v.Path).
+			Msg("Extra default APIBinding conflicts with a binding of the same export")
+		recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonDefaultAPIBindingConflict, "MergeDefaultAPIBindings",
+			"Extra default APIBinding of export %s from %s conflicts with a binding of the same export in WorkspaceType %s",
+			v.Export, v.Path, merged.WorkspaceTypePath)
+	}
+	setWorkspaceTypeBindings(inst, merged)
+
+	if err := unstructured.SetNestedSlice(obj.Object, current, "spec", "defaultAPIBindings"); err != nil {
+		return gcerrors.Wrap(err, "Failed to set defaultAPIBindings")
+	}
+	return nil
+}
+
+// mergeExtraDefaultAPIBindings sorts the extra bindings of a WorkspaceType
+// with the defaultAPIBindings current into the ones to add, duplicates of a
+// binding it already has, and conflicts with a binding of the same export from
+// another path. Bindings added earlier in extra count as already present.
+func mergeExtraDefaultAPIBindings(current []interface{}, extra []corev1alpha1.DefaultAPIBindingConfiguration) corev1alpha1.WorkspaceTypeBindings {
+	bound := map[corev1alpha1.APIExportReference]bool{}
+	exportPaths := map[string]string{}
+	for _, c := range current {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path, _ := m["path"].(string)
+		export, _ := m["export"].(string)
+		bound[corev1alpha1.APIExportReference{Path: path, Export: export}] = true
+		if _, ok := exportPaths[export]; !ok {
+			exportPaths[export] = path
+		}
+	}
+
+	var merged corev1alpha1.WorkspaceTypeBindings
+	for _, binding := range extra {
+		ref := corev1alpha1.APIExportReference{Path: binding.Path, Export: binding.Export}
+		path, ok := exportPaths[binding.Export]
+		switch {
+		case bound[ref]:
+			merged.Duplicates = append(merged.Duplicates, ref)
+		case ok && path != binding.Path:
+			merged.Conflicts = append(merged.Conflicts, ref)
+		default:
+			merged.Added = append(merged.Added, ref)
+			bound[ref] = true
+			exportPaths[binding.Export] = binding.Path
+		}
+	}
+	return merged
+}
+
+// setWorkspaceTypeBindings stores the merge result of a WorkspaceType in the
+// status of inst, replacing an earlier one of the same WorkspaceType.
+func setWorkspaceTypeBindings(inst *corev1alpha1.PlatformMesh, merged corev1alpha1.WorkspaceTypeBindings) {
+	for i, b := range inst.Status.WorkspaceTypeBindings {
+		if b.WorkspaceTypePath == merged.WorkspaceTypePath {
+			inst.Status.WorkspaceTypeBindings[i] = merged
+			return
+		}
+	}
+	inst.Status.WorkspaceTypeBindings = append(inst.Status.WorkspaceTypeBindings, merged)
+}
+
 func HasFeatureToggle(inst *corev1alpha1.PlatformMesh, name string) string {
 	for _, ft := range inst.Spec.FeatureToggles {
 		if ft.Name == name {
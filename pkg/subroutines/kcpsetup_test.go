@@ -13,6 +13,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -48,6 +51,7 @@ func TestKcpsetupTestSuite(t *testing.T) {
 
 func (s *KcpsetupTestSuite) SetupTest() {
 	s.clientMock = new(mocks.Client)
+	s.clientMock.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.PlatformMeshList")).Return(nil).Maybe()
 	s.helperMock = new(mocks.KcpHelper)
 	cfg := logger.DefaultConfig()
 	cfg.Level = "debug"
@@ -119,7 +123,7 @@ func (s *KcpsetupTestSuite) Test_applyDirStructure() {
 			return nil
 		})
 
-	err := ApplyDirStructure(ctx, "../../manifests/kcp", "root", &rest.Config{}, inventory, &corev1alpha1.PlatformMesh{}, s.helperMock)
+	err := ApplyDirStructure(ctx, "../../manifests/kcp", "root", &rest.Config{}, inventory, &corev1alpha1.PlatformMesh{}, nil, s.helperMock)
 
 	s.Assert().Nil(err)
 }
@@ -190,7 +194,7 @@ func (s *KcpsetupTestSuite) Test_getCABundleInventory() {
 		})
 
 	// First call should fetch from secrets
-	inventory, err := s.testObj.GetCABundleInventory(ctx)
+	inventory, err := s.testObj.GetCABundleInventory(ctx, &corev1alpha1.PlatformMesh{})
 	s.Assert().NoError(err)
 	s.Assert().NotNil(inventory)
 
@@ -211,7 +215,7 @@ func (s *KcpsetupTestSuite) Test_getCABundleInventory() {
 	s.Assert().Equal(expectedB64, inventory[ipdValidatingKey])
 
 	// Second call should use cache (no additional mock calls expected)
-	inventory2, err2 := s.testObj.GetCABundleInventory(ctx)
+	inventory2, err2 := s.testObj.GetCABundleInventory(ctx, &corev1alpha1.PlatformMesh{})
 	s.Assert().NoError(err2)
 	s.Assert().NotNil(inventory2)
 	s.Assert().Contains(inventory2, mutatingKey)
@@ -236,7 +240,7 @@ func (s *KcpsetupTestSuite) Test_getCABundleInventory() {
 		Return(errors.New("secret not found")).
 		Once()
 
-	inventory, err = s.testObj.GetCABundleInventory(ctx)
+	inventory, err = s.testObj.GetCABundleInventory(ctx, &corev1alpha1.PlatformMesh{})
 	s.Assert().Error(err)
 	s.Assert().Nil(inventory)
 	s.Assert().Contains(err.Error(), "Failed to get CA bundle")
@@ -311,7 +315,7 @@ func (s *KcpsetupTestSuite) Test_getCABundleInventory_CustomSecretNameAndKey() {
 			return nil
 		}).Once()
 
-	inventory, err := s.testObj.GetCABundleInventory(ctx)
+	inventory, err := s.testObj.GetCABundleInventory(ctx, &corev1alpha1.PlatformMesh{})
 	s.Assert().NoError(err)
 	s.Assert().NotNil(inventory)
 	s.Assert().Contains(inventory, "domainCA")
@@ -622,12 +626,24 @@ users:
 		Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
+	// Mock the APIBinding listing of the workspace summary
+	mockKcpClient.EXPECT().
+		List(mock.Anything, mock.AnythingOfType("*unstructured.UnstructuredList")).
+		Return(nil)
+
+	// No profile, so no tenant namespaces are provisioned
+	s.clientMock.EXPECT().
+		Get(mock.Anything, types.NamespacedName{Name: "-profile"}, mock.AnythingOfType("*v1.ConfigMap")).
+		Return(kerrors.NewNotFound(corev1.Resource("configmaps"), "-profile"))
+
 	// Call Process
-	result, err := s.testObj.Process(ctx, &corev1alpha1.PlatformMesh{})
+	inst := &corev1alpha1.PlatformMesh{}
+	result, err := s.testObj.Process(ctx, inst)
 
 	// Assertions
 	s.Assert().Nil(err)
 	s.Assert().Equal(subroutines.OK(), result)
+	s.Assert().True(apimeta.IsStatusConditionTrue(inst.Status.Conditions, KcpSetupReadyConditionType))
 
 	// Test error case - create a new instance to clear the cache
 	s.testObj = NewKcpsetupSubroutine(s.clientMock, s.helperMock, defaultTestOperatorConfig(), ManifestStructureTest, "https://kcp.example.com")
@@ -839,6 +855,8 @@ func (s *KcpsetupTestSuite) TestCreateWorkspaces() {
 
 	// Mock apply calls for applying manifests (flexible count)
 	mockKcpClient.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	// Mock the APIBinding listing of the workspace summary
+	mockKcpClient.EXPECT().List(mock.Anything, mock.AnythingOfType("*unstructured.UnstructuredList")).Return(nil)
 	err = s.testObj.CreateKcpResources(context.Background(), &rest.Config{}, ManifestStructureTest, &corev1alpha1.PlatformMesh{})
 	s.Assert().Nil(err)
 
@@ -950,7 +968,10 @@ func (s *KcpsetupTestSuite) Test_ApplyExtraWorkspaces_Success() {
 		NewKcpClient(mock.Anything, parentPath).
 		Return(kcpClientMock, nil).Once()
 
-	// Server-side apply - no Get needed
+	// The workspace is not claimed yet
+	kcpClientMock.EXPECT().
+		Get(mock.Anything, mock.Anything, mock.Anything).
+		Return(kerrors.NewNotFound(schema.GroupResource{Group: "tenancy.kcp.io", Resource: "workspaces"}, "extra-ws")).Once()
 	kcpClientMock.EXPECT().
 		Patch(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(nil).Once()
@@ -1009,10 +1030,17 @@ func (s *KcpsetupTestSuite) Test_ApplyExtraWorkspaces_Apply_Error() {
 		NewKcpClient(mock.Anything, parentPath).
 		Return(kcpClientMock, nil).Once()
 
-	// Server-side apply fails - no Get needed
+	// The workspace is not claimed yet
+	kcpClientMock.EXPECT().
+		Get(mock.Anything, mock.Anything, mock.Anything).
+		Return(kerrors.NewNotFound(schema.GroupResource{Group: "tenancy.kcp.io", Resource: "workspaces"}, "ws3")).Once()
+	// Server-side apply fails; the live workspace is looked up for immutable field changes
 	kcpClientMock.EXPECT().
 		Patch(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(errors.New("apply failed")).Once()
+	kcpClientMock.EXPECT().
+		Get(mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("not found")).Once()
 
 	inst := s.newPlatformMeshWithExtraWorkspaces([]extraWsDef{
 		{Path: fullPath, TypeName: "universal", TypePath: "root"},
@@ -1197,14 +1225,14 @@ func (s *KcpsetupTestSuite) Test_ApplyManifestFromFile_SkipsContentConfiguration
 			path := "../../manifests/kcp/01-platform-mesh-system/contentconfiguration-main-home.yaml"
 
 			if tc.expectSkipped {
-				err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+				err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 				s.Assert().NoError(err)
 				kcpClientMock.AssertNotCalled(s.T(), "Get", mock.Anything, mock.Anything, mock.Anything)
 				kcpClientMock.AssertNotCalled(s.T(), "Apply", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 			} else {
 				kcpClientMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
-				err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+				err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 				s.Assert().NoError(err)
 			}
 		})
@@ -1226,6 +1254,65 @@ func (s *KcpsetupTestSuite) Test_ApplyManifestFromFile_DoesNotSkipNonContentConf
 	// Even with toggle enabled, non-ContentConfiguration files should be applied
 	kcpClientMock.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
-	err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root", &corev1alpha1.PlatformMesh{})
+	err := ApplyManifestFromFile(ctx, path, kcpClientMock, templateData, "root", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().NoError(err)
 }
+
+func (s *KcpsetupTestSuite) Test_addExtraDefaultAPIBindings() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	manifest := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tenancy.kcp.io/v1alpha1",
+		"kind":       "WorkspaceType",
+		"metadata":   map[string]interface{}{"name": "account"},
+		"spec": map[string]interface{}{
+			"defaultAPIBindings": []interface{}{
+				map[string]interface{}{"path": "root:platform-mesh-system", "export": "core.platform-mesh.io"},
+			},
+		},
+	}}
+	inst := &corev1alpha1.PlatformMesh{}
+	inst.Spec.Kcp.ExtraDefaultAPIBindings = []corev1alpha1.DefaultAPIBindingConfiguration{
+		{WorkspaceTypePath: "root:orgs:account", Path: "root:platform-mesh-system", Export: "core.platform-mesh.io"},
+		{WorkspaceTypePath: "root:orgs:account", Path: "root:other", Export: "core.platform-mesh.io"},
+		{WorkspaceTypePath: "root:orgs:account", Path: "root:providers", Export: "example.io"},
+		{WorkspaceTypePath: "root:orgs:account", Path: "root:providers", Export: "example.io"},
+		{WorkspaceTypePath: "root:orgs:org", Path: "root:providers", Export: "other.io"},
+	}
+
+	// Every reconcile applies the manifest again.
+	var obj *unstructured.Unstructured
+	for range 2 {
+		obj = manifest.DeepCopy()
+		s.Require().NoError(addExtraDefaultAPIBindings(ctx, obj, "root:orgs", inst))
+	}
+
+	bindings, _, _ := unstructured.NestedSlice(obj.Object, "spec", "defaultAPIBindings")
+	s.Equal([]interface{}{
+		map[string]interface{}{"path": "root:platform-mesh-system", "export": "core.platform-mesh.io"},
+		map[string]interface{}{"path": "root:providers", "export": "example.io"},
+	}, bindings)
+	s.Equal([]corev1alpha1.WorkspaceTypeBindings{{
+		WorkspaceTypePath: "root:orgs:account",
+		Added:             []corev1alpha1.APIExportReference{{Path: "root:providers", Export: "example.io"}},
+		Duplicates: []corev1alpha1.APIExportReference{
+			{Path: "root:platform-mesh-system", Export: "core.platform-mesh.io"},
+			{Path: "root:providers", Export: "example.io"},
+		},
+		Conflicts: []corev1alpha1.APIExportReference{{Path: "root:other", Export: "core.platform-mesh.io"}},
+	}}, inst.Status.WorkspaceTypeBindings)
+}
+
+func (s *KcpsetupTestSuite) Test_mergeExtraDefaultAPIBindings() {
+	merged := mergeExtraDefaultAPIBindings(nil, []corev1alpha1.DefaultAPIBindingConfiguration{
+		{Path: "root:a", Export: "example.io"},
+		{Path: "root:b", Export: "example.io"},
+		{Path: "root:a", Export: "example.io"},
+		{Path: "root:a", Export: "other.io"},
+	})
+
+	s.Equal(corev1alpha1.WorkspaceTypeBindings{
+		Added:      []corev1alpha1.APIExportReference{{Path: "root:a", Export: "example.io"}, {Path: "root:a", Export: "other.io"}},
+		Duplicates: []corev1alpha1.APIExportReference{{Path: "root:a", Export: "example.io"}},
+		Conflicts:  []corev1alpha1.APIExportReference{{Path: "root:b", Export: "example.io"}},
+	}, merged)
+}
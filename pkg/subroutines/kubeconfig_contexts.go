@@ -0,0 +1,142 @@
+package subroutines
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// SecretPurposeMergedKubeconfig marks the secret holding the provider
+	// kubeconfigs of an instance merged into one.
+	SecretPurposeMergedKubeconfig = "merged-kubeconfig"
+
+	mergedKubeconfigSecretSuffix = "-merged-kubeconfig"
+)
+
+// KubeconfigEntryName returns the name of the cluster, context and user of a
+// generated kubeconfig: <instance>-<connection>-<workspace>, with the path
+// separators of the workspace replaced by dots. Empty parts are left out.
+func KubeconfigEntryName(instance, connection, workspace string) string {
+	var parts []string
+	for _, part := range []string{instance, connection, strings.ReplaceAll(workspace, ":", ".")} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// instanceKubeconfigEntryName is KubeconfigEntryName for the connection of
+// inst, which may be nil.
+func instanceKubeconfigEntryName(inst *corev1alpha1.PlatformMesh, connection, workspace string) string {
+	if inst == nil {
+		return KubeconfigEntryName("", connection, workspace)
+	}
+	return KubeconfigEntryName(inst.Name, connection, workspace)
+}
+
+// nameKubeconfig reduces cfg to its current context and renames the context,
+// its cluster and its user to name. A config without a resolvable current
+// context is left unchanged.
+func nameKubeconfig(cfg *clientcmdapi.Config, name string) {
+	kubeCtx, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok || kubeCtx == nil {
+		return
+	}
+	cluster, ok := cfg.Clusters[kubeCtx.Cluster]
+	if !ok {
+		return
+	}
+	authInfo, ok := cfg.AuthInfos[kubeCtx.AuthInfo]
+	if !ok {
+		return
+	}
+	renamed := kubeCtx.DeepCopy()
+	renamed.Cluster, renamed.AuthInfo = name, name
+	cfg.Clusters = map[string]*clientcmdapi.Cluster{name: cluster}
+	cfg.AuthInfos = map[string]*clientcmdapi.AuthInfo{name: authInfo}
+	cfg.Contexts = map[string]*clientcmdapi.Context{name: renamed}
+	cfg.CurrentContext = name
+}
+
+// mergeKubeconfigs merges the contexts of kubeconfigs into one kubeconfig,
+// keeping their names. The current context is the one of the first
+// kubeconfig.
+func mergeKubeconfigs(kubeconfigs [][]byte) ([]byte, error) {
+	merged := clientcmdapi.NewConfig()
+	for _, data := range kubeconfigs {
+		cfg, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, err
+		}
+		for name, kubeCtx := range cfg.Contexts {
+			if _, ok := merged.Contexts[name]; ok {
+				continue
+			}
+			merged.Contexts[name] = kubeCtx
+			if cluster, ok := cfg.Clusters[kubeCtx.Cluster]; ok {
+				merged.Clusters[kubeCtx.Cluster] = cluster
+			}
+			if authInfo, ok := cfg.AuthInfos[kubeCtx.AuthInfo]; ok {
+				merged.AuthInfos[kubeCtx.AuthInfo] = authInfo
+			}
+		}
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = cfg.CurrentContext
+		}
+	}
+	return clientcmd.Write(*merged)
+}
+
+// writeMergedKubeconfig writes the kubeconfigs of the provider secrets the
+// instance owns as contexts of one kubeconfig into the secret
+// <instance>-merged-kubeconfig in the namespace of the instance.
+func (r *ProvidersecretSubroutine) writeMergedKubeconfig(ctx context.Context, instance *corev1alpha1.PlatformMesh) error {
+	name := instance.Name + mergedKubeconfigSecretSuffix
+	refs := slices.Clone(instance.Status.ProviderSecrets)
+	slices.SortFunc(refs, func(a, b corev1alpha1.ProviderSecretStatus) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	var kubeconfigs [][]byte
+	for _, ref := range refs {
+		if ref.Ownership == corev1alpha1.ProviderSecretConflict || (ref.Name == name && ref.Namespace == instance.Namespace) {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, secret)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to get provider secret %s/%s", ref.Namespace, ref.Name)
+		}
+		if len(secret.Data["kubeconfig"]) > 0 {
+			kubeconfigs = append(kubeconfigs, secret.Data["kubeconfig"])
+		}
+	}
+	if len(kubeconfigs) == 0 {
+		return nil
+	}
+
+	data, err := mergeKubeconfigs(kubeconfigs)
+	if err != nil {
+		return errors.Wrap(err, "Failed to merge provider kubeconfigs")
+	}
+	if _, err := writeProviderSecret(ctx, r.client, instance, name, instance.Namespace, SecretPurposeMergedKubeconfig, "",
+		map[string][]byte{"kubeconfig": data}, nil); err != nil {
+		return errors.Wrap(err, "Failed to write merged kubeconfig secret %s/%s", instance.Namespace, name)
+	}
+	return nil
+}
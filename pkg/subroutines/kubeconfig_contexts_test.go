@@ -0,0 +1,91 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestKubeconfigEntryName(t *testing.T) {
+	assert.Equal(t, "platform-mesh-iam-kubeconfig-root.platform-mesh-system",
+		KubeconfigEntryName("platform-mesh", "iam-kubeconfig", "root:platform-mesh-system"))
+	assert.Equal(t, "wildwest-root.providers.wildwest-abc", KubeconfigEntryName("wildwest", "", "root:providers:wildwest-abc"))
+	assert.Equal(t, "init-root", instanceKubeconfigEntryName(nil, "init", "root"))
+}
+
+func TestNameKubeconfig(t *testing.T) {
+	cfg := &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"kcp":   {Server: "https://kcp.example"},
+			"other": {Server: "https://other.example"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"admin": {Token: "t"}, "other": {}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"base":  {Cluster: "kcp", AuthInfo: "admin"},
+			"other": {Cluster: "other", AuthInfo: "other"},
+		},
+		CurrentContext: "base",
+	}
+	nameKubeconfig(cfg, "pm-iam-root")
+	assert.Equal(t, "pm-iam-root", cfg.CurrentContext)
+	assert.Equal(t, map[string]*clientcmdapi.Context{"pm-iam-root": {Cluster: "pm-iam-root", AuthInfo: "pm-iam-root"}}, cfg.Contexts)
+	assert.Equal(t, map[string]*clientcmdapi.Cluster{"pm-iam-root": {Server: "https://kcp.example"}}, cfg.Clusters)
+	assert.Equal(t, map[string]*clientcmdapi.AuthInfo{"pm-iam-root": {Token: "t"}}, cfg.AuthInfos)
+
+	// Without a current context the config is kept.
+	cfg = &clientcmdapi.Config{Contexts: map[string]*clientcmdapi.Context{"base": {Cluster: "kcp"}}}
+	nameKubeconfig(cfg, "pm-iam-root")
+	assert.Contains(t, cfg.Contexts, "base")
+}
+
+func TestWriteMergedKubeconfig(t *testing.T) {
+	kubeconfigSecret := func(name, namespace, server string) *corev1.Secret {
+		data, err := clientcmd.Write(*buildScopedKubeconfig(KubeconfigEntryName("pm", name, "root"), server, "token", nil))
+		require.NoError(t, err)
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string][]byte{"kubeconfig": data},
+		}
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns", UID: "uid"}}
+	inst.Status.ProviderSecrets = []v1alpha1.ProviderSecretStatus{
+		{Name: "portal", Namespace: "pm-ns", Ownership: v1alpha1.ProviderSecretOwned},
+		{Name: "iam", Namespace: "pm-ns", Ownership: v1alpha1.ProviderSecretOwned},
+		{Name: "foreign", Namespace: "pm-ns", Ownership: v1alpha1.ProviderSecretConflict},
+		{Name: "missing", Namespace: "pm-ns", Ownership: v1alpha1.ProviderSecretOwned},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		inst,
+		kubeconfigSecret("portal", "pm-ns", "https://portal.example"),
+		kubeconfigSecret("iam", "pm-ns", "https://iam.example"),
+		kubeconfigSecret("foreign", "pm-ns", "https://foreign.example"),
+	).Build()
+	sub := &ProvidersecretSubroutine{client: cl}
+
+	require.NoError(t, sub.writeMergedKubeconfig(context.Background(), inst))
+
+	merged := &corev1.Secret{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "pm-merged-kubeconfig", Namespace: "pm-ns"}, merged))
+	assert.Equal(t, SecretPurposeMergedKubeconfig, merged.Labels[SecretPurposeLabel])
+	kubeconfig, err := clientcmd.Load(merged.Data["kubeconfig"])
+	require.NoError(t, err)
+	assert.Len(t, kubeconfig.Contexts, 2)
+	assert.Equal(t, "pm-iam-root", kubeconfig.CurrentContext)
+	assert.Equal(t, "https://portal.example", kubeconfig.Clusters["pm-portal-root"].Server)
+	assert.Equal(t, "token", kubeconfig.AuthInfos["pm-portal-root"].Token)
+}
@@ -0,0 +1,164 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	gcerrors "github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	LandscapeSubroutineName = "LandscapeSubroutine"
+	// InstancesReadyConditionType is True when every PlatformMesh selected by a
+	// PlatformMeshLandscape is Ready.
+	InstancesReadyConditionType = "InstancesReady"
+
+	landscapeRequeueInterval        = time.Minute
+	defaultLandscapeExpiryThreshold = 7 * 24 * time.Hour
+)
+
+// LandscapeSubroutine aggregates the status of the PlatformMesh instances
+// selected by a PlatformMeshLandscape. Instances do not notify the landscape,
+// it re-aggregates periodically.
+type LandscapeSubroutine struct {
+	client client.Client
+	now    func() time.Time
+}
+
+func NewLandscapeSubroutine(cl client.Client) *LandscapeSubroutine {
+	return &LandscapeSubroutine{client: cl, now: time.Now}
+}
+
+func (r *LandscapeSubroutine) GetName() string {
+	return LandscapeSubroutineName
+}
+
+func (r *LandscapeSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *LandscapeSubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *LandscapeSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	inst := obj.(*corev1alpha1.PlatformMeshLandscape)
+
+	selector, err := metav1.LabelSelectorAsSelector(&inst.Spec.Selector)
+	if err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "invalid selector")
+	}
+	list := &corev1alpha1.PlatformMeshList{}
+	if err := r.client.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "failed to list PlatformMesh instances")
+	}
+
+	threshold := defaultLandscapeExpiryThreshold
+	if inst.Spec.ExpiryThreshold != nil {
+		threshold = inst.Spec.ExpiryThreshold.Duration
+	}
+	now := r.now()
+	conditions := inst.Status.Conditions
+	inst.Status = aggregateLandscape(list.Items, now.Add(threshold))
+	inst.Status.Conditions = conditions
+	inst.Status.ObservedGeneration = inst.Generation
+	lastAggregated := metav1.NewTime(now)
+	inst.Status.LastAggregated = &lastAggregated
+
+	cond := metav1.Condition{
+		Type:               InstancesReadyConditionType,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: inst.Generation,
+		Reason:             "AllInstancesReady",
+		Message:            fmt.Sprintf("%d of %d instances are ready", inst.Status.ReadyInstances, inst.Status.Instances),
+	}
+	if inst.Status.ReadyInstances < inst.Status.Instances {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "InstancesNotReady"
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, cond)
+
+	log.Debug().Int("instances", inst.Status.Instances).Int("ready", inst.Status.ReadyInstances).Msg("Aggregated PlatformMesh instances")
+	return subroutines.OKWithRequeue(landscapeRequeueInterval), nil
+}
+
+// aggregateLandscape summarizes instances. Credentials expiring before
+// expiringBefore are reported. Lists are sorted so that the status only
+// changes when the instances do.
+func aggregateLandscape(instances []corev1alpha1.PlatformMesh, expiringBefore time.Time) corev1alpha1.PlatformMeshLandscapeStatus {
+	status := corev1alpha1.PlatformMeshLandscapeStatus{Instances: len(instances)}
+	channels := map[string]int{}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instanceKey(&instances[i]) < instanceKey(&instances[j])
+	})
+	for i := range instances {
+		pm := &instances[i]
+		key := instanceKey(pm)
+		channels[pm.Spec.Channel]++
+
+		if ready := apimeta.FindStatusCondition(pm.Status.Conditions, "Ready"); ready != nil && ready.Status == metav1.ConditionTrue {
+			status.ReadyInstances++
+		} else {
+			notReady := corev1alpha1.LandscapeInstance{Name: pm.Name, Namespace: pm.Namespace}
+			if ready != nil {
+				notReady.Reason = ready.Reason
+				notReady.Message = ready.Message
+			}
+			status.NotReady = append(status.NotReady, notReady)
+		}
+
+		for _, e := range pm.Status.ComponentRenderErrors {
+			status.DriftedComponents = append(status.DriftedComponents, corev1alpha1.LandscapeDriftedComponent{
+				Instance: key, Component: e.Component, Reason: corev1alpha1.LandscapeDriftRenderFailed,
+			})
+		}
+		for _, ws := range pm.Status.KcpWorkspaces {
+			if ws.MissingContent {
+				status.DriftedComponents = append(status.DriftedComponents, corev1alpha1.LandscapeDriftedComponent{
+					Instance: key, Component: ws.Name, Reason: corev1alpha1.LandscapeDriftMissingContent,
+				})
+			}
+		}
+		for _, pc := range pm.Status.ProviderConnections {
+			if !pc.RBACUpToDate {
+				status.DriftedComponents = append(status.DriftedComponents, corev1alpha1.LandscapeDriftedComponent{
+					Instance: key, Component: pc.Secret, Reason: corev1alpha1.LandscapeDriftRBACOutdated,
+				})
+			}
+			if pc.TokenExpiresAt != nil && pc.TokenExpiresAt.Time.Before(expiringBefore) {
+				status.ExpiringCredentials = append(status.ExpiringCredentials, corev1alpha1.LandscapeExpiringCredential{
+					Instance: key, Secret: pc.Secret, Kind: corev1alpha1.LandscapeCredentialToken, ExpiresAt: *pc.TokenExpiresAt,
+				})
+			}
+			if pc.ClientCertExpiresAt != nil && pc.ClientCertExpiresAt.Time.Before(expiringBefore) {
+				status.ExpiringCredentials = append(status.ExpiringCredentials, corev1alpha1.LandscapeExpiringCredential{
+					Instance: key, Secret: pc.Secret, Kind: corev1alpha1.LandscapeCredentialClientCert, ExpiresAt: *pc.ClientCertExpiresAt,
+				})
+			}
+		}
+	}
+
+	for channel, count := range channels {
+		status.Channels = append(status.Channels, corev1alpha1.LandscapeChannel{Channel: channel, Instances: count})
+	}
+	sort.Slice(status.Channels, func(i, j int) bool { return status.Channels[i].Channel < status.Channels[j].Channel })
+	sort.SliceStable(status.ExpiringCredentials, func(i, j int) bool {
+		return status.ExpiringCredentials[i].ExpiresAt.Before(&status.ExpiringCredentials[j].ExpiresAt)
+	})
+	return status
+}
+
+func instanceKey(pm *corev1alpha1.PlatformMesh) string {
+	return pm.Namespace + "/" + pm.Name
+}
@@ -0,0 +1,96 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestLandscapeSubroutine_Process(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	soon := metav1.NewTime(now.Add(24 * time.Hour))
+	later := metav1.NewTime(now.Add(30 * 24 * time.Hour))
+	prod := map[string]string{"environment": "production"}
+
+	ready := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "eu", Labels: prod},
+		Spec:       corev1alpha1.PlatformMeshSpec{Channel: "stable"},
+		Status: corev1alpha1.PlatformMeshStatus{
+			Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Complete"}},
+			ProviderConnections: []corev1alpha1.ProviderConnectionStatus{
+				{Secret: "portal", Path: "root:platform-mesh-system", RBACUpToDate: true, TokenExpiresAt: &later},
+				{Secret: "iam", Path: "root:platform-mesh-system", RBACUpToDate: false, ClientCertExpiresAt: &soon},
+			},
+		},
+	}
+	notReady := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "us", Labels: prod},
+		Status: corev1alpha1.PlatformMeshStatus{
+			Conditions:            []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Reason: "DeploymentReady", Message: "waiting"}},
+			ComponentRenderErrors: []corev1alpha1.ComponentRenderError{{Component: "portal", Templates: "components-runtime", Message: "boom"}},
+			KcpWorkspaces:         []corev1alpha1.KcpWorkspace{{Name: "root:orgs", Phase: "Ready", MissingContent: true}},
+		},
+	}
+	unselected := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "dev"}}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ready, notReady, unselected).Build()
+	r := NewLandscapeSubroutine(cl)
+	r.now = func() time.Time { return now }
+
+	landscape := &corev1alpha1.PlatformMeshLandscape{
+		ObjectMeta: metav1.ObjectMeta{Name: "production", Generation: 2},
+		Spec:       corev1alpha1.PlatformMeshLandscapeSpec{Selector: metav1.LabelSelector{MatchLabels: prod}},
+	}
+	res, err := r.Process(context.Background(), landscape)
+	require.NoError(t, err)
+	assert.True(t, res.IsContinue())
+
+	status := landscape.Status
+	assert.Equal(t, 2, status.Instances)
+	assert.Equal(t, 1, status.ReadyInstances)
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	assert.Equal(t, []corev1alpha1.LandscapeInstance{{Name: "pm", Namespace: "us", Reason: "DeploymentReady", Message: "waiting"}}, status.NotReady)
+	assert.Equal(t, []corev1alpha1.LandscapeChannel{{Channel: "", Instances: 1}, {Channel: "stable", Instances: 1}}, status.Channels)
+	assert.Equal(t, []corev1alpha1.LandscapeDriftedComponent{
+		{Instance: "eu/pm", Component: "iam", Reason: corev1alpha1.LandscapeDriftRBACOutdated},
+		{Instance: "us/pm", Component: "portal", Reason: corev1alpha1.LandscapeDriftRenderFailed},
+		{Instance: "us/pm", Component: "root:orgs", Reason: corev1alpha1.LandscapeDriftMissingContent},
+	}, status.DriftedComponents)
+	require.Len(t, status.ExpiringCredentials, 1)
+	assert.Equal(t, "iam", status.ExpiringCredentials[0].Secret)
+	assert.Equal(t, corev1alpha1.LandscapeCredentialClientCert, status.ExpiringCredentials[0].Kind)
+	require.NotNil(t, status.LastAggregated)
+	assert.True(t, status.LastAggregated.Time.Equal(now))
+
+	cond := apimeta.FindStatusCondition(status.Conditions, InstancesReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "1 of 2 instances are ready", cond.Message)
+
+	// A longer threshold also reports the token.
+	landscape.Spec.ExpiryThreshold = &metav1.Duration{Duration: 60 * 24 * time.Hour}
+	_, err = r.Process(context.Background(), landscape)
+	require.NoError(t, err)
+	assert.Len(t, landscape.Status.ExpiringCredentials, 2)
+	assert.Equal(t, corev1alpha1.LandscapeCredentialClientCert, landscape.Status.ExpiringCredentials[0].Kind)
+}
+
+func TestLandscapeSubroutine_InvalidSelector(t *testing.T) {
+	r := NewLandscapeSubroutine(fake.NewClientBuilder().Build())
+	landscape := &corev1alpha1.PlatformMeshLandscape{Spec: corev1alpha1.PlatformMeshLandscapeSpec{Selector: metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "environment", Operator: "Bogus"}},
+	}}}
+	_, err := r.Process(context.Background(), landscape)
+	assert.Error(t, err)
+}
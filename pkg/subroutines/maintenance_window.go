@@ -0,0 +1,160 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/maintenance"
+)
+
+// maintenanceGate decides whether the disruptive operations of a reconcile
+// may run now, and collects the ones it deferred to the next of
+// spec.maintenanceWindows. A nil *maintenanceGate allows every operation, as
+// an instance without maintenance windows has no restriction.
+type maintenanceGate struct {
+	// inst the deferral events are recorded on.
+	inst     *corev1alpha1.PlatformMesh
+	open     bool
+	next     time.Time
+	deferred []corev1alpha1.DeferredOperation
+}
+
+type maintenanceGateKey struct{}
+
+// withMaintenanceGate returns ctx carrying the gate of the maintenance
+// windows of inst at now, and nil without windows. Windows that do not parse
+// are logged and ignored, so with only invalid windows every disruptive
+// operation is deferred.
+func withMaintenanceGate(ctx context.Context, inst *corev1alpha1.PlatformMesh, now time.Time) (context.Context, *maintenanceGate) {
+	if len(inst.Spec.MaintenanceWindows) == 0 {
+		return ctx, nil
+	}
+	log := logger.LoadLoggerFromContext(ctx)
+	windows := make(maintenance.Windows, 0, len(inst.Spec.MaintenanceWindows))
+	for _, mw := range inst.Spec.MaintenanceWindows {
+		w, err := maintenance.NewWindow(mw.Cron, mw.Duration.Duration)
+		if err != nil {
+			log.Warn().Err(err).Str("cron", mw.Cron).Msg("Ignoring invalid maintenance window")
+			continue
+		}
+		windows = append(windows, w)
+	}
+	g := &maintenanceGate{inst: inst, open: windows.Open(now), next: windows.Next(now)}
+	return context.WithValue(ctx, maintenanceGateKey{}, g), g
+}
+
+func maintenanceGateFrom(ctx context.Context) *maintenanceGate {
+	g, _ := ctx.Value(maintenanceGateKey{}).(*maintenanceGate)
+	return g
+}
+
+// allow reports whether the operation op on target may run now. Outside of
+// the maintenance windows it records op as deferred with message and returns
+// false, the caller then leaves target as it is. The event is only recorded
+// when op is not deferred in the status already, so once until the window.
+func (g *maintenanceGate) allow(ctx context.Context, op corev1alpha1.MaintenanceOperation, target, message string) bool {
+	if g == nil || g.open {
+		return true
+	}
+	g.deferred = append(g.deferred, corev1alpha1.DeferredOperation{Operation: op, Target: target, Message: message})
+	log := logger.LoadLoggerFromContext(ctx)
+	if slices.ContainsFunc(g.inst.Status.DeferredOperations, func(d corev1alpha1.DeferredOperation) bool {
+		return d.Operation == op && d.Target == target
+	}) {
+		log.Debug().Str("operation", string(op)).Str("target", target).Msg("Disruptive operation still deferred")
+		return false
+	}
+	log.Info().Str("operation", string(op)).Str("target", target).Time("nextWindow", g.next).
+		Msg("Deferring disruptive operation until the next maintenance window")
+	recordEvent(ctx, g.inst, corev1.EventTypeNormal, EventReasonOperationDeferred, string(op),
+		"%s of %s deferred until the next maintenance window: %s", op, target, message)
+	return false
+}
+
+// report replaces the deferred operations of kinds ops in the status of inst
+// with the ones deferred by this reconcile, and sets when the next
+// maintenance window opens while any operation is deferred.
+func (g *maintenanceGate) report(inst *corev1alpha1.PlatformMesh, ops ...corev1alpha1.MaintenanceOperation) {
+	var deferred []corev1alpha1.DeferredOperation
+	for _, d := range inst.Status.DeferredOperations {
+		if !slices.Contains(ops, d.Operation) {
+			deferred = append(deferred, d)
+		}
+	}
+	if g != nil {
+		for _, d := range g.deferred {
+			if slices.Contains(ops, d.Operation) {
+				deferred = append(deferred, d)
+			}
+		}
+	}
+	sort.SliceStable(deferred, func(i, j int) bool {
+		if deferred[i].Operation != deferred[j].Operation {
+			return deferred[i].Operation < deferred[j].Operation
+		}
+		return deferred[i].Target < deferred[j].Target
+	})
+	inst.Status.DeferredOperations = deferred
+
+	inst.Status.NextMaintenanceWindow = nil
+	if len(deferred) > 0 && g != nil && !g.next.IsZero() {
+		next := metav1.NewTime(g.next)
+		inst.Status.NextMaintenanceWindow = &next
+	}
+}
+
+// result is the result of a reconcile that got through, requeued for the next
+// maintenance window when it deferred operations.
+func (g *maintenanceGate) result() subroutines.Result {
+	if g == nil || len(g.deferred) == 0 || g.next.IsZero() {
+		return subroutines.OK()
+	}
+	return subroutines.OKWithRequeue(time.Until(g.next))
+}
+
+// deferUpgrades wraps next. A component HelmRelease on k8sClient whose chart
+// or values changed, or an OCM Resource whose pinned version changed, is an
+// upgrade. Outside of the maintenance windows it is dropped and the component
+// keeps its objects in inv, so the object stays as it is. New objects are
+// applied right away.
+func deferUpgrades(k8sClient client.Client, inv *inventory, next func(ctx context.Context, obj *unstructured.Unstructured) error) func(ctx context.Context, obj *unstructured.Unstructured) error {
+	return func(ctx context.Context, obj *unstructured.Unstructured) error {
+		// Adoption renames the release, so the component is taken first.
+		component := renderedComponentFrom(ctx)
+		if component == "" {
+			component = obj.GetName()
+		}
+		if next != nil {
+			if err := next(ctx, obj); err != nil {
+				return err
+			}
+		}
+		gate := maintenanceGateFrom(ctx)
+		if gate == nil || gate.open {
+			return nil
+		}
+
+		changed, err := upgradeChanges(ctx, k8sClient, obj)
+		if err != nil || len(changed) == 0 {
+			return err
+		}
+		target := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		if gate.allow(ctx, corev1alpha1.MaintenanceUpgrade, target, "changed "+strings.Join(changed, ", ")) {
+			return nil
+		}
+		inv.keep(component)
+		return errSkipObject
+	}
+}
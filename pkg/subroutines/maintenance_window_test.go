@@ -0,0 +1,130 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+func maintenanceInstance(windows ...corev1alpha1.MaintenanceWindow) *corev1alpha1.PlatformMesh {
+	return &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"},
+		Spec:       corev1alpha1.PlatformMeshSpec{MaintenanceWindows: windows},
+	}
+}
+
+// saturdayNights opens at 02:00 UTC on Saturdays for four hours.
+var saturdayNights = corev1alpha1.MaintenanceWindow{Cron: "0 2 * * sat", Duration: metav1.Duration{Duration: 4 * time.Hour}}
+
+func TestMaintenanceGate(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+	// A Friday noon and the Saturday night after it.
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+
+	// Without windows there is no gate and everything is allowed.
+	inst := maintenanceInstance()
+	gateCtx, gate := withMaintenanceGate(ctx, inst, friday)
+	assert.Nil(t, gate)
+	assert.Nil(t, maintenanceGateFrom(gateCtx))
+	assert.True(t, gate.allow(ctx, corev1alpha1.MaintenanceRestart, "Deployment a/b", "restart"))
+	assert.True(t, gate.result().IsContinue())
+
+	// Inside a window everything is allowed.
+	inst = maintenanceInstance(saturdayNights)
+	_, gate = withMaintenanceGate(ctx, inst, saturday.Add(time.Hour))
+	assert.True(t, gate.allow(ctx, corev1alpha1.MaintenanceRestart, "Deployment a/b", "restart"))
+	gate.report(inst, corev1alpha1.MaintenanceRestart)
+	assert.Empty(t, inst.Status.DeferredOperations)
+	assert.Nil(t, inst.Status.NextMaintenanceWindow)
+
+	// Outside of the windows operations are deferred to the next window.
+	inst.Status.DeferredOperations = []corev1alpha1.DeferredOperation{
+		{Operation: corev1alpha1.MaintenanceRecreate, Target: "Workspace orgs in workspace root"},
+		{Operation: corev1alpha1.MaintenanceUpgrade, Target: "HelmRelease default/stale"},
+	}
+	gateCtx, gate = withMaintenanceGate(ctx, inst, friday)
+	assert.Same(t, gate, maintenanceGateFrom(gateCtx))
+	assert.False(t, gate.allow(gateCtx, corev1alpha1.MaintenanceUpgrade, "HelmRelease default/portal", "changed spec.values.replicas"))
+	assert.Equal(t, "Normal OperationDeferred Upgrade of HelmRelease default/portal deferred until the next maintenance window: changed spec.values.replicas", <-rec.Events)
+	gate.report(inst, corev1alpha1.MaintenanceUpgrade, corev1alpha1.MaintenanceRestart)
+	assert.Equal(t, []corev1alpha1.DeferredOperation{
+		{Operation: corev1alpha1.MaintenanceRecreate, Target: "Workspace orgs in workspace root"},
+		{Operation: corev1alpha1.MaintenanceUpgrade, Target: "HelmRelease default/portal", Message: "changed spec.values.replicas"},
+	}, inst.Status.DeferredOperations)
+	require.NotNil(t, inst.Status.NextMaintenanceWindow)
+	assert.Equal(t, saturday, inst.Status.NextMaintenanceWindow.UTC())
+	assert.True(t, gate.result().IsContinue())
+
+	// The next reconcile defers the operation again without another event.
+	gateCtx, gate = withMaintenanceGate(ctx, inst, friday)
+	assert.False(t, gate.allow(gateCtx, corev1alpha1.MaintenanceUpgrade, "HelmRelease default/portal", "changed spec.values.replicas"))
+	assert.Empty(t, rec.Events)
+	gate.report(inst, corev1alpha1.MaintenanceUpgrade, corev1alpha1.MaintenanceRestart)
+	assert.Len(t, inst.Status.DeferredOperations, 2)
+
+	// Invalid windows are ignored, without a valid one everything is deferred.
+	inst = maintenanceInstance(corev1alpha1.MaintenanceWindow{Cron: "every night", Duration: metav1.Duration{Duration: time.Hour}})
+	_, gate = withMaintenanceGate(ctx, inst, saturday)
+	assert.False(t, gate.allow(ctx, corev1alpha1.MaintenanceRestart, "Deployment a/b", "restart"))
+	<-rec.Events
+	gate.report(inst, corev1alpha1.MaintenanceRestart)
+	assert.Len(t, inst.Status.DeferredOperations, 1)
+	assert.Nil(t, inst.Status.NextMaintenanceWindow)
+}
+
+func TestDeferUpgrades(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	live := newAdoptionHelmRelease(t, "portal", map[string]interface{}{
+		"chart":  map[string]interface{}{"spec": map[string]interface{}{"chart": "portal", "version": "1.0.0"}},
+		"values": map[string]interface{}{"replicas": int64(2)},
+	})
+	cl := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(live).Build()
+	render := func(name, version string) *unstructured.Unstructured {
+		return newAdoptionHelmRelease(t, name, map[string]interface{}{
+			"chart":  map[string]interface{}{"spec": map[string]interface{}{"chart": name, "version": version}},
+			"values": map[string]interface{}{"replicas": float64(2)},
+		})
+	}
+
+	inv := newInventory("components-infra", plan.ClusterInfra)
+	postProcess := deferUpgrades(cl, inv, nil)
+
+	// Without a gate, and inside a window, upgrades are applied.
+	require.NoError(t, postProcess(ctx, render("portal", "1.1.0")))
+	inst := maintenanceInstance(saturdayNights)
+	gateCtx, gate := withMaintenanceGate(ctx, inst, time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC))
+	require.NoError(t, postProcess(gateCtx, render("portal", "1.1.0")))
+	assert.Empty(t, gate.deferred)
+
+	// Outside of the windows only the upgrade is dropped, an unchanged
+	// release and a new one are applied.
+	gateCtx, gate = withMaintenanceGate(ctx, inst, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, postProcess(gateCtx, render("portal", "1.1.0")), errSkipObject)
+	assert.True(t, inv.kept["portal"])
+	require.NoError(t, postProcess(gateCtx, render("portal", "1.0.0")))
+	require.NoError(t, postProcess(gateCtx, render("iam", "1.1.0")))
+	assert.Equal(t, []corev1alpha1.DeferredOperation{{
+		Operation: corev1alpha1.MaintenanceUpgrade,
+		Target:    "HelmRelease flux-system/portal",
+		Message:   "changed spec.chart.spec.version",
+	}}, gate.deferred)
+}
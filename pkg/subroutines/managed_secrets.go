@@ -0,0 +1,126 @@
+package subroutines
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// SecretPurposeLabel holds what the operator generated a secret for, one of
+	// the SecretPurpose constants.
+	SecretPurposeLabel = "platform-mesh.io/purpose"
+	// SecretConnectionLabel holds the kcp workspace a generated credential
+	// connects to, with the path separators replaced by dots.
+	SecretConnectionLabel = "platform-mesh.io/connection"
+	// SecretRotatedAtAnnotation holds when the data of a generated secret last
+	// changed, in RFC 3339.
+	SecretRotatedAtAnnotation = "platform-mesh.io/rotated-at"
+)
+
+// Purposes of the secrets the operator generates.
+const (
+	SecretPurposeProvider       = "provider"
+	SecretPurposeScoped         = "scoped"
+	SecretPurposeInitializer    = "initializer"
+	SecretPurposeWebhook        = "webhook"
+	SecretPurposeOIDCClient     = "oidc-client"
+	SecretPurposeValuesSnapshot = "values-snapshot"
+	SecretPurposeLastGoodSpecs  = "last-good-specs"
+	SecretPurposeKubeconfigCopy = "kubeconfig-copy"
+)
+
+// LabelSecretPurpose labels obj as a secret the operator generated for
+// purpose. connection is the kcp workspace path the secret connects to, the
+// label is removed when it is empty or does not fit a label value.
+func LabelSecretPurpose(obj metav1.Object, purpose, connection string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[SecretPurposeLabel] = purpose
+	value := strings.ReplaceAll(connection, ":", ".")
+	if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+		labels[SecretConnectionLabel] = value
+	} else {
+		delete(labels, SecretConnectionLabel)
+	}
+	obj.SetLabels(labels)
+}
+
+// SetSecretData sets the data of secret and stamps SecretRotatedAtAnnotation
+// when the data is new or changed.
+func SetSecretData(secret *corev1.Secret, data map[string][]byte) {
+	if secret.Annotations[SecretRotatedAtAnnotation] == "" || !reflect.DeepEqual(secret.Data, data) {
+		markSecretRotated(secret)
+	}
+	secret.Data = data
+}
+
+func markSecretRotated(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[SecretRotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	obj.SetAnnotations(annotations)
+}
+
+// summarizeManagedSecrets counts the secrets labeled with a purpose for inst
+// by purpose and finds the least recently rotated one of each purpose.
+// Secrets without a valid rotation timestamp count as never rotated.
+func summarizeManagedSecrets(ctx context.Context, cl client.Client, inst *corev1alpha1.PlatformMesh) (*corev1alpha1.ManagedSecretsSummary, error) {
+	list := &corev1.SecretList{}
+	if err := cl.List(ctx, list, client.MatchingLabels{
+		InstanceNameLabel:      inst.Name,
+		InstanceNamespaceLabel: inst.Namespace,
+	}, client.HasLabels{SecretPurposeLabel}); err != nil {
+		return nil, errors.Wrap(err, "Failed to list managed secrets")
+	}
+
+	byPurpose := map[string]*corev1alpha1.ManagedSecretPurpose{}
+	summary := &corev1alpha1.ManagedSecretsSummary{}
+	for _, secret := range list.Items {
+		purpose := secret.Labels[SecretPurposeLabel]
+		entry, ok := byPurpose[purpose]
+		if !ok {
+			entry = &corev1alpha1.ManagedSecretPurpose{Purpose: purpose}
+			byPurpose[purpose] = entry
+		}
+		entry.Count++
+		summary.Total++
+
+		var rotatedAt *metav1.Time
+		if t, err := time.Parse(time.RFC3339, secret.Annotations[SecretRotatedAtAnnotation]); err == nil {
+			rotatedAt = &metav1.Time{Time: t}
+		}
+		if entry.OldestSecret == "" || rotatedBefore(rotatedAt, entry.OldestRotation) {
+			entry.OldestRotation = rotatedAt
+			entry.OldestSecret = secret.Namespace + "/" + secret.Name
+		}
+	}
+	for _, purpose := range slices.Sorted(maps.Keys(byPurpose)) {
+		summary.Purposes = append(summary.Purposes, *byPurpose[purpose])
+	}
+	return summary, nil
+}
+
+// rotatedBefore reports whether rotation a is older than b. An unknown
+// rotation is older than any known one.
+func rotatedBefore(a, b *metav1.Time) bool {
+	if b == nil {
+		return false
+	}
+	return a == nil || a.Before(b)
+}
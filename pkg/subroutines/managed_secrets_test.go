@@ -0,0 +1,91 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestLabelSecretPurpose(t *testing.T) {
+	secret := &corev1.Secret{}
+	LabelSecretPurpose(secret, SecretPurposeProvider, "root:platform-mesh-system")
+	assert.Equal(t, map[string]string{
+		SecretPurposeLabel:    "provider",
+		SecretConnectionLabel: "root.platform-mesh-system",
+	}, secret.Labels)
+
+	LabelSecretPurpose(secret, SecretPurposeWebhook, "")
+	assert.Equal(t, map[string]string{SecretPurposeLabel: "webhook"}, secret.Labels)
+}
+
+func TestSetSecretData(t *testing.T) {
+	secret := &corev1.Secret{}
+	SetSecretData(secret, map[string][]byte{"kubeconfig": []byte("a")})
+	rotatedAt := secret.Annotations[SecretRotatedAtAnnotation]
+	require.NotEmpty(t, rotatedAt)
+
+	// Unchanged data keeps the timestamp.
+	secret.Annotations[SecretRotatedAtAnnotation] = "2026-01-01T00:00:00Z"
+	SetSecretData(secret, map[string][]byte{"kubeconfig": []byte("a")})
+	assert.Equal(t, "2026-01-01T00:00:00Z", secret.Annotations[SecretRotatedAtAnnotation])
+
+	SetSecretData(secret, map[string][]byte{"kubeconfig": []byte("b")})
+	assert.NotEqual(t, "2026-01-01T00:00:00Z", secret.Annotations[SecretRotatedAtAnnotation])
+	assert.Equal(t, []byte("b"), secret.Data["kubeconfig"])
+}
+
+func TestSummarizeManagedSecrets(t *testing.T) {
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns"}}
+	newSecret := func(name, purpose, rotatedAt string) *corev1.Secret {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pm-ns"}}
+		setInstanceLabels(secret, inst)
+		LabelSecretPurpose(secret, purpose, "")
+		if rotatedAt != "" {
+			secret.Annotations = map[string]string{SecretRotatedAtAnnotation: rotatedAt}
+		}
+		return secret
+	}
+	other := newSecret("other", SecretPurposeProvider, "")
+	other.Labels[InstanceNameLabel] = "other"
+	unlabeled := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "pm-ns"}}
+	setInstanceLabels(unlabeled, inst)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newSecret("iam", SecretPurposeProvider, "2026-03-01T00:00:00Z"),
+		newSecret("portal", SecretPurposeProvider, "2026-02-01T00:00:00Z"),
+		newSecret("webhook", SecretPurposeWebhook, "2026-03-01T00:00:00Z"),
+		newSecret("legacy-webhook", SecretPurposeWebhook, ""),
+		other,
+		unlabeled,
+	).Build()
+
+	summary, err := summarizeManagedSecrets(context.Background(), cl, inst)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), summary.Total)
+	require.Len(t, summary.Purposes, 2)
+
+	provider := summary.Purposes[0]
+	assert.Equal(t, SecretPurposeProvider, provider.Purpose)
+	assert.Equal(t, int32(2), provider.Count)
+	assert.Equal(t, "pm-ns/portal", provider.OldestSecret)
+	require.NotNil(t, provider.OldestRotation)
+	assert.True(t, provider.OldestRotation.Time.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Secrets without a rotation timestamp are the oldest.
+	webhook := summary.Purposes[1]
+	assert.Equal(t, SecretPurposeWebhook, webhook.Purpose)
+	assert.Equal(t, "pm-ns/legacy-webhook", webhook.OldestSecret)
+	assert.Nil(t, webhook.OldestRotation)
+}
@@ -0,0 +1,219 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/openfga"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+const (
+	OpenFGASubroutineName = "OpenFGASubroutine"
+	// OpenFGAReadyConditionType is True once the OpenFGA store exists and the
+	// configured authorization model is written to it.
+	OpenFGAReadyConditionType = "OpenFGAReady"
+
+	openFGAModelKey       = "model.json"
+	openFGARequestTimeout = 10 * time.Second
+)
+
+// FGAClient is the part of the OpenFGA API the OpenFGA subroutine uses.
+type FGAClient interface {
+	GetStore(ctx context.Context, id string) (*openfga.Store, error)
+	FindStore(ctx context.Context, name string) (*openfga.Store, error)
+	CreateStore(ctx context.Context, name string) (*openfga.Store, error)
+	WriteAuthorizationModel(ctx context.Context, storeID string, model []byte) (string, error)
+}
+
+// errStoreNotOwned is returned for a store of the configured name that the
+// operator did not create.
+var errStoreNotOwned = errors.New("store exists but was not created by the operator")
+
+// OpenFGASubroutine bootstraps OpenFGA for the core workspaces: it creates the
+// store, writes the configured authorization model and records both in the
+// status. Only the store recorded in the status is written to, and a new model
+// is only written when its content changed, as every write creates a new model
+// version.
+type OpenFGASubroutine struct {
+	client    client.Client
+	cfg       config.OpenFGASubroutineConfig
+	newClient func(url string) FGAClient
+	requeue   *RequeueBackoff
+}
+
+func NewOpenFGASubroutine(client client.Client, cfg *config.OperatorConfig) *OpenFGASubroutine {
+	return &OpenFGASubroutine{
+		client: client,
+		cfg:    cfg.Subroutines.OpenFGA,
+		newClient: func(url string) FGAClient {
+			return openfga.NewClient(url, &http.Client{Timeout: openFGARequestTimeout})
+		},
+		requeue: NewRequeueBackoff(OpenFGASubroutineName, cfg.Subroutines.OpenFGA.Requeue),
+	}
+}
+
+func (r *OpenFGASubroutine) GetName() string {
+	return OpenFGASubroutineName
+}
+
+// Finalize leaves the store in place, it holds the relationship tuples of the
+// workspaces and outlives the PlatformMesh.
+func (r *OpenFGASubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *OpenFGASubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *OpenFGASubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		labelResult := "success"
+		if err != nil {
+			labelResult = "error"
+		}
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), labelResult).Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	if plan.IsPlanning(ctx) {
+		return subroutines.OK(), nil
+	}
+
+	model, err := r.authorizationModel(ctx, inst)
+	if err != nil {
+		log.Warn().Err(err).Msg("Authorization model not available")
+		setOpenFGACondition(inst, metav1.ConditionFalse, "ModelUnavailable", err.Error())
+		return subroutines.Pending(r.requeue.Next(inst), "authorization model not available"), nil
+	}
+	var hash string
+	if model != nil {
+		if hash, err = openfga.ModelHash(model); err != nil {
+			setOpenFGACondition(inst, metav1.ConditionFalse, "ModelInvalid", err.Error())
+			return subroutines.Pending(r.requeue.Next(inst), "authorization model invalid"), nil
+		}
+	}
+
+	fga := r.newClient(r.url(inst))
+	store, err := r.ensureStore(ctx, fga, inst)
+	if errors.Is(err, errStoreNotOwned) {
+		log.Warn().Str("store", r.cfg.StoreName).Msg("OpenFGA store is not owned by the operator, leaving it alone")
+		setOpenFGACondition(inst, metav1.ConditionFalse, "StoreNotOwned",
+			fmt.Sprintf("Store %s exists but was not created by the operator, configure another store name", r.cfg.StoreName))
+		return subroutines.OK(), nil
+	}
+	if err != nil {
+		log.Info().Err(err).Msg("OpenFGA store not available yet")
+		setOpenFGACondition(inst, metav1.ConditionFalse, "StoreUnavailable", err.Error())
+		return subroutines.Pending(r.requeue.Next(inst), "waiting for OpenFGA"), nil
+	}
+
+	status := &corev1alpha1.OpenFGAStatus{StoreID: store.ID, StoreName: store.Name}
+	if prev := inst.Status.OpenFGA; prev != nil && prev.StoreID == store.ID {
+		status.AuthorizationModelID = prev.AuthorizationModelID
+		status.ModelHash = prev.ModelHash
+	}
+	if model == nil {
+		inst.Status.OpenFGA = status
+		setOpenFGACondition(inst, metav1.ConditionTrue, "StoreReady",
+			fmt.Sprintf("Store %s (%s) exists, no authorization model is configured", store.Name, store.ID))
+		return subroutines.OK(), nil
+	}
+	if status.AuthorizationModelID == "" || status.ModelHash != hash {
+		id, err := fga.WriteAuthorizationModel(ctx, store.ID, model)
+		if err != nil {
+			log.Warn().Err(err).Str("store", store.ID).Msg("Failed to write authorization model")
+			inst.Status.OpenFGA = status
+			setOpenFGACondition(inst, metav1.ConditionFalse, "ModelWriteFailed", err.Error())
+			return subroutines.Pending(r.requeue.Next(inst), "authorization model not written"), nil
+		}
+		log.Info().Str("store", store.ID).Str("model", id).Msg("Wrote authorization model")
+		status.AuthorizationModelID = id
+		status.ModelHash = hash
+	}
+	inst.Status.OpenFGA = status
+	setOpenFGACondition(inst, metav1.ConditionTrue, "Ready",
+		fmt.Sprintf("Authorization model %s is written to store %s (%s)", status.AuthorizationModelID, store.Name, store.ID))
+	return subroutines.OK(), nil
+}
+
+// ensureStore returns the store recorded in the status, or creates the store
+// with the configured name. A store of that name the operator did not record
+// belongs to someone else and yields errStoreNotOwned.
+func (r *OpenFGASubroutine) ensureStore(ctx context.Context, fga FGAClient, inst *corev1alpha1.PlatformMesh) (*openfga.Store, error) {
+	if s := inst.Status.OpenFGA; s != nil && s.StoreID != "" && s.StoreName == r.cfg.StoreName {
+		store, err := fga.GetStore(ctx, s.StoreID)
+		if err == nil {
+			return store, nil
+		}
+		if !errors.Is(err, openfga.ErrNotFound) {
+			return nil, err
+		}
+	}
+	_, err := fga.FindStore(ctx, r.cfg.StoreName)
+	if err == nil {
+		return nil, errStoreNotOwned
+	}
+	if !errors.Is(err, openfga.ErrNotFound) {
+		return nil, err
+	}
+	return fga.CreateStore(ctx, r.cfg.StoreName)
+}
+
+// authorizationModel returns the model from the configured ConfigMap, the
+// embedded base model when it is enabled instead, or nil when no model is
+// configured.
+func (r *OpenFGASubroutine) authorizationModel(ctx context.Context, inst *corev1alpha1.PlatformMesh) ([]byte, error) {
+	if r.cfg.ModelConfigMapName == "" {
+		if r.cfg.WriteBaseModel {
+			return openfga.BaseModel, nil
+		}
+		return nil, nil
+	}
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: instanceNamespace(inst), Name: r.cfg.ModelConfigMapName}
+	if err := r.client.Get(ctx, key, cm); err != nil {
+		return nil, fmt.Errorf("getting ConfigMap %s: %w", key, err)
+	}
+	model, ok := cm.Data[openFGAModelKey]
+	if !ok || model == "" {
+		return nil, fmt.Errorf("ConfigMap %s has no %s key", key, openFGAModelKey)
+	}
+	return []byte(model), nil
+}
+
+func (r *OpenFGASubroutine) url(inst *corev1alpha1.PlatformMesh) string {
+	if r.cfg.URL != "" {
+		return r.cfg.URL
+	}
+	return fmt.Sprintf("http://openfga.%s:8080", instanceNamespace(inst))
+}
+
+func setOpenFGACondition(inst *corev1alpha1.PlatformMesh, status metav1.ConditionStatus, reason, message string) {
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               OpenFGAReadyConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: inst.Generation,
+	})
+}
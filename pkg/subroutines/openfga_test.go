@@ -0,0 +1,188 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/openfga"
+)
+
+type fakeFGAClient struct {
+	stores  map[string]*openfga.Store
+	models  map[string][][]byte
+	failGet error
+}
+
+func (f *fakeFGAClient) GetStore(_ context.Context, id string) (*openfga.Store, error) {
+	if f.failGet != nil {
+		return nil, f.failGet
+	}
+	if s, ok := f.stores[id]; ok {
+		return s, nil
+	}
+	return nil, openfga.ErrNotFound
+}
+
+func (f *fakeFGAClient) FindStore(_ context.Context, name string) (*openfga.Store, error) {
+	for _, s := range f.stores {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, openfga.ErrNotFound
+}
+
+func (f *fakeFGAClient) CreateStore(_ context.Context, name string) (*openfga.Store, error) {
+	s := &openfga.Store{ID: fmt.Sprintf("store-%d", len(f.stores)+1), Name: name}
+	f.stores[s.ID] = s
+	return s, nil
+}
+
+func (f *fakeFGAClient) WriteAuthorizationModel(_ context.Context, storeID string, model []byte) (string, error) {
+	f.models[storeID] = append(f.models[storeID], model)
+	return fmt.Sprintf("model-%d", len(f.models[storeID])), nil
+}
+
+func TestOpenFGASubroutine_Process(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "openfga-model", Namespace: "platform-mesh-system"},
+		Data:       map[string]string{"model.json": `{"schema_version":"1.1","type_definitions":[{"type":"user"}]}`},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+
+	cfg := config.NewOperatorConfig()
+	r := NewOpenFGASubroutine(cl, &cfg)
+	fga := &fakeFGAClient{stores: map[string]*openfga.Store{}, models: map[string][][]byte{}}
+	var url string
+	r.newClient = func(u string) FGAClient {
+		url = u
+		return fga
+	}
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	// The first run creates the store, without a configured model none is
+	// written.
+	res, err := r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsPending())
+	assert.Equal(t, "http://openfga.platform-mesh-system:8080", url)
+	require.NotNil(t, inst.Status.OpenFGA)
+	assert.Equal(t, "store-1", inst.Status.OpenFGA.StoreID)
+	assert.Equal(t, "platform-mesh", inst.Status.OpenFGA.StoreName)
+	assert.Empty(t, inst.Status.OpenFGA.AuthorizationModelID)
+	assert.Empty(t, fga.models)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, OpenFGAReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, "StoreReady", cond.Reason)
+
+	// The embedded model is written once it is enabled.
+	r.cfg.WriteBaseModel = true
+	_, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, "model-1", inst.Status.OpenFGA.AuthorizationModelID)
+	assert.Equal(t, [][]byte{openfga.BaseModel}, fga.models["store-1"])
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, OpenFGAReadyConditionType))
+
+	// An unchanged model is not written again.
+	_, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Len(t, fga.models["store-1"], 1)
+
+	// A model from the ConfigMap replaces the embedded one.
+	r.cfg.ModelConfigMapName = "openfga-model"
+	_, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	require.Len(t, fga.models["store-1"], 2)
+	assert.JSONEq(t, cm.Data["model.json"], string(fga.models["store-1"][1]))
+	assert.Equal(t, "model-2", inst.Status.OpenFGA.AuthorizationModelID)
+	assert.Len(t, fga.stores, 1)
+
+	// A missing ConfigMap leaves the status in place and retries.
+	r.cfg.ModelConfigMapName = "missing"
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	assert.Equal(t, "model-2", inst.Status.OpenFGA.AuthorizationModelID)
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, OpenFGAReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, "ModelUnavailable", cond.Reason)
+
+	// An unreachable OpenFGA retries without failing the reconcile.
+	r.cfg.ModelConfigMapName = ""
+	fga.failGet = errors.New("connection refused")
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, OpenFGAReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, "StoreUnavailable", cond.Reason)
+}
+
+func TestOpenFGASubroutine_ensureStore(t *testing.T) {
+	cfg := config.NewOperatorConfig()
+	r := NewOpenFGASubroutine(nil, &cfg)
+	fga := &fakeFGAClient{stores: map[string]*openfga.Store{"existing": {ID: "existing", Name: "platform-mesh"}}, models: map[string][][]byte{}}
+	inst := &corev1alpha1.PlatformMesh{}
+
+	// The recorded store is used.
+	inst.Status.OpenFGA = &corev1alpha1.OpenFGAStatus{StoreID: "existing", StoreName: "platform-mesh"}
+	store, err := r.ensureStore(context.Background(), fga, inst)
+	require.NoError(t, err)
+	assert.Equal(t, "existing", store.ID)
+
+	// A store of the same name the operator did not record is left alone.
+	inst.Status.OpenFGA = &corev1alpha1.OpenFGAStatus{StoreID: "deleted", StoreName: "platform-mesh"}
+	_, err = r.ensureStore(context.Background(), fga, inst)
+	assert.ErrorIs(t, err, errStoreNotOwned)
+	assert.Len(t, fga.stores, 1)
+
+	// A renamed store is created.
+	r.cfg.StoreName = "renamed"
+	store, err = r.ensureStore(context.Background(), fga, inst)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", store.Name)
+	assert.Len(t, fga.stores, 2)
+}
+
+func TestOpenFGASubroutine_StoreNotOwned(t *testing.T) {
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.OpenFGA.WriteBaseModel = true
+	r := NewOpenFGASubroutine(nil, &cfg)
+	fga := &fakeFGAClient{stores: map[string]*openfga.Store{"foreign": {ID: "foreign", Name: "platform-mesh"}}, models: map[string][][]byte{}}
+	r.newClient = func(string) FGAClient { return fga }
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	res, err := r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsPending())
+	assert.Nil(t, inst.Status.OpenFGA)
+	assert.Empty(t, fga.models)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, OpenFGAReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "StoreNotOwned", cond.Reason)
+}
@@ -0,0 +1,349 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+const (
+	PrerequisitesSubroutineName = "PrerequisitesSubroutine"
+	// PrerequisitesReadyConditionType is False while objects the enabled
+	// features read but the operator does not create are missing. Its message
+	// lists every missing object.
+	PrerequisitesReadyConditionType = "PrerequisitesReady"
+
+	prerequisiteKindSecret    = "Secret"
+	prerequisiteKindConfigMap = "ConfigMap"
+)
+
+// Prerequisite is a Secret or ConfigMap on the runtime cluster that a feature
+// needs but that has to be created before the install. Components declare
+// their own under prerequisites in the components profile.
+type Prerequisite struct {
+	// Kind is Secret or ConfigMap, Secret when empty.
+	Kind string `json:"kind,omitempty"`
+	// Namespace defaults to the namespace of the PlatformMesh.
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Keys      []string `json:"keys,omitempty"`
+	// Description says what the object holds.
+	Description string `json:"description,omitempty"`
+	// RequiredBy lists the features or components that need the object.
+	RequiredBy []string `json:"-"`
+}
+
+// PrerequisitesSubroutine checks all prerequisites of the enabled features at
+// once and reports the missing ones in a single condition, instead of each
+// feature failing on its own later in the reconcile. The components are read
+// from deployment, which is nil while the Deployment subroutine is disabled.
+type PrerequisitesSubroutine struct {
+	client     client.Client
+	deployment *DeploymentSubroutine
+	cfg        *config.OperatorConfig
+	requeue    *RequeueBackoff
+}
+
+func NewPrerequisitesSubroutine(client client.Client, deployment *DeploymentSubroutine, cfg *config.OperatorConfig) *PrerequisitesSubroutine {
+	return &PrerequisitesSubroutine{
+		client:     client,
+		deployment: deployment,
+		cfg:        cfg,
+		requeue:    NewRequeueBackoff(PrerequisitesSubroutineName, cfg.Subroutines.Prerequisites.Requeue),
+	}
+}
+
+func (r *PrerequisitesSubroutine) GetName() string {
+	return PrerequisitesSubroutineName
+}
+
+func (r *PrerequisitesSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *PrerequisitesSubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *PrerequisitesSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		labelResult := "success"
+		if err != nil {
+			labelResult = "error"
+		}
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), labelResult).Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(inst, res, err) }()
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	prereqs, err := r.requiredPrerequisites(ctx, inst)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	var missing []string
+	for _, p := range prereqs {
+		problem, err := r.checkPrerequisite(ctx, p)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+		if problem == "" {
+			continue
+		}
+		log.Warn().Str("kind", p.Kind).Str("namespace", p.Namespace).Str("name", p.Name).Strs("requiredBy", p.RequiredBy).Msg("Prerequisite " + problem)
+		missing = append(missing, p.remediation(problem))
+	}
+	setPrerequisitesCondition(inst, missing)
+
+	if len(missing) > 0 && r.cfg.Subroutines.Prerequisites.Block {
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), fmt.Sprintf("%d prerequisite(s) missing", len(missing))), nil
+	}
+	return subroutines.OK(), nil
+}
+
+// requiredPrerequisites returns the prerequisites of the enabled operator
+// features and of the enabled components of inst, merged per object.
+func (r *PrerequisitesSubroutine) requiredPrerequisites(ctx context.Context, inst *corev1alpha1.PlatformMesh) ([]Prerequisite, error) {
+	var prereqs []Prerequisite
+	if r.cfg.Subroutines.KcpSetup.Enabled {
+		prereqs = append(prereqs, Prerequisite{
+			Namespace:   instanceNamespace(inst),
+			Name:        r.cfg.Subroutines.KcpSetup.DomainCertificateCASecretName,
+			Keys:        []string{r.cfg.Subroutines.KcpSetup.DomainCertificateCASecretKey},
+			Description: "the CA certificate of the domain certificate, used as CA bundle of the kcp webhooks",
+			RequiredBy:  []string{"kcp setup"},
+		})
+	}
+
+	if r.deployment != nil {
+		services, err := r.deployment.componentServices(ctx, inst)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedKeys(services) {
+			service, _ := services[name].(map[string]interface{})
+			if enabled, _ := service["enabled"].(bool); !enabled {
+				continue
+			}
+			componentPrereqs, err := componentPrerequisites(name, service)
+			if err != nil {
+				return nil, err
+			}
+			prereqs = append(prereqs, componentPrereqs...)
+		}
+	}
+	return mergePrerequisites(prereqs, inst.Namespace), nil
+}
+
+// componentPrerequisites returns the prerequisites of the well-known values of
+// an enabled component and the ones it declares in the profile.
+func componentPrerequisites(name string, service map[string]interface{}) ([]Prerequisite, error) {
+	requiredBy := []string{"component " + name}
+	var prereqs []Prerequisite
+	if enabled, _, _ := unstructured.NestedBool(service, "values", "keycloak", "enabled"); enabled {
+		prereqs = append(prereqs, Prerequisite{
+			Name:        "keycloak-admin",
+			Keys:        []string{"secret"},
+			Description: "the password of the keycloak admin user",
+			RequiredBy:  requiredBy,
+		})
+	}
+	if caSecret, _, _ := unstructured.NestedString(service, "values", "caSecret"); caSecret != "" {
+		prereqs = append(prereqs, Prerequisite{
+			Name:        caSecret,
+			Keys:        []string{"tls.crt"},
+			Description: "the CA certificate of the domain certificate",
+			RequiredBy:  requiredBy,
+		})
+	}
+	for _, name := range imagePullSecretNames(service) {
+		prereqs = append(prereqs, Prerequisite{
+			Name:        name,
+			Keys:        []string{corev1.DockerConfigJsonKey},
+			Description: "the registry credentials to pull the images, e.g. for ghcr.io",
+			RequiredBy:  requiredBy,
+		})
+	}
+
+	declared, ok := service["prerequisites"]
+	if !ok {
+		return prereqs, nil
+	}
+	raw, err := json.Marshal(declared)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal prerequisites of component %s", name)
+	}
+	var own []Prerequisite
+	if err := json.Unmarshal(raw, &own); err != nil {
+		return nil, errors.Wrap(err, "Invalid prerequisites of component %s", name)
+	}
+	for _, p := range own {
+		p.RequiredBy = requiredBy
+		prereqs = append(prereqs, p)
+	}
+	return prereqs, nil
+}
+
+// imagePullSecretNames returns the names in values.imagePullSecrets and
+// values.global.imagePullSecrets of service, given as names or as
+// LocalObjectReferences like in pod specs.
+func imagePullSecretNames(service map[string]interface{}) []string {
+	var names []string
+	for _, path := range [][]string{{"values", "imagePullSecrets"}, {"values", "global", "imagePullSecrets"}} {
+		refs, _, _ := unstructured.NestedSlice(service, path...)
+		for _, ref := range refs {
+			switch ref := ref.(type) {
+			case string:
+				names = append(names, ref)
+			case map[string]interface{}:
+				if name, _ := ref["name"].(string); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// mergePrerequisites defaults kind and namespace and merges prerequisites of
+// the same object, keeping the order of first occurrence.
+func mergePrerequisites(prereqs []Prerequisite, namespace string) []Prerequisite {
+	var merged []Prerequisite
+	index := map[string]int{}
+	for _, p := range prereqs {
+		if p.Kind == "" {
+			p.Kind = prerequisiteKindSecret
+		}
+		if p.Namespace == "" {
+			p.Namespace = namespace
+		}
+		key := p.Kind + "/" + p.Namespace + "/" + p.Name
+		i, ok := index[key]
+		if !ok {
+			p.Keys = slices.Clone(p.Keys)
+			p.RequiredBy = slices.Clone(p.RequiredBy)
+			index[key] = len(merged)
+			merged = append(merged, p)
+			continue
+		}
+		for _, k := range p.Keys {
+			if !slices.Contains(merged[i].Keys, k) {
+				merged[i].Keys = append(merged[i].Keys, k)
+			}
+		}
+		for _, by := range p.RequiredBy {
+			if !slices.Contains(merged[i].RequiredBy, by) {
+				merged[i].RequiredBy = append(merged[i].RequiredBy, by)
+			}
+		}
+	}
+	return merged
+}
+
+// checkPrerequisite returns what is wrong with p, or an empty string if the
+// object exists with all its keys.
+func (r *PrerequisitesSubroutine) checkPrerequisite(ctx context.Context, p Prerequisite) (string, error) {
+	key := client.ObjectKey{Namespace: p.Namespace, Name: p.Name}
+	var present func(k string) bool
+	switch p.Kind {
+	case prerequisiteKindSecret:
+		secret := &corev1.Secret{}
+		if err := r.client.Get(ctx, key, secret); err != nil {
+			if kerrors.IsNotFound(err) {
+				return "is missing", nil
+			}
+			return "", errors.Wrap(err, "Failed to get Secret %s/%s", p.Namespace, p.Name)
+		}
+		present = func(k string) bool { return len(secret.Data[k]) > 0 }
+	case prerequisiteKindConfigMap:
+		cm := &corev1.ConfigMap{}
+		if err := r.client.Get(ctx, key, cm); err != nil {
+			if kerrors.IsNotFound(err) {
+				return "is missing", nil
+			}
+			return "", errors.Wrap(err, "Failed to get ConfigMap %s/%s", p.Namespace, p.Name)
+		}
+		present = func(k string) bool { return cm.Data[k] != "" || len(cm.BinaryData[k]) > 0 }
+	default:
+		return fmt.Sprintf("has unsupported kind %q", p.Kind), nil
+	}
+
+	var missingKeys []string
+	for _, k := range p.Keys {
+		if !present(k) {
+			missingKeys = append(missingKeys, k)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return "lacks " + quotedKeys(missingKeys), nil
+	}
+	return "", nil
+}
+
+// remediation describes how to fix problem of p, e.g. `Secret
+// platform-mesh-system/keycloak-admin is missing (required by component infra):
+// create it with key "secret" holding the password of the keycloak admin user`.
+func (p Prerequisite) remediation(problem string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s/%s %s (required by %s)", p.Kind, p.Namespace, p.Name, problem, strings.Join(p.RequiredBy, ", "))
+	if strings.HasPrefix(problem, "is missing") {
+		b.WriteString(": create it")
+		if len(p.Keys) > 0 {
+			b.WriteString(" with " + quotedKeys(p.Keys))
+		}
+	} else if strings.HasPrefix(problem, "lacks") {
+		b.WriteString(": add it")
+	}
+	if p.Description != "" {
+		b.WriteString(" holding " + p.Description)
+	}
+	return b.String()
+}
+
+func quotedKeys(keys []string) string {
+	quoted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		quoted = append(quoted, strconv.Quote(k))
+	}
+	if len(quoted) == 1 {
+		return "key " + quoted[0]
+	}
+	return "keys " + strings.Join(quoted, ", ")
+}
+
+func setPrerequisitesCondition(inst *corev1alpha1.PlatformMesh, missing []string) {
+	cond := metav1.Condition{
+		Type:               PrerequisitesReadyConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PrerequisitesPresent",
+		Message:            "All prerequisites of the enabled features are present",
+		ObservedGeneration: inst.Generation,
+	}
+	if len(missing) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "PrerequisitesMissing"
+		cond.Message = fmt.Sprintf("%d prerequisite(s) missing: %s", len(missing), strings.Join(missing, "; "))
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, cond)
+}
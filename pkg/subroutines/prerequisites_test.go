@@ -0,0 +1,142 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestComponentPrerequisites(t *testing.T) {
+	service := map[string]interface{}{
+		"enabled": true,
+		"values": map[string]interface{}{
+			"keycloak":         map[string]interface{}{"enabled": true},
+			"caSecret":         "domain-certificate-ca",
+			"imagePullSecrets": []interface{}{map[string]interface{}{"name": "github-pull-secret"}},
+			"global":           map[string]interface{}{"imagePullSecrets": []interface{}{"registry-pull-secret"}},
+		},
+		"prerequisites": []interface{}{
+			map[string]interface{}{"name": "ghcr-pull-secret", "keys": []interface{}{".dockerconfigjson"}, "namespace": "default"},
+		},
+	}
+
+	prereqs, err := componentPrerequisites("infra", service)
+	require.NoError(t, err)
+	require.Len(t, prereqs, 5)
+	assert.Equal(t, "keycloak-admin", prereqs[0].Name)
+	assert.Equal(t, []string{"tls.crt"}, prereqs[1].Keys)
+	assert.Equal(t, "github-pull-secret", prereqs[2].Name)
+	assert.Equal(t, []string{".dockerconfigjson"}, prereqs[2].Keys)
+	assert.Equal(t, "registry-pull-secret", prereqs[3].Name)
+	assert.Equal(t, "ghcr-pull-secret", prereqs[4].Name)
+	assert.Equal(t, "default", prereqs[4].Namespace)
+	assert.Equal(t, []string{"component infra"}, prereqs[4].RequiredBy)
+
+	_, err = componentPrerequisites("broken", map[string]interface{}{"prerequisites": "ghcr-pull-secret"})
+	assert.Error(t, err)
+}
+
+func TestMergePrerequisites(t *testing.T) {
+	merged := mergePrerequisites([]Prerequisite{
+		{Name: "domain-certificate-ca", Keys: []string{"tls.crt"}, RequiredBy: []string{"component portal"}},
+		{Kind: "ConfigMap", Name: "domain-certificate-ca", RequiredBy: []string{"component iam"}},
+		{Name: "domain-certificate-ca", Keys: []string{"tls.crt", "ca.crt"}, RequiredBy: []string{"component iam"}},
+	}, "platform-mesh-system")
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, "Secret", merged[0].Kind)
+	assert.Equal(t, "platform-mesh-system", merged[0].Namespace)
+	assert.Equal(t, []string{"tls.crt", "ca.crt"}, merged[0].Keys)
+	assert.Equal(t, []string{"component portal", "component iam"}, merged[0].RequiredBy)
+	assert.Equal(t, "ConfigMap", merged[1].Kind)
+}
+
+func TestPrerequisiteRemediation(t *testing.T) {
+	p := Prerequisite{Kind: "Secret", Namespace: "platform-mesh-system", Name: "keycloak-admin", Keys: []string{"secret"},
+		Description: "the password of the keycloak admin user", RequiredBy: []string{"component infra"}}
+
+	assert.Equal(t, `Secret platform-mesh-system/keycloak-admin is missing (required by component infra): create it with key "secret" holding the password of the keycloak admin user`,
+		p.remediation("is missing"))
+	assert.Equal(t, `Secret platform-mesh-system/keycloak-admin lacks key "secret" (required by component infra): add it holding the password of the keycloak admin user`,
+		p.remediation("lacks "+quotedKeys([]string{"secret"})))
+}
+
+func TestPrerequisitesSubroutine_Process(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "domain-certificate", Namespace: "platform-mesh-system"}, Data: map[string][]byte{"tls.crt": []byte("crt")}},
+	).Build()
+
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.Deployment.Enabled = false
+	r := NewPrerequisitesSubroutine(cl, nil, &cfg)
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	res, err := r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsStopWithRequeue())
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, PrerequisitesReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Contains(t, cond.Message, `Secret platform-mesh-system/domain-certificate lacks key "ca.crt" (required by kcp setup)`)
+
+	// Blocking stops the reconcile until the key is added.
+	cfg.Subroutines.Prerequisites.Block = true
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsStopWithRequeue())
+
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "domain-certificate", Namespace: "platform-mesh-system"}, secret))
+	secret.Data["ca.crt"] = []byte("ca")
+	require.NoError(t, cl.Update(ctx, secret))
+
+	res, err = r.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsStopWithRequeue())
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, PrerequisitesReadyConditionType))
+}
+
+func TestPrerequisitesSubroutine_ComponentsWithoutWrites(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	cfg := config.NewOperatorConfig()
+	deployment := &DeploymentSubroutine{clientRuntime: cl, clientInfra: cl, cfgOperator: &cfg}
+	r := NewPrerequisitesSubroutine(cl, deployment, &cfg)
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	// The components come from the embedded default profile, which the
+	// Deployment subroutine creates later.
+	prereqs, err := r.requiredPrerequisites(ctx, inst)
+	require.NoError(t, err)
+	assert.NotEmpty(t, prereqs)
+	configMaps := &corev1.ConfigMapList{}
+	require.NoError(t, cl.List(ctx, configMaps))
+	assert.Empty(t, configMaps.Items)
+	assert.Empty(t, inst.Status.ProfileHash)
+}
@@ -0,0 +1,77 @@
+package subroutines
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/merge"
+)
+
+// profileChannelsKey holds the per-channel overrides of a unified profile:
+//
+//	infra: {...}
+//	components: {...}
+//	channels:
+//	  edge:
+//	    components: {...}
+const profileChannelsKey = "channels"
+
+// profileSections are the sections of a channel merged over the top-level ones.
+var profileSections = []string{"infra", "components"}
+
+// resolveProfile parses a unified profile and merges the infra and components
+// sections of the instance's channel over the top-level ones. Without a channel
+// the top-level sections are used as they are.
+func resolveProfile(profileYAML string, inst *v1alpha1.PlatformMesh, log *logger.Logger) (map[string]interface{}, error) {
+	var profile map[string]interface{}
+	if err := yaml.Unmarshal([]byte(profileYAML), &profile); err != nil {
+		return nil, errors.Wrap(err, "failed to parse profile YAML from ConfigMap")
+	}
+	if profile == nil {
+		profile = map[string]interface{}{}
+	}
+	channels, _ := profile[profileChannelsKey].(map[string]interface{})
+	delete(profile, profileChannelsKey)
+
+	channel := inst.Spec.Channel
+	if channel == "" {
+		return profile, nil
+	}
+	overrides, ok := channels[channel].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile has no section for channel %q, available channels: %v", channel, sortedKeys(channels))
+	}
+
+	for _, section := range profileSections {
+		override, ok := overrides[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		base, _ := profile[section].(map[string]interface{})
+		strategies, err := profileListStrategies(base)
+		if err != nil {
+			return nil, err
+		}
+		merged, err := merge.MergeMapsWithStrategies(base, override, strategies, log)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to merge %s profile of channel %s", section, channel)
+		}
+		profile[section] = merged
+	}
+	log.Debug().Str("channel", channel).Msg("Resolved profile for channel")
+	return profile, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package subroutines
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// ProfileManagedLabel marks profile ConfigMaps. Only ConfigMaps carrying
+	// it, with any value, are watched for profile changes.
+	ProfileManagedLabel = "core.platform-mesh.io/profile"
+	// ProfileManagedDefault is the value of ProfileManagedLabel on the
+	// profiles the operator created from its embedded default profile.
+	ProfileManagedDefault = "managed-default"
+	// ProfileChecksumAnnotation holds the checksum of the profile the operator
+	// last wrote. A profile whose content no longer matches it was edited and
+	// is left alone.
+	ProfileChecksumAnnotation = "core.platform-mesh.io/profile-checksum"
+)
+
+//go:embed profiles/default.yaml
+var defaultProfile string
+
+// ProfileCacheSelector selects the ConfigMaps the manager caches, the profiles
+// labeled with ProfileManagedLabel. Other ConfigMaps are read uncached.
+func ProfileCacheSelector() labels.Selector {
+	req, _ := labels.NewRequirement(ProfileManagedLabel, selection.Exists, nil)
+	return labels.NewSelector().Add(*req)
+}
+
+func profileChecksum(profile string) string {
+	sum := sha256.Sum256([]byte(profile))
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultProfileConfigMap returns the default profile ConfigMap of inst,
+// controlled by inst.
+func defaultProfileConfigMap(inst *v1alpha1.PlatformMesh) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      inst.Name + defaultProfileConfigMapSuffix,
+			Namespace: inst.Namespace,
+			Labels: map[string]string{
+				ProfileManagedLabel:    ProfileManagedDefault,
+				InstanceNameLabel:      inst.Name,
+				InstanceNamespaceLabel: inst.Namespace,
+			},
+			Annotations: map[string]string{
+				ProfileChecksumAnnotation: profileChecksum(defaultProfile),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(inst, v1alpha1.GroupVersion.WithKind("PlatformMesh")),
+			},
+		},
+		Data: map[string]string{profileConfigMapKey: defaultProfile},
+	}
+}
+
+// isUneditedDefaultProfile reports whether configMap is a default profile the
+// operator created and nobody edited since.
+func isUneditedDefaultProfile(configMap *corev1.ConfigMap) bool {
+	if configMap.Labels[ProfileManagedLabel] != ProfileManagedDefault {
+		return false
+	}
+	checksum, ok := configMap.Annotations[ProfileChecksumAnnotation]
+	return ok && checksum == profileChecksum(configMap.Data[profileConfigMapKey])
+}
+
+// ensureDefaultProfileConfigMap creates the default profile ConfigMap of inst
+// when it is missing and updates it to the embedded default profile of this
+// operator version while it is unedited. Edited default profiles are returned
+// as they are.
+func ensureDefaultProfileConfigMap(ctx context.Context, c client.Client, inst *v1alpha1.PlatformMesh, existing *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	desired := defaultProfileConfigMap(inst)
+
+	if existing == nil {
+		if err := c.Create(ctx, desired); err != nil {
+			return nil, err
+		}
+		log.Info().Str("configmap", desired.Name).Str("namespace", desired.Namespace).Msg("Created default profile ConfigMap")
+		return desired, nil
+	}
+
+	if !isUneditedDefaultProfile(existing) || existing.Data[profileConfigMapKey] == defaultProfile {
+		return existing, nil
+	}
+	existing.Data[profileConfigMapKey] = defaultProfile
+	existing.Annotations[ProfileChecksumAnnotation] = desired.Annotations[ProfileChecksumAnnotation]
+	if err := c.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	log.Info().Str("configmap", existing.Name).Str("namespace", existing.Namespace).Msg("Updated unedited default profile ConfigMap")
+	return existing, nil
+}
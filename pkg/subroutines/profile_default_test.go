@@ -0,0 +1,91 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestGetProfileConfigMapCreatesDefault(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system", UID: "uid-1"}}
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &DeploymentSubroutine{clientRuntime: cl, cfgOperator: &config.OperatorConfig{}}
+
+	cm, err := r.getProfileConfigMap(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, defaultProfile, cm.Data[profileConfigMapKey])
+
+	stored := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "pm-profile", Namespace: "platform-mesh-system"}, stored))
+	assert.Equal(t, ProfileManagedDefault, stored.Labels[ProfileManagedLabel])
+	assert.True(t, metav1.IsControlledBy(stored, inst))
+	assert.True(t, isUneditedDefaultProfile(stored))
+
+	_, _, err = r.loadProfileSections(ctx, inst)
+	require.NoError(t, err, "expected the embedded default profile to resolve")
+}
+
+func TestEnsureDefaultProfileConfigMap(t *testing.T) {
+	ctx := context.Background()
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	oldProfile := "infra: {}\ncomponents: {}\n"
+
+	outdated := defaultProfileConfigMap(inst)
+	outdated.Data[profileConfigMapKey] = oldProfile
+	outdated.Annotations[ProfileChecksumAnnotation] = profileChecksum(oldProfile)
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(outdated).Build()
+	existing := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(outdated), existing))
+
+	// An unedited default profile follows the embedded one.
+	cm, err := ensureDefaultProfileConfigMap(ctx, cl, inst, existing)
+	require.NoError(t, err)
+	assert.Equal(t, defaultProfile, cm.Data[profileConfigMapKey])
+	assert.True(t, isUneditedDefaultProfile(cm))
+
+	// An edited default profile is never overwritten.
+	edited := defaultProfileConfigMap(inst)
+	edited.Data[profileConfigMapKey] = oldProfile
+	cl = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(edited).Build()
+	existing = &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(edited), existing))
+	cm, err = ensureDefaultProfileConfigMap(ctx, cl, inst, existing)
+	require.NoError(t, err)
+	assert.Equal(t, oldProfile, cm.Data[profileConfigMapKey])
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(edited), existing))
+	assert.Equal(t, oldProfile, existing.Data[profileConfigMapKey])
+
+	// Profiles without the managed-default label are never touched.
+	existing.Labels = nil
+	assert.False(t, isUneditedDefaultProfile(existing))
+}
+
+func TestProfileCacheSelector(t *testing.T) {
+	selector := ProfileCacheSelector()
+	assert.True(t, selector.Matches(labels.Set{ProfileManagedLabel: ProfileManagedDefault}))
+	assert.True(t, selector.Matches(labels.Set{ProfileManagedLabel: "true"}))
+	assert.False(t, selector.Matches(labels.Set{"app": "profile"}))
+}
+
+func TestDefaultProfileIsNeutral(t *testing.T) {
+	// The values of the local kind setup belong into its own profile.
+	for _, kindOnly := range []string{"10.96.", "localhost", "nodePort", "skipVerify: true", "level: debug", "hostAliases"} {
+		assert.NotContains(t, defaultProfile, kindOnly)
+	}
+}
@@ -0,0 +1,136 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/merge"
+)
+
+const (
+	// effectiveProfileConfigMapSuffix names the ConfigMap holding the merged
+	// profile of an instance with spec.profiles.
+	effectiveProfileConfigMapSuffix = "-effective-profile"
+	// ProfileLayersAnnotation lists the profile ConfigMaps the effective
+	// profile was merged from, in merge order.
+	ProfileLayersAnnotation = "core.platform-mesh.io/profile-layers"
+)
+
+// ProfileReferences returns the profile ConfigMaps of inst in merge order:
+// spec.profiles, else spec.profileConfigMap, else the default profile
+// ConfigMap. Namespaces default to the one of inst.
+func ProfileReferences(inst *v1alpha1.PlatformMesh) []types.NamespacedName {
+	refs := inst.Spec.Profiles
+	if len(refs) == 0 && inst.Spec.ProfileConfigMap != nil {
+		refs = []v1alpha1.ConfigMapReference{*inst.Spec.ProfileConfigMap}
+	}
+	if len(refs) == 0 {
+		return []types.NamespacedName{{Name: inst.Name + defaultProfileConfigMapSuffix, Namespace: inst.Namespace}}
+	}
+
+	keys := make([]types.NamespacedName, 0, len(refs))
+	for _, ref := range refs {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = inst.Namespace
+		}
+		keys = append(keys, types.NamespacedName{Name: ref.Name, Namespace: namespace})
+	}
+	return keys
+}
+
+// mergeProfileLayers reads the profile ConfigMaps of spec.profiles with cl and
+// deep-merges them in order, later profiles overriding earlier ones. Channels
+// are merged like any other section and resolved afterwards.
+func mergeProfileLayers(ctx context.Context, cl client.Client, inst *v1alpha1.PlatformMesh) (string, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	merged := map[string]interface{}{}
+	for _, key := range ProfileReferences(inst) {
+		configMap := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, key, configMap); err != nil {
+			return "", fmt.Errorf("failed to get profile ConfigMap %s: %w", key, err)
+		}
+		profileYAML, ok := configMap.Data[profileConfigMapKey]
+		if !ok {
+			return "", fmt.Errorf("profile ConfigMap %s does not contain key %s", key, profileConfigMapKey)
+		}
+		var layer map[string]interface{}
+		if err := yaml.Unmarshal([]byte(profileYAML), &layer); err != nil {
+			return "", errors.Wrap(err, "Failed to parse profile ConfigMap %s", key)
+		}
+		var err error
+		if merged, err = mergeProfileLayer(merged, layer, log); err != nil {
+			return "", errors.Wrap(err, "Failed to merge profile ConfigMap %s", key)
+		}
+	}
+
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to marshal effective profile")
+	}
+	return string(out), nil
+}
+
+// mergeProfileLayer merges the sections of layer over those of base, with the
+// list merge strategies of the base section.
+func mergeProfileLayer(base, layer map[string]interface{}, log *logger.Logger) (map[string]interface{}, error) {
+	for section, value := range layer {
+		override, ok := value.(map[string]interface{})
+		current, isMap := base[section].(map[string]interface{})
+		if !ok || !isMap {
+			base[section] = value
+			continue
+		}
+		strategies, err := profileListStrategies(current)
+		if err != nil {
+			return nil, err
+		}
+		if base[section], err = merge.MergeMapsWithStrategies(current, override, strategies, log); err != nil {
+			return nil, err
+		}
+	}
+	return base, nil
+}
+
+// effectiveProfileConfigMap merges the profiles of spec.profiles and stores
+// the result in the <name>-effective-profile ConfigMap, so the profile the
+// components are rendered from can be inspected. The ConfigMap is written on
+// every merge and never read back.
+func (r *DeploymentSubroutine) effectiveProfileConfigMap(ctx context.Context, inst *v1alpha1.PlatformMesh) (*corev1.ConfigMap, error) {
+	profileYAML, err := mergeProfileLayers(ctx, r.clientRuntime, inst)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]string, 0, len(inst.Spec.Profiles))
+	for _, key := range ProfileReferences(inst) {
+		layers = append(layers, key.String())
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: inst.Name + effectiveProfileConfigMapSuffix, Namespace: inst.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.clientRuntime, configMap, func() error {
+		if err := claimLabeledObject(configMap, inst); err != nil {
+			return err
+		}
+		if configMap.Annotations == nil {
+			configMap.Annotations = map[string]string{}
+		}
+		configMap.Annotations[ProfileLayersAnnotation] = strings.Join(layers, ",")
+		configMap.Data = map[string]string{profileConfigMapKey: profileYAML}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to write effective profile ConfigMap %s/%s", configMap.Namespace, configMap.Name)
+	}
+	return configMap, nil
+}
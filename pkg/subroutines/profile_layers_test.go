@@ -0,0 +1,134 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func newProfileLayer(name, namespace, profileYAML string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string]string{profileConfigMapKey: profileYAML},
+	}
+}
+
+func TestProfileReferences(t *testing.T) {
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "ns"}}
+	assert.Equal(t, []types.NamespacedName{{Name: "pm-profile", Namespace: "ns"}}, ProfileReferences(inst))
+
+	inst.Spec.ProfileConfigMap = &v1alpha1.ConfigMapReference{Name: "custom", Namespace: "shared"}
+	assert.Equal(t, []types.NamespacedName{{Name: "custom", Namespace: "shared"}}, ProfileReferences(inst))
+
+	inst.Spec.Profiles = []v1alpha1.ConfigMapReference{{Name: "base", Namespace: "platform-mesh-system"}, {Name: "local"}}
+	assert.Equal(t, []types.NamespacedName{
+		{Name: "base", Namespace: "platform-mesh-system"},
+		{Name: "local", Namespace: "ns"},
+	}, ProfileReferences(inst))
+}
+
+func TestEffectiveProfileConfigMap(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newProfileLayer("base", "platform-mesh-system", `
+infra:
+  deploymentTechnology: fluxcd
+  baseDomain: base.example.com
+components:
+  services:
+    iam:
+      enabled: true
+      values:
+        replicaCount: 1
+        args: [--a, --b]
+channels:
+  edge:
+    components:
+      services:
+        iam:
+          values:
+            replicaCount: 3
+`),
+		newProfileLayer("cc-two", "platform-mesh-system", `
+infra:
+  baseDomain: cc-two.example.com
+components:
+  services:
+    iam:
+      values:
+        args: [--c]
+`),
+		newProfileLayer("local", "pm-ns", `
+components:
+  services:
+    portal:
+      enabled: true
+`),
+	).Build()
+
+	inst := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns"},
+		Spec: v1alpha1.PlatformMeshSpec{
+			Channel: "edge",
+			Profiles: []v1alpha1.ConfigMapReference{
+				{Name: "base", Namespace: "platform-mesh-system"},
+				{Name: "cc-two", Namespace: "platform-mesh-system"},
+				{Name: "local"},
+			},
+		},
+	}
+	sub := &DeploymentSubroutine{clientRuntime: cl}
+
+	infraYAML, componentsYAML, err := sub.loadProfileSections(ctx, inst)
+	require.NoError(t, err)
+	assert.YAMLEq(t, "deploymentTechnology: fluxcd\nbaseDomain: cc-two.example.com\n", infraYAML)
+	assert.YAMLEq(t, `
+services:
+  iam:
+    enabled: true
+    values:
+      replicaCount: 3
+      args: [--c]
+  portal:
+    enabled: true
+`, componentsYAML)
+
+	effective := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "pm-ns", Name: "pm-effective-profile"}, effective))
+	assert.Equal(t, "platform-mesh-system/base,platform-mesh-system/cc-two,pm-ns/local", effective.Annotations[ProfileLayersAnnotation])
+	assert.Equal(t, "pm", effective.Labels[InstanceNameLabel])
+	var merged map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(effective.Data[profileConfigMapKey]), &merged))
+	assert.Contains(t, merged, "channels")
+	assert.Equal(t, profileChecksum(effective.Data[profileConfigMapKey]), inst.Status.ProfileHash)
+
+	// The other subroutines read the same merged profile.
+	profile, err := loadProfile(ctx, cl, inst)
+	require.NoError(t, err)
+	assert.Equal(t, "cc-two.example.com", profile["infra"].(map[string]interface{})["baseDomain"])
+
+	inst.Spec.Profiles = append(inst.Spec.Profiles, v1alpha1.ConfigMapReference{Name: "missing"})
+	_, err = loadProfile(ctx, cl, inst)
+	assert.True(t, kerrors.IsNotFound(err))
+	assert.ErrorContains(t, err, "failed to get profile ConfigMap pm-ns/missing")
+}
@@ -0,0 +1,150 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/platform-mesh/subroutines"
+	"k8s.io/client-go/rest"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const defaultMaxConcurrentConnections = 4
+
+// ConnectionsError aggregates the errors of all provider or initializer
+// connections that failed in one reconcile.
+type ConnectionsError struct {
+	Type corev1alpha1.ConnectionType
+	// Secrets and Errors are the failed connections and their errors, in
+	// connection order.
+	Secrets []string
+	Errors  []error
+}
+
+func (e *ConnectionsError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = fmt.Sprintf("%s: %v", e.Secrets[i], err)
+	}
+	return fmt.Sprintf("%d %s connection(s) failed: %s", len(e.Errors), strings.ToLower(string(e.Type)), strings.Join(parts, "; "))
+}
+
+func (e *ConnectionsError) Unwrap() []error {
+	return e.Errors
+}
+
+type connectionResult struct {
+	instance *corev1alpha1.PlatformMesh
+	res      subroutines.Result
+	err      error
+}
+
+// handleProviderConnections handles providers with at most workers
+// connections in parallel. It returns the secrets of connections that are not
+// serving yet and the errors of all failed connections.
+func (r *ProvidersecretSubroutine) handleProviderConnections(
+	ctx context.Context, instance *corev1alpha1.PlatformMesh, providers []corev1alpha1.ProviderConnection, cfg *rest.Config, workers int,
+) ([]string, error) {
+	secrets := make([]string, len(providers))
+	for i, pc := range providers {
+		secrets[i] = pc.Secret
+	}
+	return handleConnections(instance, corev1alpha1.ConnectionTypeProvider, secrets, workers,
+		func(conn *corev1alpha1.PlatformMesh, i int) (subroutines.Result, error) {
+			return r.HandleProviderConnection(ctx, conn, providers[i], rest.CopyConfig(cfg))
+		})
+}
+
+// handleConnections calls handle for the connections writing secrets, with at
+// most workers of them in parallel. Each call records its status on its own
+// copy of instance, the copies are merged back in connection order so that
+// the status does not depend on scheduling. It returns the secrets of
+// connections that are not serving yet and a *ConnectionsError holding the
+// errors of all failed connections.
+func handleConnections(
+	instance *corev1alpha1.PlatformMesh, connType corev1alpha1.ConnectionType, secrets []string, workers int,
+	handle func(conn *corev1alpha1.PlatformMesh, i int) (subroutines.Result, error),
+) ([]string, error) {
+	if workers < 1 {
+		workers = defaultMaxConcurrentConnections
+	}
+	results := make([]connectionResult, len(secrets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, secret := range secrets {
+		connInstance := connectionInstance(instance, secret, connType)
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			res, err := handle(connInstance, i)
+			results[i] = connectionResult{instance: connInstance, res: res, err: err}
+		})
+	}
+	wg.Wait()
+
+	var notReady []string
+	var errs *ConnectionsError
+	for i, result := range results {
+		mergeConnectionStatus(instance, result.instance)
+		if result.err != nil {
+			if errs == nil {
+				errs = &ConnectionsError{Type: connType}
+			}
+			errs.Secrets = append(errs.Secrets, secrets[i])
+			errs.Errors = append(errs.Errors, result.err)
+			continue
+		}
+		if !result.res.IsContinue() {
+			notReady = append(notReady, secrets[i])
+		}
+	}
+	if errs != nil {
+		return notReady, errs
+	}
+	return notReady, nil
+}
+
+// connectionInstance copies instance for handling the connection of type
+// connType writing secret. The copy only carries the status entries of that
+// connection.
+func connectionInstance(instance *corev1alpha1.PlatformMesh, secret string, connType corev1alpha1.ConnectionType) *corev1alpha1.PlatformMesh {
+	conn := instance.DeepCopy()
+	conn.Status.ProviderSecrets = nil
+	conn.Status.ProviderConnections = nil
+	conn.Status.Connections = nil
+	for _, c := range instance.Status.Connections {
+		if c.Name == secret && c.Type == connType {
+			conn.Status.Connections = append(conn.Status.Connections, *c.DeepCopy())
+		}
+	}
+	return conn
+}
+
+// mergeConnectionStatus adds or updates the status entries recorded on conn in
+// instance.
+func mergeConnectionStatus(instance, conn *corev1alpha1.PlatformMesh) {
+	for _, s := range conn.Status.ProviderSecrets {
+		recordProviderSecretOwnership(instance, s.Name, s.Namespace, s.Ownership)
+	}
+	for _, pc := range conn.Status.ProviderConnections {
+		ref := corev1alpha1.ProviderConnection{Secret: pc.Secret, Path: pc.Path, SANamespace: pc.SANamespace}
+		recordProviderConnectionStatus(instance, ref, pc.RBACUpToDate, pc.TokenExpiresAt, pc.ClientCertExpiresAt)
+		recordProposedRules(instance, ref, pc.ProposedRules, pc.ProposedRulesHash)
+	}
+	for _, c := range conn.Status.Connections {
+		replaced := false
+		for i := range instance.Status.Connections {
+			if instance.Status.Connections[i].Name == c.Name && instance.Status.Connections[i].Type == c.Type {
+				instance.Status.Connections[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			instance.Status.Connections = append(instance.Status.Connections, c)
+		}
+	}
+}
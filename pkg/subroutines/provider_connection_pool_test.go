@@ -0,0 +1,122 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+)
+
+func TestHandleProviderConnections_AggregatesErrors(t *testing.T) {
+	errA := errors.New("workspace a unreachable")
+	errC := errors.New("workspace c unreachable")
+	kcpHelper := new(mocks.KcpHelper)
+	kcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:a").Return(nil, errA).Once()
+	kcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:b").Return(nil, errors.New("workspace b unreachable")).Once()
+	kcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:c").Return(nil, errC).Once()
+
+	var providers []corev1alpha1.ProviderConnection
+	for _, name := range []string{"a", "b", "c"} {
+		providers = append(providers, corev1alpha1.ProviderConnection{
+			AdminAuth: ptr.To(true), EndpointSliceName: ptr.To("core.platform-mesh.io"), Path: "root:" + name, Secret: name,
+		})
+	}
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	ctx = context.WithValue(ctx, keys.ConfigCtxKey, config.NewOperatorConfig())
+
+	r := NewProviderSecretSubroutine(nil, kcpHelper, fakeHelm{ready: true}, "")
+	instance := &corev1alpha1.PlatformMesh{}
+	notReady, err := r.handleProviderConnections(ctx, instance, providers, &rest.Config{Host: "https://kcp.example.com"}, 2)
+
+	assert.Empty(t, notReady)
+	var connErr *ConnectionsError
+	require.ErrorAs(t, err, &connErr)
+	assert.Equal(t, []string{"a", "b", "c"}, connErr.Secrets)
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errC)
+	assert.Contains(t, err.Error(), "3 provider connection(s) failed: a: workspace a unreachable;")
+
+	// Failed connections are still recorded, in connection order.
+	require.Len(t, instance.Status.Connections, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, name, instance.Status.Connections[i].Name)
+		assert.False(t, instance.Status.Connections[i].Healthy)
+	}
+	kcpHelper.AssertExpectations(t)
+}
+
+func TestHandleInitializerConnections_AggregatesErrors(t *testing.T) {
+	errOrg := errors.New("workspace orgs unreachable")
+	errAccount := errors.New("workspace accounts unreachable")
+	kcpHelper := new(mocks.KcpHelper)
+	kcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:orgs").Return(nil, errOrg).Once()
+	kcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:accounts").Return(nil, errAccount).Once()
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	ctx = context.WithValue(ctx, keys.ConfigCtxKey, config.NewOperatorConfig())
+
+	r := NewProviderSecretSubroutine(nil, kcpHelper, fakeHelm{ready: true}, "")
+	instance := &corev1alpha1.PlatformMesh{}
+	instance.Spec.Kcp.InitializerConnections = []corev1alpha1.InitializerConnection{
+		{WorkspaceTypeName: "org", Path: "root:orgs", Secret: "org-initializer"},
+		{WorkspaceTypeName: "account", Path: "root:accounts", Secret: "account-initializer"},
+	}
+	notReady, err := r.handleInitializerConnections(ctx, instance, &rest.Config{Host: "https://kcp.example.com"}, 2)
+
+	assert.Empty(t, notReady)
+	var connErr *ConnectionsError
+	require.ErrorAs(t, err, &connErr)
+	assert.Equal(t, corev1alpha1.ConnectionTypeInitializer, connErr.Type)
+	assert.Equal(t, []string{"org-initializer", "account-initializer"}, connErr.Secrets)
+	assert.ErrorIs(t, err, errOrg)
+	assert.ErrorIs(t, err, errAccount)
+	assert.Contains(t, err.Error(), "2 initializer connection(s) failed: org-initializer: workspace orgs unreachable;")
+
+	require.Len(t, instance.Status.Connections, 2)
+	for i, name := range []string{"org-initializer", "account-initializer"} {
+		assert.Equal(t, name, instance.Status.Connections[i].Name)
+		assert.Equal(t, corev1alpha1.ConnectionTypeInitializer, instance.Status.Connections[i].Type)
+		assert.False(t, instance.Status.Connections[i].Healthy)
+	}
+	kcpHelper.AssertExpectations(t)
+}
+
+func TestMergeConnectionStatus(t *testing.T) {
+	resolved := metav1.Now()
+	instance := &corev1alpha1.PlatformMesh{Status: corev1alpha1.PlatformMeshStatus{Connections: []corev1alpha1.ConnectionStatus{
+		{Name: "a", Type: corev1alpha1.ConnectionTypeProvider, ResolvedURL: "https://old", LastResolved: &resolved},
+		{Name: "a", Type: corev1alpha1.ConnectionTypeInitializer},
+		{Name: "b", Type: corev1alpha1.ConnectionTypeProvider},
+	}}}
+
+	conn := connectionInstance(instance, "a", corev1alpha1.ConnectionTypeProvider)
+	require.Len(t, conn.Status.Connections, 1)
+	assert.Equal(t, "https://old", conn.Status.Connections[0].ResolvedURL)
+
+	recordConnectionStatus(conn, "a", corev1alpha1.ConnectionTypeProvider, "https://new", true, true)
+	recordProviderSecretOwnership(conn, "a", "platform-mesh-system", corev1alpha1.ProviderSecretOwned)
+	mergeConnectionStatus(instance, conn)
+
+	require.Len(t, instance.Status.Connections, 3)
+	assert.Equal(t, "https://new", instance.Status.Connections[0].ResolvedURL)
+	assert.True(t, instance.Status.Connections[0].Ready)
+	assert.Empty(t, instance.Status.Connections[1].ResolvedURL)
+	assert.Equal(t, []corev1alpha1.ProviderSecretStatus{{Name: "a", Namespace: "platform-mesh-system", Ownership: corev1alpha1.ProviderSecretOwned}}, instance.Status.ProviderSecrets)
+}
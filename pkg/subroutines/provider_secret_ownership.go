@@ -0,0 +1,154 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventing"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+const (
+	// InstanceNameLabel and InstanceNamespaceLabel identify the PlatformMesh
+	// instance an applied object or provider secret belongs to. They are the
+	// only link for objects outside of the instance namespace, where owner
+	// references cannot be used.
+	InstanceNameLabel      = "core.platform-mesh.io/instance-name"
+	InstanceNamespaceLabel = "core.platform-mesh.io/instance-namespace"
+)
+
+// SecretOwnedByOtherError is returned when a provider secret is controlled by
+// another object and therefore not adopted.
+type SecretOwnedByOtherError struct {
+	Name      string
+	Namespace string
+	Owner     metav1.OwnerReference
+}
+
+func (e *SecretOwnedByOtherError) Error() string {
+	return fmt.Sprintf("secret %s/%s is controlled by %s %s", e.Namespace, e.Name, e.Owner.Kind, e.Owner.Name)
+}
+
+func isSecretOwnedByOther(err error) bool {
+	var ownedErr *SecretOwnedByOtherError
+	return stderrors.As(err, &ownedErr)
+}
+
+// setProviderSecretOwnership ties secret to instance. Secrets in the instance
+// namespace get a controller reference, pre-existing secrets without a
+// controller are adopted. Secrets in other namespaces are only labeled.
+func setProviderSecretOwnership(secret *corev1.Secret, instance *corev1alpha1.PlatformMesh) (corev1alpha1.ProviderSecretOwnership, error) {
+	if controller := metav1.GetControllerOf(secret); controller != nil && controller.UID != instance.UID {
+		return corev1alpha1.ProviderSecretConflict, &SecretOwnedByOtherError{Name: secret.Name, Namespace: secret.Namespace, Owner: *controller}
+	}
+
+	setInstanceLabels(secret, instance)
+
+	if secret.Namespace != instance.Namespace {
+		return corev1alpha1.ProviderSecretLabeled, nil
+	}
+	if metav1.IsControlledBy(secret, instance) {
+		return corev1alpha1.ProviderSecretOwned, nil
+	}
+
+	ownerRef := metav1.NewControllerRef(instance, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))
+	secret.SetOwnerReferences(append(secret.GetOwnerReferences(), *ownerRef))
+	if secret.ResourceVersion != "" {
+		return corev1alpha1.ProviderSecretAdopted, nil
+	}
+	return corev1alpha1.ProviderSecretOwned, nil
+}
+
+// writeProviderSecret creates or updates a provider secret with data and
+// annotations and sets its ownership in the same write, so no secret is left
+// without an owner. Other annotations of the secret are kept. The secret is
+// labeled with purpose and the kcp workspace path it connects to.
+func writeProviderSecret(
+	ctx context.Context, k8sClient client.Client, instance *corev1alpha1.PlatformMesh, name, namespace, purpose, connection string, data map[string][]byte, annotations map[string]string,
+) (corev1alpha1.ProviderSecretOwnership, error) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	var ownership corev1alpha1.ProviderSecretOwnership
+	rotated := false
+	op, err := controllerutil.CreateOrUpdate(ctx, k8sClient, secret, func() error {
+		var err error
+		if instance != nil {
+			ownership, err = setProviderSecretOwnership(secret, instance)
+			if err != nil {
+				return err
+			}
+		}
+		LabelSecretPurpose(secret, purpose, connection)
+		rotated = secret.ResourceVersion != "" && !reflect.DeepEqual(secret.Data, data)
+		SetSecretData(secret, data)
+		for k, v := range annotations {
+			metav1.SetMetaDataAnnotation(&secret.ObjectMeta, k, v)
+		}
+		return nil
+	})
+	if err != nil {
+		var ownedErr *SecretOwnedByOtherError
+		if stderrors.As(err, &ownedErr) && instance != nil {
+			recordProviderSecretOwnership(instance, name, namespace, corev1alpha1.ProviderSecretConflict)
+			return corev1alpha1.ProviderSecretConflict, ownedErr
+		}
+		return "", err
+	}
+	if rotated && !plan.IsPlanning(ctx) {
+		eventing.Default().Emit(eventing.NewSecretRotated(namespace, name))
+	}
+	if instance != nil {
+		recordProviderSecretOwnership(instance, name, namespace, ownership)
+		if op == controllerutil.OperationResultCreated {
+			recordEvent(ctx, instance, corev1.EventTypeNormal, EventReasonProviderSecretCreated, "WriteProviderSecret", "Created provider secret %s/%s", namespace, name)
+		} else if rotated {
+			recordEvent(ctx, instance, corev1.EventTypeNormal, EventReasonProviderSecretRotated, "WriteProviderSecret", "Rotated provider secret %s/%s", namespace, name)
+		}
+	}
+	return ownership, nil
+}
+
+// recordProviderSecretOwnership adds or updates the secret in the instance status.
+func recordProviderSecretOwnership(instance *corev1alpha1.PlatformMesh, name, namespace string, ownership corev1alpha1.ProviderSecretOwnership) {
+	for i, s := range instance.Status.ProviderSecrets {
+		if s.Name == name && s.Namespace == namespace {
+			instance.Status.ProviderSecrets[i].Ownership = ownership
+			return
+		}
+	}
+	instance.Status.ProviderSecrets = append(instance.Status.ProviderSecrets, corev1alpha1.ProviderSecretStatus{
+		Name:      name,
+		Namespace: namespace,
+		Ownership: ownership,
+	})
+}
+
+// deleteLabeledProviderSecrets removes the provider secrets of instance outside
+// of its namespace. Secrets in the instance namespace are garbage collected
+// through their owner reference.
+func deleteLabeledProviderSecrets(ctx context.Context, k8sClient client.Client, instance *corev1alpha1.PlatformMesh) error {
+	secrets := &corev1.SecretList{}
+	if err := k8sClient.List(ctx, secrets, client.MatchingLabels{
+		InstanceNameLabel:      instance.Name,
+		InstanceNamespaceLabel: instance.Namespace,
+	}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Namespace == instance.Namespace {
+			continue
+		}
+		if err := client.IgnoreNotFound(k8sClient.Delete(ctx, secret)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+type ProviderSecretOwnershipTestSuite struct {
+	suite.Suite
+	instance *corev1alpha1.PlatformMesh
+}
+
+func TestProviderSecretOwnershipTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderSecretOwnershipTestSuite))
+}
+
+func (s *ProviderSecretOwnershipTestSuite) SetupTest() {
+	s.instance = &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system", UID: "pm-uid"},
+	}
+}
+
+func (s *ProviderSecretOwnershipTestSuite) getSecret(cl client.Client, name, namespace string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	s.Require().NoError(cl.Get(context.Background(), types.NamespacedName{Name: name, Namespace: namespace}, secret))
+	return secret
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestWriteProviderSecret_Owned() {
+	cl := fake.NewClientBuilder().Build()
+
+	ownership, err := writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("data")}, nil)
+
+	s.Require().NoError(err)
+	s.Equal(corev1alpha1.ProviderSecretOwned, ownership)
+	secret := s.getSecret(cl, "kubeconfig", "platform-mesh-system")
+	s.True(metav1.IsControlledBy(secret, s.instance))
+	s.Equal("platform-mesh", secret.Labels[InstanceNameLabel])
+	s.Equal([]corev1alpha1.ProviderSecretStatus{
+		{Name: "kubeconfig", Namespace: "platform-mesh-system", Ownership: corev1alpha1.ProviderSecretOwned},
+	}, s.instance.Status.ProviderSecrets)
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestWriteProviderSecret_AdoptsUnowned() {
+	existing := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig", Namespace: "platform-mesh-system"}}
+	cl := fake.NewClientBuilder().WithObjects(existing).Build()
+
+	ownership, err := writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("data")}, nil)
+
+	s.Require().NoError(err)
+	s.Equal(corev1alpha1.ProviderSecretAdopted, ownership)
+	s.True(metav1.IsControlledBy(s.getSecret(cl, "kubeconfig", "platform-mesh-system"), s.instance))
+
+	ownership, err = writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("data")}, nil)
+	s.Require().NoError(err)
+	s.Equal(corev1alpha1.ProviderSecretOwned, ownership)
+	s.Len(s.instance.Status.ProviderSecrets, 1)
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestWriteProviderSecret_UpdatesChangedContent() {
+	cl := fake.NewClientBuilder().Build()
+	write := func(server string) {
+		_, err := writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte(server)}, nil)
+		s.Require().NoError(err)
+	}
+
+	write("https://kcp.example/services/apiexport/old")
+	unchanged := s.getSecret(cl, "kubeconfig", "platform-mesh-system").ResourceVersion
+	write("https://kcp.example/services/apiexport/old")
+	s.Equal(unchanged, s.getSecret(cl, "kubeconfig", "platform-mesh-system").ResourceVersion)
+
+	// A changed endpoint URL updates the existing secret.
+	write("https://kcp.example/services/apiexport/new")
+	secret := s.getSecret(cl, "kubeconfig", "platform-mesh-system")
+	s.NotEqual(unchanged, secret.ResourceVersion)
+	s.Equal([]byte("https://kcp.example/services/apiexport/new"), secret.Data["kubeconfig"])
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestWriteProviderSecret_CrossNamespaceLabeled() {
+	cl := fake.NewClientBuilder().Build()
+
+	ownership, err := writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "other", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("data")}, nil)
+
+	s.Require().NoError(err)
+	s.Equal(corev1alpha1.ProviderSecretLabeled, ownership)
+	secret := s.getSecret(cl, "kubeconfig", "other")
+	s.Empty(secret.OwnerReferences)
+	s.Equal("platform-mesh-system", secret.Labels[InstanceNamespaceLabel])
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestWriteProviderSecret_ControlledByOther() {
+	existing := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      "kubeconfig",
+		Namespace: "platform-mesh-system",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid", Controller: ptr.To(true),
+		}},
+	}}
+	cl := fake.NewClientBuilder().WithObjects(existing).Build()
+
+	ownership, err := writeProviderSecret(context.Background(), cl, s.instance, "kubeconfig", "platform-mesh-system", SecretPurposeProvider, "root:platform-mesh-system", map[string][]byte{"kubeconfig": []byte("data")}, nil)
+
+	s.True(isSecretOwnedByOther(err))
+	s.Equal(corev1alpha1.ProviderSecretConflict, ownership)
+	s.Empty(s.getSecret(cl, "kubeconfig", "platform-mesh-system").Data)
+	s.Equal(corev1alpha1.ProviderSecretConflict, s.instance.Status.ProviderSecrets[0].Ownership)
+}
+
+func (s *ProviderSecretOwnershipTestSuite) TestDeleteLabeledProviderSecrets() {
+	labels := map[string]string{InstanceNameLabel: "platform-mesh", InstanceNamespaceLabel: "platform-mesh-system"}
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "remote", Namespace: "other", Labels: labels}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "platform-mesh-system", Labels: labels}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "other"}},
+	).Build()
+
+	s.Require().NoError(deleteLabeledProviderSecrets(context.Background(), cl, s.instance))
+
+	err := cl.Get(context.Background(), types.NamespacedName{Name: "remote", Namespace: "other"}, &corev1.Secret{})
+	s.True(kerrors.IsNotFound(err))
+	s.getSecret(cl, "local", "platform-mesh-system")
+	s.getSecret(cl, "unrelated", "other")
+}
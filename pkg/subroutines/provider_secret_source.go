@@ -0,0 +1,33 @@
+package subroutines
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"k8s.io/client-go/rest"
+)
+
+// SourceKubeconfigHashAnnotation records on a provider secret the hash of the
+// kcp admin kubeconfig it was derived from. When the admin kubeconfig is
+// rotated the hash changes and the secret is regenerated, including the scoped
+// token that would otherwise be reused until its renewal.
+const SourceKubeconfigHashAnnotation = "core.platform-mesh.io/source-kubeconfig-hash"
+
+// sourceKubeconfigHash returns the hash of the given parts of a source
+// kubeconfig. Each part is length-prefixed, so moving bytes between parts
+// changes the hash.
+func sourceKubeconfigHash(parts ...[]byte) string {
+	sum := sha256.New()
+	for _, part := range parts {
+		_ = binary.Write(sum, binary.BigEndian, uint64(len(part)))
+		sum.Write(part)
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// restConfigSourceHash returns the hash of the server, CA and credentials of
+// cfg, the admin kubeconfig scoped provider secrets are derived from.
+func restConfigSourceHash(cfg *rest.Config) string {
+	return sourceKubeconfigHash([]byte(cfg.Host), cfg.CAData, cfg.CertData, cfg.KeyData, []byte(cfg.BearerToken))
+}
@@ -0,0 +1,69 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestSourceKubeconfigHash(t *testing.T) {
+	hash := sourceKubeconfigHash([]byte("ab"), []byte("c"))
+	assert.Len(t, hash, 16)
+	assert.Equal(t, hash, sourceKubeconfigHash([]byte("ab"), []byte("c")))
+	assert.NotEqual(t, hash, sourceKubeconfigHash([]byte("a"), []byte("bc")))
+
+	cfg := &rest.Config{Host: "https://kcp.example", BearerToken: "token"}
+	cfg.CAData = []byte("ca")
+	hash = restConfigSourceHash(cfg)
+	rotated := rest.CopyConfig(cfg)
+	rotated.CAData = []byte("new-ca")
+	assert.NotEqual(t, hash, restConfigSourceHash(rotated))
+	rotated = rest.CopyConfig(cfg)
+	rotated.BearerToken = "new-token"
+	assert.NotEqual(t, hash, restConfigSourceHash(rotated))
+	assert.Equal(t, hash, restConfigSourceHash(rest.CopyConfig(cfg)))
+}
+
+func TestProviderSecretFollowsAdminKubeconfig(t *testing.T) {
+	ctx := context.Background()
+	adminKubeconfig := func(token string) []byte {
+		data, err := clientcmd.Write(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"kcp": {Server: "https://kcp.example"}},
+			AuthInfos:      map[string]*clientcmdapi.AuthInfo{"admin": {Token: token}},
+			Contexts:       map[string]*clientcmdapi.Context{"kcp": {Cluster: "kcp", AuthInfo: "admin"}},
+			CurrentContext: "kcp",
+		})
+		require.NoError(t, err)
+		return data
+	}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system", UID: "uid"}}
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	write := func(admin []byte) *corev1.Secret {
+		_, err := writeProviderSecretFromKcpOperatorAdminKubeconfig(ctx, cl, inst, admin, "https://front-proxy:6443/clusters/root",
+			[]byte("ca"), "provider", "platform-mesh-system", "root")
+		require.NoError(t, err)
+		secret := &corev1.Secret{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "provider", Namespace: "platform-mesh-system"}, secret))
+		return secret
+	}
+
+	first := write(adminKubeconfig("old"))
+	hash := first.Annotations[SourceKubeconfigHashAnnotation]
+	assert.Equal(t, sourceKubeconfigHash(adminKubeconfig("old"), []byte("ca")), hash)
+
+	rotated := write(adminKubeconfig("new"))
+	assert.NotEqual(t, hash, rotated.Annotations[SourceKubeconfigHashAnnotation])
+	assert.NotEqual(t, first.Data["kubeconfig"], rotated.Data["kubeconfig"])
+}
@@ -36,13 +36,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	providersv1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/ocm"
+	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
 const (
 	DeploySubroutineName      = "DeploySubroutine"
 	deploySubroutineFinalizer = "providers.platform-mesh.io/runtime-deployments"
-	deployRequeueDuration     = 10 * time.Second
 )
 
 var (
@@ -137,23 +138,25 @@ func chartResourceName(chart string) string {
 type DeploySubroutine struct {
 	client  client.Client
 	limiter workqueue.TypedRateLimiter[*providersv1alpha1.ManagedProvider]
+	requeue *pmsubs.RequeueBackoff
 }
 
-func NewDeploySubroutine(cl client.Client) (*DeploySubroutine, error) {
+func NewDeploySubroutine(cl client.Client, policy config.RequeuePolicy) (*DeploySubroutine, error) {
 	rl, err := ratelimiter.NewStaticThenExponentialRateLimiter[*providersv1alpha1.ManagedProvider](
 		ratelimiter.NewConfig())
 	if err != nil {
 		return nil, fmt.Errorf("creating RateLimiter: %v", err)
 	}
-	return &DeploySubroutine{client: cl, limiter: rl}, nil
+	return &DeploySubroutine{client: cl, limiter: rl, requeue: pmsubs.NewRequeueBackoff(DeploySubroutineName, policy)}, nil
 }
 
 func (r *DeploySubroutine) GetName() string {
 	return DeploySubroutineName
 }
 
-func (r *DeploySubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *DeploySubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	inst := obj.(*providersv1alpha1.ManagedProvider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	result, err := r.doRuntimeDeployments(ctx, inst)
 	if err != nil {
@@ -170,13 +173,14 @@ func (r *DeploySubroutine) Process(ctx context.Context, obj client.Object) (subr
 
 func (r *DeploySubroutine) doRuntimeDeployments(ctx context.Context, managedProvider *providersv1alpha1.ManagedProvider) (subroutines.Result, error) {
 	runtimeKubeconfigSecretName := managedProvider.Spec.RuntimeKubeconfigSecretName
+	requeue := r.requeue.Next(managedProvider)
 
 	for _, component := range managedProvider.Spec.RuntimeDeployments {
 		switch {
 		case component.Flux != nil:
 			flux := component.Flux
 			name := chartResourceName(flux.Chart)
-			result, err := r.deployFluxComponent(ctx, managedProvider.Namespace, name, flux, runtimeKubeconfigSecretName)
+			result, err := r.deployFluxComponent(ctx, managedProvider.Namespace, name, flux, runtimeKubeconfigSecretName, requeue)
 			if err != nil {
 				return subroutines.OK(), err
 			}
@@ -186,7 +190,7 @@ func (r *DeploySubroutine) doRuntimeDeployments(ctx context.Context, managedProv
 		case component.OCM != nil:
 			ocm := component.OCM
 			name := ocmDeploymentName(ocm)
-			result, err := r.deployOCMComponent(ctx, managedProvider.Namespace, name, ocm, runtimeKubeconfigSecretName)
+			result, err := r.deployOCMComponent(ctx, managedProvider.Namespace, name, ocm, runtimeKubeconfigSecretName, requeue)
 			if err != nil {
 				return subroutines.OK(), err
 			}
@@ -201,29 +205,29 @@ func (r *DeploySubroutine) doRuntimeDeployments(ctx context.Context, managedProv
 
 // deployFluxComponent dispatches to the OCI or classic Helm-repository deploy path
 // based on the component source type (defaulting to OCI).
-func (r *DeploySubroutine) deployFluxComponent(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string) (subroutines.Result, error) {
+func (r *DeploySubroutine) deployFluxComponent(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string, requeue time.Duration) (subroutines.Result, error) {
 	if flux.Type == providersv1alpha1.FluxSourceTypeHelm {
-		return r.deployFluxHelmRepo(ctx, namespace, name, flux, runtimeKubeconfigSecretName)
+		return r.deployFluxHelmRepo(ctx, namespace, name, flux, runtimeKubeconfigSecretName, requeue)
 	}
-	return r.deployFluxOCI(ctx, namespace, name, flux, runtimeKubeconfigSecretName)
+	return r.deployFluxOCI(ctx, namespace, name, flux, runtimeKubeconfigSecretName, requeue)
 }
 
 // deployFluxOCI deploys a chart packaged as an OCI artifact via a Flux OCIRepository
 // referenced by a HelmRelease through chartRef.
-func (r *DeploySubroutine) deployFluxOCI(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string) (subroutines.Result, error) {
+func (r *DeploySubroutine) deployFluxOCI(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string, requeue time.Duration) (subroutines.Result, error) {
 	ociURL := fmt.Sprintf("oci://%s/%s", flux.Registry, flux.Chart)
 	values, err := parseFluxValues(flux)
 	if err != nil {
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to unmarshal values for %s", name)
 	}
-	return r.reconcileResolvedOCIChart(ctx, namespace, name, ociURL, flux.Version, flux.Insecure, values, runtimeKubeconfigSecretName)
+	return r.reconcileResolvedOCIChart(ctx, namespace, name, ociURL, flux.Version, flux.Insecure, values, runtimeKubeconfigSecretName, requeue)
 }
 
 // reconcileResolvedOCIChart creates/updates a Flux OCIRepository (pointing at the given
 // resolved chart OCI url + tag) and a HelmRelease referencing it via chartRef, then
 // reports readiness. It is shared by the flux OCI path and the ocm path (which first
 // resolves the OCI url + version from an OCM Resource status).
-func (r *DeploySubroutine) reconcileResolvedOCIChart(ctx context.Context, namespace, name, ociURL, version string, insecure bool, values map[string]interface{}, runtimeKubeconfigSecretName string) (subroutines.Result, error) {
+func (r *DeploySubroutine) reconcileResolvedOCIChart(ctx context.Context, namespace, name, ociURL, version string, insecure bool, values map[string]interface{}, runtimeKubeconfigSecretName string, requeue time.Duration) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", DeploySubroutineName).ChildLogger("component", name)
 
 	ociRepo := &unstructured.Unstructured{}
@@ -294,7 +298,7 @@ func (r *DeploySubroutine) reconcileResolvedOCIChart(ctx context.Context, namesp
 	// Ready=True on the HelmRelease is stale with respect to the new spec.
 	if ociResult != controllerutil.OperationResultNone || hrResult != controllerutil.OperationResultNone {
 		log.Info().Str("ociResult", string(ociResult)).Str("hrResult", string(hrResult)).Msg("Resource modified, requeuing before checking conditions")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("waiting for %s to be reconciled", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("waiting for %s to be reconciled", name)), nil
 	}
 
 	// Verify Flux has fully processed the current OCIRepository spec generation before
@@ -304,7 +308,7 @@ func (r *DeploySubroutine) reconcileResolvedOCIChart(ctx context.Context, namesp
 	ociObservedGeneration, _, _ := unstructured.NestedInt64(ociRepo.Object, "status", "observedGeneration")
 	if ociGeneration != ociObservedGeneration {
 		log.Info().Int64("generation", ociGeneration).Int64("observedGeneration", ociObservedGeneration).Msg("OCIRepository not yet reconciled, requeuing")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("waiting for OCIRepository %s/%s to be reconciled", namespace, name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("waiting for OCIRepository %s/%s to be reconciled", namespace, name)), nil
 	}
 
 	ready, err := r.helmReleaseReady(ctx, namespace, name)
@@ -313,7 +317,7 @@ func (r *DeploySubroutine) reconcileResolvedOCIChart(ctx context.Context, namesp
 	}
 	if !ready {
 		log.Info().Msg("HelmRelease not ready yet, requeuing")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("HelmRelease %s not ready", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("HelmRelease %s not ready", name)), nil
 	}
 
 	return subroutines.OK(), nil
@@ -366,7 +370,7 @@ func ocmConfigRepositoryRef(name, namespace string) []interface{} {
 // writes the resolved chart artifact (imageReference + version) into the Resource status,
 // which is then deployed via a Flux OCIRepository + HelmRelease. All three OCM objects are
 // named after the deployment name.
-func (r *DeploySubroutine) deployOCMComponent(ctx context.Context, namespace, name string, ocmSpec *providersv1alpha1.OCMComponentSpec, runtimeKubeconfigSecretName string) (subroutines.Result, error) {
+func (r *DeploySubroutine) deployOCMComponent(ctx context.Context, namespace, name string, ocmSpec *providersv1alpha1.OCMComponentSpec, runtimeKubeconfigSecretName string, requeue time.Duration) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", DeploySubroutineName).ChildLogger("component", name)
 
 	resourceName := ocmSpec.ResourceName
@@ -463,7 +467,7 @@ func (r *DeploySubroutine) deployOCMComponent(ctx context.Context, namespace, na
 		log.Info().
 			Str("repoResult", string(repoResult)).Str("compResult", string(compResult)).Str("resResult", string(resResult)).
 			Msg("OCM objects modified, requeuing for resolution")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("waiting for OCM Resource %s to be resolved", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("waiting for OCM Resource %s to be resolved", name)), nil
 	}
 
 	// Read the resolved artifact from the Resource status (populated by the ocm-controller).
@@ -474,7 +478,7 @@ func (r *DeploySubroutine) deployOCMComponent(ctx context.Context, namespace, na
 	version, _, _ := unstructured.NestedString(resource.Object, "status", "resource", "version")
 	if imageRef == "" || version == "" {
 		log.Info().Msg("OCM Resource not yet resolved, requeuing")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("waiting for OCM Resource %s status", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("waiting for OCM Resource %s status", name)), nil
 	}
 
 	ociURL, err := ocmResolvedOCIURL(imageRef, version)
@@ -487,12 +491,12 @@ func (r *DeploySubroutine) deployOCMComponent(ctx context.Context, namespace, na
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to unmarshal values for %s", name)
 	}
 
-	return r.reconcileResolvedOCIChart(ctx, namespace, name, ociURL, version, ocmSpec.Insecure, values, runtimeKubeconfigSecretName)
+	return r.reconcileResolvedOCIChart(ctx, namespace, name, ociURL, version, ocmSpec.Insecure, values, runtimeKubeconfigSecretName, requeue)
 }
 
 // deployFluxHelmRepo deploys a chart from a classic HTTP(S) Helm repository via a
 // Flux HelmRepository referenced by a HelmRelease through chart.spec.sourceRef.
-func (r *DeploySubroutine) deployFluxHelmRepo(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string) (subroutines.Result, error) {
+func (r *DeploySubroutine) deployFluxHelmRepo(ctx context.Context, namespace, name string, flux *providersv1alpha1.FluxComponentSpec, runtimeKubeconfigSecretName string, requeue time.Duration) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", DeploySubroutineName).ChildLogger("component", name)
 
 	helmRepo := &unstructured.Unstructured{}
@@ -562,7 +566,7 @@ func (r *DeploySubroutine) deployFluxHelmRepo(ctx context.Context, namespace, na
 	// Ready=True on the HelmRelease is stale with respect to the new spec.
 	if repoResult != controllerutil.OperationResultNone || hrResult != controllerutil.OperationResultNone {
 		log.Info().Str("repoResult", string(repoResult)).Str("hrResult", string(hrResult)).Msg("Resource modified, requeuing before checking conditions")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("waiting for %s to be reconciled", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("waiting for %s to be reconciled", name)), nil
 	}
 
 	ready, err := r.helmReleaseReady(ctx, namespace, name)
@@ -571,7 +575,7 @@ func (r *DeploySubroutine) deployFluxHelmRepo(ctx context.Context, namespace, na
 	}
 	if !ready {
 		log.Info().Msg("HelmRelease not ready yet, requeuing")
-		return subroutines.StopWithRequeue(deployRequeueDuration, fmt.Sprintf("HelmRelease %s not ready", name)), nil
+		return subroutines.StopWithRequeue(requeue, fmt.Sprintf("HelmRelease %s not ready", name)), nil
 	}
 
 	return subroutines.OK(), nil
@@ -36,6 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	providersv1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
 )
 
@@ -61,7 +62,7 @@ func (s *DeployTestSuite) SetupTest() {
 	s.clientMock.EXPECT().Scheme().Return(runtime.NewScheme()).Maybe()
 
 	var err error
-	s.testObj, err = NewDeploySubroutine(s.clientMock)
+	s.testObj, err = NewDeploySubroutine(s.clientMock, config.DefaultRequeuePolicy())
 	s.Require().NoError(err)
 }
 
@@ -38,9 +38,8 @@ import (
 )
 
 const (
-	KubeconfigCopySubroutineName  = "KubeconfigCopySubroutine"
-	kubeconfigCopyFinalizer       = "providers.platform-mesh.io/kubeconfig-secret"
-	kubeconfigCopyRequeueDuration = 10 * time.Second
+	KubeconfigCopySubroutineName = "KubeconfigCopySubroutine"
+	kubeconfigCopyFinalizer      = "providers.platform-mesh.io/kubeconfig-secret"
 )
 
 // KubeconfigCopySubroutine copies the kubeconfig Secret produced by the
@@ -51,6 +50,7 @@ type KubeconfigCopySubroutine struct {
 	kcpHelper   pmsubs.KcpHelper
 	operatorCfg *config.OperatorConfig
 	kcpUrl      string
+	requeue     *pmsubs.RequeueBackoff
 }
 
 func NewKubeconfigCopySubroutine(cl client.Client, kcpHelper pmsubs.KcpHelper, operatorCfg *config.OperatorConfig, kcpUrl string) *KubeconfigCopySubroutine {
@@ -59,6 +59,7 @@ func NewKubeconfigCopySubroutine(cl client.Client, kcpHelper pmsubs.KcpHelper, o
 		kcpHelper:   kcpHelper,
 		operatorCfg: operatorCfg,
 		kcpUrl:      kcpUrl,
+		requeue:     pmsubs.NewRequeueBackoff(KubeconfigCopySubroutineName, operatorCfg.Subroutines.ManagedProvider.Requeue),
 	}
 }
 
@@ -103,9 +104,10 @@ func (r *KubeconfigCopySubroutine) newRuntimeClusterClient(ctx context.Context,
 	return cl, nil
 }
 
-func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	inst := obj.(*providersv1alpha1.ManagedProvider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	wsPath := providerRefPath(inst)
 	provName := providerRefName(inst)
@@ -134,7 +136,7 @@ func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Objec
 	if provider.Status.ProviderKubeconfigSecretRef == nil {
 		log.Info().Str("workspace", wsPath).Str("provider", provider.Name).Msg("Provider providerKubeconfigSecretRef not set yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseCopyingKubeconfig
-		return subroutines.StopWithRequeue(kubeconfigCopyRequeueDuration, "waiting for Provider to set providerKubeconfigSecretRef"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "waiting for Provider to set providerKubeconfigSecretRef"), nil
 	}
 
 	// Validate that Provider's providerKubeconfigSecret must match ManagedProvider's.
@@ -142,7 +144,7 @@ func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Objec
 	if provider.Spec.ProviderKubeconfigSecret == nil || *provider.Spec.ProviderKubeconfigSecret != managedProviderKubeconfigSecretSpec {
 		log.Info().Str("workspace", wsPath).Str("provider", provider.Name).Msg("Provider providerKubeconfigSecretRef not set yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseCopyingKubeconfigFailed
-		return subroutines.StopWithRequeue(kubeconfigCopyRequeueDuration, fmt.Sprintf("providerKubeconfigSecretRef set on Provider %s:%s differs from the one set on ManagedProvider %s/%s", wsPath, provName, inst.Namespace, inst.Name)), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), fmt.Sprintf("providerKubeconfigSecretRef set on Provider %s:%s differs from the one set on ManagedProvider %s/%s", wsPath, provName, inst.Namespace, inst.Name)), nil
 	}
 
 	// Fetch the kubeconfig Secret from the provider workspace.
@@ -161,7 +163,7 @@ func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Objec
 
 	if len(kcpKubeconfig) == 0 {
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseCopyingKubeconfig
-		return subroutines.StopWithRequeue(kubeconfigCopyRequeueDuration, "waiting for Provider to set kubeconfig in secret"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "waiting for Provider to set kubeconfig in secret"), nil
 	}
 
 	// Ensure namespace for the Secret we're about to create exists.
@@ -178,9 +180,10 @@ func (r *KubeconfigCopySubroutine) Process(ctx context.Context, obj client.Objec
 		},
 	}
 	if _, err = controllerruntime.CreateOrUpdate(ctx, runtimeClusterClient, &copySecret, func() error {
-		copySecret.Data = map[string][]byte{
+		pmsubs.LabelSecretPurpose(&copySecret, pmsubs.SecretPurposeKubeconfigCopy, wsPath)
+		pmsubs.SetSecretData(&copySecret, map[string][]byte{
 			managedProviderKubeconfigSecretSpec.Key: kcpKubeconfig,
-		}
+		})
 		return nil
 	}); err != nil {
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to copy kubeconfig Secret %s/%s from workspace %s", copySecret.Namespace, copySecret.Name, wsPath)
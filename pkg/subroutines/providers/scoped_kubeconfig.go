@@ -72,16 +72,18 @@ type ScopedKubeconfigSubroutine struct {
 	kcpHelper   pmsubs.KcpHelper
 	kcpCfg      config.KCPConfig
 	kcpUrl      string
+	requeue     *pmsubs.RequeueBackoff
 
 	getClusterClientFromContext func(context.Context) (client.Client, error)
 }
 
-func NewScopedKubeconfigSubroutine(localClient client.Client, kcpHelper pmsubs.KcpHelper, kcpCfg config.KCPConfig, kcpUrl string, getClusterClientFromContext func(context.Context) (client.Client, error)) *ScopedKubeconfigSubroutine {
+func NewScopedKubeconfigSubroutine(localClient client.Client, kcpHelper pmsubs.KcpHelper, kcpCfg config.KCPConfig, kcpUrl string, policy config.RequeuePolicy, getClusterClientFromContext func(context.Context) (client.Client, error)) *ScopedKubeconfigSubroutine {
 	return &ScopedKubeconfigSubroutine{
 		localClient:                 localClient,
 		kcpHelper:                   kcpHelper,
 		kcpCfg:                      kcpCfg,
 		kcpUrl:                      kcpUrl,
+		requeue:                     pmsubs.NewRequeueBackoff(ScopedKubeconfigSubroutineName, policy),
 		getClusterClientFromContext: getClusterClientFromContext,
 	}
 }
@@ -90,9 +92,10 @@ func (r *ScopedKubeconfigSubroutine) GetName() string {
 	return ScopedKubeconfigSubroutineName
 }
 
-func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	inst := obj.(*providersv1alpha1.Provider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	saName := providerServiceAccountName(inst)
 	tokenSecretName := providerServiceAccountTokenSecretName(inst)
@@ -126,7 +129,7 @@ func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Obj
 		if kerrors.IsNotFound(err) {
 			log.Info().Str("workspace", wsPath).Msg("Provider workspace not found yet, requeuing")
 			inst.Status.Phase = providersv1alpha1.ProviderPhaseProvisioningWorkspace
-			return subroutines.StopWithRequeue(waitProviderRequeueDuration, "Waiting for provider workspace"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), "Waiting for provider workspace"), nil
 		}
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to get provider workspace %s", wsName)
 	}
@@ -134,7 +137,7 @@ func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Obj
 	if ws.Status.Phase != "Ready" {
 		log.Info().Str("workspace", wsPath).Str("phase", string(ws.Status.Phase)).Msg("Provider workspace not Ready yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ProviderPhaseProvisioningWorkspace
-		return subroutines.StopWithRequeue(waitProviderRequeueDuration, "Waiting for provider workspace to become Ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "Waiting for provider workspace to become Ready"), nil
 	}
 
 	// Get a client scoped to the provider workspace itself.
@@ -174,13 +177,14 @@ func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Obj
 			tokenSecret.Annotations = map[string]string{}
 		}
 		tokenSecret.Annotations[corev1.ServiceAccountNameKey] = saName
+		pmsubs.LabelSecretPurpose(tokenSecret, pmsubs.SecretPurposeScoped, "")
 		return nil
 	}); err != nil {
 		return subroutines.OK(), gcerrors.Wrap(err, "create or update token Secret %s", tokenSecretName)
 	}
 	if len(tokenSecret.Data["token"]) == 0 || len(tokenSecret.Data["ca.crt"]) == 0 {
 		log.Info().Str("secret", tokenSecretName).Msg("SA token not yet populated, requeuing")
-		return subroutines.StopWithRequeue(waitProviderRequeueDuration, "waiting for SA token to be populated"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "waiting for SA token to be populated"), nil
 	}
 
 	token := string(tokenSecret.Data["token"])
@@ -193,7 +197,7 @@ func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Obj
 	}
 	hostURL += fmt.Sprintf("/clusters/%s", ws.Spec.Cluster)
 
-	kubeconfigBytes, err := clientcmd.Write(buildProviderScopedKubeconfigForToken(hostURL, token, caData))
+	kubeconfigBytes, err := clientcmd.Write(buildProviderScopedKubeconfigForToken(pmsubs.KubeconfigEntryName(inst.Name, "", wsPath), hostURL, token, caData))
 	if err != nil {
 		return subroutines.OK(), gcerrors.Wrap(err, "serialize kubeconfig")
 	}
@@ -219,7 +223,8 @@ func (r *ScopedKubeconfigSubroutine) Process(ctx context.Context, obj client.Obj
 	// Write kubeconfig Secret into user's ws.
 	kubeconfigSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kubeconfigSecretName, Namespace: kubeconfigSecretNamespace}}
 	if _, err := controllerutil.CreateOrUpdate(ctx, userWsClient, kubeconfigSecret, func() error {
-		kubeconfigSecret.Data = map[string][]byte{kubeconfigSecretKey: kubeconfigBytes}
+		pmsubs.LabelSecretPurpose(kubeconfigSecret, pmsubs.SecretPurposeScoped, "")
+		pmsubs.SetSecretData(kubeconfigSecret, map[string][]byte{kubeconfigSecretKey: kubeconfigBytes})
 		return nil
 	}); err != nil {
 		return subroutines.OK(), gcerrors.Wrap(err, "write kubeconfig Secret %s", kubeconfigSecretName)
@@ -294,11 +299,11 @@ func (r *ScopedKubeconfigSubroutine) Finalizers(_ client.Object) []string {
 	return []string{scopedKubeconfigFinalizer}
 }
 
-func buildProviderScopedKubeconfigForToken(hostURL, token string, caData []byte) clientcmdapi.Config {
+func buildProviderScopedKubeconfigForToken(name, hostURL, token string, caData []byte) clientcmdapi.Config {
 	return clientcmdapi.Config{
-		Clusters:       map[string]*clientcmdapi.Cluster{"default-cluster": {Server: hostURL, CertificateAuthorityData: caData}},
-		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"default-auth": {Token: token}},
-		Contexts:       map[string]*clientcmdapi.Context{"default-context": {Cluster: "default-cluster", AuthInfo: "default-auth"}},
-		CurrentContext: "default-context",
+		Clusters:       map[string]*clientcmdapi.Cluster{name: {Server: hostURL, CertificateAuthorityData: caData}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{name: {Token: token}},
+		Contexts:       map[string]*clientcmdapi.Context{name: {Cluster: name, AuthInfo: name}},
+		CurrentContext: name,
 	}
 }
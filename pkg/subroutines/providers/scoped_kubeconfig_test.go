@@ -32,12 +32,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 
 	providersv1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
 )
 
@@ -85,6 +87,7 @@ func (s *ScopedKubeconfigTestSuite) SetupTest() {
 		s.kcpHelperMock,
 		s.kcpCfg,
 		"https://kcp.api.example.com",
+		config.DefaultRequeuePolicy(),
 		func(_ context.Context) (client.Client, error) {
 			return s.clMock, nil
 		},
@@ -365,6 +368,11 @@ func (s *ScopedKubeconfigTestSuite) TestProcess_HostOverride() {
 	s.Require().NoError(err)
 	s.Require().NotNil(capturedSecret)
 	s.Assert().Contains(string(capturedSecret.Data["kubeconfig"]), "https://custom.kcp.host")
+	s.Assert().Equal(pmsubs.SecretPurposeScoped, capturedSecret.Labels[pmsubs.SecretPurposeLabel])
+	s.Assert().NotEmpty(capturedSecret.Annotations[pmsubs.SecretRotatedAtAnnotation])
+	kubeconfig, err := clientcmd.Load(capturedSecret.Data["kubeconfig"])
+	s.Require().NoError(err)
+	s.Assert().Equal("wildwest-root.providers.wildwest-abc123", kubeconfig.CurrentContext)
 }
 
 // --- Finalize ---
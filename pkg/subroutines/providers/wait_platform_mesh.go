@@ -18,7 +18,6 @@ package providers
 
 import (
 	"context"
-	"time"
 
 	gcerrors "github.com/platform-mesh/golang-commons/errors"
 	"github.com/platform-mesh/golang-commons/logger"
@@ -30,30 +29,31 @@ import (
 
 	providersv1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
-const (
-	WaitPlatformMeshSubroutineName  = "WaitPlatformMeshSubroutine"
-	waitPlatformMeshRequeueDuration = 10 * time.Second
-)
+const WaitPlatformMeshSubroutineName = "WaitPlatformMeshSubroutine"
 
 // WaitPlatformMeshSubroutine polls the PlatformMesh referenced by
 // spec.platformMeshRef until its Ready condition is True.
 type WaitPlatformMeshSubroutine struct {
-	client client.Client
+	client  client.Client
+	requeue *pmsubs.RequeueBackoff
 }
 
-func NewWaitPlatformMeshSubroutine(cl client.Client) *WaitPlatformMeshSubroutine {
-	return &WaitPlatformMeshSubroutine{client: cl}
+func NewWaitPlatformMeshSubroutine(cl client.Client, policy config.RequeuePolicy) *WaitPlatformMeshSubroutine {
+	return &WaitPlatformMeshSubroutine{client: cl, requeue: pmsubs.NewRequeueBackoff(WaitPlatformMeshSubroutineName, policy)}
 }
 
 func (r *WaitPlatformMeshSubroutine) GetName() string {
 	return WaitPlatformMeshSubroutineName
 }
 
-func (r *WaitPlatformMeshSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *WaitPlatformMeshSubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	inst := obj.(*providersv1alpha1.ManagedProvider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	if inst.Status.Phase == "" {
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhasePending
@@ -65,7 +65,7 @@ func (r *WaitPlatformMeshSubroutine) Process(ctx context.Context, obj client.Obj
 		if kerrors.IsNotFound(err) {
 			log.Info().Str("platformmesh", pmName).Msg("PlatformMesh not found yet, requeuing")
 			inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseWaitingForPlatformMesh
-			return subroutines.StopWithRequeue(waitPlatformMeshRequeueDuration, "PlatformMesh not found yet"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), "PlatformMesh not found yet"), nil
 		}
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to get PlatformMesh %s", pmName)
 	}
@@ -73,7 +73,7 @@ func (r *WaitPlatformMeshSubroutine) Process(ctx context.Context, obj client.Obj
 	if !apimeta.IsStatusConditionTrue(pm.Status.Conditions, "Ready") {
 		log.Info().Str("platformmesh", pmName).Msg("PlatformMesh not Ready yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseWaitingForPlatformMesh
-		return subroutines.StopWithRequeue(waitPlatformMeshRequeueDuration, "waiting for PlatformMesh to become Ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "waiting for PlatformMesh to become Ready"), nil
 	}
 
 	log.Info().Str("platformmesh", pmName).Msg("PlatformMesh is Ready")
@@ -33,6 +33,7 @@ import (
 
 	providersv1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/providers/v1alpha1"
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
 )
 
@@ -57,7 +58,7 @@ func (s *WaitPlatformMeshTestSuite) SetupTest() {
 	s.clientMock = new(mocks.Client)
 	s.clientMock.EXPECT().Scheme().Return(runtime.NewScheme()).Maybe()
 
-	s.testObj = NewWaitPlatformMeshSubroutine(s.clientMock)
+	s.testObj = NewWaitPlatformMeshSubroutine(s.clientMock, config.DefaultRequeuePolicy())
 }
 
 func (s *WaitPlatformMeshTestSuite) TearDownTest() {
@@ -18,7 +18,6 @@ package providers
 
 import (
 	"context"
-	"time"
 
 	gcerrors "github.com/platform-mesh/golang-commons/errors"
 	"github.com/platform-mesh/golang-commons/logger"
@@ -32,10 +31,7 @@ import (
 	pmsubs "github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
-const (
-	WaitProviderSubroutineName  = "WaitProviderSubroutine"
-	waitProviderRequeueDuration = 10 * time.Second
-)
+const WaitProviderSubroutineName = "WaitProviderSubroutine"
 
 // WaitProviderSubroutine polls the Provider resource in the kcp workspace
 // until status.phase == "Ready", indicating that SA, RBAC, and the kubeconfig
@@ -45,6 +41,7 @@ type WaitProviderSubroutine struct {
 	kcpHelper pmsubs.KcpHelper
 	cfg       *config.OperatorConfig
 	kcpUrl    string
+	requeue   *pmsubs.RequeueBackoff
 }
 
 func NewWaitProviderSubroutine(cl client.Client, kcpHelper pmsubs.KcpHelper, cfg *config.OperatorConfig, kcpUrl string) *WaitProviderSubroutine {
@@ -53,6 +50,7 @@ func NewWaitProviderSubroutine(cl client.Client, kcpHelper pmsubs.KcpHelper, cfg
 		kcpHelper: kcpHelper,
 		cfg:       cfg,
 		kcpUrl:    kcpUrl,
+		requeue:   pmsubs.NewRequeueBackoff(WaitProviderSubroutineName, cfg.Subroutines.ManagedProvider.Requeue),
 	}
 }
 
@@ -60,9 +58,10 @@ func (r *WaitProviderSubroutine) GetName() string {
 	return WaitProviderSubroutineName
 }
 
-func (r *WaitProviderSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *WaitProviderSubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	inst := obj.(*providersv1alpha1.ManagedProvider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	wsPath := providerRefPath(inst)
 	provName := providerRefName(inst)
@@ -82,7 +81,7 @@ func (r *WaitProviderSubroutine) Process(ctx context.Context, obj client.Object)
 		if kerrors.IsNotFound(err) {
 			log.Info().Str("workspace", wsPath).Str("provider", provName).Msg("Provider not found yet, requeuing")
 			inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseWaitingForProvider
-			return subroutines.StopWithRequeue(waitProviderRequeueDuration, "Provider not found yet"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(inst), "Provider not found yet"), nil
 		}
 		return subroutines.OK(), gcerrors.Wrap(err, "failed to get Provider %s from workspace %s", provName, wsPath)
 	}
@@ -90,7 +89,7 @@ func (r *WaitProviderSubroutine) Process(ctx context.Context, obj client.Object)
 	if provider.Status.Phase != providersv1alpha1.ProviderPhaseReady {
 		log.Info().Str("workspace", wsPath).Str("phase", provider.Status.Phase).Msg("Provider not Ready yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ManagedProviderPhaseWaitingForProvider
-		return subroutines.StopWithRequeue(waitProviderRequeueDuration, "waiting for Provider to become Ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "waiting for Provider to become Ready"), nil
 	}
 
 	log.Info().Str("workspace", wsPath).Msg("Provider is Ready")
@@ -61,11 +61,12 @@ type ProviderWorkspaceSubroutine struct {
 	kcpHelper   pmsubs.KcpHelper
 	kcpCfg      config.KCPConfig
 	kcpUrl      string
+	requeue     *pmsubs.RequeueBackoff
 
 	limiter workqueue.TypedRateLimiter[*kcptenancyv1alpha.Workspace]
 }
 
-func NewProviderWorkspaceSubroutine(localClient client.Client, kcpHelper pmsubs.KcpHelper, kcpCfg config.KCPConfig, kcpUrl string) (*ProviderWorkspaceSubroutine, error) {
+func NewProviderWorkspaceSubroutine(localClient client.Client, kcpHelper pmsubs.KcpHelper, kcpCfg config.KCPConfig, kcpUrl string, policy config.RequeuePolicy) (*ProviderWorkspaceSubroutine, error) {
 	rl, err := ratelimiter.NewStaticThenExponentialRateLimiter[*kcptenancyv1alpha.Workspace](
 		ratelimiter.NewConfig())
 	if err != nil {
@@ -76,6 +77,7 @@ func NewProviderWorkspaceSubroutine(localClient client.Client, kcpHelper pmsubs.
 		kcpHelper:   kcpHelper,
 		kcpCfg:      kcpCfg,
 		kcpUrl:      kcpUrl,
+		requeue:     pmsubs.NewRequeueBackoff(ProviderWorkspaceSubroutineName, policy),
 		limiter:     rl,
 	}, nil
 }
@@ -84,9 +86,10 @@ func (r *ProviderWorkspaceSubroutine) GetName() string {
 	return ProviderWorkspaceSubroutineName
 }
 
-func (r *ProviderWorkspaceSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+func (r *ProviderWorkspaceSubroutine) Process(ctx context.Context, obj client.Object) (res subroutines.Result, err error) {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 	inst := obj.(*providersv1alpha1.Provider)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 
 	if inst.Status.Phase == "" {
 		inst.Status.Phase = providersv1alpha1.ProviderPhasePending
@@ -130,11 +133,10 @@ func (r *ProviderWorkspaceSubroutine) Process(ctx context.Context, obj client.Ob
 	if ws.Status.Phase != "Ready" {
 		log.Info().Str("workspace", providerWsPath).Str("phase", string(ws.Status.Phase)).Msg("Workspace not Ready yet, requeuing")
 		inst.Status.Phase = providersv1alpha1.ProviderPhaseProvisioningWorkspace
-		return subroutines.StopWithRequeue(r.limiter.When(&ws), "Waiting for workspace to become Ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(inst), "Waiting for workspace to become Ready"), nil
 	}
 
 	log.Info().Str("workspace", providerWsPath).Msg("Ensured provider workspace")
-	r.limiter.Forget(&ws)
 	return subroutines.OK(), nil
 }
 
@@ -73,7 +73,7 @@ func (s *WorkspaceTestSuite) SetupTest() {
 	}
 
 	var err error
-	s.testObj, err = NewProviderWorkspaceSubroutine(s.clientMock, s.kcpHelperMock, s.kcpCfg, "https://kcp.api.example.com")
+	s.testObj, err = NewProviderWorkspaceSubroutine(s.clientMock, s.kcpHelperMock, s.kcpCfg, "https://kcp.api.example.com", config.DefaultRequeuePolicy())
 	s.Require().NoError(err)
 }
 
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	pmconfig "github.com/platform-mesh/golang-commons/config"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
@@ -56,15 +58,24 @@ func NewProviderSecretSubroutine(
 		kcpUrl:    kcpUrl,
 		kcpHelper: helper,
 		helm:      helm,
+		prober:    DiscoveryProber{},
 	}
 	return sub
 }
 
+// SetRequeuePolicy sets the backoff of requeues while provider connections
+// are waited for.
+func (r *ProvidersecretSubroutine) SetRequeuePolicy(policy config.RequeuePolicy) {
+	r.requeue = NewRequeueBackoff(ProvidersecretSubroutineName, policy)
+}
+
 type ProvidersecretSubroutine struct {
 	client    client.Client
 	kcpHelper KcpHelper
 	kcpUrl    string
 	helm      HelmGetter
+	prober    ConnectionProber
+	requeue   *RequeueBackoff
 }
 
 const (
@@ -76,7 +87,69 @@ const (
 func (r *ProvidersecretSubroutine) Finalize(
 	ctx context.Context, runtimeObj client.Object,
 ) (subroutines.Result, error) {
-	return subroutines.OK(), nil // TODO: Implement
+	instance, ok := runtimeObj.(*corev1alpha1.PlatformMesh)
+	if !ok {
+		return subroutines.OK(), nil
+	}
+	// Secrets in other namespaces cannot be garbage collected via owner references.
+	if err := deleteLabeledProviderSecrets(ctx, r.client, instance); err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to delete provider secrets of %s/%s", instance.Namespace, instance.Name)
+	}
+	if err := deleteScopedClientCertificates(ctx, r.client, instance); err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to delete client certificates of %s/%s", instance.Namespace, instance.Name)
+	}
+	if instance.Spec.TeardownPolicy != corev1alpha1.TeardownPolicyDelete {
+		return subroutines.OK(), nil
+	}
+
+	cfg, err := buildKubeconfig(ctx, r.client, instance, r.kcpUrl)
+	if err != nil {
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
+	}
+	for _, pc := range scopedProviderConnections(instance) {
+		kcpClient, err := r.kcpHelper.NewKcpClient(rest.CopyConfig(cfg), pc.Path)
+		if err != nil {
+			return subroutines.OK(), gcerrors.Wrap(err, "Failed to create kcp client for workspace %s", pc.Path)
+		}
+		if err := deleteScopedProviderRBAC(ctx, kcpClient, pc.Secret, scopedSANamespace(pc)); err != nil {
+			return subroutines.OK(), gcerrors.Wrap(err, "Failed to delete scoped RBAC of %s in workspace %s", pc.Secret, pc.Path)
+		}
+	}
+	return subroutines.OK(), nil
+}
+
+// scopedProviderConnections returns the scoped connections of instance, both
+// the configured ones and the ones recorded in its status, so that RBAC of
+// connections removed from the spec is found as well.
+func scopedProviderConnections(instance *corev1alpha1.PlatformMesh) []corev1alpha1.ProviderConnection {
+	seen := map[string]bool{}
+	var scoped []corev1alpha1.ProviderConnection
+	add := func(pc corev1alpha1.ProviderConnection) {
+		key := pc.Path + "/" + pc.Secret
+		if pc.Path == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		scoped = append(scoped, pc)
+	}
+	for _, pc := range providerConnections(instance) {
+		if !isAdminConnection(pc) {
+			add(pc)
+		}
+	}
+	for _, status := range instance.Status.ProviderConnections {
+		add(corev1alpha1.ProviderConnection{Path: status.Path, Secret: status.Secret, SANamespace: status.SANamespace})
+	}
+	return scoped
+}
+
+// isAdminConnection reports whether pc gets a kubeconfig derived from the kcp
+// admin credentials: mode admin, or adminAuth when no mode is set.
+func isAdminConnection(pc corev1alpha1.ProviderConnection) bool {
+	if pc.Mode != "" {
+		return pc.Mode == corev1alpha1.ProviderConnectionModeAdmin
+	}
+	return ptr.Deref(pc.AdminAuth, false)
 }
 
 func (r *ProvidersecretSubroutine) Process(
@@ -93,13 +166,28 @@ func (r *ProvidersecretSubroutine) Process(
 	}()
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
 
+	instance := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(instance, res, err) }()
+
 	scheme := r.client.Scheme()
 	if scheme == nil {
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "client scheme is nil"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), "client scheme is nil"), nil
 	}
 
-	instance := runtimeObj.(*corev1alpha1.PlatformMesh)
 	log := logger.LoadLoggerFromContext(ctx)
+	status := trackSteps(instance, ProviderSecretsReadyConditionType, "WaitingForRootShard")
+	defer func() { status.done(res, err) }()
+
+	owner, err := kcpOwner(ctx, r.client, &operatorCfg, instance)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list PlatformMesh instances")
+		return subroutines.OK(), err
+	}
+	if owner != instanceKey(instance) {
+		log.Warn().Str("owner", owner).Msg("kcp is set up by another PlatformMesh instance")
+		status.enter("KcpOwnedByOtherInstance")
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), fmt.Sprintf("kcp at %s is set up by PlatformMesh %s", getExternalKcpHost(instance, &operatorCfg), owner)), nil
+	}
 
 	// Wait for kcp release to be ready before continuing
 	rootShard := &unstructured.Unstructured{}
@@ -108,9 +196,10 @@ func (r *ProvidersecretSubroutine) Process(
 	err = r.client.Get(ctx, types.NamespacedName{Name: operatorCfg.KCP.RootShardName, Namespace: operatorCfg.KCP.Namespace}, rootShard)
 	if err != nil || !matchesConditionWithStatus(rootShard, "Available", "True") {
 		log.Info().Msg("RootShard is not ready..")
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "RootShard is not ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), "RootShard is not ready"), nil
 	}
 
+	status.enter("WaitingForFrontProxy")
 	frontProxy := &unstructured.Unstructured{}
 	frontProxy.SetGroupVersionKind(schema.GroupVersionKind{Group: "operator.kcp.io", Version: "v1alpha1", Kind: "FrontProxy"})
 	// Wait for root shard to be ready
@@ -118,10 +207,84 @@ func (r *ProvidersecretSubroutine) Process(
 
 	if err != nil || !matchesConditionWithStatus(frontProxy, "Available", "True") {
 		log.Info().Msg("FrontProxy is not ready..")
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, "FrontProxy is not ready"), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), "FrontProxy is not ready"), nil
+	}
+
+	providers := providerConnections(instance)
+
+	// Build kcp kubeonfig
+	status.enter("BuildingKubeconfig")
+	cfg, err := buildKubeconfig(ctx, r.client, instance, r.kcpUrl)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build kubeconfig")
+		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
+	}
+	instance.Status.ProviderSecrets = nil
+	instance.Status.ProviderConnections = nil
+	status.enter("WritingProviderSecrets")
+	ctx = withConnectionProbes(ctx, operatorCfg.Dev.IsEnabled())
+	secrets := map[string]bool{}
+	for _, pc := range providers {
+		secrets[pc.Secret] = true
+	}
+	notReady, err := r.handleProviderConnections(ctx, instance, providers, cfg, operatorCfg.Subroutines.ProviderSecret.MaxConcurrentConnections)
+	pruneConnectionStatus(instance, corev1alpha1.ConnectionTypeProvider, secrets)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to handle provider connections")
+		return subroutines.OK(), err
+	}
+	initializersNotReady, err := r.handleInitializerConnections(ctx, instance, cfg, operatorCfg.Subroutines.ProviderSecret.MaxConcurrentConnections)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to handle initializer connections")
+		return subroutines.OK(), err
+	}
+	notReady = append(notReady, initializersNotReady...)
+	if operatorCfg.Subroutines.ProviderSecret.MergedKubeconfig {
+		if err := r.writeMergedKubeconfig(ctx, instance); err != nil {
+			log.Error().Err(err).Msg("Failed to write merged kubeconfig")
+			return subroutines.OK(), err
+		}
 	}
+	// The summary is informational, a failed listing keeps the previous one.
+	if summary, err := summarizeManagedSecrets(ctx, r.client, instance); err != nil {
+		log.Warn().Err(err).Msg("Failed to summarize managed secrets")
+	} else {
+		instance.Status.ManagedSecrets = summary
+	}
+
+	// Secrets are written for every connection first, consumers retry until the
+	// endpoint serves. Readiness is only declared once discovery succeeds.
+	if len(notReady) > 0 {
+		status.enter("WaitingForProviderConnections")
+		msg := "provider connections not serving yet: " + strings.Join(notReady, ", ")
+		apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               ProviderConnectionsReadyConditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             "NotServing",
+			Message:            msg,
+			ObservedGeneration: instance.Generation,
+		})
+		return subroutines.Pending(r.requeue.Next(instance), msg), nil
+	}
+	apimeta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               ProviderConnectionsReadyConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Serving",
+		Message:            "all provider connections answer discovery",
+		ObservedGeneration: instance.Generation,
+	})
+	// Scoped tokens are only renewed while reconciling, so come back in time.
+	renewBefore := connectionRenewBefore(operatorCfg.Subroutines.ProviderSecret, providers)
+	if renewIn, ok := nextScopedTokenRenewal(instance, renewBefore); ok {
+		return subroutines.OKWithRequeue(renewIn), nil
+	}
+	return subroutines.OK(), nil
+}
 
-	// Determine which provider connections to use based on configuration:
+// providerConnections returns the provider connections of instance: the
+// configured ones, or the defaults when none are configured, followed by the
+// extra ones and the connections of enabled features.
+func providerConnections(instance *corev1alpha1.PlatformMesh) []corev1alpha1.ProviderConnection {
 	var providers []corev1alpha1.ProviderConnection
 	hasProv := len(instance.Spec.Kcp.ProviderConnections) > 0
 	hasExtraProv := len(instance.Spec.Kcp.ExtraProviderConnections) > 0
@@ -149,19 +312,7 @@ func (r *ProvidersecretSubroutine) Process(
 		})
 	}
 
-	// Build kcp kubeonfig
-	cfg, err := buildKubeconfig(ctx, r.client, r.kcpUrl)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to build kubeconfig")
-		return subroutines.OK(), gcerrors.Wrap(err, "Failed to build kubeconfig")
-	}
-	for _, pc := range providers {
-		if _, connErr := r.HandleProviderConnection(ctx, instance, pc, cfg); connErr != nil {
-			log.Error().Err(connErr).Msg("Failed to handle provider connection")
-			return subroutines.OK(), connErr
-		}
-	}
-	return subroutines.OK(), nil
+	return providers
 }
 
 func (r *ProvidersecretSubroutine) Finalizers(instance client.Object) []string { // coverage-ignore
@@ -178,14 +329,29 @@ func (r *ProvidersecretSubroutine) HandleProviderConnection(
 	log := logger.LoadLoggerFromContext(ctx)
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
 
-	if !ptr.Deref(pc.AdminAuth, false) {
-		if err := writeScopedKubeconfigToSecret(ctx, r.client, r.kcpHelper, cfg, instance, pc); err != nil {
+	if !isAdminConnection(pc) {
+		ready, err := writeScopedKubeconfigToSecret(ctx, r.client, r.kcpHelper, r.prober, cfg, instance, pc)
+		if err != nil {
+			if isSecretOwnedByOther(err) {
+				log.Warn().Err(err).Str("secret", pc.Secret).Msg("Provider secret is controlled by another owner, skipping")
+				return subroutines.OK(), nil
+			}
 			log.Error().Err(err).Str("secret", pc.Secret).Msg("Failed to write scoped provider kubeconfig")
 			return subroutines.OK(), err
 		}
+		if !ready {
+			return connectionNotServing(pc.Secret, r.requeue.Next(instance)), nil
+		}
 		return subroutines.OK(), nil
 	}
 
+	resolvedURL := ""
+	healthy := false
+	ready := false
+	defer func() {
+		recordConnectionStatus(instance, pc.Secret, corev1alpha1.ConnectionTypeProvider, resolvedURL, healthy, ready)
+	}()
+
 	var address *url.URL
 
 	if ptr.Deref(pc.EndpointSliceName, "") != "" {
@@ -203,10 +369,14 @@ func (r *ProvidersecretSubroutine) HandleProviderConnection(
 		}
 
 		if len(slice.Status.APIExportEndpoints) == 0 {
-			return subroutines.StopWithRequeue(DefaultRequeueInterval, "no endpoints in slice"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(instance), "no endpoints in slice"), nil
 		}
 
-		endpointURL := slice.Status.APIExportEndpoints[0].URL
+		endpointURL, err := resolveSliceEndpoint(ctx, r.kcpHelper, cfg, &slice, pc.EndpointSelector)
+		if err != nil {
+			log.Error().Err(err).Str("secret", pc.Secret).Msg("Failed to select APIExportEndpointSlice endpoint")
+			return subroutines.OK(), err
+		}
 		address, err = url.Parse(endpointURL)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to parse endpoint URL")
@@ -226,20 +396,22 @@ func (r *ProvidersecretSubroutine) HandleProviderConnection(
 		address = kcpUrl
 	}
 
-	namespace := "platform-mesh-system"
+	namespace := instanceNamespace(instance)
 	if ptr.Deref(pc.Namespace, "") != "" {
 		namespace = *pc.Namespace
 	}
 
-	hostPort := fmt.Sprintf("https://%s-front-proxy.%s:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.Namespace, operatorCfg.KCP.FrontProxyPort)
-	if pc.External {
-		hostPort = fmt.Sprintf("https://kcp.api.%s:%d", instance.Spec.Exposure.BaseDomain, instance.Spec.Exposure.Port)
+	hostPort, err := frontProxyBaseURL(operatorCfg, instance, pc.External, pc.HostOverride)
+	if err != nil {
+		log.Error().Err(err).Str("secret", pc.Secret).Msg("Failed to resolve front-proxy host for provider connection")
+		return subroutines.OK(), err
 	}
 	host, err := url.JoinPath(hostPort, address.Path)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to join path for provider connection")
 		return subroutines.OK(), err
 	}
+	resolvedURL = host
 
 	adminKubeconfigData, err := loadKcpOperatorAdminKubeconfig(r.client, operatorCfg.KCP.Namespace)
 	if err != nil {
@@ -251,21 +423,58 @@ func (r *ProvidersecretSubroutine) HandleProviderConnection(
 		log.Error().Err(err).Str("secret", pc.Secret).Msg("Failed to build admin auth trust bundle from kubeconfig-kcp-admin and root shard CA")
 		return subroutines.OK(), err
 	}
-	if err := writeProviderSecretFromKcpOperatorAdminKubeconfig(ctx, r.client, adminKubeconfigData, host, trustBundle, pc.Secret, namespace); err != nil {
+	kubeconfig, err := writeProviderSecretFromKcpOperatorAdminKubeconfig(ctx, r.client, instance, adminKubeconfigData, host, trustBundle, pc.Secret, namespace, pc.Path)
+	if err != nil {
+		if isSecretOwnedByOther(err) {
+			log.Warn().Err(err).Str("secret", pc.Secret).Str("namespace", namespace).Msg("Provider secret is controlled by another owner, skipping")
+			return subroutines.OK(), nil
+		}
 		log.Error().Err(err).Msg("Failed to create or update secret")
 		return subroutines.OK(), err
 	}
 
 	log.Debug().Str("secret", pc.Secret).Msg("Created or updated provider secret")
+	healthy = true
 
+	if ready = probeConnection(ctx, r.prober, pc.Secret, kubeconfig); !ready {
+		return connectionNotServing(pc.Secret, r.requeue.Next(instance)), nil
+	}
 	return subroutines.OK(), nil
 }
 
+// handleInitializerConnections writes the secrets of spec.kcp.initializerConnections
+// with at most workers connections in parallel and returns the secrets whose
+// endpoint is not serving yet and the errors of all failed connections.
+func (r *ProvidersecretSubroutine) handleInitializerConnections(
+	ctx context.Context, instance *corev1alpha1.PlatformMesh, cfg *rest.Config, workers int,
+) ([]string, error) {
+	initializers := instance.Spec.Kcp.InitializerConnections
+	secrets := make([]string, len(initializers))
+	keep := map[string]bool{}
+	for i, ic := range initializers {
+		secrets[i] = ic.Secret
+		keep[ic.Secret] = true
+	}
+	notReady, err := handleConnections(instance, corev1alpha1.ConnectionTypeInitializer, secrets, workers,
+		func(conn *corev1alpha1.PlatformMesh, i int) (subroutines.Result, error) {
+			return r.HandleInitializerConnection(ctx, conn, initializers[i], rest.CopyConfig(cfg))
+		})
+	pruneConnectionStatus(instance, corev1alpha1.ConnectionTypeInitializer, keep)
+	return notReady, err
+}
+
 func (r *ProvidersecretSubroutine) HandleInitializerConnection(
 	ctx context.Context, instance *corev1alpha1.PlatformMesh, ic corev1alpha1.InitializerConnection, restCfg *rest.Config,
 ) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 
+	resolvedURL := ""
+	healthy := false
+	ready := false
+	defer func() {
+		recordConnectionStatus(instance, ic.Secret, corev1alpha1.ConnectionTypeInitializer, resolvedURL, healthy, ready)
+	}()
+
 	kcpClient, err := r.kcpHelper.NewKcpClient(restCfg, ic.Path)
 	if err != nil {
 		log.Error().Err(err).Msg("creating kcp client for initializer")
@@ -280,7 +489,7 @@ func (r *ProvidersecretSubroutine) HandleInitializerConnection(
 	if len(wt.Status.VirtualWorkspaces) == 0 {
 		err = fmt.Errorf("no virtual workspaces found in %s", ic.WorkspaceTypeName)
 		log.Error().Err(err).Msg("bad WorkspaceType")
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, err.Error()), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), err.Error()), nil
 	}
 
 	newConfig := rest.CopyConfig(restCfg)
@@ -297,7 +506,17 @@ func (r *ProvidersecretSubroutine) HandleInitializerConnection(
 	}
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
 	url.Host = fmt.Sprintf("%s-front-proxy:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.FrontProxyPort)
+	if ic.HostOverride != "" {
+		override, err := parseHostOverride(ic.HostOverride)
+		if err != nil {
+			log.Error().Err(err).Msg("parsing initializer host override")
+			return subroutines.OK(), err
+		}
+		url.Scheme, url.Host = override.Scheme, override.Host
+	}
 	apiConfig.Clusters[cluster].Server = url.String()
+	nameKubeconfig(apiConfig, instanceKubeconfigEntryName(instance, ic.Secret, ic.Path))
+	resolvedURL = url.String()
 	log.Debug().Str("url", url.String()).Msg("modified virtual workspace URL")
 
 	data, err := clientcmd.Write(*apiConfig)
@@ -306,7 +525,7 @@ func (r *ProvidersecretSubroutine) HandleInitializerConnection(
 		return subroutines.OK(), err
 	}
 
-	namespace := "platform-mesh-system"
+	namespace := instanceNamespace(instance)
 	if ic.Namespace != "" {
 		namespace = ic.Namespace
 	}
@@ -317,14 +536,24 @@ func (r *ProvidersecretSubroutine) HandleInitializerConnection(
 		},
 	}
 	_, err = controllerutil.CreateOrUpdate(ctx, r.client, initializerSecret, func() error {
-		initializerSecret.Data = map[string][]byte{"kubeconfig": data}
-		return err
+		if instance != nil {
+			if err := claimLabeledObject(initializerSecret, instance); err != nil {
+				return err
+			}
+		}
+		LabelSecretPurpose(initializerSecret, SecretPurposeInitializer, ic.Path)
+		SetSecretData(initializerSecret, map[string][]byte{"kubeconfig": data})
+		return nil
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("creating/updating initializer Secret")
 		return subroutines.OK(), err
 	}
+	healthy = true
 
+	if ready = probeConnection(ctx, r.prober, ic.Secret, data); !ready {
+		return connectionNotServing(ic.Secret, r.requeue.Next(instance)), nil
+	}
 	return subroutines.OK(), nil
 }
 
@@ -386,14 +615,15 @@ func buildAdminAuthTrustBundle(ctx context.Context, k8sClient client.Client, adm
 func writeProviderSecretFromKcpOperatorAdminKubeconfig(
 	ctx context.Context,
 	k8sClient client.Client,
+	instance *corev1alpha1.PlatformMesh,
 	adminKubeconfigData []byte,
 	targetServerURL string,
 	frontProxyCAData []byte,
-	providerSecretName, providerSecretNamespace string,
-) error {
+	providerSecretName, providerSecretNamespace, connection string,
+) ([]byte, error) {
 	apiCfg, err := clientcmd.Load(adminKubeconfigData)
 	if err != nil {
-		return fmt.Errorf("load kcp-operator admin kubeconfig: %w", err)
+		return nil, fmt.Errorf("load kcp-operator admin kubeconfig: %w", err)
 	}
 	for _, c := range apiCfg.Clusters {
 		if c == nil {
@@ -406,23 +636,15 @@ func writeProviderSecretFromKcpOperatorAdminKubeconfig(
 		}
 		c.Server = targetServerURL
 	}
+	nameKubeconfig(apiCfg, instanceKubeconfigEntryName(instance, providerSecretName, connection))
 	out, err := clientcmd.Write(*apiCfg)
 	if err != nil {
-		return fmt.Errorf("serialize provider kubeconfig: %w", err)
+		return nil, fmt.Errorf("serialize provider kubeconfig: %w", err)
 	}
-	providerSecret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      providerSecretName,
-			Namespace: providerSecretNamespace,
-		},
-	}
-	_, err = controllerutil.CreateOrUpdate(ctx, k8sClient, providerSecret, func() error {
-		providerSecret.Data = map[string][]byte{
-			"kubeconfig": out,
-		}
-		return nil
-	})
-	return err
+	_, err = writeProviderSecret(ctx, k8sClient, instance, providerSecretName, providerSecretNamespace, SecretPurposeProvider, connection, map[string][]byte{
+		"kubeconfig": out,
+	}, map[string]string{SourceKubeconfigHashAnnotation: sourceKubeconfigHash(adminKubeconfigData, frontProxyCAData)})
+	return out, err
 }
 
 func restConfigToAPIConfig(restCfg *rest.Config) *clientcmdapi.Config {
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -21,10 +22,12 @@ import (
 	"github.com/stretchr/testify/suite"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -38,6 +41,20 @@ var secretKubeconfigData, _ = os.ReadFile("test/kubeconfig.yaml")
 
 type fakeHelm struct{ ready bool }
 
+// fakeProber answers every probe with err and records the probed kubeconfigs.
+type fakeProber struct {
+	err    error
+	mu     sync.Mutex
+	probed [][]byte
+}
+
+func (f *fakeProber) Probe(_ context.Context, kubeconfig []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probed = append(f.probed, kubeconfig)
+	return f.err
+}
+
 func (f fakeHelm) GetRelease(ctx context.Context, cli client.Client, name, ns string) (*unstructured.Unstructured, error) {
 	u := &unstructured.Unstructured{Object: map[string]interface{}{
 		"status": map[string]interface{}{"ready": f.ready},
@@ -117,8 +134,11 @@ func (suite *ProvidersecretTestSuite) SetupTest() {
 	_ = kcpapiv1alpha.AddToScheme(suite.scheme)
 
 	suite.clientMock.EXPECT().Scheme().Return(suite.scheme).Maybe()
+	suite.clientMock.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1.SecretList"), mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.clientMock.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.PlatformMeshList")).Return(nil).Maybe()
 
 	suite.testObj = NewProviderSecretSubroutine(suite.clientMock, &Helper{}, fakeHelm{ready: true}, "")
+	suite.testObj.prober = &fakeProber{}
 }
 
 func (suite *ProvidersecretTestSuite) TearDownTest() {
@@ -291,6 +311,7 @@ func (s *ProvidersecretTestSuite) TestProcess() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	operatorCfg := config.OperatorConfig{
 		KCP: config.OperatorConfig{}.KCP,
@@ -340,6 +361,7 @@ func (s *ProvidersecretTestSuite) TestWrongScheme() {
 
 	// mocks
 	mockK8sClient := new(mocks.Client)
+	mockK8sClient.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.PlatformMeshList")).Return(nil).Maybe()
 	mockK8sClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 	mockK8sClient.EXPECT().Create(mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 	// return nil scheme
@@ -387,6 +409,7 @@ func (s *ProvidersecretTestSuite) TestWrongScheme() {
 
 	// s.testObj.kcpHelper = mockedKcpHelper
 	s.testObj = NewProviderSecretSubroutine(mockK8sClient, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	operatorCfg := config.OperatorConfig{
 		KCP: config.OperatorConfig{}.KCP,
@@ -452,6 +475,7 @@ func (s *ProvidersecretTestSuite) TestErrorCreatingSecret() {
 
 	// Mocks
 	mockClient := new(mocks.Client)
+	mockClient.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.PlatformMeshList")).Return(nil).Maybe()
 	mockScheme := runtime.NewScheme()
 
 	// Expect scheme call for SetOwnerReference
@@ -492,7 +516,7 @@ func (s *ProvidersecretTestSuite) TestErrorCreatingSecret() {
 	mockClient.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -550,6 +574,7 @@ func (s *ProvidersecretTestSuite) TestErrorCreatingSecret() {
 
 	// Run
 	s.testObj = NewProviderSecretSubroutine(mockClient, mockedKcpHelper, fakeHelm{ready: true}, "example.com")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -660,6 +685,7 @@ func (s *ProvidersecretTestSuite) TestFailedBuilidingKubeconfig() {
 
 	// s.testObj.kcpHelper = mockedKcpHelper
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -778,6 +804,7 @@ func (s *ProvidersecretTestSuite) TestGetName() {
 func (suite *ProvidersecretTestSuite) TestConstructor() {
 	helper := &Helper{}
 	suite.testObj = NewProviderSecretSubroutine(suite.clientMock, helper, fakeHelm{ready: true}, "")
+	suite.testObj.prober = &fakeProber{}
 }
 
 func (s *ProvidersecretTestSuite) TestFinalize() {
@@ -862,6 +889,7 @@ func (s *ProvidersecretTestSuite) TestInvalidKubeconfig() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -990,7 +1018,7 @@ func (s *ProvidersecretTestSuite) TestErrorCreatingKCPClient() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1050,6 +1078,7 @@ func (s *ProvidersecretTestSuite) TestErrorCreatingKCPClient() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1103,7 +1132,7 @@ func (s *ProvidersecretTestSuite) TestErrorGettingAPIExportEndpointSlice() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1147,6 +1176,7 @@ func (s *ProvidersecretTestSuite) TestErrorGettingAPIExportEndpointSlice() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1200,7 +1230,7 @@ func (s *ProvidersecretTestSuite) TestEmptyAPIExportEndpoints() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1253,6 +1283,7 @@ func (s *ProvidersecretTestSuite) TestEmptyAPIExportEndpoints() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1305,7 +1336,7 @@ func (s *ProvidersecretTestSuite) TestInvalidEndpointURL() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1360,6 +1391,7 @@ func (s *ProvidersecretTestSuite) TestInvalidEndpointURL() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1434,7 +1466,7 @@ func (s *ProvidersecretTestSuite) TestContextNotFoundInKubeconfig() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1481,6 +1513,7 @@ func (s *ProvidersecretTestSuite) TestContextNotFoundInKubeconfig() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1574,7 +1607,7 @@ func (s *ProvidersecretTestSuite) TestClusterNotFoundInKubeconfig() {
 	s.clientMock.EXPECT().
 		Get(mock.Anything,
 			mock.MatchedBy(func(key types.NamespacedName) bool {
-				if key.Namespace == "platform-mesh-system" {
+				if key.Namespace == "default" || key.Namespace == "platform-mesh-system" {
 					switch key.Name {
 					case "account-operator-kubeconfig",
 						"rebac-authz-webhook-kubeconfig",
@@ -1621,6 +1654,7 @@ func (s *ProvidersecretTestSuite) TestClusterNotFoundInKubeconfig() {
 	).Once()
 
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "")
+	s.testObj.prober = &fakeProber{}
 
 	// Add the missing operator config context
 	operatorCfg := config.OperatorConfig{
@@ -1634,7 +1668,9 @@ func (s *ProvidersecretTestSuite) TestClusterNotFoundInKubeconfig() {
 	s.Assert().Equal(subroutines.OK(), res)
 }
 
-func (s *ProvidersecretTestSuite) TestHandleProviderConnections() {
+// processAdminConnections runs Process for the default provider connections with
+// admin auth plus one external connection.
+func (s *ProvidersecretTestSuite) processAdminConnections(prober *fakeProber) (*corev1alpha1.PlatformMesh, []corev1alpha1.ProviderConnection, subroutines.Result) {
 	// Setup test instance
 	instance := s.getBaseInstance()
 	// Exercise admin kubeconfig wiring only: defaults may use scoped kubeconfig for some secrets.
@@ -1716,7 +1752,7 @@ func (s *ProvidersecretTestSuite) TestHandleProviderConnections() {
 	// Build expected secret keys dynamically from DefaultProviderConnections
 	expectedSecretKeys := make(map[types.NamespacedName]bool)
 	for _, pc := range DefaultProviderConnections {
-		ns := "platform-mesh-system"
+		ns := instance.Namespace
 		if ptr.Deref(pc.Namespace, "") != "" {
 			ns = *pc.Namespace
 		}
@@ -1869,10 +1905,76 @@ func (s *ProvidersecretTestSuite) TestHandleProviderConnections() {
 
 	// Run test
 	s.testObj = NewProviderSecretSubroutine(s.clientMock, mockedKcpHelper, fakeHelm{ready: true}, "example.com")
+	s.testObj.prober = prober
 
 	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
 	ctx = context.WithValue(ctx, keys.ConfigCtxKey, opCfg)
 	res, opErr := s.testObj.Process(ctx, instance)
 	s.Require().Nil(opErr)
+	return instance, adminDefaults, res
+}
+
+func (s *ProvidersecretTestSuite) TestHandleProviderConnections() {
+	prober := &fakeProber{}
+	instance, adminDefaults, res := s.processAdminConnections(prober)
 	s.Assert().Equal(subroutines.OK(), res)
+
+	opCfg := config.NewOperatorConfig()
+	s.Require().Len(instance.Status.Connections, len(DefaultProviderConnections)+1)
+	for i, pc := range DefaultProviderConnections {
+		conn := instance.Status.Connections[i]
+		s.Equal(pc.Secret, conn.Name)
+		s.Equal(corev1alpha1.ConnectionTypeProvider, conn.Type)
+		s.Equal(wantProviderKubeconfigServer(s.T(), instance, opCfg, adminDefaults[i], "example.com"), conn.ResolvedURL)
+		s.NotNil(conn.LastResolved)
+		s.True(conn.Healthy)
+		s.True(conn.Ready)
+	}
+	s.Len(prober.probed, len(DefaultProviderConnections)+1)
+	s.True(apimeta.IsStatusConditionTrue(instance.Status.Conditions, ProviderConnectionsReadyConditionType))
+}
+
+func (s *ProvidersecretTestSuite) TestHandleInitializerConnections() {
+	mockedKcpHelper := new(mocks.KcpHelper)
+	mockedKcpHelper.EXPECT().NewKcpClient(mock.Anything, "root:orgs").Return(nil, errors.New("unreachable")).Once()
+	s.testObj.kcpHelper = mockedKcpHelper
+
+	instance := s.getBaseInstance()
+	instance.Status.Connections = []corev1alpha1.ConnectionStatus{
+		{Name: "removed-initializer", Type: corev1alpha1.ConnectionTypeInitializer, Healthy: true},
+	}
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+
+	// Connections removed from the spec are pruned.
+	notReady, err := s.testObj.handleInitializerConnections(ctx, instance, &rest.Config{Host: "https://kcp.example.com"}, 1)
+	s.Require().NoError(err)
+	s.Empty(notReady)
+	s.Empty(instance.Status.Connections)
+
+	// A failing connection is reported with its secret.
+	instance.Spec.Kcp.InitializerConnections = []corev1alpha1.InitializerConnection{
+		{WorkspaceTypeName: "org", Path: "root:orgs", Secret: "org-initializer-kubeconfig"},
+	}
+	_, err = s.testObj.handleInitializerConnections(ctx, instance, &rest.Config{Host: "https://kcp.example.com"}, 1)
+	s.Require().ErrorContains(err, "org-initializer-kubeconfig")
+	s.Require().Len(instance.Status.Connections, 1)
+	s.Equal(corev1alpha1.ConnectionTypeInitializer, instance.Status.Connections[0].Type)
+	s.False(instance.Status.Connections[0].Healthy)
+}
+
+func (s *ProvidersecretTestSuite) TestHandleProviderConnectionsNotServing() {
+	prober := &fakeProber{err: errors.New("the server could not find the requested resource")}
+	instance, _, res := s.processAdminConnections(prober)
+
+	s.True(res.IsPending())
+	s.Contains(res.Message(), "provider connections not serving yet: ")
+	s.Contains(res.Message(), "external-kubeconfig")
+	for _, conn := range instance.Status.Connections {
+		s.True(conn.Healthy)
+		s.False(conn.Ready)
+	}
+	cond := apimeta.FindStatusCondition(instance.Status.Conditions, ProviderConnectionsReadyConditionType)
+	s.Require().NotNil(cond)
+	s.Equal(metav1.ConditionFalse, cond.Status)
+	s.Equal("NotServing", cond.Reason)
 }
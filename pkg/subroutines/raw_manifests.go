@@ -0,0 +1,143 @@
+package subroutines
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const (
+	// RawManifestHashAnnotation holds the hash of the raw manifest an object
+	// was last applied from.
+	RawManifestHashAnnotation = "core.platform-mesh.io/raw-manifest-hash"
+
+	fieldManagerOperator = "platform-mesh-operator"
+)
+
+// applyRawManifests applies spec.kcp.rawManifests into their workspaces with
+// the field manager of the operator, when the operator allows raw manifests.
+// Each object whose manifest changed since it was last applied is recorded in
+// an audit event on inst.
+func (r *KcpsetupSubroutine) applyRawManifests(ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh) error {
+	if len(inst.Spec.Kcp.RawManifests) == 0 {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	allowed := r.cfg.Subroutines.KcpSetup.RawManifests
+	if !allowed.Enabled {
+		log.Warn().Int("count", len(inst.Spec.Kcp.RawManifests)).Msg("Raw manifests are disabled, ignoring spec.kcp.rawManifests")
+		return nil
+	}
+	claims := NewSharedObjectClaims(r.client, inst)
+	templateData := r.instanceTemplateData(inst)
+
+	for i, raw := range inst.Spec.Kcp.RawManifests {
+		obj, err := renderRawManifest(raw, templateData)
+		if err != nil {
+			return errors.Wrap(err, "Failed to render raw manifest %d for workspace %s", i, raw.WorkspacePath)
+		}
+		desc := fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+		if obj.GetNamespace() != "" {
+			desc = fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		}
+		if err := rawManifestAllowed(allowed, raw.WorkspacePath, obj); err != nil {
+			return newClassifiedError(corev1alpha1.ErrorCategoryConfig, fmt.Errorf("raw manifest %s for workspace %s: %w", desc, raw.WorkspacePath, err))
+		}
+
+		k8sClient, err := r.kcpHelper.NewKcpClient(config, raw.WorkspacePath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create kcp client for workspace %s", raw.WorkspacePath)
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, live)
+		action := "Updated"
+		if kerrors.IsNotFound(err) {
+			action = "Created"
+		} else if err != nil {
+			return errors.Wrap(err, "Failed to get %s in workspace %s", desc, raw.WorkspacePath)
+		}
+
+		if err := claims.claim(ctx, k8sClient, obj, raw.WorkspacePath); err != nil {
+			return err
+		}
+		err = k8sClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj),
+			client.FieldOwner(fieldManagerOperator), client.ForceOwnership)
+		if err != nil {
+			recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonApplyFailed, "ApplyRawManifest",
+				"Failed to apply raw manifest %s to workspace %s: %v", desc, raw.WorkspacePath, err)
+			return errors.Wrap(err, "Failed to apply raw manifest %s to workspace %s", desc, raw.WorkspacePath)
+		}
+
+		if live.GetAnnotations()[RawManifestHashAnnotation] != obj.GetAnnotations()[RawManifestHashAnnotation] {
+			log.Info().Str("workspace", raw.WorkspacePath).Str("object", desc).Str("action", action).Msg("Applied raw manifest")
+			recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonRawManifestApplied, "ApplyRawManifest",
+				"%s %s in workspace %s from raw manifest", action, desc, raw.WorkspacePath)
+		}
+	}
+	return nil
+}
+
+// rawManifestAllowed returns an error unless allowed permits obj in the
+// workspace path.
+func rawManifestAllowed(allowed config.RawManifestsConfig, path string, obj *unstructured.Unstructured) error {
+	if !slices.ContainsFunc(allowed.WorkspacePaths, func(p string) bool {
+		if parent, ok := strings.CutSuffix(p, ":*"); ok {
+			return strings.HasPrefix(path, parent+":")
+		}
+		return p == path
+	}) {
+		return fmt.Errorf("workspace %s is not allowed for raw manifests", path)
+	}
+	kind := obj.GetKind()
+	if group := obj.GroupVersionKind().Group; group != "" {
+		kind += "." + group
+	}
+	if !slices.Contains(allowed.Kinds, kind) {
+		return fmt.Errorf("kind %s is not allowed for raw manifests", kind)
+	}
+	return nil
+}
+
+// renderRawManifest substitutes the template variables of raw and returns the
+// object annotated with the hash of the result.
+func renderRawManifest(raw corev1alpha1.RawManifest, templateData map[string]any) (*unstructured.Unstructured, error) {
+	rendered, err := ReplaceTemplate(templateData, []byte(raw.Manifest))
+	if err != nil {
+		return nil, err
+	}
+	var objMap map[string]interface{}
+	if err := yaml.Unmarshal(rendered, &objMap); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal YAML")
+	}
+	obj := &unstructured.Unstructured{Object: objMap}
+	if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+		return nil, fmt.Errorf("manifest needs apiVersion, kind and metadata.name")
+	}
+
+	sum := sha256.Sum256(rendered)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RawManifestHashAnnotation] = hex.EncodeToString(sum[:8])
+	obj.SetAnnotations(annotations)
+	return obj, nil
+}
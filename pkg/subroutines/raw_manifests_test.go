@@ -0,0 +1,120 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func TestApplyRawManifests_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+	helper := &Helper{}
+
+	cfg := &config.OperatorConfig{}
+	cfg.Subroutines.KcpSetup.RawManifests = config.RawManifestsConfig{Enabled: true, WorkspacePaths: []string{"root"}, Kinds: []string{"ConfigMap"}}
+	r := &KcpsetupSubroutine{kcpHelper: helper, cfg: cfg}
+	inst := &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"},
+		Spec: corev1alpha1.PlatformMeshSpec{
+			Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "example.com", Port: 443},
+			Kcp: corev1alpha1.Kcp{RawManifests: []corev1alpha1.RawManifest{{
+				WorkspacePath: "root",
+				Manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hotfix
+  namespace: default
+data:
+  url: https://{{ .baseDomain }}/hotfix
+`,
+			}}},
+		},
+	}
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	getHotfix := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, root.Get(ctx, client.ObjectKey{Namespace: "default", Name: "hotfix"}, cm))
+		return cm
+	}
+
+	require.NoError(t, r.applyRawManifests(ctx, server.RestConfig(), inst))
+	assert.Equal(t, "https://example.com/hotfix", getHotfix().Data["url"])
+	assert.NotEmpty(t, getHotfix().Annotations[RawManifestHashAnnotation])
+	require.Len(t, rec.Events, 1)
+	assert.Equal(t, "Normal RawManifestApplied Created ConfigMap default/hotfix in workspace root from raw manifest", <-rec.Events)
+
+	// Reapplying an unchanged manifest is not audited again.
+	require.NoError(t, r.applyRawManifests(ctx, server.RestConfig(), inst))
+	assert.Empty(t, rec.Events)
+
+	inst.Spec.Exposure.BaseDomain = "example.org"
+	require.NoError(t, r.applyRawManifests(ctx, server.RestConfig(), inst))
+	assert.Equal(t, "https://example.org/hotfix", getHotfix().Data["url"])
+	require.Len(t, rec.Events, 1)
+	assert.Equal(t, "Normal RawManifestApplied Updated ConfigMap default/hotfix in workspace root from raw manifest", <-rec.Events)
+
+	// Kinds and workspaces outside the allowed ones are rejected as
+	// configuration errors.
+	inst.Spec.Kcp.RawManifests[0].WorkspacePath = "root:orgs"
+	err = r.applyRawManifests(ctx, server.RestConfig(), inst)
+	assert.ErrorContains(t, err, "workspace root:orgs is not allowed")
+	assert.Equal(t, corev1alpha1.ErrorCategoryConfig, ClassifyError(err).Category)
+
+	// Disabled raw manifests are not applied.
+	cfg.Subroutines.KcpSetup.RawManifests.Enabled = false
+	inst.Spec.Kcp.RawManifests[0].WorkspacePath = "root"
+	inst.Spec.Exposure.BaseDomain = "example.net"
+	require.NoError(t, r.applyRawManifests(ctx, server.RestConfig(), inst))
+	assert.Equal(t, "https://example.org/hotfix", getHotfix().Data["url"])
+	assert.Empty(t, rec.Events)
+}
+
+func TestRawManifestAllowed(t *testing.T) {
+	allowed := config.RawManifestsConfig{Enabled: true, WorkspacePaths: []string{"root", "root:orgs:*"}, Kinds: []string{"ConfigMap", "APIBinding.apis.kcp.io"}}
+	obj := func(apiVersion, kind string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		return u
+	}
+
+	assert.NoError(t, rawManifestAllowed(allowed, "root", obj("v1", "ConfigMap")))
+	assert.NoError(t, rawManifestAllowed(allowed, "root:orgs:acme:team", obj("apis.kcp.io/v1alpha1", "APIBinding")))
+	assert.ErrorContains(t, rawManifestAllowed(allowed, "root:orgs", obj("v1", "ConfigMap")), "workspace root:orgs")
+	assert.ErrorContains(t, rawManifestAllowed(allowed, "root:providers", obj("v1", "ConfigMap")), "workspace root:providers")
+	assert.ErrorContains(t, rawManifestAllowed(allowed, "root", obj("v1", "Secret")), "kind Secret")
+	assert.ErrorContains(t, rawManifestAllowed(allowed, "root", obj("example.com/v1", "ConfigMap")), "kind ConfigMap.example.com")
+	assert.Error(t, rawManifestAllowed(config.RawManifestsConfig{Enabled: true}, "root", obj("v1", "ConfigMap")))
+}
+
+func TestRenderRawManifest(t *testing.T) {
+	obj, err := renderRawManifest(corev1alpha1.RawManifest{Manifest: "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: {{ .name }}\n"}, map[string]any{"name": "hotfix"})
+	require.NoError(t, err)
+	assert.Equal(t, "hotfix", obj.GetName())
+	assert.Len(t, obj.GetAnnotations()[RawManifestHashAnnotation], 16)
+
+	_, err = renderRawManifest(corev1alpha1.RawManifest{Manifest: "kind: Namespace\n"}, nil)
+	assert.ErrorContains(t, err, "apiVersion, kind and metadata.name")
+
+	_, err = renderRawManifest(corev1alpha1.RawManifest{Manifest: "{{ .missing"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package subroutines
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// policyRulesHash identifies rules independent of their order and of the
+// order of their list fields.
+func policyRulesHash(rules []rbacv1.PolicyRule) string {
+	sum := sha256.Sum256([]byte(strings.Join(policyRuleKeys(rules), "\n")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// rbacApproved reports whether hash is listed in the RBACApprovalAnnotation of
+// instance.
+func rbacApproved(instance *corev1alpha1.PlatformMesh, hash string) bool {
+	if instance == nil {
+		return false
+	}
+	for _, approved := range strings.Split(instance.GetAnnotations()[corev1alpha1.RBACApprovalAnnotation], ",") {
+		if strings.TrimSpace(approved) == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// recordProposedRules sets the rules of pc that wait for approval in the
+// instance status, or clears them when rules is empty.
+func recordProposedRules(instance *corev1alpha1.PlatformMesh, pc corev1alpha1.ProviderConnection, rules []rbacv1.PolicyRule, hash string) {
+	if instance == nil {
+		return
+	}
+	for i, c := range instance.Status.ProviderConnections {
+		if c.Secret == pc.Secret && c.Path == pc.Path {
+			instance.Status.ProviderConnections[i].ProposedRules = slices.Clone(rules)
+			instance.Status.ProviderConnections[i].ProposedRulesHash = hash
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package subroutines
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestPolicyRulesHash(t *testing.T) {
+	rules := []rbacv1.PolicyRule{
+		{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"get", "list"}},
+		{NonResourceURLs: []string{"/api"}, Verbs: []string{"get"}},
+	}
+	reordered := []rbacv1.PolicyRule{
+		{NonResourceURLs: []string{"/api"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"list", "get"}},
+	}
+
+	hash := policyRulesHash(rules)
+	assert.Len(t, hash, 16)
+	assert.Equal(t, hash, policyRulesHash(reordered))
+	assert.NotEqual(t, hash, policyRulesHash(rules[:1]))
+}
+
+func TestRBACApproved(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		corev1alpha1.RBACApprovalAnnotation: "0123456789abcdef, fedcba9876543210",
+	}}}
+
+	assert.True(t, rbacApproved(instance, "0123456789abcdef"))
+	assert.True(t, rbacApproved(instance, "fedcba9876543210"))
+	assert.False(t, rbacApproved(instance, "1111111111111111"))
+	assert.False(t, rbacApproved(nil, "0123456789abcdef"))
+}
+
+func TestRecordProposedRules(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{}
+	pc := corev1alpha1.ProviderConnection{Secret: "provider-kubeconfig", Path: "root:providers"}
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"*"}}}
+
+	recordProviderConnectionStatus(instance, pc, false, nil, nil)
+	recordProposedRules(instance, pc, rules, "0123456789abcdef")
+	require.Len(t, instance.Status.ProviderConnections, 1)
+	assert.Equal(t, rules, instance.Status.ProviderConnections[0].ProposedRules)
+	assert.Equal(t, "0123456789abcdef", instance.Status.ProviderConnections[0].ProposedRulesHash)
+
+	// The pool copies proposals of the connection instance back.
+	merged := &corev1alpha1.PlatformMesh{}
+	mergeConnectionStatus(merged, instance)
+	assert.Equal(t, "0123456789abcdef", merged.Status.ProviderConnections[0].ProposedRulesHash)
+
+	// Approved rules clear the proposal.
+	recordProposedRules(instance, pc, nil, "")
+	assert.Empty(t, instance.Status.ProviderConnections[0].ProposedRules)
+	assert.Empty(t, instance.Status.ProviderConnections[0].ProposedRulesHash)
+}
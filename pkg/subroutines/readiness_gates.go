@@ -0,0 +1,162 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const readinessGateProbeTimeout = 5 * time.Second
+
+// evaluateReadinessGates checks the readiness gates of inst, records their
+// results in its status and returns the gates that did not pass.
+func (r *WaitSubroutine) evaluateReadinessGates(ctx context.Context, inst *corev1alpha1.PlatformMesh, log *logger.Logger) []string {
+	if len(inst.Spec.ReadinessGates) == 0 {
+		inst.Status.ReadinessGates = nil
+		return nil
+	}
+	now := metav1.Now()
+	statuses := make([]corev1alpha1.ReadinessGateStatus, 0, len(inst.Spec.ReadinessGates))
+	var failed []string
+	for _, gate := range inst.Spec.ReadinessGates {
+		status := corev1alpha1.ReadinessGateStatus{Type: gate.Type, Target: gate.Target, Passed: true, LastTransitionTime: now}
+		if err := r.checkReadinessGate(ctx, gate); err != nil {
+			log.Info().Err(err).Str("type", string(gate.Type)).Str("target", gate.Target).Msg("Readiness gate did not pass")
+			status.Passed, status.Message = false, err.Error()
+			failed = append(failed, fmt.Sprintf("%s %s", gate.Type, gate.Target))
+		}
+		previous := findReadinessGateStatus(inst.Status.ReadinessGates, gate)
+		if previous != nil && previous.Passed == status.Passed {
+			status.LastTransitionTime = previous.LastTransitionTime
+		} else if !status.Passed {
+			recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonReadinessGateFailed, "Probe",
+				"Readiness gate %s %s did not pass: %s", gate.Type, gate.Target, status.Message)
+		}
+		statuses = append(statuses, status)
+	}
+	inst.Status.ReadinessGates = statuses
+	return failed
+}
+
+func findReadinessGateStatus(statuses []corev1alpha1.ReadinessGateStatus, gate corev1alpha1.ReadinessGate) *corev1alpha1.ReadinessGateStatus {
+	for i := range statuses {
+		if statuses[i].Type == gate.Type && statuses[i].Target == gate.Target {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// checkReadinessGate returns why gate does not pass, nil if it passes.
+func (r *WaitSubroutine) checkReadinessGate(ctx context.Context, gate corev1alpha1.ReadinessGate) error {
+	switch gate.Type {
+	case corev1alpha1.ReadinessGateHTTPProbe:
+		return r.probeReadinessGate(ctx, gate)
+	case corev1alpha1.ReadinessGateResourceCondition:
+		return checkResourceConditionGate(ctx, r.client, gate)
+	}
+	return fmt.Errorf("unknown readiness gate type %q", gate.Type)
+}
+
+// probeReadinessGate sends a GET request to the target of gate and compares
+// the status code with the expected one.
+func (r *WaitSubroutine) probeReadinessGate(ctx context.Context, gate corev1alpha1.ReadinessGate) error {
+	expected := gate.Expected
+	if expected == "" {
+		expected = strconv.Itoa(http.StatusOK)
+	}
+	httpClient := r.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: readinessGateProbeTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gate.Target, nil)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if !statusCodeMatches(resp.StatusCode, expected) {
+		return fmt.Errorf("status code %d, expected %s", resp.StatusCode, expected)
+	}
+	return nil
+}
+
+// statusCodeMatches reports whether code is expected, a status code or a
+// class like 2xx.
+func statusCodeMatches(code int, expected string) bool {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if class, ok := strings.CutSuffix(expected, "xx"); ok && len(class) == 1 {
+		return strconv.Itoa(code/100) == class
+	}
+	return strconv.Itoa(code) == expected
+}
+
+// checkResourceConditionGate reads the target object of gate and checks
+// that it has the expected condition.
+func checkResourceConditionGate(ctx context.Context, cl client.Client, gate corev1alpha1.ReadinessGate) error {
+	gvk, key, err := parseReadinessGateTarget(gate.Target)
+	if err != nil {
+		return err
+	}
+	conditionType, conditionStatus, ok := strings.Cut(gate.Expected, "=")
+	if conditionType == "" {
+		conditionType = "Ready"
+	}
+	if !ok {
+		conditionStatus = string(metav1.ConditionTrue)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	if !matchesConditionWithStatus(obj, conditionType, conditionStatus) {
+		return fmt.Errorf("condition %s is not %s", conditionType, conditionStatus)
+	}
+	return nil
+}
+
+// parseReadinessGateTarget parses apiVersion/kind/namespace/name or
+// apiVersion/kind/name. The apiVersion of the core group is v1.
+func parseReadinessGateTarget(target string) (schema.GroupVersionKind, client.ObjectKey, error) {
+	parts := strings.Split(target, "/")
+	if len(parts) > 0 && parts[0] != "v1" {
+		// group/version
+		if len(parts) < 2 {
+			return schema.GroupVersionKind{}, client.ObjectKey{}, fmt.Errorf("invalid target %q, expected apiVersion/kind/namespace/name", target)
+		}
+		parts = append([]string{parts[0] + "/" + parts[1]}, parts[2:]...)
+	}
+	var key client.ObjectKey
+	switch len(parts) {
+	case 3:
+		key = client.ObjectKey{Name: parts[2]}
+	case 4:
+		key = client.ObjectKey{Namespace: parts[2], Name: parts[3]}
+	default:
+		return schema.GroupVersionKind{}, client.ObjectKey{}, fmt.Errorf("invalid target %q, expected apiVersion/kind/namespace/name", target)
+	}
+	gv, err := schema.ParseGroupVersion(parts[0])
+	if err != nil {
+		return schema.GroupVersionKind{}, client.ObjectKey{}, fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	if parts[1] == "" || key.Name == "" {
+		return schema.GroupVersionKind{}, client.ObjectKey{}, fmt.Errorf("invalid target %q, expected apiVersion/kind/namespace/name", target)
+	}
+	return gv.WithKind(parts[1]), key, nil
+}
@@ -0,0 +1,110 @@
+package subroutines
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestParseReadinessGateTarget(t *testing.T) {
+	gvk, key, err := parseReadinessGateTarget("helm.toolkit.fluxcd.io/v2/HelmRelease/platform-mesh-system/portal")
+	require.NoError(t, err)
+	assert.Equal(t, schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"}, gvk)
+	assert.Equal(t, client.ObjectKey{Namespace: "platform-mesh-system", Name: "portal"}, key)
+
+	gvk, key, err = parseReadinessGateTarget("v1/Namespace/openfga")
+	require.NoError(t, err)
+	assert.Equal(t, schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, gvk)
+	assert.Equal(t, client.ObjectKey{Name: "openfga"}, key)
+
+	for _, target := range []string{"portal", "v1/ConfigMap", "apps/v1/Deployment/ns/name/extra", "v1//name"} {
+		_, _, err := parseReadinessGateTarget(target)
+		assert.Error(t, err, target)
+	}
+}
+
+func TestStatusCodeMatches(t *testing.T) {
+	assert.True(t, statusCodeMatches(200, "200"))
+	assert.True(t, statusCodeMatches(204, "2xx"))
+	assert.True(t, statusCodeMatches(401, "4XX"))
+	assert.False(t, statusCodeMatches(500, "2xx"))
+	assert.False(t, statusCodeMatches(201, "200"))
+}
+
+func TestEvaluateReadinessGates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	newRelease := func(name string) *unstructured.Unstructured {
+		release := &unstructured.Unstructured{}
+		release.SetGroupVersionKind(helmReleaseGVK)
+		release.SetNamespace("platform-mesh-system")
+		release.SetName(name)
+		require.NoError(t, unstructured.SetNestedSlice(release.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False"},
+		}, "status", "conditions"))
+		return release
+	}
+	release := newRelease("portal")
+	cl := fake.NewClientBuilder().WithObjects(release, newRelease("iam")).Build()
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+	sub := &WaitSubroutine{client: cl, httpClient: server.Client()}
+	releaseTarget := "helm.toolkit.fluxcd.io/v2/HelmRelease/platform-mesh-system/portal"
+	inst := &v1alpha1.PlatformMesh{Spec: v1alpha1.PlatformMeshSpec{ReadinessGates: []v1alpha1.ReadinessGate{
+		{Type: v1alpha1.ReadinessGateHTTPProbe, Target: server.URL + "/healthz"},
+		{Type: v1alpha1.ReadinessGateHTTPProbe, Target: server.URL + "/portal", Expected: "5xx"},
+		{Type: v1alpha1.ReadinessGateResourceCondition, Target: releaseTarget},
+		{Type: v1alpha1.ReadinessGateResourceCondition, Target: "helm.toolkit.fluxcd.io/v2/HelmRelease/platform-mesh-system/iam", Expected: "Ready=False"},
+	}}}
+
+	failed := sub.evaluateReadinessGates(ctx, inst, log)
+	assert.Equal(t, []string{"resourceCondition " + releaseTarget}, failed)
+	require.Len(t, inst.Status.ReadinessGates, 4)
+	assert.False(t, inst.Status.ReadinessGates[2].Passed)
+	assert.Equal(t, "condition Ready is not True", inst.Status.ReadinessGates[2].Message)
+	assert.Equal(t, "Warning ReadinessGateFailed Readiness gate resourceCondition "+releaseTarget+" did not pass: condition Ready is not True", <-rec.Events)
+
+	// A gate that keeps failing keeps its transition time and is not
+	// reported again.
+	transition := metav1.NewTime(inst.Status.ReadinessGates[2].LastTransitionTime.Add(-time.Hour))
+	inst.Status.ReadinessGates[2].LastTransitionTime = transition
+	failed = sub.evaluateReadinessGates(ctx, inst, log)
+	assert.Len(t, failed, 1)
+	assert.Equal(t, transition, inst.Status.ReadinessGates[2].LastTransitionTime)
+	assert.Empty(t, rec.Events)
+
+	require.NoError(t, unstructured.SetNestedSlice(release.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions"))
+	require.NoError(t, cl.Update(ctx, release))
+	inst.Spec.ReadinessGates = inst.Spec.ReadinessGates[:3]
+	assert.Empty(t, sub.evaluateReadinessGates(ctx, inst, log))
+	assert.Len(t, inst.Status.ReadinessGates, 3)
+	assert.True(t, inst.Status.ReadinessGates[2].Passed)
+
+	inst.Spec.ReadinessGates = nil
+	assert.Empty(t, sub.evaluateReadinessGates(ctx, inst, log))
+	assert.Nil(t, inst.Status.ReadinessGates)
+}
@@ -0,0 +1,53 @@
+package subroutines
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// reconcileTriggerBufferSize is the number of requests a ReconcileTrigger
+// holds until the controller takes them.
+const reconcileTriggerBufferSize = 64
+
+// ReconcileTrigger requests reconciles of PlatformMesh instances from
+// runnables outside the controller, such as the EndpointSliceWatcher and the
+// DriftDetector. The controller watches Source, so requesting a reconcile does
+// not write to the instance.
+type ReconcileTrigger struct {
+	events chan event.GenericEvent
+}
+
+func NewReconcileTrigger() *ReconcileTrigger {
+	return &ReconcileTrigger{events: make(chan event.GenericEvent, reconcileTriggerBufferSize)}
+}
+
+// Request enqueues a reconcile of the PlatformMesh key. It blocks while the
+// buffer is full and returns the error of ctx when ctx ends first.
+func (t *ReconcileTrigger) Request(ctx context.Context, key types.NamespacedName) error {
+	inst := &corev1alpha1.PlatformMesh{}
+	inst.Namespace, inst.Name = key.Namespace, key.Name
+	select {
+	case t.events <- event.GenericEvent{Object: inst}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Source returns the source of the requested reconciles for the controller of
+// the PlatformMesh instances in the local cluster.
+func (t *ReconcileTrigger) Source() source.TypedSource[mcreconcile.Request] {
+	return source.TypedChannel[client.Object, mcreconcile.Request](t.events, handler.TypedEnqueueRequestsFromMapFunc[client.Object, mcreconcile.Request](
+		func(_ context.Context, obj client.Object) []mcreconcile.Request {
+			return []mcreconcile.Request{{Request: reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)}}}
+		}))
+}
@@ -0,0 +1,161 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+)
+
+const (
+	RecoverySubroutineName = "RecoverySubroutine"
+	// RecoveryConditionType is False while a recovery requested with
+	// corev1alpha1.RecoverAnnotation runs and True once it completed.
+	RecoveryConditionType = "Recovery"
+)
+
+// recoverySecretPurposes are the purposes of the generated secrets a recovery
+// deletes, so the subroutines create them from scratch.
+var recoverySecretPurposes = []string{
+	SecretPurposeProvider, SecretPurposeScoped, SecretPurposeInitializer, SecretPurposeWebhook, SecretPurposeMergedKubeconfig,
+}
+
+// RecoveryInProgress reports whether a recovery of inst runs. Subroutines
+// bypass their caches while it does.
+func RecoveryInProgress(inst *corev1alpha1.PlatformMesh) bool {
+	return inst.Status.Recovery != nil && inst.Status.Recovery.Phase == corev1alpha1.RecoveryPhaseInProgress
+}
+
+// StartRecovery starts the recovery of the PlatformMesh key if it carries
+// corev1alpha1.RecoverAnnotation: it deletes the generated secrets of
+// recoverySecretPurposes, marks the recovery in progress and removes the
+// annotation, so the following reconciliation rebuilds everything. It reports
+// whether a recovery was started.
+func StartRecovery(ctx context.Context, cl client.Client, key client.ObjectKey) (bool, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	inst := &corev1alpha1.PlatformMesh{}
+	if err := cl.Get(ctx, key, inst); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if inst.Annotations[corev1alpha1.RecoverAnnotation] != "true" || !inst.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+
+	deleted, err := deleteRecoverySecrets(ctx, cl, inst)
+	if err != nil {
+		return false, err
+	}
+
+	orig := inst.DeepCopy()
+	inst.Status.Recovery = &corev1alpha1.RecoveryStatus{
+		Phase:          corev1alpha1.RecoveryPhaseInProgress,
+		StartedAt:      metav1.Now(),
+		DeletedSecrets: deleted,
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               RecoveryConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             string(corev1alpha1.RecoveryPhaseInProgress),
+		Message:            fmt.Sprintf("Rebuilding derived secrets and kcp wiring, %d generated secrets deleted", len(deleted)),
+		ObservedGeneration: inst.Generation,
+	})
+	if err := cl.Status().Patch(ctx, inst, client.MergeFrom(orig)); err != nil {
+		return false, errors.Wrap(err, "Failed to mark recovery of %s in progress", key)
+	}
+
+	orig = inst.DeepCopy()
+	delete(inst.Annotations, corev1alpha1.RecoverAnnotation)
+	if err := cl.Patch(ctx, inst, client.MergeFrom(orig)); err != nil {
+		return false, errors.Wrap(err, "Failed to remove %s annotation from %s", corev1alpha1.RecoverAnnotation, key)
+	}
+
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonRecoveryStarted, "Recover", "Started recovery, deleted %d generated secrets", len(deleted))
+	log.Info().Str("instance", key.String()).Strs("deletedSecrets", deleted).Msg("Started recovery")
+	return true, nil
+}
+
+// deleteRecoverySecrets deletes the generated secrets of inst with one of
+// recoverySecretPurposes and returns them as namespace/name.
+func deleteRecoverySecrets(ctx context.Context, cl client.Client, inst *corev1alpha1.PlatformMesh) ([]string, error) {
+	list := &corev1.SecretList{}
+	if err := cl.List(ctx, list, client.MatchingLabels{
+		InstanceNameLabel:      inst.Name,
+		InstanceNamespaceLabel: inst.Namespace,
+	}, client.HasLabels{SecretPurposeLabel}); err != nil {
+		return nil, errors.Wrap(err, "Failed to list managed secrets")
+	}
+
+	var deleted []string
+	for i := range list.Items {
+		secret := &list.Items[i]
+		if !slices.Contains(recoverySecretPurposes, secret.Labels[SecretPurposeLabel]) {
+			continue
+		}
+		if err := cl.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+			return deleted, errors.Wrap(err, "Failed to delete secret %s/%s", secret.Namespace, secret.Name)
+		}
+		deleted = append(deleted, secret.Namespace+"/"+secret.Name)
+	}
+	slices.Sort(deleted)
+	return deleted, nil
+}
+
+// RecoverySubroutine completes a recovery started by StartRecovery. It runs
+// after all other subroutines, so it is only reached once they all passed.
+type RecoverySubroutine struct{}
+
+func NewRecoverySubroutine() *RecoverySubroutine {
+	return &RecoverySubroutine{}
+}
+
+func (r *RecoverySubroutine) GetName() string {
+	return RecoverySubroutineName
+}
+
+func (r *RecoverySubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *RecoverySubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *RecoverySubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), "success").Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	if !RecoveryInProgress(inst) {
+		return subroutines.OK(), nil
+	}
+
+	now := metav1.Now()
+	inst.Status.Recovery.Phase = corev1alpha1.RecoveryPhaseCompleted
+	inst.Status.Recovery.CompletedAt = &now
+	took := now.Sub(inst.Status.Recovery.StartedAt.Time).Round(time.Second)
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               RecoveryConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             string(corev1alpha1.RecoveryPhaseCompleted),
+		Message:            fmt.Sprintf("All subroutines completed %s after the recovery started", took),
+		ObservedGeneration: inst.Generation,
+	})
+	recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonRecoveryCompleted, "Recover", "Completed recovery after %s", took)
+	logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName()).Info().Dur("took", took).Msg("Completed recovery")
+	return subroutines.OK(), nil
+}
@@ -0,0 +1,121 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestStartRecovery(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pm",
+		Namespace:   "pm-ns",
+		Annotations: map[string]string{corev1alpha1.RecoverAnnotation: "true"},
+	}}
+	newSecret := func(name, purpose string) *corev1.Secret {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pm-ns"}}
+		setInstanceLabels(secret, inst)
+		LabelSecretPurpose(secret, purpose, "")
+		return secret
+	}
+	other := newSecret("other-provider", SecretPurposeProvider)
+	other.Labels[InstanceNameLabel] = "other"
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		inst,
+		newSecret("iam-kubeconfig", SecretPurposeProvider),
+		newSecret("scoped", SecretPurposeScoped),
+		newSecret("webhook", SecretPurposeWebhook),
+		newSecret("values", SecretPurposeValuesSnapshot),
+		other,
+	).WithStatusSubresource(inst).Build()
+
+	started, err := StartRecovery(ctx, cl, client.ObjectKeyFromObject(inst))
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	for _, name := range []string{"iam-kubeconfig", "scoped", "webhook"} {
+		err := cl.Get(ctx, client.ObjectKey{Name: name, Namespace: "pm-ns"}, &corev1.Secret{})
+		assert.True(t, kerrors.IsNotFound(err), name)
+	}
+	for _, name := range []string{"values", "other-provider"} {
+		assert.NoError(t, cl.Get(ctx, client.ObjectKey{Name: name, Namespace: "pm-ns"}, &corev1.Secret{}), name)
+	}
+
+	got := &corev1alpha1.PlatformMesh{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(inst), got))
+	assert.NotContains(t, got.Annotations, corev1alpha1.RecoverAnnotation)
+	require.NotNil(t, got.Status.Recovery)
+	assert.Equal(t, corev1alpha1.RecoveryPhaseInProgress, got.Status.Recovery.Phase)
+	assert.Equal(t, []string{"pm-ns/iam-kubeconfig", "pm-ns/scoped", "pm-ns/webhook"}, got.Status.Recovery.DeletedSecrets)
+	assert.True(t, RecoveryInProgress(got))
+	cond := apimeta.FindStatusCondition(got.Status.Conditions, RecoveryConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Contains(t, <-rec.Events, EventReasonRecoveryStarted)
+
+	// Without the annotation nothing happens.
+	started, err = StartRecovery(ctx, cl, client.ObjectKeyFromObject(inst))
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	started, err = StartRecovery(ctx, cl, client.ObjectKey{Name: "missing", Namespace: "pm-ns"})
+	require.NoError(t, err)
+	assert.False(t, started)
+}
+
+func TestRecoverySubroutine(t *testing.T) {
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.Background(), rec)
+	sub := NewRecoverySubroutine()
+	assert.Equal(t, RecoverySubroutineName, sub.GetName())
+
+	inst := &corev1alpha1.PlatformMesh{}
+	_, err := sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Nil(t, inst.Status.Recovery)
+	assert.Empty(t, inst.Status.Conditions)
+
+	inst.Status.Recovery = &corev1alpha1.RecoveryStatus{
+		Phase:     corev1alpha1.RecoveryPhaseInProgress,
+		StartedAt: metav1.NewTime(time.Now().Add(-time.Minute)),
+	}
+	_, err = sub.Process(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, corev1alpha1.RecoveryPhaseCompleted, inst.Status.Recovery.Phase)
+	assert.NotNil(t, inst.Status.Recovery.CompletedAt)
+	assert.False(t, RecoveryInProgress(inst))
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, RecoveryConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Contains(t, <-rec.Events, EventReasonRecoveryCompleted)
+
+	res, err := sub.Finalize(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, subroutines.OK(), res)
+}
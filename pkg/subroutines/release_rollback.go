@@ -0,0 +1,305 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// DegradedRollbackConditionType is True while at least one component
+// HelmRelease is rolled back to its last Ready spec.
+const DegradedRollbackConditionType = "DegradedRollback"
+
+// releaseRollback rolls a component HelmRelease back to the last rendered
+// spec it was Ready with, once flux reports Ready=False for the changed spec
+// for longer than after since it was applied. The inventory references the
+// specs by digest, the specs themselves hold values and are kept in the last
+// good Secret. The rollback holds until the rendered spec changes again, so a
+// broken change is not retried in a loop. A nil *releaseRollback rolls
+// nothing back.
+type releaseRollback struct {
+	// inst the rollbacks are recorded on.
+	inst  *corev1alpha1.PlatformMesh
+	after time.Duration
+	now   time.Time
+	// previous is the recorded inventory, by key.
+	previous map[string]inventoryEntry
+	// specs are the last good specs by digest, as loaded from the Secret and
+	// added by this reconcile.
+	specs map[string]map[string]interface{}
+	// referenced are the digests of the specs the inventory references.
+	referenced map[string]bool
+	// seen are the keys of the HelmReleases this reconcile rendered.
+	seen       map[string]bool
+	rolledBack []string
+}
+
+// newReleaseRollback returns the rollback of the HelmReleases of inst with
+// the recorded inventory previous and the last good specs, and nil when
+// after is not positive.
+func newReleaseRollback(inst *corev1alpha1.PlatformMesh, after time.Duration, now time.Time, previous []inventoryEntry, specs map[string]map[string]interface{}) *releaseRollback {
+	if after <= 0 {
+		return nil
+	}
+	rb := &releaseRollback{inst: inst, after: after, now: now, previous: map[string]inventoryEntry{},
+		specs: map[string]map[string]interface{}{}, referenced: map[string]bool{}, seen: map[string]bool{}}
+	for _, e := range previous {
+		rb.previous[e.key()] = e
+	}
+	for digest, spec := range specs {
+		rb.specs[digest] = spec
+	}
+	return rb
+}
+
+// postProcess wraps next. It remembers the rendered spec of a component
+// HelmRelease that is Ready with it in inv, and replaces the rendered spec
+// with the remembered one while the release is rolled back.
+func (rb *releaseRollback) postProcess(k8sClient client.Client, inv *inventory, next func(ctx context.Context, obj *unstructured.Unstructured) error) func(ctx context.Context, obj *unstructured.Unstructured) error {
+	return func(ctx context.Context, obj *unstructured.Unstructured) error {
+		component := renderedComponentFrom(ctx)
+		if next != nil {
+			if err := next(ctx, obj); err != nil {
+				return err
+			}
+		}
+		if rb == nil || obj.GroupVersionKind() != helmReleaseGVK {
+			return nil
+		}
+
+		e := inventoryEntry{Cluster: inv.cluster, APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		prev := rb.previous[e.key()]
+		rb.seen[e.key()] = true
+		// A spec missing from the Secret, e.g. as its write failed, leaves
+		// nothing to roll back to.
+		lastGood := rb.specs[prev.LastGoodDigest]
+		if lastGood != nil {
+			e.LastGoodDigest = prev.LastGoodDigest
+		}
+		defer func() {
+			if e.LastGoodDigest != "" {
+				rb.referenced[e.LastGoodDigest] = true
+			}
+		}()
+		rendered, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		digest, err := valuesChecksum(rendered)
+		if err != nil {
+			return errors.Wrap(err, "Failed to hash HelmRelease %s/%s", obj.GetNamespace(), obj.GetName())
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(helmReleaseGVK)
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), live)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			inv.remember(e)
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to get HelmRelease %s/%s", obj.GetNamespace(), obj.GetName())
+		}
+		liveSpec, _, _ := unstructured.NestedFieldNoCopy(live.Object, "spec")
+		applied := len(appendDrift(nil, "spec", rendered, liveSpec)) == 0
+		// The time a failure is measured from is when the rendered spec was
+		// first seen applied, not when it was rendered, as applying it may
+		// have been deferred.
+		e.AppliedDigest, e.AppliedAt = prev.AppliedDigest, prev.AppliedAt
+		if applied && (prev.AppliedDigest != digest || prev.AppliedAt == nil) {
+			e.AppliedDigest, e.AppliedAt = digest, &metav1.Time{Time: rb.now}
+		}
+
+		switch {
+		case lastGood != nil && prev.FailedDigest == digest:
+			// The spec that failed is still rendered, the rollback holds.
+			e.FailedDigest = digest
+			rb.rollBack(obj, component, lastGood)
+		case applied && helmReleaseReady(live):
+			e.LastGoodDigest = digest
+			rb.specs[digest] = rendered
+		case lastGood != nil && applied && rb.failedFor(live, e.AppliedAt) > rb.after && len(appendDrift(nil, "spec", lastGood, liveSpec)) > 0:
+			// A rollback changes the chart or values like an upgrade.
+			target := fmt.Sprintf("HelmRelease %s/%s", obj.GetNamespace(), obj.GetName())
+			if !maintenanceGateFrom(ctx).allow(ctx, corev1alpha1.MaintenanceUpgrade, target, "roll back to the last Ready spec") {
+				break
+			}
+			e.FailedDigest = digest
+			rb.rollBack(obj, component, lastGood)
+			logger.LoadLoggerFromContext(ctx).Warn().Str("component", component).Str("name", obj.GetName()).
+				Msg("Rolling HelmRelease back to its last Ready spec")
+			recordEvent(ctx, rb.inst, corev1.EventTypeWarning, EventReasonReleaseRolledBack, "Rollback",
+				"HelmRelease %s/%s was not Ready for %s after a change, rolled back to its last Ready spec: %s",
+				obj.GetNamespace(), obj.GetName(), rb.after, readyMessage(live))
+		}
+		inv.remember(e)
+		return nil
+	}
+}
+
+// rollBack replaces the spec of obj with spec.
+func (rb *releaseRollback) rollBack(obj *unstructured.Unstructured, component string, spec map[string]interface{}) {
+	obj.Object["spec"] = spec
+	if !slices.Contains(rb.rolledBack, component) {
+		rb.rolledBack = append(rb.rolledBack, component)
+	}
+}
+
+// failedFor returns how long the HelmRelease live reports Ready=False for its
+// current generation since its spec was applied at appliedAt, and zero
+// otherwise. The Ready condition may have turned False before the change, so
+// the later of both times counts.
+func (rb *releaseRollback) failedFor(live *unstructured.Unstructured, appliedAt *metav1.Time) time.Duration {
+	observed, _, _ := unstructured.NestedInt64(live.Object, "status", "observedGeneration")
+	if observed < live.GetGeneration() || appliedAt == nil {
+		return 0
+	}
+	cond := readyCondition(live)
+	if cond == nil || cond.Status != metav1.ConditionFalse {
+		return 0
+	}
+	since := appliedAt.Time
+	if cond.LastTransitionTime.After(since) {
+		since = cond.LastTransitionTime.Time
+	}
+	return rb.now.Sub(since)
+}
+
+// lastGoodSpecs returns the specs the inventory references once it is
+// recorded: those of the HelmReleases rendered by this reconcile, and those of
+// the previous inventory that were not rendered, as a component whose
+// templates failed keeps its objects.
+func (rb *releaseRollback) lastGoodSpecs() map[string]map[string]interface{} {
+	specs := map[string]map[string]interface{}{}
+	for digest := range rb.referenced {
+		if rb.specs[digest] != nil {
+			specs[digest] = rb.specs[digest]
+		}
+	}
+	for key, e := range rb.previous {
+		if !rb.seen[key] && rb.specs[e.LastGoodDigest] != nil {
+			specs[e.LastGoodDigest] = rb.specs[e.LastGoodDigest]
+		}
+	}
+	return specs
+}
+
+func lastGoodSecretName(inst *corev1alpha1.PlatformMesh) string {
+	return inst.Name + "-last-good-specs"
+}
+
+// loadLastGoodSpecs returns the last good specs of the HelmReleases of inst by
+// digest.
+func (r *DeploymentSubroutine) loadLastGoodSpecs(ctx context.Context, inst *corev1alpha1.PlatformMesh) (map[string]map[string]interface{}, error) {
+	secret := &corev1.Secret{}
+	err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: lastGoodSecretName(inst), Namespace: inst.Namespace}, secret)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get last good HelmRelease specs")
+	}
+	specs := map[string]map[string]interface{}{}
+	for digest, data := range secret.Data {
+		var spec map[string]interface{}
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse last good HelmRelease spec %s", digest)
+		}
+		specs[digest] = spec
+	}
+	return specs, nil
+}
+
+// storeLastGoodSpecs writes the specs rb references to the last good Secret
+// before the inventory referencing them is recorded. Specs no longer
+// referenced are dropped.
+func (r *DeploymentSubroutine) storeLastGoodSpecs(ctx context.Context, inst *corev1alpha1.PlatformMesh, rb *releaseRollback) error {
+	if rb == nil {
+		return nil
+	}
+	data := map[string][]byte{}
+	for digest, spec := range rb.lastGoodSpecs() {
+		b, err := json.Marshal(spec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to marshal last good HelmRelease spec %s", digest)
+		}
+		data[digest] = b
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: lastGoodSecretName(inst), Namespace: inst.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.clientRuntime, secret, func() error {
+		setInstanceLabels(secret, inst)
+		LabelSecretPurpose(secret, SecretPurposeLastGoodSpecs, "")
+		if !metav1.IsControlledBy(secret, inst) {
+			secret.SetOwnerReferences(append(secret.GetOwnerReferences(), *metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))))
+		}
+		SetSecretData(secret, data)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to write last good HelmRelease specs")
+	}
+	return nil
+}
+
+// report sets the DegradedRollback condition of inst from the releases rolled
+// back by this reconcile, and removes it when none is.
+func (rb *releaseRollback) report(inst *corev1alpha1.PlatformMesh) {
+	if rb == nil || len(rb.rolledBack) == 0 {
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, DegradedRollbackConditionType)
+		return
+	}
+	components := slices.Clone(rb.rolledBack)
+	sort.Strings(components)
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               DegradedRollbackConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RolledBack",
+		Message:            fmt.Sprintf("HelmReleases rolled back to their last Ready spec until the rendered spec changes: %s", strings.Join(components, ", ")),
+		ObservedGeneration: inst.Generation,
+	})
+}
+
+// readyCondition returns the Ready condition of the flux object obj.
+func readyCondition(obj *unstructured.Unstructured) *metav1.Condition {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		m, _ := c.(map[string]interface{})
+		if m["type"] != "Ready" {
+			continue
+		}
+		cond := &metav1.Condition{Type: "Ready"}
+		status, _ := m["status"].(string)
+		cond.Status = metav1.ConditionStatus(status)
+		cond.Reason, _ = m["reason"].(string)
+		cond.Message, _ = m["message"].(string)
+		if ts, _ := m["lastTransitionTime"].(string); ts != "" {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				cond.LastTransitionTime = metav1.NewTime(t)
+			}
+		}
+		return cond
+	}
+	return nil
+}
+
+// readyMessage returns the message of the Ready condition of obj.
+func readyMessage(obj *unstructured.Unstructured) string {
+	if cond := readyCondition(obj); cond != nil {
+		return cond.Message
+	}
+	return ""
+}
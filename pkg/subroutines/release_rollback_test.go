@@ -0,0 +1,163 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+func TestReleaseRollback(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := withRenderedComponent(WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec), "portal")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	inst := maintenanceInstance()
+
+	// live is the portal release at version, Ready with status since the
+	// given time.
+	live := func(version, status string, since time.Time) *unstructured.Unstructured {
+		release := newUpgradeHelmRelease(t, "portal", version, status)
+		require.NoError(t, unstructured.SetNestedSlice(release.Object, []interface{}{map[string]interface{}{
+			"type": "Ready", "status": status, "message": "upgrade retries exhausted", "lastTransitionTime": since.Format(time.RFC3339),
+		}}, "status", "conditions"))
+		return release
+	}
+	render := func(version string) *unstructured.Unstructured {
+		return newAdoptionHelmRelease(t, "portal", map[string]interface{}{
+			"chart": map[string]interface{}{"spec": map[string]interface{}{"chart": "portal", "version": version}},
+		})
+	}
+	version := func(obj *unstructured.Unstructured) string {
+		v, _, _ := unstructured.NestedString(obj.Object, "spec", "chart", "spec", "version")
+		return v
+	}
+	// apply runs the rollback of one reconcile against the live release and
+	// returns the recorded inventory entry and the object to apply.
+	apply := func(rb *releaseRollback, release *unstructured.Unstructured, rendered string) (inventoryEntry, *unstructured.Unstructured) {
+		cl := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(release).Build()
+		inv := newInventory("components-infra", plan.ClusterInfra)
+		obj := render(rendered)
+		require.NoError(t, rb.postProcess(cl, inv, nil)(ctx, obj))
+		inv.add(inv.cluster, "portal", obj)
+		require.Len(t, inv.entries, 1)
+		return inv.entries[0], obj
+	}
+
+	// A release that is Ready with the rendered spec is remembered by digest,
+	// the spec itself goes to the last good Secret.
+	rb := newReleaseRollback(inst, 10*time.Minute, now, nil, nil)
+	good, obj := apply(rb, live("1.0.0", "True", now), "1.0.0")
+	assert.Equal(t, "1.0.0", version(obj))
+	require.NotEmpty(t, good.LastGoodDigest)
+	assert.Empty(t, good.FailedDigest)
+	specs := rb.lastGoodSpecs()
+	require.Contains(t, specs, good.LastGoodDigest)
+	assert.Equal(t, "1.0.0", version(&unstructured.Unstructured{Object: map[string]interface{}{"spec": specs[good.LastGoodDigest]}}))
+
+	// A change is measured from when it was applied, not from when Ready
+	// turned False before it.
+	changed, obj := apply(newReleaseRollback(inst, 10*time.Minute, now, []inventoryEntry{good}, specs), live("1.1.0", "False", now.Add(-time.Hour)), "1.1.0")
+	assert.Equal(t, "1.1.0", version(obj))
+	assert.Equal(t, good.LastGoodDigest, changed.LastGoodDigest)
+	assert.Empty(t, changed.FailedDigest)
+	require.NotNil(t, changed.AppliedAt)
+	assert.Equal(t, now, changed.AppliedAt.Time)
+
+	// Outside of the maintenance windows the rollback is deferred.
+	later := now.Add(20 * time.Minute)
+	ungated := ctx
+	ctx, gate := withMaintenanceGate(ctx, maintenanceInstance(saturdayNights), later)
+	entry, obj := apply(newReleaseRollback(inst, 10*time.Minute, later, []inventoryEntry{changed}, specs), live("1.1.0", "False", now.Add(-time.Hour)), "1.1.0")
+	assert.Equal(t, "1.1.0", version(obj))
+	assert.Empty(t, entry.FailedDigest)
+	require.Len(t, gate.deferred, 1)
+	assert.Equal(t, corev1alpha1.MaintenanceUpgrade, gate.deferred[0].Operation)
+	assert.Contains(t, <-rec.Events, EventReasonOperationDeferred)
+	ctx = ungated
+
+	// After the window it is rolled back.
+	rb = newReleaseRollback(inst, 10*time.Minute, later, []inventoryEntry{changed}, specs)
+	failed, obj := apply(rb, live("1.1.0", "False", now.Add(-time.Hour)), "1.1.0")
+	assert.Equal(t, "1.0.0", version(obj))
+	assert.NotEmpty(t, failed.FailedDigest)
+	assert.Contains(t, <-rec.Events, EventReasonReleaseRolledBack)
+	rb.report(inst)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, DegradedRollbackConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Contains(t, cond.Message, "portal")
+
+	// The rollback holds while the failed spec is rendered, even once the
+	// release is Ready again.
+	entry, obj = apply(newReleaseRollback(inst, 10*time.Minute, later, []inventoryEntry{failed}, specs), live("1.0.0", "True", later), "1.1.0")
+	assert.Equal(t, "1.0.0", version(obj))
+	assert.Equal(t, failed.FailedDigest, entry.FailedDigest)
+
+	// Without its spec in the Secret there is nothing to roll back to.
+	entry, obj = apply(newReleaseRollback(inst, 10*time.Minute, later, []inventoryEntry{failed}, nil), live("1.0.0", "True", later), "1.1.0")
+	assert.Equal(t, "1.1.0", version(obj))
+	assert.Empty(t, entry.LastGoodDigest)
+
+	// A new rendered spec is tried.
+	rb = newReleaseRollback(inst, 10*time.Minute, later, []inventoryEntry{failed}, specs)
+	entry, obj = apply(rb, live("1.0.0", "True", later), "1.2.0")
+	assert.Equal(t, "1.2.0", version(obj))
+	assert.Empty(t, entry.FailedDigest)
+	rb.report(inst)
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, DegradedRollbackConditionType))
+
+	// Without a window nothing is rolled back.
+	rb = newReleaseRollback(inst, 0, now, []inventoryEntry{good}, specs)
+	assert.Nil(t, rb)
+	_, obj = apply(rb, live("1.1.0", "False", now.Add(-time.Hour)), "1.1.0")
+	assert.Equal(t, "1.1.0", version(obj))
+}
+
+func TestLastGoodSpecs(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	r := &DeploymentSubroutine{clientRuntime: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	inst := maintenanceInstance()
+
+	specs, err := r.loadLastGoodSpecs(ctx, inst)
+	require.NoError(t, err)
+	assert.Empty(t, specs)
+
+	// Specs of releases that were not rendered, like those of a component
+	// whose templates failed, are kept, unreferenced ones are dropped.
+	kept := inventoryEntry{Cluster: plan.ClusterInfra, APIVersion: "helm.toolkit.fluxcd.io/v2", Kind: "HelmRelease", Namespace: "default", Name: "iam", LastGoodDigest: "kept"}
+	rb := newReleaseRollback(inst, time.Minute, time.Now(), []inventoryEntry{kept}, map[string]map[string]interface{}{
+		"kept":  {"chart": "iam"},
+		"stale": {"chart": "old"},
+	})
+	require.NoError(t, r.storeLastGoodSpecs(ctx, inst, rb))
+
+	specs, err = r.loadLastGoodSpecs(ctx, inst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{"kept": {"chart": "iam"}}, specs)
+	secret := &corev1.Secret{}
+	require.NoError(t, r.clientRuntime.Get(ctx, types.NamespacedName{Name: "platform-mesh-last-good-specs", Namespace: inst.Namespace}, secret))
+	assert.Equal(t, SecretPurposeLastGoodSpecs, secret.Labels[SecretPurposeLabel])
+	assert.True(t, metav1.IsControlledBy(secret, inst))
+
+	require.NoError(t, r.storeLastGoodSpecs(ctx, inst, nil))
+}
@@ -0,0 +1,224 @@
+package subroutines
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+type renderRecordKey struct{}
+
+// renderRecord collects the objects rendered in one reconcile and the result
+// of applying them, for the RenderSnapshot of the reconcile.
+type renderRecord struct {
+	mu      sync.Mutex
+	objects []corev1alpha1.RenderedObject
+	applied int
+	failed  []corev1alpha1.RenderedObjectFailure
+	// exportManifests keeps copies of the rendered objects in exported, for
+	// writeRenderedManifests.
+	exportManifests bool
+	exported        []exportedManifest
+}
+
+// withRenderRecord returns ctx carrying an empty renderRecord.
+func withRenderRecord(ctx context.Context) context.Context {
+	return context.WithValue(ctx, renderRecordKey{}, &renderRecord{})
+}
+
+// renderRecordFrom returns the renderRecord of ctx. A nil *renderRecord
+// records nothing.
+func renderRecordFrom(ctx context.Context) *renderRecord {
+	rec, _ := ctx.Value(renderRecordKey{}).(*renderRecord)
+	return rec
+}
+
+// templateCluster returns the cluster objects rendered from templateType are
+// applied to. The runtime templates apply OCM Resources to the runtime
+// cluster and everything else to the infra cluster. Templates rendered for a
+// cluster of spec.runtimeClusters are applied to that cluster.
+func templateCluster(templateType string, obj *unstructured.Unstructured) string {
+	if _, cluster, ok := strings.Cut(templateType, ":"); ok {
+		return cluster
+	}
+	switch templateType {
+	case "components-runtime":
+		return plan.ClusterRuntime
+	case "runtime":
+		if obj.GetAPIVersion() == "delivery.ocm.software/v1alpha1" && obj.GetKind() == "Resource" {
+			return plan.ClusterRuntime
+		}
+	}
+	return plan.ClusterInfra
+}
+
+// rendered records manifests as rendered from templateType.
+func (rec *renderRecord) rendered(templateType string, manifests []renderedManifest) error {
+	if rec == nil {
+		return nil
+	}
+	objects := make([]corev1alpha1.RenderedObject, 0, len(manifests))
+	var exported []exportedManifest
+	for _, m := range manifests {
+		if rec.exportManifests {
+			exported = append(exported, exportedManifest{templateType: templateType, component: m.component, obj: m.obj.DeepCopy()})
+		}
+		data, err := json.Marshal(m.obj.Object)
+		if err != nil {
+			return errors.Wrap(err, "Failed to hash %s %s", m.obj.GetKind(), m.obj.GetName())
+		}
+		sum := sha256.Sum256(data)
+		objects = append(objects, corev1alpha1.RenderedObject{
+			TemplateType: templateType,
+			Cluster:      templateCluster(templateType, m.obj),
+			Component:    m.component,
+			APIVersion:   m.obj.GetAPIVersion(),
+			Kind:         m.obj.GetKind(),
+			Namespace:    m.obj.GetNamespace(),
+			Name:         m.obj.GetName(),
+			Hash:         hex.EncodeToString(sum[:]),
+		})
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.objects = append(rec.objects, objects...)
+	rec.exported = append(rec.exported, exported...)
+	return nil
+}
+
+// result records the result of applying m.
+func (rec *renderRecord) result(templateType string, m renderedManifest, err error) {
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err == nil {
+		rec.applied++
+		return
+	}
+	rec.failed = append(rec.failed, corev1alpha1.RenderedObjectFailure{
+		TemplateType: templateType,
+		Kind:         m.obj.GetKind(),
+		Namespace:    m.obj.GetNamespace(),
+		Name:         m.obj.GetName(),
+		Message:      err.Error(),
+	})
+}
+
+// digest hashes the rendered objects independent of their order.
+func (rec *renderRecord) digest() string {
+	return renderedObjectsDigest(rec.objects)
+}
+
+// clusterDigest hashes the objects rendered for cluster. It is empty without
+// a record.
+func (rec *renderRecord) clusterDigest(cluster string) string {
+	if rec == nil {
+		return ""
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var objects []corev1alpha1.RenderedObject
+	for _, o := range rec.objects {
+		if o.Cluster == cluster {
+			objects = append(objects, o)
+		}
+	}
+	return renderedObjectsDigest(objects)
+}
+
+func renderedObjectsDigest(objects []corev1alpha1.RenderedObject) string {
+	hashes := make([]string, 0, len(objects))
+	for _, o := range objects {
+		hashes = append(hashes, o.TemplateType+"/"+o.Cluster+"/"+o.APIVersion+"/"+o.Kind+"/"+o.Namespace+"/"+o.Name+"/"+o.Hash)
+	}
+	slices.Sort(hashes)
+	sum := sha256.New()
+	for _, h := range hashes {
+		sum.Write([]byte(h + "\n"))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// writeRenderSnapshot records rec as a RenderSnapshot of inst. When the newest
+// snapshot has the same generation and digest only its status is updated.
+// Snapshots beyond the configured number are deleted, oldest first.
+func (r *DeploymentSubroutine) writeRenderSnapshot(ctx context.Context, inst *corev1alpha1.PlatformMesh, rec *renderRecord, log *logger.Logger) error {
+	limit := r.cfgOperator.Subroutines.Deployment.RenderSnapshots
+	if rec == nil || limit <= 0 || len(rec.objects) == 0 {
+		return nil
+	}
+	list := &corev1alpha1.RenderSnapshotList{}
+	err := r.clientRuntime.List(ctx, list, client.InNamespace(inst.Namespace), client.MatchingLabels{
+		InstanceNameLabel:      inst.Name,
+		InstanceNamespaceLabel: inst.Namespace,
+	})
+	if apimeta.IsNoMatchError(err) {
+		log.Debug().Msg("RenderSnapshot CRD is not installed, skipping the render snapshot")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to list render snapshots")
+	}
+	snapshots := list.Items
+	// Newest first
+	slices.SortFunc(snapshots, func(a, b corev1alpha1.RenderSnapshot) int {
+		return cmp.Or(b.CreationTimestamp.Time.Compare(a.CreationTimestamp.Time), cmp.Compare(b.Name, a.Name))
+	})
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	digest := rec.digest()
+	var current *corev1alpha1.RenderSnapshot
+	if len(snapshots) > 0 && snapshots[0].Spec.Generation == inst.Generation && snapshots[0].Spec.Digest == digest {
+		current, snapshots = &snapshots[0], snapshots[1:]
+	} else {
+		current = &corev1alpha1.RenderSnapshot{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: inst.Name + "-", Namespace: inst.Namespace},
+			Spec: corev1alpha1.RenderSnapshotSpec{
+				Instance:   inst.Name,
+				Generation: inst.Generation,
+				Digest:     digest,
+				Objects:    rec.objects,
+			},
+		}
+		setInstanceLabels(current, inst)
+		current.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))})
+		if err := r.clientRuntime.Create(ctx, current); err != nil {
+			return errors.Wrap(err, "Failed to create render snapshot")
+		}
+	}
+
+	now := metav1.Now()
+	current.Status = corev1alpha1.RenderSnapshotStatus{Applied: rec.applied, Failed: rec.failed, LastApplied: &now}
+	if err := r.clientRuntime.Status().Update(ctx, current); err != nil {
+		return errors.Wrap(err, "Failed to update render snapshot %s", current.Name)
+	}
+
+	// The snapshot just written is always kept.
+	for i := range snapshots {
+		if i < limit-1 {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.clientRuntime.Delete(ctx, &snapshots[i])); err != nil {
+			return errors.Wrap(err, "Failed to delete render snapshot %s", snapshots[i].Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+func renderSnapshotManifest(apiVersion, kind, name string) renderedManifest {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace("pm-ns")
+	return renderedManifest{path: name + ".yaml", obj: obj}
+}
+
+func TestRenderRecord(t *testing.T) {
+	resource := renderSnapshotManifest("delivery.ocm.software/v1alpha1", "Resource", "iam")
+	release := renderSnapshotManifest("helm.toolkit.fluxcd.io/v2", "HelmRelease", "iam")
+
+	ctx := withRenderRecord(context.Background())
+	rec := renderRecordFrom(ctx)
+	require.NoError(t, rec.rendered("runtime", []renderedManifest{resource, release}))
+	rec.result("runtime", resource, nil)
+	rec.result("runtime", release, assert.AnError)
+
+	require.Len(t, rec.objects, 2)
+	assert.Equal(t, plan.ClusterRuntime, rec.objects[0].Cluster)
+	assert.Equal(t, plan.ClusterInfra, rec.objects[1].Cluster)
+	assert.Len(t, rec.objects[0].Hash, 64)
+	assert.NotEqual(t, rec.objects[0].Hash, rec.objects[1].Hash)
+	assert.Equal(t, 1, rec.applied)
+	assert.Equal(t, []v1alpha1.RenderedObjectFailure{{
+		TemplateType: "runtime", Kind: "HelmRelease", Namespace: "pm-ns", Name: "iam", Message: assert.AnError.Error(),
+	}}, rec.failed)
+
+	// The digest does not depend on the order objects were rendered in.
+	reordered := &renderRecord{}
+	require.NoError(t, reordered.rendered("runtime", []renderedManifest{release, resource}))
+	assert.Equal(t, rec.digest(), reordered.digest())
+
+	// Without a record in the context nothing is recorded.
+	none := renderRecordFrom(context.Background())
+	assert.NoError(t, none.rendered("runtime", []renderedManifest{resource}))
+	none.result("runtime", resource, nil)
+}
+
+func TestWriteRenderSnapshot(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns", UID: "uid", Generation: 4}}
+	snapshot := func(name string, age time.Duration, generation int64, digest string) *v1alpha1.RenderSnapshot {
+		s := &v1alpha1.RenderSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pm-ns", CreationTimestamp: metav1.NewTime(time.Now().Add(-age))},
+			Spec:       v1alpha1.RenderSnapshotSpec{Instance: "pm", Generation: generation, Digest: digest},
+		}
+		setInstanceLabels(s, inst)
+		return s
+	}
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	newRecord := func() *renderRecord {
+		rec := &renderRecord{}
+		m := renderSnapshotManifest("helm.toolkit.fluxcd.io/v2", "HelmRelease", "iam")
+		require.NoError(t, rec.rendered("components-infra", []renderedManifest{m}))
+		rec.result("components-infra", m, nil)
+		return rec
+	}
+	list := func(cl client.Client) []v1alpha1.RenderSnapshot {
+		l := &v1alpha1.RenderSnapshotList{}
+		require.NoError(t, cl.List(context.Background(), l, client.InNamespace("pm-ns")))
+		return l.Items
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.RenderSnapshot{}).WithObjects(
+		snapshot("pm-old", 3*time.Hour, 2, "a"),
+		snapshot("pm-older", 4*time.Hour, 1, "b"),
+		snapshot("pm-newer", time.Hour, 3, "c"),
+	).Build()
+	cfg := &config.OperatorConfig{}
+	cfg.Subroutines.Deployment.RenderSnapshots = 2
+	sub := &DeploymentSubroutine{clientRuntime: cl, cfgOperator: cfg}
+
+	require.NoError(t, sub.writeRenderSnapshot(context.Background(), inst, newRecord(), log))
+	snapshots := list(cl)
+	require.Len(t, snapshots, 2)
+	var created v1alpha1.RenderSnapshot
+	for _, s := range snapshots {
+		if s.Name != "pm-newer" {
+			created = s
+		}
+	}
+	assert.Equal(t, "pm", created.Spec.Instance)
+	assert.Equal(t, int64(4), created.Spec.Generation)
+	assert.Len(t, created.Spec.Objects, 1)
+	assert.Equal(t, 1, created.Status.Applied)
+	assert.NotNil(t, created.Status.LastApplied)
+	assert.True(t, metav1.IsControlledBy(&created, inst))
+	assert.Equal(t, instanceKey(inst), labeledInstance(&created))
+
+	// An unchanged render only updates the status of the newest snapshot.
+	digest := newRecord().digest()
+	cl = fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.RenderSnapshot{}).WithObjects(
+		snapshot("pm-current", time.Hour, 4, digest),
+	).Build()
+	sub.clientRuntime = cl
+	rec := newRecord()
+	m := renderSnapshotManifest("v1", "ConfigMap", "values")
+	rec.result("components-infra", m, assert.AnError)
+	require.NoError(t, sub.writeRenderSnapshot(context.Background(), inst, rec, log))
+	snapshots = list(cl)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "pm-current", snapshots[0].Name)
+	assert.Len(t, snapshots[0].Status.Failed, 1)
+
+	// Disabled snapshots write nothing.
+	cfg.Subroutines.Deployment.RenderSnapshots = 0
+	sub.clientRuntime = fake.NewClientBuilder().WithScheme(scheme).Build()
+	require.NoError(t, sub.writeRenderSnapshot(context.Background(), inst, newRecord(), log))
+	assert.Empty(t, list(sub.clientRuntime))
+}
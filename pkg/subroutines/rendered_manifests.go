@@ -0,0 +1,289 @@
+package subroutines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	renderedManifestsConfigMapSuffix = "-rendered-manifests"
+
+	// RenderedGenerationLabel is set on the rendered manifests ConfigMap to
+	// the generation of the instance they were rendered for.
+	RenderedGenerationLabel = "core.platform-mesh.io/rendered-generation"
+	// RenderedProfileHashLabel is set on the rendered manifests ConfigMap to
+	// the first 16 characters of status.profileHash of the instance.
+	RenderedProfileHashLabel = "core.platform-mesh.io/rendered-profile-hash"
+
+	// RenderedManifestsOmittedAnnotation lists the keys of the template types
+	// left out of the rendered manifests ConfigMap, as they did not fit even
+	// gzipped.
+	RenderedManifestsOmittedAnnotation = "core.platform-mesh.io/rendered-omitted"
+
+	// renderedManifestsMaxSize is the size of the rendered manifests above
+	// which they are stored gzipped, to stay below the ConfigMap size limit.
+	// Gzipped manifests above it are left out.
+	renderedManifestsMaxSize = 900 * 1024
+
+	redactedValue = "<redacted>"
+)
+
+func renderedManifestsConfigMapName(inst *corev1alpha1.PlatformMesh) string {
+	return inst.Name + renderedManifestsConfigMapSuffix
+}
+
+// exportedManifest is a rendered object kept for the rendered manifests
+// ConfigMap.
+type exportedManifest struct {
+	templateType string
+	component    string
+	obj          *unstructured.Unstructured
+}
+
+// renderedManifestsKey returns the ConfigMap key of the manifests rendered
+// from templateType. Keys may not contain the colon of runtime cluster
+// template types.
+func renderedManifestsKey(templateType string) string {
+	return strings.ReplaceAll(templateType, ":", "_") + ".yaml"
+}
+
+// renderedManifestsData returns the manifests of exported as one YAML stream
+// per template type, each document headed by its component. The data of
+// Secrets and the values of HelmReleases and Applications are redacted.
+func renderedManifestsData(exported []exportedManifest) (map[string]string, error) {
+	streams := map[string]*strings.Builder{}
+	for _, m := range exported {
+		obj := m.obj
+		switch obj.GroupVersionKind() {
+		case corev1.SchemeGroupVersion.WithKind("Secret"):
+			obj = redactSecret(obj)
+		case helmReleaseGVK, argoApplicationGVK:
+			obj = redactValues(obj)
+		}
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to marshal %s %s", obj.GetKind(), obj.GetName())
+		}
+		key := renderedManifestsKey(m.templateType)
+		b, ok := streams[key]
+		if !ok {
+			b = &strings.Builder{}
+			streams[key] = b
+		}
+		b.WriteString("---\n")
+		if m.component != "" {
+			b.WriteString("# component: " + m.component + "\n")
+		}
+		b.Write(data)
+	}
+	out := make(map[string]string, len(streams))
+	for key, b := range streams {
+		out[key] = b.String()
+	}
+	return out, nil
+}
+
+// redactSecret returns a copy of the Secret obj with the values of its data
+// and stringData replaced.
+func redactSecret(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := obj.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range values {
+			values[k] = redactedValue
+		}
+	}
+	return obj
+}
+
+// redactValues returns a copy of the HelmRelease or Application obj with the
+// values it passes to its chart replaced, keeping their keys. Values are
+// rendered from the profile and may carry credentials.
+func redactValues(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	if obj.GetKind() == "HelmRelease" {
+		redactField(spec, "values")
+		return obj
+	}
+	sources, _ := spec["sources"].([]interface{})
+	if source, ok := spec["source"]; ok {
+		sources = append(sources, source)
+	}
+	for _, s := range sources {
+		source, _ := s.(map[string]interface{})
+		helm, ok := source["helm"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		redactField(helm, "values")
+		redactField(helm, "valuesObject")
+		params, _ := helm["parameters"].([]interface{})
+		for _, param := range params {
+			if p, ok := param.(map[string]interface{}); ok {
+				redactField(p, "value")
+			}
+		}
+	}
+	return obj
+}
+
+// redactField replaces the value of field in m with redactedValue, recursing
+// into maps and lists so their keys stay visible.
+func redactField(m map[string]interface{}, field string) {
+	if v, ok := m[field]; ok {
+		m[field] = redactLeaves(v)
+	}
+}
+
+func redactLeaves(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = redactLeaves(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactLeaves(value)
+		}
+		return v
+	}
+	return redactedValue
+}
+
+// gzipData compresses every value of data into binary data, with the key
+// suffixed .gz.
+func gzipData(data map[string]string) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(data))
+	for key, value := range data {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write([]byte(value)); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		out[key+".gz"] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// omitOversizedManifests removes the largest values of the gzipped data until
+// the rest is at most renderedManifestsMaxSize in total, and returns the keys
+// of the removed values without their .gz suffix.
+func omitOversizedManifests(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	size := 0
+	for key, value := range data {
+		keys = append(keys, key)
+		size += len(value)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(data[keys[i]]) != len(data[keys[j]]) {
+			return len(data[keys[i]]) > len(data[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	var omitted []string
+	for _, key := range keys {
+		if size <= renderedManifestsMaxSize {
+			break
+		}
+		size -= len(data[key])
+		delete(data, key)
+		omitted = append(omitted, strings.TrimSuffix(key, ".gz"))
+	}
+	sort.Strings(omitted)
+	return omitted
+}
+
+// writeRenderedManifests replaces the rendered manifests ConfigMap of inst
+// with the manifests rec kept, labeled with the generation and profile hash
+// of inst. Manifests larger than renderedManifestsMaxSize in total are stored
+// gzipped in binaryData, leaving out the largest template types until the
+// rest fits.
+func (r *DeploymentSubroutine) writeRenderedManifests(ctx context.Context, inst *corev1alpha1.PlatformMesh, rec *renderRecord, log *logger.Logger) error {
+	if rec == nil || !rec.exportManifests || len(rec.exported) == 0 {
+		return nil
+	}
+	rec.mu.Lock()
+	data, err := renderedManifestsData(rec.exported)
+	rec.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(data))
+	size := 0
+	for key, value := range data {
+		keys = append(keys, key)
+		size += len(value)
+	}
+	sort.Strings(keys)
+	var binaryData map[string][]byte
+	var omitted []string
+	if size > renderedManifestsMaxSize {
+		if binaryData, err = gzipData(data); err != nil {
+			return errors.Wrap(err, "Failed to compress rendered manifests")
+		}
+		data = nil
+		omitted = omitOversizedManifests(binaryData)
+	}
+	cmName := renderedManifestsConfigMapName(inst)
+	if len(omitted) > 0 {
+		log.Warn().Strs("keys", omitted).Msg("Rendered manifests do not fit into the ConfigMap even gzipped, leaving them out")
+		recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonRenderedManifestsOmitted, "Export",
+			"Rendered manifests %s do not fit into ConfigMap %s even gzipped and were left out", strings.Join(omitted, ", "), cmName)
+	}
+
+	profileHash := inst.Status.ProfileHash
+	if len(profileHash) > 16 {
+		profileHash = profileHash[:16]
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cmName, Namespace: inst.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.clientRuntime, cm, func() error {
+		setInstanceLabels(cm, inst)
+		cm.Labels[RenderedGenerationLabel] = strconv.FormatInt(inst.Generation, 10)
+		cm.Labels[RenderedProfileHashLabel] = profileHash
+		if !metav1.IsControlledBy(cm, inst) {
+			cm.SetOwnerReferences(append(cm.GetOwnerReferences(), *metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))))
+		}
+		if len(omitted) > 0 {
+			if cm.Annotations == nil {
+				cm.Annotations = map[string]string{}
+			}
+			cm.Annotations[RenderedManifestsOmittedAnnotation] = strings.Join(omitted, ",")
+		} else {
+			delete(cm.Annotations, RenderedManifestsOmittedAnnotation)
+		}
+		cm.Data = data
+		cm.BinaryData = binaryData
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to write rendered manifests")
+	}
+	log.Debug().Str("configMap", cm.Name).Strs("keys", keys).Int("size", size).Msg("Exported rendered manifests")
+	return nil
+}
@@ -0,0 +1,131 @@
+package subroutines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestWriteRenderedManifests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "pm-ns", UID: "uid", Generation: 4},
+		Status:     v1alpha1.PlatformMeshStatus{ProfileHash: strings.Repeat("ab", 32)},
+	}
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	sub := &DeploymentSubroutine{clientRuntime: cl}
+	ctx := context.Background()
+
+	release := renderSnapshotManifest("helm.toolkit.fluxcd.io/v2", "HelmRelease", "iam")
+	release.component = "iam"
+	secret := renderSnapshotManifest("v1", "Secret", "iam-credentials")
+	secret.obj.Object["stringData"] = map[string]interface{}{"password": "hunter2"}
+
+	// Without the export nothing is kept or written.
+	rec := &renderRecord{}
+	require.NoError(t, rec.rendered("components-infra", []renderedManifest{release}))
+	assert.Empty(t, rec.exported)
+	require.NoError(t, sub.writeRenderedManifests(ctx, inst, rec, log))
+	require.NoError(t, sub.writeRenderedManifests(ctx, inst, nil, log))
+
+	rec = &renderRecord{exportManifests: true}
+	require.NoError(t, rec.rendered("components-infra", []renderedManifest{release}))
+	require.NoError(t, rec.rendered("runtime:edge", []renderedManifest{secret}))
+	require.NoError(t, sub.writeRenderedManifests(ctx, inst, rec, log))
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "pm-rendered-manifests", Namespace: "pm-ns"}, cm))
+	assert.Equal(t, "4", cm.Labels[RenderedGenerationLabel])
+	assert.Equal(t, strings.Repeat("ab", 8), cm.Labels[RenderedProfileHashLabel])
+	assert.Equal(t, "pm", cm.Labels[InstanceNameLabel])
+	assert.True(t, metav1.IsControlledBy(cm, inst))
+	assert.Equal(t, "---\n# component: iam\napiVersion: helm.toolkit.fluxcd.io/v2\nkind: HelmRelease\nmetadata:\n  name: iam\n  namespace: pm-ns\n", cm.Data["components-infra.yaml"])
+	assert.Contains(t, cm.Data["runtime_edge.yaml"], "password: <redacted>")
+	assert.NotContains(t, cm.Data["runtime_edge.yaml"], "hunter2")
+	assert.Equal(t, "hunter2", secret.obj.Object["stringData"].(map[string]interface{})["password"])
+
+	// Large manifests are stored gzipped.
+	release.obj.Object["spec"] = map[string]interface{}{"description": strings.Repeat("x", renderedManifestsMaxSize)}
+	rec = &renderRecord{exportManifests: true}
+	require.NoError(t, rec.rendered("components-infra", []renderedManifest{release}))
+	require.NoError(t, sub.writeRenderedManifests(ctx, inst, rec, log))
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "pm-rendered-manifests", Namespace: "pm-ns"}, cm))
+	assert.Empty(t, cm.Data)
+	r, err := gzip.NewReader(bytes.NewReader(cm.BinaryData["components-infra.yaml.gz"]))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "kind: HelmRelease")
+	assert.Empty(t, cm.Annotations[RenderedManifestsOmittedAnnotation])
+
+	// Manifests that are too large even gzipped are left out, the largest
+	// first.
+	blob := make([]byte, 2*renderedManifestsMaxSize)
+	_, err = rand.Read(blob)
+	require.NoError(t, err)
+	release.obj.Object["spec"] = map[string]interface{}{"description": hex.EncodeToString(blob)}
+	recorder := events.NewFakeRecorder(1)
+	rec = &renderRecord{exportManifests: true}
+	require.NoError(t, rec.rendered("components-infra", []renderedManifest{release}))
+	require.NoError(t, rec.rendered("runtime:edge", []renderedManifest{secret}))
+	require.NoError(t, sub.writeRenderedManifests(WithEventRecorder(ctx, recorder), inst, rec, log))
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "pm-rendered-manifests", Namespace: "pm-ns"}, cm))
+	assert.Equal(t, "components-infra.yaml", cm.Annotations[RenderedManifestsOmittedAnnotation])
+	assert.NotContains(t, cm.BinaryData, "components-infra.yaml.gz")
+	assert.Contains(t, cm.BinaryData, "runtime_edge.yaml.gz")
+	assert.Contains(t, <-recorder.Events, EventReasonRenderedManifestsOmitted)
+}
+
+func TestRenderedManifestsDataRedactsValues(t *testing.T) {
+	release := renderSnapshotManifest("helm.toolkit.fluxcd.io/v2", "HelmRelease", "iam")
+	release.obj.Object["spec"] = map[string]interface{}{
+		"chart":  map[string]interface{}{"spec": map[string]interface{}{"version": "1.0.0"}},
+		"values": map[string]interface{}{"oidc": map[string]interface{}{"clientSecret": "hunter2"}, "replicas": int64(2)},
+	}
+	app := renderSnapshotManifest("argoproj.io/v1alpha1", "Application", "portal")
+	app.obj.Object["spec"] = map[string]interface{}{
+		"source": map[string]interface{}{"helm": map[string]interface{}{"values": "password: hunter2\n"}},
+		"sources": []interface{}{map[string]interface{}{"helm": map[string]interface{}{
+			"valuesObject": map[string]interface{}{"token": "hunter2"},
+			"parameters":   []interface{}{map[string]interface{}{"name": "apiKey", "value": "hunter2"}},
+		}}},
+	}
+
+	data, err := renderedManifestsData([]exportedManifest{
+		{templateType: "components-infra", obj: release.obj},
+		{templateType: "components-infra", obj: app.obj},
+	})
+	require.NoError(t, err)
+	out := data["components-infra.yaml"]
+	assert.NotContains(t, out, "hunter2")
+	assert.Contains(t, out, "clientSecret: <redacted>")
+	assert.Contains(t, out, "replicas: <redacted>")
+	assert.Contains(t, out, "version: 1.0.0")
+	assert.Contains(t, out, "name: apiKey")
+	assert.Contains(t, out, "token: <redacted>")
+	values, _, _ := unstructured.NestedMap(release.obj.Object, "spec", "values")
+	assert.Equal(t, int64(2), values["replicas"])
+}
@@ -0,0 +1,118 @@
+package subroutines
+
+import (
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/subroutines"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const (
+	// requeueConditionSuffix is appended to the name of a subroutine for the
+	// condition that records since when it waits on an object.
+	requeueConditionSuffix = "Waiting"
+	requeueReasonWaiting   = "Requeued"
+)
+
+// conditionsObject is an object keeping its conditions in its status.
+type conditionsObject interface {
+	client.Object
+	GetConditions() []metav1.Condition
+	SetConditions([]metav1.Condition)
+}
+
+// RequeueBackoff spaces out the requeues of a subroutine that keeps waiting
+// on the same object. The delays double from the initial one up to the
+// maximum; a reconcile that gets past the subroutine or fails resets them.
+//
+// On objects with conditions the backoff is kept in the condition
+// <subroutine>Waiting, whose lastTransitionTime is when the subroutine started
+// waiting. Each delay is the time waited so far, which doubles it with every
+// requeue, and the backoff survives restarts and leader changes. Other
+// objects count their requeues in memory. A nil RequeueBackoff always
+// requeues after DefaultRequeueInterval.
+type RequeueBackoff struct {
+	name   string
+	policy config.RequeuePolicy
+	now    func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// NewRequeueBackoff returns the backoff of the subroutine name with policy.
+func NewRequeueBackoff(name string, policy config.RequeuePolicy) *RequeueBackoff {
+	return &RequeueBackoff{name: name, policy: policy, now: time.Now, attempts: map[string]int{}}
+}
+
+func (b *RequeueBackoff) conditionType() string {
+	return b.name + requeueConditionSuffix
+}
+
+// Next returns the delay of the next requeue of obj.
+func (b *RequeueBackoff) Next(obj client.Object) time.Duration {
+	if b == nil || b.policy.Initial <= 0 {
+		return DefaultRequeueInterval
+	}
+	d := b.policy.Initial
+	if withConditions, ok := obj.(conditionsObject); ok {
+		if cond := apimeta.FindStatusCondition(withConditions.GetConditions(), b.conditionType()); cond != nil {
+			d = max(d, b.now().Sub(cond.LastTransitionTime.Time))
+		}
+	} else {
+		b.mu.Lock()
+		attempts := b.attempts[client.ObjectKeyFromObject(obj).String()]
+		b.mu.Unlock()
+		for i := 0; i < attempts && (b.policy.Max <= 0 || d < b.policy.Max); i++ {
+			d *= 2
+		}
+	}
+	if b.policy.Max > 0 && d > b.policy.Max {
+		d = b.policy.Max
+	}
+	if b.policy.Jitter > 0 {
+		d = wait.Jitter(d, b.policy.Jitter)
+	}
+	return d
+}
+
+// Observe counts res towards the backoff of obj. Errors are left to the rate
+// limiter of the controller and reset the backoff like any result that does
+// not requeue.
+func (b *RequeueBackoff) Observe(obj client.Object, res subroutines.Result, err error) {
+	if b == nil || obj == nil {
+		return
+	}
+	waiting := err == nil && (res.IsStopWithRequeue() || res.IsPending())
+	if withConditions, ok := obj.(conditionsObject); ok {
+		conditions := withConditions.GetConditions()
+		if waiting {
+			apimeta.SetStatusCondition(&conditions, metav1.Condition{
+				Type:               b.conditionType(),
+				Status:             metav1.ConditionTrue,
+				Reason:             requeueReasonWaiting,
+				Message:            res.Message(),
+				ObservedGeneration: obj.GetGeneration(),
+				LastTransitionTime: metav1.NewTime(b.now()),
+			})
+		} else {
+			apimeta.RemoveStatusCondition(&conditions, b.conditionType())
+		}
+		withConditions.SetConditions(conditions)
+		return
+	}
+	key := client.ObjectKeyFromObject(obj).String()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if waiting {
+		b.attempts[key]++
+		return
+	}
+	delete(b.attempts, key)
+}
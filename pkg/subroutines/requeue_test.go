@@ -0,0 +1,97 @@
+package subroutines
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestRequeueBackoff(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "default"}}
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+	b := NewRequeueBackoff("TestSubroutine", config.RequeuePolicy{Initial: time.Second, Max: 5 * time.Second})
+	now := time.Now().Truncate(time.Second)
+	b.now = func() time.Time { return now }
+	waiting := subroutines.StopWithRequeue(time.Second, "waiting")
+
+	// Each requeue waits as long as the subroutine waited so far.
+	var delays []time.Duration
+	for range 5 {
+		d := b.Next(inst)
+		delays = append(delays, d)
+		b.Observe(inst, waiting, nil)
+		now = now.Add(d)
+	}
+	assert.Equal(t, []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+	assert.Equal(t, time.Second, b.Next(other))
+
+	// The start of the wait is kept in the status, so a new backoff, as after
+	// a leader change, continues it.
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, "TestSubroutineWaiting")
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, "waiting", cond.Message)
+	restarted := NewRequeueBackoff("TestSubroutine", config.RequeuePolicy{Initial: time.Second, Max: 5 * time.Second})
+	restarted.now = b.now
+	assert.Equal(t, 5*time.Second, restarted.Next(inst))
+
+	b.Observe(inst, subroutines.Pending(time.Second, "pending"), nil)
+	assert.Equal(t, 5*time.Second, b.Next(inst))
+
+	b.Observe(inst, subroutines.OK(), errors.New("failed"))
+	assert.Equal(t, time.Second, b.Next(inst))
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, "TestSubroutineWaiting"))
+
+	b.Observe(inst, waiting, nil)
+	b.Observe(inst, subroutines.OK(), nil)
+	assert.Equal(t, time.Second, b.Next(inst))
+}
+
+func TestRequeueBackoffWithoutConditions(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetName("resource")
+	obj.SetNamespace("default")
+	b := NewRequeueBackoff("TestSubroutine", config.RequeuePolicy{Initial: time.Second, Max: 5 * time.Second})
+	waiting := subroutines.StopWithRequeue(time.Second, "waiting")
+
+	var delays []time.Duration
+	for range 5 {
+		delays = append(delays, b.Next(obj))
+		b.Observe(obj, waiting, nil)
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+
+	b.Observe(obj, subroutines.OK(), nil)
+	assert.Equal(t, time.Second, b.Next(obj))
+}
+
+func TestRequeueBackoffJitter(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "default"}}
+	b := NewRequeueBackoff("TestSubroutine", config.RequeuePolicy{Initial: 10 * time.Second, Max: time.Minute, Jitter: 0.5})
+
+	for range 20 {
+		d := b.Next(inst)
+		assert.GreaterOrEqual(t, d, 10*time.Second)
+		assert.Less(t, d, 15*time.Second)
+	}
+}
+
+func TestRequeueBackoffDefaults(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "default"}}
+
+	var b *RequeueBackoff
+	assert.Equal(t, DefaultRequeueInterval, b.Next(inst))
+	b.Observe(inst, subroutines.StopWithRequeue(time.Second, "waiting"), nil)
+
+	assert.Equal(t, DefaultRequeueInterval, NewRequeueBackoff("TestSubroutine", config.RequeuePolicy{}).Next(inst))
+}
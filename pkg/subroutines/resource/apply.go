@@ -0,0 +1,57 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// defaultForceConflicts is used when the subroutine runs without operator config.
+var defaultForceConflicts = []string{"Application", "GitRepository", "HelmRelease", "HelmRepository", "OCIRepository"}
+
+// instanceFieldManager is the field manager of the fields inst resolves. Each
+// Resource gets its own, so that the chart and image Resources of the same
+// HelmRelease do not remove each other's fields.
+func instanceFieldManager(inst *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s-%s", resourceFieldManager, inst.GetName())
+}
+
+// applyOptions returns the server-side apply options for an object of kind.
+func (r *ResourceSubroutine) applyOptions(fieldManager, kind string) []client.PatchOption {
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	forceKinds := defaultForceConflicts
+	if r.cfg != nil {
+		forceKinds = r.cfg.Subroutines.Resource.ForceConflicts
+	}
+	if slices.Contains(forceKinds, kind) {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return opts
+}
+
+// applyFields writes the fields set by setFields into existing. With the
+// server-side strategy only those fields are applied under fieldManager, so
+// the fields the Deployment subroutine renders stay untouched. The update
+// strategy sets them on existing and updates the whole object.
+func (r *ResourceSubroutine) applyFields(ctx context.Context, existing *unstructured.Unstructured, fieldManager string, setFields func(obj *unstructured.Unstructured) error) error {
+	if r.cfg != nil && r.cfg.Subroutines.Resource.ApplyStrategy == config.ApplyStrategyUpdate {
+		if err := setFields(existing); err != nil {
+			return err
+		}
+		return r.client.Update(ctx, existing)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(existing.GroupVersionKind())
+	obj.SetName(existing.GetName())
+	obj.SetNamespace(existing.GetNamespace())
+	if err := setFields(obj); err != nil {
+		return err
+	}
+	return r.client.Patch(ctx, obj, client.Apply, r.applyOptions(fieldManager, obj.GetKind())...) //nolint:staticcheck // Apply via Patch is required for unstructured objects
+}
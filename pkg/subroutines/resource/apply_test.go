@@ -0,0 +1,75 @@
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+)
+
+func existingHelmRelease() *unstructured.Unstructured {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(helmReleaseGvk)
+	existing.SetName("iam")
+	existing.SetNamespace("default")
+	existing.Object["spec"] = map[string]interface{}{"interval": "5m", "chart": map[string]interface{}{"spec": map[string]interface{}{"chart": "iam"}}}
+	return existing
+}
+
+func setChartVersion(obj *unstructured.Unstructured) error {
+	return unstructured.SetNestedField(obj.Object, "1.2.3", "spec", "chart", "spec", "version")
+}
+
+func TestApplyFields_ServerSide(t *testing.T) {
+	clientMock := new(mocks.Client)
+	cfg := config.NewOperatorConfig()
+	r := NewResourceSubroutine(clientMock, &cfg, nil)
+
+	clientMock.EXPECT().Patch(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+		u := obj.(*unstructured.Unstructured)
+		_, hasInterval, _ := unstructured.NestedString(u.Object, "spec", "interval")
+		version, _, _ := unstructured.NestedString(u.Object, "spec", "chart", "spec", "version")
+		// Only the resolved field is applied.
+		return u.GetKind() == "HelmRelease" && u.GetName() == "iam" && !hasInterval && version == "1.2.3"
+	}), client.Apply, client.FieldOwner("platform-mesh-resource-iam-chart"), client.ForceOwnership).Return(nil).Once()
+
+	require.NoError(t, r.applyFields(context.Background(), existingHelmRelease(), "platform-mesh-resource-iam-chart", setChartVersion))
+	clientMock.AssertExpectations(t)
+}
+
+func TestApplyFields_Update(t *testing.T) {
+	clientMock := new(mocks.Client)
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.Resource.ApplyStrategy = config.ApplyStrategyUpdate
+	r := NewResourceSubroutine(clientMock, &cfg, nil)
+
+	clientMock.EXPECT().Update(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+		u := obj.(*unstructured.Unstructured)
+		interval, _, _ := unstructured.NestedString(u.Object, "spec", "interval")
+		version, _, _ := unstructured.NestedString(u.Object, "spec", "chart", "spec", "version")
+		return interval == "5m" && version == "1.2.3"
+	})).Return(nil).Once()
+
+	require.NoError(t, r.applyFields(context.Background(), existingHelmRelease(), "platform-mesh-resource-iam-chart", setChartVersion))
+	clientMock.AssertExpectations(t)
+}
+
+func TestApplyOptions(t *testing.T) {
+	cfg := config.NewOperatorConfig()
+	cfg.Subroutines.Resource.ForceConflicts = []string{"OCIRepository"}
+	r := NewResourceSubroutine(new(mocks.Client), &cfg, nil)
+
+	assert.Equal(t, []client.PatchOption{client.FieldOwner("m"), client.ForceOwnership}, r.applyOptions("m", "OCIRepository"))
+	assert.Equal(t, []client.PatchOption{client.FieldOwner("m")}, r.applyOptions("m", "HelmRelease"))
+
+	// Without operator config all kinds the subroutine writes are forced.
+	r = NewResourceSubroutine(new(mocks.Client), nil, nil)
+	assert.Contains(t, r.applyOptions("m", "HelmRelease"), client.ForceOwnership)
+}
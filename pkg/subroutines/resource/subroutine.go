@@ -22,8 +22,6 @@ import (
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines"
 )
 
-const requeueShort = 5 * time.Second
-
 var ociRepoGvk = schema.GroupVersionKind{
 	Group:   "source.toolkit.fluxcd.io",
 	Version: "v1",
@@ -61,10 +59,15 @@ type ResourceSubroutine struct {
 	clientRuntime     client.Client // runtime client for reading profile ConfigMaps
 	cfg               *config.OperatorConfig
 	imageVersionStore *subroutines.ImageVersionStore
+	requeue           *subroutines.RequeueBackoff
 }
 
 func NewResourceSubroutine(client client.Client, cfg *config.OperatorConfig, imageVersionStore *subroutines.ImageVersionStore) *ResourceSubroutine {
-	return &ResourceSubroutine{client: client, clientRuntime: client, cfg: cfg, imageVersionStore: imageVersionStore}
+	r := &ResourceSubroutine{client: client, clientRuntime: client, cfg: cfg, imageVersionStore: imageVersionStore}
+	if cfg != nil {
+		r.requeue = subroutines.NewRequeueBackoff(r.GetName(), cfg.Subroutines.Resource.Requeue)
+	}
+	return r
 }
 
 // SetRuntimeClient sets the runtime client for reading profile ConfigMaps
@@ -108,6 +111,16 @@ func getMetadataValue(obj *unstructured.Unstructured, key string) string {
 	return ""
 }
 
+// resourceVersion returns the version pinned on the Resource with the
+// subroutines.PinnedVersionAnnotation, else the version at path.
+func resourceVersion(inst *unstructured.Unstructured, path ...string) string {
+	if version := getAnnotations(inst)[subroutines.PinnedVersionAnnotation]; version != "" {
+		return version
+	}
+	version, _, _ := unstructured.NestedString(inst.Object, path...)
+	return version
+}
+
 func (r *ResourceSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutineslib.Result, err error) {
 	start := time.Now()
 	defer func() {
@@ -119,6 +132,7 @@ func (r *ResourceSubroutine) Process(ctx context.Context, runtimeObj client.Obje
 		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
 	}()
 	inst := runtimeObj.(*unstructured.Unstructured)
+	defer func() { r.requeue.Observe(inst, res, err) }()
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("name", r.GetName())
 
 	deploymentTech, err := r.getDeploymentTechnologyFromProfile(ctx, inst.GetNamespace(), log)
@@ -212,29 +226,29 @@ func (r *ResourceSubroutine) updateHelmReleaseWithImageTag(ctx context.Context,
 	updatePath := append([]string{"spec", "values"}, parsePath(getMetadataValue(inst, "path"), "image.tag")...)
 	versionPath := parsePath(getMetadataValue(inst, "version-path"), "status.resource.version")
 
-	version, found, _ := unstructured.NestedString(inst.Object, versionPath...)
-	if !found || version == "" {
+	version := resourceVersion(inst, versionPath...)
+	if version == "" {
 		return subroutineslib.OK(), fmt.Errorf("version not available at path %v", versionPath)
 	}
 
-	// GET the existing HelmRelease so we can do a merge patch instead of SSA.
-	// SSA with ForceOwnership would require the full valid spec (chart/chartRef, interval) in the patch,
-	// but we only want to update a nested values field without replacing the whole object.
+	// The HelmRelease is created by the DeploymentSubroutine. Applying only the
+	// values field to a missing one would create an invalid object.
 	existing := &unstructured.Unstructured{}
 	existing.SetGroupVersionKind(helmReleaseGvk)
 	if err := r.client.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, existing); err != nil {
 		return subroutineslib.OK(), fmt.Errorf("HelmRelease %s/%s not found: %w", namespace, name, err)
 	}
 
-	if err := unstructured.SetNestedField(existing.Object, version, updatePath...); err != nil {
-		return subroutineslib.OK(), err
-	}
-
-	if getMetadataValue(inst, "unsuspend") == "true" {
-		_ = unstructured.SetNestedField(existing.Object, false, "spec", "suspend")
-	}
-
-	if err := r.client.Update(ctx, existing); err != nil {
+	err := r.applyFields(ctx, existing, instanceFieldManager(inst), func(obj *unstructured.Unstructured) error {
+		if err := unstructured.SetNestedField(obj.Object, version, updatePath...); err != nil {
+			return err
+		}
+		if getMetadataValue(inst, "unsuspend") == "true" {
+			return unstructured.SetNestedField(obj.Object, false, "spec", "suspend")
+		}
+		return nil
+	})
+	if err != nil {
 		log.Error().Err(err).Msg("Failed to update HelmRelease")
 		return subroutineslib.OK(), err
 	}
@@ -280,8 +294,7 @@ func (r *ResourceSubroutine) updateArgoCDApplication(ctx context.Context, inst *
 		return subroutineslib.OK(), err
 	}
 
-	fieldManager := fmt.Sprintf("%s-%s", resourceFieldManager, inst.GetName())
-	if err := r.client.Patch(ctx, patchObj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err := r.client.Patch(ctx, patchObj, client.Apply, r.applyOptions(instanceFieldManager(inst), patchObj.GetKind())...); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
 		log.Error().Err(err).Msg("Failed to update ArgoCD Application")
 		return subroutineslib.OK(), err
 	}
@@ -303,7 +316,7 @@ func (r *ResourceSubroutine) resolveArgoCDSource(inst *unstructured.Unstructured
 
 	// Helm repository
 	if helmRepo := getString("status", "resource", "access", "helmRepository"); helmRepo != "" {
-		version := getString("status", "resource", "version")
+		version := resourceVersion(inst, "status", "resource", "version")
 		if version == "" {
 			return "", "", "", fmt.Errorf("version not found for helm chart")
 		}
@@ -338,7 +351,7 @@ func (r *ResourceSubroutine) resolveArgoCDSource(inst *unstructured.Unstructured
 		return "", "", "", err
 	}
 
-	version := getString("status", "resource", "version")
+	version := resourceVersion(inst, "status", "resource", "version")
 	if version == "" {
 		return "", "", "", fmt.Errorf("version not found for OCI chart")
 	}
@@ -369,8 +382,8 @@ func (r *ResourceSubroutine) updateArgoCDApplicationHelmValues(ctx context.Conte
 	updatePath := parsePath(getMetadataValue(inst, "path"), "image.tag")
 	pathStr := strings.Join(updatePath, ".")
 
-	version, found, _ := unstructured.NestedString(inst.Object, "status", "resource", "version")
-	if !found {
+	version := resourceVersion(inst, "status", "resource", "version")
+	if version == "" {
 		return subroutineslib.OK(), fmt.Errorf("version not found")
 	}
 
@@ -401,8 +414,7 @@ func (r *ResourceSubroutine) updateArgoCDApplicationHelmValues(ctx context.Conte
 		return subroutineslib.OK(), err
 	}
 
-	fieldManager := fmt.Sprintf("%s-%s", resourceFieldManager, inst.GetName())
-	if err := r.client.Patch(ctx, patchObj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err := r.client.Patch(ctx, patchObj, client.Apply, r.applyOptions(instanceFieldManager(inst), patchObj.GetKind())...); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
 		return subroutineslib.OK(), err
 	}
 
@@ -491,42 +503,46 @@ func getNestedString(m map[string]interface{}, path ...string) (string, bool) {
 }
 
 func (r *ResourceSubroutine) updateHelmRelease(ctx context.Context, inst *unstructured.Unstructured, log *logger.Logger) (subroutineslib.Result, error) {
-	version, found, _ := unstructured.NestedString(inst.Object, "status", "resource", "version")
-	if !found || version == "" {
+	version := resourceVersion(inst, "status", "resource", "version")
+	if version == "" {
 		return subroutineslib.OK(), fmt.Errorf("version not available")
 	}
 
 	name := trimPMSuffixes(inst.GetName())
 	namespace := inst.GetNamespace()
 
-	// GET the existing HelmRelease so we can do a merge update instead of SSA,
-	// which would require a full valid spec (chart.spec.chart, chart.spec.sourceRef, etc.).
+	// The HelmRelease is created by the DeploymentSubroutine. Applying only the
+	// chart version to a missing one would create an invalid object.
 	existing := &unstructured.Unstructured{}
 	existing.SetGroupVersionKind(helmReleaseGvk)
 	if err := r.client.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, existing); err != nil {
 		return subroutineslib.OK(), fmt.Errorf("HelmRelease %s/%s not found: %w", namespace, name, err)
 	}
 
-	if err := unstructured.SetNestedField(existing.Object, version, "spec", "chart", "spec", "version"); err != nil {
+	unsuspend := getMetadataValue(inst, "unsuspend") == "true"
+	err := r.applyFields(ctx, existing, instanceFieldManager(inst), func(obj *unstructured.Unstructured) error {
+		if err := unstructured.SetNestedField(obj.Object, version, "spec", "chart", "spec", "version"); err != nil {
+			return err
+		}
+		if unsuspend {
+			return unstructured.SetNestedField(obj.Object, false, "spec", "suspend")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update HelmRelease")
 		return subroutineslib.OK(), err
 	}
-
-	if getMetadataValue(inst, "unsuspend") == "true" {
-		_ = unstructured.SetNestedField(existing.Object, false, "spec", "suspend")
+	if unsuspend {
 		r.storeUnsuspended(namespace, name)
 	}
-
-	if err := r.client.Update(ctx, existing); err != nil {
-		log.Error().Err(err).Msg("Failed to update HelmRelease")
-		return subroutineslib.OK(), err
-	}
 	return subroutineslib.OK(), nil
 }
 
 func (r *ResourceSubroutine) updateHelmRepository(ctx context.Context, inst *unstructured.Unstructured, log *logger.Logger) (subroutineslib.Result, error) {
 	url, found, _ := unstructured.NestedString(inst.Object, "status", "resource", "access", "helmRepository")
 	if !found || url == "" {
-		return subroutineslib.StopWithRequeue(requeueShort, "helmRepository not available in Resource status"), nil
+		return subroutineslib.StopWithRequeue(r.requeue.Next(inst), "helmRepository not available in Resource status"), nil
 	}
 
 	obj := &unstructured.Unstructured{}
@@ -537,7 +553,7 @@ func (r *ResourceSubroutine) updateHelmRepository(ctx context.Context, inst *uns
 	_ = unstructured.SetNestedField(obj.Object, "generic", "spec", "provider")
 	_ = unstructured.SetNestedField(obj.Object, "5m", "spec", "interval")
 
-	if err := r.client.Patch(ctx, obj, client.Apply, client.FieldOwner(resourceFieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err := r.client.Patch(ctx, obj, client.Apply, r.applyOptions(resourceFieldManager, obj.GetKind())...); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
 		log.Error().Err(err).Msg("Failed to apply HelmRepository")
 		return subroutineslib.OK(), err
 	}
@@ -566,6 +582,7 @@ func (r *ResourceSubroutine) updateOciRepo(ctx context.Context, inst *unstructur
 
 	url = "oci://" + url
 	url = strings.TrimSuffix(url, ":"+version)
+	version = resourceVersion(inst, "status", "resource", "version")
 
 	spec, err := ocm.ParseRef(url)
 	if err != nil {
@@ -603,7 +620,7 @@ func (r *ResourceSubroutine) updateOciRepo(ctx context.Context, inst *unstructur
 	}
 
 	// Apply using SSA (creates if not exists, updates if exists)
-	if err := r.client.Patch(ctx, obj, client.Apply, client.FieldOwner(resourceFieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err := r.client.Patch(ctx, obj, client.Apply, r.applyOptions(resourceFieldManager, obj.GetKind())...); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
 		log.Error().Err(err).Msg("Failed to apply OCIRepository")
 		return subroutineslib.OK(), err
 	}
@@ -649,7 +666,7 @@ func (r *ResourceSubroutine) updateGitRepo(ctx context.Context, inst *unstructur
 	}
 
 	// Apply using SSA (creates if not exists, updates if exists)
-	if err := r.client.Patch(ctx, obj, client.Apply, client.FieldOwner(resourceFieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+	if err := r.client.Patch(ctx, obj, client.Apply, r.applyOptions(resourceFieldManager, obj.GetKind())...); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
 		log.Error().Err(err).Msg("Failed to apply GitRepository")
 		return subroutineslib.OK(), err
 	}
@@ -285,7 +285,7 @@ func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag() {
 					return nil
 				},
 			)
-			clientMock.EXPECT().Update(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+			clientMock.EXPECT().Patch(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
 				helmRelease, ok := obj.(*unstructured.Unstructured)
 				if !ok {
 					return false
@@ -301,7 +301,7 @@ func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag() {
 					return false
 				}
 				return actualVersion == tt.expectedVersion
-			}), mock.Anything).Return(nil)
+			}), mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 			result, err := subroutine.Process(ctx, inst)
 			s.Nil(err)
@@ -469,11 +469,11 @@ func (s *ResourceTestSuite) Test_updateHelmRepository() {
 			return nil
 		},
 	).Times(1)
-	clientMock.EXPECT().Update(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+	clientMock.EXPECT().Patch(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
 		unstr := obj.(*unstructured.Unstructured)
 		version, found, err := unstructured.NestedString(unstr.Object, "spec", "chart", "spec", "version")
-		return err == nil && found && version == "1.2.3"
-	}), mock.Anything).Return(nil).Times(1)
+		return unstr.GetKind() == "HelmRelease" && err == nil && found && version == "1.2.3"
+	}), mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(1)
 
 	result, err := s.subroutine.Process(ctx, inst)
 	s.Nil(err)
@@ -559,11 +559,11 @@ func (s *ResourceTestSuite) Test_updateHelmRelease() {
 			return nil
 		},
 	).Times(1)
-	clientMock.EXPECT().Update(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+	clientMock.EXPECT().Patch(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
 		unstr := obj.(*unstructured.Unstructured)
 		version, found, err := unstructured.NestedString(unstr.Object, "spec", "chart", "spec", "version")
-		return err == nil && found && version == "2.5.0"
-	}), mock.Anything).Return(nil).Times(1)
+		return unstr.GetKind() == "HelmRelease" && err == nil && found && version == "2.5.0"
+	}), mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(1)
 
 	result, err := subroutine.Process(ctx, inst)
 	s.Nil(err)
@@ -610,7 +610,7 @@ func (s *ResourceTestSuite) Test_updateHelmRelease_GetError() {
 	s.NotNil(result)
 }
 
-func (s *ResourceTestSuite) Test_updateHelmRelease_UpdateError() {
+func (s *ResourceTestSuite) Test_updateHelmRelease_ApplyError() {
 	ctx := context.TODO()
 
 	inst := &unstructured.Unstructured{
@@ -652,7 +652,9 @@ func (s *ResourceTestSuite) Test_updateHelmRelease_UpdateError() {
 			return nil
 		},
 	).Times(1)
-	clientMock.EXPECT().Update(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("update error")).Times(1)
+	clientMock.EXPECT().Patch(mock.Anything, mock.MatchedBy(func(obj client.Object) bool {
+		return obj.(*unstructured.Unstructured).GetKind() == "HelmRelease"
+	}), mock.Anything, mock.Anything, mock.Anything).Return(errors.New("apply error")).Times(1)
 
 	result, err := subroutine.Process(ctx, inst)
 	s.NotNil(err)
@@ -694,7 +696,7 @@ func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag_GetError() {
 	s.NotNil(result)
 }
 
-func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag_UpdateError() {
+func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag_ApplyError() {
 	ctx := context.TODO()
 
 	inst := &unstructured.Unstructured{
@@ -731,7 +733,7 @@ func (s *ResourceTestSuite) Test_updateHelmReleaseWithImageTag_UpdateError() {
 			return nil
 		},
 	)
-	clientMock.EXPECT().Update(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("update error"))
+	clientMock.EXPECT().Patch(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("apply error"))
 
 	result, err := subroutine.Process(ctx, inst)
 	s.NotNil(err)
@@ -1008,6 +1010,44 @@ func (s *ResourceTestSuite) Test_resolveArgoCDSource_OCI() {
 	s.Equal("oci", chartType)
 }
 
+func (s *ResourceTestSuite) Test_resolveArgoCDSource_Pinned() {
+	inst := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{subroutines.PinnedVersionAnnotation: "1.1.0"},
+			},
+			"status": map[string]interface{}{
+				"resource": map[string]interface{}{
+					"version": "1.2.3",
+					"access":  map[string]interface{}{"imageReference": "oci://registry.example.com/charts/mychart:1.2.3@sha256:abc"},
+				},
+			},
+		},
+	}
+	repoURL, rev, chartType, err := s.subroutine.resolveArgoCDSource(inst)
+	s.Nil(err)
+	s.Equal("registry.example.com/charts", repoURL)
+	s.Equal("1.1.0", rev)
+	s.Equal("oci", chartType)
+}
+
+func Test_resourceVersion(t *testing.T) {
+	inst := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"resource": map[string]interface{}{"version": "1.2.3"}},
+	}}
+	if got := resourceVersion(inst, "status", "resource", "version"); got != "1.2.3" {
+		t.Errorf("got %q want %q", got, "1.2.3")
+	}
+	inst.SetAnnotations(map[string]string{subroutines.PinnedVersionAnnotation: "1.1.0"})
+	if got := resourceVersion(inst, "status", "resource", "version"); got != "1.1.0" {
+		t.Errorf("got %q want %q", got, "1.1.0")
+	}
+	inst.SetAnnotations(map[string]string{subroutines.PinnedVersionAnnotation: ""})
+	if got := resourceVersion(inst, "status", "resource", "missing"); got != "" {
+		t.Errorf("got %q want empty", got)
+	}
+}
+
 func (s *ResourceTestSuite) Test_resolveArgoCDSource_NoSource() {
 	inst := &unstructured.Unstructured{
 		Object: map[string]interface{}{"status": map[string]interface{}{"resource": map[string]interface{}{"access": map[string]interface{}{}}}},
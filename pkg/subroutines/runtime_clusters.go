@@ -0,0 +1,290 @@
+package subroutines
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const defaultRuntimeClusterKubeconfigKey = "kubeconfig"
+
+// newRuntimeClusterClient returns a client for the cluster of kubeconfig.
+func newRuntimeClusterClient(kubeconfig []byte) (client.Client, error) {
+	raw, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.Wrap(err, "Failed to load kubeconfig"))
+	}
+	if err := checkRuntimeClusterKubeconfig(raw); err != nil {
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, err)
+	}
+	cfg, err := clientcmd.NewDefaultClientConfig(*raw, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.Wrap(err, "Failed to load kubeconfig"))
+	}
+	return client.New(cfg, client.Options{Scheme: GetClientScheme()})
+}
+
+// checkRuntimeClusterKubeconfig rejects the credentials of cfg that would run
+// commands or read files in the operator pod: exec and auth-provider plugins,
+// token files and certificates or keys given as file paths. The kubeconfig
+// comes from a Secret in the namespace of the instance, so whoever may write
+// that Secret must not get the privileges of the operator.
+func checkRuntimeClusterKubeconfig(cfg *clientcmdapi.Config) error {
+	for name, auth := range cfg.AuthInfos {
+		switch {
+		case auth.Exec != nil:
+			return errors.New("user %s of the kubeconfig uses an exec plugin, which is not allowed", name)
+		case auth.AuthProvider != nil:
+			return errors.New("user %s of the kubeconfig uses an auth-provider, which is not allowed", name)
+		case auth.TokenFile != "":
+			return errors.New("user %s of the kubeconfig reads its token from a file, which is not allowed", name)
+		case auth.ClientCertificate != "" || auth.ClientKey != "":
+			return errors.New("user %s of the kubeconfig reads its client certificate from a file, which is not allowed", name)
+		}
+	}
+	for name, cluster := range cfg.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return errors.New("cluster %s of the kubeconfig reads its CA from a file, which is not allowed", name)
+		}
+	}
+	return nil
+}
+
+// runtimeClusterTemplateType returns templateType rendered for the runtime
+// cluster name of spec.runtimeClusters, e.g. runtime:eu-1.
+func runtimeClusterTemplateType(templateType, name string) string {
+	return templateType + ":" + name
+}
+
+// runtimeTemplateFilter returns a skipFile function that skips the templates
+// not matched by selector, or nil if selector matches all templates.
+func runtimeTemplateFilter(selector *corev1alpha1.RuntimeTemplateSelector) func(fileName string) bool {
+	if selector == nil || len(selector.Templates) == 0 {
+		return nil
+	}
+	return func(fileName string) bool {
+		return !slices.ContainsFunc(selector.Templates, func(pattern string) bool {
+			matched, _ := filepath.Match(pattern, fileName)
+			return matched
+		})
+	}
+}
+
+// selectRuntimeComponents returns tmplVars with values.services reduced to
+// the components of selector. Other values are shared, not copied.
+func selectRuntimeComponents(tmplVars map[string]interface{}, selector *corev1alpha1.RuntimeTemplateSelector) map[string]interface{} {
+	if selector == nil || len(selector.Components) == 0 {
+		return tmplVars
+	}
+	values, _ := tmplVars["values"].(map[string]interface{})
+	services, _ := values["services"].(map[string]interface{})
+	selected := map[string]interface{}{}
+	for name, config := range services {
+		if slices.Contains(selector.Components, name) {
+			selected[name] = config
+		}
+	}
+	values = maps.Clone(values)
+	values["services"] = selected
+	out := maps.Clone(tmplVars)
+	out["values"] = values
+	return out
+}
+
+// withRuntimeCluster sets the template variables of the runtime cluster name.
+// Its objects are applied to the cluster directly, so FluxCD does not need a
+// kubeconfig to reach it.
+func withRuntimeCluster(tmplVars map[string]interface{}, name string) map[string]interface{} {
+	out := maps.Clone(tmplVars)
+	out["runtimeCluster"] = name
+	out["kubeConfigEnabled"] = false
+	delete(out, "kubeConfigSecretName")
+	delete(out, "kubeConfigSecretKey")
+	return out
+}
+
+// applyRuntimeClusters renders the runtime and components runtime templates
+// for each cluster of spec.runtimeClusters and applies them to it. A cluster
+// that fails does not hold up the others, its failure is reported in
+// status.runtimeClusters. The objects applied to clusters that were removed
+// from spec.runtimeClusters are deleted.
+func (r *DeploymentSubroutine) applyRuntimeClusters(ctx context.Context, inst *corev1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	var statuses []corev1alpha1.RuntimeClusterStatus
+	for _, rc := range inst.Spec.RuntimeClusters {
+		status := corev1alpha1.RuntimeClusterStatus{Name: rc.Name}
+		if i := slices.IndexFunc(inst.Status.RuntimeClusters, func(s corev1alpha1.RuntimeClusterStatus) bool { return s.Name == rc.Name }); i >= 0 {
+			status = inst.Status.RuntimeClusters[i]
+		}
+		status.KubeconfigSecretRef = rc.KubeconfigSecretRef.DeepCopy()
+		applied, err := r.applyRuntimeCluster(ctx, inst, rc, templateVars, log)
+		status.Applied = applied
+		switch {
+		case err == nil:
+			status.Phase, status.Message = corev1alpha1.RuntimeClusterApplied, ""
+			// The time only moves when other objects were applied, so an
+			// unchanged cluster does not rewrite the status.
+			if digest := renderRecordFrom(ctx).clusterDigest(rc.Name); status.LastApplied == nil || digest != status.Digest {
+				now := metav1.Now()
+				status.LastApplied, status.Digest = &now, digest
+			}
+		case isClusterUnavailable(err):
+			log.Warn().Err(err).Str("runtimeCluster", rc.Name).Msg("Runtime cluster unavailable")
+			status.Phase, status.Message = corev1alpha1.RuntimeClusterUnreachable, err.Error()
+		default:
+			log.Error().Err(err).Str("runtimeCluster", rc.Name).Msg("Failed to apply templates to runtime cluster")
+			status.Phase, status.Message = corev1alpha1.RuntimeClusterFailed, err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, s := range inst.Status.RuntimeClusters {
+		if slices.ContainsFunc(inst.Spec.RuntimeClusters, func(rc corev1alpha1.RuntimeCluster) bool { return rc.Name == s.Name }) {
+			continue
+		}
+		if err := r.removeRuntimeCluster(ctx, inst, s, log); err != nil {
+			log.Error().Err(err).Str("runtimeCluster", s.Name).Msg("Failed to delete the objects of a removed runtime cluster")
+			s.Phase, s.Message = corev1alpha1.RuntimeClusterRemoving, err.Error()
+			statuses = append(statuses, s)
+			continue
+		}
+		// Render errors of clusters that were removed are dropped with them.
+		recordComponentRenderErrors(inst, runtimeClusterTemplateType("components-runtime", s.Name), nil)
+	}
+	inst.Status.RuntimeClusters = statuses
+}
+
+// finalizeRuntimeClusters deletes the objects applied to the clusters in
+// status.runtimeClusters. It returns the number of clusters whose objects
+// could not be deleted yet, which stay in the status.
+func (r *DeploymentSubroutine) finalizeRuntimeClusters(ctx context.Context, inst *corev1alpha1.PlatformMesh) int {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+	var remaining []corev1alpha1.RuntimeClusterStatus
+	for _, s := range inst.Status.RuntimeClusters {
+		if err := r.removeRuntimeCluster(ctx, inst, s, log); err != nil {
+			log.Error().Err(err).Str("runtimeCluster", s.Name).Msg("Failed to delete the objects of a runtime cluster")
+			s.Phase, s.Message = corev1alpha1.RuntimeClusterRemoving, err.Error()
+			remaining = append(remaining, s)
+		}
+	}
+	inst.Status.RuntimeClusters = remaining
+	return len(remaining)
+}
+
+// removeRuntimeCluster deletes the objects recorded in the inventories of
+// the runtime cluster of s and drops the inventories. Without its kubeconfig
+// Secret the objects are left behind.
+func (r *DeploymentSubroutine) removeRuntimeCluster(ctx context.Context, inst *corev1alpha1.PlatformMesh, s corev1alpha1.RuntimeClusterStatus, log *logger.Logger) error {
+	templateTypes := []string{runtimeClusterTemplateType("runtime", s.Name), runtimeClusterTemplateType("components-runtime", s.Name)}
+	inventories, err := r.loadInventory(ctx, inst)
+	if err != nil {
+		return err
+	}
+	recorded := slices.ContainsFunc(templateTypes, func(templateType string) bool { return len(inventories[templateType]) > 0 })
+	if recorded && s.KubeconfigSecretRef != nil {
+		cl, err := r.runtimeClusterClient(ctx, inst, s.Name, *s.KubeconfigSecretRef)
+		switch {
+		case kerrors.IsNotFound(err):
+			log.Warn().Str("runtimeCluster", s.Name).Msg("Kubeconfig secret of a removed runtime cluster is gone, leaving its objects behind")
+		case err != nil:
+			return err
+		default:
+			for _, templateType := range templateTypes {
+				for _, e := range inventories[templateType] {
+					if err := r.deleteOrphan(ctx, inst, cl, templateType, e, log); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	for _, templateType := range templateTypes {
+		if err := r.dropInventory(ctx, inst, templateType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runtimeClusterClient returns a client for the runtime cluster name with the
+// kubeconfig of ref in the namespace of inst.
+func (r *DeploymentSubroutine) runtimeClusterClient(ctx context.Context, inst *corev1alpha1.PlatformMesh, name string, ref corev1alpha1.SecretKeyReference) (client.Client, error) {
+	key := ref.Key
+	if key == "" {
+		key = defaultRuntimeClusterKubeconfigKey
+	}
+	secret := &corev1.Secret{}
+	if err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: inst.Namespace}, secret); err != nil {
+		return nil, errors.Wrap(err, "Failed to get kubeconfig secret %s", ref.Name)
+	}
+	kubeconfig := secret.Data[key]
+	if len(kubeconfig) == 0 {
+		return nil, newClassifiedError(corev1alpha1.ErrorCategoryConfig, errors.New("kubeconfig secret %s has no key %s", ref.Name, key))
+	}
+	newClient := r.newClusterClient
+	if newClient == nil {
+		newClient = newRuntimeClusterClient
+	}
+	cl, err := newClient(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create client for runtime cluster %s", name)
+	}
+	return cl, nil
+}
+
+// applyRuntimeCluster renders the templates selected for rc, applies them to
+// it and records them in the inventories of the cluster. It returns the
+// number of applied manifests.
+func (r *DeploymentSubroutine) applyRuntimeCluster(ctx context.Context, inst *corev1alpha1.PlatformMesh, rc corev1alpha1.RuntimeCluster, templateVars apiextensionsv1.JSON, log *logger.Logger) (int, error) {
+	cl, err := r.runtimeClusterClient(ctx, inst, rc.Name, rc.KubeconfigSecretRef)
+	if err != nil {
+		return 0, err
+	}
+
+	skipFile := runtimeTemplateFilter(rc.TemplateSelector)
+	claims := NewSharedObjectClaims(r.clientRuntime, inst)
+
+	runtimeVars, err := r.buildRuntimeTemplateVars(ctx, inst, templateVars)
+	if err != nil {
+		return 0, err
+	}
+	templateType := runtimeClusterTemplateType("runtime", rc.Name)
+	inv := newRuntimeClusterInventory(templateType, rc.Name, cl)
+	applied, err := r.renderAndApplyTemplates(ctx, r.gotemplatesInfraDir+"/runtime", withRuntimeCluster(runtimeVars, rc.Name), cl, claims, inv, log, templateType, skipFile, nil)
+	recordManifestsApplied(ctx, inst, templateType, applied, err)
+	if err != nil {
+		return applied, err
+	}
+	if err := r.pruneInventory(ctx, inst, inv, log); err != nil {
+		return applied, err
+	}
+
+	componentsVars, err := r.buildComponentsTemplateVars(ctx, inst, templateVars)
+	if err != nil {
+		return applied, err
+	}
+	componentsVars = selectRuntimeComponents(withRuntimeCluster(componentsVars, rc.Name), rc.TemplateSelector)
+	templateType = runtimeClusterTemplateType("components-runtime", rc.Name)
+	inv = newRuntimeClusterInventory(templateType, rc.Name, cl)
+	n, err := r.renderAndApplyComponentTemplates(ctx, inst, r.gotemplatesComponentsDir+"/runtime", componentsVars, cl, claims, inv, log, templateType, skipFile, nil)
+	recordManifestsApplied(ctx, inst, templateType, n, err)
+	if err != nil {
+		return applied + n, err
+	}
+	return applied + n, r.pruneInventory(ctx, inst, inv, log)
+}
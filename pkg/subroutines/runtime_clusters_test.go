@@ -0,0 +1,226 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const runtimeClusterTemplate = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: runtime-{{ .runtimeCluster }}
+  namespace: {{ .releaseNamespace }}
+data:
+  kubeConfigEnabled: "{{ .kubeConfigEnabled }}"
+`
+
+func TestRuntimeTemplateFilter(t *testing.T) {
+	assert.Nil(t, runtimeTemplateFilter(nil))
+	assert.Nil(t, runtimeTemplateFilter(&v1alpha1.RuntimeTemplateSelector{Components: []string{"iam"}}))
+
+	skip := runtimeTemplateFilter(&v1alpha1.RuntimeTemplateSelector{Templates: []string{"resource*.yaml", "["}})
+	assert.False(t, skip("resource.yaml"))
+	assert.False(t, skip("resource-image.yaml"))
+	assert.True(t, skip("availability.yaml"))
+}
+
+func TestApplyRuntimeClusters(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	inst := &v1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system"},
+		Spec: v1alpha1.PlatformMeshSpec{RuntimeClusters: []v1alpha1.RuntimeCluster{
+			{
+				Name:                "eu-1",
+				KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "eu-1"},
+				TemplateSelector:    &v1alpha1.RuntimeTemplateSelector{Templates: []string{"resource*.yaml"}},
+			},
+			{
+				Name:                "eu-2",
+				KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "eu-2", Key: "config"},
+				TemplateSelector:    &v1alpha1.RuntimeTemplateSelector{Components: []string{"portal"}},
+			},
+			{Name: "us-1", KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "us-1"}},
+			{Name: "down", KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "down"}},
+		}},
+	}
+	secret := func(name, key string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: inst.Namespace},
+			Data:       map[string][]byte{key: []byte(name)},
+		}
+	}
+	profile := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-profile", Namespace: inst.Namespace},
+		Data:       map[string]string{profileConfigMapKey: pruneProfileYAML},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(inst, profile, secret("eu-1", "kubeconfig"), secret("eu-2", "config"), secret("down", "kubeconfig")).Build()
+	eu1 := fake.NewClientBuilder().WithScheme(scheme).Build()
+	targets := map[string]client.Client{
+		"eu-1": eu1,
+		"eu-2": fake.NewClientBuilder().WithScheme(scheme).Build(),
+		"down": fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+				return kerrors.NewServiceUnavailable("down")
+			},
+			Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+				return kerrors.NewServiceUnavailable("down")
+			},
+		}).Build(),
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "infra", "runtime"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "components", "runtime"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "infra", "runtime", "resource.yaml"), []byte(runtimeClusterTemplate), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "infra", "runtime", "skipped.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: skipped\n  namespace: default\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "components", "runtime", "resources.yaml"), []byte(switchedResourcesTemplate), 0o644))
+	sub := &DeploymentSubroutine{
+		clientRuntime:            cl,
+		clientInfra:              cl,
+		cfgOperator:              &config.OperatorConfig{},
+		gotemplatesInfraDir:      filepath.Join(dir, "infra"),
+		gotemplatesComponentsDir: filepath.Join(dir, "components"),
+		newClusterClient: func(kubeconfig []byte) (client.Client, error) {
+			return targets[string(kubeconfig)], nil
+		},
+	}
+
+	sub.applyRuntimeClusters(ctx, inst, apiextensionsv1.JSON{})
+	statuses := map[string]v1alpha1.RuntimeClusterStatus{}
+	for _, s := range inst.Status.RuntimeClusters {
+		statuses[s.Name] = s
+	}
+	require.Len(t, statuses, 4)
+	assert.Equal(t, v1alpha1.RuntimeClusterApplied, statuses["eu-1"].Phase)
+	assert.NotNil(t, statuses["eu-1"].LastApplied)
+	assert.Equal(t, 3, statuses["eu-1"].Applied)
+	assert.Equal(t, v1alpha1.RuntimeClusterApplied, statuses["eu-2"].Phase)
+	assert.Equal(t, 2, statuses["eu-2"].Applied)
+	assert.Equal(t, v1alpha1.RuntimeClusterFailed, statuses["us-1"].Phase)
+	assert.NotEmpty(t, statuses["us-1"].Message)
+	assert.Equal(t, v1alpha1.RuntimeClusterUnreachable, statuses["down"].Phase)
+	assert.Nil(t, statuses["down"].LastApplied)
+
+	// eu-1 only gets the selected templates, eu-2 only the selected components.
+	runtimeCM := &corev1.ConfigMap{}
+	require.NoError(t, targets["eu-1"].Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "runtime-eu-1"}, runtimeCM))
+	assert.Equal(t, "false", runtimeCM.Data["kubeConfigEnabled"])
+	assert.Equal(t, instanceKey(inst), labeledInstance(runtimeCM))
+	assert.True(t, kerrors.IsNotFound(targets["eu-1"].Get(ctx, client.ObjectKey{Namespace: "default", Name: "skipped"}, &corev1.ConfigMap{})))
+	assert.NoError(t, targets["eu-1"].Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "iam-resources"}, &corev1.ConfigMap{}))
+	assert.NoError(t, targets["eu-2"].Get(ctx, client.ObjectKey{Namespace: "default", Name: "skipped"}, &corev1.ConfigMap{}))
+	assert.True(t, kerrors.IsNotFound(targets["eu-2"].Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "iam-resources"}, &corev1.ConfigMap{})))
+	// Nothing is applied to the runtime cluster of the operator.
+	assert.True(t, kerrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "runtime-eu-1"}, &corev1.ConfigMap{})))
+
+	// The applied objects are recorded per cluster.
+	inventories, err := sub.loadInventory(ctx, inst)
+	require.NoError(t, err)
+	assert.Len(t, inventories["runtime:eu-2"], 2)
+	assert.Equal(t, "eu-2", inventories["runtime:eu-2"][0].Cluster)
+	assert.Empty(t, inventories["runtime:down"])
+
+	// An unchanged apply keeps the time of the last one.
+	lastApplied := statuses["eu-1"].LastApplied
+	inst.Status.RuntimeClusters[0].LastApplied = &metav1.Time{Time: lastApplied.Add(-time.Hour)}
+	lastApplied = inst.Status.RuntimeClusters[0].LastApplied
+	sub.applyRuntimeClusters(ctx, inst, apiextensionsv1.JSON{})
+	assert.Equal(t, lastApplied, inst.Status.RuntimeClusters[0].LastApplied)
+
+	// A failing apply keeps the time of the last successful one, the objects
+	// of removed clusters are deleted and they are dropped from the status.
+	targets["eu-1"] = targets["down"]
+	inst.Spec.RuntimeClusters = inst.Spec.RuntimeClusters[:1]
+	sub.applyRuntimeClusters(ctx, inst, apiextensionsv1.JSON{})
+	require.Len(t, inst.Status.RuntimeClusters, 1)
+	assert.Equal(t, v1alpha1.RuntimeClusterUnreachable, inst.Status.RuntimeClusters[0].Phase)
+	assert.Equal(t, lastApplied, inst.Status.RuntimeClusters[0].LastApplied)
+	assert.True(t, kerrors.IsNotFound(targets["eu-2"].Get(ctx, client.ObjectKey{Namespace: "default", Name: "skipped"}, &corev1.ConfigMap{})))
+	inventories, err = sub.loadInventory(ctx, inst)
+	require.NoError(t, err)
+	assert.NotContains(t, inventories, "runtime:eu-2")
+	assert.NotContains(t, inventories, "components-runtime:eu-2")
+
+	// At teardown the objects of the remaining clusters are deleted, an
+	// unreachable cluster holds the finalizer.
+	res, err := sub.Finalize(ctx, inst)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	require.Len(t, inst.Status.RuntimeClusters, 1)
+	assert.Equal(t, v1alpha1.RuntimeClusterRemoving, inst.Status.RuntimeClusters[0].Phase)
+	targets["eu-1"] = eu1
+	res, err = sub.Finalize(ctx, inst)
+	require.NoError(t, err)
+	assert.False(t, res.IsPending())
+	assert.Empty(t, inst.Status.RuntimeClusters)
+	assert.True(t, kerrors.IsNotFound(eu1.Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: "runtime-eu-1"}, &corev1.ConfigMap{})))
+}
+
+func TestCheckRuntimeClusterKubeconfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		auth    *clientcmdapi.AuthInfo
+		cluster *clientcmdapi.Cluster
+		wantErr bool
+	}{
+		"token":              {auth: &clientcmdapi.AuthInfo{Token: "secret"}},
+		"client certificate": {auth: &clientcmdapi.AuthInfo{ClientCertificateData: []byte("cert"), ClientKeyData: []byte("key")}},
+		"exec":               {auth: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "sh"}}, wantErr: true},
+		"auth-provider":      {auth: &clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc"}}, wantErr: true},
+		"token file":         {auth: &clientcmdapi.AuthInfo{TokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token"}, wantErr: true},
+		"certificate file":   {auth: &clientcmdapi.AuthInfo{ClientCertificate: "/tmp/cert", ClientKey: "/tmp/key"}, wantErr: true},
+		"CA file":            {auth: &clientcmdapi.AuthInfo{Token: "secret"}, cluster: &clientcmdapi.Cluster{CertificateAuthority: "/tmp/ca"}, wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := clientcmdapi.NewConfig()
+			cfg.AuthInfos["user"] = tc.auth
+			cfg.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://eu-1.example.com"}
+			if tc.cluster != nil {
+				cfg.Clusters["cluster"] = tc.cluster
+			}
+			err := checkRuntimeClusterKubeconfig(cfg)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// The checked kubeconfig is rejected as a configuration error.
+	cfg := clientcmdapi.NewConfig()
+	cfg.AuthInfos["user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "sh"}}
+	kubeconfig, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+	_, err = newRuntimeClusterClient(kubeconfig)
+	require.Error(t, err)
+	assert.Equal(t, v1alpha1.ErrorCategoryConfig, ClassifyError(err).Category)
+}
@@ -0,0 +1,172 @@
+package subroutines
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+const (
+	fieldManagerProviderSecret = "platform-mesh-provider-secret"
+	// scopedClientCertUserPrefix prefixes the user name of client certificate
+	// identities, which kcp takes from the common name.
+	scopedClientCertUserPrefix = "platform-mesh-provider:"
+	scopedClientCertSuffix     = "-client-cert"
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// scopedClientCert is an issued client certificate with its key.
+type scopedClientCert struct {
+	certPEM   []byte
+	keyPEM    []byte
+	expiresAt time.Time
+}
+
+// scopedClientCertIssuer returns the Issuer signing client certificates.
+func scopedClientCertIssuer(operatorCfg config.OperatorConfig) string {
+	if operatorCfg.Subroutines.ProviderSecret.ClientCertIssuerName != "" {
+		return operatorCfg.Subroutines.ProviderSecret.ClientCertIssuerName
+	}
+	return operatorCfg.KCP.RootShardName + "-client-ca"
+}
+
+// scopedClientCertificate returns the cert-manager Certificate issuing the
+// client certificate of userName for the provider secret secretName. It lives
+// in the KCP namespace next to its issuer and stores the certificate in a
+// secret of the same name.
+func scopedClientCertificate(operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, secretName, userName string) *unstructured.Unstructured {
+	expiration, renewBefore := scopedTokenSettings(operatorCfg.Subroutines.ProviderSecret)
+	name := scopedSAPrefix + secretName + scopedClientCertSuffix
+	labels := map[string]any{
+		InstanceNameLabel:      instance.Name,
+		InstanceNamespaceLabel: instance.Namespace,
+	}
+	cert := &unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": operatorCfg.KCP.Namespace,
+			"labels":    labels,
+		},
+		"spec": map[string]any{
+			"secretName":     name,
+			"secretTemplate": map[string]any{"labels": labels},
+			"commonName":     userName,
+			"duration":       expiration.String(),
+			"renewBefore":    renewBefore.String(),
+			"usages":         []any{"client auth"},
+			"privateKey": map[string]any{
+				"algorithm":      "ECDSA",
+				"size":           int64(256),
+				"rotationPolicy": "Always",
+			},
+			"issuerRef": map[string]any{
+				"group": certificateGVK.Group,
+				"kind":  "Issuer",
+				"name":  scopedClientCertIssuer(operatorCfg),
+			},
+		},
+	}}
+	cert.SetGroupVersionKind(certificateGVK)
+	return cert
+}
+
+// ensureScopedClientCert applies the Certificate of the provider secret
+// secretName and returns the issued certificate. It returns nil while
+// cert-manager has not issued the certificate yet.
+func ensureScopedClientCert(
+	ctx context.Context, k8sClient client.Client, operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, secretName, userName string,
+) (*scopedClientCert, error) {
+	cert := scopedClientCertificate(operatorCfg, instance, secretName, userName)
+	if err := k8sClient.Patch(ctx, cert, client.Apply, client.FieldOwner(fieldManagerProviderSecret), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+		return nil, fmt.Errorf("apply Certificate %s/%s: %w", cert.GetNamespace(), cert.GetName(), err)
+	}
+
+	issued := &corev1.Secret{}
+	err := k8sClient.Get(ctx, client.ObjectKey{Name: cert.GetName(), Namespace: cert.GetNamespace()}, issued)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get client certificate secret %s/%s: %w", cert.GetNamespace(), cert.GetName(), err)
+	}
+	certPEM, keyPEM := issued.Data[corev1.TLSCertKey], issued.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, nil
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("client certificate secret %s/%s has no PEM certificate", cert.GetNamespace(), cert.GetName())
+	}
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse client certificate %s/%s: %w", cert.GetNamespace(), cert.GetName(), err)
+	}
+	return &scopedClientCert{certPEM: certPEM, keyPEM: keyPEM, expiresAt: parsed.NotAfter}, nil
+}
+
+// scopedClientCertSecretData returns the provider secret of a client
+// certificate connection: the kubeconfig with embedded credentials and the
+// certificate, key and server CA as separate files.
+func scopedClientCertSecretData(name, hostURL string, caData []byte, cert *scopedClientCert) (map[string][]byte, error) {
+	kubeconfig := buildScopedKubeconfig(name, hostURL, "", caData)
+	kubeconfig.AuthInfos[name] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: cert.certPEM,
+		ClientKeyData:         cert.keyPEM,
+	}
+	kubeconfigBytes, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		"kubeconfig":                   kubeconfigBytes,
+		corev1.TLSCertKey:              cert.certPEM,
+		corev1.TLSPrivateKeyKey:        cert.keyPEM,
+		corev1.ServiceAccountRootCAKey: caData,
+	}, nil
+}
+
+// deleteScopedClientCertificates removes the Certificates of instance and the
+// secrets they were issued into.
+func deleteScopedClientCertificates(ctx context.Context, k8sClient client.Client, instance *corev1alpha1.PlatformMesh) error {
+	certs := &unstructured.UnstructuredList{}
+	certs.SetGroupVersionKind(certificateGVK.GroupVersion().WithKind("CertificateList"))
+	err := k8sClient.List(ctx, certs, client.MatchingLabels{
+		InstanceNameLabel:      instance.Name,
+		InstanceNamespaceLabel: instance.Namespace,
+	})
+	if apimeta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i := range certs.Items {
+		cert := &certs.Items[i]
+		if err := client.IgnoreNotFound(k8sClient.Delete(ctx, cert)); err != nil {
+			return err
+		}
+		secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+		issued := &corev1.Secret{}
+		issued.SetName(secretName)
+		issued.SetNamespace(cert.GetNamespace())
+		if err := client.IgnoreNotFound(k8sClient.Delete(ctx, issued)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package subroutines
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func clientCertTestConfig() config.OperatorConfig {
+	cfg := config.OperatorConfig{}
+	cfg.KCP.Namespace = "platform-mesh-system"
+	cfg.KCP.RootShardName = "root"
+	cfg.Subroutines.ProviderSecret.TokenExpiration = 24 * time.Hour
+	cfg.Subroutines.ProviderSecret.TokenRenewBefore = 6 * time.Hour
+	return cfg
+}
+
+func issuedClientCertPEM(t *testing.T, notAfter time.Time) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: scopedClientCertUserPrefix + "provider-kubeconfig"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestScopedClientCertIssuer(t *testing.T) {
+	cfg := clientCertTestConfig()
+	assert.Equal(t, "root-client-ca", scopedClientCertIssuer(cfg))
+
+	cfg.Subroutines.ProviderSecret.ClientCertIssuerName = "provider-issuer"
+	assert.Equal(t, "provider-issuer", scopedClientCertIssuer(cfg))
+}
+
+func TestScopedClientCertificate(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "default"}}
+	cert := scopedClientCertificate(clientCertTestConfig(), instance, "provider-kubeconfig", "platform-mesh-provider:provider-kubeconfig")
+
+	assert.Equal(t, certificateGVK, cert.GroupVersionKind())
+	assert.Equal(t, "platform-mesh-provider-provider-kubeconfig-client-cert", cert.GetName())
+	assert.Equal(t, "platform-mesh-system", cert.GetNamespace())
+	assert.Equal(t, "pm", cert.GetLabels()[InstanceNameLabel])
+
+	commonName, _, _ := unstructured.NestedString(cert.Object, "spec", "commonName")
+	assert.Equal(t, "platform-mesh-provider:provider-kubeconfig", commonName)
+	duration, _, _ := unstructured.NestedString(cert.Object, "spec", "duration")
+	assert.Equal(t, "24h0m0s", duration)
+	renewBefore, _, _ := unstructured.NestedString(cert.Object, "spec", "renewBefore")
+	assert.Equal(t, "6h0m0s", renewBefore)
+	issuer, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "name")
+	assert.Equal(t, "root-client-ca", issuer)
+}
+
+func TestEnsureScopedClientCert(t *testing.T) {
+	ctx := context.Background()
+	cfg := clientCertTestConfig()
+	instance := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "default"}}
+	var applied []string
+	funcs := interceptor.Funcs{
+		Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch, _ ...client.PatchOption) error {
+			applied = append(applied, string(patch.Type())+":"+obj.GetName())
+			return nil
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(funcs).Build()
+	cert, err := ensureScopedClientCert(ctx, cl, cfg, instance, "provider-kubeconfig", "user")
+	require.NoError(t, err)
+	assert.Nil(t, cert, "expected no certificate before it is issued")
+	assert.Equal(t, []string{"application/apply-patch+yaml:platform-mesh-provider-provider-kubeconfig-client-cert"}, applied)
+
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	certPEM, keyPEM := issuedClientCertPEM(t, notAfter)
+	issued := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh-provider-provider-kubeconfig-client-cert", Namespace: "platform-mesh-system"},
+		Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+	}
+	cl = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(issued).WithInterceptorFuncs(funcs).Build()
+	cert, err = ensureScopedClientCert(ctx, cl, cfg, instance, "provider-kubeconfig", "user")
+	require.NoError(t, err)
+	require.NotNil(t, cert)
+	assert.True(t, notAfter.Equal(cert.expiresAt))
+	assert.Equal(t, certPEM, cert.certPEM)
+}
+
+func TestScopedClientCertSecretData(t *testing.T) {
+	certPEM, keyPEM := issuedClientCertPEM(t, time.Now().Add(time.Hour))
+	caData := []byte("ca")
+
+	data, err := scopedClientCertSecretData("pm-iam-kubeconfig-root", "https://kcp.example/clusters/root", caData, &scopedClientCert{certPEM: certPEM, keyPEM: keyPEM})
+	require.NoError(t, err)
+	assert.Equal(t, certPEM, data[corev1.TLSCertKey])
+	assert.Equal(t, keyPEM, data[corev1.TLSPrivateKeyKey])
+	assert.Equal(t, caData, data[corev1.ServiceAccountRootCAKey])
+
+	kubeconfig, err := clientcmd.Load(data["kubeconfig"])
+	require.NoError(t, err)
+	assert.Equal(t, "pm-iam-kubeconfig-root", kubeconfig.CurrentContext)
+	authInfo := kubeconfig.AuthInfos[kubeconfig.Contexts[kubeconfig.CurrentContext].AuthInfo]
+	assert.Empty(t, authInfo.Token)
+	assert.Equal(t, certPEM, authInfo.ClientCertificateData)
+	assert.Equal(t, keyPEM, authInfo.ClientKeyData)
+	assert.Equal(t, "https://kcp.example/clusters/root", kubeconfig.Clusters[kubeconfig.Contexts[kubeconfig.CurrentContext].Cluster].Server)
+}
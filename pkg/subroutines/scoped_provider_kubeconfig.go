@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	kcpapiv1alpha1 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
 	kcpapiv1alpha2 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha2"
@@ -15,6 +18,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -25,6 +29,7 @@ import (
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
 )
 
 const (
@@ -145,14 +150,84 @@ func hasUpdatePatchVerbs(verbs []string) bool {
 	return false
 }
 
-func ensureScopedProviderServiceAccountAndRBAC(ctx context.Context, kcpClient client.Client, policyRules []rbacv1.PolicyRule, providerSuffix string) (saName string, err error) {
+// policyRulesEqual compares rules ignoring the order of rules and of their
+// list fields, as the API server may return them differently ordered.
+func policyRulesEqual(a, b []rbacv1.PolicyRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keysA, keysB := policyRuleKeys(a), policyRuleKeys(b)
+	for i := range keysA {
+		if keysA[i] != keysB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func policyRuleKeys(rules []rbacv1.PolicyRule) []string {
+	keys := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		keys = append(keys, strings.Join([]string{
+			sortedJoin(rule.APIGroups),
+			sortedJoin(rule.Resources),
+			sortedJoin(rule.ResourceNames),
+			sortedJoin(rule.NonResourceURLs),
+			sortedJoin(rule.Verbs),
+		}, "|"))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedJoin(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// ensureScopedClusterRole creates the ClusterRole or replaces its rules when
+// they drifted from policyRules, e.g. after the APIExport gained resources or
+// permission claims. It reports whether an existing ClusterRole was updated.
+func ensureScopedClusterRole(ctx context.Context, kcpClient client.Client, crName string, policyRules []rbacv1.PolicyRule) (bool, error) {
+	cr := &rbacv1.ClusterRole{}
+	err := kcpClient.Get(ctx, client.ObjectKey{Name: crName}, cr)
+	if kerrors.IsNotFound(err) {
+		cr = &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: crName}, Rules: policyRules}
+		if err := kcpClient.Create(ctx, cr); err != nil {
+			return false, fmt.Errorf("create ClusterRole %s: %w", crName, err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get ClusterRole %s: %w", crName, err)
+	}
+	if policyRulesEqual(cr.Rules, policyRules) {
+		return false, nil
+	}
+	cr.Rules = policyRules
+	if err := kcpClient.Update(ctx, cr); err != nil {
+		return false, fmt.Errorf("update ClusterRole %s: %w", crName, err)
+	}
+	return true, nil
+}
+
+// scopedSANamespace returns the namespace of the ServiceAccount of pc in its
+// workspace.
+func scopedSANamespace(pc corev1alpha1.ProviderConnection) string {
+	if pc.SANamespace != "" {
+		return pc.SANamespace
+	}
+	return defaultScopedSANamespace
+}
+
+func ensureScopedProviderServiceAccountAndRBAC(
+	ctx context.Context, kcpClient client.Client, policyRules []rbacv1.PolicyRule, providerSuffix, saNamespace string,
+) (saName string, err error) {
 	if providerSuffix == "" {
 		return "", fmt.Errorf("provider suffix for scoped RBAC is empty")
 	}
 	saName = scopedSAPrefix + providerSuffix
-	crName := scopedClusterRolePrefix + providerSuffix
-	workspaceAccessCRBName := scopedWorkspaceAccessCRBPrefix + providerSuffix
-	saNamespace := defaultScopedSANamespace
 	if err := ensureScopedNamespaceExists(ctx, kcpClient, saNamespace); err != nil {
 		return "", fmt.Errorf("ensure namespace %s for scoped ServiceAccount: %w", saNamespace, err)
 	}
@@ -169,14 +244,30 @@ func ensureScopedProviderServiceAccountAndRBAC(ctx context.Context, kcpClient cl
 		}
 	}
 
-	cr := &rbacv1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{Name: crName},
+	subject := rbacv1.Subject{
+		Kind:      rbacv1.ServiceAccountKind,
+		Namespace: saNamespace,
+		Name:      saName,
 	}
-	if _, err := controllerutil.CreateOrUpdate(ctx, kcpClient, cr, func() error {
-		cr.Rules = policyRules
-		return nil
-	}); err != nil {
-		return "", fmt.Errorf("create or update ClusterRole %s: %w", crName, err)
+	if err := ensureScopedProviderRBAC(ctx, kcpClient, policyRules, providerSuffix, subject); err != nil {
+		return "", err
+	}
+	return saName, nil
+}
+
+// ensureScopedProviderRBAC grants subject the scoped ClusterRole of the
+// provider and access to the workspace.
+func ensureScopedProviderRBAC(ctx context.Context, kcpClient client.Client, policyRules []rbacv1.PolicyRule, providerSuffix string, subject rbacv1.Subject) error {
+	log := logger.LoadLoggerFromContext(ctx)
+	crName := scopedClusterRolePrefix + providerSuffix
+	workspaceAccessCRBName := scopedWorkspaceAccessCRBPrefix + providerSuffix
+
+	updated, err := ensureScopedClusterRole(ctx, kcpClient, crName, policyRules)
+	if err != nil {
+		return err
+	}
+	if updated {
+		log.Info().Str("clusterRole", crName).Msg("Updated stale scoped provider ClusterRole from APIExport")
 	}
 
 	crb := &rbacv1.ClusterRoleBinding{
@@ -188,16 +279,10 @@ func ensureScopedProviderServiceAccountAndRBAC(ctx context.Context, kcpClient cl
 			Kind:     "ClusterRole",
 			Name:     crName,
 		}
-		crb.Subjects = []rbacv1.Subject{
-			{
-				Kind:      rbacv1.ServiceAccountKind,
-				Namespace: saNamespace,
-				Name:      saName,
-			},
-		}
+		crb.Subjects = []rbacv1.Subject{subject}
 		return nil
 	}); err != nil {
-		return "", fmt.Errorf("create or update ClusterRoleBinding %s: %w", crName, err)
+		return fmt.Errorf("create or update ClusterRoleBinding %s: %w", crName, err)
 	}
 
 	workspaceAccessCRB := &rbacv1.ClusterRoleBinding{
@@ -209,18 +294,40 @@ func ensureScopedProviderServiceAccountAndRBAC(ctx context.Context, kcpClient cl
 			Kind:     "ClusterRole",
 			Name:     kcpWorkspaceAccessRoleName,
 		}
-		workspaceAccessCRB.Subjects = []rbacv1.Subject{
-			{
-				Kind:      rbacv1.ServiceAccountKind,
-				Namespace: saNamespace,
-				Name:      saName,
-			},
-		}
+		workspaceAccessCRB.Subjects = []rbacv1.Subject{subject}
 		return nil
 	}); err != nil {
-		return "", fmt.Errorf("create or update ClusterRoleBinding %s for workspace access: %w", workspaceAccessCRBName, err)
+		return fmt.Errorf("create or update ClusterRoleBinding %s for workspace access: %w", workspaceAccessCRBName, err)
 	}
-	return saName, nil
+	return nil
+}
+
+// deleteScopedProviderRBAC deletes the ServiceAccount, ClusterRole and
+// ClusterRoleBindings created for the provider. Objects that are gone already,
+// including the ones in a deleted workspace, are skipped.
+func deleteScopedProviderRBAC(ctx context.Context, kcpClient client.Client, providerSuffix, saNamespace string) error {
+	log := logger.LoadLoggerFromContext(ctx)
+	name := scopedClusterRolePrefix + providerSuffix
+	objs := []struct {
+		kind string
+		obj  client.Object
+	}{
+		{"ClusterRoleBinding", &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: scopedWorkspaceAccessCRBPrefix + providerSuffix}}},
+		{"ClusterRoleBinding", &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name}}},
+		{"ClusterRole", &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}},
+		{"ServiceAccount", &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: scopedSAPrefix + providerSuffix, Namespace: saNamespace}}},
+	}
+	for _, o := range objs {
+		err := kcpClient.Delete(ctx, o.obj)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("delete %s %s: %w", o.kind, o.obj.GetName(), err)
+		}
+		log.Info().Str("kind", o.kind).Str("name", o.obj.GetName()).Msg("Deleted scoped provider RBAC object")
+	}
+	return nil
 }
 
 func ensureScopedNamespaceExists(ctx context.Context, kcpClient client.Client, namespace string) error {
@@ -236,7 +343,7 @@ func ensureScopedNamespaceExists(ctx context.Context, kcpClient client.Client, n
 	return nil
 }
 
-func createTokenForSA(ctx context.Context, kcpWorkspaceClient client.Client, namespace, saName string, expirationSeconds int64) (string, error) {
+func createTokenForSA(ctx context.Context, kcpWorkspaceClient client.Client, namespace, saName string, expirationSeconds int64) (scopedToken, error) {
 	expSec := expirationSeconds
 	if expSec <= 0 {
 		expSec = defaultTokenExpirationSeconds
@@ -253,12 +360,16 @@ func createTokenForSA(ctx context.Context, kcpWorkspaceClient client.Client, nam
 		},
 	}
 	if err := kcpWorkspaceClient.SubResource("token").Create(ctx, sa, tr); err != nil {
-		return "", fmt.Errorf("create token for ServiceAccount %s/%s: %w", namespace, saName, err)
+		return scopedToken{}, fmt.Errorf("create token for ServiceAccount %s/%s: %w", namespace, saName, err)
 	}
-	if tr.Status.Token == "" {
-		return "", fmt.Errorf("empty token in TokenRequest status for ServiceAccount %s/%s", namespace, saName)
+	if tr.Status.Token == "" && !plan.IsPlanning(ctx) {
+		return scopedToken{}, fmt.Errorf("empty token in TokenRequest status for ServiceAccount %s/%s", namespace, saName)
 	}
-	return tr.Status.Token, nil
+	expiresAt := tr.Status.ExpirationTimestamp.Time
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(time.Duration(expSec) * time.Second)
+	}
+	return scopedToken{value: tr.Status.Token, expiresAt: expiresAt}, nil
 }
 
 // virtualWorkspaceServerURLFromSlice returns status.apiExportEndpoints[0].url as the kubeconfig cluster server (kcp’s published VirtualWorkspace URL).
@@ -269,7 +380,12 @@ func virtualWorkspaceServerURLFromSlice(slice *kcpapiv1alpha1.APIExportEndpointS
 	if len(slice.Status.APIExportEndpoints) == 0 {
 		return "", fmt.Errorf("no endpoints in APIExportEndpointSlice %q", slice.Name)
 	}
-	raw := strings.TrimSpace(slice.Status.APIExportEndpoints[0].URL)
+	return virtualWorkspaceServerURL(slice, slice.Status.APIExportEndpoints[0].URL)
+}
+
+// virtualWorkspaceServerURL validates the endpoint URL raw of slice as kubeconfig cluster server.
+func virtualWorkspaceServerURL(slice *kcpapiv1alpha1.APIExportEndpointSlice, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return "", fmt.Errorf("empty endpoint URL on APIExportEndpointSlice %q", slice.Name)
 	}
@@ -350,10 +466,47 @@ func parseScopedKubeconfigExportSource(pc corev1alpha1.ProviderConnection) (endp
 	return endpointSliceName, apiExportName, nil
 }
 
-func createScopedKubeconfigURLForAPIExportName(operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, pcPath string, external bool) (string, error) {
-	hostPort := fmt.Sprintf("https://%s-front-proxy.%s:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.Namespace, operatorCfg.KCP.FrontProxyPort)
+// frontProxyBaseURL returns the scheme and host written into connection
+// kubeconfigs: hostOverride when set, the exposed kcp API when external, the
+// in-cluster front-proxy Service otherwise.
+func frontProxyBaseURL(operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, external bool, hostOverride string) (string, error) {
+	if hostOverride != "" {
+		u, err := parseHostOverride(hostOverride)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
 	if external {
-		hostPort = fmt.Sprintf("https://kcp.api.%s:%d", instance.Spec.Exposure.BaseDomain, instance.Spec.Exposure.Port)
+		if instance.Spec.Exposure == nil {
+			return "", fmt.Errorf("provider connection with external: true requires spec.exposure")
+		}
+		return fmt.Sprintf("https://kcp.api.%s:%d", instance.Spec.Exposure.BaseDomain, instance.Spec.Exposure.Port), nil
+	}
+	return fmt.Sprintf("https://%s-front-proxy.%s:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.Namespace, operatorCfg.KCP.FrontProxyPort), nil
+}
+
+// parseHostOverride parses a connection hostOverride into a URL with only
+// scheme and host set. A value without scheme uses https.
+func parseHostOverride(hostOverride string) (*url.URL, error) {
+	raw := hostOverride
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse hostOverride %q: %w", hostOverride, err)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, fmt.Errorf("hostOverride %q must be a host with optional scheme and port", hostOverride)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+func createScopedKubeconfigURLForAPIExportName(operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, pcPath string, external bool, hostOverride string) (string, error) {
+	hostPort, err := frontProxyBaseURL(operatorCfg, instance, external, hostOverride)
+	if err != nil {
+		return "", err
 	}
 	hostURL, err := url.JoinPath(hostPort, "clusters", pcPath)
 	if err != nil {
@@ -363,22 +516,20 @@ func createScopedKubeconfigURLForAPIExportName(operatorCfg config.OperatorConfig
 }
 
 // rewriteScopedVirtualWorkspaceURLToFrontProxy replaces the host from KCP's APIExportEndpointSlice status URL
-// with the same base URL used for admin provider kubeconfigs (in-cluster front-proxy Service DNS, or exposure URL when pc.External),
-// preserving path and raw query. This matches HandleProviderConnection's url.JoinPath(hostPort, address.Path) behavior.
-func rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL string, operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, pcExternal bool) (string, error) {
+// with the same base URL used for admin provider kubeconfigs (in-cluster front-proxy Service DNS, exposure URL when pc.External,
+// or pc.HostOverride), preserving path and raw query. This matches HandleProviderConnection's url.JoinPath(hostPort, address.Path) behavior.
+func rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL string, operatorCfg config.OperatorConfig, instance *corev1alpha1.PlatformMesh, pcExternal bool, hostOverride string) (string, error) {
 	u, err := url.Parse(hostURL)
 	if err != nil {
 		return "", fmt.Errorf("parse virtual workspace URL: %w", err)
 	}
-	if u.Path == "" || u.Path == "/" {
+	// Dot segments are resolved by url.JoinPath, so "/." has no path either.
+	if path.Clean("/"+u.Path) == "/" {
 		return "", fmt.Errorf("virtual workspace URL %q has no path", hostURL)
 	}
-	hostPort := fmt.Sprintf("https://%s-front-proxy.%s:%s", operatorCfg.KCP.FrontProxyName, operatorCfg.KCP.Namespace, operatorCfg.KCP.FrontProxyPort)
-	if pcExternal {
-		if instance.Spec.Exposure == nil {
-			return "", fmt.Errorf("provider connection with external: true requires spec.exposure")
-		}
-		hostPort = fmt.Sprintf("https://kcp.api.%s:%d", instance.Spec.Exposure.BaseDomain, instance.Spec.Exposure.Port)
+	hostPort, err := frontProxyBaseURL(operatorCfg, instance, pcExternal, hostOverride)
+	if err != nil {
+		return "", err
 	}
 	out, err := url.JoinPath(hostPort, u.Path)
 	if err != nil {
@@ -391,50 +542,68 @@ func rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL string, operatorCfg co
 	return out, nil
 }
 
-// writeScopedKubeconfigToSecret builds a scoped kubeconfig: ServiceAccount token in pc.Path, RBAC from APIExport; server is virtual workspace when endpointSliceName is set, else workspace cluster URL when apiExportName is set.
+// writeScopedKubeconfigToSecret builds a scoped kubeconfig: ServiceAccount token (or client certificate with authMode clientCert) in pc.Path, RBAC from APIExport; server is virtual workspace when endpointSliceName is set, else workspace cluster URL when apiExportName is set.
+// It reports whether the written kubeconfig passed the prober.
 func writeScopedKubeconfigToSecret(
 	ctx context.Context,
 	k8sClient client.Client,
 	kcpHelper KcpHelper,
+	prober ConnectionProber,
 	cfg *rest.Config,
 	instance *corev1alpha1.PlatformMesh,
 	pc corev1alpha1.ProviderConnection,
-) error {
+) (ready bool, err error) {
 	log := logger.LoadLoggerFromContext(ctx)
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
+	operatorCfg.Subroutines.ProviderSecret = connectionTokenConfig(operatorCfg.Subroutines.ProviderSecret, pc)
+
+	rbacUpToDate := false
+	var hostURL string
+	var tokenExpiresAt, clientCertExpiresAt *metav1.Time
+	var proposedRules []rbacv1.PolicyRule
+	var proposedRulesHash string
+	healthy := false
+	defer func() {
+		recordProviderConnectionStatus(instance, pc, rbacUpToDate, tokenExpiresAt, clientCertExpiresAt)
+		recordProposedRules(instance, pc, proposedRules, proposedRulesHash)
+		recordConnectionStatus(instance, pc.Secret, corev1alpha1.ConnectionTypeProvider, hostURL, healthy, ready)
+	}()
 
 	pcPath := strings.TrimSpace(pc.Path)
 	if pcPath == "" {
-		return fmt.Errorf("scoped kubeconfig requires Path (workspace)")
+		return false, fmt.Errorf("scoped kubeconfig requires Path (workspace)")
 	}
 
 	endpointSliceName, apiExportNameField, err := parseScopedKubeconfigExportSource(pc)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	kcpWorkspaceClient, err := kcpHelper.NewKcpClient(rest.CopyConfig(cfg), pcPath)
 	if err != nil {
-		return errors.Wrap(err, "kcp client for provider workspace")
+		return false, errors.Wrap(err, "kcp client for provider workspace")
 	}
 
-	var hostURL string
 	var apiExportName string
 	var exportWorkspacePath string
 
 	if endpointSliceName != "" {
 		var endpointSlice kcpapiv1alpha1.APIExportEndpointSlice
 		if err := kcpWorkspaceClient.Get(ctx, client.ObjectKey{Name: endpointSliceName}, &endpointSlice); err != nil {
-			return fmt.Errorf("get APIExportEndpointSlice %q in %s: %w", endpointSliceName, pcPath, err)
+			return false, fmt.Errorf("get APIExportEndpointSlice %q in %s: %w", endpointSliceName, pcPath, err)
+		}
+		endpointURL, err := resolveSliceEndpoint(ctx, kcpHelper, cfg, &endpointSlice, pc.EndpointSelector)
+		if err != nil {
+			return false, err
 		}
-		hostURL, err = virtualWorkspaceServerURLFromSlice(&endpointSlice)
+		hostURL, err = virtualWorkspaceServerURL(&endpointSlice, endpointURL)
 		if err != nil {
-			return err
+			return false, err
 		}
 		sliceStatusURL := hostURL
-		hostURL, err = rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL, operatorCfg, instance, pc.External)
+		hostURL, err = rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL, operatorCfg, instance, pc.External, pc.HostOverride)
 		if err != nil {
-			return errors.Wrap(err, "rewrite scoped virtual workspace URL to front-proxy base")
+			return false, errors.Wrap(err, "rewrite scoped virtual workspace URL to front-proxy base")
 		}
 		if hostURL != sliceStatusURL {
 			log.Info().
@@ -445,7 +614,7 @@ func writeScopedKubeconfigToSecret(
 		}
 		apiExportName, exportWorkspacePath, err = apiExportLocationFromEndpointSlice(&endpointSlice)
 		if err != nil {
-			return err
+			return false, err
 		}
 		log.Info().
 			Str("secret", pc.Secret).
@@ -457,9 +626,9 @@ func writeScopedKubeconfigToSecret(
 	} else {
 		apiExportName = apiExportNameField
 		exportWorkspacePath = pcPath
-		hostURL, err = createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, pcPath, pc.External)
+		hostURL, err = createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, pcPath, pc.External, pc.HostOverride)
 		if err != nil {
-			return err
+			return false, err
 		}
 		log.Info().
 			Str("secret", pc.Secret).
@@ -471,11 +640,22 @@ func writeScopedKubeconfigToSecret(
 
 	export, err := resolveAPIExport(ctx, kcpHelper, cfg, apiExportName, exportWorkspacePath)
 	if err != nil {
-		return errors.Wrap(err, "resolve APIExport")
+		return false, errors.Wrap(err, "resolve APIExport")
 	}
 	rules, err := getPolicyRulesFromAPIExport(export)
 	if err != nil {
-		return errors.Wrap(err, "build RBAC from APIExport")
+		return false, errors.Wrap(err, "build RBAC from APIExport")
+	}
+	if operatorCfg.Subroutines.ProviderSecret.RequireRBACApproval {
+		if hash := policyRulesHash(rules); !rbacApproved(instance, hash) {
+			proposedRules, proposedRulesHash = rules, hash
+			log.Info().Str("secret", pc.Secret).Str("rulesHash", hash).Msg("Waiting for approval of scoped provider RBAC")
+			if instance != nil {
+				recordEvent(ctx, instance, corev1.EventTypeNormal, EventReasonRBACApprovalRequired, "ProposeRBAC",
+					"RBAC of provider connection %s waits for approval of rules %s", pc.Secret, hash)
+			}
+			return false, nil
+		}
 	}
 
 	caData := cfg.TLSClientConfig.CAData
@@ -483,54 +663,152 @@ func writeScopedKubeconfigToSecret(
 		caData = []byte{}
 	}
 	caData = AppendRootShardCAPEMIfMissing(ctx, k8sClient, &operatorCfg, caData)
+	secretNamespace := ptr.Deref(pc.Namespace, instanceNamespace(instance))
+	entryName := instanceKubeconfigEntryName(instance, pc.Secret, pc.Path)
+	sourceHash := restConfigSourceHash(cfg)
+
+	if pc.AuthMode == corev1alpha1.ProviderAuthModeClientCert {
+		userName := scopedClientCertUserPrefix + pc.Secret
+		subject := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: userName}
+		if err := ensureScopedProviderRBAC(ctx, kcpWorkspaceClient, rules, pc.Secret, subject); err != nil {
+			return false, errors.Wrap(err, "ensure client certificate RBAC")
+		}
+		rbacUpToDate = true
 
-	saName, err := ensureScopedProviderServiceAccountAndRBAC(ctx, kcpWorkspaceClient, rules, pc.Secret)
-	if err != nil {
-		return errors.Wrap(err, "ensure ServiceAccount and RBAC")
+		cert, err := ensureScopedClientCert(ctx, k8sClient, operatorCfg, instance, pc.Secret, userName)
+		if err != nil {
+			return false, errors.Wrap(err, "ensure client certificate")
+		}
+		if cert == nil {
+			log.Info().Str("secret", pc.Secret).Msg("Waiting for the scoped client certificate to be issued")
+			return false, nil
+		}
+		data, err := scopedClientCertSecretData(entryName, hostURL, caData, cert)
+		if err != nil {
+			return false, errors.Wrap(err, "write kubeconfig")
+		}
+		_, err = writeProviderSecret(ctx, k8sClient, instance, pc.Secret, secretNamespace, SecretPurposeScoped, pc.Path, data,
+			map[string]string{SourceKubeconfigHashAnnotation: sourceHash})
+		if isSecretOwnedByOther(err) {
+			return false, err
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "write provider secret")
+		}
+		clientCertExpiresAt = &metav1.Time{Time: cert.expiresAt}
+		healthy = true
+		return probeConnection(ctx, prober, pc.Secret, data["kubeconfig"]), nil
 	}
 
-	token, err := createTokenForSA(ctx, kcpWorkspaceClient, defaultScopedSANamespace, saName, defaultTokenExpirationSeconds)
+	saNamespace := scopedSANamespace(pc)
+	saName, err := ensureScopedProviderServiceAccountAndRBAC(ctx, kcpWorkspaceClient, rules, pc.Secret, saNamespace)
 	if err != nil {
-		return errors.Wrap(err, "create token for ServiceAccount")
+		return false, errors.Wrap(err, "ensure ServiceAccount and RBAC")
 	}
-	kubeconfig := buildScopedKubeconfig(hostURL, token, caData)
-	kubeconfigBytes, err := clientcmd.Write(*kubeconfig)
-	if err != nil {
-		return errors.Wrap(err, "write kubeconfig")
+	rbacUpToDate = true
+
+	expiration, renewBefore := scopedTokenSettings(operatorCfg.Subroutines.ProviderSecret)
+	writeToken := func(token scopedToken) ([]byte, error) {
+		kubeconfig := buildScopedKubeconfig(entryName, hostURL, token.value, caData)
+		kubeconfigBytes, err := clientcmd.Write(*kubeconfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "write kubeconfig")
+		}
+		_, err = writeProviderSecret(ctx, k8sClient, instance, pc.Secret, secretNamespace, SecretPurposeScoped, pc.Path,
+			map[string][]byte{"kubeconfig": kubeconfigBytes},
+			map[string]string{
+				ScopedTokenExpiresAtAnnotation: token.expiresAt.UTC().Format(time.RFC3339),
+				SourceKubeconfigHashAnnotation: sourceHash,
+			})
+		if isSecretOwnedByOther(err) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "write provider secret")
+		}
+		tokenExpiresAt = &metav1.Time{Time: token.expiresAt}
+		return kubeconfigBytes, nil
+	}
+	issueToken := func() ([]byte, error) {
+		token, err := createTokenForSA(ctx, kcpWorkspaceClient, saNamespace, saName, int64(expiration.Seconds()))
+		if err != nil {
+			return nil, errors.Wrap(err, "create token for ServiceAccount")
+		}
+		return writeToken(token)
 	}
 
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{Name: pc.Secret, Namespace: ptr.Deref(pc.Namespace, operatorCfg.KCP.Namespace)},
+	var kubeconfigBytes []byte
+	token, reused := existingScopedToken(ctx, k8sClient, pc.Secret, secretNamespace, renewBefore)
+	if reused && token.sourceHash != sourceHash {
+		log.Info().Str("secret", pc.Secret).Msg("Re-issuing scoped token, the kcp admin kubeconfig changed")
+		reused = false
+	}
+	if reused {
+		kubeconfigBytes, err = writeToken(token)
+	} else {
+		kubeconfigBytes, err = issueToken()
 	}
-	_, err = controllerutil.CreateOrUpdate(ctx, k8sClient, secret, func() error {
-		secret.Data = map[string][]byte{"kubeconfig": kubeconfigBytes}
-		return nil
-	})
 	if err != nil {
-		return errors.Wrap(err, "write provider secret")
+		return false, err
+	}
+	healthy = true
+	ready = probeConnection(ctx, prober, pc.Secret, kubeconfigBytes)
+	if !ready && reused {
+		// A recreated ServiceAccount invalidates the tokens issued for it.
+		log.Info().Str("secret", pc.Secret).Msg("Re-issuing scoped token that is not accepted")
+		if kubeconfigBytes, err = issueToken(); err != nil {
+			return false, err
+		}
+		ready = probeConnection(ctx, prober, pc.Secret, kubeconfigBytes)
 	}
-	return nil
+	return ready, nil
+}
+
+// recordProviderConnectionStatus adds or updates the scoped connection in the instance status.
+func recordProviderConnectionStatus(instance *corev1alpha1.PlatformMesh, pc corev1alpha1.ProviderConnection, rbacUpToDate bool, tokenExpiresAt, clientCertExpiresAt *metav1.Time) {
+	if instance == nil {
+		return
+	}
+	for i, c := range instance.Status.ProviderConnections {
+		if c.Secret == pc.Secret && c.Path == pc.Path {
+			instance.Status.ProviderConnections[i].RBACUpToDate = rbacUpToDate
+			instance.Status.ProviderConnections[i].TokenExpiresAt = tokenExpiresAt
+			instance.Status.ProviderConnections[i].ClientCertExpiresAt = clientCertExpiresAt
+			instance.Status.ProviderConnections[i].SANamespace = pc.SANamespace
+			return
+		}
+	}
+	instance.Status.ProviderConnections = append(instance.Status.ProviderConnections, corev1alpha1.ProviderConnectionStatus{
+		Secret:              pc.Secret,
+		Path:                pc.Path,
+		RBACUpToDate:        rbacUpToDate,
+		TokenExpiresAt:      tokenExpiresAt,
+		ClientCertExpiresAt: clientCertExpiresAt,
+		SANamespace:         pc.SANamespace,
+	})
 }
 
-func buildScopedKubeconfig(hostURL string, token string, caData []byte) *clientcmdapi.Config {
+// buildScopedKubeconfig returns a kubeconfig with a single cluster, context
+// and user, all called name.
+func buildScopedKubeconfig(name, hostURL, token string, caData []byte) *clientcmdapi.Config {
 	return &clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{
-			"default-cluster": {
+			name: {
 				Server:                   hostURL,
 				CertificateAuthorityData: caData,
 			},
 		},
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{
-			"default-auth": {
+			name: {
 				Token: token,
 			},
 		},
 		Contexts: map[string]*clientcmdapi.Context{
-			"default-context": {
-				Cluster:  "default-cluster",
-				AuthInfo: "default-auth",
+			name: {
+				Cluster:  name,
+				AuthInfo: name,
 			},
 		},
-		CurrentContext: "default-context",
+		CurrentContext: name,
 	}
 }
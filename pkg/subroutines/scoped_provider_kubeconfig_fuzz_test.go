@@ -0,0 +1,69 @@
+package subroutines
+
+import (
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// FuzzRewriteScopedVirtualWorkspaceURLToFrontProxy rewrites arbitrary virtual
+// workspace URLs with arbitrary host overrides. The rewrite must never panic;
+// when it succeeds the result points at the front-proxy base URL with the
+// cleaned path and the query of the input, and rewriting it again changes
+// nothing.
+func FuzzRewriteScopedVirtualWorkspaceURLToFrontProxy(f *testing.F) {
+	seeds := []struct {
+		hostURL, hostOverride string
+		external              bool
+	}{
+		{"https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io/?watch=true", "", false},
+		{"https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io", "", true},
+		{"https://root.kcp.localhost:8443/services/apiexport/abc", "kcp-internal.platform-mesh-system.svc:6443", false},
+		{"https://root.kcp.localhost:8443/services/apiexport/abc", "http://kcp.example.com", true},
+		{"https://root.kcp.localhost:8443/", "", false},
+		{"https://root.kcp.localhost:8443/.", "", false},
+		{"/services/../apiexport//abc/", "", false},
+		{"https://h/a%2Fb%20c?x=%zz", "", false},
+		{"://bad", "", false},
+		{"https://h/p", "https://h/with/path", false},
+		{"https://h/p", "h:port?q", false},
+	}
+	for _, s := range seeds {
+		f.Add(s.hostURL, s.hostOverride, s.external)
+	}
+	operatorCfg := config.NewOperatorConfig()
+	instance := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{
+		Exposure: &corev1alpha1.ExposureConfig{BaseDomain: "example.com", Port: 8443},
+	}}
+
+	f.Fuzz(func(t *testing.T, hostURL, hostOverride string, external bool) {
+		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(hostURL, operatorCfg, instance, external, hostOverride)
+		if err != nil {
+			return
+		}
+
+		in, err := url.Parse(hostURL)
+		require.NoError(t, err)
+		out, err := url.Parse(got)
+		require.NoError(t, err, "rewritten URL %q does not parse", got)
+		base, err := frontProxyBaseURL(operatorCfg, instance, external, hostOverride)
+		require.NoError(t, err)
+		baseURL, err := url.Parse(base)
+		require.NoError(t, err)
+
+		assert.Equal(t, baseURL.Scheme, out.Scheme, "scheme of %q", got)
+		assert.Equal(t, baseURL.Host, out.Host, "host of %q", got)
+		assert.Equal(t, path.Clean("/"+in.Path), out.Path, "path of %q", got)
+		assert.Equal(t, in.RawQuery, out.RawQuery, "query of %q", got)
+
+		again, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(got, operatorCfg, instance, external, hostOverride)
+		require.NoError(t, err, "rewriting %q again", got)
+		assert.Equal(t, got, again, "rewrite is not idempotent")
+	})
+}
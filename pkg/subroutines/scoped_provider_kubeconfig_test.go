@@ -14,12 +14,18 @@ import (
 	"time"
 
 	kcpapiv1alpha1 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
 )
 
 func TestVirtualWorkspacePathFromSlice(t *testing.T) {
@@ -478,7 +484,7 @@ func TestRewriteScopedVirtualWorkspaceURLToFrontProxy(t *testing.T) {
 	t.Run("in-cluster: host rewritten, path+query preserved, trailing slash trimmed", func(t *testing.T) {
 		t.Parallel()
 		in := "https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io/?watch=true"
-		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(in, operatorCfg, instance, false)
+		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(in, operatorCfg, instance, false, "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -491,7 +497,7 @@ func TestRewriteScopedVirtualWorkspaceURLToFrontProxy(t *testing.T) {
 	t.Run("external: base uses exposure domain+port", func(t *testing.T) {
 		t.Parallel()
 		in := "https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io"
-		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(in, operatorCfg, instance, true)
+		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(in, operatorCfg, instance, true, "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -501,6 +507,33 @@ func TestRewriteScopedVirtualWorkspaceURLToFrontProxy(t *testing.T) {
 		}
 	})
 
+	t.Run("host override wins over external", func(t *testing.T) {
+		t.Parallel()
+		in := "https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io"
+		got, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(in, operatorCfg, instance, true, "kcp-internal.platform-mesh-system.svc:6443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "https://kcp-internal.platform-mesh-system.svc:6443/services/apiexport/abc/core.platform-mesh.io"
+		if got != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	})
+
+	t.Run("host override with path errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(
+			"https://root.kcp.localhost:8443/services/apiexport/abc/core.platform-mesh.io",
+			operatorCfg,
+			instance,
+			false,
+			"https://kcp.internal/clusters/root",
+		)
+		if err == nil || !strings.Contains(err.Error(), "must be a host") {
+			t.Fatalf("expected hostOverride error, got %v", err)
+		}
+	})
+
 	t.Run("external without exposure errors", func(t *testing.T) {
 		t.Parallel()
 		_, err := rewriteScopedVirtualWorkspaceURLToFrontProxy(
@@ -508,6 +541,7 @@ func TestRewriteScopedVirtualWorkspaceURLToFrontProxy(t *testing.T) {
 			operatorCfg,
 			&corev1alpha1.PlatformMesh{},
 			true,
+			"",
 		)
 		if err == nil || !strings.Contains(err.Error(), "requires spec.exposure") {
 			t.Fatalf("expected exposure error, got %v", err)
@@ -529,7 +563,7 @@ func TestCreateScopedKubeconfigURLForAPIExportName(t *testing.T) {
 	}
 
 	t.Run("in-cluster front-proxy URL for workspace path", func(t *testing.T) {
-		got, err := createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, "root:providers:provider2", false)
+		got, err := createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, "root:providers:provider2", false, "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -539,8 +573,19 @@ func TestCreateScopedKubeconfigURLForAPIExportName(t *testing.T) {
 		}
 	})
 
+	t.Run("host override keeps its scheme", func(t *testing.T) {
+		got, err := createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, "root:providers:provider2", false, "http://kcp.internal:8080")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "http://kcp.internal:8080/clusters/root:providers:provider2"
+		if got != want {
+			t.Fatalf("server URL: got %q want %q", got, want)
+		}
+	})
+
 	t.Run("external URL uses exposure domain and port", func(t *testing.T) {
-		got, err := createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, "root:providers:provider2", true)
+		got, err := createScopedKubeconfigURLForAPIExportName(operatorCfg, instance, "root:providers:provider2", true, "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -700,3 +745,172 @@ func TestMergeRootCAPEMIfMissing(t *testing.T) {
 		}
 	})
 }
+
+func TestPolicyRulesEqual(t *testing.T) {
+	t.Parallel()
+	a := []rbacv1.PolicyRule{
+		{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"get", "list"}},
+		{NonResourceURLs: []string{"/api"}, Verbs: []string{"get"}},
+	}
+	reordered := []rbacv1.PolicyRule{
+		{NonResourceURLs: []string{"/api"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"list", "get"}},
+	}
+	if !policyRulesEqual(a, reordered) {
+		t.Fatalf("expected reordered rules to be equal")
+	}
+	added := append(append([]rbacv1.PolicyRule(nil), a...), rbacv1.PolicyRule{APIGroups: []string{"g"}, Resources: []string{"new"}, Verbs: []string{"*"}})
+	if policyRulesEqual(a, added) {
+		t.Fatalf("expected rules with an added resource to differ")
+	}
+}
+
+func TestEnsureScopedClusterRole(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{"g"}, Resources: []string{"r"}, Verbs: []string{"*"}}}
+	cl := fake.NewClientBuilder().Build()
+
+	updated, err := ensureScopedClusterRole(ctx, cl, "platform-mesh-provider-p", rules)
+	if err != nil || updated {
+		t.Fatalf("create: updated=%v err=%v", updated, err)
+	}
+
+	updated, err = ensureScopedClusterRole(ctx, cl, "platform-mesh-provider-p", rules)
+	if err != nil || updated {
+		t.Fatalf("unchanged: updated=%v err=%v", updated, err)
+	}
+
+	rules = append(rules, rbacv1.PolicyRule{APIGroups: []string{"g"}, Resources: []string{"claimed"}, Verbs: []string{"get"}})
+	updated, err = ensureScopedClusterRole(ctx, cl, "platform-mesh-provider-p", rules)
+	if err != nil || !updated {
+		t.Fatalf("stale: updated=%v err=%v", updated, err)
+	}
+	cr := &rbacv1.ClusterRole{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: "platform-mesh-provider-p"}, cr); err != nil {
+		t.Fatalf("get ClusterRole: %v", err)
+	}
+	if len(cr.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(cr.Rules))
+	}
+}
+
+func TestRecordProviderConnectionStatus(t *testing.T) {
+	t.Parallel()
+	instance := &corev1alpha1.PlatformMesh{}
+	pc := corev1alpha1.ProviderConnection{Secret: "provider-kubeconfig", Path: "root:providers"}
+
+	expiresAt := &metav1.Time{Time: time.Now().Add(time.Hour)}
+	recordProviderConnectionStatus(instance, pc, false, nil, nil)
+	recordProviderConnectionStatus(instance, pc, true, expiresAt, nil)
+
+	if len(instance.Status.ProviderConnections) != 1 {
+		t.Fatalf("expected one status entry, got %d", len(instance.Status.ProviderConnections))
+	}
+	if !instance.Status.ProviderConnections[0].RBACUpToDate {
+		t.Fatalf("expected rbacUpToDate to be true")
+	}
+	if instance.Status.ProviderConnections[0].TokenExpiresAt != expiresAt {
+		t.Fatalf("expected tokenExpiresAt to be recorded")
+	}
+}
+
+func TestEnsureScopedProviderRBAC_FakeKcp(t *testing.T) {
+	t.Parallel()
+	server := fakekcp.New()
+	defer server.Close()
+	ctx := context.Background()
+
+	kcpClient, err := (&Helper{}).NewKcpClient(server.RestConfig(), fakekcp.RootCluster)
+	if err != nil {
+		t.Fatalf("kcp client: %v", err)
+	}
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{"core.platform-mesh.io"}, Resources: []string{"accounts"}, Verbs: []string{"*"}}}
+
+	saName, err := ensureScopedProviderServiceAccountAndRBAC(ctx, kcpClient, rules, "provider", defaultScopedSANamespace)
+	if err != nil {
+		t.Fatalf("ensure RBAC: %v", err)
+	}
+	if _, err := ensureScopedProviderServiceAccountAndRBAC(ctx, kcpClient, rules, "provider", defaultScopedSANamespace); err != nil {
+		t.Fatalf("ensure RBAC is not idempotent: %v", err)
+	}
+
+	token, err := createTokenForSA(ctx, kcpClient, defaultScopedSANamespace, saName, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if token.value == "" {
+		t.Fatalf("expected a token")
+	}
+	if time.Until(token.expiresAt) < 6*24*time.Hour {
+		t.Fatalf("expected the default expiration, got %s", token.expiresAt)
+	}
+
+	cr := &rbacv1.ClusterRole{}
+	if err := kcpClient.Get(ctx, client.ObjectKey{Name: scopedClusterRolePrefix + "provider"}, cr); err != nil {
+		t.Fatalf("get ClusterRole: %v", err)
+	}
+	if !policyRulesEqual(cr.Rules, rules) {
+		t.Fatalf("unexpected rules: %v", cr.Rules)
+	}
+}
+
+func TestDeleteScopedProviderRBAC_FakeKcp(t *testing.T) {
+	t.Parallel()
+	server := fakekcp.New()
+	defer server.Close()
+	ctx := context.Background()
+
+	kcpClient, err := (&Helper{}).NewKcpClient(server.RestConfig(), fakekcp.RootCluster)
+	if err != nil {
+		t.Fatalf("kcp client: %v", err)
+	}
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{"core.platform-mesh.io"}, Resources: []string{"accounts"}, Verbs: []string{"*"}}}
+	saName, err := ensureScopedProviderServiceAccountAndRBAC(ctx, kcpClient, rules, "provider", "providers")
+	if err != nil {
+		t.Fatalf("ensure RBAC: %v", err)
+	}
+
+	if err := deleteScopedProviderRBAC(ctx, kcpClient, "provider", "providers"); err != nil {
+		t.Fatalf("delete RBAC: %v", err)
+	}
+	if err := deleteScopedProviderRBAC(ctx, kcpClient, "provider", "providers"); err != nil {
+		t.Fatalf("delete RBAC is not idempotent: %v", err)
+	}
+
+	for _, obj := range []client.Object{
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: scopedClusterRolePrefix + "provider"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: scopedClusterRolePrefix + "provider"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: scopedWorkspaceAccessCRBPrefix + "provider"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: saName, Namespace: "providers"}},
+	} {
+		if err := kcpClient.Get(ctx, client.ObjectKeyFromObject(obj), obj); !kerrors.IsNotFound(err) {
+			t.Fatalf("expected %s to be deleted, got %v", obj.GetName(), err)
+		}
+	}
+}
+
+func TestScopedProviderConnections(t *testing.T) {
+	t.Parallel()
+	instance := &corev1alpha1.PlatformMesh{
+		Spec: corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{ProviderConnections: []corev1alpha1.ProviderConnection{
+			{Path: "root:providers", Secret: "scoped", APIExportName: ptr.To("export")},
+			{Path: "root", Secret: "admin", AdminAuth: ptr.To(true)},
+			{Path: "root", Secret: "mode-admin", Mode: corev1alpha1.ProviderConnectionModeAdmin},
+			{Path: "root", Secret: "mode-scoped", AdminAuth: ptr.To(true), Mode: corev1alpha1.ProviderConnectionModeScoped},
+		}}},
+		Status: corev1alpha1.PlatformMeshStatus{ProviderConnections: []corev1alpha1.ProviderConnectionStatus{
+			{Path: "root:providers", Secret: "scoped"},
+			{Path: "root:old", Secret: "removed", SANamespace: "providers"},
+		}},
+	}
+
+	var got []string
+	for _, pc := range scopedProviderConnections(instance) {
+		got = append(got, pc.Path+"/"+pc.Secret+"@"+scopedSANamespace(pc))
+	}
+	want := []string{"root:providers/scoped@default", "root/mode-scoped@default", "root:old/removed@providers"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package subroutines
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+// ScopedTokenExpiresAtAnnotation records on a scoped provider secret when the
+// ServiceAccount token in its kubeconfig expires. The token is reused until it
+// is due for renewal instead of being issued again on every reconciliation.
+const ScopedTokenExpiresAtAnnotation = "core.platform-mesh.io/token-expires-at"
+
+// scopedToken is a ServiceAccount token with its expiry. sourceHash is the
+// SourceKubeconfigHashAnnotation of the secret it was read from.
+type scopedToken struct {
+	value      string
+	expiresAt  time.Time
+	sourceHash string
+}
+
+// scopedTokenSettings returns the lifetime of new tokens and how long before
+// expiry they are renewed. A renewal period of at least half the lifetime
+// would renew tokens right after issuing them, so it is capped there.
+func scopedTokenSettings(cfg config.ProviderSecretSubroutineConfig) (expiration, renewBefore time.Duration) {
+	expiration = cfg.TokenExpiration
+	if expiration <= 0 {
+		expiration = defaultTokenExpirationSeconds * time.Second
+	}
+	renewBefore = cfg.TokenRenewBefore
+	if renewBefore <= 0 || renewBefore > expiration/2 {
+		renewBefore = expiration / 2
+	}
+	return expiration, renewBefore
+}
+
+// existingScopedToken returns the token of the scoped kubeconfig in secret
+// name/namespace. It reports false when there is no token with a recorded
+// expiry or when the token is due for renewal.
+func existingScopedToken(ctx context.Context, k8sClient client.Client, name, namespace string, renewBefore time.Duration) (scopedToken, bool) {
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, secret); err != nil {
+		return scopedToken{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[ScopedTokenExpiresAtAnnotation])
+	if err != nil || !time.Now().Before(expiresAt.Add(-renewBefore)) {
+		return scopedToken{}, false
+	}
+	kubeconfig, err := clientcmd.Load(secret.Data["kubeconfig"])
+	if err != nil {
+		return scopedToken{}, false
+	}
+	kubeCtx, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok {
+		return scopedToken{}, false
+	}
+	authInfo, ok := kubeconfig.AuthInfos[kubeCtx.AuthInfo]
+	if !ok || authInfo.Token == "" {
+		return scopedToken{}, false
+	}
+	return scopedToken{value: authInfo.Token, expiresAt: expiresAt, sourceHash: secret.Annotations[SourceKubeconfigHashAnnotation]}, true
+}
+
+// connectionTokenConfig returns cfg with the token lifetime of pc, if set.
+func connectionTokenConfig(cfg config.ProviderSecretSubroutineConfig, pc corev1alpha1.ProviderConnection) config.ProviderSecretSubroutineConfig {
+	if pc.TokenExpiration != nil && pc.TokenExpiration.Duration > 0 {
+		cfg.TokenExpiration = pc.TokenExpiration.Duration
+	}
+	return cfg
+}
+
+// connectionRenewBefore returns the renewal period of the connection with the
+// given secret and path, which depends on its token lifetime.
+func connectionRenewBefore(cfg config.ProviderSecretSubroutineConfig, connections []corev1alpha1.ProviderConnection) func(secret, path string) time.Duration {
+	_, fallback := scopedTokenSettings(cfg)
+	renewBefore := map[string]time.Duration{}
+	for _, pc := range connections {
+		_, renewBefore[pc.Secret+"/"+pc.Path] = scopedTokenSettings(connectionTokenConfig(cfg, pc))
+	}
+	return func(secret, path string) time.Duration {
+		if d, ok := renewBefore[secret+"/"+path]; ok {
+			return d
+		}
+		return fallback
+	}
+}
+
+// nextScopedTokenRenewal returns the time until the first scoped token or
+// client certificate in the status of instance is due for renewal, and false
+// when there is none. Renewed client certificates are copied into the provider
+// secret on the next reconciliation.
+func nextScopedTokenRenewal(instance *corev1alpha1.PlatformMesh, renewBefore func(secret, path string) time.Duration) (time.Duration, bool) {
+	var next *time.Time
+	for _, c := range instance.Status.ProviderConnections {
+		for _, expiresAt := range []*metav1.Time{c.TokenExpiresAt, c.ClientCertExpiresAt} {
+			if expiresAt == nil {
+				continue
+			}
+			renewAt := expiresAt.Add(-renewBefore(c.Secret, c.Path))
+			if next == nil || renewAt.Before(*next) {
+				next = &renewAt
+			}
+		}
+	}
+	if next == nil {
+		return 0, false
+	}
+	return max(time.Until(*next), DefaultRequeueInterval), true
+}
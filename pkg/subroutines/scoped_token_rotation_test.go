@@ -0,0 +1,139 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func TestScopedTokenSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.ProviderSecretSubroutineConfig
+		expiration  time.Duration
+		renewBefore time.Duration
+	}{
+		{name: "configured", cfg: config.ProviderSecretSubroutineConfig{TokenExpiration: 24 * time.Hour, TokenRenewBefore: 6 * time.Hour}, expiration: 24 * time.Hour, renewBefore: 6 * time.Hour},
+		{name: "defaults", expiration: 7 * 24 * time.Hour, renewBefore: 84 * time.Hour},
+		{name: "renewal capped", cfg: config.ProviderSecretSubroutineConfig{TokenExpiration: time.Hour, TokenRenewBefore: 2 * time.Hour}, expiration: time.Hour, renewBefore: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiration, renewBefore := scopedTokenSettings(tt.cfg)
+			assert.Equal(t, tt.expiration, expiration)
+			assert.Equal(t, tt.renewBefore, renewBefore)
+		})
+	}
+}
+
+func scopedTokenSecret(t *testing.T, token string, expiresAt time.Time) *corev1.Secret {
+	kubeconfig, err := clientcmd.Write(*buildScopedKubeconfig("test", "https://kcp.example", token, nil))
+	require.NoError(t, err)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "provider-kubeconfig", Namespace: "platform-mesh-system",
+			Annotations: map[string]string{ScopedTokenExpiresAtAnnotation: expiresAt.UTC().Format(time.RFC3339)},
+		},
+		Data: map[string][]byte{"kubeconfig": kubeconfig},
+	}
+}
+
+func TestExistingScopedToken(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(scopedTokenSecret(t, "valid", expiresAt)).Build()
+	token, ok := existingScopedToken(ctx, cl, "provider-kubeconfig", "platform-mesh-system", 24*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, "valid", token.value)
+	assert.True(t, expiresAt.Equal(token.expiresAt))
+	assert.Empty(t, token.sourceHash)
+
+	withSource := scopedTokenSecret(t, "valid", expiresAt)
+	withSource.Annotations[SourceKubeconfigHashAnnotation] = "0123456789abcdef"
+	sourceCl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(withSource).Build()
+	token, ok = existingScopedToken(ctx, sourceCl, "provider-kubeconfig", "platform-mesh-system", 24*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, "0123456789abcdef", token.sourceHash)
+
+	// Within the renewal period the token is issued again.
+	_, ok = existingScopedToken(ctx, cl, "provider-kubeconfig", "platform-mesh-system", 72*time.Hour)
+	assert.False(t, ok)
+
+	_, ok = existingScopedToken(ctx, cl, "missing", "platform-mesh-system", time.Hour)
+	assert.False(t, ok)
+
+	unknownExpiry := scopedTokenSecret(t, "valid", expiresAt)
+	unknownExpiry.Annotations = nil
+	cl = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(unknownExpiry).Build()
+	_, ok = existingScopedToken(ctx, cl, "provider-kubeconfig", "platform-mesh-system", time.Hour)
+	assert.False(t, ok)
+}
+
+func TestConnectionRenewBefore(t *testing.T) {
+	cfg := config.ProviderSecretSubroutineConfig{TokenExpiration: 24 * time.Hour, TokenRenewBefore: 6 * time.Hour}
+	renewBefore := connectionRenewBefore(cfg, []corev1alpha1.ProviderConnection{
+		{Secret: "short", Path: "root", TokenExpiration: &metav1.Duration{Duration: time.Hour}},
+		{Secret: "default", Path: "root"},
+	})
+	assert.Equal(t, 30*time.Minute, renewBefore("short", "root"))
+	assert.Equal(t, 6*time.Hour, renewBefore("default", "root"))
+	assert.Equal(t, 6*time.Hour, renewBefore("removed", "root"))
+
+	assert.Equal(t, time.Hour, connectionTokenConfig(cfg, corev1alpha1.ProviderConnection{TokenExpiration: &metav1.Duration{Duration: time.Hour}}).TokenExpiration)
+	assert.Equal(t, 24*time.Hour, connectionTokenConfig(cfg, corev1alpha1.ProviderConnection{}).TokenExpiration)
+}
+
+func fixedRenewBefore(d time.Duration) func(secret, path string) time.Duration {
+	return func(string, string) time.Duration { return d }
+}
+
+func TestNextScopedTokenRenewal(t *testing.T) {
+	instance := &corev1alpha1.PlatformMesh{}
+	_, ok := nextScopedTokenRenewal(instance, fixedRenewBefore(time.Hour))
+	assert.False(t, ok)
+
+	instance.Status.ProviderConnections = []corev1alpha1.ProviderConnectionStatus{
+		{Secret: "admin"},
+		{Secret: "later", TokenExpiresAt: &metav1.Time{Time: time.Now().Add(10 * time.Hour)}},
+		{Secret: "sooner", TokenExpiresAt: &metav1.Time{Time: time.Now().Add(3 * time.Hour)}},
+	}
+	renewIn, ok := nextScopedTokenRenewal(instance, fixedRenewBefore(time.Hour))
+	require.True(t, ok)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), renewIn.Seconds(), 5)
+
+	// Overdue tokens are renewed with the next regular requeue.
+	renewIn, _ = nextScopedTokenRenewal(instance, fixedRenewBefore(5*time.Hour))
+	assert.Equal(t, DefaultRequeueInterval, renewIn)
+
+	// Renewed client certificates are picked up as well.
+	instance.Status.ProviderConnections = append(instance.Status.ProviderConnections, corev1alpha1.ProviderConnectionStatus{
+		Secret: "cert", ClientCertExpiresAt: &metav1.Time{Time: time.Now().Add(2 * time.Hour)},
+	})
+	renewIn, _ = nextScopedTokenRenewal(instance, fixedRenewBefore(time.Hour))
+	assert.InDelta(t, time.Hour.Seconds(), renewIn.Seconds(), 5)
+
+	// Each connection renews within its own period.
+	instance.Status.ProviderConnections = []corev1alpha1.ProviderConnectionStatus{
+		{Secret: "long", Path: "root", TokenExpiresAt: &metav1.Time{Time: time.Now().Add(10 * time.Hour)}},
+		{Secret: "short", Path: "root", TokenExpiresAt: &metav1.Time{Time: time.Now().Add(4 * time.Hour)}},
+	}
+	renewIn, _ = nextScopedTokenRenewal(instance, func(secret, _ string) time.Duration {
+		if secret == "long" {
+			return 8 * time.Hour
+		}
+		return time.Hour
+	})
+	assert.InDelta(t, (2 * time.Hour).Seconds(), renewIn.Seconds(), 5)
+}
@@ -0,0 +1,126 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+
+	gcerrors "github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/client-go/rest"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcpcorev1alpha "github.com/kcp-dev/kcp/sdk/apis/core/v1alpha1"
+)
+
+const (
+	// rootShardName is the name of the shard serving the root workspace.
+	rootShardName = "root"
+	// shardLocalCluster is the logical cluster every shard other than the
+	// root shard serves for itself. The root workspace is only served by the
+	// root shard.
+	shardLocalCluster = "system:admin"
+)
+
+// shardDirectory holds the manifests applied to every shard. It sits next to
+// the kcp manifest directory.
+func (r *KcpsetupSubroutine) shardDirectory() string {
+	return r.kcpDirectory + "-shards"
+}
+
+// shardCluster returns the logical cluster the shard manifests are applied to
+// on the shard name: the root workspace on the root shard, and the shard-local
+// cluster on the others.
+func shardCluster(name string) string {
+	if name == rootShardName {
+		return "root"
+	}
+	return shardLocalCluster
+}
+
+// setupShards lists the shards of the kcp instance, reads the APIExport
+// identity hashes from the root workspace and applies the manifests of
+// shardDirectory on each shard. The result of every shard is stored in
+// status.shards. A failing shard does not stop the others; the number of
+// failed shards is returned.
+func (r *KcpsetupSubroutine) setupShards(ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh) (int, error) {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	if !r.cfg.Subroutines.KcpSetup.Shards {
+		inst.Status.Shards = nil
+		return 0, nil
+	}
+
+	rootClient, err := r.kcpHelper.NewKcpClient(rest.CopyConfig(config), "root")
+	if err != nil {
+		return 0, gcerrors.Wrap(err, "Failed to create kcp client for root workspace")
+	}
+	shards := &kcpcorev1alpha.ShardList{}
+	if err := rootClient.List(ctx, shards); err != nil {
+		return 0, gcerrors.Wrap(err, "Failed to list kcp shards")
+	}
+
+	// The APIExports live in the root workspace, so their identity hashes are
+	// the same on every shard.
+	hashes, err := r.getAPIExportHashInventory(ctx, rest.CopyConfig(config))
+	if err != nil {
+		return 0, gcerrors.Wrap(err, "Failed to read APIExport identity hashes")
+	}
+	var manifests bool
+	if _, err := os.Stat(r.shardDirectory()); err == nil {
+		manifests = true
+	}
+
+	statuses := make([]corev1alpha1.ShardStatus, 0, len(shards.Items))
+	failed := 0
+	for _, shard := range shards.Items {
+		status := corev1alpha1.ShardStatus{Name: shard.Name, BaseURL: shard.Spec.BaseURL, APIExportIdentityHashes: hashes}
+		if err := r.setupShard(ctx, config, inst, &status, manifests); err != nil {
+			log.Warn().Err(err).Str("shard", shard.Name).Msg("Failed to set up kcp shard")
+			status.Message = err.Error()
+			failed++
+		} else {
+			status.Ready = true
+		}
+		statuses = append(statuses, status)
+	}
+	inst.Status.Shards = statuses
+	return failed, nil
+}
+
+// setupShard applies the shard manifests to the cluster of the shard in
+// status, at the base URL of the shard.
+func (r *KcpsetupSubroutine) setupShard(
+	ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh, status *corev1alpha1.ShardStatus, manifests bool,
+) error {
+	if status.BaseURL == "" {
+		return gcerrors.New("shard %s has no base URL", status.Name)
+	}
+	if !manifests {
+		return nil
+	}
+	shardConfig := rest.CopyConfig(config)
+	shardConfig.Host = status.BaseURL
+
+	caBundles, err := r.getCABundleInventory(ctx, inst)
+	if err != nil {
+		return gcerrors.Wrap(err, "Failed to get CA bundle inventory")
+	}
+	templateData := make(map[string]any)
+	for k, v := range caBundles {
+		templateData[k] = v
+	}
+	for k, v := range status.APIExportIdentityHashes {
+		templateData[k] = v
+	}
+	for k, v := range r.instanceTemplateData(inst) {
+		templateData[k] = v
+	}
+	templateData["shardName"] = status.Name
+	templateData["shardBaseURL"] = status.BaseURL
+
+	if err := ApplyDirStructure(ctx, r.shardDirectory(), shardCluster(status.Name), shardConfig, templateData, inst, NewSharedObjectClaims(r.client, inst), r.kcpHelper); err != nil {
+		return gcerrors.Wrap(err, "Failed to apply shard manifests")
+	}
+	return nil
+}
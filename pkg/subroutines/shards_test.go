@@ -0,0 +1,105 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func kcpShard(name, baseURL string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("core.kcp.io/v1alpha1")
+	obj.SetKind("Shard")
+	obj.SetName(name)
+	obj.Object["spec"] = map[string]interface{}{"baseURL": baseURL}
+	return obj
+}
+
+func identityExports(hash string) []runtime.Object {
+	var objs []runtime.Object
+	for _, name := range []string{"tenancy.kcp.io", "shards.core.kcp.io", "topology.kcp.io"} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("apis.kcp.io/v1alpha1")
+		obj.SetKind("APIExport")
+		obj.SetName(name)
+		obj.Object["status"] = map[string]interface{}{"identityHash": hash + "-" + name}
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+func TestSetupShards(t *testing.T) {
+	root := fakekcp.New()
+	defer root.Close()
+	beta := fakekcp.New()
+	defer beta.Close()
+	require.NoError(t, root.AddObjects("root", identityExports("root")...))
+	require.NoError(t, root.AddObjects("root",
+		kcpShard("root", root.URL()), kcpShard("beta", beta.URL()), kcpShard("broken", "")))
+
+	kcpDir := filepath.Join(t.TempDir(), "kcp")
+	require.NoError(t, os.MkdirAll(kcpDir+"-shards", 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(kcpDir+"-shards", "clusterrole.yaml"), []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: shard-{{ .shardName }}
+  annotations:
+    tenancy-identity: "{{ .apiExportRootTenancyKcpIoIdentityHash }}"
+`), 0o644))
+
+	cfg := &config.OperatorConfig{}
+	cfg.Subroutines.KcpSetup.Shards = true
+	r := &KcpsetupSubroutine{kcpHelper: &Helper{}, kcpDirectory: kcpDir, cfg: cfg,
+		caBundleCache: map[string]map[string]string{"/": {"domainCA": "ca"}}}
+
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	inst := &corev1alpha1.PlatformMesh{}
+
+	failed, err := r.setupShards(ctx, root.RestConfig(), inst)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+	require.Len(t, inst.Status.Shards, 3)
+
+	byName := map[string]corev1alpha1.ShardStatus{}
+	for _, s := range inst.Status.Shards {
+		byName[s.Name] = s
+	}
+	assert.True(t, byName["root"].Ready)
+	assert.True(t, byName["beta"].Ready)
+	assert.Equal(t, "root-tenancy.kcp.io", byName["beta"].APIExportIdentityHashes["apiExportRootTenancyKcpIoIdentityHash"])
+	assert.False(t, byName["broken"].Ready)
+	assert.Equal(t, "shard broken has no base URL", byName["broken"].Message)
+
+	clusterRoles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	// The root shard gets the manifests in the root workspace, the other
+	// shards in their shard-local cluster.
+	role, ok := beta.Get(shardLocalCluster, clusterRoles, "", "shard-beta")
+	require.True(t, ok)
+	assert.Equal(t, "root-tenancy.kcp.io", role.GetAnnotations()["tenancy-identity"])
+	_, ok = beta.Get("root", clusterRoles, "", "shard-beta")
+	assert.False(t, ok)
+	_, ok = root.Get("root", clusterRoles, "", "shard-root")
+	assert.True(t, ok)
+
+	// Disabling the per-shard setup clears the status.
+	cfg.Subroutines.KcpSetup.Shards = false
+	failed, err = r.setupShards(ctx, root.RestConfig(), inst)
+	require.NoError(t, err)
+	assert.Zero(t, failed)
+	assert.Empty(t, inst.Status.Shards)
+}
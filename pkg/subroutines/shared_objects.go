@@ -0,0 +1,158 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// SharedObjectClaimAnnotation records the PlatformMesh instance, as
+	// <namespace>/<name>, that applies a cluster-scoped object. Operator
+	// replicas reconciling the same instance share the claim.
+	SharedObjectClaimAnnotation = "core.platform-mesh.io/claimed-by"
+
+	SharedObjectConflictConditionType    = "SharedObjectConflict"
+	sharedObjectConflictReasonDeployment = "DeploymentObjectClaimed"
+	sharedObjectConflictReasonKcp        = "KcpObjectClaimed"
+	sharedObjectConflictReasonFeature    = "FeatureObjectClaimed"
+)
+
+// SharedObjectClaimedError is returned when an object is claimed
+// by another PlatformMesh instance and therefore not applied.
+type SharedObjectClaimedError struct {
+	Kind      string
+	Name      string
+	Workspace string
+	Owner     string
+}
+
+func (e *SharedObjectClaimedError) Error() string {
+	if e.Workspace != "" {
+		return fmt.Sprintf("%s %s in workspace %s is claimed by PlatformMesh %s", e.Kind, e.Name, e.Workspace, e.Owner)
+	}
+	return fmt.Sprintf("%s %s is claimed by PlatformMesh %s", e.Kind, e.Name, e.Owner)
+}
+
+// SharedObjectClaims claims the objects applied for an instance, so that two
+// instances never overwrite each other's objects. A nil
+// *SharedObjectClaims claims nothing.
+type SharedObjectClaims struct {
+	// instances reads PlatformMesh objects to detect claims of deleted instances.
+	instances client.Client
+	inst      *corev1alpha1.PlatformMesh
+}
+
+func NewSharedObjectClaims(instances client.Client, inst *corev1alpha1.PlatformMesh) *SharedObjectClaims {
+	return &SharedObjectClaims{instances: instances, inst: inst}
+}
+
+func (c *SharedObjectClaims) owner() string {
+	return instanceKey(c.inst)
+}
+
+// claim labels obj with the instance, and annotates cluster-scoped objects
+// with its claim, unless the live object in k8sClient belongs to another
+// instance that still exists, in which case a *SharedObjectClaimedError is
+// returned. Namespaced objects belong to the instance they are labeled for.
+func (c *SharedObjectClaims) claim(ctx context.Context, k8sClient client.Client, obj *unstructured.Unstructured, wsPath string) error {
+	if c == nil || c.inst == nil {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx)
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := k8sClient.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, live)
+	if err != nil && !apierrors.IsNotFound(err) && !apimeta.IsNoMatchError(err) {
+		return err
+	}
+
+	owner := ""
+	if err == nil {
+		owner = live.GetAnnotations()[SharedObjectClaimAnnotation]
+		if owner == "" {
+			owner = labeledInstance(live)
+		}
+	}
+	if owner != "" && owner != c.owner() {
+		exists, err := c.instanceExists(ctx, owner)
+		if err != nil {
+			return err
+		}
+		name := obj.GetName()
+		if obj.GetNamespace() != "" {
+			name = obj.GetNamespace() + "/" + name
+		}
+		if exists {
+			return &SharedObjectClaimedError{Kind: obj.GetKind(), Name: name, Workspace: wsPath, Owner: owner}
+		}
+		log.Info().Str("kind", obj.GetKind()).Str("name", name).Str("previousOwner", owner).
+			Msg("Taking over claim of deleted PlatformMesh instance")
+	}
+
+	setInstanceLabels(obj, c.inst)
+	if obj.GetNamespace() != "" {
+		return nil
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[SharedObjectClaimAnnotation] = c.owner()
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+func (c *SharedObjectClaims) instanceExists(ctx context.Context, owner string) (bool, error) {
+	namespace, name, ok := strings.Cut(owner, "/")
+	if !ok {
+		return false, nil
+	}
+	err := c.instances.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &corev1alpha1.PlatformMesh{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// sharedObjectConflict reports a SharedObjectClaimedError through the
+// SharedObjectConflict condition instead of failing the reconciliation, so the
+// instances do not keep overwriting each other.
+func sharedObjectConflict(inst *corev1alpha1.PlatformMesh, err error, reason string, requeue time.Duration, log *logger.Logger) (subroutines.Result, bool) {
+	var claimedErr *SharedObjectClaimedError
+	if !stderrors.As(err, &claimedErr) {
+		return subroutines.Result{}, false
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               SharedObjectConflictConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            claimedErr.Error(),
+		ObservedGeneration: inst.Generation,
+	})
+	log.Warn().Str("kind", claimedErr.Kind).Str("name", claimedErr.Name).Str("workspace", claimedErr.Workspace).
+		Str("owner", claimedErr.Owner).Msg("Shared object is claimed by another PlatformMesh instance")
+	return subroutines.StopWithRequeue(requeue, claimedErr.Error()), true
+}
+
+// clearSharedObjectConflict removes the SharedObjectConflict condition if it
+// was set with reason, leaving conflicts reported by other subroutines intact.
+func clearSharedObjectConflict(inst *corev1alpha1.PlatformMesh, reason string) {
+	if cond := apimeta.FindStatusCondition(inst.Status.Conditions, SharedObjectConflictConditionType); cond != nil && cond.Reason == reason {
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, SharedObjectConflictConditionType)
+	}
+}
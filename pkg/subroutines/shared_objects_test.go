@@ -0,0 +1,161 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/suite"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+type SharedObjectClaimsTestSuite struct {
+	suite.Suite
+	scheme   *runtime.Scheme
+	instance *corev1alpha1.PlatformMesh
+	log      *logger.Logger
+}
+
+func TestSharedObjectClaimsTestSuite(t *testing.T) {
+	suite.Run(t, new(SharedObjectClaimsTestSuite))
+}
+
+func (s *SharedObjectClaimsTestSuite) SetupTest() {
+	s.scheme = runtime.NewScheme()
+	s.Require().NoError(clientgoscheme.AddToScheme(s.scheme))
+	s.Require().NoError(corev1alpha1.AddToScheme(s.scheme))
+	s.instance = &corev1alpha1.PlatformMesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform-mesh", Namespace: "platform-mesh-system", Generation: 2},
+	}
+	var err error
+	s.log, err = logger.New(logger.DefaultConfig())
+	s.Require().NoError(err)
+}
+
+func clusterRole(name, claimedBy string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	obj.SetKind("ClusterRole")
+	obj.SetName(name)
+	if claimedBy != "" {
+		obj.SetAnnotations(map[string]string{SharedObjectClaimAnnotation: claimedBy})
+	}
+	return obj
+}
+
+func (s *SharedObjectClaimsTestSuite) claim(cl client.Client, obj *unstructured.Unstructured) error {
+	return NewSharedObjectClaims(cl, s.instance).claim(context.Background(), cl, obj, "")
+}
+
+func (s *SharedObjectClaimsTestSuite) TestClaimNewObject() {
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).Build()
+	obj := clusterRole("shared", "")
+
+	s.Require().NoError(s.claim(cl, obj))
+	s.Equal("platform-mesh-system/platform-mesh", obj.GetAnnotations()[SharedObjectClaimAnnotation])
+}
+
+func (s *SharedObjectClaimsTestSuite) TestClaimOwnObject() {
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).
+		WithObjects(clusterRole("shared", "platform-mesh-system/platform-mesh")).Build()
+
+	s.Require().NoError(s.claim(cl, clusterRole("shared", "")))
+}
+
+func (s *SharedObjectClaimsTestSuite) TestClaimedByOtherInstance() {
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "tenant"}}
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).
+		WithObjects(other, clusterRole("shared", "tenant/other")).Build()
+	obj := clusterRole("shared", "")
+
+	err := s.claim(cl, obj)
+
+	var claimedErr *SharedObjectClaimedError
+	s.Require().ErrorAs(err, &claimedErr)
+	s.Equal("tenant/other", claimedErr.Owner)
+	s.Equal("ClusterRole shared is claimed by PlatformMesh tenant/other", err.Error())
+	s.Empty(obj.GetAnnotations())
+}
+
+func (s *SharedObjectClaimsTestSuite) TestClaimOfDeletedInstanceIsTakenOver() {
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).
+		WithObjects(clusterRole("shared", "tenant/gone")).Build()
+	obj := clusterRole("shared", "")
+
+	s.Require().NoError(s.claim(cl, obj))
+	s.Equal("platform-mesh-system/platform-mesh", obj.GetAnnotations()[SharedObjectClaimAnnotation])
+}
+
+func configMap(name, namespace string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func (s *SharedObjectClaimsTestSuite) TestNamespacedObjectsAreLabeled() {
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).Build()
+	obj := configMap("cm", "default", nil)
+
+	s.Require().NoError(s.claim(cl, obj))
+	s.Empty(obj.GetAnnotations())
+	s.Equal("platform-mesh", obj.GetLabels()[InstanceNameLabel])
+	s.Equal("platform-mesh-system", obj.GetLabels()[InstanceNamespaceLabel])
+
+	var claims *SharedObjectClaims
+	s.Require().NoError(claims.claim(context.Background(), cl, clusterRole("shared", ""), ""))
+}
+
+func (s *SharedObjectClaimsTestSuite) TestNamespacedObjectLabeledForOtherInstance() {
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "tenant"}}
+	otherLabels := map[string]string{InstanceNameLabel: "other", InstanceNamespaceLabel: "tenant"}
+	cl := fake.NewClientBuilder().WithScheme(s.scheme).
+		WithObjects(other).WithRuntimeObjects(configMap("cm", "default", otherLabels)).Build()
+
+	err := s.claim(cl, configMap("cm", "default", nil))
+
+	var claimedErr *SharedObjectClaimedError
+	s.Require().ErrorAs(err, &claimedErr)
+	s.Equal("ConfigMap default/cm is claimed by PlatformMesh tenant/other", err.Error())
+
+	// The labels of a deleted instance are taken over.
+	s.Require().NoError(cl.Delete(context.Background(), other))
+	obj := configMap("cm", "default", nil)
+	s.Require().NoError(s.claim(cl, obj))
+	s.Equal("platform-mesh", obj.GetLabels()[InstanceNameLabel])
+}
+
+func (s *SharedObjectClaimsTestSuite) TestSharedObjectConflictCondition() {
+	_, ok := sharedObjectConflict(s.instance, errors.New("other"), sharedObjectConflictReasonKcp, DefaultRequeueInterval, s.log)
+	s.False(ok)
+
+	err := &SharedObjectClaimedError{Kind: "APIExport", Name: "core.platform-mesh.io", Workspace: "root", Owner: "tenant/other"}
+	res, ok := sharedObjectConflict(s.instance, err, sharedObjectConflictReasonKcp, DefaultRequeueInterval, s.log)
+	s.Require().True(ok)
+	s.False(res.IsContinue())
+
+	cond := apimeta.FindStatusCondition(s.instance.Status.Conditions, SharedObjectConflictConditionType)
+	s.Require().NotNil(cond)
+	s.Equal(metav1.ConditionTrue, cond.Status)
+	s.Equal(sharedObjectConflictReasonKcp, cond.Reason)
+	s.Equal("APIExport core.platform-mesh.io in workspace root is claimed by PlatformMesh tenant/other", cond.Message)
+	s.Equal(int64(2), cond.ObservedGeneration)
+
+	// Only the subroutine that reported the conflict clears it.
+	clearSharedObjectConflict(s.instance, sharedObjectConflictReasonDeployment)
+	s.NotNil(apimeta.FindStatusCondition(s.instance.Status.Conditions, SharedObjectConflictConditionType))
+	clearSharedObjectConflict(s.instance, sharedObjectConflictReasonKcp)
+	s.Nil(apimeta.FindStatusCondition(s.instance.Status.Conditions, SharedObjectConflictConditionType))
+}
@@ -0,0 +1,100 @@
+package subroutines
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/platform-mesh/platform-mesh-operator/pkg/merge"
+)
+
+const (
+	SizingSmall  = "s"
+	SizingMedium = "m"
+	SizingLarge  = "l"
+	// SizingCustom applies no preset, only the per-component resources.
+	SizingCustom = "custom"
+
+	defaultResourcesPath = "resources"
+)
+
+// sizingPresets are the container resources rendered into every enabled
+// component for a landscape size. CPU is not limited to avoid throttling.
+var sizingPresets = map[string]map[string]interface{}{
+	SizingSmall: {
+		"requests": map[string]interface{}{"cpu": "50m", "memory": "64Mi"},
+		"limits":   map[string]interface{}{"memory": "256Mi"},
+	},
+	SizingMedium: {
+		"requests": map[string]interface{}{"cpu": "100m", "memory": "128Mi"},
+		"limits":   map[string]interface{}{"memory": "512Mi"},
+	},
+	SizingLarge: {
+		"requests": map[string]interface{}{"cpu": "250m", "memory": "256Mi"},
+		"limits":   map[string]interface{}{"memory": "1Gi"},
+	},
+}
+
+// applySizing writes the resources of the selected sizing preset into the
+// helm values of every enabled service. Resources already present in the
+// values take precedence over the preset, the service's own "resources" map
+// takes precedence over both. "resourcesPath" points to a dot-separated
+// location for charts not using the top-level "resources" key.
+func applySizing(values map[string]interface{}, services map[string]interface{}, log *logger.Logger) error {
+	sizing, _ := values["sizing"].(string)
+	sizing = strings.ToLower(sizing)
+	if sizing == "" {
+		return nil
+	}
+	preset, ok := sizingPresets[sizing]
+	if !ok && sizing != SizingCustom {
+		return fmt.Errorf("unknown sizing %q, expected one of s, m, l, custom", sizing)
+	}
+
+	for name, raw := range services {
+		service, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if enabled, _ := service["enabled"].(bool); !enabled {
+			continue
+		}
+		override, _ := service["resources"].(map[string]interface{})
+		if preset == nil && override == nil {
+			continue
+		}
+
+		path := defaultResourcesPath
+		if p, ok := service["resourcesPath"].(string); ok && p != "" {
+			path = p
+		}
+		fields := strings.Split(path, ".")
+
+		helmValues, _ := service["values"].(map[string]interface{})
+		if helmValues == nil {
+			helmValues = map[string]interface{}{}
+		}
+		existing, _, err := unstructured.NestedMap(helmValues, fields...)
+		if err != nil {
+			return fmt.Errorf("service %s: %s is not a map: %w", name, path, err)
+		}
+
+		resources := map[string]interface{}{}
+		for _, layer := range []map[string]interface{}{preset, existing, override} {
+			if layer == nil {
+				continue
+			}
+			resources, err = merge.MergeMaps(resources, layer, log)
+			if err != nil {
+				return fmt.Errorf("service %s: merge resources: %w", name, err)
+			}
+		}
+		if err := unstructured.SetNestedMap(helmValues, resources, fields...); err != nil {
+			return fmt.Errorf("service %s: set %s: %w", name, path, err)
+		}
+		service["values"] = helmValues
+	}
+	return nil
+}
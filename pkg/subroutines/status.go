@@ -0,0 +1,65 @@
+package subroutines
+
+import (
+	"github.com/platform-mesh/subroutines"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	DeploymentReadyConditionType      = "DeploymentReady"
+	WebhooksReadyConditionType        = "WebhooksReady"
+	KcpSetupReadyConditionType        = "KcpSetupReady"
+	ProviderSecretsReadyConditionType = "ProviderSecretsReady"
+
+	stepReadyReason = "Ready"
+)
+
+// stepStatus reports the progress of a subroutine as a readiness condition.
+// The subroutine records each step it enters and the condition carries the
+// step it stopped in as reason, so a stuck reconciliation shows where it is
+// stuck in kubectl get platformmesh.
+type stepStatus struct {
+	inst          *corev1alpha1.PlatformMesh
+	conditionType string
+	step          string
+}
+
+// trackSteps starts tracking conditionType of inst in step.
+func trackSteps(inst *corev1alpha1.PlatformMesh, conditionType, step string) *stepStatus {
+	return &stepStatus{inst: inst, conditionType: conditionType, step: step}
+}
+
+// enter records that the subroutine entered step. Steps are CamelCase as they
+// are used as condition reason.
+func (s *stepStatus) enter(step string) {
+	s.step = step
+}
+
+// done sets the condition from the outcome of the subroutine. An error or a
+// stopped chain is False, a pending result Unknown and everything else True.
+func (s *stepStatus) done(res subroutines.Result, err error) {
+	cond := metav1.Condition{
+		Type:               s.conditionType,
+		Reason:             s.step,
+		ObservedGeneration: s.inst.Generation,
+	}
+	switch {
+	case err != nil:
+		cond.Status = metav1.ConditionFalse
+		cond.Message = err.Error()
+	case res.IsPending():
+		cond.Status = metav1.ConditionUnknown
+		cond.Message = res.Message()
+	case res.IsStopWithRequeue() || res.IsStop():
+		cond.Status = metav1.ConditionFalse
+		cond.Message = res.Message()
+	default:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = stepReadyReason
+		cond.Message = "all steps completed"
+	}
+	apimeta.SetStatusCondition(&s.inst.Status.Conditions, cond)
+}
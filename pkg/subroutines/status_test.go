@@ -0,0 +1,46 @@
+package subroutines
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+func TestStepStatusDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		res     subroutines.Result
+		err     error
+		status  metav1.ConditionStatus
+		reason  string
+		message string
+	}{
+		{name: "error", res: subroutines.OK(), err: errors.New("boom"), status: metav1.ConditionFalse, reason: "ApplyingKcpManifests", message: "boom"},
+		{name: "stopped", res: subroutines.StopWithRequeue(DefaultRequeueInterval, "FrontProxy is not ready"), status: metav1.ConditionFalse, reason: "ApplyingKcpManifests", message: "FrontProxy is not ready"},
+		{name: "pending", res: subroutines.Pending(DefaultRequeueInterval, "not serving"), status: metav1.ConditionUnknown, reason: "ApplyingKcpManifests", message: "not serving"},
+		{name: "ok", res: subroutines.OK(), status: metav1.ConditionTrue, reason: stepReadyReason, message: "all steps completed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+			status := trackSteps(inst, KcpSetupReadyConditionType, "WaitingForRootShard")
+			status.enter("ApplyingKcpManifests")
+			status.done(tt.res, tt.err)
+
+			cond := apimeta.FindStatusCondition(inst.Status.Conditions, KcpSetupReadyConditionType)
+			require.NotNil(t, cond)
+			assert.Equal(t, tt.status, cond.Status)
+			assert.Equal(t, tt.reason, cond.Reason)
+			assert.Equal(t, tt.message, cond.Message)
+			assert.Equal(t, int64(3), cond.ObservedGeneration)
+		})
+	}
+}
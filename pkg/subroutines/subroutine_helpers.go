@@ -6,14 +6,16 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	stderrors "errors"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"text/template"
-	"time"
 
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	fluxcdv2 "github.com/fluxcd/helm-controller/api/v2"
@@ -37,7 +39,6 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
@@ -50,6 +51,8 @@ import (
 
 	"github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventing"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
 )
 
 type KcpHelper interface {
@@ -80,7 +83,7 @@ func (h *Helper) NewKcpClient(config *rest.Config, workspacePath string) (client
 	utilruntime.Must(admissionv1.AddToScheme(scheme))
 	utilruntime.Must(providers1alpha1.AddToScheme(scheme))
 
-	cl, err := client.New(config, client.Options{
+	cl, err := client.NewWithWatch(config, client.Options{
 		Scheme: scheme,
 	})
 	if err != nil {
@@ -353,60 +356,10 @@ func ListFiles(dir string) ([]string, error) {
 	return files, nil
 }
 
-func MergeValuesAndServices(inst *v1alpha1.PlatformMesh, templateVars apiextensionsv1.JSON, config config.OperatorConfig) (apiextensionsv1.JSON, error) {
-	services := inst.Spec.Values
-	var mapValues map[string]interface{}
-	if len(templateVars.Raw) > 0 {
-		if err := json.Unmarshal(templateVars.Raw, &mapValues); err != nil {
-			return apiextensionsv1.JSON{}, err
-		}
-	} else {
-		mapValues = map[string]interface{}{}
-	}
-	// Unmarshal 'services'
-	var mapServices map[string]interface{}
-	if len(services.Raw) > 0 {
-		if err := json.Unmarshal(services.Raw, &mapServices); err != nil {
-			return apiextensionsv1.JSON{}, err
-		}
-	} else {
-		mapServices = map[string]interface{}{}
-	}
-
-	// Create 'services' key in 'values' if it doesn't exist
-	if _, ok := mapValues["services"]; !ok {
-		mapValues["services"] = map[string]interface{}{}
-	}
-
-	// add 'services' to mapValues["services"]
-	if _, ok := mapValues["services"].(map[string]interface{}); !ok {
-		return apiextensionsv1.JSON{}, fmt.Errorf("services is not a map")
-	}
-	for k, v := range mapServices {
-		mapValues["services"].(map[string]interface{})[k] = v
-	}
-
-	mergeOCMConfig(mapValues, inst)
-
-	mapValues["kubeConfigEnabled"] = config.RemoteRuntime.IsEnabled()
-	if config.RemoteRuntime.IsEnabled() {
-		mapValues["kubeConfigSecretName"] = config.RemoteRuntime.InfraSecretName
-		mapValues["kubeConfigSecretKey"] = config.RemoteRuntime.InfraSecretKey
-	}
-
-	// Marshal back to apiextensionsv1.JSON
-	mergedRaw, err := json.Marshal(mapValues)
-	if err != nil {
-		return apiextensionsv1.JSON{}, err
-	}
-	return apiextensionsv1.JSON{Raw: mergedRaw}, nil
-
-}
-
 func baseDomainPortProtocol(inst *v1alpha1.PlatformMesh) (string, string, int, string) {
-	port := 8443
-	baseDomain := "portal.localhost"
-	protocol := "https"
+	port := DefaultExposurePort
+	baseDomain := DefaultExposureBaseDomain
+	protocol := DefaultExposureProtocol
 	baseDomainPort := ""
 
 	if inst.Spec.Exposure != nil {
@@ -421,12 +374,48 @@ func baseDomainPortProtocol(inst *v1alpha1.PlatformMesh) (string, string, int, s
 		}
 	}
 
+	baseDomainPort = domainWithPort(baseDomain, port)
+	return baseDomain, baseDomainPort, port, protocol
+}
+
+// domainWithPort returns domain with port appended unless port is 80 or 443.
+func domainWithPort(domain string, port int) string {
 	if port == 80 || port == 443 {
-		baseDomainPort = baseDomain
-	} else {
-		baseDomainPort = fmt.Sprintf("%s:%d", baseDomain, port)
+		return domain
+	}
+	return fmt.Sprintf("%s:%d", domain, port)
+}
+
+// exposureDomains returns the base domain of inst followed by its additional
+// base domains without duplicates, and the same domains with the port like
+// baseDomainPort.
+func exposureDomains(inst *v1alpha1.PlatformMesh) ([]string, []string) {
+	baseDomain, _, port, _ := baseDomainPortProtocol(inst)
+	domains := []string{baseDomain}
+	if inst.Spec.Exposure != nil {
+		for _, domain := range inst.Spec.Exposure.AdditionalBaseDomains {
+			if domain != "" && !slices.Contains(domains, domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	domainPorts := make([]string, len(domains))
+	for i, domain := range domains {
+		domainPorts[i] = domainWithPort(domain, port)
+	}
+	return domains, domainPorts
+}
+
+// domainTemplateData returns the template variables of all domains of inst:
+// baseDomains and baseDomainPorts start with baseDomain, additionalBaseDomains
+// holds the others.
+func domainTemplateData(inst *v1alpha1.PlatformMesh) map[string]any {
+	domains, domainPorts := exposureDomains(inst)
+	return map[string]any{
+		"baseDomains":           domains,
+		"additionalBaseDomains": domains[1:],
+		"baseDomainPorts":       domainPorts,
 	}
-	return baseDomain, baseDomainPort, port, protocol
 }
 
 func TemplateVars(ctx context.Context, inst *v1alpha1.PlatformMesh, cl client.Client) (apiextensionsv1.JSON, error) {
@@ -439,6 +428,7 @@ func TemplateVars(ctx context.Context, inst *v1alpha1.PlatformMesh, cl client.Cl
 		"baseDomainPort":       baseDomainPort,
 		"helmReleaseNamespace": inst.Namespace,
 	}
+	maps.Copy(values, domainTemplateData(inst))
 
 	result := apiextensionsv1.JSON{}
 	result.Raw, _ = json.Marshal(values)
@@ -451,35 +441,97 @@ func TemplateVars(ctx context.Context, inst *v1alpha1.PlatformMesh, cl client.Cl
 	return result, nil
 }
 
-func buildKubeconfig(ctx context.Context, client client.Client, kcpUrl string) (*rest.Config, error) {
+// buildKubeconfig builds the kcp admin config for inst, trying spec.kcp.adminSecretRefs
+// before the configured cluster admin secret. The secret in use is recorded in
+// inst.Status.AdminSecret; inst may be nil.
+func buildKubeconfig(ctx context.Context, client client.Client, inst *v1alpha1.PlatformMesh, kcpUrl string) (*rest.Config, error) {
 	operatorCfg := pmconfig.LoadConfigFromContext(ctx).(config.OperatorConfig)
-	return BuildKubeconfigFromConfig(client, &operatorCfg.KCP, kcpUrl)
+	var refs []v1alpha1.SecretReference
+	if inst != nil {
+		refs = inst.Spec.Kcp.AdminSecretRefs
+	}
+	cfg, used, err := BuildKubeconfigWithFallback(client, &operatorCfg.KCP, refs, kcpUrl)
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		return cfg, nil
+	}
+	if prev := inst.Status.AdminSecret; prev != nil && *prev != used {
+		logger.LoadLoggerFromContext(ctx).Warn().
+			Str("previous", prev.Namespace+"/"+prev.Name).
+			Str("secret", used.Namespace+"/"+used.Name).
+			Msg("Switched kcp admin credential")
+	}
+	inst.Status.AdminSecret = &used
+	return cfg, nil
+}
+
+// adminSecretCandidates returns the secrets to try for kcp admin access, in order.
+// The configured cluster admin secret is always the last resort.
+func adminSecretCandidates(kcpConfig *config.KCPConfig, refs []v1alpha1.SecretReference) []v1alpha1.SecretReference {
+	fallback := v1alpha1.SecretReference{Name: kcpConfig.ClusterAdminSecretName, Namespace: kcpConfig.Namespace}
+	candidates := make([]v1alpha1.SecretReference, 0, len(refs)+1)
+	for _, ref := range refs {
+		if ref.Name == "" {
+			continue
+		}
+		if ref.Namespace == "" {
+			ref.Namespace = kcpConfig.Namespace
+		}
+		if ref == fallback {
+			continue
+		}
+		candidates = append(candidates, ref)
+	}
+	return append(candidates, fallback)
+}
+
+// BuildKubeconfigWithFallback builds a *rest.Config for the kcp admin from the first
+// usable secret in refs, falling back to the configured cluster admin secret. It returns
+// the secret that was used.
+func BuildKubeconfigWithFallback(client client.Client, kcpConfig *config.KCPConfig, refs []v1alpha1.SecretReference, kcpUrl string) (*rest.Config, v1alpha1.SecretReference, error) {
+	var errs []error
+	for _, ref := range adminSecretCandidates(kcpConfig, refs) {
+		cfg, err := buildKubeconfigFromSecret(client, ref.Name, ref.Namespace, kcpUrl, kcpConfig.TLSServerName)
+		if err == nil {
+			return cfg, ref, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, v1alpha1.SecretReference{}, stderrors.Join(errs...)
 }
 
 // BuildKubeconfigFromConfig builds a *rest.Config for the kcp admin from the cluster-admin
 // certificate Secret. It is the exported equivalent of buildKubeconfigFromConfig.
 func BuildKubeconfigFromConfig(client client.Client, kcpConfig *config.KCPConfig, kcpUrl string) (*rest.Config, error) {
-	secretName := kcpConfig.ClusterAdminSecretName
-	secret, err := GetSecret(client, secretName, kcpConfig.Namespace)
+	return buildKubeconfigFromSecret(client, kcpConfig.ClusterAdminSecretName, kcpConfig.Namespace, kcpUrl, kcpConfig.TLSServerName)
+}
+
+func buildKubeconfigFromSecret(client client.Client, secretName, namespace, kcpUrl, tlsServerName string) (*rest.Config, error) {
+	secret, err := GetSecret(client, secretName, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("getting secret %s/%s: %w", kcpConfig.Namespace, secretName, err)
+		return nil, fmt.Errorf("getting secret %s/%s: %w", namespace, secretName, err)
 	}
 	if secret == nil {
-		return nil, fmt.Errorf("secret %s/%s is nil", kcpConfig.Namespace, secretName)
+		return nil, fmt.Errorf("secret %s/%s is nil", namespace, secretName)
 	}
 	if secret.Data == nil {
-		return nil, fmt.Errorf("secret %s/%s has no Data", kcpConfig.Namespace, secretName)
+		return nil, fmt.Errorf("secret %s/%s has no Data", namespace, secretName)
 	}
 
 	// Try kubeconfig key first (Opaque secret with pre-built kubeconfig)
 	if kubeconfigData, ok := secret.Data["kubeconfig"]; ok && len(kubeconfigData) > 0 {
 		cfg, err := clientcmd.Load(kubeconfigData)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse kubeconfig from secret %s/%s: %w", kcpConfig.Namespace, secretName, err)
+			return nil, fmt.Errorf("failed to parse kubeconfig from secret %s/%s: %w", namespace, secretName, err)
 		}
 		// Override the server URL in all clusters with the provided kcpUrl
 		for _, cluster := range cfg.Clusters {
 			cluster.Server = kcpUrl
+			if tlsServerName != "" {
+				cluster.TLSServerName = tlsServerName
+			}
 		}
 		return clientcmd.NewDefaultClientConfig(*cfg, nil).ClientConfig()
 	}
@@ -487,15 +539,15 @@ func BuildKubeconfigFromConfig(client client.Client, kcpConfig *config.KCPConfig
 	// Fall back to cert-based approach (kubernetes.io/tls secret with ca.crt, tls.crt, tls.key)
 	caData, ok := secret.Data["ca.crt"]
 	if !ok || len(caData) == 0 {
-		return nil, fmt.Errorf("secret %s/%s missing both \"kubeconfig\" and \"ca.crt\" keys", kcpConfig.Namespace, secretName)
+		return nil, fmt.Errorf("secret %s/%s missing both \"kubeconfig\" and \"ca.crt\" keys", namespace, secretName)
 	}
 	tlsCrt, ok := secret.Data["tls.crt"]
 	if !ok || len(tlsCrt) == 0 {
-		return nil, fmt.Errorf("secret %s/%s missing or empty key \"tls.crt\"", kcpConfig.Namespace, secretName)
+		return nil, fmt.Errorf("secret %s/%s missing or empty key \"tls.crt\"", namespace, secretName)
 	}
 	tlsKey, ok := secret.Data["tls.key"]
 	if !ok || len(tlsKey) == 0 {
-		return nil, fmt.Errorf("secret %s/%s missing or empty key \"tls.key\"", kcpConfig.Namespace, secretName)
+		return nil, fmt.Errorf("secret %s/%s missing or empty key \"tls.key\"", namespace, secretName)
 	}
 
 	cfg := clientcmdapi.NewConfig()
@@ -503,6 +555,7 @@ func BuildKubeconfigFromConfig(client client.Client, kcpConfig *config.KCPConfig
 		"kcp": {
 			Server:                   kcpUrl,
 			CertificateAuthorityData: caData,
+			TLSServerName:            tlsServerName,
 		},
 	}
 	cfg.Contexts = map[string]*clientcmdapi.Context{
@@ -521,41 +574,55 @@ func BuildKubeconfigFromConfig(client client.Client, kcpConfig *config.KCPConfig
 	return clientcmd.NewDefaultClientConfig(*cfg, nil).ClientConfig()
 }
 
-func WaitForWorkspace(
+// checkWorkspaceIn checks once whether the workspace name in the workspace
+// parentPath is ready. It returns a *WorkspaceNotReadyError if it is not.
+func checkWorkspaceIn(
 	ctx context.Context,
-	config *rest.Config, name string, log *logger.Logger,
+	config *rest.Config, parentPath, name string, log *logger.Logger,
 	kcpHelper KcpHelper,
 ) error {
-	client, err := kcpHelper.NewKcpClient(config, "root")
+	if plan.IsPlanning(ctx) {
+		// a planned workspace is never created, so there is nothing to check
+		return nil
+	}
+	client, err := kcpHelper.NewKcpClient(config, parentPath)
 	if err != nil {
 		return err
 	}
+	// workspaces below root are reported by their path
+	id := name
+	if parentPath != "root" {
+		id = parentPath + ":" + name
+	}
 
-	err = wait.PollUntilContextTimeout(
-		ctx, time.Second, time.Second*15, true,
-		func(ctx context.Context) (bool, error) {
-			ws := &kcptenancyv1alpha.Workspace{}
-			if err := client.Get(ctx, types.NamespacedName{Name: name}, ws); err != nil {
-				return false, nil //nolint:nilerr
-			}
-			ready := ws.Status.Phase == "Ready"
-			log.Info().Str("workspace", name).Bool("ready", ready).Msg("waiting for workspace to be ready")
-			return ready, nil
-		})
+	if err := checkWorkspace(ctx, client, parentPath+":"+name, name); err != nil {
+		return err
+	}
+	log.Debug().Str("workspace", id).Msg("Workspace is ready")
+	eventing.Default().EmitOnTransition("workspace/"+id, eventing.NewWorkspaceCreated(id))
+	return nil
+}
 
+func ApplyManifestFromFile(
+	ctx context.Context,
+	path string, k8sClient client.Client, templateData map[string]any, wsPath string, inst *v1alpha1.PlatformMesh, claims *SharedObjectClaims,
+) error {
+	manifestBytes, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("workspace %s did not become ready: %w", name, err)
+		return errors.Wrap(err, "Failed to read file, pwd: %s", path)
 	}
-	return err
+	return applyManifestTemplate(ctx, path, manifestBytes, k8sClient, templateData, wsPath, inst, claims)
 }
 
-func ApplyManifestFromFile(
+// applyManifestTemplate renders the manifest template read from path and applies it
+// to the workspace wsPath the way ApplyManifestFromFile applies a file.
+func applyManifestTemplate(
 	ctx context.Context,
-	path string, k8sClient client.Client, templateData map[string]any, wsPath string, inst *v1alpha1.PlatformMesh,
+	path string, manifest []byte, k8sClient client.Client, templateData map[string]any, wsPath string, inst *v1alpha1.PlatformMesh, claims *SharedObjectClaims,
 ) error {
 	log := logger.LoadLoggerFromContext(ctx)
 
-	obj, err := unstructuredFromFile(path, templateData, log)
+	obj, err := unstructuredFromManifest(path, manifest, templateData, log)
 	if err != nil {
 		return err
 	}
@@ -572,26 +639,8 @@ func ApplyManifestFromFile(
 	}
 
 	if obj.GetKind() == "WorkspaceType" && obj.GetAPIVersion() == "tenancy.kcp.io/v1alpha1" {
-		extraDefaultApiBindings := getExtraDefaultApiBindings(obj, wsPath, inst)
-		currentDefAPiBindings, found, err := unstructured.NestedSlice(obj.Object, "spec", "defaultAPIBindings")
-		if err != nil || !found {
-			currentDefAPiBindings = []interface{}{}
-		}
-		for _, v := range extraDefaultApiBindings {
-			newExport := kcptenancyv1alpha.APIExportReference{Path: v.Path, Export: v.Export}
-			var m map[string]interface{}
-			b, marshalErr := yaml.Marshal(newExport)
-			if marshalErr != nil {
-				return errors.Wrap(marshalErr, "Failed to marshal APIExportReference")
-			}
-			if unmarshalErr := yaml.Unmarshal(b, &m); unmarshalErr != nil {
-				return errors.Wrap(unmarshalErr, "Failed to unmarshal APIExportReference")
-			}
-			currentDefAPiBindings = append(currentDefAPiBindings, m)
-		}
-		err = unstructured.SetNestedSlice(obj.Object, currentDefAPiBindings, "spec", "defaultAPIBindings")
-		if err != nil {
-			return errors.Wrap(err, "Failed to set defaultAPIBindings")
+		if err := addExtraDefaultAPIBindings(ctx, &obj, wsPath, inst); err != nil {
+			return err
 		}
 	}
 
@@ -605,17 +654,27 @@ func ApplyManifestFromFile(
 		templateData["apiExportSystemPlatformMeshIoIdentityHash"] = apiExport.Status.IdentityHash
 	}
 
+	if err := claims.claim(ctx, k8sClient, &obj, wsPath); err != nil {
+		return err
+	}
+
 	err = k8sClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(&obj),
-		client.FieldOwner("platform-mesh-operator"), client.ForceOwnership)
+		client.FieldOwner(fieldManagerOperator), client.ForceOwnership)
 	if err != nil {
 		if obj.GetKind() == "IdentityProviderConfiguration" && obj.GetAPIVersion() == "core.platform-mesh.io/v1alpha1" {
 			log.Warn().Err(err).Str("file", path).Str("kind", obj.GetKind()).Str("name", obj.GetName()).
 				Msg("Failed to apply IdentityProviderConfiguration (webhook may not be ready yet), will retry on next reconciliation")
 			return nil
 		}
+		if recreateErr := detectRecreateRequired(ctx, k8sClient, &obj, wsPath, deletionPolicy(inst)); recreateErr != nil {
+			return recreateErr
+		}
 		return errors.Wrap(err, "Failed to apply manifest file: %s (%s/%s)", path, obj.GetKind(), obj.GetName())
 	}
 	log.Info().Str("file", path).Str("kind", obj.GetKind()).Str("name", obj.GetName()).Msg("Applied manifest file")
+	if obj.GetKind() == "APIBinding" && obj.GroupVersionKind().Group == kcpapiv1alpha.SchemeGroupVersion.Group {
+		apiBindingWaitFrom(ctx).add(wsPath, obj.GetName())
+	}
 	return nil
 }
 
@@ -626,6 +685,7 @@ func ApplyDirStructure(
 	config *rest.Config,
 	templateData map[string]any,
 	inst *v1alpha1.PlatformMesh,
+	claims *SharedObjectClaims,
 	kcpHelper KcpHelper,
 ) error {
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", "")
@@ -644,15 +704,16 @@ func ApplyDirStructure(
 	for _, file := range files {
 		log.Debug().Str("file", file).Msg("Applying file")
 		path := filepath.Join(dir, file)
-		err := ApplyManifestFromFile(ctx, path, k8sClient, templateData, kcpPath, inst)
+		err := ApplyManifestFromFile(ctx, path, k8sClient, templateData, kcpPath, inst, claims)
 		if err != nil {
 			log.Warn().Err(err).Str("file", path).Msg("Failed to apply manifest file, continuing to next file in directory")
-			errApplyManifests = err
+			errApplyManifests = keepApplyError(errApplyManifests, err)
 		}
 	}
 	if errApplyManifests != nil {
 		return errApplyManifests
 	}
+	apiBindingWaitFrom(ctx).check(ctx, k8sClient, kcpPath, inst, log)
 
 	for _, wsDir := range GetWorkspaceDirs(dir) {
 		wsName, err := GetWorkspaceName(wsDir)
@@ -666,13 +727,16 @@ func ApplyDirStructure(
 			// while already at "root"), so there is no child workspace to wait for.
 			wsPath = kcpPath
 		} else {
-			err = WaitForWorkspace(ctx, config, wsName, log, kcpHelper)
+			err = checkWorkspaceIn(ctx, config, kcpPath, wsName, log, kcpHelper)
+			if workspaceWaitFrom(ctx).skip(ctx, err, inst) {
+				continue
+			}
 			if err != nil {
 				return err
 			}
 		}
 
-		err = ApplyDirStructure(ctx, dir+"/"+wsDir, wsPath, config, templateData, inst, kcpHelper)
+		err = ApplyDirStructure(ctx, dir+"/"+wsDir, wsPath, config, templateData, inst, claims, kcpHelper)
 		if err != nil {
 			return err
 		}
@@ -681,6 +745,18 @@ func ApplyDirStructure(
 	return nil
 }
 
+// keepApplyError returns the error to report after err failed a manifest. A
+// recreate or claim error is kept over later errors so that it can be
+// surfaced as a condition.
+func keepApplyError(kept, err error) error {
+	var recreateErr *RecreateRequiredError
+	var claimedErr *SharedObjectClaimedError
+	if stderrors.As(kept, &recreateErr) || stderrors.As(kept, &claimedErr) {
+		return kept
+	}
+	return err
+}
+
 func matchesConditionWithStatus(resource *unstructured.Unstructured, conditionType string, conditionStatus string) bool {
 	if resource == nil {
 		return false
@@ -708,7 +784,12 @@ func unstructuredFromFile(path string, templateData map[string]any, log *logger.
 	if err != nil {
 		return unstructured.Unstructured{}, errors.Wrap(err, "Failed to read file, pwd: %s", path)
 	}
+	return unstructuredFromManifest(path, manifestBytes, templateData, log)
+}
 
+// unstructuredFromManifest renders the manifest template read from path with
+// templateData.
+func unstructuredFromManifest(path string, manifestBytes []byte, templateData map[string]any, log *logger.Logger) (unstructured.Unstructured, error) {
 	res, err := ReplaceTemplate(templateData, manifestBytes)
 	if err != nil {
 		return unstructured.Unstructured{}, errors.Wrap(err, "Failed to replace template with path: %s", path)
@@ -786,7 +867,18 @@ func GetClientScheme() *runtime.Scheme {
 	return scheme
 }
 
-func GetDeploymentTechnologyFromProfile(ctx context.Context, cl client.Client, inst *v1alpha1.PlatformMesh) (string, error) {
+// loadProfile reads the profile ConfigMap of inst, or merges its
+// spec.profiles, with cl and returns the profile resolved for the channel of
+// inst.
+func loadProfile(ctx context.Context, cl client.Client, inst *v1alpha1.PlatformMesh) (map[string]interface{}, error) {
+	if len(inst.Spec.Profiles) > 0 {
+		profileYAML, err := mergeProfileLayers(ctx, cl, inst)
+		if err != nil {
+			return nil, err
+		}
+		return resolveProfile(profileYAML, inst, logger.LoadLoggerFromContext(ctx))
+	}
+
 	var configMapName, configMapNamespace string
 	if inst.Spec.ProfileConfigMap != nil {
 		configMapName = inst.Spec.ProfileConfigMap.Name
@@ -801,17 +893,25 @@ func GetDeploymentTechnologyFromProfile(ctx context.Context, cl client.Client, i
 
 	configMap := &corev1.ConfigMap{}
 	if err := cl.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: configMapNamespace}, configMap); err != nil {
-		return "", fmt.Errorf("failed to get profile ConfigMap %s/%s: %w", configMapNamespace, configMapName, err)
+		return nil, fmt.Errorf("failed to get profile ConfigMap %s/%s: %w", configMapNamespace, configMapName, err)
 	}
 
 	profileYAML, ok := configMap.Data["profile.yaml"]
 	if !ok {
-		return "", fmt.Errorf("profile ConfigMap %s/%s does not contain key 'profile.yaml'", configMapNamespace, configMapName)
+		return nil, fmt.Errorf("profile ConfigMap %s/%s does not contain key 'profile.yaml'", configMapNamespace, configMapName)
 	}
 
-	var profile map[string]interface{}
-	if err := yaml.Unmarshal([]byte(profileYAML), &profile); err != nil {
-		return "", fmt.Errorf("failed to parse profile YAML from ConfigMap %s/%s: %w", configMapNamespace, configMapName, err)
+	profile, err := resolveProfile(profileYAML, inst, logger.LoadLoggerFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve profile from ConfigMap %s/%s: %w", configMapNamespace, configMapName, err)
+	}
+	return profile, nil
+}
+
+func GetDeploymentTechnologyFromProfile(ctx context.Context, cl client.Client, inst *v1alpha1.PlatformMesh) (string, error) {
+	profile, err := loadProfile(ctx, cl, inst)
+	if err != nil {
+		return "", err
 	}
 
 	if infra, ok := profile["infra"].(map[string]interface{}); ok {
@@ -10,12 +10,17 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/platform-mesh/golang-commons/context/keys"
 	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
 	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
@@ -65,6 +70,134 @@ func TestAppendPEMCertsDedupe(t *testing.T) {
 	require.Equal(t, wantN, countPEMCertificateBlocks(t, got2), "appending same bundle again should not duplicate")
 }
 
+func adminCertSecret(name, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data: map[string][]byte{
+			"ca.crt":  []byte("test-ca-data"),
+			"tls.crt": []byte("test-tls-crt"),
+			"tls.key": []byte("test-tls-key"),
+		},
+	}
+}
+
+func TestBuildKubeconfigWithFallback(t *testing.T) {
+	kcpCfg := &config.KCPConfig{Namespace: "platform-mesh-system", ClusterAdminSecretName: "kcp-cluster-admin-client-cert"}
+	incomplete := adminCertSecret("primary", "platform-mesh-system")
+	delete(incomplete.Data, "tls.key")
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		refs    []corev1alpha1.SecretReference
+		want    corev1alpha1.SecretReference
+		wantErr bool
+	}{
+		{
+			name:    "no refs uses configured secret",
+			objects: []client.Object{adminCertSecret("kcp-cluster-admin-client-cert", "platform-mesh-system")},
+			want:    corev1alpha1.SecretReference{Name: "kcp-cluster-admin-client-cert", Namespace: "platform-mesh-system"},
+		},
+		{
+			name: "first usable ref wins",
+			objects: []client.Object{
+				adminCertSecret("primary", "platform-mesh-system"),
+				adminCertSecret("secondary", "other"),
+			},
+			refs: []corev1alpha1.SecretReference{{Name: "primary"}, {Name: "secondary", Namespace: "other"}},
+			want: corev1alpha1.SecretReference{Name: "primary", Namespace: "platform-mesh-system"},
+		},
+		{
+			name:    "fails over past missing and incomplete secrets",
+			objects: []client.Object{incomplete, adminCertSecret("secondary", "other")},
+			refs:    []corev1alpha1.SecretReference{{Name: "missing"}, {Name: "primary"}, {Name: "secondary", Namespace: "other"}},
+			want:    corev1alpha1.SecretReference{Name: "secondary", Namespace: "other"},
+		},
+		{
+			name:    "falls back to configured secret",
+			objects: []client.Object{adminCertSecret("kcp-cluster-admin-client-cert", "platform-mesh-system")},
+			refs:    []corev1alpha1.SecretReference{{Name: "missing"}},
+			want:    corev1alpha1.SecretReference{Name: "kcp-cluster-admin-client-cert", Namespace: "platform-mesh-system"},
+		},
+		{
+			name:    "no usable secret",
+			refs:    []corev1alpha1.SecretReference{{Name: "missing"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			cfg, used, err := BuildKubeconfigWithFallback(cl, kcpCfg, tt.refs, "https://kcp.example.com")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "https://kcp.example.com", cfg.Host)
+			require.Equal(t, tt.want, used)
+		})
+	}
+}
+
+func TestBuildKubeconfigFromConfigTLSServerName(t *testing.T) {
+	kcpCfg := &config.KCPConfig{
+		Namespace:              "platform-mesh-system",
+		ClusterAdminSecretName: "kcp-cluster-admin-client-cert",
+		TLSServerName:          "frontproxy-front-proxy.platform-mesh-system",
+	}
+	cl := fake.NewClientBuilder().WithObjects(adminCertSecret("kcp-cluster-admin-client-cert", "platform-mesh-system")).Build()
+
+	cfg, err := BuildKubeconfigFromConfig(cl, kcpCfg, "https://localhost:8443")
+
+	require.NoError(t, err)
+	require.Equal(t, "https://localhost:8443", cfg.Host)
+	require.Equal(t, "frontproxy-front-proxy.platform-mesh-system", cfg.ServerName)
+}
+
+func TestAdminSecretCandidates(t *testing.T) {
+	kcpCfg := &config.KCPConfig{Namespace: "ns", ClusterAdminSecretName: "admin"}
+	got := adminSecretCandidates(kcpCfg, []corev1alpha1.SecretReference{{Name: "admin"}, {}, {Name: "a", Namespace: "x"}})
+	require.Equal(t, []corev1alpha1.SecretReference{{Name: "a", Namespace: "x"}, {Name: "admin", Namespace: "ns"}}, got)
+}
+
+func TestDomainTemplateData(t *testing.T) {
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Exposure: &corev1alpha1.ExposureConfig{
+		BaseDomain:            "example.com",
+		Port:                  8443,
+		AdditionalBaseDomains: []string{"example.org", "", "example.com", "example.org", "example.net"},
+	}}}
+	require.Equal(t, map[string]any{
+		"baseDomains":           []string{"example.com", "example.org", "example.net"},
+		"additionalBaseDomains": []string{"example.org", "example.net"},
+		"baseDomainPorts":       []string{"example.com:8443", "example.org:8443", "example.net:8443"},
+	}, domainTemplateData(inst))
+
+	inst.Spec.Exposure = nil
+	domains, domainPorts := exposureDomains(inst)
+	require.Equal(t, []string{"portal.localhost"}, domains)
+	require.Equal(t, []string{"portal.localhost:8443"}, domainPorts)
+}
+
+func TestBuildKubeconfigRecordsAdminSecret(t *testing.T) {
+	operatorCfg := config.OperatorConfig{}
+	operatorCfg.KCP.Namespace = "platform-mesh-system"
+	operatorCfg.KCP.ClusterAdminSecretName = "kcp-cluster-admin-client-cert"
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(t.Context(), keys.LoggerCtxKey, log)
+	ctx = context.WithValue(ctx, keys.ConfigCtxKey, operatorCfg)
+
+	inst := &corev1alpha1.PlatformMesh{}
+	inst.Spec.Kcp.AdminSecretRefs = []corev1alpha1.SecretReference{{Name: "primary"}}
+	inst.Status.AdminSecret = &corev1alpha1.SecretReference{Name: "primary", Namespace: "platform-mesh-system"}
+	cl := fake.NewClientBuilder().WithObjects(adminCertSecret("kcp-cluster-admin-client-cert", "platform-mesh-system")).Build()
+
+	_, err = buildKubeconfig(ctx, cl, inst, "https://kcp.example.com")
+	require.NoError(t, err)
+	require.Equal(t, &corev1alpha1.SecretReference{Name: "kcp-cluster-admin-client-cert", Namespace: "platform-mesh-system"}, inst.Status.AdminSecret)
+}
+
 func (s *HelperTestSuite) TestGetWorkspaceName() {
 	tests := []struct {
 		input       string
@@ -335,21 +468,22 @@ func (s *HelperTestSuite) TestApplyManifestFromFile() {
 	cl := new(mocks.Client)
 	// Server-side apply (no Get needed)
 	cl.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
-	err := ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/workspace-platform-mesh-system.yaml", cl, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+	err := ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/workspace-platform-mesh-system.yaml", cl, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Nil(err)
 
-	err = ApplyManifestFromFile(s.T().Context(), "invalid", nil, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+	err = ApplyManifestFromFile(s.T().Context(), "invalid", nil, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Error(err)
 
-	err = ApplyManifestFromFile(s.T().Context(), "./kcpsetup.go", nil, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+	err = ApplyManifestFromFile(s.T().Context(), "./kcpsetup.go", nil, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Error(err)
 
 	cl.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error")).Once()
-	err = ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/workspace-platform-mesh-system.yaml", cl, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+	cl.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found")).Once()
+	err = ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/workspace-platform-mesh-system.yaml", cl, make(map[string]any), "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Error(err)
 
 	cl.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
-	err = ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/02-root/workspace-orgs.yaml", cl, make(map[string]any), "root:orgs", &corev1alpha1.PlatformMesh{})
+	err = ApplyManifestFromFile(s.T().Context(), "../../manifests/kcp/02-root/workspace-orgs.yaml", cl, make(map[string]any), "root:orgs", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Nil(err)
 
 	cl.EXPECT().Apply(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
@@ -361,6 +495,6 @@ func (s *HelperTestSuite) TestApplyManifestFromFile() {
 		KCP: config.OperatorConfig{}.KCP,
 	}
 	ctx := context.WithValue(s.T().Context(), keys.ConfigCtxKey, operatorCfg)
-	err = ApplyManifestFromFile(ctx, "../../manifests/kcp/04-platform-mesh-system/mutatingwebhookconfiguration-admissionregistration.k8s.io.yaml", cl, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{})
+	err = ApplyManifestFromFile(ctx, "../../manifests/kcp/04-platform-mesh-system/mutatingwebhookconfiguration-admissionregistration.k8s.io.yaml", cl, templateData, "root:platform-mesh-system", &corev1alpha1.PlatformMesh{}, nil)
 	s.Assert().Nil(err)
 }
@@ -17,8 +17,8 @@ func (r *KcpsetupSubroutine) GetCaBundle(ctx context.Context, webhookConfig *cor
 	return r.getCaBundle(ctx, webhookConfig)
 }
 
-func (r *KcpsetupSubroutine) GetCABundleInventory(ctx context.Context) (map[string]string, error) {
-	return r.getCABundleInventory(ctx)
+func (r *KcpsetupSubroutine) GetCABundleInventory(ctx context.Context, inst *corev1alpha1.PlatformMesh) (map[string]string, error) {
+	return r.getCABundleInventory(ctx, inst)
 }
 
 func (r *KcpsetupSubroutine) CreateKcpResources(ctx context.Context, config *rest.Config, dir string, inst *corev1alpha1.PlatformMesh) error {
@@ -0,0 +1,117 @@
+package subroutines
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// templateContractPattern matches a contract header line of a template, a
+// template comment declaring the inputs the template requires:
+//
+//	{{- /* requires: releaseNamespace, values.services.*.chart */ -}}
+var templateContractPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*requires:(.*?)\*/\s*-?\}\}$`)
+
+// templateInputAliases maps template inputs to the name the same value has in
+// the data of other templates, e.g. the component templates get the base
+// domain with port as baseDomainWithPort, all others as baseDomainPort.
+var templateInputAliases = map[string]string{
+	"baseDomainWithPort": "baseDomainPort",
+	"baseDomainPort":     "baseDomainWithPort",
+}
+
+// templateContractError is returned for a template whose data lacks inputs
+// required by its contract header. renamed maps missing inputs to the alias
+// the data holds instead.
+type templateContractError struct {
+	path    string
+	missing []string
+	renamed map[string]string
+}
+
+func (e *templateContractError) Error() string {
+	inputs := make([]string, 0, len(e.missing))
+	for _, input := range e.missing {
+		if alias, ok := e.renamed[input]; ok {
+			input = fmt.Sprintf("%s (renamed to %s)", input, alias)
+		}
+		inputs = append(inputs, input)
+	}
+	return fmt.Sprintf("template %s misses required inputs: %s", e.path, strings.Join(inputs, ", "))
+}
+
+// templateContract returns the inputs declared by the contract header of a
+// template, the contract lines before its first other non-empty line. Inputs
+// are dot-separated paths into the template data, * matches every entry of a
+// map or list.
+func templateContract(templateBytes []byte) []string {
+	var inputs []string
+	scanner := bufio.NewScanner(bytes.NewReader(templateBytes))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m := templateContractPattern.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		for _, input := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			inputs = append(inputs, strings.TrimPrefix(input, "."))
+		}
+	}
+	return inputs
+}
+
+// checkTemplateContract returns a templateContractError if data lacks any of
+// the inputs required by the contract header of the template at path.
+func checkTemplateContract(path string, templateBytes []byte, data map[string]any) error {
+	var missing []string
+	renamed := map[string]string{}
+	for _, input := range templateContract(templateBytes) {
+		if hasTemplateInput(data, strings.Split(input, ".")) {
+			continue
+		}
+		missing = append(missing, input)
+		if alias, ok := templateInputAliases[input]; ok && hasTemplateInput(data, strings.Split(alias, ".")) {
+			renamed[input] = alias
+		}
+	}
+	if len(missing) > 0 {
+		return &templateContractError{path: path, missing: missing, renamed: renamed}
+	}
+	return nil
+}
+
+// hasTemplateInput reports whether v holds a non-nil value at path.
+func hasTemplateInput(v any, path []string) bool {
+	if len(path) == 0 {
+		return v != nil
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		if path[0] == "*" {
+			for _, e := range t {
+				if !hasTemplateInput(e, path[1:]) {
+					return false
+				}
+			}
+			return true
+		}
+		e, ok := t[path[0]]
+		return ok && hasTemplateInput(e, path[1:])
+	case []any:
+		if path[0] != "*" {
+			return false
+		}
+		for _, e := range t {
+			if !hasTemplateInput(e, path[1:]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
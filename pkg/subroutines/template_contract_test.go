@@ -0,0 +1,53 @@
+package subroutines
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateContract(t *testing.T) {
+	tmpl := []byte(`
+{{- /* requires: releaseNamespace, values.services */ -}}
+{{/* requires: .baseDomainPort values.services.*.chart */}}
+apiVersion: v1
+{{- /* requires: ignored, the header ended */ -}}
+`)
+	assert.Equal(t, []string{"releaseNamespace", "values.services", "baseDomainPort", "values.services.*.chart"}, templateContract(tmpl))
+	assert.Empty(t, templateContract([]byte("{{/* a plain comment */}}\n{{/* requires: a */}}\n")))
+}
+
+func TestCheckTemplateContract(t *testing.T) {
+	tmpl := []byte("{{- /* requires: releaseNamespace, baseDomainWithPort, values.services.*.chart, values.list.*.name */ -}}\n")
+	data := map[string]any{
+		"releaseNamespace": "platform-mesh-system",
+		"baseDomainPort":   "example.com",
+		"values": map[string]any{
+			"services": map[string]any{
+				"portal": map[string]any{"chart": "portal"},
+				"iam":    map[string]any{"enabled": true},
+			},
+			"list": []any{map[string]any{"name": "a"}},
+		},
+	}
+
+	err := checkTemplateContract("helmreleases.yaml", tmpl, data)
+	var contractErr *templateContractError
+	require.ErrorAs(t, err, &contractErr)
+	assert.Equal(t, []string{"baseDomainWithPort", "values.services.*.chart"}, contractErr.missing)
+	assert.EqualError(t, err, "template helmreleases.yaml misses required inputs: baseDomainWithPort (renamed to baseDomainPort), values.services.*.chart")
+
+	// Without the alias the input is plainly missing.
+	delete(data, "baseDomainPort")
+	assert.EqualError(t, checkTemplateContract("helmreleases.yaml", tmpl, data),
+		"template helmreleases.yaml misses required inputs: baseDomainWithPort, values.services.*.chart")
+
+	data["baseDomainWithPort"] = "example.com"
+	data["values"].(map[string]any)["services"].(map[string]any)["iam"].(map[string]any)["chart"] = "iam"
+	assert.NoError(t, checkTemplateContract("helmreleases.yaml", tmpl, data))
+
+	// A nil value does not satisfy an input.
+	data["releaseNamespace"] = nil
+	assert.Error(t, checkTemplateContract("helmreleases.yaml", tmpl, data))
+}
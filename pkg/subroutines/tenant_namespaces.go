@@ -0,0 +1,214 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+)
+
+const (
+	// TenantLabel holds the tenant, the name of its organization workspace, a
+	// tenant namespace was provisioned for.
+	TenantLabel = "core.platform-mesh.io/tenant"
+
+	// tenantOrgsWorkspacePath is the workspace whose child workspaces are the
+	// tenants.
+	tenantOrgsWorkspacePath = "root:orgs"
+	tenantNamespacesKey     = "tenantNamespaces"
+	// tenantNamespacesObjectName is the name of the ResourceQuota and the
+	// LimitRange in a tenant namespace.
+	tenantNamespacesObjectName = "platform-mesh-tenant"
+)
+
+// tenantNamespacesConfig is the tenantNamespaces entry of a service in the
+// components profile.
+type tenantNamespacesConfig struct {
+	// Prefix is prepended to the tenant to name its namespace, defaults to
+	// the service name.
+	Prefix     string                    `json:"prefix,omitempty"`
+	Quota      *corev1.ResourceQuotaSpec `json:"quota,omitempty"`
+	LimitRange *corev1.LimitRangeSpec    `json:"limitRange,omitempty"`
+}
+
+// profileTenantNamespaces returns the tenantNamespaces entries of the enabled
+// services in the components section of profile, by service name.
+func profileTenantNamespaces(profile map[string]interface{}) (map[string]tenantNamespacesConfig, error) {
+	components, _ := profile["components"].(map[string]interface{})
+	services, _ := components["services"].(map[string]interface{})
+
+	entries := map[string]tenantNamespacesConfig{}
+	for name, v := range services {
+		service, _ := v.(map[string]interface{})
+		entry, ok := service[tenantNamespacesKey]
+		if enabled, _ := service["enabled"].(bool); !ok || !enabled {
+			continue
+		}
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to marshal tenantNamespaces of service %s", name)
+		}
+		var cfg tenantNamespacesConfig
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, errors.Wrap(err, "Invalid tenantNamespaces of service %s", name)
+		}
+		if cfg.Prefix == "" {
+			cfg.Prefix = name
+		}
+		entries[name] = cfg
+	}
+	return entries, nil
+}
+
+// tenantNamespaceName returns the namespace of tenant for the service with
+// cfg.
+func tenantNamespaceName(cfg tenantNamespacesConfig, tenant string) string {
+	return cfg.Prefix + "-" + tenant
+}
+
+// provisionTenantNamespaces creates a namespace on the runtime cluster for
+// every tenant and every service with a tenantNamespaces entry in the profile,
+// with the ResourceQuota and LimitRange of the entry. The tenants are the
+// organization workspaces under root:orgs, so the namespaces follow the
+// organizations on every kcp setup. Namespaces of removed tenants are left in
+// place as they may still hold workloads. The provisioned namespaces are
+// stored in status.tenantNamespaces.
+func (r *KcpsetupSubroutine) provisionTenantNamespaces(ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh) error {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	// The profile is created by the deployment, until then there is nothing
+	// to provision.
+	profile, err := loadProfile(ctx, r.client, inst)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := profileTenantNamespaces(profile)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		inst.Status.TenantNamespaces = nil
+		return nil
+	}
+
+	tenants, err := r.listTenants(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	var provisioned []string
+	for _, service := range slices.Sorted(maps.Keys(entries)) {
+		cfg := entries[service]
+		for _, tenant := range tenants {
+			name := tenantNamespaceName(cfg, tenant)
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				log.Warn().Str("service", service).Str("tenant", tenant).Str("namespace", name).Strs("errors", errs).Msg("Invalid tenant namespace name, skipping")
+				continue
+			}
+			if err := r.applyTenantNamespace(ctx, inst, name, tenant, cfg); err != nil {
+				return errors.Wrap(err, "Failed to provision namespace %s of tenant %s", name, tenant)
+			}
+			provisioned = append(provisioned, name)
+		}
+	}
+	slices.Sort(provisioned)
+
+	if !slices.Equal(provisioned, inst.Status.TenantNamespaces) {
+		for _, name := range inst.Status.TenantNamespaces {
+			if !slices.Contains(provisioned, name) {
+				log.Info().Str("namespace", name).Msg("Tenant namespace is no longer provisioned and left in place")
+			}
+		}
+		log.Info().Int("tenants", len(tenants)).Int("namespaces", len(provisioned)).Msg("Provisioned tenant namespaces")
+		recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonTenantNamespacesProvisioned, "ProvisionTenantNamespaces",
+			"Provisioned %d namespaces for %d tenants", len(provisioned), len(tenants))
+	}
+	inst.Status.TenantNamespaces = provisioned
+	return nil
+}
+
+// listTenants returns the sorted names of the organization workspaces that
+// are not being deleted.
+func (r *KcpsetupSubroutine) listTenants(ctx context.Context, config *rest.Config) ([]string, error) {
+	kcpClient, err := r.kcpHelper.NewKcpClient(config, tenantOrgsWorkspacePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create kcp client for workspace %s", tenantOrgsWorkspacePath)
+	}
+	list := &kcptenancyv1alpha.WorkspaceList{}
+	if err := kcpClient.List(ctx, list); err != nil {
+		return nil, errors.Wrap(err, "Failed to list workspaces in %s", tenantOrgsWorkspacePath)
+	}
+
+	var tenants []string
+	for _, ws := range list.Items {
+		if ws.DeletionTimestamp.IsZero() {
+			tenants = append(tenants, ws.Name)
+		}
+	}
+	slices.Sort(tenants)
+	return tenants, nil
+}
+
+// applyTenantNamespace creates or updates the namespace name of tenant and its
+// ResourceQuota and LimitRange. A ResourceQuota or LimitRange that is no
+// longer configured is deleted.
+func (r *KcpsetupSubroutine) applyTenantNamespace(ctx context.Context, inst *corev1alpha1.PlatformMesh, name, tenant string, cfg tenantNamespacesConfig) error {
+	setLabels := func(obj client.Object) error {
+		if err := claimLabeledObject(obj, inst); err != nil {
+			return err
+		}
+		labels := obj.GetLabels()
+		labels[TenantLabel] = tenant
+		labels[operatorCreatedLabel] = "true"
+		obj.SetLabels(labels)
+		return nil
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.client, ns, func() error { return setLabels(ns) }); err != nil {
+		return err
+	}
+
+	quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: tenantNamespacesObjectName, Namespace: name}}
+	if cfg.Quota == nil {
+		if err := client.IgnoreNotFound(r.client.Delete(ctx, quota)); err != nil {
+			return fmt.Errorf("failed to delete ResourceQuota: %w", err)
+		}
+	} else if _, err := controllerutil.CreateOrUpdate(ctx, r.client, quota, func() error {
+		quota.Spec = *cfg.Quota
+		return setLabels(quota)
+	}); err != nil {
+		return fmt.Errorf("failed to apply ResourceQuota: %w", err)
+	}
+
+	limitRange := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: tenantNamespacesObjectName, Namespace: name}}
+	if cfg.LimitRange == nil {
+		if err := client.IgnoreNotFound(r.client.Delete(ctx, limitRange)); err != nil {
+			return fmt.Errorf("failed to delete LimitRange: %w", err)
+		}
+	} else if _, err := controllerutil.CreateOrUpdate(ctx, r.client, limitRange, func() error {
+		limitRange.Spec = *cfg.LimitRange
+		return setLabels(limitRange)
+	}); err != nil {
+		return fmt.Errorf("failed to apply LimitRange: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func TestProvisionTenantNamespaces_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+	helper := &Helper{}
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "orgs"}}))
+	orgs, err := helper.NewKcpClient(server.RestConfig(), "root:orgs")
+	require.NoError(t, err)
+	require.NoError(t, orgs.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	profile := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pm-profile", Namespace: "platform-mesh-system"},
+		Data: map[string]string{"profile.yaml": `components:
+  services:
+    extension-runtime:
+      enabled: true
+      tenantNamespaces:
+        prefix: ext
+        quota:
+          hard:
+            pods: "20"
+        limitRange:
+          limits:
+          - type: Container
+            default:
+              memory: 256Mi
+    disabled:
+      enabled: false
+      tenantNamespaces: {}
+`},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build()
+
+	r := &KcpsetupSubroutine{client: cl, kcpHelper: helper, cfg: &config.OperatorConfig{}}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	require.NoError(t, r.provisionTenantNamespaces(ctx, server.RestConfig(), inst))
+	assert.Equal(t, []string{"ext-acme"}, inst.Status.TenantNamespaces)
+	assert.Equal(t, "Normal TenantNamespacesProvisioned Provisioned 1 namespaces for 1 tenants", <-rec.Events)
+
+	ns := &corev1.Namespace{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "ext-acme"}, ns))
+	assert.Equal(t, "acme", ns.Labels[TenantLabel])
+	assert.Equal(t, "pm", ns.Labels[InstanceNameLabel])
+	quota := &corev1.ResourceQuota{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "ext-acme", Name: tenantNamespacesObjectName}, quota))
+	assert.Equal(t, resource.MustParse("20"), quota.Spec.Hard[corev1.ResourcePods])
+	limitRange := &corev1.LimitRange{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "ext-acme", Name: tenantNamespacesObjectName}, limitRange))
+	require.Len(t, limitRange.Spec.Limits, 1)
+	assert.Equal(t, resource.MustParse("256Mi"), limitRange.Spec.Limits[0].Default[corev1.ResourceMemory])
+
+	// Unchanged tenants are not reported again.
+	require.NoError(t, r.provisionTenantNamespaces(ctx, server.RestConfig(), inst))
+	assert.Empty(t, rec.Events)
+
+	// A new organization gets its namespace on the next run.
+	require.NoError(t, orgs.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "globex"}}))
+	require.NoError(t, r.provisionTenantNamespaces(ctx, server.RestConfig(), inst))
+	assert.Equal(t, []string{"ext-acme", "ext-globex"}, inst.Status.TenantNamespaces)
+	assert.Equal(t, "Normal TenantNamespacesProvisioned Provisioned 2 namespaces for 2 tenants", <-rec.Events)
+
+	// A namespace labeled for another instance is not taken over.
+	other := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "platform-mesh-system"}, Spec: corev1alpha1.PlatformMeshSpec{
+		ProfileConfigMap: &corev1alpha1.ConfigMapReference{Name: "pm-profile"},
+	}}
+	assert.ErrorContains(t, r.provisionTenantNamespaces(ctx, server.RestConfig(), other), "labeled for PlatformMesh platform-mesh-system/pm")
+}
+
+func TestProfileTenantNamespaces(t *testing.T) {
+	entries, err := profileTenantNamespaces(map[string]interface{}{"components": map[string]interface{}{"services": map[string]interface{}{
+		"runtime": map[string]interface{}{"enabled": true, "tenantNamespaces": map[string]interface{}{}},
+		"plain":   map[string]interface{}{"enabled": true},
+	}}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]tenantNamespacesConfig{"runtime": {Prefix: "runtime"}}, entries)
+	assert.Equal(t, "runtime-acme", tenantNamespaceName(entries["runtime"], "acme"))
+
+	_, err = profileTenantNamespaces(map[string]interface{}{"components": map[string]interface{}{"services": map[string]interface{}{
+		"runtime": map[string]interface{}{"enabled": true, "tenantNamespaces": map[string]interface{}{"quota": "big"}},
+	}}})
+	assert.ErrorContains(t, err, "Invalid tenantNamespaces of service runtime")
+}
@@ -0,0 +1,181 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// typedManifest decodes and checks one well-known kind. check returns the
+// problems found, an empty result means the object is valid.
+type typedManifest struct {
+	newObject func() client.Object
+	check     func(obj client.Object, u *unstructured.Unstructured) []string
+}
+
+var typedManifests = map[schema.GroupVersionKind]typedManifest{
+	corev1.SchemeGroupVersion.WithKind("Secret"): {
+		newObject: func() client.Object { return &corev1.Secret{} },
+		check:     checkSecret,
+	},
+	appsv1.SchemeGroupVersion.WithKind("Deployment"): {
+		newObject: func() client.Object { return &appsv1.Deployment{} },
+		check:     checkDeployment,
+	},
+	certmanager.SchemeGroupVersion.WithKind("Certificate"): {
+		newObject: func() client.Object { return &certmanager.Certificate{} },
+		check:     checkCertificate,
+	},
+	certmanager.SchemeGroupVersion.WithKind("Issuer"): {
+		newObject: func() client.Object { return &certmanager.Issuer{} },
+		check:     checkIssuer,
+	},
+}
+
+// ManifestError reports a manifest of a well-known kind that has unknown
+// fields or misses required ones.
+type ManifestError struct {
+	Kind     string
+	Name     string
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("invalid %s %s: %s", e.Kind, e.Name, strings.Join(e.Problems, "; "))
+}
+
+// toTypedManifest decodes obj into its typed struct if it is a well-known
+// kind and checks its required fields. Unknown fields are rejected, so typos
+// fail before anything is applied. Other kinds return nil.
+func toTypedManifest(obj *unstructured.Unstructured) (client.Object, error) {
+	kind, ok := typedManifests[obj.GroupVersionKind()]
+	if !ok {
+		return nil, nil
+	}
+	typed := kind.newObject()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(obj.Object, typed, true); err != nil {
+		return nil, &ManifestError{Kind: obj.GetKind(), Name: obj.GetName(), Problems: []string{err.Error()}}
+	}
+	var problems []string
+	if obj.GetName() == "" {
+		problems = append(problems, "metadata.name is required")
+	}
+	problems = append(problems, kind.check(typed, obj)...)
+	if len(problems) > 0 {
+		return nil, &ManifestError{Kind: obj.GetKind(), Name: obj.GetName(), Problems: problems}
+	}
+	return typed, nil
+}
+
+// validateTypedManifests checks every manifest of a well-known kind and
+// returns the problems of all of them.
+func validateTypedManifests(manifests []renderedManifest) error {
+	var problems []string
+	for _, m := range manifests {
+		if _, err := toTypedManifest(m.obj); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", m.path, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d invalid manifest(s): %s", len(problems), strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// applyManifest applies obj with server-side apply. Well-known kinds are
+// validated and applied through their typed struct when the client's scheme
+// knows them, everything else is applied as is.
+func applyManifest(ctx context.Context, k8sClient client.Client, obj *unstructured.Unstructured, fieldManager string) error {
+	typed, err := toTypedManifest(obj)
+	if err != nil {
+		return err
+	}
+	var target client.Object = obj
+	if typed != nil {
+		if scheme := k8sClient.Scheme(); scheme != nil && scheme.Recognizes(obj.GroupVersionKind()) {
+			target = typed
+		}
+	}
+	if err := k8sClient.Patch(ctx, target, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil { //nolint:staticcheck // Apply via Patch is required for unstructured objects
+		return errors.Wrap(err, "Failed to apply %s %s", obj.GetKind(), obj.GetName())
+	}
+	return nil
+}
+
+func checkSecret(obj client.Object, _ *unstructured.Unstructured) []string {
+	secret := obj.(*corev1.Secret)
+	var problems []string
+	for key := range secret.Data {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			problems = append(problems, fmt.Sprintf("data key %q: %s", key, msg))
+		}
+	}
+	for key := range secret.StringData {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			problems = append(problems, fmt.Sprintf("stringData key %q: %s", key, msg))
+		}
+	}
+	return problems
+}
+
+func checkDeployment(obj client.Object, _ *unstructured.Unstructured) []string {
+	deployment := obj.(*appsv1.Deployment)
+	var problems []string
+	if deployment.Spec.Selector == nil {
+		problems = append(problems, "spec.selector is required")
+	} else if selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector); err != nil {
+		problems = append(problems, fmt.Sprintf("spec.selector: %v", err))
+	} else if selector.Empty() || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+		problems = append(problems, "spec.selector must match spec.template.metadata.labels")
+	}
+	if len(deployment.Spec.Template.Spec.Containers) == 0 {
+		problems = append(problems, "spec.template.spec.containers must not be empty")
+	}
+	for i, c := range deployment.Spec.Template.Spec.Containers {
+		if c.Name == "" {
+			problems = append(problems, fmt.Sprintf("spec.template.spec.containers[%d].name is required", i))
+		}
+		if c.Image == "" {
+			problems = append(problems, fmt.Sprintf("spec.template.spec.containers[%d].image is required", i))
+		}
+	}
+	return problems
+}
+
+func checkCertificate(obj client.Object, _ *unstructured.Unstructured) []string {
+	cert := obj.(*certmanager.Certificate)
+	var problems []string
+	if cert.Spec.SecretName == "" {
+		problems = append(problems, "spec.secretName is required")
+	}
+	if cert.Spec.IssuerRef.Name == "" {
+		problems = append(problems, "spec.issuerRef.name is required")
+	}
+	if cert.Spec.CommonName == "" && len(cert.Spec.DNSNames) == 0 && len(cert.Spec.URIs) == 0 &&
+		len(cert.Spec.IPAddresses) == 0 && len(cert.Spec.EmailAddresses) == 0 {
+		problems = append(problems, "one of spec.commonName, dnsNames, uris, ipAddresses or emailAddresses is required")
+	}
+	return problems
+}
+
+// checkIssuer only checks that an issuer type is configured, the types are
+// checked on the unstructured spec so that new ones do not need code changes.
+func checkIssuer(_ client.Object, u *unstructured.Unstructured) []string {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	if len(spec) == 0 {
+		return []string{"spec must configure an issuer type, e.g. selfSigned or ca"}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func manifestFromYAML(t *testing.T, doc string) *unstructured.Unstructured {
+	var obj map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestToTypedManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		typed    bool
+		problems []string
+	}{
+		{
+			name: "valid certificate",
+			manifest: `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata: {name: cert}
+spec:
+  secretName: cert
+  issuerRef: {name: issuer}
+  dnsNames: [example.com]`,
+			typed: true,
+		},
+		{
+			name: "certificate with typo",
+			manifest: `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata: {name: cert}
+spec:
+  secretname: cert
+  issuerRef: {name: issuer}
+  dnsNames: [example.com]`,
+			problems: []string{`unknown field "spec.secretname"`},
+		},
+		{
+			name: "certificate without required fields",
+			manifest: `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata: {name: cert}
+spec: {}`,
+			problems: []string{"spec.secretName is required", "spec.issuerRef.name is required", "one of spec.commonName"},
+		},
+		{
+			name: "issuer without type",
+			manifest: `apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata: {name: issuer}
+spec: {}`,
+			problems: []string{"spec must configure an issuer type"},
+		},
+		{
+			name: "secret with invalid key",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata: {name: secret}
+stringData: {"not/valid": x}`,
+			problems: []string{`stringData key "not/valid"`},
+		},
+		{
+			name: "deployment with mismatched selector",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: app}
+spec:
+  selector: {matchLabels: {app: a}}
+  template:
+    metadata: {labels: {app: b}}
+    spec:
+      containers: [{name: app}]`,
+			problems: []string{"spec.selector must match", "containers[0].image is required"},
+		},
+		{
+			name: "other kind",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata: {name: cm}
+dta: {}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typed, err := toTypedManifest(manifestFromYAML(t, tt.manifest))
+			if len(tt.problems) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, tt.typed, typed != nil)
+				return
+			}
+			require.Error(t, err)
+			var manifestErr *ManifestError
+			require.ErrorAs(t, err, &manifestErr)
+			for _, p := range tt.problems {
+				assert.Contains(t, err.Error(), p)
+			}
+		})
+	}
+}
+
+func TestValidateTypedManifests(t *testing.T) {
+	err := validateTypedManifests([]renderedManifest{
+		{path: "a.yaml", obj: manifestFromYAML(t, "apiVersion: cert-manager.io/v1\nkind: Issuer\nmetadata: {name: a}\nspec: {}")},
+		{path: "b.yaml", obj: manifestFromYAML(t, "apiVersion: cert-manager.io/v1\nkind: Issuer\nmetadata: {name: b}\nspec: {selfSigned: {}}")},
+		{path: "c.yaml", obj: manifestFromYAML(t, "apiVersion: cert-manager.io/v1\nkind: Issuer\nmetadata: {name: c}\nspec: {}")},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 invalid manifest(s)")
+	assert.Contains(t, err.Error(), "a.yaml")
+	assert.NotContains(t, err.Error(), "b.yaml")
+}
+
+func TestApplyManifest_Typed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	obj := manifestFromYAML(t, `apiVersion: v1
+kind: Secret
+metadata: {name: kcp-webhook-secret, namespace: platform-mesh-system}
+type: Opaque
+data: {kubeconfig: Y29uZmln}`)
+	require.NoError(t, applyManifest(context.Background(), cl, obj, fieldManagerDeployment))
+
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Name: "kcp-webhook-secret", Namespace: "platform-mesh-system"}, secret))
+	assert.Equal(t, []byte("config"), secret.Data["kubeconfig"])
+
+	invalid := manifestFromYAML(t, "apiVersion: v1\nkind: Secret\nmetadata: {name: broken}\ntpye: Opaque")
+	assert.Error(t, applyManifest(context.Background(), cl, invalid, fieldManagerDeployment))
+}
@@ -0,0 +1,226 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+var ocmResourceGVK = schema.GroupVersionKind{Group: "delivery.ocm.software", Version: "v1alpha1", Kind: "Resource"}
+
+// upgradeOrder upgrades the components in the order of their sync waves, the
+// configured syncWave or else one more than the highest wave of dependsOn, so
+// e.g. a CRDs chart is upgraded before the operators using it and kcp before
+// its consumers. An upgrade of a component is held while more than
+// maxUnready HelmReleases of components of earlier waves are not Ready. A nil
+// *upgradeOrder allows every upgrade.
+type upgradeOrder struct {
+	// inst the held upgrades are recorded on.
+	inst       *corev1alpha1.PlatformMesh
+	infra      client.Client
+	maxUnready int
+	planned    bool
+	waves      map[string]int
+	// unready holds the components whose HelmRelease is not Ready, or that
+	// were upgraded or held in this reconcile.
+	unready map[string]bool
+	held    []string
+}
+
+type upgradeOrderKey struct{}
+
+type renderedComponentKey struct{}
+
+// withUpgradeOrder returns ctx carrying the upgrade order of inst, and nil
+// when maxUnready is negative. The HelmReleases are looked up on infra.
+func withUpgradeOrder(ctx context.Context, inst *corev1alpha1.PlatformMesh, infra client.Client, maxUnready int) (context.Context, *upgradeOrder) {
+	if maxUnready < 0 {
+		return ctx, nil
+	}
+	o := &upgradeOrder{inst: inst, infra: infra, maxUnready: maxUnready, unready: map[string]bool{}}
+	return context.WithValue(ctx, upgradeOrderKey{}, o), o
+}
+
+func upgradeOrderFrom(ctx context.Context) *upgradeOrder {
+	o, _ := ctx.Value(upgradeOrderKey{}).(*upgradeOrder)
+	return o
+}
+
+// withRenderedComponent returns ctx naming the component whose templates are
+// rendered with it.
+func withRenderedComponent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, renderedComponentKey{}, name)
+}
+
+func renderedComponentFrom(ctx context.Context) string {
+	name, _ := ctx.Value(renderedComponentKey{}).(string)
+	return name
+}
+
+// plan computes the waves of the services of tmplVars and which of their
+// HelmReleases in the release namespace are not Ready. Only the first call of
+// a reconcile plans, later ones keep the upgrades already recorded. A
+// HelmRelease that cannot be read counts as not Ready.
+func (o *upgradeOrder) plan(ctx context.Context, tmplVars map[string]interface{}) {
+	if o == nil || o.planned {
+		return
+	}
+	o.planned = true
+	services := templateServices(tmplVars)
+	o.waves, _ = serviceSyncWaves(services)
+	releaseNamespace, _ := tmplVars["releaseNamespace"].(string)
+	log := logger.LoadLoggerFromContext(ctx)
+	for name, svc := range services {
+		config, _ := svc.(map[string]interface{})
+		if enabled, _ := config["enabled"].(bool); !enabled {
+			continue
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(helmReleaseGVK)
+		err := o.infra.Get(ctx, client.ObjectKey{Name: name, Namespace: releaseNamespace}, live)
+		if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("component", name).Msg("Failed to get HelmRelease, counting it as not Ready")
+			o.unready[name] = true
+			continue
+		}
+		if !helmReleaseReady(live) {
+			o.unready[name] = true
+		}
+	}
+}
+
+// sort orders the component names by wave, keeping the order of components
+// of the same wave.
+func (o *upgradeOrder) sort(names []string) {
+	if o == nil {
+		return
+	}
+	sort.SliceStable(names, func(i, j int) bool { return o.waves[names[i]] < o.waves[names[j]] })
+}
+
+// allow reports whether the upgrade of component, changing the fields
+// changed of target, may run now. While too many components of earlier waves
+// are not Ready it records the upgrade as held and returns false, the caller
+// then leaves target as it is. Either way component counts as not Ready for
+// the later waves of this reconcile.
+func (o *upgradeOrder) allow(ctx context.Context, component, target string, changed []string) bool {
+	if o == nil {
+		return true
+	}
+	wave := o.waves[component]
+	var blocking []string
+	for name := range o.unready {
+		if name != component && o.waves[name] < wave {
+			blocking = append(blocking, name)
+		}
+	}
+	o.unready[component] = true
+	if len(blocking) <= o.maxUnready {
+		return true
+	}
+	sort.Strings(blocking)
+	logger.LoadLoggerFromContext(ctx).Info().Str("component", component).Str("target", target).Strs("notReady", blocking).
+		Msg("Holding upgrade until the components of earlier waves are Ready")
+	recordEvent(ctx, o.inst, corev1.EventTypeNormal, EventReasonUpgradeHeld, "Upgrade",
+		"Upgrade of %s (changed %s) held until the components of waves before %d are Ready: %s", target, strings.Join(changed, ", "), wave, strings.Join(blocking, ", "))
+	if !slices.Contains(o.held, component) {
+		o.held = append(o.held, component)
+	}
+	return false
+}
+
+// message tells which upgrades were held, or "" when none was.
+func (o *upgradeOrder) message() string {
+	if o == nil || len(o.held) == 0 {
+		return ""
+	}
+	held := slices.Clone(o.held)
+	sort.Strings(held)
+	return fmt.Sprintf("upgrades of %s held until the components of earlier waves are Ready", strings.Join(held, ", "))
+}
+
+// helmReleaseReady reports whether the HelmRelease obj is Ready for its
+// current generation.
+func helmReleaseReady(obj *unstructured.Unstructured) bool {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return matchesConditionWithStatus(obj, "Ready", "True") && observed >= obj.GetGeneration()
+}
+
+// upgradeChanges returns the fields by which obj upgrades its live version
+// on k8sClient: the chart and values of a HelmRelease, and the pinned version
+// of an OCM Resource. Other objects, and objects that do not exist yet, are
+// no upgrade.
+func upgradeChanges(ctx context.Context, k8sClient client.Client, obj *unstructured.Unstructured) ([]string, error) {
+	gvk := obj.GroupVersionKind()
+	if gvk != helmReleaseGVK && gvk != ocmResourceGVK {
+		return nil, nil
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if kerrors.IsNotFound(err) || apimeta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	var changed []string
+	if gvk == ocmResourceGVK {
+		desired, ok := obj.GetAnnotations()[PinnedVersionAnnotation]
+		if ok && desired != live.GetAnnotations()[PinnedVersionAnnotation] {
+			changed = append(changed, "metadata.annotations."+PinnedVersionAnnotation)
+		}
+		return changed, nil
+	}
+	for _, field := range []string{"chart", "chartRef", "values"} {
+		desired, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", field)
+		current, _, _ := unstructured.NestedFieldNoCopy(live.Object, "spec", field)
+		changed = appendDrift(changed, "spec."+field, desired, current)
+	}
+	return changed, nil
+}
+
+// holdUpgrades wraps next. An upgrade of a component HelmRelease or of the
+// pinned version of its OCM Resources is dropped while the upgrade order of
+// ctx holds it, and the component keeps its objects in inv, so they stay as
+// they are. New objects are applied right away.
+func holdUpgrades(k8sClient client.Client, inv *inventory, next func(ctx context.Context, obj *unstructured.Unstructured) error) func(ctx context.Context, obj *unstructured.Unstructured) error {
+	return func(ctx context.Context, obj *unstructured.Unstructured) error {
+		component := renderedComponentFrom(ctx)
+		if next != nil {
+			if err := next(ctx, obj); err != nil {
+				return err
+			}
+		}
+		order := upgradeOrderFrom(ctx)
+		if order == nil || component == "" {
+			return nil
+		}
+		changed, err := upgradeChanges(ctx, k8sClient, obj)
+		if err != nil || len(changed) == 0 {
+			return err
+		}
+		target := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		if order.allow(ctx, component, target, changed) {
+			return nil
+		}
+		inv.keep(component)
+		return errSkipObject
+	}
+}
@@ -0,0 +1,131 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/platform-mesh/platform-mesh-operator/pkg/plan"
+)
+
+// newUpgradeHelmRelease returns the HelmRelease name at chart version with a
+// Ready condition of status ready for its generation.
+func newUpgradeHelmRelease(t *testing.T, name, version, ready string) *unstructured.Unstructured {
+	release := newAdoptionHelmRelease(t, name, map[string]interface{}{
+		"chart": map[string]interface{}{"spec": map[string]interface{}{"chart": name, "version": version}},
+	})
+	require.NoError(t, unstructured.SetNestedSlice(release.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": ready},
+	}, "status", "conditions"))
+	require.NoError(t, unstructured.SetNestedField(release.Object, int64(1), "status", "observedGeneration"))
+	return release
+}
+
+func TestUpgradeOrder(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+	cl := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(
+		newUpgradeHelmRelease(t, "crds", "1.0.0", "False"),
+		newUpgradeHelmRelease(t, "operator", "1.0.0", "True"),
+		newUpgradeHelmRelease(t, "portal", "1.0.0", "True"),
+	).Build()
+	tmplVars := map[string]interface{}{
+		"releaseNamespace": "flux-system",
+		"values": map[string]interface{}{"services": map[string]interface{}{
+			"crds":     map[string]interface{}{"enabled": true},
+			"operator": map[string]interface{}{"enabled": true, "dependsOn": []interface{}{map[string]interface{}{"name": "crds"}}},
+			"portal":   map[string]interface{}{"enabled": true},
+		}},
+	}
+	inst := maintenanceInstance()
+
+	// A negative maxUnready disables the order.
+	_, order := withUpgradeOrder(ctx, inst, cl, -1)
+	assert.Nil(t, order)
+	order.plan(ctx, tmplVars)
+	assert.True(t, order.allow(ctx, "operator", "HelmRelease flux-system/operator", nil))
+	assert.Empty(t, order.message())
+
+	orderCtx, order := withUpgradeOrder(ctx, inst, cl, 0)
+	assert.Same(t, order, upgradeOrderFrom(orderCtx))
+	order.plan(orderCtx, tmplVars)
+	assert.Equal(t, map[string]int{"crds": 0, "operator": 1, "portal": 0}, order.waves)
+	assert.Equal(t, map[string]bool{"crds": true}, order.unready)
+	names := []string{"crds", "operator", "portal"}
+	order.sort(names)
+	assert.Equal(t, []string{"crds", "portal", "operator"}, names)
+
+	// The operator waits for the crds release, components of the same wave
+	// do not.
+	inv := newInventory("components-infra", plan.ClusterInfra)
+	postProcess := holdUpgrades(cl, inv, nil)
+	render := func(name, version string) *unstructured.Unstructured {
+		return newAdoptionHelmRelease(t, name, map[string]interface{}{
+			"chart": map[string]interface{}{"spec": map[string]interface{}{"chart": name, "version": version}},
+		})
+	}
+	require.NoError(t, postProcess(withRenderedComponent(orderCtx, "portal"), render("portal", "1.1.0")))
+	require.NoError(t, postProcess(withRenderedComponent(orderCtx, "operator"), render("operator", "1.0.0")))
+	assert.ErrorIs(t, postProcess(withRenderedComponent(orderCtx, "operator"), render("operator", "1.1.0")), errSkipObject)
+	assert.True(t, inv.kept["operator"])
+	assert.Contains(t, <-rec.Events, EventReasonUpgradeHeld)
+	assert.Equal(t, "upgrades of operator held until the components of earlier waves are Ready", order.message())
+
+	// With one not Ready release allowed the operator is upgraded, and then
+	// counts as not Ready itself.
+	orderCtx, order = withUpgradeOrder(ctx, inst, cl, 1)
+	order.plan(orderCtx, tmplVars)
+	require.NoError(t, postProcess(withRenderedComponent(orderCtx, "operator"), render("operator", "1.1.0")))
+	assert.True(t, order.unready["operator"])
+	assert.Empty(t, order.message())
+}
+
+func TestHelmReleaseReady(t *testing.T) {
+	release := newUpgradeHelmRelease(t, "portal", "1.0.0", "True")
+	assert.True(t, helmReleaseReady(release))
+	release.SetGeneration(2)
+	assert.False(t, helmReleaseReady(release))
+	assert.False(t, helmReleaseReady(newUpgradeHelmRelease(t, "portal", "1.0.0", "False")))
+}
+
+func TestUpgradeChanges(t *testing.T) {
+	ctx := context.Background()
+	resource := func(pin string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(ocmResourceGVK)
+		obj.SetName("iam-chart")
+		obj.SetNamespace("platform-mesh-system")
+		if pin != "" {
+			obj.SetAnnotations(map[string]string{PinnedVersionAnnotation: pin})
+		}
+		return obj
+	}
+	cl := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(resource("0.4.0")).Build()
+
+	changed, err := upgradeChanges(ctx, cl, resource("0.4.2"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metadata.annotations." + PinnedVersionAnnotation}, changed)
+	changed, err = upgradeChanges(ctx, cl, resource("0.4.0"))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// An unpinned Resource follows the resolved version, a new one is no upgrade.
+	changed, err = upgradeChanges(ctx, cl, resource(""))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	missing := resource("0.4.2")
+	missing.SetName("portal-chart")
+	changed, err = upgradeChanges(ctx, cl, missing)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
@@ -0,0 +1,289 @@
+package subroutines
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// ValuesPinnedConditionType is True while at least one component is
+	// rendered from a values snapshot, see status.pinnedComponents.
+	ValuesPinnedConditionType = "ValuesPinned"
+	// ValuesSnapshotLabel is set on values snapshot secrets to the component.
+	ValuesSnapshotLabel = "core.platform-mesh.io/values-snapshot"
+
+	valuesSnapshotsKey = "snapshots"
+)
+
+// valuesSnapshot is the merged values of a component that rendered and
+// applied successfully.
+type valuesSnapshot struct {
+	Checksum   string                 `json:"checksum"`
+	Generation int64                  `json:"generation"`
+	CreatedAt  metav1.Time            `json:"createdAt"`
+	Values     map[string]interface{} `json:"values"`
+}
+
+func valuesSnapshotSecretName(inst *corev1alpha1.PlatformMesh, component string) string {
+	return inst.Name + "-values-" + component
+}
+
+// valuesChecksum hashes the JSON of values. Map keys are marshalled sorted, so
+// equal values always have the same checksum.
+func valuesChecksum(values interface{}) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// loadValuesSnapshots returns the snapshots of component, newest first.
+func (r *DeploymentSubroutine) loadValuesSnapshots(ctx context.Context, inst *corev1alpha1.PlatformMesh, component string) ([]valuesSnapshot, error) {
+	secret := &corev1.Secret{}
+	err := r.clientRuntime.Get(ctx, types.NamespacedName{Name: valuesSnapshotSecretName(inst, component), Namespace: inst.Namespace}, secret)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get values snapshots of %s", component)
+	}
+	var snapshots []valuesSnapshot
+	if err := json.Unmarshal(secret.Data[valuesSnapshotsKey], &snapshots); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse values snapshots of %s", component)
+	}
+	return snapshots, nil
+}
+
+// recordValuesSnapshots stores the values of every service that rendered
+// without errors as the newest snapshot of its component, keeping the
+// configured number of snapshots. Pinned components and unchanged values are
+// skipped.
+func (r *DeploymentSubroutine) recordValuesSnapshots(ctx context.Context, inst *corev1alpha1.PlatformMesh, tmplVars map[string]interface{}) error {
+	limit := r.cfgOperator.Subroutines.Deployment.ValuesSnapshots
+	if limit <= 0 {
+		return nil
+	}
+	skip := map[string]bool{}
+	for _, e := range inst.Status.ComponentRenderErrors {
+		skip[e.Component] = true
+	}
+	for _, p := range inst.Status.PinnedComponents {
+		skip[p.Component] = true
+	}
+
+	services := templateServices(tmplVars)
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values, ok := services[name].(map[string]interface{})
+		if !ok || skip[name] {
+			continue
+		}
+		checksum, err := valuesChecksum(values)
+		if err != nil {
+			return errors.Wrap(err, "Failed to hash values of %s", name)
+		}
+		snapshots, err := r.loadValuesSnapshots(ctx, inst, name)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) > 0 && snapshots[0].Checksum == checksum {
+			continue
+		}
+		snapshots = append([]valuesSnapshot{{Checksum: checksum, Generation: inst.Generation, CreatedAt: metav1.Now(), Values: values}}, snapshots...)
+		if len(snapshots) > limit {
+			snapshots = snapshots[:limit]
+		}
+		if err := r.writeValuesSnapshots(ctx, inst, name, snapshots); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DeploymentSubroutine) writeValuesSnapshots(ctx context.Context, inst *corev1alpha1.PlatformMesh, component string, snapshots []valuesSnapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal values snapshots of %s", component)
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: valuesSnapshotSecretName(inst, component), Namespace: inst.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.clientRuntime, secret, func() error {
+		labels := secret.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[ValuesSnapshotLabel] = component
+		labels[InstanceNameLabel] = inst.Name
+		labels[InstanceNamespaceLabel] = inst.Namespace
+		secret.SetLabels(labels)
+		LabelSecretPurpose(secret, SecretPurposeValuesSnapshot, "")
+		if !metav1.IsControlledBy(secret, inst) {
+			secret.SetOwnerReferences(append(secret.GetOwnerReferences(), *metav1.NewControllerRef(inst, corev1alpha1.GroupVersion.WithKind("PlatformMesh"))))
+		}
+		SetSecretData(secret, map[string][]byte{valuesSnapshotsKey: data})
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to write values snapshots of %s", component)
+	}
+	return nil
+}
+
+// applyValuesPins replaces the values of pinned components in services with
+// their snapshot. Pins are released when the generation changed since they
+// were set, and a new value of ValuesRollbackAnnotation pins the requested
+// components to the newest snapshot that differs from their current values.
+func (r *DeploymentSubroutine) applyValuesPins(ctx context.Context, inst *corev1alpha1.PlatformMesh, services map[string]interface{}, log *logger.Logger) error {
+	var pins []corev1alpha1.PinnedComponent
+	for _, p := range inst.Status.PinnedComponents {
+		if p.PinnedGeneration != inst.Generation {
+			log.Info().Str("component", p.Component).Msg("Spec changed, releasing values rollback")
+			continue
+		}
+		pins = append(pins, p)
+	}
+
+	if request := inst.GetAnnotations()[corev1alpha1.ValuesRollbackAnnotation]; request != "" && request != inst.Status.ValuesRollback {
+		for _, component := range rollbackComponents(request, services) {
+			pin, err := r.rollbackPin(ctx, inst, component, services[component])
+			if err != nil {
+				return err
+			}
+			if pin == nil {
+				log.Warn().Str("component", component).Msg("No previous values snapshot to roll back to")
+				continue
+			}
+			pins = replacePin(pins, *pin)
+			log.Info().Str("component", component).Int64("snapshotGeneration", pin.SnapshotGeneration).Msg("Rolled back component values")
+		}
+		inst.Status.ValuesRollback = request
+	}
+
+	var applied []corev1alpha1.PinnedComponent
+	for _, p := range pins {
+		snapshots, err := r.loadValuesSnapshots(ctx, inst, p.Component)
+		if err != nil {
+			return err
+		}
+		idx := snapshotIndex(snapshots, p.SnapshotChecksum)
+		if idx < 0 {
+			log.Warn().Str("component", p.Component).Msg("Pinned values snapshot no longer exists, releasing values rollback")
+			continue
+		}
+		services[p.Component] = snapshots[idx].Values
+		applied = append(applied, p)
+	}
+	inst.Status.PinnedComponents = applied
+	setValuesPinnedCondition(inst)
+	return nil
+}
+
+// rollbackPin returns the pin of component to the newest snapshot that
+// differs from current, or nil if there is none.
+func (r *DeploymentSubroutine) rollbackPin(ctx context.Context, inst *corev1alpha1.PlatformMesh, component string, current interface{}) (*corev1alpha1.PinnedComponent, error) {
+	snapshots, err := r.loadValuesSnapshots(ctx, inst, component)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := valuesChecksum(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to hash values of %s", component)
+	}
+	for _, s := range snapshots {
+		if s.Checksum != checksum {
+			return &corev1alpha1.PinnedComponent{
+				Component:          component,
+				SnapshotChecksum:   s.Checksum,
+				SnapshotGeneration: s.Generation,
+				PinnedGeneration:   inst.Generation,
+				PinnedAt:           metav1.Now(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// rollbackComponents returns the components listed in request, or all
+// services for "*".
+func rollbackComponents(request string, services map[string]interface{}) []string {
+	var components []string
+	if strings.TrimSpace(request) == "*" {
+		for name := range services {
+			components = append(components, name)
+		}
+	} else {
+		for _, c := range strings.Split(request, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				components = append(components, c)
+			}
+		}
+	}
+	sort.Strings(components)
+	return components
+}
+
+func replacePin(pins []corev1alpha1.PinnedComponent, pin corev1alpha1.PinnedComponent) []corev1alpha1.PinnedComponent {
+	for i := range pins {
+		if pins[i].Component == pin.Component {
+			pins[i] = pin
+			return pins
+		}
+	}
+	return append(pins, pin)
+}
+
+func snapshotIndex(snapshots []valuesSnapshot, checksum string) int {
+	for i, s := range snapshots {
+		if s.Checksum == checksum {
+			return i
+		}
+	}
+	return -1
+}
+
+func setValuesPinnedCondition(inst *corev1alpha1.PlatformMesh) {
+	if len(inst.Status.PinnedComponents) == 0 {
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, ValuesPinnedConditionType)
+		return
+	}
+	var pinned []string
+	for _, p := range inst.Status.PinnedComponents {
+		pinned = append(pinned, fmt.Sprintf("%s (generation %d)", p.Component, p.SnapshotGeneration))
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               ValuesPinnedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RolledBack",
+		Message:            "components rendered from values snapshots until the spec changes: " + strings.Join(pinned, ", "),
+		ObservedGeneration: inst.Generation,
+	})
+}
+
+// templateServices returns values.services of component template vars.
+func templateServices(tmplVars map[string]interface{}) map[string]interface{} {
+	values, _ := tmplVars["values"].(map[string]interface{})
+	services, _ := values["services"].(map[string]interface{})
+	return services
+}
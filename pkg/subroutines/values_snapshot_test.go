@@ -0,0 +1,139 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+)
+
+func newValuesSnapshotSubroutine(t *testing.T, limit int) *DeploymentSubroutine {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+	cfg := config.OperatorConfig{}
+	cfg.Subroutines.Deployment.ValuesSnapshots = limit
+	return &DeploymentSubroutine{
+		clientRuntime: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		cfgOperator:   &cfg,
+	}
+}
+
+func componentVars(services map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"values": map[string]interface{}{"services": services}}
+}
+
+func TestRecordValuesSnapshots(t *testing.T) {
+	ctx := context.Background()
+	r := newValuesSnapshotSubroutine(t, 2)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system", Generation: 1}}
+
+	for i, replicas := range []int{1, 1, 2, 3} {
+		inst.Generation = int64(i + 1)
+		services := map[string]interface{}{
+			"iam":    map[string]interface{}{"replicas": replicas},
+			"broken": map[string]interface{}{"replicas": replicas},
+		}
+		inst.Status.ComponentRenderErrors = []corev1alpha1.ComponentRenderError{{Component: "broken"}}
+		require.NoError(t, r.recordValuesSnapshots(ctx, inst, componentVars(services)))
+	}
+
+	snapshots, err := r.loadValuesSnapshots(ctx, inst, "iam")
+	require.NoError(t, err)
+	// Unchanged values are not recorded again and only the newest two are kept.
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, int64(4), snapshots[0].Generation)
+	assert.Equal(t, int64(3), snapshots[1].Generation)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.clientRuntime.Get(ctx, types.NamespacedName{Name: "pm-values-iam", Namespace: inst.Namespace}, secret))
+	assert.Equal(t, "iam", secret.Labels[ValuesSnapshotLabel])
+
+	snapshots, err = r.loadValuesSnapshots(ctx, inst, "broken")
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestRecordValuesSnapshots_Disabled(t *testing.T) {
+	ctx := context.Background()
+	r := newValuesSnapshotSubroutine(t, 0)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+
+	require.NoError(t, r.recordValuesSnapshots(ctx, inst, componentVars(map[string]interface{}{"iam": map[string]interface{}{}})))
+
+	snapshots, err := r.loadValuesSnapshots(ctx, inst, "iam")
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestApplyValuesPins(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	r := newValuesSnapshotSubroutine(t, 3)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system", Generation: 1}}
+
+	good := map[string]interface{}{"replicas": float64(1)}
+	require.NoError(t, r.recordValuesSnapshots(ctx, inst, componentVars(map[string]interface{}{"iam": good})))
+	inst.Generation = 2
+	bad := map[string]interface{}{"replicas": float64(0)}
+	require.NoError(t, r.recordValuesSnapshots(ctx, inst, componentVars(map[string]interface{}{"iam": bad})))
+
+	// The rollback pins iam to the previous snapshot.
+	inst.Annotations = map[string]string{corev1alpha1.ValuesRollbackAnnotation: "iam"}
+	services := map[string]interface{}{"iam": bad, "kcp": map[string]interface{}{}}
+	require.NoError(t, r.applyValuesPins(ctx, inst, services, log))
+	assert.Equal(t, good, services["iam"])
+	require.Len(t, inst.Status.PinnedComponents, 1)
+	assert.Equal(t, int64(1), inst.Status.PinnedComponents[0].SnapshotGeneration)
+	assert.Equal(t, int64(2), inst.Status.PinnedComponents[0].PinnedGeneration)
+	assert.Equal(t, "iam", inst.Status.ValuesRollback)
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, ValuesPinnedConditionType))
+
+	// The pin holds while the spec is unchanged, the annotation is not handled twice.
+	services = map[string]interface{}{"iam": bad}
+	require.NoError(t, r.applyValuesPins(ctx, inst, services, log))
+	assert.Equal(t, good, services["iam"])
+	require.Len(t, inst.Status.PinnedComponents, 1)
+
+	// A spec change releases the pin.
+	inst.Generation = 3
+	services = map[string]interface{}{"iam": bad}
+	require.NoError(t, r.applyValuesPins(ctx, inst, services, log))
+	assert.Equal(t, bad, services["iam"])
+	assert.Empty(t, inst.Status.PinnedComponents)
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, ValuesPinnedConditionType))
+}
+
+func TestApplyValuesPins_NoSnapshot(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	r := newValuesSnapshotSubroutine(t, 3)
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{
+		Name: "pm", Namespace: "platform-mesh-system", Generation: 1,
+		Annotations: map[string]string{corev1alpha1.ValuesRollbackAnnotation: "*"},
+	}}
+
+	services := map[string]interface{}{"iam": map[string]interface{}{}}
+	require.NoError(t, r.applyValuesPins(ctx, inst, services, log))
+	assert.Empty(t, inst.Status.PinnedComponents)
+	assert.Equal(t, "*", inst.Status.ValuesRollback)
+}
+
+func TestRollbackComponents(t *testing.T) {
+	services := map[string]interface{}{"kcp": nil, "iam": nil}
+	assert.Equal(t, []string{"iam", "kcp"}, rollbackComponents("*", services))
+	assert.Equal(t, []string{"iam", "portal"}, rollbackComponents(" portal, iam,", services))
+}
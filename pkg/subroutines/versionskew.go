@@ -0,0 +1,130 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	gcerrors "github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/version"
+)
+
+const (
+	VersionSkewSubroutineName = "VersionSkewSubroutine"
+	// VersionSkewConditionType is True while the operator refuses to reconcile
+	// because its bundles come from an incompatible major version.
+	VersionSkewConditionType = "VersionSkew"
+
+	versionSkewRequeueInterval = time.Minute
+)
+
+// VersionSkewSubroutine blocks reconciliation when the operator binary, the
+// gotemplates bundle and the installed CRDs come from different major versions.
+type VersionSkewSubroutine struct {
+	client          client.Client
+	gotemplatesDir  string
+	operatorVersion string
+}
+
+func NewVersionSkewSubroutine(client client.Client, cfg *config.OperatorConfig) *VersionSkewSubroutine {
+	return &VersionSkewSubroutine{
+		client:          client,
+		gotemplatesDir:  filepath.Join(cfg.WorkspaceDir, "gotemplates"),
+		operatorVersion: version.Version,
+	}
+}
+
+func (r *VersionSkewSubroutine) GetName() string {
+	return VersionSkewSubroutineName
+}
+
+func (r *VersionSkewSubroutine) Finalize(_ context.Context, _ client.Object) (subroutines.Result, error) {
+	return subroutines.OK(), nil
+}
+
+func (r *VersionSkewSubroutine) Finalizers(_ client.Object) []string { // coverage-ignore
+	return []string{}
+}
+
+func (r *VersionSkewSubroutine) Process(ctx context.Context, runtimeObj client.Object) (res subroutines.Result, err error) {
+	start := time.Now()
+	defer func() {
+		labelResult := "success"
+		if err != nil {
+			labelResult = "error"
+		}
+		metrics.SubroutineTotal.WithLabelValues(r.GetName(), labelResult).Inc()
+		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
+	}()
+	inst := runtimeObj.(*corev1alpha1.PlatformMesh)
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	skewErr := CheckBundleVersionSkew(ctx, r.client, r.gotemplatesDir, r.operatorVersion)
+	var skew *version.SkewError
+	if skewErr != nil && !errors.As(skewErr, &skew) {
+		return subroutines.OK(), skewErr
+	}
+
+	if skew != nil {
+		log.Error().Err(skew).Str("operatorVersion", skew.OperatorVersion).Str("bundleVersion", skew.BundleVersion).Msg("Refusing to reconcile across incompatible versions")
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               VersionSkewConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             "IncompatibleMajorVersion",
+			Message:            skew.Error(),
+			ObservedGeneration: inst.Generation,
+		})
+		return subroutines.StopWithRequeue(versionSkewRequeueInterval, skew.Error()), nil
+	}
+
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               VersionSkewConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Compatible",
+		Message:            "operator version " + r.operatorVersion + " is compatible with installed bundles",
+		ObservedGeneration: inst.Generation,
+	})
+	return subroutines.OK(), nil
+}
+
+// CheckBundleVersionSkew compares operatorVersion with the version recorded in
+// the gotemplates bundle and on the PlatformMesh CRD. It returns a
+// *version.SkewError if either is from a different major version.
+func CheckBundleVersionSkew(ctx context.Context, cl client.Client, gotemplatesDir, operatorVersion string) error {
+	bundleVersion, err := version.ReadBundleVersion(gotemplatesDir)
+	if err != nil {
+		return gcerrors.Wrap(err, "Failed to read bundle version from %s", gotemplatesDir)
+	}
+	if err := version.CheckCompatibility("gotemplates", operatorVersion, bundleVersion); err != nil {
+		return err
+	}
+
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "apiextensions.k8s.io",
+		Version: "v1",
+		Kind:    "CustomResourceDefinition",
+	})
+	if err := cl.Get(ctx, types.NamespacedName{Name: version.PlatformMeshCRDName}, crd); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return gcerrors.Wrap(err, "Failed to get CRD %s", version.PlatformMeshCRDName)
+	}
+	crdVersion := crd.GetAnnotations()[version.BundleVersionAnnotation]
+	return version.CheckCompatibility("CRD "+version.PlatformMeshCRDName, operatorVersion, crdVersion)
+}
@@ -0,0 +1,130 @@
+package subroutines
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/subroutines/mocks"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/version"
+)
+
+type VersionSkewTestSuite struct {
+	suite.Suite
+	clientMock *mocks.Client
+	testObj    *VersionSkewSubroutine
+	log        *logger.Logger
+	dir        string
+}
+
+func TestVersionSkewTestSuite(t *testing.T) {
+	suite.Run(t, new(VersionSkewTestSuite))
+}
+
+func (s *VersionSkewTestSuite) SetupTest() {
+	s.clientMock = new(mocks.Client)
+	s.dir = s.T().TempDir()
+
+	cfg := config.OperatorConfig{WorkspaceDir: s.dir}
+	s.Require().NoError(os.MkdirAll(filepath.Join(s.dir, "gotemplates"), 0o755))
+
+	logCfg := logger.DefaultConfig()
+	logCfg.Level = "debug"
+	logCfg.NoJSON = true
+	logCfg.Name = "VersionSkewTestSuite"
+	s.log, _ = logger.New(logCfg)
+
+	s.testObj = NewVersionSkewSubroutine(s.clientMock, &cfg)
+	s.testObj.operatorVersion = "v1.3.0"
+}
+
+func (s *VersionSkewTestSuite) writeBundleVersion(v string) {
+	s.Require().NoError(os.WriteFile(filepath.Join(s.dir, "gotemplates", version.BundleVersionFile), []byte(v), 0o600))
+}
+
+func (s *VersionSkewTestSuite) mockCRD(bundleVersion string) {
+	s.clientMock.EXPECT().
+		Get(mock.Anything, types.NamespacedName{Name: version.PlatformMeshCRDName}, mock.AnythingOfType("*unstructured.Unstructured")).
+		RunAndReturn(func(ctx context.Context, nn types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+			crd := obj.(*unstructured.Unstructured)
+			crd.SetAnnotations(map[string]string{version.BundleVersionAnnotation: bundleVersion})
+			return nil
+		})
+}
+
+func (s *VersionSkewTestSuite) TestProcess_Compatible() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	s.writeBundleVersion("v1.1.0")
+	s.mockCRD("v1.2.0")
+
+	inst := &corev1alpha1.PlatformMesh{}
+	res, err := s.testObj.Process(ctx, inst)
+
+	s.Require().NoError(err)
+	s.True(res.IsContinue())
+	s.True(apimeta.IsStatusConditionFalse(inst.Status.Conditions, VersionSkewConditionType))
+}
+
+func (s *VersionSkewTestSuite) TestProcess_GotemplatesSkew() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	s.writeBundleVersion("v2.0.0")
+
+	inst := &corev1alpha1.PlatformMesh{}
+	res, err := s.testObj.Process(ctx, inst)
+
+	s.Require().NoError(err)
+	s.True(res.IsStopWithRequeue())
+	s.True(apimeta.IsStatusConditionTrue(inst.Status.Conditions, VersionSkewConditionType))
+}
+
+func (s *VersionSkewTestSuite) TestProcess_CRDSkew() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	s.mockCRD("v0.9.0")
+
+	inst := &corev1alpha1.PlatformMesh{}
+	res, err := s.testObj.Process(ctx, inst)
+
+	s.Require().NoError(err)
+	s.True(res.IsStopWithRequeue())
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, VersionSkewConditionType)
+	s.Require().NotNil(cond)
+	s.Equal("IncompatibleMajorVersion", cond.Reason)
+}
+
+func (s *VersionSkewTestSuite) TestProcess_CRDNotFound() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	s.clientMock.EXPECT().
+		Get(mock.Anything, types.NamespacedName{Name: version.PlatformMeshCRDName}, mock.Anything).
+		Return(kerrors.NewNotFound(schema.GroupResource{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}, version.PlatformMeshCRDName))
+
+	res, err := s.testObj.Process(ctx, &corev1alpha1.PlatformMesh{})
+
+	s.Require().NoError(err)
+	s.True(res.IsContinue())
+}
+
+func (s *VersionSkewTestSuite) TestProcess_GetCRDError() {
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, s.log)
+	s.clientMock.EXPECT().
+		Get(mock.Anything, types.NamespacedName{Name: version.PlatformMeshCRDName}, mock.Anything).
+		Return(errors.New("boom"))
+
+	_, err := s.testObj.Process(ctx, &corev1alpha1.PlatformMesh{})
+
+	s.Error(err)
+}
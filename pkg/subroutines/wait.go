@@ -3,6 +3,8 @@ package subroutines
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/platform-mesh/golang-commons/logger"
@@ -15,6 +17,7 @@ import (
 
 	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
 	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/internal/eventing"
 	"github.com/platform-mesh/platform-mesh-operator/internal/metrics"
 )
 
@@ -31,6 +34,8 @@ func NewWaitSubroutine(
 		cfg:           cfg,
 		kcpHelper:     helper,
 		kcpUrl:        kcpUrl,
+		requeue:       NewRequeueBackoff(WaitSubroutineName, cfg.Subroutines.Wait.Requeue),
+		httpClient:    &http.Client{Timeout: readinessGateProbeTimeout},
 	}
 }
 
@@ -40,6 +45,8 @@ type WaitSubroutine struct {
 	cfg           *config.OperatorConfig
 	kcpHelper     KcpHelper
 	kcpUrl        string
+	requeue       *RequeueBackoff
+	httpClient    *http.Client // readiness gate probes
 }
 
 const (
@@ -65,6 +72,7 @@ func (r *WaitSubroutine) Process(
 		metrics.SubroutineDuration.WithLabelValues(r.GetName()).Observe(time.Since(start).Seconds())
 	}()
 	instance := runtimeObj.(*corev1alpha1.PlatformMesh)
+	defer func() { r.requeue.Observe(instance, res, err) }()
 	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
 
 	waitConfig := DEFAULT_WAIT_CONFIG
@@ -75,6 +83,10 @@ func (r *WaitSubroutine) Process(
 		log.Info().Msg("No WaitConfig specified, using defaults")
 	}
 
+	// All gates are evaluated up front so their status is complete, they
+	// hold up Ready after the built-in checks passed.
+	failedGates := r.evaluateReadinessGates(ctx, instance, log)
+
 	for _, resourceType := range waitConfig.ResourceTypes {
 		log.Info().Msgf("Waiting for resource type: %s", resourceType)
 
@@ -91,11 +103,13 @@ func (r *WaitSubroutine) Process(
 			}, res)
 			if err != nil {
 				log.Info().Msgf("Error getting resource %s/%s: %v", resourceType.Namespace, resourceType.Name, err)
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, "get resource"), nil
+				return subroutines.StopWithRequeue(r.requeue.Next(instance), "get resource"), nil
 			}
-			if !matchesConditionWithStatus(res, string(resourceType.RowConditionType), string(resourceType.ConditionStatus)) {
+			ready := matchesConditionWithStatus(res, string(resourceType.RowConditionType), string(resourceType.ConditionStatus))
+			emitComponentReadiness(res, ready)
+			if !ready {
 				log.Info().Msgf("Resource %s/%s of type %s is not ready yet", resourceType.Namespace, resourceType.Name, res.GetKind())
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, fmt.Sprintf("resource %s/%s of type %s is not ready yet", resourceType.Namespace, resourceType.Name, res.GetKind())), nil
+				return subroutines.StopWithRequeue(r.requeue.Next(instance), fmt.Sprintf("resource %s/%s of type %s is not ready yet", resourceType.Namespace, resourceType.Name, res.GetKind())), nil
 			}
 			continue
 		}
@@ -110,20 +124,22 @@ func (r *WaitSubroutine) Process(
 		ls, err := v1.LabelSelectorAsSelector(&resourceType.LabelSelector)
 		if err != nil {
 			log.Info().Msgf("Error converting label selector: %v", err)
-			return subroutines.StopWithRequeue(DefaultRequeueInterval, "label selector"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(instance), "label selector"), nil
 		}
 		if err := r.client.List(ctx, waitList, &client.ListOptions{
 			Namespace:     resourceType.Namespace,
 			LabelSelector: ls,
 		}); err != nil {
 			log.Info().Msgf("Error listing resources: %v", err)
-			return subroutines.StopWithRequeue(DefaultRequeueInterval, "list resources"), nil
+			return subroutines.StopWithRequeue(r.requeue.Next(instance), "list resources"), nil
 		}
 
 		for _, item := range waitList.Items {
-			if !matchesConditionWithStatus(&item, string(resourceType.RowConditionType), string(resourceType.ConditionStatus)) {
+			ready := matchesConditionWithStatus(&item, string(resourceType.RowConditionType), string(resourceType.ConditionStatus))
+			emitComponentReadiness(&item, ready)
+			if !ready {
 				log.Info().Msgf("Resource %s/%s of type %s is not ready yet", item.GetNamespace(), item.GetName(), item.GetKind())
-				return subroutines.StopWithRequeue(DefaultRequeueInterval, fmt.Sprintf("resource %s/%s of type %s is not ready yet", item.GetNamespace(), item.GetName(), item.GetKind())), nil
+				return subroutines.StopWithRequeue(r.requeue.Next(instance), fmt.Sprintf("resource %s/%s of type %s is not ready yet", item.GetNamespace(), item.GetName(), item.GetKind())), nil
 			}
 		}
 	}
@@ -131,14 +147,29 @@ func (r *WaitSubroutine) Process(
 	// Check if WorkspaceAuthenticationConfiguration audience is still a placeholder
 	// If so, trigger a reconcile to ensure all logic is finished
 	if err := r.checkWorkspaceAuthConfigAudience(ctx, log, instance); err != nil {
-		return subroutines.StopWithRequeue(DefaultRequeueInterval, err.Error()), nil
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), err.Error()), nil
+	}
+
+	if len(failedGates) > 0 {
+		return subroutines.StopWithRequeue(r.requeue.Next(instance), fmt.Sprintf("readiness gates did not pass: %s", strings.Join(failedGates, ", "))), nil
 	}
 
 	return subroutines.OK(), nil
 }
 
+// emitComponentReadiness reports obj turning ready once; a resource falling
+// back to not ready is reported again on its next transition.
+func emitComponentReadiness(obj *unstructured.Unstructured, ready bool) {
+	key := fmt.Sprintf("component/%s/%s/%s", obj.GroupVersionKind().String(), obj.GetNamespace(), obj.GetName())
+	if !ready {
+		eventing.Default().Forget(key)
+		return
+	}
+	eventing.Default().EmitOnTransition(key, eventing.NewComponentReady(obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName()))
+}
+
 func (r *WaitSubroutine) checkWorkspaceAuthConfigAudience(ctx context.Context, log *logger.Logger, inst *corev1alpha1.PlatformMesh) error {
-	kubeCfg, err := BuildKubeconfigFromConfig(r.clientRuntime, &r.cfg.KCP, getExternalKcpHost(inst, r.cfg))
+	kubeCfg, _, err := BuildKubeconfigWithFallback(r.clientRuntime, &r.cfg.KCP, inst.Spec.Kcp.AdminSecretRefs, getExternalKcpHost(inst, r.cfg))
 	if err != nil {
 		log.Debug().Err(err).Msg("Failed to build kubeconfig, skipping WorkspaceAuthenticationConfiguration check")
 		return nil
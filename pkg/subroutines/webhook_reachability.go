@@ -0,0 +1,351 @@
+package subroutines
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+// webhookProbe checks that the webhook endpoint at rawURL accepts TLS
+// connections verified against caBundle.
+type webhookProbe func(ctx context.Context, rawURL string, caBundle []byte) error
+
+// tlsWebhookProbe returns a webhookProbe that completes a TLS handshake with
+// the endpoint within timeout. The system roots are used without caBundle.
+func tlsWebhookProbe(timeout time.Duration) webhookProbe {
+	return func(ctx context.Context, rawURL string, caBundle []byte) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" {
+			return fmt.Errorf("scheme %q is not https", u.Scheme)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		cfg := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+		if len(caBundle) > 0 {
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(caBundle) {
+				return fmt.Errorf("caBundle contains no PEM certificate")
+			}
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		dialer := &tls.Dialer{Config: cfg}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func isWebhookConfiguration(obj unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().Group == "admissionregistration.k8s.io" &&
+		(obj.GetKind() == "MutatingWebhookConfiguration" || obj.GetKind() == "ValidatingWebhookConfiguration")
+}
+
+// webhookEndpointKey identifies a webhook endpoint of an instance.
+type webhookEndpointKey struct {
+	instance      types.NamespacedName
+	workspace     string
+	configuration string
+	webhook       string
+}
+
+// webhookEndpoint is the endpoint of one webhook as configured in kcp, with
+// the result of its last probe. status is nil until it was probed.
+type webhookEndpoint struct {
+	configuration string
+	webhook       string
+	url           string
+	caBundle      []byte
+	status        *corev1alpha1.WebhookEndpointStatus
+}
+
+// workspaceWebhookEndpoints returns the endpoints of every webhook with a URL
+// of the webhook configurations among objs as they exist in kcpClient.
+// Configurations that do not exist are left out.
+func workspaceWebhookEndpoints(ctx context.Context, kcpClient client.Client, objs []unstructured.Unstructured) ([]webhookEndpoint, error) {
+	var endpoints []webhookEndpoint
+	for _, obj := range objs {
+		if !isWebhookConfiguration(obj) {
+			continue
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err := kcpClient.Get(ctx, types.NamespacedName{Name: obj.GetName()}, live)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get %s %s", obj.GetKind(), obj.GetName())
+		}
+
+		webhooks, _, _ := unstructured.NestedSlice(live.Object, "webhooks")
+		for _, w := range webhooks {
+			webhook, _ := w.(map[string]interface{})
+			rawURL, _, _ := unstructured.NestedString(webhook, "clientConfig", "url")
+			if rawURL == "" {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(webhook, "name")
+			// caBundle is base64 encoded in the unstructured object.
+			encoded, _, _ := unstructured.NestedString(webhook, "clientConfig", "caBundle")
+			caBundle, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				// The probe fails on the invalid bundle like kcp would.
+				caBundle = []byte(encoded)
+			}
+			endpoints = append(endpoints, webhookEndpoint{
+				configuration: obj.GetKind() + "/" + obj.GetName(),
+				webhook:       name,
+				url:           rawURL,
+				caBundle:      caBundle,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+// WebhookProber probes the endpoints of the managed kcp webhook
+// configurations every interval, outside of the reconciliation. It implements
+// manager.Runnable. The KcpSetup subroutine tracks the endpoints of each
+// instance and reads the latest results from it; when a result changes, a
+// reconcile of the instance is requested through trigger so it is recorded
+// in the status.
+type WebhookProber struct {
+	probe    webhookProbe
+	trigger  *ReconcileTrigger
+	interval time.Duration
+	// wake starts a probe of the endpoints not probed yet.
+	wake chan struct{}
+
+	mu        sync.Mutex
+	endpoints map[webhookEndpointKey]*webhookEndpoint
+	now       func() time.Time
+}
+
+func NewWebhookProber(trigger *ReconcileTrigger, interval, timeout time.Duration) *WebhookProber {
+	return newWebhookProber(tlsWebhookProbe(timeout), trigger, interval)
+}
+
+func newWebhookProber(probe webhookProbe, trigger *ReconcileTrigger, interval time.Duration) *WebhookProber {
+	return &WebhookProber{
+		probe:     probe,
+		trigger:   trigger,
+		interval:  interval,
+		wake:      make(chan struct{}, 1),
+		endpoints: map[webhookEndpointKey]*webhookEndpoint{},
+		now:       time.Now,
+	}
+}
+
+func (p *WebhookProber) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.Probe(ctx, true)
+		case <-p.wake:
+			p.Probe(ctx, false)
+		}
+	}
+}
+
+func (p *WebhookProber) NeedLeaderElection() bool {
+	return true
+}
+
+// Track replaces the endpoints tracked for workspace of inst with endpoints
+// and returns the latest results of those probed already. Endpoints whose URL
+// or caBundle changed count as not probed; they are probed right away.
+func (p *WebhookProber) Track(inst types.NamespacedName, workspace string, endpoints []webhookEndpoint) []corev1alpha1.WebhookEndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keep := map[webhookEndpointKey]bool{}
+	var statuses []corev1alpha1.WebhookEndpointStatus
+	unprobed := false
+	for _, e := range endpoints {
+		key := webhookEndpointKey{instance: inst, workspace: workspace, configuration: e.configuration, webhook: e.webhook}
+		keep[key] = true
+		tracked, ok := p.endpoints[key]
+		if !ok || tracked.url != e.url || !bytes.Equal(tracked.caBundle, e.caBundle) {
+			p.endpoints[key] = &webhookEndpoint{configuration: e.configuration, webhook: e.webhook, url: e.url, caBundle: e.caBundle}
+			unprobed = true
+			continue
+		}
+		if tracked.status != nil {
+			statuses = append(statuses, *tracked.status)
+		}
+	}
+	for key := range p.endpoints {
+		if key.instance == inst && key.workspace == workspace && !keep[key] {
+			delete(p.endpoints, key)
+		}
+	}
+	if unprobed {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+	return statuses
+}
+
+// Forget stops probing the endpoints of inst.
+func (p *WebhookProber) Forget(inst types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.endpoints {
+		if key.instance == inst {
+			delete(p.endpoints, key)
+		}
+	}
+}
+
+// Probe probes the tracked endpoints, all of them or only those not probed
+// yet, and requests a reconcile of the instances whose results changed.
+func (p *WebhookProber) Probe(ctx context.Context, all bool) {
+	p.mu.Lock()
+	pending := map[webhookEndpointKey]webhookEndpoint{}
+	for key, e := range p.endpoints {
+		if all || e.status == nil {
+			pending[key] = *e
+		}
+	}
+	p.mu.Unlock()
+
+	results := map[webhookEndpointKey]error{}
+	for key, e := range pending {
+		results[key] = p.probe(ctx, e.url, e.caBundle)
+	}
+
+	changed := map[types.NamespacedName]bool{}
+	p.mu.Lock()
+	for key, err := range results {
+		e, ok := p.endpoints[key]
+		// Endpoints changed or dropped while probing are left to the next
+		// probe.
+		if !ok || e.url != pending[key].url || !bytes.Equal(e.caBundle, pending[key].caBundle) {
+			continue
+		}
+		status := corev1alpha1.WebhookEndpointStatus{
+			Configuration: e.configuration,
+			Webhook:       e.webhook,
+			URL:           e.url,
+			Reachable:     err == nil,
+		}
+		if err != nil {
+			status.Message = err.Error()
+		}
+		if e.status != nil && e.status.Reachable == status.Reachable && e.status.Message == status.Message {
+			continue
+		}
+		status.LastTransitionTime = metav1.NewTime(p.now().Truncate(time.Second))
+		e.status = &status
+		changed[key.instance] = true
+	}
+	p.mu.Unlock()
+
+	for inst := range changed {
+		// Requests only fail once ctx ended.
+		if p.trigger.Request(ctx, inst) != nil {
+			return
+		}
+	}
+}
+
+// verifyWebhookEndpoints records in workspaces the latest probe results of
+// the endpoints of the webhook configurations expected in each workspace, see
+// WebhookProber. An endpoint that became unreachable since the status of inst
+// was written is recorded as a WebhookUnreachable event; unreachable
+// endpoints do not fail the reconciliation.
+func (r *KcpsetupSubroutine) verifyWebhookEndpoints(
+	ctx context.Context, inst *corev1alpha1.PlatformMesh, config *rest.Config,
+	expected map[string][]unstructured.Unstructured, workspaces []corev1alpha1.KcpWorkspace,
+) error {
+	if r.webhooks == nil {
+		return nil
+	}
+	log := logger.LoadLoggerFromContext(ctx)
+	key := client.ObjectKeyFromObject(inst)
+	for i := range workspaces {
+		ws := &workspaces[i]
+		if _, skipped := workspaceWaitFrom(ctx).skipped(ws.Name); skipped {
+			ws.Webhooks = previousWebhookStatus(inst, ws.Name)
+			continue
+		}
+		if !containsWebhookConfiguration(expected[ws.Name]) {
+			r.webhooks.Track(key, ws.Name, nil)
+			continue
+		}
+		kcpClient, err := r.kcpHelper.NewKcpClient(config, ws.Name)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create kcp client for workspace %s", ws.Name)
+		}
+		endpoints, err := workspaceWebhookEndpoints(ctx, kcpClient, expected[ws.Name])
+		if err != nil {
+			return errors.Wrap(err, "Failed to get webhooks in workspace %s", ws.Name)
+		}
+		ws.Webhooks = r.webhooks.Track(key, ws.Name, endpoints)
+		previous := previousWebhookStatus(inst, ws.Name)
+		for _, w := range ws.Webhooks {
+			if w.Reachable || slices.ContainsFunc(previous, func(p corev1alpha1.WebhookEndpointStatus) bool {
+				return p.Configuration == w.Configuration && p.Webhook == w.Webhook && !p.Reachable && p.LastTransitionTime.Equal(&w.LastTransitionTime)
+			}) {
+				continue
+			}
+			log.Warn().Str("workspace", ws.Name).Str("webhook", w.Webhook).Str("url", w.URL).Str("reason", w.Message).Msg("Webhook endpoint is not reachable")
+			recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonWebhookUnreachable, "Probe",
+				"Webhook %s of %s in workspace %s is not reachable at %s: %s", w.Webhook, w.Configuration, ws.Name, w.URL, w.Message)
+		}
+	}
+	return nil
+}
+
+// previousWebhookStatus returns the webhook results recorded for workspace in
+// the status of inst.
+func previousWebhookStatus(inst *corev1alpha1.PlatformMesh, workspace string) []corev1alpha1.WebhookEndpointStatus {
+	for _, ws := range inst.Status.KcpWorkspaces {
+		if ws.Name == workspace {
+			return ws.Webhooks
+		}
+	}
+	return nil
+}
+
+func containsWebhookConfiguration(objs []unstructured.Unstructured) bool {
+	for _, obj := range objs {
+		if isWebhookConfiguration(obj) {
+			return true
+		}
+	}
+	return false
+}
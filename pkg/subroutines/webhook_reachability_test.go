@@ -0,0 +1,150 @@
+package subroutines
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTLSWebhookProbe(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	probe := tlsWebhookProbe(time.Second)
+
+	// httptest certificates are issued for example.com and 127.0.0.1.
+	assert.NoError(t, probe(context.Background(), server.URL+"/validate", caBundle))
+
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	otherCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other.Certificate().Raw})
+	other.Close()
+	assert.Error(t, probe(context.Background(), server.URL, otherCA))
+	assert.Error(t, probe(context.Background(), other.URL, caBundle))
+	assert.Error(t, probe(context.Background(), server.URL, []byte("not a certificate")))
+	assert.Error(t, probe(context.Background(), "http://127.0.0.1:1", nil))
+	server.Close()
+}
+
+func TestWorkspaceWebhookEndpoints(t *testing.T) {
+	webhookConfiguration := func(name string, webhooks ...interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       "ValidatingWebhookConfiguration",
+			"metadata":   map[string]interface{}{"name": name},
+			"webhooks":   webhooks,
+		}}
+		return obj
+	}
+	webhook := func(name, url string) interface{} {
+		clientConfig := map[string]interface{}{"caBundle": base64.StdEncoding.EncodeToString([]byte(name))}
+		if url != "" {
+			clientConfig["url"] = url
+		}
+		return map[string]interface{}{"name": name, "clientConfig": clientConfig}
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		webhookConfiguration("iam",
+			webhook("up.example", "https://up.example/validate"),
+			webhook("down.example", "https://down.example/validate"),
+			webhook("service.example", "")),
+	).Build()
+
+	configMap := unstructured.Unstructured{}
+	configMap.SetAPIVersion("v1")
+	configMap.SetKind("ConfigMap")
+	configMap.SetName("iam")
+
+	endpoints, err := workspaceWebhookEndpoints(context.Background(), cl, []unstructured.Unstructured{
+		*webhookConfiguration("iam"), *webhookConfiguration("missing"), configMap,
+	})
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "ValidatingWebhookConfiguration/iam", endpoints[0].configuration)
+	assert.Equal(t, "up.example", endpoints[0].webhook)
+	assert.Equal(t, "https://up.example/validate", endpoints[0].url)
+	assert.Equal(t, []byte("up.example"), endpoints[0].caBundle)
+	assert.Equal(t, "down.example", endpoints[1].webhook)
+}
+
+func TestWebhookProber(t *testing.T) {
+	ctx := context.Background()
+	inst := types.NamespacedName{Namespace: "default", Name: "pm"}
+	down := map[string]bool{}
+	var probed []string
+	probe := func(ctx context.Context, rawURL string, caBundle []byte) error {
+		probed = append(probed, rawURL)
+		if down[rawURL] {
+			return assert.AnError
+		}
+		return nil
+	}
+	trigger := NewReconcileTrigger()
+	p := newWebhookProber(probe, trigger, time.Minute)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	requested := func() bool {
+		select {
+		case <-trigger.events:
+			return true
+		default:
+			return false
+		}
+	}
+	endpoints := []webhookEndpoint{
+		{configuration: "ValidatingWebhookConfiguration/iam", webhook: "up.example", url: "https://up.example/validate"},
+		{configuration: "ValidatingWebhookConfiguration/iam", webhook: "down.example", url: "https://down.example/validate"},
+	}
+	down["https://down.example/validate"] = true
+
+	// Endpoints are probed outside of Track, which only reports results.
+	assert.Empty(t, p.Track(inst, "root:orgs", endpoints))
+	assert.Empty(t, probed)
+	p.Probe(ctx, false)
+	assert.ElementsMatch(t, []string{"https://up.example/validate", "https://down.example/validate"}, probed)
+	assert.True(t, requested())
+	statuses := p.Track(inst, "root:orgs", endpoints)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Reachable)
+	assert.False(t, statuses[1].Reachable)
+	assert.Equal(t, assert.AnError.Error(), statuses[1].Message)
+	assert.Equal(t, metav1.NewTime(now), statuses[1].LastTransitionTime)
+
+	// Unchanged results neither change the status nor request a reconcile.
+	now = now.Add(time.Minute)
+	p.Probe(ctx, true)
+	assert.False(t, requested())
+	assert.Equal(t, statuses, p.Track(inst, "root:orgs", endpoints))
+
+	delete(down, "https://down.example/validate")
+	p.Probe(ctx, true)
+	assert.True(t, requested())
+	statuses = p.Track(inst, "root:orgs", endpoints)
+	assert.True(t, statuses[1].Reachable)
+	assert.Equal(t, metav1.NewTime(now), statuses[1].LastTransitionTime)
+
+	// A changed URL is probed again, dropped and forgotten endpoints are not.
+	probed = nil
+	endpoints[0].url = "https://moved.example/validate"
+	assert.Len(t, p.Track(inst, "root:orgs", endpoints[:1]), 0)
+	p.Probe(ctx, false)
+	assert.Equal(t, []string{"https://moved.example/validate"}, probed)
+	p.Forget(inst)
+	probed = nil
+	p.Probe(ctx, true)
+	assert.Empty(t, probed)
+}
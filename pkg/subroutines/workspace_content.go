@@ -0,0 +1,195 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcpapiv1alpha "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha1"
+)
+
+// workspaceContentKinds are the managed kinds whose absence leaves a workspace
+// unusable even though the workspace itself is Ready.
+var workspaceContentKinds = map[string]string{
+	"APIBinding":                     "apis.kcp.io",
+	"WorkspaceType":                  "tenancy.kcp.io",
+	"MutatingWebhookConfiguration":   "admissionregistration.k8s.io",
+	"ValidatingWebhookConfiguration": "admissionregistration.k8s.io",
+}
+
+func isWorkspaceContent(obj unstructured.Unstructured) bool {
+	group, ok := workspaceContentKinds[obj.GetKind()]
+	return ok && obj.GroupVersionKind().Group == group
+}
+
+// kcpObject is a manifest rendered for the kcp workspace path.
+type kcpObject struct {
+	path string
+	obj  unstructured.Unstructured
+}
+
+// renderDirStructure renders the manifests below dir the same way
+// ApplyDirStructure applies them and returns the objects in apply order,
+// together with the workspace paths in apply order. Files that fail to render
+// are skipped.
+func renderDirStructure(ctx context.Context, dir, kcpPath string, templateData map[string]any) ([]kcpObject, []string, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	var objs []kcpObject
+	var order []string
+	seen := map[string]bool{}
+
+	var walk func(dir, kcpPath string) error
+	walk = func(dir, kcpPath string) error {
+		if !seen[kcpPath] {
+			seen[kcpPath] = true
+			order = append(order, kcpPath)
+		}
+		files, err := ListFiles(dir)
+		if err != nil {
+			return errors.Wrap(err, "Failed to list files in workspace")
+		}
+		for _, file := range files {
+			obj, err := unstructuredFromFile(filepath.Join(dir, file), templateData, log)
+			if err != nil {
+				log.Debug().Err(err).Str("file", file).Msg("Skipping manifest file that failed to render")
+				continue
+			}
+			if obj.Object == nil {
+				continue
+			}
+			objs = append(objs, kcpObject{path: kcpPath, obj: obj})
+		}
+		for _, wsDir := range GetWorkspaceDirs(dir) {
+			wsName, err := GetWorkspaceName(wsDir)
+			if err != nil {
+				continue
+			}
+			wsPath := fmt.Sprintf("%s:%s", kcpPath, wsName)
+			if wsName == kcpPath {
+				wsPath = kcpPath
+			}
+			if err := walk(filepath.Join(dir, wsDir), wsPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(dir, kcpPath); err != nil {
+		return nil, nil, err
+	}
+	return objs, order, nil
+}
+
+// expectedWorkspaceContent returns the tracked objects of objs per workspace
+// path of order.
+func expectedWorkspaceContent(objs []kcpObject, order []string) map[string][]unstructured.Unstructured {
+	expected := make(map[string][]unstructured.Unstructured, len(order))
+	for _, wsPath := range order {
+		expected[wsPath] = nil
+	}
+	for _, o := range objs {
+		if isWorkspaceContent(o.obj) {
+			expected[o.path] = append(expected[o.path], o.obj)
+		}
+	}
+	return expected
+}
+
+// summarizeWorkspaceContent checks that the expected objects exist in each
+// workspace and returns the per-kind counts. Workspaces with absent objects are
+// flagged with MissingContent.
+func summarizeWorkspaceContent(
+	ctx context.Context, config *rest.Config, kcpHelper KcpHelper,
+	expected map[string][]unstructured.Unstructured, order []string,
+) ([]corev1alpha1.KcpWorkspace, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	workspaces := make([]corev1alpha1.KcpWorkspace, 0, len(order))
+
+	for _, wsPath := range order {
+		if phase, skipped := workspaceWaitFrom(ctx).skipped(wsPath); skipped {
+			// the content of a workspace that is not ready was not applied
+			workspaces = append(workspaces, corev1alpha1.KcpWorkspace{Name: wsPath, Phase: phase})
+			continue
+		}
+		ws := corev1alpha1.KcpWorkspace{Name: wsPath, Phase: "Ready"}
+		kcpClient, err := kcpHelper.NewKcpClient(config, wsPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create kcp client for workspace %s", wsPath)
+		}
+		ws.APIBindings = workspaceAPIBindings(ctx, kcpClient, wsPath)
+
+		objs := expected[wsPath]
+		if len(objs) == 0 {
+			workspaces = append(workspaces, ws)
+			continue
+		}
+
+		counts := map[string]*corev1alpha1.WorkspaceContentCount{}
+		var missing []string
+		for _, obj := range objs {
+			count, ok := counts[obj.GetKind()]
+			if !ok {
+				count = &corev1alpha1.WorkspaceContentCount{Kind: obj.GetKind()}
+				counts[obj.GetKind()] = count
+			}
+			count.Expected++
+
+			current := &unstructured.Unstructured{}
+			current.SetGroupVersionKind(obj.GroupVersionKind())
+			err := kcpClient.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, current)
+			if kerrors.IsNotFound(err) {
+				missing = append(missing, obj.GetKind()+"/"+obj.GetName())
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to get %s %s in workspace %s", obj.GetKind(), obj.GetName(), wsPath)
+			}
+			count.Present++
+		}
+
+		for _, count := range counts {
+			ws.Content = append(ws.Content, *count)
+		}
+		sort.Slice(ws.Content, func(i, j int) bool { return ws.Content[i].Kind < ws.Content[j].Kind })
+
+		if len(missing) > 0 {
+			ws.MissingContent = true
+			log.Warn().Str("workspace", wsPath).Strs("missing", missing).Msg("Workspace is missing expected content")
+		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, nil
+}
+
+// workspaceAPIBindings lists the APIBindings in the workspace wsPath with
+// their phase, and why they are not ready. The list is informational, so a
+// failure to list is only logged.
+func workspaceAPIBindings(ctx context.Context, kcpClient client.Client, wsPath string) []corev1alpha1.WorkspaceAPIBinding {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(kcpapiv1alpha.SchemeGroupVersion.WithKind("APIBindingList"))
+	if err := kcpClient.List(ctx, list); err != nil {
+		logger.LoadLoggerFromContext(ctx).Warn().Err(err).Str("workspace", wsPath).Msg("Failed to list APIBindings")
+		return nil
+	}
+	var bindings []corev1alpha1.WorkspaceAPIBinding
+	for i := range list.Items {
+		binding := &list.Items[i]
+		phase, _, _ := unstructured.NestedString(binding.Object, "status", "phase")
+		_, message := apiBindingReady(binding)
+		bindings = append(bindings, corev1alpha1.WorkspaceAPIBinding{Name: binding.GetName(), Phase: phase, Message: message})
+	}
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].Name < bindings[j].Name })
+	return bindings
+}
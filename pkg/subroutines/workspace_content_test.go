@@ -0,0 +1,135 @@
+package subroutines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kcpapiv1alpha2 "github.com/kcp-dev/kcp/sdk/apis/apis/v1alpha2"
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func writeManifest(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func workspaceContentDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeManifest(t, dir, "workspace-type-universal.yaml", `apiVersion: tenancy.kcp.io/v1alpha1
+kind: WorkspaceType
+metadata:
+  name: universal
+`)
+	writeManifest(t, dir, "workspace-type-account.yaml", `apiVersion: tenancy.kcp.io/v1alpha1
+kind: WorkspaceType
+metadata:
+  name: {{ .name }}
+`)
+	writeManifest(t, dir, "configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+  namespace: default
+`)
+	writeManifest(t, filepath.Join(dir, "01-orgs"), "apibinding-core.yaml", `apiVersion: apis.kcp.io/v1alpha2
+kind: APIBinding
+metadata:
+  name: core
+spec:
+  reference:
+    export:
+      name: core.platform-mesh.io
+      path: root
+`)
+	return dir
+}
+
+func TestExpectedWorkspaceContent(t *testing.T) {
+	dir := workspaceContentDir(t)
+
+	objs, order, err := renderDirStructure(context.Background(), dir, "root", map[string]any{"name": "account"})
+	require.NoError(t, err)
+	expected := expectedWorkspaceContent(objs, order)
+	require.Equal(t, []string{"root", "root:orgs"}, order)
+	require.Len(t, expected["root"], 2)
+	require.Len(t, expected["root:orgs"], 1)
+	require.Equal(t, "APIBinding", expected["root:orgs"][0].GetKind())
+}
+
+func TestSummarizeWorkspaceContent_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), keys.LoggerCtxKey, log)
+	helper := &Helper{}
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "orgs"},
+		Spec: kcptenancyv1alpha.WorkspaceSpec{
+			Type: &kcptenancyv1alpha.WorkspaceTypeReference{Name: "organization", Path: "root"},
+		},
+	}))
+
+	dir := workspaceContentDir(t)
+	objs, order, err := renderDirStructure(ctx, dir, "root", map[string]any{"name": "account"})
+	require.NoError(t, err)
+	expected := expectedWorkspaceContent(objs, order)
+
+	// The account WorkspaceType and the APIBinding in root:orgs were never applied.
+	workspaces, err := summarizeWorkspaceContent(ctx, server.RestConfig(), helper, expected, order)
+	require.NoError(t, err)
+	require.Equal(t, []corev1alpha1.KcpWorkspace{
+		{
+			Name:           "root",
+			Phase:          "Ready",
+			Content:        []corev1alpha1.WorkspaceContentCount{{Kind: "WorkspaceType", Expected: 2, Present: 1}},
+			MissingContent: true,
+		},
+		{
+			Name:           "root:orgs",
+			Phase:          "Ready",
+			Content:        []corev1alpha1.WorkspaceContentCount{{Kind: "APIBinding", Expected: 1, Present: 0}},
+			MissingContent: true,
+		},
+	}, workspaces)
+
+	orgs, err := helper.NewKcpClient(server.RestConfig(), "root:orgs")
+	require.NoError(t, err)
+	require.NoError(t, orgs.Create(ctx, &kcpapiv1alpha2.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "core"},
+		Spec: kcpapiv1alpha2.APIBindingSpec{
+			Reference: kcpapiv1alpha2.BindingReference{
+				Export: &kcpapiv1alpha2.ExportBindingReference{Name: "core.platform-mesh.io", Path: "root"},
+			},
+		},
+	}))
+
+	workspaces, err = summarizeWorkspaceContent(ctx, server.RestConfig(), helper, expected, order)
+	require.NoError(t, err)
+	require.False(t, workspaces[1].MissingContent)
+	require.Equal(t, 1, workspaces[1].Content[0].Present)
+	require.Equal(t, []corev1alpha1.WorkspaceAPIBinding{{Name: "core", Message: `phase is ""`}}, workspaces[1].APIBindings)
+
+	binding := &kcpapiv1alpha2.APIBinding{}
+	require.NoError(t, orgs.Get(ctx, client.ObjectKey{Name: "core"}, binding))
+	binding.Status.Phase = kcpapiv1alpha2.APIBindingPhaseBound
+	require.NoError(t, orgs.Status().Update(ctx, binding))
+
+	workspaces, err = summarizeWorkspaceContent(ctx, server.RestConfig(), helper, expected, order)
+	require.NoError(t, err)
+	require.Equal(t, []corev1alpha1.WorkspaceAPIBinding{{Name: "core", Phase: "Bound"}}, workspaces[1].APIBindings)
+}
@@ -0,0 +1,177 @@
+package subroutines
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+)
+
+const (
+	// WorkspaceProtectionFinalizer keeps a protected workspace from being
+	// deleted until its protection is unlocked.
+	WorkspaceProtectionFinalizer = "core.platform-mesh.io/deletion-protection"
+	// WorkspacesProtectedConditionType is False when the protection of a
+	// workspace was tampered with or the deletion of a locked workspace was
+	// requested. It is only set while workspaces are protected.
+	WorkspacesProtectedConditionType = "WorkspacesProtected"
+
+	workspaceProtectionReasonProtected = "Protected"
+	workspaceProtectionReasonTampered  = "ProtectionTampered"
+	workspaceProtectionReasonBlocked   = "DeletionBlocked"
+)
+
+// workspaceProtectionUnlocked reports whether both unlock steps were taken
+// for the protected workspace ws at path.
+func workspaceProtectionUnlocked(inst *corev1alpha1.PlatformMesh, path string, ws client.Object) bool {
+	protection := inst.Spec.Kcp.DeletionProtection
+	return protection != nil && slices.Contains(protection.Unlocked, path) &&
+		ws.GetAnnotations()[corev1alpha1.WorkspaceDeletionUnlockAnnotation] == "true"
+}
+
+// protectWorkspaces keeps the finalizer on the workspaces listed in
+// spec.kcp.deletionProtection and removes it from unlocked ones. A protected
+// workspace that lost its finalizer or was deleted counts as tampered with and
+// is reported through WorkspacesProtectedConditionType and a warning event,
+// as is a pending deletion of a locked workspace. The protected workspaces
+// are stored in status.protectedWorkspaces.
+func (r *KcpsetupSubroutine) protectWorkspaces(ctx context.Context, config *rest.Config, inst *corev1alpha1.PlatformMesh) error {
+	log := logger.LoadLoggerFromContext(ctx).ChildLogger("subroutine", r.GetName())
+
+	var paths []string
+	if inst.Spec.Kcp.DeletionProtection != nil {
+		paths = inst.Spec.Kcp.DeletionProtection.Workspaces
+	}
+	wasProtected := inst.Status.ProtectedWorkspaces
+	var protected, tampered, blocked []string
+
+	for _, path := range paths {
+		lastColon := strings.LastIndex(path, ":")
+		if lastColon == -1 {
+			log.Warn().Str("path", path).Msg("Invalid workspace path format for deletion protection, skipping. Must be 'parent:name'.")
+			continue
+		}
+		kcpClient, err := r.kcpHelper.NewKcpClient(config, path[:lastColon])
+		if err != nil {
+			return errors.Wrap(err, "Failed to create kcp client for parent workspace %s", path[:lastColon])
+		}
+
+		ws := &unstructured.Unstructured{}
+		ws.SetGroupVersionKind(kcptenancyv1alpha.SchemeGroupVersion.WithKind("Workspace"))
+		err = kcpClient.Get(ctx, types.NamespacedName{Name: path[lastColon+1:]}, ws)
+		if kerrors.IsNotFound(err) {
+			if slices.Contains(wasProtected, path) {
+				tampered = append(tampered, path+" was deleted")
+				recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonProtectionTampered, "ProtectWorkspace", "Protected workspace %s was deleted", path)
+			}
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to get workspace %s", path)
+		}
+
+		hasFinalizer := controllerutil.ContainsFinalizer(ws, WorkspaceProtectionFinalizer)
+		if workspaceProtectionUnlocked(inst, path, ws) {
+			if hasFinalizer {
+				if err := patchWorkspaceFinalizer(ctx, kcpClient, ws, false); err != nil {
+					return errors.Wrap(err, "Failed to release deletion protection of workspace %s", path)
+				}
+				log.Info().Str("workspace", path).Msg("Released deletion protection of workspace")
+				recordEvent(ctx, inst, corev1.EventTypeNormal, EventReasonProtectionReleased, "ProtectWorkspace", "Released deletion protection of workspace %s", path)
+			}
+			continue
+		}
+
+		if !hasFinalizer && slices.Contains(wasProtected, path) {
+			tampered = append(tampered, path+" lost its finalizer")
+			log.Warn().Str("workspace", path).Msg("Deletion protection of workspace was removed")
+			recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonProtectionTampered, "ProtectWorkspace", "Deletion protection of workspace %s was removed", path)
+		}
+		if ws.GetDeletionTimestamp() != nil {
+			// Finalizers cannot be added to a workspace that is being deleted.
+			if hasFinalizer {
+				blocked = append(blocked, path)
+				protected = append(protected, path)
+				log.Warn().Str("workspace", path).Msg("Deletion of protected workspace is blocked")
+				recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonDeletionBlocked, "ProtectWorkspace",
+					"Deletion of protected workspace %s is blocked until it is unlocked", path)
+			}
+			continue
+		}
+		if !hasFinalizer {
+			if err := patchWorkspaceFinalizer(ctx, kcpClient, ws, true); err != nil {
+				return errors.Wrap(err, "Failed to protect workspace %s", path)
+			}
+			log.Info().Str("workspace", path).Msg("Protected workspace from deletion")
+		}
+		protected = append(protected, path)
+	}
+
+	inst.Status.ProtectedWorkspaces = protected
+	setWorkspaceProtectionCondition(inst, protected, tampered, blocked)
+	return nil
+}
+
+func patchWorkspaceFinalizer(ctx context.Context, kcpClient client.Client, ws *unstructured.Unstructured, add bool) error {
+	patch := client.MergeFrom(ws.DeepCopy())
+	if add {
+		controllerutil.AddFinalizer(ws, WorkspaceProtectionFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(ws, WorkspaceProtectionFinalizer)
+	}
+	return kcpClient.Patch(ctx, ws, patch)
+}
+
+func setWorkspaceProtectionCondition(inst *corev1alpha1.PlatformMesh, protected, tampered, blocked []string) {
+	if len(protected) == 0 && len(tampered) == 0 {
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, WorkspacesProtectedConditionType)
+		return
+	}
+	cond := metav1.Condition{
+		Type:               WorkspacesProtectedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             workspaceProtectionReasonProtected,
+		Message:            fmt.Sprintf("%d workspace(s) protected from deletion", len(protected)),
+		ObservedGeneration: inst.Generation,
+	}
+	switch {
+	case len(tampered) > 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = workspaceProtectionReasonTampered
+		cond.Message = "Deletion protection was tampered with: " + strings.Join(tampered, "; ")
+	case len(blocked) > 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = workspaceProtectionReasonBlocked
+		cond.Message = "Deletion of locked workspaces requested: " + strings.Join(blocked, ", ")
+	}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, cond)
+}
+
+// withoutProtectedWorkspaces drops the workspaces in protected from objs, so
+// the teardown neither deletes nor waits for them.
+func withoutProtectedWorkspaces(objs []kcpObject, protected []string) []kcpObject {
+	kept := make([]kcpObject, 0, len(objs))
+	for _, o := range objs {
+		if isWorkspace(o.obj) && slices.Contains(protected, o.path+":"+o.obj.GetName()) {
+			continue
+		}
+		kept = append(kept, o)
+	}
+	return kept
+}
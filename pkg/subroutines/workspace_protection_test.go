@@ -0,0 +1,101 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func TestProtectWorkspaces_FakeKcp(t *testing.T) {
+	server := fakekcp.New()
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rec := events.NewFakeRecorder(10)
+	ctx := WithEventRecorder(context.WithValue(context.Background(), keys.LoggerCtxKey, log), rec)
+	helper := &Helper{}
+
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	orgType := &kcptenancyv1alpha.WorkspaceTypeReference{Name: "organization", Path: "root"}
+	require.NoError(t, root.Create(ctx, &kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "orgs"}, Spec: kcptenancyv1alpha.WorkspaceSpec{Type: orgType}}))
+
+	r := &KcpsetupSubroutine{kcpHelper: helper, cfg: &config.OperatorConfig{}}
+	inst := &corev1alpha1.PlatformMesh{Spec: corev1alpha1.PlatformMeshSpec{Kcp: corev1alpha1.Kcp{
+		DeletionProtection: &corev1alpha1.WorkspaceDeletionProtection{Workspaces: []string{"root:orgs", "root:missing"}},
+	}}}
+	getOrgs := func() *kcptenancyv1alpha.Workspace {
+		ws := &kcptenancyv1alpha.Workspace{}
+		require.NoError(t, root.Get(ctx, client.ObjectKey{Name: "orgs"}, ws))
+		return ws
+	}
+
+	require.NoError(t, r.protectWorkspaces(ctx, server.RestConfig(), inst))
+	assert.Contains(t, getOrgs().Finalizers, WorkspaceProtectionFinalizer)
+	assert.Equal(t, []string{"root:orgs"}, inst.Status.ProtectedWorkspaces)
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, WorkspacesProtectedConditionType))
+
+	// Removing the finalizer is reported and undone.
+	ws := getOrgs()
+	ws.Finalizers = nil
+	require.NoError(t, root.Update(ctx, ws))
+	require.NoError(t, r.protectWorkspaces(ctx, server.RestConfig(), inst))
+	assert.Contains(t, getOrgs().Finalizers, WorkspaceProtectionFinalizer)
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, WorkspacesProtectedConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, workspaceProtectionReasonTampered, cond.Reason)
+	assert.Equal(t, "Warning ProtectionTampered Deletion protection of workspace root:orgs was removed", <-rec.Events)
+
+	// A deletion is blocked while the workspace is locked.
+	require.NoError(t, root.Delete(ctx, getOrgs()))
+	require.NoError(t, r.protectWorkspaces(ctx, server.RestConfig(), inst))
+	cond = apimeta.FindStatusCondition(inst.Status.Conditions, WorkspacesProtectedConditionType)
+	assert.Equal(t, workspaceProtectionReasonBlocked, cond.Reason)
+	assert.Equal(t, "Warning DeletionBlocked Deletion of protected workspace root:orgs is blocked until it is unlocked", <-rec.Events)
+
+	// Listing the workspace as unlocked alone is not enough.
+	inst.Spec.Kcp.DeletionProtection.Unlocked = []string{"root:orgs"}
+	require.NoError(t, r.protectWorkspaces(ctx, server.RestConfig(), inst))
+	assert.Contains(t, getOrgs().Finalizers, WorkspaceProtectionFinalizer)
+	<-rec.Events
+
+	// With the annotation the protection is released and the deletion completes.
+	ws = getOrgs()
+	ws.Annotations = map[string]string{corev1alpha1.WorkspaceDeletionUnlockAnnotation: "true"}
+	require.NoError(t, root.Update(ctx, ws))
+	require.NoError(t, r.protectWorkspaces(ctx, server.RestConfig(), inst))
+	assert.Equal(t, "Normal ProtectionReleased Released deletion protection of workspace root:orgs", <-rec.Events)
+	assert.Empty(t, inst.Status.ProtectedWorkspaces)
+	assert.Nil(t, apimeta.FindStatusCondition(inst.Status.Conditions, WorkspacesProtectedConditionType))
+	err = root.Get(ctx, client.ObjectKey{Name: "orgs"}, &kcptenancyv1alpha.Workspace{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestWithoutProtectedWorkspaces(t *testing.T) {
+	workspace := func(name string) unstructured.Unstructured {
+		ws := unstructured.Unstructured{}
+		ws.SetGroupVersionKind(kcptenancyv1alpha.SchemeGroupVersion.WithKind("Workspace"))
+		ws.SetName(name)
+		return ws
+	}
+	objs := []kcpObject{{path: "root", obj: workspace("orgs")}, {path: "root", obj: workspace("extra")}}
+
+	kept := withoutProtectedWorkspaces(objs, []string{"root:orgs"})
+	require.Len(t, kept, 1)
+	assert.Equal(t, "extra", kept[0].obj.GetName())
+}
@@ -0,0 +1,201 @@
+package subroutines
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+)
+
+const (
+	// WorkspacesReadyConditionType is False when kcp workspaces did not become
+	// ready within their timeout, so their subtrees were not set up.
+	WorkspacesReadyConditionType = "WorkspacesReady"
+
+	workspacesReasonReady    = "Ready"
+	workspacesReasonNotReady = "NotReady"
+	workspacePhaseReady      = "Ready"
+	// workspacePhasePending is reported for the workspaces below one that did
+	// not become ready, and workspacePhaseUnknown for a workspace that was
+	// never seen.
+	workspacePhasePending = "Pending"
+	workspacePhaseUnknown = "Unknown"
+
+	defaultWorkspaceTimeout = 15 * time.Second
+)
+
+// WorkspaceNotReadyError is returned when a kcp workspace is not ready.
+type WorkspaceNotReadyError struct {
+	// Path of the workspace and the phase it was last seen in.
+	Path  string
+	Phase string
+	// Created is when the workspace was created, zero if it was not found.
+	Created time.Time
+	Err     error
+}
+
+func (e *WorkspaceNotReadyError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("workspace %s is not ready (phase %s)", e.Path, e.Phase)
+	}
+	return fmt.Sprintf("workspace %s did not become ready (phase %s): %v", e.Path, e.Phase, e.Err)
+}
+
+func (e *WorkspaceNotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// WorkspacesNotReadyError is returned when the kcp setup skipped the subtrees
+// of workspaces that did not become ready.
+type WorkspacesNotReadyError struct {
+	NotReady []corev1alpha1.KcpWorkspace
+}
+
+func (e *WorkspacesNotReadyError) Error() string {
+	names := make([]string, 0, len(e.NotReady))
+	for _, ws := range e.NotReady {
+		names = append(names, fmt.Sprintf("%s (%s)", ws.Name, ws.Phase))
+	}
+	return fmt.Sprintf("%d workspace(s) not ready: %s", len(e.NotReady), strings.Join(names, ", "))
+}
+
+// workspaceWait collects the workspaces that are not ready yet while the kcp
+// manifests are applied, so that the setup skips their subtrees, carries on
+// with the other workspaces and requeues instead of waiting for them. A nil
+// *workspaceWait fails the setup on the first workspace that is not ready.
+type workspaceWait struct {
+	// timeout only sets when a warning event is recorded for a workspace that
+	// is still not ready after its creation. Nothing waits for the workspace.
+	timeout  time.Duration
+	now      func() time.Time
+	notReady []corev1alpha1.KcpWorkspace
+}
+
+type workspaceWaitKey struct{}
+
+// withWorkspaceWait returns ctx carrying a wait that warns about workspaces
+// not ready timeout after their creation. A zero timeout warns after
+// defaultWorkspaceTimeout.
+func withWorkspaceWait(ctx context.Context, timeout time.Duration) (context.Context, *workspaceWait) {
+	if timeout <= 0 {
+		timeout = defaultWorkspaceTimeout
+	}
+	w := &workspaceWait{timeout: timeout, now: time.Now}
+	return context.WithValue(ctx, workspaceWaitKey{}, w), w
+}
+
+func workspaceWaitFrom(ctx context.Context) *workspaceWait {
+	w, _ := ctx.Value(workspaceWaitKey{}).(*workspaceWait)
+	return w
+}
+
+// skip records the workspace of a *WorkspaceNotReadyError and reports whether
+// the caller skips its subtree. Other errors are not skipped. A workspace
+// still not ready timeout after its creation is warned about.
+func (w *workspaceWait) skip(ctx context.Context, err error, inst *corev1alpha1.PlatformMesh) bool {
+	var notReady *WorkspaceNotReadyError
+	if w == nil || !stderrors.As(err, &notReady) {
+		return false
+	}
+	w.notReady = append(w.notReady, corev1alpha1.KcpWorkspace{Name: notReady.Path, Phase: notReady.Phase})
+	log := logger.LoadLoggerFromContext(ctx)
+	if notReady.Created.IsZero() || w.now().Sub(notReady.Created) < w.timeout {
+		log.Info().Str("workspace", notReady.Path).Str("phase", notReady.Phase).Msg("Workspace is not ready yet, skipping its subtree")
+		return true
+	}
+	log.Warn().Err(err).Str("workspace", notReady.Path).Msg("Workspace did not become ready, skipping its subtree")
+	recordEvent(ctx, inst, corev1.EventTypeWarning, EventReasonWorkspaceNotReady, "WaitForWorkspace",
+		"Workspace %s did not become ready within %s, it is in phase %s", notReady.Path, w.timeout, notReady.Phase)
+	return true
+}
+
+// skipped reports whether the workspace at wsPath or one of its ancestors did
+// not become ready, and the phase to report for it.
+func (w *workspaceWait) skipped(wsPath string) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	for _, ws := range w.notReady {
+		if ws.Name == wsPath {
+			return ws.Phase, true
+		}
+		if strings.HasPrefix(wsPath, ws.Name+":") {
+			return workspacePhasePending, true
+		}
+	}
+	return "", false
+}
+
+// report sets WorkspacesReadyConditionType of inst. It returns a
+// *WorkspacesNotReadyError if any workspace did not become ready.
+func (w *workspaceWait) report(inst *corev1alpha1.PlatformMesh) error {
+	if w == nil {
+		apimeta.RemoveStatusCondition(&inst.Status.Conditions, WorkspacesReadyConditionType)
+		return nil
+	}
+	if len(w.notReady) == 0 {
+		apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               WorkspacesReadyConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             workspacesReasonReady,
+			Message:            "All kcp workspaces are ready",
+			ObservedGeneration: inst.Generation,
+		})
+		return nil
+	}
+	notReady := &WorkspacesNotReadyError{NotReady: w.notReady}
+	apimeta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+		Type:               WorkspacesReadyConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             workspacesReasonNotReady,
+		Message:            notReady.Error(),
+		ObservedGeneration: inst.Generation,
+	})
+	return notReady
+}
+
+// workspacesNotReady leaves the kcp setup pending for a
+// WorkspacesNotReadyError instead of failing the reconciliation, since the
+// workspaces may still become ready.
+func workspacesNotReady(err error, requeue time.Duration) (subroutines.Result, bool) {
+	var notReady *WorkspacesNotReadyError
+	if !stderrors.As(err, &notReady) {
+		return subroutines.Result{}, false
+	}
+	return subroutines.Pending(requeue, notReady.Error()), true
+}
+
+// checkWorkspace reads the workspace name of the workspace cl points to once
+// and returns a *WorkspaceNotReadyError for path if it is not ready, so that
+// the reconcile requeues instead of waiting for it.
+func checkWorkspace(ctx context.Context, cl client.Client, path, name string) error {
+	ws := &kcptenancyv1alpha.Workspace{}
+	err := cl.Get(ctx, types.NamespacedName{Name: name}, ws)
+	if kerrors.IsNotFound(err) {
+		return &WorkspaceNotReadyError{Path: path, Phase: workspacePhaseUnknown}
+	}
+	if err != nil {
+		return err
+	}
+	if ws.Status.Phase == workspacePhaseReady {
+		return nil
+	}
+	phase := workspacePhaseUnknown
+	if ws.Status.Phase != "" {
+		phase = string(ws.Status.Phase)
+	}
+	return &WorkspaceNotReadyError{Path: path, Phase: phase, Created: ws.CreationTimestamp.Time}
+}
@@ -0,0 +1,131 @@
+package subroutines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kcptenancyv1alpha "github.com/kcp-dev/kcp/sdk/apis/tenancy/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/platform-mesh/platform-mesh-operator/api/v1alpha1"
+	"github.com/platform-mesh/platform-mesh-operator/internal/config"
+	"github.com/platform-mesh/platform-mesh-operator/pkg/fakekcp"
+)
+
+func TestCheckWorkspace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kcptenancyv1alpha.AddToScheme(scheme))
+	created := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "orgs", CreationTimestamp: created}, Status: kcptenancyv1alpha.WorkspaceStatus{Phase: "Initializing"}},
+		&kcptenancyv1alpha.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "providers"}, Status: kcptenancyv1alpha.WorkspaceStatus{Phase: "Ready"}},
+	).Build()
+
+	assert.NoError(t, checkWorkspace(context.Background(), cl, "root:providers", "providers"))
+
+	var notReady *WorkspaceNotReadyError
+	require.ErrorAs(t, checkWorkspace(context.Background(), cl, "root:orgs", "orgs"), &notReady)
+	assert.Equal(t, "Initializing", notReady.Phase)
+	assert.True(t, created.Time.Equal(notReady.Created))
+	assert.EqualError(t, notReady, "workspace root:orgs is not ready (phase Initializing)")
+
+	require.ErrorAs(t, checkWorkspace(context.Background(), cl, "root:missing", "missing"), &notReady)
+	assert.Equal(t, workspacePhaseUnknown, notReady.Phase)
+	assert.True(t, notReady.Created.IsZero())
+}
+
+func TestWorkspaceWait(t *testing.T) {
+	var w *workspaceWait
+	assert.False(t, w.skip(context.Background(), &WorkspaceNotReadyError{Path: "root:orgs"}, nil))
+
+	rec := events.NewFakeRecorder(10)
+	ctx, w := withWorkspaceWait(WithEventRecorder(context.Background(), rec), 0)
+	assert.Equal(t, defaultWorkspaceTimeout, w.timeout)
+	assert.Same(t, w, workspaceWaitFrom(ctx))
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	inst := &corev1alpha1.PlatformMesh{}
+	require.NoError(t, w.report(inst))
+	assert.True(t, apimeta.IsStatusConditionTrue(inst.Status.Conditions, WorkspacesReadyConditionType))
+
+	// A new workspace is skipped without a warning, one that is not ready
+	// within the timeout is warned about.
+	assert.False(t, w.skip(ctx, assert.AnError, inst))
+	assert.True(t, w.skip(ctx, &WorkspaceNotReadyError{Path: "root:orgs:new", Phase: "Scheduling", Created: now.Add(-time.Second)}, inst))
+	assert.Empty(t, rec.Events)
+	assert.True(t, w.skip(ctx, &WorkspaceNotReadyError{Path: "root:orgs", Phase: "Initializing", Created: now.Add(-time.Minute)}, inst))
+	assert.Equal(t, "Warning WorkspaceNotReady Workspace root:orgs did not become ready within 15s, it is in phase Initializing", <-rec.Events)
+	w.notReady = w.notReady[1:]
+
+	phase, skipped := w.skipped("root:orgs")
+	assert.True(t, skipped)
+	assert.Equal(t, "Initializing", phase)
+	phase, skipped = w.skipped("root:orgs:default")
+	assert.True(t, skipped)
+	assert.Equal(t, workspacePhasePending, phase)
+	_, skipped = w.skipped("root:orgsx")
+	assert.False(t, skipped)
+
+	err := w.report(inst)
+	var notReady *WorkspacesNotReadyError
+	require.ErrorAs(t, err, &notReady)
+	assert.EqualError(t, err, "1 workspace(s) not ready: root:orgs (Initializing)")
+	cond := apimeta.FindStatusCondition(inst.Status.Conditions, WorkspacesReadyConditionType)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, err.Error(), cond.Message)
+
+	res, ok := workspacesNotReady(err, DefaultRequeueInterval)
+	require.True(t, ok)
+	assert.True(t, res.IsPending())
+	_, ok = workspacesNotReady(assert.AnError, DefaultRequeueInterval)
+	assert.False(t, ok)
+}
+
+func TestApplyWorkspaceSteps_WorkspaceNotReady(t *testing.T) {
+	server := fakekcp.New(fakekcp.WithWorkspacePhase("Initializing"))
+	defer server.Close()
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	ctx, w := withWorkspaceWait(context.WithValue(context.Background(), keys.LoggerCtxKey, log), 100*time.Millisecond)
+	helper := &Helper{}
+
+	dir := declaredWorkspacesDir(t)
+	orgType := &corev1alpha1.WorkspaceTypeReference{Name: "organization", Path: "root"}
+	steps, err := resolveWorkspaceSteps(dir, []corev1alpha1.KcpWorkspaceManifests{
+		{Path: "root", Manifests: []string{"root/*.yaml"}},
+		{Path: "root:orgs", Type: orgType, Manifests: []string{"orgs/*.yaml"}},
+		{Path: "root:orgs:default", Type: orgType},
+	})
+	require.NoError(t, err)
+
+	r := &KcpsetupSubroutine{kcpHelper: helper, cfg: &config.OperatorConfig{}}
+	inst := &corev1alpha1.PlatformMesh{ObjectMeta: metav1.ObjectMeta{Name: "pm", Namespace: "platform-mesh-system"}}
+	require.NoError(t, r.applyWorkspaceSteps(ctx, server.RestConfig(), steps, map[string]any{"baseDomain": "example.com"}, inst, nil))
+
+	// The manifests of the ready root workspace are applied, the subtree of
+	// the initializing workspace is skipped.
+	root, err := helper.NewKcpClient(server.RestConfig(), "root")
+	require.NoError(t, err)
+	require.NoError(t, root.Get(ctx, client.ObjectKey{Name: "org"}, &kcptenancyv1alpha.WorkspaceType{}))
+	assert.False(t, server.HasCluster("root:orgs:default"))
+
+	workspaces, err := summarizeWorkspaceContent(ctx, server.RestConfig(), helper, nil, []string{"root", "root:orgs", "root:orgs:default"})
+	require.NoError(t, err)
+	require.Len(t, workspaces, 3)
+	assert.Equal(t, "Ready", workspaces[0].Phase)
+	assert.Equal(t, corev1alpha1.KcpWorkspace{Name: "root:orgs", Phase: "Initializing"}, workspaces[1])
+	assert.Equal(t, corev1alpha1.KcpWorkspace{Name: "root:orgs:default", Phase: workspacePhasePending}, workspaces[2])
+	assert.EqualError(t, w.report(inst), "1 workspace(s) not ready: root:orgs (Initializing)")
+}
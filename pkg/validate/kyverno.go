@@ -0,0 +1,125 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Kyverno evaluates objects with `kyverno apply` against the policies in PolicyDir.
+type Kyverno struct {
+	Binary    string
+	PolicyDir string
+	Run       Runner
+}
+
+// NewKyverno returns a Kyverno validator using the given CLI binary.
+func NewKyverno(binary, policyDir string) *Kyverno {
+	return &Kyverno{Binary: binary, PolicyDir: policyDir, Run: ExecRunner}
+}
+
+func (k *Kyverno) Name() string { return "kyverno" }
+
+type policyReport struct {
+	Kind    string               `json:"kind"`
+	Results []policyReportResult `json:"results"`
+}
+
+type policyReportResult struct {
+	Policy    string `json:"policy"`
+	Rule      string `json:"rule"`
+	Result    string `json:"result"`
+	Message   string `json:"message"`
+	Resources []struct {
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"resources"`
+}
+
+func (k *Kyverno) Validate(ctx context.Context, objs []*unstructured.Unstructured) ([]Violation, error) {
+	dir, err := os.MkdirTemp("", "kyverno-resources-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	resourceFile := filepath.Join(dir, "resources.yaml")
+	if err := writeManifests(resourceFile, objs); err != nil {
+		return nil, err
+	}
+
+	// kyverno exits non-zero when a rule fails, so the report is parsed before
+	// the error is considered.
+	out, runErr := k.Run(ctx, nil, k.Binary, "apply", k.PolicyDir, "--resource", resourceFile, "--policy-report")
+	violations, parseErr := parseKyvernoReport(out)
+	if parseErr != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, parseErr
+	}
+	if runErr != nil && len(violations) == 0 {
+		return nil, runErr
+	}
+	return violations, nil
+}
+
+// parseKyvernoReport extracts failed results from the policy report that
+// `kyverno apply --policy-report` prints after its progress output.
+func parseKyvernoReport(out []byte) ([]Violation, error) {
+	text := string(out)
+	start := strings.Index(text, "apiVersion:")
+	if start < 0 {
+		return nil, fmt.Errorf("no policy report in kyverno output")
+	}
+
+	var violations []Violation
+	for _, doc := range strings.Split(text[start:], "\n---") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var report policyReport
+		if err := yaml.Unmarshal([]byte(doc), &report); err != nil {
+			return nil, fmt.Errorf("failed to parse kyverno policy report: %w", err)
+		}
+		for _, res := range report.Results {
+			if res.Result != "fail" && res.Result != "error" {
+				continue
+			}
+			policy := res.Policy
+			if res.Rule != "" {
+				policy += "/" + res.Rule
+			}
+			for _, r := range res.Resources {
+				violations = append(violations, Violation{
+					Validator: "kyverno",
+					Policy:    policy,
+					Kind:      r.Kind,
+					Namespace: r.Namespace,
+					Name:      r.Name,
+					Message:   res.Message,
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+func writeManifests(path string, objs []*unstructured.Unstructured) error {
+	var b strings.Builder
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		b.WriteString("---\n")
+		b.Write(data)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o600)
+}
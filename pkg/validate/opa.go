@@ -0,0 +1,102 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DefaultOPAQuery is the rule evaluated when no query is configured. It follows
+// the conftest convention of a deny set holding one message per failure.
+const DefaultOPAQuery = "data.platformmesh.deny"
+
+// OPA evaluates each object as input to `opa eval` with the rego policies in PolicyDir.
+type OPA struct {
+	Binary    string
+	PolicyDir string
+	Query     string
+	Run       Runner
+}
+
+// NewOPA returns an OPA validator using the given CLI binary.
+func NewOPA(binary, policyDir, query string) *OPA {
+	if query == "" {
+		query = DefaultOPAQuery
+	}
+	return &OPA{Binary: binary, PolicyDir: policyDir, Query: query, Run: ExecRunner}
+}
+
+func (o *OPA) Name() string { return "opa" }
+
+type opaEvalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func (o *OPA) Validate(ctx context.Context, objs []*unstructured.Unstructured) ([]Violation, error) {
+	var violations []Violation
+	for _, obj := range objs {
+		input, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		out, err := o.Run(ctx, input, o.Binary, "eval", "--format", "json", "--data", o.PolicyDir, "--stdin-input", o.Query)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := parseOPAMessages(out)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			violations = append(violations, Violation{
+				Validator: "opa",
+				Policy:    o.Query,
+				Kind:      obj.GetKind(),
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   msg,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// parseOPAMessages reads the query value as a list of messages. Entries may be
+// plain strings or objects carrying a "msg" field.
+func parseOPAMessages(out []byte) ([]string, error) {
+	var res opaEvalOutput
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse opa output: %w", err)
+	}
+	var messages []string
+	for _, r := range res.Result {
+		for _, expr := range r.Expressions {
+			var entries []json.RawMessage
+			if err := json.Unmarshal(expr.Value, &entries); err != nil {
+				return nil, fmt.Errorf("opa query must evaluate to a set or array: %w", err)
+			}
+			for _, entry := range entries {
+				var msg string
+				if err := json.Unmarshal(entry, &msg); err == nil {
+					messages = append(messages, msg)
+					continue
+				}
+				var obj struct {
+					Msg string `json:"msg"`
+				}
+				if err := json.Unmarshal(entry, &obj); err != nil || obj.Msg == "" {
+					messages = append(messages, string(entry))
+					continue
+				}
+				messages = append(messages, obj.Msg)
+			}
+		}
+	}
+	return messages, nil
+}
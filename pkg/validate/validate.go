@@ -0,0 +1,109 @@
+// Package validate evaluates rendered manifests against local policies before
+// they are applied, so a render pass either writes every object or none.
+//
+// Validators shell out to the policy engines' CLIs (kyverno, opa) with the
+// policies bundled next to the templates. The CLIs are not part of the
+// operator image; BinaryAvailable tells whether a configured one can be run. All validators run on the full set
+// of objects and every violation is collected before Run reports failure.
+package validate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Violation is a single policy failure reported for a rendered object.
+type Violation struct {
+	Validator string
+	Policy    string
+	Kind      string
+	Namespace string
+	Name      string
+	Message   string
+}
+
+func (v Violation) String() string {
+	ref := v.Kind + " " + v.Name
+	if v.Namespace != "" {
+		ref = v.Kind + " " + v.Namespace + "/" + v.Name
+	}
+	return fmt.Sprintf("[%s] %s: %s: %s", v.Validator, ref, v.Policy, v.Message)
+}
+
+// Validator checks a batch of rendered objects. An error means the validator
+// could not run; policy failures are returned as violations.
+type Validator interface {
+	Name() string
+	Validate(ctx context.Context, objs []*unstructured.Unstructured) ([]Violation, error)
+}
+
+// ViolationsError lists every violation found in a render pass.
+type ViolationsError struct {
+	Violations []Violation
+}
+
+func (e *ViolationsError) Error() string {
+	lines := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		lines = append(lines, v.String())
+	}
+	return fmt.Sprintf("%d policy violation(s) in rendered manifests: %s", len(e.Violations), strings.Join(lines, "; "))
+}
+
+// Run evaluates objs with every validator and returns a *ViolationsError
+// holding all violations, sorted for stable output, when any are found.
+func Run(ctx context.Context, validators []Validator, objs []*unstructured.Unstructured) error {
+	if len(validators) == 0 || len(objs) == 0 {
+		return nil
+	}
+	var violations []Violation
+	for _, v := range validators {
+		found, err := v.Validate(ctx, objs)
+		if err != nil {
+			return fmt.Errorf("validator %s failed: %w", v.Name(), err)
+		}
+		violations = append(violations, found...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].String() < violations[j].String()
+	})
+	return &ViolationsError{Violations: violations}
+}
+
+// BinaryAvailable reports whether binary resolves to an executable, either as
+// a path or through PATH.
+func BinaryAvailable(binary string) bool {
+	if binary == "" {
+		return false
+	}
+	_, err := exec.LookPath(binary)
+	return err == nil
+}
+
+// Runner executes a CLI with the given stdin and returns its stdout. A non-zero
+// exit is returned as an error alongside whatever was written to stdout.
+type Runner func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands with os/exec and includes stderr in returned errors.
+func ExecRunner(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
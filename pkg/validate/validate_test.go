@@ -0,0 +1,189 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testObject(kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+type staticValidator struct {
+	name       string
+	violations []Violation
+	err        error
+	calls      int
+}
+
+func (s *staticValidator) Name() string { return s.name }
+
+func (s *staticValidator) Validate(context.Context, []*unstructured.Unstructured) ([]Violation, error) {
+	s.calls++
+	return s.violations, s.err
+}
+
+func TestRunCollectsViolationsFromAllValidators(t *testing.T) {
+	first := &staticValidator{name: "a", violations: []Violation{{Validator: "a", Policy: "p2", Kind: "Service", Name: "svc", Message: "bad port"}}}
+	second := &staticValidator{name: "b", violations: []Violation{{Validator: "b", Policy: "p1", Kind: "Deployment", Namespace: "ns", Name: "app", Message: "no limits"}}}
+
+	err := Run(context.Background(), []Validator{first, second}, []*unstructured.Unstructured{testObject("Service", "", "svc")})
+
+	var vErr *ViolationsError
+	require.True(t, errors.As(err, &vErr))
+	assert.Len(t, vErr.Violations, 2)
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 1, second.calls)
+	assert.Equal(t, "2 policy violation(s) in rendered manifests: [a] Service svc: p2: bad port; [b] Deployment ns/app: p1: no limits", err.Error())
+}
+
+func TestRunReturnsValidatorError(t *testing.T) {
+	failing := &staticValidator{name: "broken", err: errors.New("binary not found")}
+
+	err := Run(context.Background(), []Validator{failing}, []*unstructured.Unstructured{testObject("Service", "", "svc")})
+
+	assert.EqualError(t, err, "validator broken failed: binary not found")
+}
+
+func TestRunWithoutValidatorsOrObjects(t *testing.T) {
+	v := &staticValidator{name: "a", violations: []Violation{{Message: "x"}}}
+
+	assert.NoError(t, Run(context.Background(), nil, []*unstructured.Unstructured{testObject("Service", "", "svc")}))
+	assert.NoError(t, Run(context.Background(), []Validator{v}, nil))
+	assert.Zero(t, v.calls)
+}
+
+func TestBinaryAvailable(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "kyverno")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o700))
+
+	assert.True(t, BinaryAvailable(bin))
+	assert.False(t, BinaryAvailable(filepath.Join(dir, "opa")))
+	assert.False(t, BinaryAvailable(""))
+}
+
+const kyvernoOutput = `
+Applying 2 policy rule(s) to 2 resource(s)...
+----------------------------------------------------------------------
+POLICY REPORT:
+----------------------------------------------------------------------
+apiVersion: wgpolicyk8s.io/v1alpha2
+kind: ClusterPolicyReport
+metadata:
+  name: merged
+results:
+- message: validation rule 'check-limits' passed.
+  policy: require-limits
+  resources:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: ok
+    namespace: platform-mesh-system
+  result: pass
+  rule: check-limits
+- message: 'validation error: resource limits are required.'
+  policy: require-limits
+  resources:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: app
+    namespace: platform-mesh-system
+  result: fail
+  rule: check-limits
+summary:
+  fail: 1
+  pass: 1
+`
+
+func TestKyvernoParsesFailedResults(t *testing.T) {
+	var gotArgs []string
+	var resources string
+	k := NewKyverno("kyverno", "/policies/kyverno")
+	k.Run = func(_ context.Context, _ []byte, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string{name}, args...)
+		data, err := os.ReadFile(args[3])
+		require.NoError(t, err)
+		resources = string(data)
+		return []byte(kyvernoOutput), errors.New("exit status 1")
+	}
+
+	violations, err := k.Validate(context.Background(), []*unstructured.Unstructured{
+		testObject("Deployment", "platform-mesh-system", "ok"),
+		testObject("Deployment", "platform-mesh-system", "app"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kyverno", "apply", "/policies/kyverno", "--resource"}, gotArgs[:4])
+	assert.Equal(t, "--policy-report", gotArgs[5])
+	assert.Equal(t, 2, strings.Count(resources, "kind: Deployment"))
+	require.Len(t, violations, 1)
+	assert.Equal(t, Violation{
+		Validator: "kyverno",
+		Policy:    "require-limits/check-limits",
+		Kind:      "Deployment",
+		Namespace: "platform-mesh-system",
+		Name:      "app",
+		Message:   "validation error: resource limits are required.",
+	}, violations[0])
+}
+
+func TestKyvernoReturnsRunErrorWithoutReport(t *testing.T) {
+	k := NewKyverno("kyverno", "/policies/kyverno")
+	k.Run = func(context.Context, []byte, string, ...string) ([]byte, error) {
+		return nil, errors.New("kyverno: executable file not found")
+	}
+
+	_, err := k.Validate(context.Background(), []*unstructured.Unstructured{testObject("Service", "", "svc")})
+
+	assert.EqualError(t, err, "kyverno: executable file not found")
+}
+
+func TestOPAEvaluatesEachObject(t *testing.T) {
+	var inputs []string
+	o := NewOPA("opa", "/policies/opa", "")
+	o.Run = func(_ context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+		assert.Equal(t, "opa", name)
+		assert.Equal(t, []string{"eval", "--format", "json", "--data", "/policies/opa", "--stdin-input", DefaultOPAQuery}, args)
+		inputs = append(inputs, string(stdin))
+		if strings.Contains(string(stdin), `"name":"bad"`) {
+			return []byte(`{"result":[{"expressions":[{"value":["hostNetwork is not allowed",{"msg":"privileged containers are not allowed"}]}]}]}`), nil
+		}
+		return []byte(`{"result":[{"expressions":[{"value":[]}]}]}`), nil
+	}
+
+	violations, err := o.Validate(context.Background(), []*unstructured.Unstructured{
+		testObject("Pod", "default", "good"),
+		testObject("Pod", "default", "bad"),
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, inputs, 2)
+	require.Len(t, violations, 2)
+	assert.Equal(t, "[opa] Pod default/bad: data.platformmesh.deny: hostNetwork is not allowed", violations[0].String())
+	assert.Equal(t, "privileged containers are not allowed", violations[1].Message)
+}
+
+func TestOPARejectsNonListQueryResult(t *testing.T) {
+	o := NewOPA("opa", "/policies/opa", "data.custom.allow")
+	o.Run = func(context.Context, []byte, string, ...string) ([]byte, error) {
+		return []byte(`{"result":[{"expressions":[{"value":true}]}]}`), nil
+	}
+
+	_, err := o.Validate(context.Background(), []*unstructured.Unstructured{testObject("Pod", "default", "p")})
+
+	assert.ErrorContains(t, err, "opa query must evaluate to a set or array")
+}
@@ -0,0 +1,92 @@
+package version
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DevVersion is reported by binaries built without a release version.
+	// Dev builds are considered compatible with any bundle.
+	DevVersion = "v0.0.0-dev"
+
+	// BundleVersionAnnotation is set on the operator CRDs to record the
+	// release bundle they were generated from.
+	BundleVersionAnnotation = "core.platform-mesh.io/bundle-version"
+
+	// BundleVersionFile is the file name inside the gotemplates directory that
+	// records the bundle version shipped with the image.
+	BundleVersionFile = "BUNDLE_VERSION"
+
+	// PlatformMeshCRDName is the CRD whose annotations are checked for skew.
+	PlatformMeshCRDName = "platformmeshes.core.platform-mesh.io"
+)
+
+// Version is the operator version, injected at build time via
+// -ldflags "-X github.com/platform-mesh/platform-mesh-operator/pkg/version.Version=<version>".
+var Version = DevVersion
+
+// SkewError is returned when two components are from incompatible major versions.
+type SkewError struct {
+	Source          string
+	OperatorVersion string
+	BundleVersion   string
+}
+
+func (e *SkewError) Error() string {
+	return fmt.Sprintf("version skew: operator %s is incompatible with %s version %s", e.OperatorVersion, e.Source, e.BundleVersion)
+}
+
+// IsDev reports whether v is empty or a development build.
+func IsDev(v string) bool {
+	return v == "" || v == DevVersion
+}
+
+// Major returns the major component of a semver-like version string such as
+// "v1.2.3" or "1.2.3-rc.1".
+func Major(v string) (int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	majorStr, _, _ := strings.Cut(trimmed, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: %w", v, err)
+	}
+	return major, nil
+}
+
+// CheckCompatibility verifies that the bundle version reported by source
+// (e.g. "gotemplates" or a CRD name) shares the major version of the operator.
+// Dev builds and unknown bundle versions are treated as compatible.
+func CheckCompatibility(source, operatorVersion, bundleVersion string) error {
+	if IsDev(operatorVersion) || IsDev(bundleVersion) {
+		return nil
+	}
+	opMajor, err := Major(operatorVersion)
+	if err != nil {
+		return err
+	}
+	bundleMajor, err := Major(bundleVersion)
+	if err != nil {
+		return err
+	}
+	if opMajor != bundleMajor {
+		return &SkewError{Source: source, OperatorVersion: operatorVersion, BundleVersion: bundleVersion}
+	}
+	return nil
+}
+
+// ReadBundleVersion reads the bundle version file from dir. A missing file
+// yields an empty version and no error.
+func ReadBundleVersion(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BundleVersionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,50 @@
+package version
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMajor(t *testing.T) {
+	major, err := Major("v1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, major)
+
+	major, err = Major("2.0.0-rc.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, major)
+
+	_, err = Major("latest")
+	assert.Error(t, err)
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	assert.NoError(t, CheckCompatibility("gotemplates", "v1.2.0", "v1.9.1"))
+	assert.NoError(t, CheckCompatibility("gotemplates", DevVersion, "v2.0.0"))
+	assert.NoError(t, CheckCompatibility("gotemplates", "v1.0.0", ""))
+
+	err := CheckCompatibility("gotemplates", "v1.2.0", "v2.0.0")
+	var skew *SkewError
+	assert.True(t, errors.As(err, &skew))
+	assert.Equal(t, "gotemplates", skew.Source)
+	assert.Equal(t, "v2.0.0", skew.BundleVersion)
+
+	assert.Error(t, CheckCompatibility("gotemplates", "v1.2.0", "garbage"))
+}
+
+func TestReadBundleVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	v, err := ReadBundleVersion(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, BundleVersionFile), []byte("v1.4.0\n"), 0o600))
+	v, err = ReadBundleVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.4.0", v)
+}
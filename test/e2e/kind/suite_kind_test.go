@@ -385,7 +385,7 @@ func (s *KindTestSuite) createSecrets(ctx context.Context, dirRootPath []byte) e
 }
 
 func (s *KindTestSuite) createReleases(ctx context.Context) error {
-	if err := ApplyManifestFromFile(ctx, "../../../test/e2e/kind/yaml/flux2-v2.6.4/flux2-install.yaml", s.client, make(map[string]string)); err != nil {
+	if err := ApplyManifestFromFile(ctx, "../../../pkg/bootstrap/manifests/flux2-v2.6.4/flux2-install.yaml", s.client, make(map[string]string)); err != nil {
 		return err
 	}
 	avail := s.Eventually(func() bool {
@@ -581,6 +581,16 @@ func (s *KindTestSuite) InstallCRDs(ctx context.Context) error {
 		return err
 	}
 
+	if err := ApplyManifestFromFile(ctx, "../../../config/crd/core.platform-mesh.io_platformmeshlandscapes.yaml", s.client, make(map[string]string)); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to apply PlatformMeshLandscape CRD manifest")
+		return err
+	}
+
+	if err := ApplyManifestFromFile(ctx, "../../../config/crd/core.platform-mesh.io_rendersnapshots.yaml", s.client, make(map[string]string)); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to apply RenderSnapshot CRD manifest")
+		return err
+	}
+
 	if err := ApplyManifestFromFile(ctx, "../../../config/crd/providers.platform-mesh.io_managedproviders.yaml", s.client, make(map[string]string)); err != nil {
 		s.logger.Error().Err(err).Msg("Failed to apply ManagedProvider CRD manifest")
 		return err